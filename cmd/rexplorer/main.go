@@ -0,0 +1,749 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/spf13/cobra"
+	"github.com/threefoldfoundation/rexplorer/pkg/rexplorer"
+	"github.com/threefoldfoundation/tfchain/pkg/config"
+	"github.com/threefoldfoundation/tfchain/pkg/types"
+)
+
+func main() {
+	cmd := new(rexplorer.Commands)
+	cmd.RPCaddr = ":23112"
+	cmd.DatabaseDriver = "redis"
+	cmd.SelfCheckSampleSize = rexplorer.DefaultSelfCheckSampleSize
+	cmd.DatabaseBatchSize = rexplorer.DefaultRedisBatchSize
+	cmd.DatabaseEncoding = rexplorer.EncodingTypeJSON.String()
+	cmd.DiffTop = 10
+	cmd.SyncWorkers = runtime.NumCPU()
+	cmd.ExportHeight = -1
+	cmd.KafkaBlockTopic = rexplorer.DefaultKafkaBlockTopic
+	cmd.KafkaTransactionTopic = rexplorer.DefaultKafkaTransactionTopic
+	cmd.KafkaBalanceTopic = rexplorer.DefaultKafkaBalanceTopic
+	cmd.NATSSubjectPrefix = rexplorer.DefaultNATSSubjectPrefix
+	cmd.TraceSampleRatio = rexplorer.DefaultTraceSampleRatio
+	cmd.BlockchainInfo = config.GetBlockchainInfo()
+
+	// define commands
+	cmdRoot := &cobra.Command{
+		Use:   "rexplorer",
+		Short: "start the rexplorer daemon",
+		Args:  cobra.ExactArgs(0),
+		PersistentPreRunE: func(*cobra.Command, []string) error {
+			switch cmd.BlockchainInfo.NetworkName {
+			case config.NetworkNameStandard:
+				// Register the transaction controllers for all transaction versions
+				// supported on the standard network
+				types.RegisterTransactionTypesForStandardNetwork()
+				// Forbid the usage of MultiSignatureCondition (and thus the multisig feature),
+				// until the blockchain reached a height of 42000 blocks.
+				types.RegisterBlockHeightLimitedMultiSignatureCondition(42000)
+				// get chain constants and bootstrap peers
+				cmd.ChainConstants = config.GetStandardnetGenesis()
+				cmd.BootstrapPeers = config.GetStandardnetBootstrapPeers()
+			case config.NetworkNameTest:
+				// Register the transaction controllers for all transaction versions
+				// supported on the test network
+				types.RegisterTransactionTypesForTestNetwork()
+				// Use our custom MultiSignatureCondition, just for testing purposes
+				types.RegisterBlockHeightLimitedMultiSignatureCondition(0)
+				// get chain constants and bootstrap peers
+				cmd.ChainConstants = config.GetTestnetGenesis()
+				cmd.BootstrapPeers = config.GetTestnetBootstrapPeers()
+			default:
+				return fmt.Errorf(
+					"%q is an invalid network name, has to be one of {standard,testnet}",
+					cmd.BlockchainInfo.NetworkName)
+			}
+			return nil
+		},
+		RunE: cmd.Root,
+	}
+
+	cmdVersion := &cobra.Command{
+		Use:   "version",
+		Short: "show versions of this tool",
+		Args:  cobra.ExactArgs(0),
+		Run:   cmd.Version,
+	}
+
+	cmdOutput := &cobra.Command{
+		Use:   "output <coinOutputID>",
+		Short: "show all stored data of a coin output, including its full condition",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmd.Output,
+	}
+
+	cmdBlockStakeOutput := &cobra.Command{
+		Use:   "bsoutput <blockStakeOutputID>",
+		Short: "show all stored data of a block stake output, including its full condition",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmd.BlockStakeOutput,
+	}
+
+	cmdBlock := &cobra.Command{
+		Use:   "block <height|blockID>",
+		Short: "show the stored record of a block, referencing its miner payouts and transactions",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmd.Block,
+	}
+
+	cmdTransaction := &cobra.Command{
+		Use:   "tx <txID>",
+		Short: "show the stored record of a transaction, including the owner and value of the coin outputs it spent",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmd.Transaction,
+	}
+
+	cmdAtomicSwap := &cobra.Command{
+		Use:   "atomicswap <contractID|address>",
+		Short: "show the details and state of an atomic swap contract, or of all contracts sent or received by an address",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmd.AtomicSwap,
+	}
+
+	cmdArbitraryData := &cobra.Command{
+		Use:   "data <prefix|hash>",
+		Short: "list the transactions of which the arbitrary data starts with the given prefix, or has the given hash",
+		Long: `List the transactions of which the arbitrary data starts with the given (text) prefix,
+together with their full arbitrary data. Arbitrary data is indexed by its first 8 bytes,
+such that a shorter prefix only matches the arbitrary data equal to it. When passing the --hex flag
+the prefix is hex-encoded instead, while the --hash flag lists the transactions of which
+the arbitrary data has the given (hex-encoded blake2b) hash.`,
+		Args: cobra.ExactArgs(1),
+		RunE: cmd.ArbitraryData,
+	}
+	cmdArbitraryData.Flags().BoolVar(
+		&cmd.ArbitraryDataHex,
+		"hex",
+		cmd.ArbitraryDataHex,
+		"the prefix is hex-encoded",
+	)
+	cmdArbitraryData.Flags().BoolVar(
+		&cmd.ArbitraryDataHash,
+		"hash",
+		cmd.ArbitraryDataHash,
+		"look up the transactions of which the arbitrary data has the given hash",
+	)
+
+	cmdPreview := &cobra.Command{
+		Use:   "preview [transaction.json]",
+		Short: "preview the effect of a (JSON-encoded) transaction on the tracked wallets, without applying it",
+		Long: `Preview the effect of a (JSON-encoded and optionally unsigned) transaction on the tracked wallets,
+reporting the balance changes, outputs consumed and created, and the lock state of the created outputs,
+without committing anything. The transaction is read from STDIN if no file (or "-") is given.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: cmd.Preview,
+	}
+
+	cmdFlows := &cobra.Command{
+		Use:   "flows",
+		Short: "report the daily sweeps and refills between the labeled hot and cold wallets",
+		Args:  cobra.ExactArgs(0),
+		RunE:  cmd.Flows,
+	}
+
+	cmdBlocks := &cobra.Command{
+		Use:   "blocks <fromHeight> [toHeight]",
+		Short: "report the difficulty, txs, value transferred, fees, payouts, creator and outputs of each block within the given height range",
+		Long: `Report the difficulty, transaction count, value transferred, fees, miner payouts and creator, as well as the output count,
+total output value and output value histogram (with the value ranges expressed in coins)
+of each block within the given (inclusive) height range, as to render a block list without decoding the transactions,
+or to detect anomalies such as sudden bursts of dust creation.
+Only the block at the given height is reported if no end height is given.`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: cmd.Blocks,
+	}
+
+	cmdDaily := &cobra.Command{
+		Use:   "daily <fromDate> [toDate]",
+		Short: "report the blocks, txs, value transferred, fees and new and active addresses of each UTC day within the given date range",
+		Long: `Report the amount of blocks and transactions, the value transferred, the fees (total, average, p50 and p90)
+and the amount of new and active addresses of each UTC day within the given (inclusive) date range,
+the dates formatted as YYYY-MM-DD. The active addresses are counted for the day itself,
+as well as for the 30 days up to the latest block of that day. The velocity of a day is the value transferred
+divided by the circulating supply as of the latest block of that day.
+Only the given day is reported if no end date is given.`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: cmd.Daily,
+	}
+
+	cmdStatsHistory := &cobra.Command{
+		Use:   "statshistory <fromDate> [toDate]",
+		Short: "report the supply, txs and locked coins as of the latest block of each UTC day within the given date range",
+		Long: `Report the network stats as of the latest block of each UTC day within the given (inclusive) date range,
+the dates formatted as YYYY-MM-DD: the block height, the amount of transactions, the coins (and the liquid,
+locked and burned coins) and the amount of locked coin outputs.
+Only the given day is reported if no end date is given.`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: cmd.StatsHistory,
+	}
+
+	cmdWhales := &cobra.Command{
+		Use:   "whales <date> [n]",
+		Short: "list the addresses which sent and received the most coins on a UTC day, 100 unless specified otherwise",
+		Long: `List the addresses which sent the most coins, and the addresses which received the most coins,
+on the given UTC day, formatted as YYYY-MM-DD, ranked by the coins sent or received that day.
+The leaderboards of a day are trimmed to the top 100 addresses once the first block of the next day is explored,
+hence at most 100 addresses are listed for past days.`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: cmd.Whales,
+	}
+
+	cmdRolling := &cobra.Command{
+		Use:   "rolling",
+		Short: "report the blocks, txs, value transferred, fees and new addresses of the last 24 hours and 7 days",
+		Long: `Report the amount of blocks and transactions, the value transferred, the fees
+and the amount of new addresses of the blocks created within the last 24 hours and 7 days,
+up to the latest explored block, together with the height of the oldest block within each window.`,
+		Args: cobra.NoArgs,
+		RunE: cmd.Rolling,
+	}
+
+	cmdSigners := &cobra.Command{
+		Use:   "signers <multisigAddress>",
+		Short: "report which owners of a multisig wallet signed its spent coin outputs",
+		Long: `Report the signing activity of each owner of a multisig wallet, counting the spent coin outputs
+(and their total value) each owner signed, followed by the owners that signed each spent coin output,
+such that organizations can audit who actually authorizes the spends from their shared wallets.`,
+		Args: cobra.ExactArgs(1),
+		RunE: cmd.Signers,
+	}
+
+	cmdWallet := &cobra.Command{
+		Use:   "wallet <address>",
+		Short: "show the stored wallet of an address, optionally merged with the wallets of its aliases",
+		Long: `Show the stored wallet of an address. When passing the --merge-aliases flag,
+the wallets of the address and all its (direct and indirect) aliases are merged into a single view,
+resolving the given address first, such that the same view is shown for any of those addresses.`,
+		Args: cobra.ExactArgs(1),
+		RunE: cmd.Wallet,
+	}
+	cmdWallet.Flags().BoolVar(
+		&cmd.MergeAliases,
+		"merge-aliases",
+		cmd.MergeAliases,
+		"merge the wallets of the address and all its aliases",
+	)
+
+	cmdAlias := &cobra.Command{
+		Use:   "alias <aliasAddress> <address>",
+		Short: "record an (old) address as alias of another (new) address, e.g. after a wallet migration",
+		Args:  cobra.ExactArgs(2),
+		RunE:  cmd.Alias,
+	}
+
+	cmdUnalias := &cobra.Command{
+		Use:   "unalias <aliasAddress>",
+		Short: "remove a recorded address alias",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmd.Unalias,
+	}
+
+	cmdAliases := &cobra.Command{
+		Use:   "aliases",
+		Short: "list all recorded address aliases",
+		Args:  cobra.ExactArgs(0),
+		RunE:  cmd.Aliases,
+	}
+
+	cmdWatch := &cobra.Command{
+		Use:   "watch <address> <url>",
+		Short: "register a webhook notified of the balance changes and output (un)locks of an address",
+		Args:  cobra.ExactArgs(2),
+		RunE:  cmd.Watch,
+	}
+
+	cmdUnwatch := &cobra.Command{
+		Use:   "unwatch <address> [url]",
+		Short: "remove a registered webhook, or all webhooks of the address if no url is given",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE:  cmd.Unwatch,
+	}
+
+	cmdWebhooks := &cobra.Command{
+		Use:   "webhooks",
+		Short: "list all registered webhooks",
+		Args:  cobra.ExactArgs(0),
+		RunE:  cmd.Webhooks,
+	}
+
+	cmdPrefixes := &cobra.Command{
+		Use:   "prefixes [prefix...]",
+		Short: "report the wallet count and balance rolled up per address prefix",
+		Long: `Report the amount of wallets and their total (un)locked balance, rolled up per address prefix,
+the prefix being the first 6 characters of an address, and thus the bucket (a:<prefix> key) the Redis drivers store its wallet in.
+All prefixes are reported if none are given. When passing the --verify flag, the stats of each reported prefix
+are recomputed from the wallets stored for it, pinpointing the prefixes which are inconsistent.`,
+		RunE: cmd.Prefixes,
+	}
+	cmdPrefixes.Flags().BoolVar(
+		&cmd.VerifyPrefixes,
+		"verify",
+		cmd.VerifyPrefixes,
+		"verify the stats of the reported prefixes against their wallets",
+	)
+
+	cmdSnapshots := &cobra.Command{
+		Use:   "snapshots",
+		Short: "list the heights at which the balance of all wallets was snapshotted",
+		Args:  cobra.ExactArgs(0),
+		RunE:  cmd.Snapshots,
+	}
+
+	cmdDiff := &cobra.Command{
+		Use:   "diff <height> <height>",
+		Short: "report the supply, lock and balance changes in between two snapshotted heights",
+		Long: `Report the changes in between two heights at which the balance of all wallets was snapshotted
+(see the --snapshot-interval flag): the changes of the coin supply and locked coins,
+followed by the wallets of which the balance changed, biggest (absolute) change of their total balance first.`,
+		Args: cobra.ExactArgs(2),
+		RunE: cmd.Diff,
+	}
+	cmdDiff.Flags().IntVar(
+		&cmd.DiffTop,
+		"top",
+		cmd.DiffTop,
+		"amount of changed wallets reported, 0 to report all of them",
+	)
+
+	cmdMintConditions := &cobra.Command{
+		Use:   "minters [height]",
+		Short: "list the history of the mint condition, or show the mint condition active at the given height",
+		Long: `List all changes of the mint condition, being the condition which has to be fulfilled in order to create coins,
+as defined by the minter definition transactions: the height and tx of each change, and the address of the new condition.
+When a height is given, the full mint condition active at that height is shown instead.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: cmd.MintConditions,
+	}
+
+	cmdThreeBot := &cobra.Command{
+		Use:   "threebot <id|name>",
+		Short: "show the record of a 3Bot, by its ID or one of its names",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmd.ThreeBot,
+	}
+
+	cmdGenesis := &cobra.Command{
+		Use:   "genesis",
+		Short: "show the coin and block stake outputs allocated by the genesis block",
+		Args:  cobra.NoArgs,
+		RunE:  cmd.Genesis,
+	}
+
+	cmdERC20 := &cobra.Command{
+		Use:   "erc20 [address]",
+		Short: "show the volumes bridged from and to ERC20 tokens, or the ERC20 or TFT address registered for an address",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  cmd.ERC20,
+	}
+
+	cmdRichList := &cobra.Command{
+		Use:   "richlist [n]",
+		Short: "list the addresses with the highest coin balance, 100 unless specified otherwise",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  cmd.RichList,
+	}
+
+	cmdDistribution := &cobra.Command{
+		Use:   "distribution",
+		Short: "count the addresses with a non-zero coin balance per balance range",
+		Args:  cobra.NoArgs,
+		RunE:  cmd.Distribution,
+	}
+
+	cmdBlockCreators := &cobra.Command{
+		Use:   "creators [n]",
+		Short: "list the block creators which created the most blocks, 100 unless specified otherwise",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  cmd.BlockCreators,
+	}
+
+	cmdMinerPayouts := &cobra.Command{
+		Use:   "payouts <address>",
+		Short: "list the miner payouts received by an address, oldest first, with their total value",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmd.MinerPayouts,
+	}
+
+	cmdHistory := &cobra.Command{
+		Use:   "history <address>",
+		Short: "list the txs in which an address sent or received coins, oldest first, with the addresses which sent the coins",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmd.History,
+	}
+
+	cmdExportUTXO := &cobra.Command{
+		Use:   "export-utxo",
+		Short: "export all coin outputs unspent at a given height, one JSON object per line, ordered by ID",
+		Long: `Export all coin outputs unspent at the given height (the current height by default): the ID, owner, value,
+condition and lock of each coin output, one JSON object per line, ordered by ID, such that exports of the same height are identical.
+Heights prior to the current height can only be exported if the provenance of all coin outputs is recorded.`,
+		Args: cobra.ExactArgs(0),
+		RunE: cmd.ExportUTXO,
+	}
+	cmdExportUTXO.Flags().Int64Var(
+		&cmd.ExportHeight,
+		"height",
+		cmd.ExportHeight,
+		"height at which the unspent coin outputs are exported, -1 for the current height",
+	)
+	cmdExportUTXO.Flags().StringVar(
+		&cmd.ExportFile,
+		"out",
+		cmd.ExportFile,
+		"file the unspent coin outputs are exported to, the standard output if not defined",
+	)
+
+	cmdMigrate := &cobra.Command{
+		Use:   "migrate",
+		Short: "upgrade the stored data to the latest schema version, instead of exploring the chain again",
+		Long: `Upgrade the stored data to the latest schema version, one version at a time,
+such that data stored by an older version of this tool doesn't have to be explored again starting from the genesis block.
+Stop the rexplorer daemon prior to migrating its data, as it refuses to start until the data uses the latest schema version.`,
+		Args: cobra.ExactArgs(0),
+		RunE: cmd.Migrate,
+	}
+
+	cmdRecomputeStats := &cobra.Command{
+		Use:   "recompute-stats",
+		Short: "recompute the network stats from the stored coin outputs, reporting the stats which diverge",
+		Long: `Recompute the network stats which can be derived from the stored coin outputs: the amount of coin outputs and inputs,
+the coins, the locked coin outputs and coins (by lock type), the burned coin outputs and coins and the unspent genesis coins.
+The stats of which the stored value diverges from the recomputed value are reported, and only repaired
+when using the --apply flag, keeping all other stats as stored. Stop the rexplorer daemon prior to repairing its stats.`,
+		Args: cobra.ExactArgs(0),
+		RunE: cmd.RecomputeStats,
+	}
+	cmdRecomputeStats.Flags().BoolVar(
+		&cmd.RecomputeApply,
+		"apply",
+		cmd.RecomputeApply,
+		"store the recomputed network stats, instead of only reporting the stats which diverge",
+	)
+
+	// define command tree
+	cmdRoot.AddCommand(
+		cmdVersion,
+		cmdOutput,
+		cmdBlockStakeOutput,
+		cmdBlock,
+		cmdTransaction,
+		cmdAtomicSwap,
+		cmdArbitraryData,
+		cmdPreview,
+		cmdFlows,
+		cmdBlocks,
+		cmdDaily,
+		cmdRolling,
+		cmdStatsHistory,
+		cmdWhales,
+		cmdSigners,
+		cmdWallet,
+		cmdAlias,
+		cmdUnalias,
+		cmdAliases,
+		cmdWatch,
+		cmdUnwatch,
+		cmdWebhooks,
+		cmdPrefixes,
+		cmdSnapshots,
+		cmdDiff,
+		cmdMintConditions,
+		cmdThreeBot,
+		cmdERC20,
+		cmdGenesis,
+		cmdRichList,
+		cmdDistribution,
+		cmdMinerPayouts,
+		cmdHistory,
+		cmdBlockCreators,
+		cmdExportUTXO,
+		cmdMigrate,
+		cmdRecomputeStats,
+	)
+
+	// define flags
+	cmdRoot.Flags().StringVarP(
+		&cmd.RootPersistentDir,
+		"persistent-directory", "d",
+		cmd.RootPersistentDir,
+		"location of the root diretory used to store persistent data of the daemon of "+cmd.BlockchainInfo.Name,
+	)
+	cmdRoot.Flags().StringVar(
+		&cmd.RPCaddr,
+		"rpc-addr",
+		cmd.RPCaddr,
+		"which port the gateway listens on",
+	)
+	cmdRoot.Flags().StringVar(
+		&cmd.APIaddr,
+		"api-addr",
+		cmd.APIaddr,
+		"host:port to serve the read-only HTTP API on, disabled if not defined",
+	)
+	cmdRoot.Flags().StringVar(
+		&cmd.GRPCaddr,
+		"grpc-addr",
+		cmd.GRPCaddr,
+		"host:port to serve the read-only gRPC service on (using unencrypted HTTP/2), disabled if not defined",
+	)
+	cmdRoot.Flags().StringVar(
+		&cmd.APIKeysFile,
+		"api-keys-file",
+		cmd.APIKeysFile,
+		"file defining the API keys (and their scopes) required by the HTTP API and gRPC service, not required if not defined",
+	)
+	cmdRoot.Flags().IntVar(
+		&cmd.APIRateLimit,
+		"api-rate-limit",
+		cmd.APIRateLimit,
+		"maximum amount of requests per second made to the HTTP API and gRPC service by a single client IP, 0 for no limit",
+	)
+	cmdRoot.Flags().IntVar(
+		&cmd.APIKeyRateLimit,
+		"api-key-rate-limit",
+		cmd.APIKeyRateLimit,
+		"maximum amount of requests per second made to the HTTP API and gRPC service using a single API key, 0 for no limit",
+	)
+	cmdRoot.Flags().StringSliceVar(
+		&cmd.APICORSOrigins,
+		"api-cors-origin",
+		cmd.APICORSOrigins,
+		"origin(s) allowed to make cross-origin requests to the HTTP API (e.g. \"https://explorer.example.com\"), \"*\" allowing any origin",
+	)
+	cmdRoot.Flags().StringSliceVar(
+		&cmd.APICORSHeaders,
+		"api-cors-header",
+		cmd.APICORSHeaders,
+		"request header(s) cross-origin requests can use besides the Authorization and Content-Type headers, \"*\" allowing any header",
+	)
+	cmdRoot.Flags().BoolVar(
+		&cmd.DatabasePublishEvents,
+		"db-publish-events",
+		cmd.DatabasePublishEvents,
+		"publish the block, output and wallet events of every applied and reverted block to redis pub/sub channels",
+	)
+	cmdRoot.Flags().BoolVar(
+		&cmd.DatabaseArchiveSpentOutputs,
+		"db-archive-spent-outputs",
+		cmd.DatabaseArchiveSpentOutputs,
+		"move spent coin outputs to the spent:<id[:4]> redis keys, together with the height they were spent at, rather than keeping them in place",
+	)
+	cmdRoot.Flags().StringSliceVar(
+		&cmd.KafkaBrokers,
+		"kafka-brokers",
+		cmd.KafkaBrokers,
+		"host:port address(es) of the Kafka brokers the block, transaction and balance events of every consensus change are produced to, disabled if not defined",
+	)
+	cmdRoot.Flags().StringVar(
+		&cmd.KafkaBlockTopic,
+		"kafka-block-topic",
+		cmd.KafkaBlockTopic,
+		"Kafka topic the block events are produced to, not produced if empty",
+	)
+	cmdRoot.Flags().StringVar(
+		&cmd.KafkaTransactionTopic,
+		"kafka-tx-topic",
+		cmd.KafkaTransactionTopic,
+		"Kafka topic the transaction events are produced to, not produced if empty",
+	)
+	cmdRoot.Flags().StringVar(
+		&cmd.KafkaBalanceTopic,
+		"kafka-balance-topic",
+		cmd.KafkaBalanceTopic,
+		"Kafka topic the balance events are produced to, not produced if empty",
+	)
+	cmdRoot.Flags().StringVar(
+		&cmd.NATSURL,
+		"nats-url",
+		cmd.NATSURL,
+		"URL (nats://[user:password@]host:port) of the NATS server the events of every consensus change are published to, disabled if not defined",
+	)
+	cmdRoot.Flags().StringVar(
+		&cmd.NATSSubjectPrefix,
+		"nats-subject-prefix",
+		cmd.NATSSubjectPrefix,
+		"prefix of the NATS subjects the events are published to",
+	)
+	cmdRoot.Flags().StringArrayVar(
+		&cmd.NATSSubjects,
+		"nats-subject",
+		cmd.NATSSubjects,
+		fmt.Sprintf("NATS subject in the <event>=<subject> format, relative to the subject prefix, overwriting the default subject of the event, an empty subject disabling it, one of %v", rexplorer.NATSSubjectEvents()),
+	)
+	cmdRoot.Flags().StringVar(
+		&cmd.OTLPEndpoint,
+		"otlp-endpoint",
+		cmd.OTLPEndpoint,
+		"base URL of the OTLP/HTTP collector the traces of the processed consensus changes are exported to, defaults to $"+rexplorer.OTLPEndpointEnvVar+", disabled if not defined",
+	)
+	cmdRoot.Flags().StringArrayVar(
+		&cmd.OTLPHeaders,
+		"otlp-header",
+		cmd.OTLPHeaders,
+		"header in the key=value format added to every trace export request, in addition to those of $"+rexplorer.OTLPHeadersEnvVar,
+	)
+	cmdRoot.Flags().Float64Var(
+		&cmd.TraceSampleRatio,
+		"trace-sample-ratio",
+		cmd.TraceSampleRatio,
+		"ratio of the consensus changes which are traced, within the [0, 1] range",
+	)
+	cmdRoot.Flags().BoolVar(
+		&cmd.SkipSelfCheck,
+		"skip-selfcheck",
+		cmd.SkipSelfCheck,
+		"skip the consistency self-check of the stored data on startup, starting even if the data is corrupt",
+	)
+	cmdRoot.Flags().IntVar(
+		&cmd.SelfCheckSampleSize,
+		"selfcheck-sample-size",
+		cmd.SelfCheckSampleSize,
+		"amount of randomly sampled wallets verified by the startup self-check, 0 to only verify the network stats",
+	)
+	cmdRoot.Flags().StringSliceVar(
+		&cmd.HotWallets,
+		"hot-wallet",
+		cmd.HotWallets,
+		"address(es) labeled as hot wallet, used to track the flows from/to the cold wallets",
+	)
+	cmdRoot.Flags().StringSliceVar(
+		&cmd.ColdWallets,
+		"cold-wallet",
+		cmd.ColdWallets,
+		"address(es) labeled as cold wallet, used to track the flows from/to the hot wallets",
+	)
+	cmdRoot.Flags().StringArrayVar(
+		&cmd.Hooks,
+		"hook",
+		cmd.Hooks,
+		fmt.Sprintf("hook in the <event>=<command|URL> format, invoked with a JSON payload for each occurrence of its event, one of %v", rexplorer.HookEvents()),
+	)
+	cmdRoot.Flags().Uint64Var(
+		&cmd.SnapshotInterval,
+		"snapshot-interval",
+		cmd.SnapshotInterval,
+		"interval (in blocks) at which the balance of all wallets is snapshotted, 0 to disable balance snapshots",
+	)
+	cmdRoot.Flags().IntVar(
+		&cmd.SyncWorkers,
+		"sync-workers",
+		cmd.SyncWorkers,
+		"amount of workers decoding consensus changes concurrently while the consensus set isn't synced, 0 to process them sequentially",
+	)
+	cmdRoot.Flags().Uint64Var(
+		&cmd.StateFlushBlocks,
+		"state-flush-blocks",
+		cmd.StateFlushBlocks,
+		"interval (in blocks) at which the explorer state and network stats are stored while the consensus set isn't synced, 0 to store them for every consensus change (unless --state-flush-interval is defined), only supported by database drivers supporting checkpoints",
+	)
+	cmdRoot.Flags().DurationVar(
+		&cmd.StateFlushInterval,
+		"state-flush-interval",
+		cmd.StateFlushInterval,
+		"interval (in time) at which the explorer state and network stats are stored while the consensus set isn't synced, 0 to store them for every consensus change (unless --state-flush-blocks is defined), only supported by database drivers supporting checkpoints",
+	)
+	cmdRoot.Flags().StringVar(
+		&cmd.MirrorDatabaseDriver,
+		"mirror-db-driver",
+		cmd.MirrorDatabaseDriver,
+		fmt.Sprintf("database driver of a secondary database all database calls are mirrored onto, one of %v", rexplorer.DatabaseDriverNames()),
+	)
+	cmdRoot.Flags().StringVar(
+		&cmd.MirrorDatabaseAddress,
+		"mirror-db-address",
+		cmd.MirrorDatabaseAddress,
+		"address of the secondary database, its format depends on the driver",
+	)
+	cmdRoot.Flags().IntVar(
+		&cmd.MirrorDatabaseSlot,
+		"mirror-db-slot",
+		cmd.MirrorDatabaseSlot,
+		"which database slot of the secondary database to use, if supported by the driver",
+	)
+	cmdRoot.PersistentFlags().StringVar(
+		&cmd.DatabaseDriver,
+		"db-driver",
+		cmd.DatabaseDriver,
+		fmt.Sprintf("which database driver to use, one of %v", rexplorer.DatabaseDriverNames()),
+	)
+	cmdRoot.PersistentFlags().StringVar(
+		&cmd.DatabaseAddress,
+		"db-address",
+		cmd.DatabaseAddress,
+		"address of the database, its format depends on the driver (defaults to \":6379\" for redis)",
+	)
+	cmdRoot.PersistentFlags().IntVar(
+		&cmd.DatabaseSlot,
+		"db-slot",
+		cmd.DatabaseSlot,
+		"which database slot to use, if supported by the driver",
+	)
+	cmdRoot.PersistentFlags().StringVar(
+		&cmd.DatabasePassword,
+		"db-password",
+		cmd.DatabasePassword,
+		fmt.Sprintf("password used to authenticate to the redis server, defaults to the %s environment variable", rexplorer.DatabasePasswordEnvVar),
+	)
+	cmdRoot.PersistentFlags().BoolVar(
+		&cmd.DatabaseTLS,
+		"db-tls",
+		cmd.DatabaseTLS,
+		"connect to the redis server using TLS",
+	)
+	cmdRoot.PersistentFlags().IntVar(
+		&cmd.DatabaseCommandRate,
+		"db-command-rate",
+		cmd.DatabaseCommandRate,
+		"maximum amount of commands per second issued to the redis server, 0 for no limit",
+	)
+	cmdRoot.PersistentFlags().IntVar(
+		&cmd.DatabaseBatchSize,
+		"db-batch-size",
+		cmd.DatabaseBatchSize,
+		"maximum amount of writes pipelined at once to the redis server while syncing",
+	)
+	cmdRoot.PersistentFlags().StringVar(
+		&cmd.DatabaseKeyPrefix,
+		"db-key-prefix",
+		cmd.DatabaseKeyPrefix,
+		"prefix of all redis keys (e.g. \"tft:standard:\"), such that multiple networks can share a single redis database",
+	)
+	cmdRoot.PersistentFlags().StringVar(
+		&cmd.DatabaseEncoding,
+		"db-encoding",
+		cmd.DatabaseEncoding,
+		"encoding of the values stored in a fresh redis database, one of [json msgpack protobuf], existing data keeps its encoding",
+	)
+	// deprecated redis flags, kept for backwards compatibility
+	cmdRoot.PersistentFlags().StringVar(
+		&cmd.DatabaseAddress,
+		"redis-addr",
+		cmd.DatabaseAddress,
+		"which (tcp) address the redis server listens on",
+	)
+	cmdRoot.PersistentFlags().MarkDeprecated("redis-addr", "use --db-address instead")
+	cmdRoot.PersistentFlags().IntVar(
+		&cmd.DatabaseSlot,
+		"redis-db",
+		cmd.DatabaseSlot,
+		"which redis database slot to use",
+	)
+	cmdRoot.PersistentFlags().MarkDeprecated("redis-db", "use --db-slot instead")
+	cmdRoot.PersistentFlags().StringVarP(
+		&cmd.BlockchainInfo.NetworkName,
+		"network", "n",
+		cmd.BlockchainInfo.NetworkName,
+		"the name of the network to which the daemon connects, one of {standard,testnet}",
+	)
+
+	// execute logic
+	if err := cmdRoot.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
@@ -1,12 +1,12 @@
 package main
 
 import (
-	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
 
 	"github.com/rivine/rivine/types"
+	explorerclient "github.com/threefoldfoundation/rexplorer/pkg/client"
 
 	"github.com/gomodule/redigo/redis"
 )
@@ -25,25 +25,27 @@ func main() {
 		panic(fmt.Sprintf("invalid uh %q: %v", args[0], err))
 	}
 
-	conn, err := redis.Dial("tcp", dbAddress, redis.DialDatabase(dbSlot))
+	cl, err := explorerclient.Dial(dbAddress, dbSlot,
+		redis.DialPassword(dbPassword), redis.DialUseTLS(dbTLS))
 	if err != nil {
 		panic(err)
 	}
+	cl.SetKeyPrefix(dbKeyPrefix)
+	conn := cl.Conn()
 
 	addressKey, addressField := getAddressKeyAndField(uh)
 	var wallet struct {
 		MultiSignAddresses []types.UnlockHash `json:"multisignAddresses,omitempty"`
 	}
 	b, err := redis.Bytes(conn.Do("HGET", addressKey, addressField))
-	if err != nil {
-		if err != redis.ErrNil {
-			panic("failed to get wallet " + err.Error())
-		}
-		b = []byte("{}")
+	if err != nil && err != redis.ErrNil {
+		panic("failed to get wallet " + err.Error())
 	}
-	err = json.Unmarshal(b, &wallet)
-	if err != nil {
-		panic("failed to json-unmarshal wallet: " + err.Error())
+	if err == nil {
+		err = cl.UnmarshalWallet(b, &wallet)
+		if err != nil {
+			panic("failed to unmarshal wallet: " + err.Error())
+		}
 	}
 
 	// print all unlock hashes
@@ -54,16 +56,22 @@ func main() {
 
 func getAddressKeyAndField(uh types.UnlockHash) (key, field string) {
 	str := uh.String()
-	key, field = "a:"+str[:6], str[6:]
+	key, field = dbKeyPrefix+"a:"+str[:6], str[6:]
 	return
 }
 
 var (
-	dbAddress string
-	dbSlot    int
+	dbAddress   string
+	dbSlot      int
+	dbPassword  string
+	dbTLS       bool
+	dbKeyPrefix string
 )
 
 func init() {
 	flag.StringVar(&dbAddress, "db-address", ":6379", "(tcp) address of the redis db")
 	flag.IntVar(&dbSlot, "db-slot", 0, "slot/index of the redis db")
+	flag.StringVar(&dbPassword, "db-password", "", "password used to authenticate to the redis db")
+	flag.BoolVar(&dbTLS, "db-tls", false, "connect to the redis db using TLS")
+	flag.StringVar(&dbKeyPrefix, "db-key-prefix", "", "prefix of all keys, as used by rexplorer (e.g. \"tft:standard:\")")
 }
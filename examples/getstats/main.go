@@ -1,13 +1,13 @@
 package main
 
 import (
-	"encoding/json"
 	"flag"
 	"fmt"
 	"math/big"
 
 	"github.com/rivine/rivine/pkg/client"
 	"github.com/rivine/rivine/types"
+	explorerclient "github.com/threefoldfoundation/rexplorer/pkg/client"
 	"github.com/threefoldfoundation/tfchain/pkg/config"
 
 	"github.com/gomodule/redigo/redis"
@@ -16,17 +16,17 @@ import (
 func main() {
 	flag.Parse()
 
-	conn, err := redis.Dial("tcp", dbAddress, redis.DialDatabase(dbSlot))
+	cl, err := explorerclient.Dial(dbAddress, dbSlot,
+		redis.DialPassword(dbPassword), redis.DialUseTLS(dbTLS))
 	if err != nil {
 		panic(err)
 	}
+	cl.SetKeyPrefix(dbKeyPrefix)
+	conn := cl.Conn()
 
-	const (
-		statsKey     = "stats"
-		addressesKey = "addresses"
-	)
+	const statsKey = "stats"
 
-	b, err := redis.Bytes(conn.Do("GET", statsKey))
+	b, err := redis.Bytes(conn.Do("GET", cl.Key(statsKey)))
 	if err != nil {
 		panic("failed to get network stats: " + err.Error())
 	}
@@ -45,12 +45,12 @@ func main() {
 		Coins                  types.Currency    `json:"coins"`
 		LockedCoins            types.Currency    `json:"lockedCoins"`
 	}
-	err = json.Unmarshal(b, &stats)
+	err = cl.Unmarshal(b, &stats)
 	if err != nil {
-		panic("failed to json-unmarshal network stats: " + err.Error())
+		panic("failed to unmarshal network stats: " + err.Error())
 	}
 
-	uniqueAddressCount, err := redis.Uint64(conn.Do("SCARD", addressesKey))
+	uniqueAddressCount, err := cl.AddressCount()
 	if err != nil {
 		panic("failed to get length of unique addresses: " + err.Error())
 	}
@@ -105,11 +105,17 @@ func main() {
 }
 
 var (
-	dbAddress string
-	dbSlot    int
+	dbAddress   string
+	dbSlot      int
+	dbPassword  string
+	dbTLS       bool
+	dbKeyPrefix string
 )
 
 func init() {
 	flag.StringVar(&dbAddress, "db-address", ":6379", "(tcp) address of the redis db")
 	flag.IntVar(&dbSlot, "db-slot", 0, "slot/index of the redis db")
+	flag.StringVar(&dbPassword, "db-password", "", "password used to authenticate to the redis db")
+	flag.BoolVar(&dbTLS, "db-tls", false, "connect to the redis db using TLS")
+	flag.StringVar(&dbKeyPrefix, "db-key-prefix", "", "prefix of all keys, as used by rexplorer (e.g. \"tft:standard:\")")
 }
@@ -0,0 +1,346 @@
+// Package client provides a read-only Go client,
+// which can be used to consume the data stored by a rexplorer instance in Redis.
+package client
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rivine/rivine/types"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/threefoldfoundation/rexplorer/pkg/msgpack"
+	"github.com/threefoldfoundation/rexplorer/pkg/rediscluster"
+	"github.com/threefoldfoundation/rexplorer/pkg/redissentinel"
+)
+
+// Keys as used by rexplorer, and consumed by this client,
+// prefixed with the key prefix of the client (see SetKeyPrefix).
+const (
+	AddressesKey      = "addresses"
+	AddressesCountKey = "addresses.count"
+	SnapshotHoldKey   = "snapshot.hold"
+	SnapshotAckKey    = "snapshot.ack"
+	InternalKey       = "internal"
+)
+
+// The encodings of the values stored by rexplorer, as returned by Client.Encoding.
+const (
+	EncodingJSON     = "json"
+	EncodingMsgPack  = "msgpack"
+	EncodingProtobuf = "protobuf"
+)
+
+// DefaultScanCount is the default amount of elements
+// which are requested per SSCAN call (a hint for Redis, not a guarantee).
+const DefaultScanCount = 1000
+
+// Client is a read-only client for the data stored by rexplorer in Redis.
+type Client struct {
+	conn      redis.Conn
+	keyPrefix string
+	// encoding of the stored values, loaded when first required
+	encoding string
+}
+
+// NewClient creates a new client, using an existing Redis connection.
+func NewClient(conn redis.Conn) *Client {
+	return &Client{conn: conn}
+}
+
+// Dial creates a new client, by dialing a TCP connection to the Redis server,
+// and selecting the given database (slot).
+func Dial(address string, db int, options ...redis.DialOption) (*Client, error) {
+	conn, err := redis.Dial("tcp", address, append(options, redis.DialDatabase(db))...)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to dial a Redis connection to tcp://%s@%d: %v", address, db, err)
+	}
+	return NewClient(conn), nil
+}
+
+// DialCluster creates a new client for a Redis Cluster, using the given addresses as startup nodes,
+// routing each command to the node serving the hash slot of its key.
+func DialCluster(addresses []string, options ...redis.DialOption) (*Client, error) {
+	conn, err := rediscluster.Dial(addresses, options...)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to dial a Redis Cluster connection to %s: %v", strings.Join(addresses, ","), err)
+	}
+	return NewClient(conn), nil
+}
+
+// DialSentinel creates a new client for the master of a group monitored by Redis Sentinel,
+// using the given sentinel addresses to discover that master, and selecting the given database (slot).
+// The client follows the master as it fails over.
+func DialSentinel(masterName string, sentinels []string, db int, options ...redis.DialOption) (*Client, error) {
+	conn, err := redissentinel.Dial(masterName, sentinels, append(options, redis.DialDatabase(db))...)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to dial a Redis Sentinel connection to master %s@%d via %s: %v",
+			masterName, db, strings.Join(sentinels, ","), err)
+	}
+	return NewClient(conn), nil
+}
+
+// Conn returns the underlying Redis connection.
+func (c *Client) Conn() redis.Conn {
+	return c.conn
+}
+
+// SetKeyPrefix sets the prefix of all keys, which has to match the key prefix used by rexplorer
+// (see its --db-key-prefix flag), e.g. "tft:standard:". No prefix is used by default.
+func (c *Client) SetKeyPrefix(prefix string) {
+	c.keyPrefix = prefix
+}
+
+// Key returns the given key, prefixed with the key prefix of the client,
+// such that it can be used when querying the underlying Redis connection directly.
+func (c *Client) Key(name string) string {
+	return c.keyPrefix + name
+}
+
+// Encoding returns the encoding of the (structured) values stored by rexplorer,
+// EncodingJSON, EncodingMsgPack or EncodingProtobuf, as recorded by rexplorer when it created the dataset.
+func (c *Client) Encoding() (string, error) {
+	if c.encoding != "" {
+		return c.encoding, nil
+	}
+	encoding, err := redis.String(c.conn.Do("HGET", c.Key(InternalKey), "encoding"))
+	if err == redis.ErrNil {
+		encoding, err = EncodingJSON, nil // recorded since the encoding is configurable
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get encoding: %v", err)
+	}
+	if encoding != EncodingJSON && encoding != EncodingMsgPack && encoding != EncodingProtobuf {
+		return "", fmt.Errorf("unsupported encoding %q", encoding)
+	}
+	c.encoding = encoding
+	return encoding, nil
+}
+
+// Unmarshal decodes a (structured) value stored by rexplorer, such as the network stats,
+// into the given (reference) value, using the encoding recorded by rexplorer (see Encoding).
+// The struct tags and types used to decode a JSON-encoded value can be used for any encoding.
+// Wallets have to be decoded using UnmarshalWallet instead, as they are stored as protobuf messages
+// when using the protobuf encoding, while all other values are stored as MessagePack.
+func (c *Client) Unmarshal(data []byte, v interface{}) error {
+	encoding, err := c.Encoding()
+	if err != nil {
+		return err
+	}
+	if encoding == EncodingMsgPack || encoding == EncodingProtobuf {
+		return msgpack.Unmarshal(data, v)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// UnmarshalWallet decodes a wallet stored by rexplorer into the given (reference) value,
+// using the encoding recorded by rexplorer (see Encoding). The struct tags and types used
+// to decode a JSON-encoded wallet can be used for any encoding, as a protobuf-encoded wallet
+// (a rexplorer.StoredWallet message) is decoded using its JSON representation.
+func (c *Client) UnmarshalWallet(data []byte, v interface{}) error {
+	encoding, err := c.Encoding()
+	if err != nil {
+		return err
+	}
+	if encoding != EncodingProtobuf {
+		return c.Unmarshal(data, v)
+	}
+	wallet, err := decodeStoredWallet(data)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(wallet)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// Close closes the underlying Redis connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// AddressCount returns the amount of unique addresses used in the network,
+// as maintained by rexplorer, an O(1) operation.
+func (c *Client) AddressCount() (uint64, error) {
+	n, err := redis.Uint64(c.conn.Do("GET", c.Key(AddressesCountKey)))
+	if err == redis.ErrNil {
+		return 0, nil
+	}
+	return n, err
+}
+
+// ScanAddresses returns a batch of unique addresses, using the given cursor.
+// Start a scan using cursor 0, and stop it once the returned cursor is 0 once again.
+// The count is a hint of how many addresses should be returned,
+// it defaults to DefaultScanCount if 0 is given.
+//
+// As it is based on SSCAN, it provides the same guarantees:
+// an address which is stored for the entire duration of the scan is returned at least once.
+func (c *Client) ScanAddresses(cursor uint64, count int) (uint64, []types.UnlockHash, error) {
+	if count <= 0 {
+		count = DefaultScanCount
+	}
+	values, err := redis.Values(c.conn.Do("SSCAN", c.Key(AddressesKey), cursor, "COUNT", count))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to scan addresses: %v", err)
+	}
+	if len(values) != 2 {
+		return 0, nil, fmt.Errorf("failed to scan addresses: unexpected reply of length %d", len(values))
+	}
+	next, err := redis.Uint64(values[0], nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to scan addresses: invalid cursor: %v", err)
+	}
+	strs, err := redis.Strings(values[1], nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to scan addresses: invalid addresses: %v", err)
+	}
+	addresses := make([]types.UnlockHash, len(strs))
+	for i, str := range strs {
+		err = addresses[i].LoadString(str)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to scan addresses: invalid address %q: %v", str, err)
+		}
+	}
+	return next, addresses, nil
+}
+
+// AddressIterator can be used to iterate over all unique addresses,
+// without having to load them all in memory at once.
+//
+//	it := client.Addresses(0)
+//	for it.Next() {
+//	    fmt.Println(it.Address().String())
+//	}
+//	if err := it.Err(); err != nil {
+//	    panic(err)
+//	}
+//
+// Note that, as it is based on SSCAN, the same address can be returned multiple times.
+type AddressIterator struct {
+	client *Client
+	count  int
+
+	cursor    uint64
+	started   bool
+	addresses []types.UnlockHash
+	address   types.UnlockHash
+	err       error
+}
+
+// Addresses returns an iterator over all unique addresses,
+// fetching them in batches of (roughly) the given count.
+func (c *Client) Addresses(count int) *AddressIterator {
+	return &AddressIterator{
+		client: c,
+		count:  count,
+	}
+}
+
+// Next moves the iterator to the next address,
+// returning false if no more addresses are available or an error occurred.
+func (it *AddressIterator) Next() bool {
+	for len(it.addresses) == 0 {
+		if it.err != nil || (it.started && it.cursor == 0) {
+			return false
+		}
+		it.started = true
+		it.cursor, it.addresses, it.err = it.client.ScanAddresses(it.cursor, it.count)
+	}
+	it.address, it.addresses = it.addresses[0], it.addresses[1:]
+	return true
+}
+
+// Address returns the current address of the iterator.
+func (it *AddressIterator) Address() types.UnlockHash {
+	return it.address
+}
+
+// Err returns the error which stopped the iteration, if any.
+func (it *AddressIterator) Err() error {
+	return it.err
+}
+
+// ErrSnapshotHeld is returned by HoldSnapshot in case another client holds a snapshot already.
+var ErrSnapshotHeld = errors.New("a snapshot is already held by another client")
+
+// snapshotPollInterval is the interval used to poll for the acknowledgement of a snapshot hold.
+const snapshotPollInterval = 100 * time.Millisecond
+
+// releaseSnapshotScript releases a snapshot hold, only if it is still held using the given token.
+var releaseSnapshotScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// SnapshotHold is a hold on a logically consistent snapshot of the data stored by rexplorer,
+// obtained using HoldSnapshot. As long as it is held, rexplorer pauses the application of blocks,
+// such that long-running exports and verifications aren't skewed by blocks applied mid-scan.
+type SnapshotHold struct {
+	client *Client
+	token  string
+}
+
+// HoldSnapshot requests rexplorer to pause the application of blocks,
+// waiting (at most for the given timeout) until rexplorer acknowledges it has paused.
+// The hold expires automatically after the given TTL, such that rexplorer doesn't pause forever
+// should the client never release it. ErrSnapshotHeld is returned if another client holds a snapshot already.
+//
+// Note that the acknowledgement is only given by a running rexplorer instance,
+// meaning this call times out if no rexplorer instance is writing to the database.
+func (c *Client) HoldSnapshot(ttl, timeout time.Duration) (*SnapshotHold, error) {
+	var b [16]byte
+	_, err := rand.Read(b[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate snapshot hold token: %v", err)
+	}
+	hold := &SnapshotHold{
+		client: c,
+		token:  hex.EncodeToString(b[:]),
+	}
+	reply, err := c.conn.Do("SET", c.Key(SnapshotHoldKey), hold.token, "PX", int64(ttl/time.Millisecond), "NX")
+	if err != nil {
+		return nil, fmt.Errorf("failed to hold snapshot: %v", err)
+	}
+	if reply == nil {
+		return nil, ErrSnapshotHeld
+	}
+	// wait until rexplorer acknowledges it has paused for our hold
+	deadline := time.Now().Add(timeout)
+	for {
+		ack, err := redis.String(c.conn.Do("GET", c.Key(SnapshotAckKey)))
+		if err != nil && err != redis.ErrNil {
+			hold.Release()
+			return nil, fmt.Errorf("failed to get snapshot acknowledgement: %v", err)
+		}
+		if ack == hold.token {
+			return hold, nil
+		}
+		if time.Now().After(deadline) {
+			hold.Release()
+			return nil, fmt.Errorf("snapshot hold wasn't acknowledged within %v, is rexplorer running?", timeout)
+		}
+		time.Sleep(snapshotPollInterval)
+	}
+}
+
+// Release releases the snapshot hold, allowing rexplorer to resume the application of blocks.
+func (hold *SnapshotHold) Release() error {
+	_, err := releaseSnapshotScript.Do(hold.client.conn, hold.client.Key(SnapshotHoldKey), hold.token)
+	if err != nil {
+		return fmt.Errorf("failed to release snapshot hold: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package client
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+var errProtoTruncated = errors.New("truncated protobuf message")
+
+// protoMessage is a decoded protobuf message, mapping each field number onto its values,
+// which are uint64 values for varint fields, and []byte values for length-delimited fields.
+// Fields of other wire types are skipped.
+type protoMessage map[int][]interface{}
+
+// decodeProtoMessage decodes a protobuf message.
+func decodeProtoMessage(b []byte) (protoMessage, error) {
+	msg := protoMessage{}
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errProtoTruncated
+		}
+		b = b[n:]
+		field := int(tag >> 3)
+		switch wireType := tag & 7; wireType {
+		case 0:
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				return nil, errProtoTruncated
+			}
+			msg[field] = append(msg[field], v)
+			b = b[n:]
+		case 1, 5:
+			size := 8
+			if wireType == 5 {
+				size = 4
+			}
+			if len(b) < size {
+				return nil, errProtoTruncated
+			}
+			b = b[size:]
+		case 2:
+			length, n := binary.Uvarint(b)
+			if n <= 0 || length > uint64(len(b)-n) {
+				return nil, errProtoTruncated
+			}
+			msg[field] = append(msg[field], b[n:n+int(length)])
+			b = b[n+int(length):]
+		default:
+			return nil, fmt.Errorf("unsupported protobuf wire type %d", wireType)
+		}
+	}
+	return msg, nil
+}
+
+// uint64 returns the (last) value of a varint field, 0 if not defined.
+func (msg protoMessage) uint64(field int) uint64 {
+	values := msg[field]
+	if len(values) == 0 {
+		return 0
+	}
+	v, _ := values[len(values)-1].(uint64)
+	return v
+}
+
+// bytes returns the (last) value of a length-delimited field, nil if not defined.
+func (msg protoMessage) bytes(field int) []byte {
+	values := msg[field]
+	if len(values) == 0 {
+		return nil
+	}
+	b, _ := values[len(values)-1].([]byte)
+	return b
+}
+
+// currency returns the (last) value of a (decimal) string field, "0" if not defined.
+func (msg protoMessage) currency(field int) string {
+	if b := msg.bytes(field); len(b) > 0 {
+		return string(b)
+	}
+	return "0"
+}
+
+// strings returns all values of a (repeated) string field, nil if not defined.
+func (msg protoMessage) strings(field int) []string {
+	var strs []string
+	for _, v := range msg[field] {
+		if b, ok := v.([]byte); ok {
+			strs = append(strs, string(b))
+		}
+	}
+	return strs
+}
+
+// messages decodes all values of a (repeated) embedded message field.
+func (msg protoMessage) messages(field int) ([]protoMessage, error) {
+	var msgs []protoMessage
+	for _, v := range msg[field] {
+		b, ok := v.([]byte)
+		if !ok {
+			continue
+		}
+		m, err := decodeProtoMessage(b)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, nil
+}
+
+// message decodes the (last) value of an embedded message field, nil if not defined.
+func (msg protoMessage) message(field int) (protoMessage, error) {
+	msgs, err := msg.messages(field)
+	if err != nil || len(msgs) == 0 {
+		return nil, err
+	}
+	return msgs[len(msgs)-1], nil
+}
+
+// decodeStoredWallet decodes a rexplorer.StoredWallet message (see rexplorer.proto) into its JSON representation,
+// the same as the one stored by rexplorer when using the JSON encoding.
+func decodeStoredWallet(data []byte) (map[string]interface{}, error) {
+	wallet, err := decodeProtoMessage(data)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := wallet.message(2)
+	if err != nil {
+		return nil, err
+	}
+	locked, err := balance.message(2)
+	if err != nil {
+		return nil, err
+	}
+	outputs, err := locked.messages(2)
+	if err != nil {
+		return nil, err
+	}
+	lockedOutputs := make(map[string]interface{}, len(outputs))
+	for _, output := range outputs {
+		lockedOutput := map[string]interface{}{
+			"amount":      output.currency(2),
+			"lockedUntil": output.uint64(3),
+			"description": output.bytes(4),
+		}
+		if reason := output.bytes(5); len(reason) > 0 {
+			lockedOutput["reason"] = string(reason)
+		}
+		lockedOutputs[string(output.bytes(1))] = lockedOutput
+	}
+	lockedBalance := map[string]interface{}{
+		"total":   locked.currency(1),
+		"outputs": lockedOutputs,
+	}
+	horizons, err := locked.message(3)
+	if err != nil {
+		return nil, err
+	}
+	if horizons != nil {
+		lockedBalance["horizons"] = map[string]interface{}{
+			"asOf":  horizons.uint64(1),
+			"day":   horizons.currency(2),
+			"month": horizons.currency(3),
+			"year":  horizons.currency(4),
+			"later": horizons.currency(5),
+		}
+	}
+	multisig, err := wallet.message(4)
+	if err != nil {
+		return nil, err
+	}
+	blockStakes, err := wallet.message(5)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"version": wallet.uint64(1),
+		"balance": map[string]interface{}{
+			"unlocked": balance.currency(1),
+			"locked":   lockedBalance,
+		},
+		"multisignaddresses": wallet.strings(3),
+		"multisign": map[string]interface{}{
+			"owners":             multisig.strings(1),
+			"signaturesRequired": multisig.uint64(2),
+		},
+		"blockstakes": map[string]interface{}{
+			"unlocked": blockStakes.currency(1),
+			"locked":   blockStakes.currency(2),
+		},
+	}, nil
+}
@@ -0,0 +1,361 @@
+// Package msgpack encodes values as MessagePack (https://msgpack.org),
+// the compact binary alternative to JSON used by rexplorer when configured to do so.
+//
+// Values are encoded using their JSON representation, such that the JSON struct tags
+// and (custom) JSON marshalers of the encoded types are respected, and a value decodes
+// into the same types it would decode into from JSON. Objects are encoded as maps (sorted by key),
+// numbers as the smallest integer type which fits them (or as a float64 if they aren't integral),
+// and all other JSON values as their MessagePack equivalent.
+//
+// As Lua (and thus the Redis scripts of rexplorer) cannot tell an empty array from an empty map,
+// an empty array is decoded as null, which decodes into a nil slice or map.
+package msgpack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Marshal returns the MessagePack encoding of the given value.
+func Marshal(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	err = decoder.Decode(&value)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	err = encodeValue(&buf, value)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes the given MessagePack-encoded data into the given (reference) value.
+func Unmarshal(data []byte, v interface{}) error {
+	d := decoder{data: data}
+	value, err := d.decodeValue()
+	if err != nil {
+		return fmt.Errorf("msgpack: %v", err)
+	}
+	if d.offset != len(data) {
+		return fmt.Errorf("msgpack: %d trailing byte(s) after value", len(data)-d.offset)
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// encodeValue encodes a value as decoded from JSON into the given buffer.
+func encodeValue(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		return encodeNumber(buf, v)
+	case string:
+		encodeString(buf, v)
+	case []interface{}:
+		encodeHeader(buf, len(v), 0x90, 16, 0xdc, 0xdd)
+		for _, element := range v {
+			err := encodeValue(buf, element)
+			if err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		encodeHeader(buf, len(v), 0x80, 16, 0xde, 0xdf)
+		for _, key := range keys {
+			encodeString(buf, key)
+			err := encodeValue(buf, v[key])
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack: cannot encode value of type %T", value)
+	}
+	return nil
+}
+
+// encodeNumber encodes a JSON number as the smallest integer type which fits it,
+// or as a float64 if it isn't integral (or too big).
+func encodeNumber(buf *bytes.Buffer, n json.Number) error {
+	if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		encodeInt(buf, i)
+		return nil
+	}
+	if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+		buf.WriteByte(0xcf)
+		binary.Write(buf, binary.BigEndian, u)
+		return nil
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return fmt.Errorf("msgpack: invalid number %q: %v", n, err)
+	}
+	buf.WriteByte(0xcb)
+	binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+	return nil
+}
+
+func encodeInt(buf *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i < 128:
+		buf.WriteByte(byte(i))
+	case i >= -32 && i < 0:
+		buf.WriteByte(byte(int8(i)))
+	case i > 0 && i <= math.MaxUint8:
+		buf.WriteByte(0xcc)
+		buf.WriteByte(byte(i))
+	case i > 0 && i <= math.MaxUint16:
+		buf.WriteByte(0xcd)
+		binary.Write(buf, binary.BigEndian, uint16(i))
+	case i > 0 && i <= math.MaxUint32:
+		buf.WriteByte(0xce)
+		binary.Write(buf, binary.BigEndian, uint32(i))
+	case i > 0:
+		buf.WriteByte(0xcf)
+		binary.Write(buf, binary.BigEndian, uint64(i))
+	case i >= math.MinInt8:
+		buf.WriteByte(0xd0)
+		buf.WriteByte(byte(int8(i)))
+	case i >= math.MinInt16:
+		buf.WriteByte(0xd1)
+		binary.Write(buf, binary.BigEndian, int16(i))
+	case i >= math.MinInt32:
+		buf.WriteByte(0xd2)
+		binary.Write(buf, binary.BigEndian, int32(i))
+	default:
+		buf.WriteByte(0xd3)
+		binary.Write(buf, binary.BigEndian, i)
+	}
+}
+
+func encodeString(buf *bytes.Buffer, s string) {
+	if len(s) < 32 {
+		buf.WriteByte(0xa0 | byte(len(s)))
+	} else if len(s) <= math.MaxUint8 {
+		buf.WriteByte(0xd9)
+		buf.WriteByte(byte(len(s)))
+	} else {
+		encodeHeader(buf, len(s), 0, 0, 0xda, 0xdb)
+	}
+	buf.WriteString(s)
+}
+
+// encodeHeader encodes the length of an array, map or string,
+// using the fix type if the length is less than fixMax, or the 16/32-bit type otherwise.
+func encodeHeader(buf *bytes.Buffer, n int, fix byte, fixMax int, type16, type32 byte) {
+	switch {
+	case n < fixMax:
+		buf.WriteByte(fix | byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(type16)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(type32)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+var errUnexpectedEnd = errors.New("unexpected end of data")
+
+// decoder decodes MessagePack data into the values it would be decoded into from JSON,
+// using json.Number for all numbers.
+type decoder struct {
+	data   []byte
+	offset int
+}
+
+func (d *decoder) decodeValue() (interface{}, error) {
+	b, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+	switch t := b[0]; {
+	case t <= 0x7f:
+		return json.Number(strconv.Itoa(int(t))), nil
+	case t >= 0xe0:
+		return json.Number(strconv.Itoa(int(int8(t)))), nil
+	case t&0xf0 == 0x80:
+		return d.decodeMap(int(t & 0x0f))
+	case t&0xf0 == 0x90:
+		return d.decodeArray(int(t & 0x0f))
+	case t&0xe0 == 0xa0:
+		return d.decodeString(int(t & 0x1f))
+	}
+	switch t := b[0]; t {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xd9: // bin8 or str8
+		n, err := d.readUint(1)
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeString(int(n))
+	case 0xc5, 0xda: // bin16 or str16
+		n, err := d.readUint(2)
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeString(int(n))
+	case 0xc6, 0xdb: // bin32 or str32
+		n, err := d.readUint(4)
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeString(int(n))
+	case 0xca:
+		u, err := d.readUint(4)
+		if err != nil {
+			return nil, err
+		}
+		return formatFloat(float64(math.Float32frombits(uint32(u))))
+	case 0xcb:
+		u, err := d.readUint(8)
+		if err != nil {
+			return nil, err
+		}
+		return formatFloat(math.Float64frombits(u))
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := d.readUint(1 << (t - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		return json.Number(strconv.FormatUint(u, 10)), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (t - 0xd0)
+		u, err := d.readUint(size)
+		if err != nil {
+			return nil, err
+		}
+		// sign-extend the integer of the given size
+		shift := uint(64 - 8*size)
+		return json.Number(strconv.FormatInt(int64(u<<shift)>>shift, 10)), nil
+	case 0xdc, 0xdd:
+		n, err := d.readUint(2 << (t - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(int(n))
+	case 0xde, 0xdf:
+		n, err := d.readUint(2 << (t - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(int(n))
+	default:
+		return nil, fmt.Errorf("unsupported type 0x%02x at offset %d", t, d.offset-1)
+	}
+}
+
+func (d *decoder) decodeString(n int) (interface{}, error) {
+	b, err := d.read(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (d *decoder) decodeArray(n int) (interface{}, error) {
+	if n == 0 {
+		return nil, nil // could have been an empty map as well
+	}
+	if n > len(d.data)-d.offset {
+		return nil, errUnexpectedEnd // each element takes at least one byte
+	}
+	array := make([]interface{}, n)
+	for i := range array {
+		var err error
+		array[i], err = d.decodeValue()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return array, nil
+}
+
+func (d *decoder) decodeMap(n int) (interface{}, error) {
+	if n > len(d.data)-d.offset {
+		return nil, errUnexpectedEnd // each pair takes at least two bytes
+	}
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key, err := d.decodeValue()
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.decodeValue()
+		if err != nil {
+			return nil, err
+		}
+		switch key := key.(type) {
+		case string:
+			m[key] = value
+		case json.Number:
+			m[key.String()] = value
+		default:
+			return nil, fmt.Errorf("unsupported map key of type %T at offset %d", key, d.offset)
+		}
+	}
+	return m, nil
+}
+
+func (d *decoder) read(n int) ([]byte, error) {
+	if n < 0 || n > len(d.data)-d.offset {
+		return nil, errUnexpectedEnd
+	}
+	b := d.data[d.offset : d.offset+n]
+	d.offset += n
+	return b, nil
+}
+
+// readUint reads a big-endian unsigned integer of the given size (1, 2, 4 or 8 bytes).
+func (d *decoder) readUint(size int) (uint64, error) {
+	b, err := d.read(size)
+	if err != nil {
+		return 0, err
+	}
+	var u uint64
+	for _, c := range b {
+		u = u<<8 | uint64(c)
+	}
+	return u, nil
+}
+
+func formatFloat(f float64) (interface{}, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, fmt.Errorf("unsupported float %v", f)
+	}
+	return json.Number(strconv.FormatFloat(f, 'g', -1, 64)), nil
+}
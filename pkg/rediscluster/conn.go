@@ -0,0 +1,461 @@
+// Package rediscluster provides a Redis Cluster aware connection,
+// which can be used anywhere a (redigo) redis.Conn is expected.
+//
+// Each command is routed to the (master) node serving the hash slot of its key,
+// following MOVED and ASK redirections as the cluster gets resharded.
+// Pipelined commands (using Send) are grouped per node when flushed,
+// while their replies are still received in the order the commands were sent.
+//
+// Commands which operate on multiple keys (including Lua scripts declaring multiple keys)
+// are only supported if all these keys map to the same hash slot.
+package rediscluster
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// SlotCount is the amount of hash slots a Redis Cluster divides its keyspace in.
+const SlotCount = 16384
+
+// maxRedirects is the maximum amount of redirections followed for a single command.
+const maxRedirects = 5
+
+// Slot returns the hash slot of the given key, taking hash tags into account:
+// if the key contains a non-empty substring between the first { and the first } following it,
+// only that substring is hashed, such that related keys can be forced into the same slot.
+func Slot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key) % SlotCount)
+}
+
+type (
+	// Conn is a redis.Conn for a Redis Cluster, see the package documentation for more information.
+	//
+	// Just like any other redis.Conn, a Conn is not safe for concurrent use.
+	Conn struct {
+		addresses []string
+		options   []redis.DialOption
+
+		// address of the node serving each slot, empty if unknown
+		slots [SlotCount]string
+		// (lazily dialed) connections, by node address
+		nodes map[string]redis.Conn
+		// sources of the loaded Lua scripts, loaded on each node as it gets dialed
+		scripts []string
+
+		// commands sent but not flushed yet
+		pending []command
+		// commands flushed, of which the reply hasn't been received yet
+		inflight []command
+
+		closed bool
+	}
+
+	command struct {
+		name string
+		args []interface{}
+		node string
+	}
+)
+
+var _ redis.Conn = (*Conn)(nil)
+
+// Dial connects to the Redis Cluster, using the given addresses as startup nodes
+// to discover the nodes (and the slots they serve) of the cluster.
+// The options are used to dial each node of the cluster.
+func Dial(addresses []string, options ...redis.DialOption) (*Conn, error) {
+	if len(addresses) == 0 {
+		return nil, errors.New("rediscluster: no startup node addresses given")
+	}
+	c := &Conn{
+		addresses: addresses,
+		options:   options,
+		nodes:     make(map[string]redis.Conn),
+	}
+	err := c.refreshSlots()
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Close implements redis.Conn.Close,
+// closing the connections to all nodes.
+func (c *Conn) Close() error {
+	var err error
+	for addr, conn := range c.nodes {
+		if cerr := conn.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("rediscluster: failed to close connection to %s: %v", addr, cerr)
+		}
+	}
+	c.nodes = make(map[string]redis.Conn)
+	c.closed = true
+	return err
+}
+
+// Err implements redis.Conn.Err
+func (c *Conn) Err() error {
+	if c.closed {
+		return errors.New("rediscluster: connection closed")
+	}
+	return nil
+}
+
+// Do implements redis.Conn.Do
+//
+// Just like a regular redis.Conn, all pending commands are flushed and their replies received first,
+// returning the first error reply (if any) of those commands. Calling Do with an empty command name
+// flushes the pending commands and returns all of their replies.
+func (c *Conn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	err := c.Flush()
+	if err != nil {
+		return nil, err
+	}
+	replies := make([]interface{}, 0, len(c.inflight))
+	var pendingErr error
+	for len(c.inflight) > 0 {
+		reply, err := c.Receive()
+		if err != nil {
+			if _, ok := err.(redis.Error); !ok {
+				return nil, err
+			}
+			if pendingErr == nil {
+				pendingErr = err
+			}
+			reply = err
+		}
+		replies = append(replies, reply)
+	}
+	if cmd == "" {
+		return replies, nil
+	}
+	reply, err := c.do(cmd, args)
+	if err == nil && pendingErr != nil {
+		err = pendingErr
+	}
+	return reply, err
+}
+
+// Send implements redis.Conn.Send
+func (c *Conn) Send(cmd string, args ...interface{}) error {
+	if c.closed {
+		return c.Err()
+	}
+	c.pending = append(c.pending, command{
+		name: cmd,
+		args: args,
+		node: c.nodeFor(cmd, args),
+	})
+	return nil
+}
+
+// Flush implements redis.Conn.Flush,
+// sending all pending commands to their nodes, pipelined per node.
+func (c *Conn) Flush() error {
+	if len(c.pending) == 0 {
+		return nil
+	}
+	pending := c.pending
+	c.pending = nil
+	used := make(map[string]redis.Conn)
+	for _, cmd := range pending {
+		conn, err := c.node(cmd.node)
+		if err != nil {
+			return err
+		}
+		err = conn.Send(cmd.name, cmd.args...)
+		if err != nil {
+			return fmt.Errorf("rediscluster: failed to send %s to %s: %v", cmd.name, cmd.node, err)
+		}
+		used[cmd.node] = conn
+		c.inflight = append(c.inflight, cmd)
+	}
+	for addr, conn := range used {
+		err := conn.Flush()
+		if err != nil {
+			return fmt.Errorf("rediscluster: failed to flush commands to %s: %v", addr, err)
+		}
+	}
+	return nil
+}
+
+// Receive implements redis.Conn.Receive,
+// receiving the reply of the oldest flushed command, retrying that command in case it got redirected.
+func (c *Conn) Receive() (interface{}, error) {
+	if len(c.inflight) == 0 {
+		return nil, errors.New("rediscluster: no reply pending")
+	}
+	cmd := c.inflight[0]
+	c.inflight = c.inflight[1:]
+	conn, err := c.node(cmd.node)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := conn.Receive()
+	if _, ok := parseRedirect(err); ok {
+		return c.do(cmd.name, cmd.args)
+	}
+	return reply, err
+}
+
+// do executes a single command on the node serving its key, following redirections.
+func (c *Conn) do(cmd string, args []interface{}) (interface{}, error) {
+	if c.closed {
+		return nil, c.Err()
+	}
+	if strings.EqualFold(cmd, "SCRIPT") {
+		return c.broadcastScript(args)
+	}
+	addr := c.nodeFor(cmd, args)
+	asking := false
+	for i := 0; ; i++ {
+		conn, err := c.node(addr)
+		if err != nil {
+			return nil, err
+		}
+		if asking {
+			err = conn.Send("ASKING")
+			if err != nil {
+				return nil, fmt.Errorf("rediscluster: failed to send ASKING to %s: %v", addr, err)
+			}
+		}
+		reply, err := conn.Do(cmd, args...)
+		if asking {
+			// the reply of the ASKING command is discarded by Do, only the error of the actual command matters
+			asking = false
+		}
+		redirect, ok := parseRedirect(err)
+		if !ok {
+			return reply, err
+		}
+		if i >= maxRedirects {
+			return nil, fmt.Errorf("rediscluster: too many redirections for %s: %v", cmd, err)
+		}
+		addr = redirect.addr
+		if redirect.ask {
+			asking = true
+			continue
+		}
+		// the slot moved permanently, most likely the cluster got resharded
+		c.slots[redirect.slot] = redirect.addr
+		c.refreshSlots()
+	}
+}
+
+// broadcastScript executes a SCRIPT command on all known nodes,
+// remembering the sources of loaded scripts, such that they can be loaded on nodes dialed later as well.
+func (c *Conn) broadcastScript(args []interface{}) (reply interface{}, err error) {
+	if len(args) == 2 && strings.EqualFold(fmt.Sprint(args[0]), "LOAD") {
+		c.scripts = append(c.scripts, keyString(args[1]))
+	}
+	for _, addr := range c.masters() {
+		var conn redis.Conn
+		conn, err = c.node(addr)
+		if err != nil {
+			return nil, err
+		}
+		reply, err = conn.Do("SCRIPT", args...)
+		if err != nil {
+			return nil, fmt.Errorf("rediscluster: failed to execute SCRIPT on %s: %v", addr, err)
+		}
+	}
+	return reply, nil
+}
+
+// refreshSlots fetches the slots served by each node, using the first known node which replies.
+func (c *Conn) refreshSlots() error {
+	var lastErr error
+	for _, addr := range append(c.masters(), c.addresses...) {
+		conn, err := c.node(addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		values, err := redis.Values(conn.Do("CLUSTER", "SLOTS"))
+		if err != nil {
+			lastErr = fmt.Errorf("rediscluster: failed to get slots from %s: %v", addr, err)
+			continue
+		}
+		err = c.loadSlots(addr, values)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return nil
+	}
+	return lastErr
+}
+
+// loadSlots loads the reply of a CLUSTER SLOTS command, received from the node at the given address.
+func (c *Conn) loadSlots(from string, values []interface{}) error {
+	var slots [SlotCount]string
+	for _, value := range values {
+		info, err := redis.Values(value, nil)
+		if err != nil || len(info) < 3 {
+			return fmt.Errorf("rediscluster: invalid slot range received from %s", from)
+		}
+		start, err := redis.Int(info[0], nil)
+		if err != nil {
+			return fmt.Errorf("rediscluster: invalid slot range start received from %s: %v", from, err)
+		}
+		end, err := redis.Int(info[1], nil)
+		if err != nil {
+			return fmt.Errorf("rediscluster: invalid slot range end received from %s: %v", from, err)
+		}
+		master, err := redis.Values(info[2], nil)
+		if err != nil || len(master) < 2 {
+			return fmt.Errorf("rediscluster: invalid slot range master received from %s", from)
+		}
+		host, _ := redis.String(master[0], nil)
+		port, err := redis.Int(master[1], nil)
+		if err != nil {
+			return fmt.Errorf("rediscluster: invalid slot range master port received from %s: %v", from, err)
+		}
+		if host == "" {
+			// an empty host means the node itself
+			host, _, _ = net.SplitHostPort(from)
+		}
+		addr := net.JoinHostPort(host, strconv.Itoa(port))
+		for slot := start; slot <= end && slot < SlotCount; slot++ {
+			slots[slot] = addr
+		}
+	}
+	c.slots = slots
+	return nil
+}
+
+// masters returns the (unique) addresses of all nodes known to serve slots.
+func (c *Conn) masters() []string {
+	var addresses []string
+	seen := make(map[string]struct{})
+	for _, addr := range c.slots {
+		if _, ok := seen[addr]; ok || addr == "" {
+			continue
+		}
+		seen[addr] = struct{}{}
+		addresses = append(addresses, addr)
+	}
+	return addresses
+}
+
+// node returns the connection to the node at the given address, dialing it if needed.
+func (c *Conn) node(addr string) (redis.Conn, error) {
+	if conn, ok := c.nodes[addr]; ok {
+		if conn.Err() == nil {
+			return conn, nil
+		}
+		conn.Close()
+		delete(c.nodes, addr)
+	}
+	conn, err := redis.Dial("tcp", addr, c.options...)
+	if err != nil {
+		return nil, fmt.Errorf("rediscluster: failed to dial node %s: %v", addr, err)
+	}
+	for _, src := range c.scripts {
+		_, err = conn.Do("SCRIPT", "LOAD", src)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("rediscluster: failed to load script on node %s: %v", addr, err)
+		}
+	}
+	c.nodes[addr] = conn
+	return conn, nil
+}
+
+// nodeFor returns the address of the node serving the key of the given command,
+// any known node if the command has no key (or if the node serving the slot isn't known).
+func (c *Conn) nodeFor(cmd string, args []interface{}) string {
+	if key, ok := commandKey(cmd, args); ok {
+		if addr := c.slots[Slot(key)]; addr != "" {
+			return addr
+		}
+	}
+	if masters := c.masters(); len(masters) > 0 {
+		return masters[0]
+	}
+	return c.addresses[0]
+}
+
+// commandKey returns the (first) key of the given command, if it has one.
+func commandKey(cmd string, args []interface{}) (string, bool) {
+	switch strings.ToUpper(cmd) {
+	case "EVAL", "EVALSHA":
+		if len(args) < 3 {
+			return "", false
+		}
+		if n, err := strconv.Atoi(keyString(args[1])); err != nil || n == 0 {
+			return "", false
+		}
+		return keyString(args[2]), true
+	case "ASKING", "CLUSTER", "INFO", "PING", "SCRIPT":
+		return "", false
+	default:
+		if len(args) == 0 {
+			return "", false
+		}
+		return keyString(args[0]), true
+	}
+}
+
+// keyString returns the string value of a command argument.
+func keyString(arg interface{}) string {
+	switch v := arg.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// redirect is a MOVED or ASK redirection, returned by a node not serving the slot of a key.
+type redirect struct {
+	ask  bool
+	slot int
+	addr string
+}
+
+// parseRedirect parses an error as a MOVED or ASK redirection, if possible.
+func parseRedirect(err error) (redirect, bool) {
+	rerr, ok := err.(redis.Error)
+	if !ok {
+		return redirect{}, false
+	}
+	parts := strings.Fields(string(rerr))
+	if len(parts) != 3 || (parts[0] != "MOVED" && parts[0] != "ASK") {
+		return redirect{}, false
+	}
+	slot, perr := strconv.Atoi(parts[1])
+	if perr != nil || slot < 0 || slot >= SlotCount {
+		return redirect{}, false
+	}
+	return redirect{ask: parts[0] == "ASK", slot: slot, addr: parts[2]}, true
+}
+
+// crc16 computes the CRC16 (XMODEM) checksum of the given key, as used by Redis Cluster.
+func crc16(key string) uint16 {
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc ^= uint16(key[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,330 @@
+// Package redispool provides a reconnecting connection, backed by a (redigo) redis.Pool,
+// which can be used anywhere a (redigo) redis.Conn is expected.
+//
+// Should the connection fail (e.g. because of a network hiccup, or because the Redis server restarted),
+// it is replaced by a new connection taken from the pool, retrying with an exponential backoff
+// (starting at InitialBackoff, capped at MaxBackoff) at most MaxRetries times,
+// loading the Lua scripts loaded so far on the new connection.
+//
+// As the reply of a command can get lost after the command has been applied, commands of which the reply
+// wasn't received yet when the connection failed are never resent, as they (or part of a MULTI/EXEC batch)
+// might have been applied already. Instead they are dropped, failing the call receiving their replies,
+// such that the caller can redo them once it knows which of them were applied.
+// Only a command rejected by a server which is still loading its dataset is retried over the new connection.
+package redispool
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	// MaxRetries is the maximum amount of times a failed connection is replaced,
+	// prior to the connection becoming unusable.
+	MaxRetries = 10
+	// InitialBackoff is the duration waited prior to replacing a failed connection for the first time,
+	// doubled for each subsequent retry.
+	InitialBackoff = 100 * time.Millisecond
+	// MaxBackoff is the maximum duration waited in between two retries.
+	MaxBackoff = 10 * time.Second
+)
+
+type (
+	// Conn is a reconnecting redis.Conn, see the package documentation for more information.
+	//
+	// Just like any other redis.Conn, a Conn is not safe for concurrent use.
+	Conn struct {
+		pool *redis.Pool
+		// closed together with the Conn, if the pool was created by Dial
+		ownsPool bool
+
+		// the connection currently taken from the pool
+		conn redis.Conn
+		// sources of the loaded Lua scripts, loaded on each new connection
+		scripts []string
+
+		// commands sent but not flushed yet
+		pending []command
+		// commands flushed, of which the reply hasn't been received yet
+		inflight []command
+
+		// set once the connection is closed, or reconnecting failed
+		err error
+	}
+
+	command struct {
+		name string
+		args []interface{}
+	}
+)
+
+var _ redis.Conn = (*Conn)(nil)
+
+// NewPool creates a pool of connections to the Redis server at the given (TCP) address,
+// dialed using the given options. Connections idle for more than a minute are tested prior to being reused.
+func NewPool(address string, options ...redis.DialOption) *redis.Pool {
+	return &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", address, options...)
+		},
+		TestOnBorrow: func(conn redis.Conn, t time.Time) error {
+			if time.Since(t) < time.Minute {
+				return nil
+			}
+			_, err := conn.Do("PING")
+			return err
+		},
+		MaxIdle:     2,
+		IdleTimeout: 5 * time.Minute,
+	}
+}
+
+// Dial creates a pool of connections to the Redis server at the given (TCP) address (see NewPool),
+// returning a Conn backed by it, which closes the pool once closed itself.
+func Dial(address string, options ...redis.DialOption) (*Conn, error) {
+	pool := NewPool(address, options...)
+	c, err := New(pool)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+	c.ownsPool = true
+	return c, nil
+}
+
+// New creates a Conn backed by the given pool, taking a connection from it.
+func New(pool *redis.Pool) (*Conn, error) {
+	c := &Conn{pool: pool}
+	err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Close implements redis.Conn.Close,
+// returning the current connection to the pool, and closing the pool if it was created by Dial.
+func (c *Conn) Close() error {
+	if c.err == nil {
+		c.err = errors.New("redispool: connection closed")
+	}
+	var err error
+	if c.conn != nil {
+		err = c.conn.Close()
+		c.conn = nil
+	}
+	if c.ownsPool {
+		c.ownsPool = false
+		if perr := c.pool.Close(); err == nil {
+			err = perr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("redispool: failed to close connection: %v", err)
+	}
+	return nil
+}
+
+// Err implements redis.Conn.Err
+func (c *Conn) Err() error {
+	return c.err
+}
+
+// Do implements redis.Conn.Do
+//
+// Just like a regular redis.Conn, all pending commands are flushed and their replies received first,
+// returning the first error reply (if any) of those commands. Calling Do with an empty command name
+// flushes the pending commands and returns all of their replies.
+func (c *Conn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	err := c.Flush()
+	if err != nil {
+		return nil, err
+	}
+	replies := make([]interface{}, 0, len(c.inflight))
+	var pendingErr error
+	for len(c.inflight) > 0 {
+		reply, err := c.Receive()
+		if err != nil {
+			if _, ok := err.(redis.Error); !ok {
+				return nil, err
+			}
+			if pendingErr == nil {
+				pendingErr = err
+			}
+			reply = err
+		}
+		replies = append(replies, reply)
+	}
+	if cmd == "" {
+		return replies, nil
+	}
+	reply, err := c.do(cmd, args)
+	if err == nil && pendingErr != nil {
+		err = pendingErr
+	}
+	return reply, err
+}
+
+// Send implements redis.Conn.Send
+func (c *Conn) Send(cmd string, args ...interface{}) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.pending = append(c.pending, command{name: cmd, args: args})
+	return nil
+}
+
+// Flush implements redis.Conn.Flush,
+// sending all pending commands over the current connection.
+func (c *Conn) Flush() error {
+	if c.err != nil {
+		return c.err
+	}
+	if len(c.pending) == 0 {
+		return nil
+	}
+	pending := c.pending
+	c.pending = nil
+	c.inflight = append(c.inflight, pending...)
+	err := c.send(pending)
+	if c.isConnectionFailure(err) {
+		return c.drop(err)
+	}
+	return err
+}
+
+// Receive implements redis.Conn.Receive,
+// receiving the reply of the oldest flushed command. In case the current connection failed,
+// all unreplied commands are dropped (see drop), and the connection is replaced by a new one.
+func (c *Conn) Receive() (interface{}, error) {
+	if len(c.inflight) == 0 {
+		return nil, errors.New("redispool: no reply pending")
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	reply, err := c.conn.Receive()
+	if c.isConnectionFailure(err) {
+		return nil, c.drop(err)
+	}
+	c.inflight = c.inflight[1:]
+	return reply, err
+}
+
+// do executes a single command over the current connection, replacing the connection should it fail.
+// The command is only retried over the new connection if it was rejected, and thus not applied.
+func (c *Conn) do(cmd string, args []interface{}) (interface{}, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	for {
+		reply, err := c.conn.Do(cmd, args...)
+		if !c.isConnectionFailure(err) {
+			if err == nil && strings.EqualFold(cmd, "SCRIPT") &&
+				len(args) == 2 && strings.EqualFold(fmt.Sprint(args[0]), "LOAD") {
+				c.scripts = append(c.scripts, argString(args[1]))
+			}
+			return reply, err
+		}
+		_, rejected := err.(redis.Error)
+		rerr := c.reconnect(err)
+		if rerr != nil {
+			return nil, rerr
+		}
+		if !rejected {
+			return nil, fmt.Errorf("redispool: connection failed (%v), %s command is not resent", err, cmd)
+		}
+	}
+}
+
+// drop drops all inflight commands, as they might have been applied (in part) already,
+// and replaces the failed connection. The given error is the cause of the failure.
+func (c *Conn) drop(cause error) error {
+	dropped := len(c.inflight)
+	c.inflight = nil
+	err := c.reconnect(cause)
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("redispool: connection failed (%v), dropped %d commands of which the reply wasn't received", cause, dropped)
+}
+
+// send sends the given commands over the current connection, flushing them.
+func (c *Conn) send(cmds []command) error {
+	for _, cmd := range cmds {
+		err := c.conn.Send(cmd.name, cmd.args...)
+		if err != nil {
+			return err
+		}
+	}
+	return c.conn.Flush()
+}
+
+// isConnectionFailure returns true if the given error indicates the current connection failed,
+// or the Redis server is still loading its dataset (e.g. because it just restarted).
+func (c *Conn) isConnectionFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	if rerr, ok := err.(redis.Error); ok {
+		return strings.HasPrefix(string(rerr), "LOADING ")
+	}
+	return c.conn.Err() != nil
+}
+
+// reconnect replaces the current connection by a new one taken from the pool.
+// The given error is the cause of the reconnect.
+// Should no connection succeed within MaxRetries retries, the connection becomes unusable.
+func (c *Conn) reconnect(cause error) error {
+	c.conn.Close()
+	c.conn = nil
+	var err error
+	backoff := InitialBackoff
+	for retry := 0; retry < MaxRetries; retry++ {
+		time.Sleep(backoff)
+		err = c.connect()
+		if err == nil {
+			return nil
+		}
+		backoff *= 2
+		if backoff > MaxBackoff {
+			backoff = MaxBackoff
+		}
+	}
+	c.err = fmt.Errorf("redispool: failed to reconnect within %d retries (%v): %v", MaxRetries, cause, err)
+	return c.err
+}
+
+// connect takes a connection from the pool, and loads all known scripts on it.
+func (c *Conn) connect() error {
+	conn := c.pool.Get()
+	if err := conn.Err(); err != nil {
+		conn.Close()
+		return fmt.Errorf("redispool: failed to get connection: %v", err)
+	}
+	for _, src := range c.scripts {
+		_, err := conn.Do("SCRIPT", "LOAD", src)
+		if err != nil {
+			conn.Close()
+			return fmt.Errorf("redispool: failed to load script: %v", err)
+		}
+	}
+	c.conn = conn
+	return nil
+}
+
+// argString returns the string value of a command argument.
+func argString(arg interface{}) string {
+	switch v := arg.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,345 @@
+// Package redissentinel provides a Redis Sentinel aware connection,
+// which can be used anywhere a (redigo) redis.Conn is expected.
+//
+// The master of the monitored group is discovered by querying the sentinels,
+// and is rediscovered whenever the connection to it fails (or turns out to be a replica),
+// such that the connection follows a failover instead of failing.
+// The Lua scripts loaded so far are loaded on the new master.
+//
+// Commands of which the reply wasn't received yet when the connection failed are never resent to the new master,
+// as they (or part of a MULTI/EXEC batch) might have been applied by the old master already (and replicated)
+// prior to the failover. Instead they are dropped, failing the call receiving their replies,
+// such that the caller can redo them once it knows which of them were applied.
+// Only a command rejected by a node which is no longer the master (or still loading its dataset)
+// is retried on the new master. As Redis replication is asynchronous, a failover can lose writes acknowledged by the old master.
+package redissentinel
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	// FailoverTimeout is the maximum duration a connection waits for a (new) master to become available,
+	// after the connection to the previous master failed.
+	FailoverTimeout = time.Minute
+	// failoverRetryInterval is the interval at which the sentinels are queried during a failover
+	failoverRetryInterval = time.Second
+)
+
+type (
+	// Conn is a redis.Conn for the master of a group monitored by Redis Sentinel,
+	// see the package documentation for more information.
+	//
+	// Just like any other redis.Conn, a Conn is not safe for concurrent use.
+	Conn struct {
+		masterName string
+		sentinels  []string
+		options    []redis.DialOption
+
+		// address of the current master, and the connection to it
+		master string
+		conn   redis.Conn
+		// sources of the loaded Lua scripts, loaded on each master as it gets dialed
+		scripts []string
+
+		// commands sent but not flushed yet
+		pending []command
+		// commands flushed, of which the reply hasn't been received yet
+		inflight []command
+
+		// set once the connection is closed, or a failover failed
+		err error
+	}
+
+	command struct {
+		name string
+		args []interface{}
+	}
+)
+
+var _ redis.Conn = (*Conn)(nil)
+
+// Dial connects to the master of the group with the given name,
+// using the given sentinel addresses to discover the address of that master.
+// The options are used to dial the master, not the sentinels.
+func Dial(masterName string, sentinels []string, options ...redis.DialOption) (*Conn, error) {
+	if len(sentinels) == 0 {
+		return nil, errors.New("redissentinel: no sentinel addresses given")
+	}
+	c := &Conn{
+		masterName: masterName,
+		sentinels:  sentinels,
+		options:    options,
+	}
+	err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Master returns the address of the current master.
+func (c *Conn) Master() string {
+	return c.master
+}
+
+// Close implements redis.Conn.Close,
+// closing the connection to the current master.
+func (c *Conn) Close() error {
+	if c.err == nil {
+		c.err = errors.New("redissentinel: connection closed")
+	}
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	if err != nil {
+		return fmt.Errorf("redissentinel: failed to close connection to %s: %v", c.master, err)
+	}
+	return nil
+}
+
+// Err implements redis.Conn.Err
+func (c *Conn) Err() error {
+	return c.err
+}
+
+// Do implements redis.Conn.Do
+//
+// Just like a regular redis.Conn, all pending commands are flushed and their replies received first,
+// returning the first error reply (if any) of those commands. Calling Do with an empty command name
+// flushes the pending commands and returns all of their replies.
+func (c *Conn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	err := c.Flush()
+	if err != nil {
+		return nil, err
+	}
+	replies := make([]interface{}, 0, len(c.inflight))
+	var pendingErr error
+	for len(c.inflight) > 0 {
+		reply, err := c.Receive()
+		if err != nil {
+			if _, ok := err.(redis.Error); !ok {
+				return nil, err
+			}
+			if pendingErr == nil {
+				pendingErr = err
+			}
+			reply = err
+		}
+		replies = append(replies, reply)
+	}
+	if cmd == "" {
+		return replies, nil
+	}
+	reply, err := c.do(cmd, args)
+	if err == nil && pendingErr != nil {
+		err = pendingErr
+	}
+	return reply, err
+}
+
+// Send implements redis.Conn.Send
+func (c *Conn) Send(cmd string, args ...interface{}) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.pending = append(c.pending, command{name: cmd, args: args})
+	return nil
+}
+
+// Flush implements redis.Conn.Flush,
+// sending all pending commands to the current master.
+func (c *Conn) Flush() error {
+	if c.err != nil {
+		return c.err
+	}
+	if len(c.pending) == 0 {
+		return nil
+	}
+	pending := c.pending
+	c.pending = nil
+	c.inflight = append(c.inflight, pending...)
+	err := c.send(pending)
+	if c.isFailover(err) {
+		return c.drop(err)
+	}
+	return err
+}
+
+// Receive implements redis.Conn.Receive,
+// receiving the reply of the oldest flushed command. In case the connection to the current master failed,
+// all unreplied commands are dropped (see drop), and the connection fails over to the new master.
+func (c *Conn) Receive() (interface{}, error) {
+	if len(c.inflight) == 0 {
+		return nil, errors.New("redissentinel: no reply pending")
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	reply, err := c.conn.Receive()
+	if c.isFailover(err) {
+		return nil, c.drop(err)
+	}
+	c.inflight = c.inflight[1:]
+	return reply, err
+}
+
+// do executes a single command on the current master, failing over to the new master should the master fail.
+// The command is only retried on the new master if it was rejected by the old master, and thus not applied.
+func (c *Conn) do(cmd string, args []interface{}) (interface{}, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	for {
+		reply, err := c.conn.Do(cmd, args...)
+		if !c.isFailover(err) {
+			if err == nil && strings.EqualFold(cmd, "SCRIPT") &&
+				len(args) == 2 && strings.EqualFold(fmt.Sprint(args[0]), "LOAD") {
+				c.scripts = append(c.scripts, argString(args[1]))
+			}
+			return reply, err
+		}
+		_, rejected := err.(redis.Error)
+		ferr := c.failover(err)
+		if ferr != nil {
+			return nil, ferr
+		}
+		if !rejected {
+			return nil, fmt.Errorf("redissentinel: master failed (%v), %s command is not resent", err, cmd)
+		}
+	}
+}
+
+// drop drops all inflight commands, as they might have been applied (in part) already,
+// and fails over to the new master. The given error is the cause of the failover.
+func (c *Conn) drop(cause error) error {
+	dropped := len(c.inflight)
+	c.inflight = nil
+	err := c.failover(cause)
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("redissentinel: master failed (%v), dropped %d commands of which the reply wasn't received", cause, dropped)
+}
+
+// send sends the given commands to the current master, flushing them.
+func (c *Conn) send(cmds []command) error {
+	for _, cmd := range cmds {
+		err := c.conn.Send(cmd.name, cmd.args...)
+		if err != nil {
+			return err
+		}
+	}
+	return c.conn.Flush()
+}
+
+// isFailover returns true if the given error indicates the current master is no longer available as master,
+// either because the connection to it failed, or because it got demoted to a replica.
+func (c *Conn) isFailover(err error) bool {
+	if err == nil {
+		return false
+	}
+	if rerr, ok := err.(redis.Error); ok {
+		return strings.HasPrefix(string(rerr), "READONLY ") || strings.HasPrefix(string(rerr), "LOADING ")
+	}
+	return c.conn.Err() != nil
+}
+
+// failover reconnects to the (new) master, discovered using the sentinels.
+// The given error is the cause of the failover.
+// Should no master become available within the FailoverTimeout, the connection becomes unusable.
+func (c *Conn) failover(cause error) error {
+	c.conn.Close()
+	c.conn = nil
+	deadline := time.Now().Add(FailoverTimeout)
+	for {
+		err := c.connect()
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			c.err = fmt.Errorf(
+				"redissentinel: failed to fail over from master %s (%v): %v", c.master, cause, err)
+			return c.err
+		}
+		time.Sleep(failoverRetryInterval)
+	}
+}
+
+// connect discovers the current master using the sentinels,
+// dials it and loads all known scripts on it.
+func (c *Conn) connect() error {
+	addr, err := c.discoverMaster()
+	if err != nil {
+		return err
+	}
+	conn, err := redis.Dial("tcp", addr, c.options...)
+	if err != nil {
+		return fmt.Errorf("redissentinel: failed to dial master %s: %v", addr, err)
+	}
+	// the sentinels might not have noticed the failover yet, so verify the role of the dialed node
+	role, err := redis.Values(conn.Do("ROLE"))
+	if err != nil || len(role) == 0 {
+		conn.Close()
+		return fmt.Errorf("redissentinel: failed to get role of master %s: %v", addr, err)
+	}
+	if name, _ := redis.String(role[0], nil); name != "master" {
+		conn.Close()
+		return fmt.Errorf("redissentinel: %s is reported as master by the sentinels, but has role %s", addr, name)
+	}
+	for _, src := range c.scripts {
+		_, err = conn.Do("SCRIPT", "LOAD", src)
+		if err != nil {
+			conn.Close()
+			return fmt.Errorf("redissentinel: failed to load script on master %s: %v", addr, err)
+		}
+	}
+	c.master, c.conn = addr, conn
+	return nil
+}
+
+// discoverMaster returns the address of the master, as reported by the first sentinel which replies.
+func (c *Conn) discoverMaster() (string, error) {
+	var lastErr error
+	for _, sentinel := range c.sentinels {
+		conn, err := redis.Dial("tcp", sentinel,
+			redis.DialConnectTimeout(failoverRetryInterval), redis.DialReadTimeout(failoverRetryInterval))
+		if err != nil {
+			lastErr = fmt.Errorf("redissentinel: failed to dial sentinel %s: %v", sentinel, err)
+			continue
+		}
+		info, err := redis.Strings(conn.Do("SENTINEL", "get-master-addr-by-name", c.masterName))
+		conn.Close()
+		if err == redis.ErrNil {
+			lastErr = fmt.Errorf("redissentinel: sentinel %s does not monitor master %q", sentinel, c.masterName)
+			continue
+		}
+		if err != nil || len(info) != 2 {
+			lastErr = fmt.Errorf("redissentinel: failed to get address of master %q from sentinel %s: %v",
+				c.masterName, sentinel, err)
+			continue
+		}
+		return net.JoinHostPort(info[0], info[1]), nil
+	}
+	return "", lastErr
+}
+
+// argString returns the string value of a command argument.
+func argString(arg interface{}) string {
+	switch v := arg.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
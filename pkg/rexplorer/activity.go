@@ -0,0 +1,62 @@
+package rexplorer
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rivine/rivine/types"
+)
+
+type (
+	// AddressActivity records the block an address first appeared in, and the last block it was involved in,
+	// enabling dormancy analysis. An address is involved in a block when it receives a miner payout,
+	// or when it sends or receives coins as part of a transaction.
+	AddressActivity struct {
+		FirstSeen  ActivityPoint `json:"firstSeen"`
+		LastActive ActivityPoint `json:"lastActive"`
+	}
+
+	// ActivityPoint identifies a block by its height and timestamp.
+	ActivityPoint struct {
+		BlockHeight types.BlockHeight `json:"height"`
+		Timestamp   types.Timestamp   `json:"timestamp"`
+	}
+)
+
+// sortedAddresses returns the given set of addresses as a sorted slice.
+func sortedAddresses(set map[types.UnlockHash]struct{}) []types.UnlockHash {
+	addresses := make([]types.UnlockHash, 0, len(set))
+	for uh := range set {
+		addresses = append(addresses, uh)
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		return addresses[i].Cmp(addresses[j]) < 0
+	})
+	return addresses
+}
+
+// applyAddressActivity records the given addresses as active at the current block height and time,
+// in case the database supports it.
+func (explorer *Explorer) applyAddressActivity(active map[types.UnlockHash]struct{}) {
+	adb, ok := explorer.db.(AddressActivityDatabase)
+	if !ok || len(active) == 0 {
+		return
+	}
+	err := adb.ApplyAddressActivity(explorer.stats.BlockHeight, explorer.stats.Timestamp, sortedAddresses(active))
+	if err != nil {
+		panic(fmt.Sprintf("failed to apply address activity of block %d: %v", explorer.stats.BlockHeight, err))
+	}
+}
+
+// revertAddressActivity restores the activity of the addresses active at the current block height,
+// in case the database supports it.
+func (explorer *Explorer) revertAddressActivity() {
+	adb, ok := explorer.db.(AddressActivityDatabase)
+	if !ok {
+		return
+	}
+	err := adb.RevertAddressActivity(explorer.stats.BlockHeight)
+	if err != nil {
+		panic(fmt.Sprintf("failed to revert address activity of block %d: %v", explorer.stats.BlockHeight, err))
+	}
+}
@@ -0,0 +1,95 @@
+package rexplorer
+
+import (
+	"fmt"
+
+	"github.com/rivine/rivine/types"
+)
+
+// AddressHistoryRecord records a single transaction in which an address sent and/or received coins,
+// as well as the addresses which sent the coins, being the owners of the coin outputs spent by the transaction,
+// such that the history of an address answers who sent coins to it (and where its own coins went to).
+type AddressHistoryRecord struct {
+	BlockHeight   types.BlockHeight   `json:"height"`
+	TransactionID types.TransactionID `json:"txID"`
+	Senders       []types.UnlockHash  `json:"senders,omitempty"`
+	Sent          types.Currency      `json:"sent"`
+	Received      types.Currency      `json:"received"`
+}
+
+// newAddressHistoryRecords records the given transaction, applied at the given height,
+// in the history of each address sending or receiving coins. The coin inputs are given as recorded by the transaction record,
+// as their owner and value aren't part of the transaction.
+func newAddressHistoryRecords(tx types.Transaction, height types.BlockHeight, inputs []TransactionRecordCoinInput) map[types.UnlockHash]*AddressHistoryRecord {
+	senders := make(map[types.UnlockHash]struct{}, len(inputs))
+	for _, input := range inputs {
+		senders[input.UnlockHash] = struct{}{}
+	}
+	sortedSenders := sortedAddresses(senders)
+	records := make(map[types.UnlockHash]*AddressHistoryRecord)
+	record := func(uh types.UnlockHash) *AddressHistoryRecord {
+		r, ok := records[uh]
+		if !ok {
+			r = &AddressHistoryRecord{
+				BlockHeight:   height,
+				TransactionID: tx.ID(),
+				Senders:       sortedSenders,
+				Sent:          types.ZeroCurrency,
+				Received:      types.ZeroCurrency,
+			}
+			records[uh] = r
+		}
+		return r
+	}
+	for _, input := range inputs {
+		r := record(input.UnlockHash)
+		r.Sent = r.Sent.Add(input.Value)
+	}
+	for _, co := range tx.CoinOutputs {
+		r := record(co.Condition.UnlockHash())
+		r.Received = r.Received.Add(co.Value)
+	}
+	return records
+}
+
+// applyAddressHistory records the given transaction, applied at the current block height,
+// in the history of the addresses sending or receiving coins, in case the database supports it.
+func (explorer *Explorer) applyAddressHistory(tx types.Transaction, inputs []TransactionRecordCoinInput) {
+	ahdb, ok := explorer.db.(AddressHistoryDatabase)
+	if !ok {
+		return
+	}
+	records := newAddressHistoryRecords(tx, explorer.stats.BlockHeight, inputs)
+	addresses := make(map[types.UnlockHash]struct{}, len(records))
+	for uh := range records {
+		addresses[uh] = struct{}{}
+	}
+	for _, uh := range sortedAddresses(addresses) {
+		err := ahdb.ApplyAddressHistory(uh, *records[uh])
+		if err != nil {
+			panic(fmt.Sprintf("failed to apply tx %s to the history of %s: %v", tx.ID().String(), uh.String(), err))
+		}
+	}
+}
+
+// revertAddressHistory drops the given transaction, reverted at the current block height,
+// from the history of the given senders and the addresses which received coins, in case the database supports it.
+func (explorer *Explorer) revertAddressHistory(tx types.Transaction, senders map[types.UnlockHash]struct{}) {
+	ahdb, ok := explorer.db.(AddressHistoryDatabase)
+	if !ok {
+		return
+	}
+	addresses := make(map[types.UnlockHash]struct{}, len(senders)+len(tx.CoinOutputs))
+	for uh := range senders {
+		addresses[uh] = struct{}{}
+	}
+	for _, co := range tx.CoinOutputs {
+		addresses[co.Condition.UnlockHash()] = struct{}{}
+	}
+	for _, uh := range sortedAddresses(addresses) {
+		err := ahdb.RevertAddressHistory(uh, explorer.stats.BlockHeight)
+		if err != nil {
+			panic(fmt.Sprintf("failed to revert tx %s from the history of %s: %v", tx.ID().String(), uh.String(), err))
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package rexplorer
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rivine/rivine/types"
+)
+
+// AddressPrefixLength is the amount of (hex) characters of an address which make up its prefix,
+// the prefix being the bucket the Redis drivers store the wallet of that address in (under the a:<prefix> key).
+const AddressPrefixLength = 6
+
+// AddressPrefixStats rolls up the wallets of all addresses sharing the same prefix,
+// such that the stored data can be verified (and capacity can be planned) bucket by bucket.
+type AddressPrefixStats struct {
+	// Wallets is the amount of wallets stored for the prefix,
+	// not stored as part of the rolled up stats, as it is the size of the bucket of the prefix
+	Wallets uint64 `json:"wallets,omitempty"`
+	// Unlocked and Locked are the sums of the (un)locked balances of those wallets
+	Unlocked types.Currency `json:"unlocked"`
+	Locked   types.Currency `json:"locked"`
+}
+
+// AddressPrefix returns the prefix of the given address.
+func AddressPrefix(uh types.UnlockHash) string {
+	return uh.String()[:AddressPrefixLength]
+}
+
+// Equals returns true if both stats are identical.
+func (stats AddressPrefixStats) Equals(other AddressPrefixStats) bool {
+	return stats.Wallets == other.Wallets &&
+		stats.Unlocked.Cmp(other.Unlocked) == 0 && stats.Locked.Cmp(other.Locked) == 0
+}
+
+// AddWallet adds the balance of a single wallet to the stats.
+func (stats *AddressPrefixStats) AddWallet(balance WalletBalance) {
+	stats.Wallets++
+	stats.Unlocked = stats.Unlocked.Add(balance.Unlocked)
+	stats.Locked = stats.Locked.Add(balance.Locked.Total)
+}
+
+// VerifyAddressPrefixes recomputes the stats of the given prefixes from the wallets stored for them,
+// returning a problem for each prefix of which the stored (rolled up) stats don't match the recomputed stats.
+func VerifyAddressPrefixes(db AddressPrefixDatabase, prefixes []string) ([]string, error) {
+	stored, err := db.GetAddressPrefixStats()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get address prefix stats: %v", err)
+	}
+	sort.Strings(prefixes)
+	var problems []string
+	for _, prefix := range prefixes {
+		computed, err := db.ComputeAddressPrefixStats(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute stats of address prefix %s: %v", prefix, err)
+		}
+		if stats := stored[prefix]; !stats.Equals(computed) {
+			problems = append(problems, fmt.Sprintf(
+				"stats of address prefix %s (%d wallets, %s unlocked, %s locked) "+
+					"don't match its wallets (%d wallets, %s unlocked, %s locked)",
+				prefix, stats.Wallets, stats.Unlocked.String(), stats.Locked.String(),
+				computed.Wallets, computed.Unlocked.String(), computed.Locked.String()))
+		}
+	}
+	return problems, nil
+}
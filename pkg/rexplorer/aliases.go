@@ -0,0 +1,144 @@
+package rexplorer
+
+import (
+	"fmt"
+
+	"github.com/rivine/rivine/types"
+)
+
+type (
+	// AddressAlias records that an (old) address is an alias of another (new) address,
+	// e.g. because the wallet of the old address was migrated to the new address.
+	// Aliases are recorded by the operator, as they cannot be derived from the chain itself.
+	AddressAlias struct {
+		Alias   types.UnlockHash `json:"alias"`
+		Address types.UnlockHash `json:"address"`
+	}
+
+	// MergedWallet is the merged view of the wallets of an address and all its aliases.
+	MergedWallet struct {
+		// Address is the address all other addresses are (directly or indirectly) an alias of
+		Address types.UnlockHash `json:"address"`
+		// Aliases are all addresses which are (directly or indirectly) an alias of Address
+		Aliases []types.UnlockHash `json:"aliases,omitempty"`
+		// Wallet is the merged wallet, see MergeWallets for more information
+		Wallet Wallet `json:"wallet"`
+	}
+)
+
+// AddAddressAlias records the given alias of the given address, returning the updated aliases.
+// An address can only be an alias of a single address, and aliases cannot form a cycle.
+func AddAddressAlias(aliases []AddressAlias, alias, address types.UnlockHash) ([]AddressAlias, error) {
+	if alias == address {
+		return nil, fmt.Errorf("address %s cannot be an alias of itself", alias.String())
+	}
+	for _, record := range aliases {
+		if record.Alias == alias {
+			return nil, fmt.Errorf("address %s is already an alias of %s", alias.String(), record.Address.String())
+		}
+	}
+	if ResolveAddressAlias(aliases, address) == alias {
+		return nil, fmt.Errorf("address %s is already (indirectly) an alias of %s", address.String(), alias.String())
+	}
+	return append(aliases, AddressAlias{Alias: alias, Address: address}), nil
+}
+
+// RemoveAddressAlias removes the given alias, returning the updated aliases.
+func RemoveAddressAlias(aliases []AddressAlias, alias types.UnlockHash) ([]AddressAlias, error) {
+	for i, record := range aliases {
+		if record.Alias == alias {
+			return append(aliases[:i:i], aliases[i+1:]...), nil
+		}
+	}
+	return nil, fmt.Errorf("address %s is not an alias", alias.String())
+}
+
+// ResolveAddressAlias returns the address the given address is (directly or indirectly) an alias of,
+// the given address itself if it isn't an alias.
+func ResolveAddressAlias(aliases []AddressAlias, address types.UnlockHash) types.UnlockHash {
+	targets := make(map[types.UnlockHash]types.UnlockHash, len(aliases))
+	for _, record := range aliases {
+		targets[record.Alias] = record.Address
+	}
+	// guard against cycles, even though AddAddressAlias refuses to create them
+	for i := 0; i <= len(aliases); i++ {
+		target, ok := targets[address]
+		if !ok {
+			break
+		}
+		address = target
+	}
+	return address
+}
+
+// GetMergedWallet returns the merged view of the wallets of the given address and all its aliases,
+// resolving the given address first, such that the same view is returned for any of those addresses.
+// Addresses without a stored wallet are skipped, ErrNotFound is returned only if none of them has one.
+func GetMergedWallet(db Database, aliases []AddressAlias, address types.UnlockHash) (MergedWallet, error) {
+	merged := MergedWallet{Address: ResolveAddressAlias(aliases, address)}
+	for _, record := range aliases {
+		if record.Alias != merged.Address && ResolveAddressAlias(aliases, record.Alias) == merged.Address {
+			merged.Aliases = append(merged.Aliases, record.Alias)
+		}
+	}
+	var found bool
+	for _, uh := range append([]types.UnlockHash{merged.Address}, merged.Aliases...) {
+		wallet, err := db.GetWallet(uh)
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return MergedWallet{}, fmt.Errorf("failed to get wallet %s: %v", uh.String(), err)
+		}
+		if !found {
+			merged.Wallet, found = wallet, true
+			continue
+		}
+		merged.Wallet, err = MergeWallets(merged.Wallet, wallet)
+		if err != nil {
+			return MergedWallet{}, fmt.Errorf("failed to merge wallet %s: %v", uh.String(), err)
+		}
+	}
+	if !found {
+		return MergedWallet{}, ErrNotFound
+	}
+	return merged, nil
+}
+
+// MergeWallets merges the (block stake) balance and multisign addresses of the other wallet into the given wallet.
+// The multisign data of the given wallet is kept as is, as the owners of different multisign wallets
+// cannot be merged in a meaningful way.
+func MergeWallets(wallet, other Wallet) (Wallet, error) {
+	merged := Wallet{
+		Balance: WalletBalance{
+			Unlocked: wallet.Balance.Unlocked.Add(other.Balance.Unlocked),
+		},
+		MultiSignData: wallet.MultiSignData,
+		BlockStakes: WalletBlockStakeBalance{
+			Unlocked: wallet.BlockStakes.Unlocked.Add(other.BlockStakes.Unlocked),
+			Locked:   wallet.BlockStakes.Locked.Add(other.BlockStakes.Locked),
+		},
+	}
+	var horizonsAsOf types.Timestamp
+	for _, balance := range []WalletLockedBalance{wallet.Balance.Locked, other.Balance.Locked} {
+		for id, co := range balance.Outputs {
+			err := merged.Balance.Locked.AddLockedCoinOutput(id, co)
+			if err != nil {
+				return Wallet{}, err
+			}
+		}
+		if balance.Horizons != nil && balance.Horizons.AsOf > horizonsAsOf {
+			horizonsAsOf = balance.Horizons.AsOf
+		}
+	}
+	// the unlock horizons are recomputed as of the most recent horizons of both wallets, if any
+	if horizonsAsOf != 0 {
+		merged.Balance.Locked.updateHorizons(horizonsAsOf)
+	}
+	focus := WalletFocusMultiSignAddresses{}
+	for _, address := range append(wallet.MultiSignAddresses, other.MultiSignAddresses...) {
+		focus.AddUniqueMultisignAddress(address)
+	}
+	merged.MultiSignAddresses = focus.MultiSignAddresses
+	return merged, nil
+}
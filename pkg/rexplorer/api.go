@@ -0,0 +1,650 @@
+package rexplorer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rivine/rivine/types"
+)
+
+// API serves the data stored in a Database as JSON over HTTP, using the following (read-only) endpoints:
+//
+//	GET /stats              the network stats, see NetworkStats
+//	GET /wallets/<address>  the wallet of an address, see Wallet
+//	GET /wallets/<address>/outputs?cursor=&limit=
+//	                        a page of the locked outputs of a wallet, see WalletLockedOutputPage
+//	GET /outputs/<id>       the info of a coin output, see CoinOutputInfo
+//	GET /ws                 a WebSocket streaming the published events, see streamWebSocket
+//	GET /events             a Server-Sent Events stream of the published events, see streamServerSentEvents
+//	GET/POST /graphql       a GraphQL query of the stats, wallets, outputs and transactions, see executeGraphQLQuery
+//	POST /rosetta/...       the Rosetta Data API, see RosettaAPI (only served by the API of an Explorer)
+//	GET /api/v2/...         the Blockbook-compatible API, see BlockbookAPI (only served by the API of an Explorer)
+//	GET/POST/DELETE /admin/webhooks
+//	                        list, register and remove the webhooks of watched addresses, see adminWebhooks
+//
+// such that consumers don't need direct access to the database, nor knowledge of the way the data is stored.
+// Errors are returned as a JSON object with a single "error" field, using status 404 for unknown wallets and outputs,
+// and status 400 for malformed addresses, IDs and query parameters.
+//
+// Once API keys are required (see RequireAPIKeys), every request has to pass a key granting the scope of the endpoint:
+// APIScopeStats for /stats, APIScopeAdmin for the /admin endpoints, and APIScopeWallets for all other endpoints.
+// Requests without a known key are responded with status 401, and requests with a key lacking the scope with status 403.
+// Requests exceeding the rate of their client (see LimitRate) are responded with status 429.
+// Cross-origin requests are only allowed by browsers once a CORS policy is defined, see AllowCORS.
+type API struct {
+	db      Database
+	mut     sync.Locker
+	feed    *EventFeed
+	keys    *APIKeys
+	limiter *RateLimiter
+	cors    *CORSPolicy
+	mux     *http.ServeMux
+}
+
+// adminPathPrefix is the path under which the administrative endpoints are served.
+const adminPathPrefix = "/admin"
+
+// graphqlMaxRequestSize is the maximum size of the body of a GraphQL request.
+const graphqlMaxRequestSize = 1 << 20
+
+// blockbookPathPrefix is the path under which the Blockbook-compatible API is served.
+const blockbookPathPrefix = "/api"
+
+// rosettaPathPrefix is the path under which the Rosetta Data API is served.
+const rosettaPathPrefix = "/rosetta"
+
+// sseKeepAliveInterval is the interval at which a comment is sent to the clients of a Server-Sent Events stream.
+const sseKeepAliveInterval = 30 * time.Second
+
+// websocketRequest is a (JSON-encoded) message sent by a WebSocket client,
+// (un)subscribing to the balance of the given addresses.
+type websocketRequest struct {
+	Subscribe   []types.UnlockHash `json:"subscribe"`
+	Unsubscribe []types.UnlockHash `json:"unsubscribe"`
+}
+
+// apiError is the JSON body of all error responses of the API.
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// NewAPI creates an API serving the data stored in the given database, as well as the events published by the given feed.
+// The given locker (if not nil) is held while reading the database,
+// such that the API only observes the data stored by entire consensus changes.
+// Events can't be streamed if no feed is given.
+func NewAPI(db Database, mut sync.Locker, feed *EventFeed) *API {
+	api := &API{
+		db:   db,
+		mut:  mut,
+		feed: feed,
+		mux:  http.NewServeMux(),
+	}
+	api.mux.HandleFunc("/stats", api.getStats)
+	api.mux.HandleFunc("/wallets/", api.getWallet)
+	api.mux.HandleFunc("/outputs/", api.getCoinOutput)
+	api.mux.HandleFunc("/ws", api.streamWebSocket)
+	api.mux.HandleFunc("/events", api.streamServerSentEvents)
+	api.mux.HandleFunc("/graphql", api.executeGraphQLQuery)
+	api.mux.HandleFunc(adminPathPrefix+"/webhooks", api.adminWebhooks)
+	api.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		api.writeError(w, http.StatusNotFound, fmt.Errorf("unknown endpoint %s", r.URL.Path))
+	})
+	return api
+}
+
+// Events returns the feed of the events published by this explorer, closed when the explorer is closed.
+func (explorer *Explorer) Events() *EventFeed {
+	return explorer.feed
+}
+
+// API creates an API serving the data stored and the events published by this explorer,
+// reading the database in between the consensus changes it processes.
+// The Rosetta Data API and the Blockbook-compatible API of the explorer are served as well,
+// under the /rosetta and /api paths respectively.
+func (explorer *Explorer) API() *API {
+	api := NewAPI(explorer.db, &explorer.mut, explorer.feed)
+	api.mux.Handle(rosettaPathPrefix+"/", http.StripPrefix(rosettaPathPrefix, explorer.RosettaAPI()))
+	api.mux.Handle(blockbookPathPrefix+"/", http.StripPrefix(blockbookPathPrefix, explorer.BlockbookAPI()))
+	return api
+}
+
+// RequireAPIKeys requires every request to pass one of the given keys, granting the scope of the requested endpoint.
+// Without keys, all endpoints except for the /admin endpoints are served to everyone.
+func (api *API) RequireAPIKeys(keys *APIKeys) {
+	api.keys = keys
+}
+
+// LimitRate limits the rate of the requests of every client using the given limiter, which can be shared with a GRPCService.
+func (api *API) LimitRate(limiter *RateLimiter) {
+	api.limiter = limiter
+}
+
+// AllowCORS allows the cross-origin requests defined by the given policy, nil disallowing all of them.
+func (api *API) AllowCORS(policy *CORSPolicy) {
+	api.cors = policy
+}
+
+// ServeHTTP implements http.Handler.ServeHTTP
+func (api *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if api.cors.handle(w, r) {
+		// preflight requests are responded to without an API key, as browsers never pass it
+		return
+	}
+	if !api.limiter.allowRequest(r, api.keys) {
+		w.Header().Set("Retry-After", "1")
+		api.writeError(w, http.StatusTooManyRequests, errors.New("rate limit exceeded"))
+		return
+	}
+	if status, err := api.keys.authorizeRequest(r, apiPathScope(r.URL.Path)); err != nil {
+		if status == http.StatusUnauthorized {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="rexplorer"`)
+		}
+		api.writeError(w, status, err)
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, rosettaPathPrefix+"/") || strings.HasPrefix(r.URL.Path, adminPathPrefix+"/") {
+		// the Rosetta Data API only accepts POST requests,
+		// while the administrative endpoints check the methods they accept themselves
+		api.mux.ServeHTTP(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead && !(r.Method == http.MethodPost && r.URL.Path == "/graphql") {
+		w.Header().Set("Allow", "GET, HEAD")
+		api.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	api.mux.ServeHTTP(w, r)
+}
+
+// apiPathScope returns the scope required to access the endpoint of the given path.
+func apiPathScope(path string) APIScope {
+	switch {
+	case path == "/stats":
+		return APIScopeStats
+	case strings.HasPrefix(path, adminPathPrefix+"/"):
+		return APIScopeAdmin
+	default:
+		return APIScopeWallets
+	}
+}
+
+func (api *API) getStats(w http.ResponseWriter, r *http.Request) {
+	api.lock()
+	defer api.unlock()
+	stats, err := api.db.GetNetworkStats()
+	if err != nil {
+		api.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to get network stats: %v", err))
+		return
+	}
+	api.writeJSON(w, stats)
+}
+
+func (api *API) getWallet(w http.ResponseWriter, r *http.Request) {
+	var address types.UnlockHash
+	str := strings.TrimPrefix(r.URL.Path, "/wallets/")
+	if strings.HasSuffix(str, "/outputs") {
+		api.getWalletLockedOutputs(w, r, strings.TrimSuffix(str, "/outputs"))
+		return
+	}
+	err := address.LoadString(str)
+	if err != nil {
+		api.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid address %q: %v", str, err))
+		return
+	}
+	api.lock()
+	defer api.unlock()
+	// the unlock horizons of the locked balance are recomputed as of the current network time
+	stats, err := api.db.GetNetworkStats()
+	if err != nil {
+		api.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to get network stats: %v", err))
+		return
+	}
+	wallet, err := getWalletDetails(api.db, address, stats.Timestamp)
+	switch err {
+	case nil:
+		api.writeJSON(w, wallet)
+	case ErrNotFound:
+		api.writeError(w, http.StatusNotFound, fmt.Errorf("wallet %s not found", address.String()))
+	default:
+		api.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to get wallet %s: %v", address.String(), err))
+	}
+}
+
+// getWalletLockedOutputs writes a page of the locked outputs of the wallet of the given address,
+// starting after the output identified by the cursor query parameter, if any,
+// the size of the page being defined by the limit query parameter, see normalizeWalletOutputsPageSize.
+func (api *API) getWalletLockedOutputs(w http.ResponseWriter, r *http.Request, str string) {
+	var address types.UnlockHash
+	err := address.LoadString(str)
+	if err != nil {
+		api.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid address %q: %v", str, err))
+		return
+	}
+	query := r.URL.Query()
+	cursor, err := parseWalletOutputsCursor(query.Get("cursor"))
+	if err != nil {
+		api.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var limit int
+	if str := query.Get("limit"); str != "" {
+		limit, err = strconv.Atoi(str)
+		if err != nil || limit <= 0 {
+			api.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q: expected a positive integer", str))
+			return
+		}
+	}
+	api.lock()
+	defer api.unlock()
+	page, err := getWalletLockedOutputs(api.db, address, cursor, limit)
+	switch err {
+	case nil:
+		api.writeJSON(w, page)
+	case ErrNotFound:
+		api.writeError(w, http.StatusNotFound, fmt.Errorf("wallet %s not found", address.String()))
+	default:
+		api.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to get locked outputs of wallet %s: %v", address.String(), err))
+	}
+}
+
+func (api *API) getCoinOutput(w http.ResponseWriter, r *http.Request) {
+	var id types.CoinOutputID
+	str := strings.TrimPrefix(r.URL.Path, "/outputs/")
+	err := id.LoadString(str)
+	if err != nil {
+		api.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid coin output ID %q: %v", str, err))
+		return
+	}
+	api.lock()
+	defer api.unlock()
+	info, err := getCoinOutputDetails(api.db, id)
+	switch err {
+	case nil:
+		api.writeJSON(w, info)
+	case ErrNotFound:
+		api.writeError(w, http.StatusNotFound, fmt.Errorf("coin output %s not found", id.String()))
+	default:
+		api.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to get coin output %s: %v", id.String(), err))
+	}
+}
+
+// executeGraphQLQuery executes a GraphQL query, see newGraphQLSchema for the schema it is executed against,
+// such that clients can fetch the (nested) data they need in a single request.
+// The query is passed either as a JSON-encoded graphqlRequest in the body of a POST request,
+// or as the query, operationName and (JSON-encoded) variables query parameters of a GET request.
+// The response is a JSON-encoded graphqlResponse, using status 400 if the query failed.
+func (api *API) executeGraphQLQuery(w http.ResponseWriter, r *http.Request) {
+	var request graphqlRequest
+	if r.Method == http.MethodPost {
+		err := json.NewDecoder(io.LimitReader(r.Body, graphqlMaxRequestSize)).Decode(&request)
+		if err != nil {
+			api.writeResponse(w, http.StatusBadRequest, graphqlResponse{
+				Errors: []graphqlError{{Message: fmt.Sprintf("invalid request: %v", err)}},
+			})
+			return
+		}
+	} else {
+		query := r.URL.Query()
+		request.Query = query.Get("query")
+		request.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			err := json.Unmarshal([]byte(variables), &request.Variables)
+			if err != nil {
+				api.writeResponse(w, http.StatusBadRequest, graphqlResponse{
+					Errors: []graphqlError{{Message: fmt.Sprintf("invalid variables: %v", err)}},
+				})
+				return
+			}
+		}
+	}
+	api.lock()
+	response := executeGraphQL(newGraphQLSchema(api.db), request)
+	api.unlock()
+	status := http.StatusOK
+	if len(response.Errors) > 0 {
+		status = http.StatusBadRequest
+	}
+	api.writeResponse(w, status, response)
+}
+
+// streamWebSocket streams all events published from now on as (JSON-encoded) text messages, see Event,
+// together with the balance of the addresses the client subscribes to: initially when subscribing,
+// and afterwards each time that balance changed. The client subscribes to addresses using the address query parameter
+// (which can be passed multiple times), and by sending websocketRequest messages.
+// The connection is closed by the server in case the client doesn't keep up with the published events,
+// as well as when the explorer is closed.
+func (api *API) streamWebSocket(w http.ResponseWriter, r *http.Request) {
+	if api.feed == nil {
+		api.writeError(w, http.StatusNotFound, errors.New("events are not published"))
+		return
+	}
+	addresses, ok := api.parseAddresses(w, r)
+	if !ok {
+		return
+	}
+	ws, err := upgradeWebSocket(w, r)
+	if err != nil {
+		return
+	}
+	defer ws.Close()
+	sub := api.feed.Subscribe()
+	defer sub.Close()
+
+	// read the requests of the client in the background, until the connection is closed
+	requests := make(chan websocketRequest)
+	readErr := make(chan error, 1)
+	closing := make(chan struct{})
+	defer close(closing)
+	go func() {
+		for {
+			message, err := ws.ReadMessage()
+			if err != nil {
+				readErr <- err
+				return
+			}
+			var request websocketRequest
+			err = json.Unmarshal(message, &request)
+			if err != nil {
+				readErr <- ws.fail(websocketCloseInvalidData, fmt.Sprintf("invalid request: %v", err))
+				return
+			}
+			select {
+			case requests <- request:
+			case <-closing:
+				return
+			}
+		}
+	}()
+
+	tracker := newBalanceTracker(api.db, api.mut)
+	send := func(events []Event, err error) bool {
+		if err == errTooManyBalanceSubscriptions {
+			ws.fail(websocketClosePolicy, err.Error())
+			return false
+		}
+		if err != nil {
+			log.Println("[ERROR] failed to track the subscribed balances of a WebSocket client:", err)
+			ws.fail(websocketCloseInternalError, "failed to track the subscribed balances")
+			return false
+		}
+		for _, event := range events {
+			b, err := json.Marshal(event)
+			if err == nil {
+				err = ws.WriteText(b)
+			}
+			if err != nil {
+				return false
+			}
+		}
+		return true
+	}
+	if !send(tracker.subscribe(addresses)) {
+		return
+	}
+	for {
+		select {
+		case request := <-requests:
+			tracker.unsubscribe(request.Unsubscribe)
+			if !send(tracker.subscribe(request.Subscribe)) {
+				return
+			}
+		case event, ok := <-sub.Events():
+			if !ok {
+				if sub.Dropped() {
+					ws.WriteClose(websocketCloseTryAgain, "too slow to receive the events")
+				} else {
+					ws.WriteClose(websocketCloseGoingAway, "explorer is closing")
+				}
+				return
+			}
+			if !send([]Event{event}, nil) {
+				return
+			}
+			// balances only change when blocks are applied or reverted,
+			// and are only compared once all events published so far are sent
+			if len(sub.Events()) == 0 && !send(tracker.update()) {
+				return
+			}
+		case <-readErr:
+			return
+		}
+	}
+}
+
+// streamServerSentEvents streams all events published from now on as Server-Sent Events,
+// using the event type as the name of each event and its (JSON-encoded) data as the data of each event, see Event,
+// together with the balance of the addresses passed as the address query parameter (which can be passed multiple times):
+// initially, and afterwards each time that balance changed.
+// A comment is sent every sseKeepAliveInterval, such that idle connections aren't closed by proxies.
+// The stream ends in case the client doesn't keep up with the published events, as well as when the explorer is closed.
+func (api *API) streamServerSentEvents(w http.ResponseWriter, r *http.Request) {
+	if api.feed == nil {
+		api.writeError(w, http.StatusNotFound, errors.New("events are not published"))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		api.writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported by the connection"))
+		return
+	}
+	addresses, ok := api.parseAddresses(w, r)
+	if !ok {
+		return
+	}
+	sub := api.feed.Subscribe()
+	defer sub.Close()
+	tracker := newBalanceTracker(api.db, api.mut)
+	initial, err := tracker.subscribe(addresses)
+	if err == errTooManyBalanceSubscriptions {
+		api.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		api.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	send := func(events []Event) bool {
+		for _, event := range events {
+			b, err := json.Marshal(event.Data)
+			if err != nil {
+				log.Printf("[ERROR] failed to JSON-encode %s event: %v", event.Type, err)
+				return false
+			}
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, b)
+			if err != nil {
+				return false
+			}
+		}
+		flusher.Flush()
+		return true
+	}
+	if !send(initial) {
+		return
+	}
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			if !send([]Event{event}) {
+				return
+			}
+			// balances only change when blocks are applied or reverted,
+			// and are only compared once all events published so far are sent
+			if len(sub.Events()) == 0 {
+				events, err := tracker.update()
+				if err != nil {
+					log.Println("[ERROR] failed to track the subscribed balances of an SSE client:", err)
+					return
+				}
+				if !send(events) {
+					return
+				}
+			}
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// adminWebhooks administers the webhooks of the watched addresses, see WebhookDatabase:
+// a GET request lists all registered webhooks, including their secrets,
+// a POST request registers the webhook defined by the JSON-encoded body (its address and url), responding with its secret,
+// and a DELETE request removes the webhook of the address and url query parameters
+// (or all webhooks of the address if no url is given).
+func (api *API) adminWebhooks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		api.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	wdb, ok := api.db.(WebhookDatabase)
+	if !ok {
+		api.writeError(w, http.StatusNotImplemented, errors.New("the database does not support webhooks"))
+		return
+	}
+	var update func([]Webhook) ([]Webhook, error)
+	var response interface{}
+	switch r.Method {
+	case http.MethodPost:
+		var request struct {
+			Address types.UnlockHash `json:"address"`
+			URL     string           `json:"url"`
+		}
+		err := json.NewDecoder(io.LimitReader(r.Body, graphqlMaxRequestSize)).Decode(&request)
+		if err != nil {
+			api.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %v", err))
+			return
+		}
+		update = func(webhooks []Webhook) ([]Webhook, error) {
+			webhooks, webhook, err := AddWebhook(webhooks, request.Address, request.URL)
+			response = webhook
+			return webhooks, err
+		}
+	case http.MethodDelete:
+		var address types.UnlockHash
+		str := r.URL.Query().Get("address")
+		err := address.LoadString(str)
+		if err != nil {
+			api.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid address %q: %v", str, err))
+			return
+		}
+		update = func(webhooks []Webhook) ([]Webhook, error) {
+			webhooks, err := RemoveWebhook(webhooks, address, r.URL.Query().Get("url"))
+			response = webhooks
+			return webhooks, err
+		}
+	}
+	api.lock()
+	defer api.unlock()
+	webhooks, err := wdb.GetWebhooks()
+	if err != nil {
+		api.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to get webhooks: %v", err))
+		return
+	}
+	if update == nil {
+		if webhooks == nil {
+			webhooks = []Webhook{}
+		}
+		api.writeJSON(w, webhooks)
+		return
+	}
+	webhooks, err = update(webhooks)
+	if err != nil {
+		api.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	err = wdb.SetWebhooks(webhooks)
+	if err != nil {
+		api.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to store webhooks: %v", err))
+		return
+	}
+	api.writeJSON(w, response)
+}
+
+// parseAddresses parses the addresses passed as the address query parameter,
+// writing an error response (and returning false) if any of them is invalid.
+func (api *API) parseAddresses(w http.ResponseWriter, r *http.Request) ([]types.UnlockHash, bool) {
+	var addresses []types.UnlockHash
+	for _, str := range r.URL.Query()["address"] {
+		var address types.UnlockHash
+		err := address.LoadString(str)
+		if err != nil {
+			api.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid address %q: %v", str, err))
+			return nil, false
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, true
+}
+
+func (api *API) lock() {
+	if api.mut != nil {
+		api.mut.Lock()
+	}
+}
+
+func (api *API) unlock() {
+	if api.mut != nil {
+		api.mut.Unlock()
+	}
+}
+
+func (api *API) writeJSON(w http.ResponseWriter, v interface{}) {
+	api.writeResponse(w, http.StatusOK, v)
+}
+
+func (api *API) writeError(w http.ResponseWriter, status int, err error) {
+	api.writeResponse(w, status, apiError{Error: err.Error()})
+}
+
+func (api *API) writeResponse(w http.ResponseWriter, status int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		status = http.StatusInternalServerError
+		b, _ = json.Marshal(apiError{Error: fmt.Sprintf("failed to JSON-encode response: %v", err)})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(b)
+}
+
+// ServeAPI starts serving the given API on the given (TCP) address in the background,
+// returning the server such that it can be closed once no longer required.
+// An error is only returned if the address cannot be listened on.
+func ServeAPI(addr string, api *API) (*http.Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on API address %s: %v", addr, err)
+	}
+	server := &http.Server{Handler: api}
+	go func() {
+		err := server.Serve(ln)
+		if err != nil && err != http.ErrServerClosed {
+			log.Println("[ERROR] API server stopped unexpectedly:", err)
+		}
+	}()
+	return server, nil
+}
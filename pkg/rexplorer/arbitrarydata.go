@@ -0,0 +1,60 @@
+package rexplorer
+
+import (
+	"fmt"
+
+	"github.com/rivine/rivine/crypto"
+	"github.com/rivine/rivine/types"
+)
+
+// ArbitraryDataPrefixLength is the length (in bytes) of the prefix by which the arbitrary data of transactions is indexed,
+// arbitrary data shorter than that being indexed by itself.
+const ArbitraryDataPrefixLength = 8
+
+// ArbitraryDataTransaction defines a transaction referenced by the index of arbitrary data,
+// as well as the (full) arbitrary data of that transaction.
+type ArbitraryDataTransaction struct {
+	ID   types.TransactionID `json:"id"`
+	Data types.ByteSlice     `json:"data"`
+}
+
+// arbitraryDataPrefix returns the prefix by which the given arbitrary data is indexed.
+func arbitraryDataPrefix(data []byte) []byte {
+	if len(data) > ArbitraryDataPrefixLength {
+		return data[:ArbitraryDataPrefixLength]
+	}
+	return data
+}
+
+// applyArbitraryData indexes the arbitrary data of the given transaction, by its hash and prefix,
+// in case the transaction has arbitrary data and the database supports it.
+func (explorer *Explorer) applyArbitraryData(tx types.Transaction) {
+	addb, ok := explorer.db.(ArbitraryDataDatabase)
+	if !ok || len(tx.ArbitraryData) == 0 {
+		return
+	}
+	id := tx.ID()
+	err := addb.ApplyArbitraryData(id, tx.ArbitraryData)
+	if err != nil {
+		panic(fmt.Sprintf("failed to index arbitrary data of tx %s: %v", id.String(), err))
+	}
+}
+
+// revertArbitraryData removes the arbitrary data of the given transaction from the index,
+// in case the transaction has arbitrary data and the database supports it.
+func (explorer *Explorer) revertArbitraryData(tx types.Transaction) {
+	addb, ok := explorer.db.(ArbitraryDataDatabase)
+	if !ok || len(tx.ArbitraryData) == 0 {
+		return
+	}
+	id := tx.ID()
+	err := addb.RevertArbitraryData(id, tx.ArbitraryData)
+	if err != nil {
+		panic(fmt.Sprintf("failed to unindex arbitrary data of tx %s: %v", id.String(), err))
+	}
+}
+
+// arbitraryDataHash returns the hash by which the given arbitrary data is indexed.
+func arbitraryDataHash(data []byte) crypto.Hash {
+	return crypto.HashBytes(data)
+}
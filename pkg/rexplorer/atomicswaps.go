@@ -0,0 +1,148 @@
+package rexplorer
+
+import (
+	"fmt"
+
+	"github.com/rivine/rivine/types"
+)
+
+type (
+	// AtomicSwapContract records a single atomic swap contract, being a coin output protected by an AtomicSwapCondition,
+	// identified by the ID of that coin output. The contract is linked from the wallets of both its sender and receiver,
+	// and is open until it is either redeemed by the receiver (revealing the secret), or refunded to the sender
+	// (once its timelock expired), in which case the transaction spending it is recorded as well.
+	AtomicSwapContract struct {
+		ID                 types.CoinOutputID           `json:"id"`
+		Address            types.UnlockHash             `json:"address"`
+		Value              types.Currency               `json:"value"`
+		Sender             types.UnlockHash             `json:"sender"`
+		Receiver           types.UnlockHash             `json:"receiver"`
+		HashedSecret       types.AtomicSwapHashedSecret `json:"hashedSecret"`
+		TimeLock           types.Timestamp              `json:"timelock"`
+		TransactionID      types.TransactionID          `json:"txID"`
+		State              AtomicSwapContractState      `json:"state"`
+		Secret             *types.AtomicSwapSecret      `json:"secret,omitempty"`
+		SpendTransactionID *types.TransactionID         `json:"spendTxID,omitempty"`
+	}
+
+	// AtomicSwapContractState defines the state of an atomic swap contract.
+	AtomicSwapContractState string
+)
+
+// The states of an atomic swap contract.
+const (
+	AtomicSwapContractStateOpen     AtomicSwapContractState = "open"
+	AtomicSwapContractStateRedeemed AtomicSwapContractState = "redeemed"
+	AtomicSwapContractStateRefunded AtomicSwapContractState = "refunded"
+)
+
+// atomicSwapFulfillment is implemented by both the (new) and legacy atomic swap fulfillments.
+type atomicSwapFulfillment interface {
+	AtomicSwapSecret() types.AtomicSwapSecret
+}
+
+// newAtomicSwapContract records the atomic swap contract created by the given coin output of the given transaction,
+// returning false if the output isn't protected by an AtomicSwapCondition.
+func newAtomicSwapContract(tx types.Transaction, index int) (AtomicSwapContract, bool) {
+	co := tx.CoinOutputs[index]
+	asc, ok := co.Condition.Condition.(*types.AtomicSwapCondition)
+	if !ok {
+		return AtomicSwapContract{}, false
+	}
+	return AtomicSwapContract{
+		ID:            tx.CoinOutputID(uint64(index)),
+		Address:       co.Condition.UnlockHash(),
+		Value:         co.Value,
+		Sender:        asc.Sender,
+		Receiver:      asc.Receiver,
+		HashedSecret:  asc.HashedSecret,
+		TimeLock:      asc.TimeLock,
+		TransactionID: tx.ID(),
+		State:         AtomicSwapContractStateOpen,
+	}, true
+}
+
+// applyAtomicSwapContracts redeems or refunds the atomic swap contracts spent by the coin inputs of the given transaction,
+// and adds the atomic swap contracts created by its coin outputs, in case the database supports it.
+// Contracts fulfilled using a legacy atomic swap fulfillment (spending an atomic swap unlock hash rather than condition),
+// as well as contracts created prior to the dataset indexing them, aren't stored and are skipped.
+func (explorer *Explorer) applyAtomicSwapContracts(tx types.Transaction) {
+	asdb, ok := explorer.db.(AtomicSwapDatabase)
+	if !ok {
+		return
+	}
+	txID := tx.ID()
+	for _, ci := range tx.CoinInputs {
+		asf, ok := ci.Fulfillment.Fulfillment.(atomicSwapFulfillment)
+		if !ok {
+			continue
+		}
+		contract, err := asdb.GetAtomicSwapContract(ci.ParentID)
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			panic(fmt.Sprintf("failed to get atomic swap contract %s: %v", ci.ParentID.String(), err))
+		}
+		contract.State = AtomicSwapContractStateRefunded
+		if secret := asf.AtomicSwapSecret(); secret != (types.AtomicSwapSecret{}) {
+			contract.State = AtomicSwapContractStateRedeemed
+			contract.Secret = &secret
+		}
+		contract.SpendTransactionID = &txID
+		err = asdb.SetAtomicSwapContract(contract)
+		if err != nil {
+			panic(fmt.Sprintf("failed to mark atomic swap contract %s as %s: %v",
+				ci.ParentID.String(), contract.State, err))
+		}
+	}
+	for i := range tx.CoinOutputs {
+		contract, ok := newAtomicSwapContract(tx, i)
+		if !ok {
+			continue
+		}
+		err := asdb.SetAtomicSwapContract(contract)
+		if err != nil {
+			panic(fmt.Sprintf("failed to add atomic swap contract %s: %v", contract.ID.String(), err))
+		}
+	}
+}
+
+// revertAtomicSwapContracts drops the atomic swap contracts created by the coin outputs of the given transaction,
+// and opens the atomic swap contracts spent by its coin inputs again, in case the database supports it,
+// skipping the contracts which aren't stored (see applyAtomicSwapContracts).
+func (explorer *Explorer) revertAtomicSwapContracts(tx types.Transaction) {
+	asdb, ok := explorer.db.(AtomicSwapDatabase)
+	if !ok {
+		return
+	}
+	for i := range tx.CoinOutputs {
+		contract, ok := newAtomicSwapContract(tx, i)
+		if !ok {
+			continue
+		}
+		err := asdb.RevertAtomicSwapContract(contract)
+		if err != nil {
+			panic(fmt.Sprintf("failed to revert atomic swap contract %s: %v", contract.ID.String(), err))
+		}
+	}
+	for _, ci := range tx.CoinInputs {
+		if _, ok := ci.Fulfillment.Fulfillment.(atomicSwapFulfillment); !ok {
+			continue
+		}
+		contract, err := asdb.GetAtomicSwapContract(ci.ParentID)
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			panic(fmt.Sprintf("failed to get atomic swap contract %s: %v", ci.ParentID.String(), err))
+		}
+		contract.State = AtomicSwapContractStateOpen
+		contract.Secret = nil
+		contract.SpendTransactionID = nil
+		err = asdb.SetAtomicSwapContract(contract)
+		if err != nil {
+			panic(fmt.Sprintf("failed to reopen atomic swap contract %s: %v", ci.ParentID.String(), err))
+		}
+	}
+}
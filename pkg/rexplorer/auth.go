@@ -0,0 +1,179 @@
+package rexplorer
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// APIScope defines what the bearer of an API key is allowed to access.
+type APIScope string
+
+// All scopes an API key can be granted.
+const (
+	// APIScopeStats grants access to the network stats.
+	APIScopeStats APIScope = "stats"
+	// APIScopeWallets grants access to the wallets, coin outputs, blocks and transactions,
+	// as well as to the streamed events, as those include the balance of the subscribed addresses.
+	APIScopeWallets APIScope = "wallets"
+	// APIScopeAdmin grants access to everything, including the administration of the registered webhooks.
+	APIScopeAdmin APIScope = "admin"
+)
+
+var (
+	errAPIKeyMissing = errors.New("missing API key: pass it as bearer token using the Authorization header")
+	errAPIKeyUnknown = errors.New("unknown API key")
+)
+
+// APIKeys are the keys granting access to the HTTP API and the gRPC service, each of them granting one or multiple scopes.
+// A nil (or empty) set of keys grants everyone access to everything, except for the endpoints requiring APIScopeAdmin.
+type APIKeys struct {
+	keys []apiKey
+}
+
+// apiKey is a single API key, of which only the hash is kept, such that keys can be compared in constant time.
+type apiKey struct {
+	hash   [sha256.Size]byte
+	scopes map[APIScope]struct{}
+}
+
+// LoadAPIKeys loads the API keys defined by the file at the given path, see ParseAPIKeys.
+func LoadAPIKeys(path string) (*APIKeys, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open API keys file: %v", err)
+	}
+	defer file.Close()
+	keys, err := ParseAPIKeys(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API keys file %s: %v", path, err)
+	}
+	return keys, nil
+}
+
+// ParseAPIKeys parses API keys, one per line, formatted as the key followed by the (space-separated) scopes it grants,
+// e.g. "s3cr3t stats wallets". Empty lines, as well as lines starting with '#', are ignored.
+func ParseAPIKeys(r io.Reader) (*APIKeys, error) {
+	keys := new(APIKeys)
+	seen := make(map[[sha256.Size]byte]struct{})
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) == 1 {
+			return nil, fmt.Errorf("line %d: no scopes granted", n)
+		}
+		key := apiKey{
+			hash:   sha256.Sum256([]byte(fields[0])),
+			scopes: make(map[APIScope]struct{}, len(fields)-1),
+		}
+		if _, ok := seen[key.hash]; ok {
+			return nil, fmt.Errorf("line %d: duplicate key", n)
+		}
+		seen[key.hash] = struct{}{}
+		for _, str := range fields[1:] {
+			scope := APIScope(str)
+			switch scope {
+			case APIScopeStats, APIScopeWallets, APIScopeAdmin:
+				key.scopes[scope] = struct{}{}
+			default:
+				return nil, fmt.Errorf("line %d: unknown scope %q", n, str)
+			}
+		}
+		keys.keys = append(keys.keys, key)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Len returns the amount of keys.
+func (keys *APIKeys) Len() int {
+	if keys == nil {
+		return 0
+	}
+	return len(keys.keys)
+}
+
+// authorize returns nil if the given key grants the given scope,
+// errAPIKeyMissing or errAPIKeyUnknown if the key isn't known, or an error describing the missing scope.
+// Without any keys, all scopes but APIScopeAdmin are granted to everyone.
+func (keys *APIKeys) authorize(token string, scope APIScope) error {
+	if keys.Len() == 0 {
+		if scope == APIScopeAdmin {
+			return fmt.Errorf("scope %s requires API keys to be configured", scope)
+		}
+		return nil
+	}
+	if token == "" {
+		return errAPIKeyMissing
+	}
+	match := keys.lookup(token)
+	if match == nil {
+		return errAPIKeyUnknown
+	}
+	if _, ok := match.scopes[APIScopeAdmin]; ok {
+		return nil
+	}
+	if _, ok := match.scopes[scope]; !ok {
+		return fmt.Errorf("API key does not grant the %s scope", scope)
+	}
+	return nil
+}
+
+// lookup returns the key matching the given token, nil if none does.
+func (keys *APIKeys) lookup(token string) *apiKey {
+	if keys.Len() == 0 || token == "" {
+		return nil
+	}
+	hash := sha256.Sum256([]byte(token))
+	var match *apiKey
+	for i := range keys.keys {
+		// compare all keys, such that the time taken doesn't reveal which key matched
+		if subtle.ConstantTimeCompare(hash[:], keys.keys[i].hash[:]) == 1 {
+			match = &keys.keys[i]
+		}
+	}
+	return match
+}
+
+// requestAPIKey returns the API key passed by the given request, empty if none is passed.
+// The key is passed as bearer token using the Authorization header,
+// or as the access_token query parameter, as browsers can't set headers for WebSocket and EventSource requests.
+func requestAPIKey(r *http.Request) (string, error) {
+	auth := r.Header.Get("Authorization")
+	if auth == "" {
+		return r.URL.Query().Get("access_token"), nil
+	}
+	const prefix = "Bearer "
+	if len(auth) < len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return "", errors.New("invalid Authorization header: expected a bearer token")
+	}
+	return strings.TrimSpace(auth[len(prefix):]), nil
+}
+
+// authorizeRequest authorizes the given request for the given scope, see authorize and requestAPIKey,
+// returning the HTTP status to respond with if it isn't authorized.
+func (keys *APIKeys) authorizeRequest(r *http.Request, scope APIScope) (int, error) {
+	token, err := requestAPIKey(r)
+	if err != nil {
+		return http.StatusUnauthorized, err
+	}
+	switch err := keys.authorize(token, scope); err {
+	case nil:
+		return http.StatusOK, nil
+	case errAPIKeyMissing, errAPIKeyUnknown:
+		return http.StatusUnauthorized, err
+	default:
+		return http.StatusForbidden, err
+	}
+}
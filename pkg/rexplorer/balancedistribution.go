@@ -0,0 +1,45 @@
+package rexplorer
+
+import (
+	"github.com/rivine/rivine/types"
+)
+
+// BalanceDistribution counts the addresses with a non-zero coin balance per range of their total
+// (unlocked and locked) balance, see BalanceDistributionLabels for the balance range of each bucket.
+type BalanceDistribution [5]uint64
+
+// BalanceDistributionLabels returns the (human-readable) balance range of each bucket of a BalanceDistribution,
+// expressed in coins.
+func BalanceDistributionLabels() []string {
+	return []string{"<1", "<100", "<10k", "<1M", ">=1M"}
+}
+
+// Total returns the amount of addresses counted by all buckets.
+func (dist BalanceDistribution) Total() (n uint64) {
+	for _, count := range dist {
+		n += count
+	}
+	return n
+}
+
+// balanceDistributionBounds returns the (exclusive) upper bound of the balance of each bucket of a BalanceDistribution,
+// except for the last bucket, which is unbounded.
+func balanceDistributionBounds(oneCoin types.Currency) []types.Currency {
+	return []types.Currency{
+		oneCoin,
+		oneCoin.Mul64(100),
+		oneCoin.Mul64(10000),
+		oneCoin.Mul64(1000000),
+	}
+}
+
+// balanceDistributionBucket returns the bucket of a BalanceDistribution the given (non-zero) balance belongs to.
+func balanceDistributionBucket(balance, oneCoin types.Currency) int {
+	bounds := balanceDistributionBounds(oneCoin)
+	for bucket, bound := range bounds {
+		if balance.Cmp(bound) < 0 {
+			return bucket
+		}
+	}
+	return len(bounds)
+}
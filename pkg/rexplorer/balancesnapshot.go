@@ -0,0 +1,138 @@
+package rexplorer
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/rivine/rivine/types"
+)
+
+type (
+	// BalanceSnapshot is a snapshot of the network stats and the balance of all wallets,
+	// taken once a block height was applied, such that the changes in between two heights can be reported.
+	BalanceSnapshot struct {
+		Stats NetworkStats `json:"stats"`
+		// Balances only contains the wallets with a non-zero balance
+		Balances map[types.UnlockHash]SnapshotBalance `json:"balances"`
+	}
+	// SnapshotBalance is the balance of a single wallet, as stored in a BalanceSnapshot.
+	SnapshotBalance struct {
+		Unlocked types.Currency `json:"unlocked"`
+		Locked   types.Currency `json:"locked"`
+	}
+
+	// BalanceSnapshotDiff defines the changes in between two balance snapshots.
+	BalanceSnapshotDiff struct {
+		From, To NetworkStats
+		// Changes of the balance of all wallets of which the balance changed,
+		// ordered by the absolute change of their total balance, biggest first
+		Changes []BalanceChange
+	}
+	// BalanceChange defines the change of the balance of a single wallet.
+	BalanceChange struct {
+		Address       types.UnlockHash
+		Before, After SnapshotBalance
+	}
+)
+
+// Total returns the total (unlocked and locked) balance.
+func (balance SnapshotBalance) Total() types.Currency {
+	return balance.Unlocked.Add(balance.Locked)
+}
+
+// IsZero returns true if neither an unlocked nor a locked balance is defined.
+func (balance SnapshotBalance) IsZero() bool {
+	return balance.Unlocked.IsZero() && balance.Locked.IsZero()
+}
+
+// UnlockedDelta returns the (signed) change of the unlocked balance.
+func (change BalanceChange) UnlockedDelta() *big.Int {
+	return currencyDelta(change.Before.Unlocked, change.After.Unlocked)
+}
+
+// LockedDelta returns the (signed) change of the locked balance.
+func (change BalanceChange) LockedDelta() *big.Int {
+	return currencyDelta(change.Before.Locked, change.After.Locked)
+}
+
+// TotalDelta returns the (signed) change of the total balance.
+func (change BalanceChange) TotalDelta() *big.Int {
+	return currencyDelta(change.Before.Total(), change.After.Total())
+}
+
+// currencyDelta returns the (signed) change from one currency to another.
+func currencyDelta(from, to types.Currency) *big.Int {
+	return new(big.Int).Sub(to.Big(), from.Big())
+}
+
+// DiffBalanceSnapshots reports the changes from one balance snapshot to another.
+func DiffBalanceSnapshots(from, to BalanceSnapshot) BalanceSnapshotDiff {
+	diff := BalanceSnapshotDiff{
+		From: from.Stats,
+		To:   to.Stats,
+	}
+	for address, before := range from.Balances {
+		after := to.Balances[address]
+		if before.Unlocked.Cmp(after.Unlocked) != 0 || before.Locked.Cmp(after.Locked) != 0 {
+			diff.Changes = append(diff.Changes, BalanceChange{Address: address, Before: before, After: after})
+		}
+	}
+	for address, after := range to.Balances {
+		if _, ok := from.Balances[address]; !ok {
+			diff.Changes = append(diff.Changes, BalanceChange{Address: address, After: after})
+		}
+	}
+	sort.Slice(diff.Changes, func(i, j int) bool {
+		a, b := diff.Changes[i].TotalDelta(), diff.Changes[j].TotalDelta()
+		if c := a.CmpAbs(b); c != 0 {
+			return c > 0
+		}
+		return diff.Changes[i].Address.Cmp(diff.Changes[j].Address) < 0
+	})
+	return diff
+}
+
+// storeBalanceSnapshot snapshots the balance of all wallets, as its own transaction if supported,
+// in case the current block height is a multiple of the snapshot interval, and the database supports it.
+// As the snapshot is taken once a consensus change is committed, no snapshot is taken
+// for the heights in the middle of a consensus change applying multiple blocks.
+func (explorer *Explorer) storeBalanceSnapshot() {
+	if explorer.snapshotInterval == 0 || explorer.stats.BlockHeight%explorer.snapshotInterval != 0 {
+		return
+	}
+	bdb, ok := explorer.db.(BalanceSnapshotDatabase)
+	if !ok {
+		return
+	}
+	tdb, transactional := explorer.db.(TransactionalDatabase)
+	if transactional {
+		err := tdb.Begin()
+		if err != nil {
+			panic("failed to begin db transaction: " + err.Error())
+		}
+	}
+	err := bdb.StoreBalanceSnapshot(explorer.stats)
+	if err != nil {
+		panic(fmt.Sprintf("failed to store balance snapshot at height %d: %v", explorer.stats.BlockHeight, err))
+	}
+	if transactional {
+		err = tdb.Commit()
+		if err != nil {
+			panic("failed to commit db transaction: " + err.Error())
+		}
+	}
+}
+
+// revertBalanceSnapshot deletes the balance snapshot taken at the current block height, if any,
+// regardless of the snapshot interval, as it might have been taken using another interval.
+func (explorer *Explorer) revertBalanceSnapshot() {
+	bdb, ok := explorer.db.(BalanceSnapshotDatabase)
+	if !ok {
+		return
+	}
+	err := bdb.DeleteBalanceSnapshot(explorer.stats.BlockHeight)
+	if err != nil {
+		panic(fmt.Sprintf("failed to delete balance snapshot at height %d: %v", explorer.stats.BlockHeight, err))
+	}
+}
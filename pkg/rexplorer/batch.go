@@ -0,0 +1,190 @@
+package rexplorer
+
+import (
+	"fmt"
+
+	"github.com/rivine/rivine/types"
+)
+
+type (
+	// BatchDatabase is an optional interface which can be implemented by a Database,
+	// applying (or reverting) all coin output changes of a block using a single call (see BlockChanges),
+	// rather than using one call per coin output created or spent, such that the database can apply
+	// an entire block in a single round trip. Databases not implementing it are called once per change instead,
+	// see applyCoinOutputChanges.
+	//
+	// ApplyBlock applies the changes in order, while RevertBlock reverts the (previously applied) changes in reverse order.
+	// Both return the result of every change, in the order of the given changes.
+	BatchDatabase interface {
+		Database
+
+		ApplyBlock(changes BlockChanges) ([]CoinOutputChangeResult, error)
+		RevertBlock(changes BlockChanges) ([]CoinOutputChangeResult, error)
+	}
+
+	// BlockChanges are the coin output changes of a single block, in the order they are applied:
+	// the creation of the miner payouts of the block, followed by the spending of the coin inputs
+	// and the creation of the coin outputs of each transaction of the block.
+	BlockChanges struct {
+		Height  types.BlockHeight  `json:"height"`
+		ID      types.BlockID      `json:"id"`
+		Changes []CoinOutputChange `json:"changes"`
+	}
+
+	// CoinOutputChange is the creation of a (possibly locked) coin output, or the spending of a coin output.
+	CoinOutputChange struct {
+		Type CoinOutputChangeType `json:"type"`
+		ID   types.CoinOutputID   `json:"id"`
+		// Output, LockType and LockValue define the created coin output, and are only defined for CoinOutputChangeCreate.
+		// The coin output is locked unless its lock type is LockTypeNone.
+		Output    CoinOutput `json:"output"`
+		LockType  LockType   `json:"lockType,omitempty"`
+		LockValue LockValue  `json:"lockValue,omitempty"`
+	}
+
+	// CoinOutputChangeResult is the result of applying (or reverting) a CoinOutputChange:
+	// the owner and value of the coin output, as well as its state once created (CoinOutputStateLiquid
+	// or CoinOutputStateLocked), spent (CoinOutputStateSpent), unspent again (CoinOutputStateLiquid),
+	// or the state it had when it was dropped (in case its creation is reverted).
+	CoinOutputChangeResult struct {
+		Owner types.UnlockHash `json:"owner"`
+		Value types.Currency   `json:"value"`
+		State CoinOutputState  `json:"state"`
+	}
+)
+
+// CoinOutputChangeType defines how a CoinOutputChange changes a coin output.
+type CoinOutputChangeType uint8
+
+// The different types of coin output changes.
+const (
+	// CoinOutputChangeCreate creates a coin output, see Database.AddCoinOutput and Database.AddLockedCoinOutput,
+	// reverted using Database.RevertCoinOutput.
+	CoinOutputChangeCreate CoinOutputChangeType = iota
+	// CoinOutputChangeSpend spends a coin output, see Database.SpendCoinOutput,
+	// reverted using Database.RevertCoinInput.
+	CoinOutputChangeSpend
+)
+
+// blockChanges returns the coin output changes of the given block, applied at the current block height and time.
+func (explorer *Explorer) blockChanges(block types.Block) BlockChanges {
+	return newBlockChanges(block, explorer.stats.BlockHeight, explorer.stats.Timestamp, explorer.chainCts.MaturityDelay)
+}
+
+// newBlockChanges returns the coin output changes of the given block, applied at the given block height and time,
+// the miner payouts of the block maturing after the given delay.
+func newBlockChanges(block types.Block, height types.BlockHeight, timestamp types.Timestamp, maturityDelay types.BlockHeight) BlockChanges {
+	changes := BlockChanges{
+		Height: height,
+		ID:     block.ID(),
+	}
+	for i, mp := range block.MinerPayouts {
+		var description types.ByteSlice
+		if i == 0 {
+			description = minerPayoutDescription(blockRewardDescriptionPrefix, changes.ID.String())
+		} else {
+			txID := getTransactionIDForMinerPayout(block, uint64(i-1))
+			description = minerPayoutDescription(txFeeDescriptionPrefix, txID.String())
+		}
+		co := types.CoinOutput{
+			Value: mp.Value,
+			Condition: types.NewCondition(
+				types.NewTimeLockCondition(
+					uint64(height+maturityDelay),
+					types.NewUnlockHashCondition(mp.UnlockHash))),
+		}
+		changes.Changes = append(changes.Changes,
+			newCoinOutputCreation(block.MinerPayoutID(uint64(i)), co, description, height, timestamp))
+	}
+	for _, tx := range block.Transactions {
+		for _, ci := range tx.CoinInputs {
+			changes.Changes = append(changes.Changes, CoinOutputChange{Type: CoinOutputChangeSpend, ID: ci.ParentID})
+		}
+		for i, co := range tx.CoinOutputs {
+			changes.Changes = append(changes.Changes,
+				newCoinOutputCreation(tx.CoinOutputID(uint64(i)), co, types.ByteSlice(tx.ArbitraryData), height, timestamp))
+		}
+	}
+	return changes
+}
+
+// newCoinOutputCreation returns the creation of the given coin output,
+// locked in case it cannot be fulfilled as of the given block height and time.
+//
+// A burned coin output can never be spent, no matter its lock, hence it is created as unlocked,
+// such that its coins are counted as burned coins only, and never as locked coins as well (see updateSupply).
+func newCoinOutputCreation(id types.CoinOutputID, co types.CoinOutput, description types.ByteSlice, height types.BlockHeight, timestamp types.Timestamp) CoinOutputChange {
+	lt, lockValue, _ := outputLockAt(co.Condition, height, timestamp)
+	if isUnspendable(co.Condition) {
+		lt, lockValue = LockTypeNone, 0
+	}
+	return CoinOutputChange{
+		Type: CoinOutputChangeCreate,
+		ID:   id,
+		Output: CoinOutput{
+			Value:       co.Value,
+			Condition:   co.Condition,
+			Description: description,
+		},
+		LockType:  lt,
+		LockValue: lockValue,
+	}
+}
+
+// applyBlockChanges applies (or reverts) the given changes using a single call,
+// should the given database implement BatchDatabase, and one call per change otherwise.
+func applyBlockChanges(db Database, changes BlockChanges, revert bool) ([]CoinOutputChangeResult, error) {
+	if bdb, ok := db.(BatchDatabase); ok {
+		if revert {
+			return bdb.RevertBlock(changes)
+		}
+		return bdb.ApplyBlock(changes)
+	}
+	return applyCoinOutputChanges(db, changes, revert)
+}
+
+// applyCoinOutputChanges applies the given changes one by one in order,
+// or reverts them one by one in reverse order, using the methods of the Database interface.
+// It can be used by a BatchDatabase to apply the changes once it prepared itself for them.
+func applyCoinOutputChanges(db Database, changes BlockChanges, revert bool) ([]CoinOutputChangeResult, error) {
+	results := make([]CoinOutputChangeResult, len(changes.Changes))
+	for n := range changes.Changes {
+		i := n
+		if revert {
+			i = len(changes.Changes) - 1 - n
+		}
+		change, result := changes.Changes[i], &results[i]
+		var err error
+		switch change.Type {
+		case CoinOutputChangeCreate:
+			result.Owner, result.Value = change.Output.Condition.UnlockHash(), change.Output.Value
+			switch {
+			case revert:
+				result.State, err = db.RevertCoinOutput(change.ID)
+			case change.LockType == LockTypeNone:
+				result.State = CoinOutputStateLiquid
+				err = db.AddCoinOutput(change.ID, change.Output)
+			default:
+				result.State = CoinOutputStateLocked
+				err = db.AddLockedCoinOutput(change.ID, change.Output, change.LockType, change.LockValue)
+			}
+		case CoinOutputChangeSpend:
+			if revert {
+				result.State = CoinOutputStateLiquid
+				result.Owner, result.Value, err = db.RevertCoinInput(change.ID)
+			} else {
+				result.State = CoinOutputStateSpent
+				result.Owner, result.Value, err = db.SpendCoinOutput(change.ID)
+			}
+		default:
+			err = fmt.Errorf("unknown change type %d", change.Type)
+		}
+		if err != nil {
+			if revert {
+				return nil, fmt.Errorf("failed to revert change %d of block %d (coin output %s): %v", i, changes.Height, change.ID.String(), err)
+			}
+			return nil, fmt.Errorf("failed to apply change %d of block %d (coin output %s): %v", i, changes.Height, change.ID.String(), err)
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,687 @@
+package rexplorer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rivine/rivine/crypto"
+	"github.com/rivine/rivine/types"
+)
+
+// The page sizes of the transactions listed by the address endpoint of the BlockbookAPI.
+const (
+	blockbookDefaultPageSize = 1000
+	blockbookMaxPageSize     = 1000
+)
+
+// BlockbookAPI implements the commonly used (GET) endpoints of version 2 of the Blockbook API
+// (see https://github.com/trezor/blockbook/blob/master/docs/api.md), on top of the data stored in a Database,
+// such that existing wallet software expecting that API can use the explorer instead:
+//
+//	/v2                  the status of the explorer and the chain
+//	/v2/address/<address>?page=&pageSize=&details=
+//	                     the balance and (paginated) transactions of an address, details being basic, txids (default) or txs
+//	/v2/utxo/<address>   the unspent (including locked) coin outputs of an address
+//	/v2/tx/<txid>        a transaction
+//
+// Amounts are strings in the smallest unit of the coin. The miner payouts of a block are represented as
+// a coinbase transaction, identified by the ID of the block, such that every coin output has a txid and vout.
+// Addresses and UTXOs can only be served if the database stores the address history (see AddressHistoryDatabase),
+// miner payouts (see MinerPayoutDatabase) and the block and transaction records (see BlockDatabase and TransactionDatabase).
+// The inputs of a transaction only define the txid and vout of the output they spend if the database stores
+// the provenance of the coin outputs as well (see CoinOutputProvenanceDatabase).
+// Errors are returned as a JSON object with a single "error" field, the same as the API.
+type BlockbookAPI struct {
+	db     Database
+	mut    sync.Locker
+	bcInfo types.BlockchainInfo
+}
+
+// NewBlockbookAPI creates a BlockbookAPI serving the data stored in the given database, explored for the given chain.
+// The given locker (if not nil) is held while reading the database,
+// such that the API only observes the data stored by entire consensus changes.
+func NewBlockbookAPI(db Database, mut sync.Locker, bcInfo types.BlockchainInfo) *BlockbookAPI {
+	return &BlockbookAPI{
+		db:     db,
+		mut:    mut,
+		bcInfo: bcInfo,
+	}
+}
+
+// BlockbookAPI creates a BlockbookAPI serving the data stored by this explorer,
+// reading the database in between the consensus changes it processes.
+func (explorer *Explorer) BlockbookAPI() *BlockbookAPI {
+	return NewBlockbookAPI(explorer.db, &explorer.mut, explorer.bcInfo)
+}
+
+// The (JSON-encoded) responses of the BlockbookAPI.
+type (
+	blockbookStatus struct {
+		Blockbook struct {
+			Coin          string    `json:"coin"`
+			Host          string    `json:"host"`
+			Version       string    `json:"version"`
+			BestHeight    uint64    `json:"bestHeight"`
+			LastBlockTime time.Time `json:"lastBlockTime"`
+			InSync        bool      `json:"inSync"`
+		} `json:"blockbook"`
+		Backend struct {
+			Chain         string `json:"chain"`
+			Blocks        uint64 `json:"blocks"`
+			BestBlockHash string `json:"bestBlockHash,omitempty"`
+			Version       string `json:"version"`
+		} `json:"backend"`
+	}
+	blockbookAddress struct {
+		Page               int              `json:"page"`
+		TotalPages         int              `json:"totalPages"`
+		ItemsOnPage        int              `json:"itemsOnPage"`
+		Address            string           `json:"address"`
+		Balance            string           `json:"balance"`
+		TotalReceived      string           `json:"totalReceived"`
+		TotalSent          string           `json:"totalSent"`
+		UnconfirmedBalance string           `json:"unconfirmedBalance"`
+		UnconfirmedTxs     int              `json:"unconfirmedTxs"`
+		Txs                int              `json:"txs"`
+		TxIDs              []string         `json:"txids,omitempty"`
+		Transactions       []blockbookTx    `json:"transactions,omitempty"`
+		Locked             *blockbookLocked `json:"locked,omitempty"`
+	}
+	// blockbookLocked extends the Blockbook address with the part of its balance which is locked.
+	blockbookLocked struct {
+		Balance string `json:"balance"`
+	}
+	blockbookUTXO struct {
+		TxID          string `json:"txid"`
+		Vout          int    `json:"vout"`
+		Value         string `json:"value"`
+		Height        uint64 `json:"height"`
+		Confirmations uint64 `json:"confirmations"`
+		// LockTime is the height or timestamp the output unlocks at, only defined for locked outputs
+		LockTime uint64 `json:"lockTime,omitempty"`
+		Coinbase bool   `json:"coinbase,omitempty"`
+	}
+	blockbookTx struct {
+		TxID          string          `json:"txid"`
+		Version       int             `json:"version"`
+		Vin           []blockbookVin  `json:"vin"`
+		Vout          []blockbookVout `json:"vout"`
+		BlockHash     string          `json:"blockHash"`
+		BlockHeight   uint64          `json:"blockHeight"`
+		Confirmations uint64          `json:"confirmations"`
+		BlockTime     int64           `json:"blockTime"`
+		Value         string          `json:"value"`
+		ValueIn       string          `json:"valueIn"`
+		Fees          string          `json:"fees"`
+	}
+	blockbookVin struct {
+		TxID      string   `json:"txid,omitempty"`
+		Vout      *int     `json:"vout,omitempty"`
+		N         int      `json:"n"`
+		Addresses []string `json:"addresses"`
+		IsAddress bool     `json:"isAddress"`
+		Value     string   `json:"value"`
+	}
+	blockbookVout struct {
+		Value     string   `json:"value"`
+		N         int      `json:"n"`
+		Spent     bool     `json:"spent,omitempty"`
+		Addresses []string `json:"addresses"`
+		IsAddress bool     `json:"isAddress"`
+	}
+)
+
+// blockbookError is an error returned by an endpoint of the BlockbookAPI, with the HTTP status to respond with.
+type blockbookError struct {
+	status  int
+	message string
+}
+
+func blockbookErrorf(status int, format string, args ...interface{}) *blockbookError {
+	return &blockbookError{status: status, message: fmt.Sprintf(format, args...)}
+}
+
+// blockbookEntry is a transaction listed by the address endpoint of the BlockbookAPI,
+// either a transaction, or the miner payouts of a block received by the address.
+type blockbookEntry struct {
+	height   types.BlockHeight
+	txID     *types.TransactionID
+	received types.Currency
+	sent     types.Currency
+}
+
+// ServeHTTP implements http.Handler.ServeHTTP
+func (api *BlockbookAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var (
+		response interface{}
+		err      *blockbookError
+	)
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	switch {
+	case path == "" || path == "/v2":
+		response, err = api.status()
+	case strings.HasPrefix(path, "/v2/address/"):
+		response, err = api.address(strings.TrimPrefix(path, "/v2/address/"), r)
+	case strings.HasPrefix(path, "/v2/utxo/"):
+		response, err = api.utxo(strings.TrimPrefix(path, "/v2/utxo/"))
+	case strings.HasPrefix(path, "/v2/tx/"):
+		response, err = api.tx(strings.TrimPrefix(path, "/v2/tx/"))
+	default:
+		err = blockbookErrorf(http.StatusNotFound, "unknown endpoint %s", r.URL.Path)
+	}
+	if err != nil {
+		writeBlockbookResponse(w, err.status, apiError{Error: err.message})
+		return
+	}
+	writeBlockbookResponse(w, http.StatusOK, response)
+}
+
+func (api *BlockbookAPI) status() (interface{}, *blockbookError) {
+	api.lock()
+	defer api.unlock()
+	stats, err := api.db.GetNetworkStats()
+	if err != nil {
+		return nil, blockbookErrorf(http.StatusInternalServerError, "failed to get network stats: %v", err)
+	}
+	var status blockbookStatus
+	status.Blockbook.Coin = api.bcInfo.Name
+	status.Blockbook.Host = "rexplorer"
+	status.Blockbook.Version = version.String()
+	status.Blockbook.BestHeight = uint64(stats.BlockHeight)
+	status.Blockbook.LastBlockTime = time.Unix(int64(stats.Timestamp), 0).UTC()
+	// the API is only served once the explorer caught up with the consensus set
+	status.Blockbook.InSync = true
+	status.Backend.Chain = api.bcInfo.NetworkName
+	status.Backend.Blocks = uint64(stats.BlockHeight)
+	status.Backend.Version = api.bcInfo.ChainVersion.String()
+	if bdb, ok := api.db.(BlockDatabase); ok {
+		record, err := bdb.GetBlockRecord(stats.BlockHeight)
+		if err != nil && err != ErrNotFound {
+			return nil, blockbookErrorf(http.StatusInternalServerError, "failed to get block %d: %v", stats.BlockHeight, err)
+		}
+		if err == nil {
+			status.Backend.BestBlockHash = record.ID.String()
+		}
+	}
+	return status, nil
+}
+
+func (api *BlockbookAPI) address(str string, r *http.Request) (interface{}, *blockbookError) {
+	var address types.UnlockHash
+	err := address.LoadString(str)
+	if err != nil {
+		return nil, blockbookErrorf(http.StatusBadRequest, "invalid address %q: %v", str, err)
+	}
+	query := r.URL.Query()
+	page, berr := parseBlockbookInt(query.Get("page"), 1)
+	if berr != nil {
+		return nil, berr
+	}
+	pageSize, berr := parseBlockbookInt(query.Get("pageSize"), blockbookDefaultPageSize)
+	if berr != nil {
+		return nil, berr
+	}
+	if pageSize > blockbookMaxPageSize {
+		pageSize = blockbookMaxPageSize
+	}
+	details := query.Get("details")
+	switch details {
+	case "":
+		details = "txids"
+	case "basic", "txids", "txs":
+	default:
+		return nil, blockbookErrorf(http.StatusBadRequest, "invalid details %q: expected basic, txids or txs", details)
+	}
+
+	api.lock()
+	defer api.unlock()
+	entries, berr := api.addressEntries(address)
+	if berr != nil {
+		return nil, berr
+	}
+	response := blockbookAddress{
+		Page:               page,
+		TotalPages:         (len(entries) + pageSize - 1) / pageSize,
+		ItemsOnPage:        pageSize,
+		Address:            address.String(),
+		UnconfirmedBalance: "0",
+		Txs:                len(entries),
+	}
+	wallet, err := api.db.GetWallet(address)
+	if err != nil && err != ErrNotFound {
+		return nil, blockbookErrorf(http.StatusInternalServerError, "failed to get wallet %s: %v", address.String(), err)
+	}
+	response.Balance = wallet.Balance.Unlocked.Add(wallet.Balance.Locked.Total).String()
+	if !wallet.Balance.Locked.Total.IsZero() {
+		response.Locked = &blockbookLocked{Balance: wallet.Balance.Locked.Total.String()}
+	}
+	totals := AddressTotals{TotalReceived: types.ZeroCurrency, TotalSent: types.ZeroCurrency}
+	if atdb, ok := api.db.(AddressTotalsDatabase); ok {
+		totals, err = atdb.GetAddressTotals(address)
+		if err != nil && err != ErrNotFound {
+			return nil, blockbookErrorf(http.StatusInternalServerError, "failed to get totals of %s: %v", address.String(), err)
+		}
+		if err == ErrNotFound {
+			totals = AddressTotals{TotalReceived: types.ZeroCurrency, TotalSent: types.ZeroCurrency}
+		}
+	} else {
+		for _, entry := range entries {
+			totals.TotalReceived = totals.TotalReceived.Add(entry.received)
+			totals.TotalSent = totals.TotalSent.Add(entry.sent)
+		}
+	}
+	response.TotalReceived = totals.TotalReceived.String()
+	response.TotalSent = totals.TotalSent.String()
+	if details == "basic" {
+		return response, nil
+	}
+
+	start := (page - 1) * pageSize
+	if start > len(entries) {
+		start = len(entries)
+	}
+	end := start + pageSize
+	if end > len(entries) {
+		end = len(entries)
+	}
+	for _, entry := range entries[start:end] {
+		if details == "txids" {
+			txID, berr := api.entryTxID(entry)
+			if berr != nil {
+				return nil, berr
+			}
+			response.TxIDs = append(response.TxIDs, txID)
+			continue
+		}
+		tx, berr := api.entryTx(entry)
+		if berr != nil {
+			return nil, berr
+		}
+		response.Transactions = append(response.Transactions, tx)
+	}
+	return response, nil
+}
+
+func (api *BlockbookAPI) utxo(str string) (interface{}, *blockbookError) {
+	var address types.UnlockHash
+	err := address.LoadString(str)
+	if err != nil {
+		return nil, blockbookErrorf(http.StatusBadRequest, "invalid address %q: %v", str, err)
+	}
+	txdb, ok := api.db.(TransactionDatabase)
+	if !ok {
+		return nil, blockbookErrorf(http.StatusNotImplemented, "the database does not store transaction records")
+	}
+	bdb, ok := api.db.(BlockDatabase)
+	if !ok {
+		return nil, blockbookErrorf(http.StatusNotImplemented, "the database does not store block records")
+	}
+
+	api.lock()
+	defer api.unlock()
+	entries, berr := api.addressEntries(address)
+	if berr != nil {
+		return nil, berr
+	}
+	stats, err := api.db.GetNetworkStats()
+	if err != nil {
+		return nil, blockbookErrorf(http.StatusInternalServerError, "failed to get network stats: %v", err)
+	}
+	utxos := []blockbookUTXO{}
+	addUnspent := func(txID string, vout int, id types.CoinOutputID, height types.BlockHeight, coinbase bool) *blockbookError {
+		info, err := api.db.GetCoinOutput(id)
+		if err != nil {
+			return blockbookErrorf(http.StatusInternalServerError, "failed to get coin output %s: %v", id.String(), err)
+		}
+		if info.State != CoinOutputStateLiquid && info.State != CoinOutputStateLocked {
+			return nil
+		}
+		utxo := blockbookUTXO{
+			TxID:          txID,
+			Vout:          vout,
+			Value:         info.Value.String(),
+			Height:        uint64(height),
+			Confirmations: uint64(stats.BlockHeight-height) + 1,
+			Coinbase:      coinbase,
+		}
+		if info.State == CoinOutputStateLocked {
+			utxo.LockTime = uint64(info.LockValue)
+		}
+		utxos = append(utxos, utxo)
+		return nil
+	}
+	for _, entry := range entries {
+		if entry.received.IsZero() {
+			continue
+		}
+		if entry.txID == nil {
+			record, err := bdb.GetBlockRecord(entry.height)
+			if err != nil {
+				return nil, blockbookErrorf(http.StatusInternalServerError, "failed to get block %d: %v", entry.height, err)
+			}
+			for i, payout := range record.MinerPayouts {
+				if payout.UnlockHash != address {
+					continue
+				}
+				if berr := addUnspent(record.ID.String(), i, payout.ID, entry.height, true); berr != nil {
+					return nil, berr
+				}
+			}
+			continue
+		}
+		record, err := txdb.GetTransactionRecord(*entry.txID)
+		if err != nil {
+			return nil, blockbookErrorf(http.StatusInternalServerError, "failed to get tx %s: %v", entry.txID.String(), err)
+		}
+		for i, co := range record.CoinOutputs {
+			if co.Condition.UnlockHash() != address {
+				continue
+			}
+			if berr := addUnspent(record.ID.String(), i, co.ID, entry.height, false); berr != nil {
+				return nil, berr
+			}
+		}
+	}
+	return utxos, nil
+}
+
+func (api *BlockbookAPI) tx(str string) (interface{}, *blockbookError) {
+	var txID types.TransactionID
+	err := txID.LoadString(str)
+	if err != nil {
+		return nil, blockbookErrorf(http.StatusBadRequest, "invalid txid %q: %v", str, err)
+	}
+	api.lock()
+	defer api.unlock()
+	tx, berr := api.entryTx(blockbookEntry{txID: &txID})
+	if berr == nil || berr.status != http.StatusNotFound {
+		return tx, berr
+	}
+	// the miner payouts of a block are identified by the ID of the block
+	bdb, ok := api.db.(BlockDatabase)
+	if !ok {
+		return nil, berr
+	}
+	var blockID types.BlockID
+	err = (*crypto.Hash)(&blockID).LoadString(str)
+	if err != nil {
+		return nil, blockbookErrorf(http.StatusBadRequest, "invalid txid %q: %v", str, err)
+	}
+	record, err := bdb.GetBlockRecordByID(blockID)
+	if err == ErrNotFound || (err == nil && len(record.MinerPayouts) == 0) {
+		return nil, berr
+	}
+	if err != nil {
+		return nil, blockbookErrorf(http.StatusInternalServerError, "failed to get block %s: %v", str, err)
+	}
+	return api.coinbaseTx(record)
+}
+
+// addressEntries returns the transactions in which the given address sent or received coins,
+// as well as the blocks of which it received miner payouts, the most recent one first.
+func (api *BlockbookAPI) addressEntries(address types.UnlockHash) ([]blockbookEntry, *blockbookError) {
+	ahdb, ok := api.db.(AddressHistoryDatabase)
+	if !ok {
+		return nil, blockbookErrorf(http.StatusNotImplemented, "the database does not store the history of addresses")
+	}
+	mpdb, ok := api.db.(MinerPayoutDatabase)
+	if !ok {
+		return nil, blockbookErrorf(http.StatusNotImplemented, "the database does not store miner payouts")
+	}
+	history, err := ahdb.GetAddressHistory(address)
+	if err != nil {
+		return nil, blockbookErrorf(http.StatusInternalServerError, "failed to get history of %s: %v", address.String(), err)
+	}
+	payouts, err := mpdb.GetMinerPayouts(address)
+	if err != nil {
+		return nil, blockbookErrorf(http.StatusInternalServerError, "failed to get miner payouts of %s: %v", address.String(), err)
+	}
+	entries := make([]blockbookEntry, 0, len(history)+len(payouts))
+	for i := range history {
+		entries = append(entries, blockbookEntry{
+			height:   history[i].BlockHeight,
+			txID:     &history[i].TransactionID,
+			received: history[i].Received,
+			sent:     history[i].Sent,
+		})
+	}
+	// all payouts of a single block are part of the same coinbase transaction
+	received := make(map[types.BlockHeight]types.Currency)
+	for _, payout := range payouts {
+		if _, ok := received[payout.BlockHeight]; !ok {
+			entries = append(entries, blockbookEntry{height: payout.BlockHeight})
+		}
+		received[payout.BlockHeight] = received[payout.BlockHeight].Add(payout.Value)
+	}
+	for i := range entries {
+		if entries[i].txID == nil {
+			entries[i].received = received[entries[i].height]
+			entries[i].sent = types.ZeroCurrency
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].height != entries[j].height {
+			return entries[i].height > entries[j].height
+		}
+		// the miner payouts of a block are listed after its transactions
+		return entries[i].txID != nil && entries[j].txID == nil
+	})
+	return entries, nil
+}
+
+// entryTxID returns the txid of the given entry, the ID of the block for the miner payouts of a block.
+func (api *BlockbookAPI) entryTxID(entry blockbookEntry) (string, *blockbookError) {
+	if entry.txID != nil {
+		return entry.txID.String(), nil
+	}
+	bdb, ok := api.db.(BlockDatabase)
+	if !ok {
+		return "", blockbookErrorf(http.StatusNotImplemented, "the database does not store block records")
+	}
+	record, err := bdb.GetBlockRecord(entry.height)
+	if err != nil {
+		return "", blockbookErrorf(http.StatusInternalServerError, "failed to get block %d: %v", entry.height, err)
+	}
+	return record.ID.String(), nil
+}
+
+// entryTx returns the transaction of the given entry, the coinbase transaction for the miner payouts of a block.
+func (api *BlockbookAPI) entryTx(entry blockbookEntry) (blockbookTx, *blockbookError) {
+	txdb, ok := api.db.(TransactionDatabase)
+	if !ok {
+		return blockbookTx{}, blockbookErrorf(http.StatusNotImplemented, "the database does not store transaction records")
+	}
+	bdb, ok := api.db.(BlockDatabase)
+	if !ok {
+		return blockbookTx{}, blockbookErrorf(http.StatusNotImplemented, "the database does not store block records")
+	}
+	if entry.txID == nil {
+		record, err := bdb.GetBlockRecord(entry.height)
+		if err != nil {
+			return blockbookTx{}, blockbookErrorf(http.StatusInternalServerError, "failed to get block %d: %v", entry.height, err)
+		}
+		return api.coinbaseTx(record)
+	}
+	record, err := txdb.GetTransactionRecord(*entry.txID)
+	switch err {
+	case nil:
+	case ErrNotFound:
+		return blockbookTx{}, blockbookErrorf(http.StatusNotFound, "tx %s not found", entry.txID.String())
+	default:
+		return blockbookTx{}, blockbookErrorf(http.StatusInternalServerError, "failed to get tx %s: %v", entry.txID.String(), err)
+	}
+	block, err := bdb.GetBlockRecord(record.BlockHeight)
+	if err != nil {
+		return blockbookTx{}, blockbookErrorf(http.StatusInternalServerError, "failed to get block %d: %v", record.BlockHeight, err)
+	}
+	tx, berr := api.newTx(record.ID.String(), block)
+	if berr != nil {
+		return blockbookTx{}, berr
+	}
+	tx.Version = int(record.Version)
+	valueIn := types.ZeroCurrency
+	for i, ci := range record.CoinInputs {
+		vin := blockbookVin{
+			N:         i,
+			Addresses: []string{ci.UnlockHash.String()},
+			IsAddress: true,
+			Value:     ci.Value.String(),
+		}
+		txID, vout, berr := api.outpoint(ci.ParentID, txdb, bdb)
+		if berr != nil {
+			return blockbookTx{}, berr
+		}
+		if txID != "" {
+			vin.TxID, vin.Vout = txID, &vout
+		}
+		tx.Vin = append(tx.Vin, vin)
+		valueIn = valueIn.Add(ci.Value)
+	}
+	value := types.ZeroCurrency
+	for i, co := range record.CoinOutputs {
+		vout, berr := api.newVout(i, co.ID, co.Value, co.Condition.UnlockHash())
+		if berr != nil {
+			return blockbookTx{}, berr
+		}
+		tx.Vout = append(tx.Vout, vout)
+		value = value.Add(co.Value)
+	}
+	tx.Value, tx.ValueIn, tx.Fees = value.String(), valueIn.String(), record.Fee.String()
+	return tx, nil
+}
+
+// coinbaseTx returns the coinbase transaction of the miner payouts of the given block, identified by the ID of the block.
+func (api *BlockbookAPI) coinbaseTx(block BlockRecord) (blockbookTx, *blockbookError) {
+	tx, berr := api.newTx(block.ID.String(), block)
+	if berr != nil {
+		return blockbookTx{}, berr
+	}
+	value := types.ZeroCurrency
+	for i, payout := range block.MinerPayouts {
+		vout, berr := api.newVout(i, payout.ID, payout.Value, payout.UnlockHash)
+		if berr != nil {
+			return blockbookTx{}, berr
+		}
+		tx.Vout = append(tx.Vout, vout)
+		value = value.Add(payout.Value)
+	}
+	tx.Value, tx.ValueIn, tx.Fees = value.String(), "0", "0"
+	return tx, nil
+}
+
+// newTx creates a transaction with the given txid, without inputs and outputs, applied in the given block.
+func (api *BlockbookAPI) newTx(txID string, block BlockRecord) (blockbookTx, *blockbookError) {
+	stats, err := api.db.GetNetworkStats()
+	if err != nil {
+		return blockbookTx{}, blockbookErrorf(http.StatusInternalServerError, "failed to get network stats: %v", err)
+	}
+	return blockbookTx{
+		TxID:          txID,
+		Vin:           []blockbookVin{},
+		Vout:          []blockbookVout{},
+		BlockHash:     block.ID.String(),
+		BlockHeight:   uint64(block.Height),
+		Confirmations: uint64(stats.BlockHeight-block.Height) + 1,
+		BlockTime:     int64(block.Timestamp),
+	}, nil
+}
+
+// newVout creates the n-th output of a transaction, creating the coin output with the given ID.
+func (api *BlockbookAPI) newVout(n int, id types.CoinOutputID, value types.Currency, address types.UnlockHash) (blockbookVout, *blockbookError) {
+	info, err := api.db.GetCoinOutput(id)
+	if err != nil {
+		return blockbookVout{}, blockbookErrorf(http.StatusInternalServerError, "failed to get coin output %s: %v", id.String(), err)
+	}
+	vout := blockbookVout{
+		Value:     value.String(),
+		N:         n,
+		Spent:     info.State == CoinOutputStateSpent,
+		Addresses: []string{},
+	}
+	// outputs with a nil condition can be spent by anyone, and thus don't belong to an address
+	if address.Type != types.UnlockTypeNil {
+		vout.Addresses = append(vout.Addresses, address.String())
+		vout.IsAddress = true
+	}
+	return vout, nil
+}
+
+// outpoint returns the txid and vout of the coin output with the given ID,
+// an empty txid if the database doesn't store the provenance of coin outputs.
+func (api *BlockbookAPI) outpoint(id types.CoinOutputID, txdb TransactionDatabase, bdb BlockDatabase) (string, int, *blockbookError) {
+	pdb, ok := api.db.(CoinOutputProvenanceDatabase)
+	if !ok {
+		return "", 0, nil
+	}
+	provenance, err := pdb.GetCoinOutputProvenance(id)
+	if err == ErrNotFound {
+		return "", 0, nil
+	}
+	if err != nil {
+		return "", 0, blockbookErrorf(http.StatusInternalServerError, "failed to get provenance of coin output %s: %v", id.String(), err)
+	}
+	if provenance.TransactionID == nil {
+		block, err := bdb.GetBlockRecord(provenance.BlockHeight)
+		if err != nil {
+			return "", 0, blockbookErrorf(http.StatusInternalServerError, "failed to get block %d: %v", provenance.BlockHeight, err)
+		}
+		for i, payout := range block.MinerPayouts {
+			if payout.ID == id {
+				return block.ID.String(), i, nil
+			}
+		}
+		return "", 0, nil
+	}
+	record, err := txdb.GetTransactionRecord(*provenance.TransactionID)
+	if err != nil {
+		return "", 0, blockbookErrorf(http.StatusInternalServerError, "failed to get tx %s: %v", provenance.TransactionID.String(), err)
+	}
+	for i, co := range record.CoinOutputs {
+		if bytes.Equal(co.ID[:], id[:]) {
+			return record.ID.String(), i, nil
+		}
+	}
+	return "", 0, nil
+}
+
+// parseBlockbookInt parses the given (positive) integer query parameter, returning the given default if it is empty.
+func parseBlockbookInt(str string, def int) (int, *blockbookError) {
+	if str == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(str)
+	if err != nil || n <= 0 {
+		return 0, blockbookErrorf(http.StatusBadRequest, "invalid value %q: expected a positive integer", str)
+	}
+	return n, nil
+}
+
+func (api *BlockbookAPI) lock() {
+	if api.mut != nil {
+		api.mut.Lock()
+	}
+}
+
+func (api *BlockbookAPI) unlock() {
+	if api.mut != nil {
+		api.mut.Unlock()
+	}
+}
+
+func writeBlockbookResponse(w http.ResponseWriter, status int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		status = http.StatusInternalServerError
+		b, _ = json.Marshal(apiError{Error: fmt.Sprintf("failed to JSON-encode response: %v", err)})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(b)
+}
@@ -0,0 +1,95 @@
+package rexplorer
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rivine/rivine/types"
+)
+
+// DefaultBlockCreatorCount is the amount of block creators listed by the creators command, unless specified otherwise.
+const DefaultBlockCreatorCount = 100
+
+type (
+	// BlockCreatorStats records how many blocks were created by a block stake holder,
+	// and the total value of the miner payouts it received for those blocks (block rewards and transaction fees).
+	// The creator of a block is identified by the address receiving its block reward (the first miner payout),
+	// being the address owning the block stake output used to create the block.
+	BlockCreatorStats struct {
+		BlockCount uint64         `json:"blockCount"`
+		Payouts    types.Currency `json:"payouts"`
+	}
+
+	// BlockCreatorEntry is a single block creator of the block creator leaderboard, together with its stats.
+	BlockCreatorEntry struct {
+		Address types.UnlockHash  `json:"address"`
+		Stats   BlockCreatorStats `json:"stats"`
+	}
+)
+
+// blockCreator returns the creator of the given block, as well as the total value of the miner payouts
+// it received for creating it, false is returned for blocks without miner payouts (e.g. the genesis block).
+func blockCreator(block types.Block) (types.UnlockHash, types.Currency, bool) {
+	if len(block.MinerPayouts) == 0 {
+		return types.UnlockHash{}, types.Currency{}, false
+	}
+	creator := block.MinerPayouts[0].UnlockHash
+	payouts := types.ZeroCurrency
+	for _, mp := range block.MinerPayouts {
+		if mp.UnlockHash.Cmp(creator) == 0 {
+			payouts = payouts.Add(mp.Value)
+		}
+	}
+	return creator, payouts, true
+}
+
+// sortBlockCreators orders the given entries by their block count, highest first,
+// ordering entries with an equal block count by their payouts (highest first) and address,
+// such that the order is deterministic.
+func sortBlockCreators(entries []BlockCreatorEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Stats.BlockCount != entries[j].Stats.BlockCount {
+			return entries[i].Stats.BlockCount > entries[j].Stats.BlockCount
+		}
+		if c := entries[i].Stats.Payouts.Cmp(entries[j].Stats.Payouts); c != 0 {
+			return c > 0
+		}
+		return entries[i].Address.Cmp(entries[j].Address) < 0
+	})
+}
+
+// applyBlockCreator credits the given block, applied at the current block height, to the stats of its creator,
+// in case the database supports it.
+func (explorer *Explorer) applyBlockCreator(block types.Block) {
+	bcdb, ok := explorer.db.(BlockCreatorDatabase)
+	if !ok {
+		return
+	}
+	creator, payouts, ok := blockCreator(block)
+	if !ok {
+		return
+	}
+	err := bcdb.ApplyCreatedBlock(creator, payouts)
+	if err != nil {
+		panic(fmt.Sprintf("failed to apply block %d to the stats of block creator %s: %v",
+			explorer.stats.BlockHeight, creator.String(), err))
+	}
+}
+
+// revertBlockCreator withdraws the given block, reverted at the current block height, from the stats of its creator,
+// in case the database supports it.
+func (explorer *Explorer) revertBlockCreator(block types.Block) {
+	bcdb, ok := explorer.db.(BlockCreatorDatabase)
+	if !ok {
+		return
+	}
+	creator, payouts, ok := blockCreator(block)
+	if !ok {
+		return
+	}
+	err := bcdb.RevertCreatedBlock(creator, payouts)
+	if err != nil {
+		panic(fmt.Sprintf("failed to revert block %d from the stats of block creator %s: %v",
+			explorer.stats.BlockHeight, creator.String(), err))
+	}
+}
@@ -0,0 +1,79 @@
+package rexplorer
+
+import (
+	"fmt"
+
+	"github.com/rivine/rivine/types"
+)
+
+type (
+	// BlockRecord records a single block, such that block pages can be rendered from the stored data alone,
+	// without requiring a daemon. Transactions are only referenced by their ID.
+	BlockRecord struct {
+		Height         types.BlockHeight     `json:"height"`
+		ID             types.BlockID         `json:"id"`
+		ParentID       types.BlockID         `json:"parentID"`
+		Timestamp      types.Timestamp       `json:"timestamp"`
+		MinerPayouts   []BlockRecordPayout   `json:"minerPayouts"`
+		TransactionIDs []types.TransactionID `json:"txIDs"`
+		// the total of the miner fees defined by the transactions of the block
+		MinerFees types.Currency `json:"minerFees"`
+	}
+
+	// BlockRecordPayout records a single miner payout of a block, as well as the ID of the coin output it created.
+	BlockRecordPayout struct {
+		ID         types.CoinOutputID `json:"id"`
+		Value      types.Currency     `json:"value"`
+		UnlockHash types.UnlockHash   `json:"unlockhash"`
+	}
+)
+
+// newBlockRecord records the given block, applied at the given height.
+func newBlockRecord(block types.Block, height types.BlockHeight) BlockRecord {
+	record := BlockRecord{
+		Height:         height,
+		ID:             block.ID(),
+		ParentID:       block.ParentID,
+		Timestamp:      block.Timestamp,
+		MinerPayouts:   make([]BlockRecordPayout, 0, len(block.MinerPayouts)),
+		TransactionIDs: make([]types.TransactionID, 0, len(block.Transactions)),
+	}
+	for i, mp := range block.MinerPayouts {
+		record.MinerPayouts = append(record.MinerPayouts, BlockRecordPayout{
+			ID:         block.MinerPayoutID(uint64(i)),
+			Value:      mp.Value,
+			UnlockHash: mp.UnlockHash,
+		})
+	}
+	for _, tx := range block.Transactions {
+		record.TransactionIDs = append(record.TransactionIDs, tx.ID())
+		record.MinerFees = record.MinerFees.Add(transactionFee(tx))
+	}
+	return record
+}
+
+// storeBlockRecord stores the record of the given block, applied at the current block height,
+// in case the database supports it.
+func (explorer *Explorer) storeBlockRecord(block types.Block) {
+	bdb, ok := explorer.db.(BlockDatabase)
+	if !ok {
+		return
+	}
+	err := bdb.SetBlockRecord(newBlockRecord(block, explorer.stats.BlockHeight))
+	if err != nil {
+		panic(fmt.Sprintf("failed to set record of block %d: %v", explorer.stats.BlockHeight, err))
+	}
+}
+
+// revertBlockRecord deletes the record of the given block, reverted at the current block height,
+// in case the database supports it.
+func (explorer *Explorer) revertBlockRecord(block types.Block) {
+	bdb, ok := explorer.db.(BlockDatabase)
+	if !ok {
+		return
+	}
+	err := bdb.RevertBlockRecord(explorer.stats.BlockHeight, block.ID())
+	if err != nil {
+		panic(fmt.Sprintf("failed to revert record of block %d: %v", explorer.stats.BlockHeight, err))
+	}
+}
@@ -0,0 +1,132 @@
+package rexplorer
+
+import (
+	"fmt"
+
+	"github.com/rivine/rivine/types"
+)
+
+type (
+	// DatabaseBlockStakeOutput is used to store all spent/unspent block stake outputs
+	// in the custom CSV format (used internally only), just like a DatabaseCoinOutput.
+	DatabaseBlockStakeOutput struct {
+		UnlockHash   types.UnlockHash
+		Value        types.Currency
+		State        CoinOutputState
+		LockType     LockType
+		LockValue    LockValue
+		RawCondition types.ByteSlice
+	}
+
+	// BlockStakeOutputInfo collects all stored data of a single block stake output,
+	// exposing the condition both as a decoded condition tree and as the raw (binary-encoded) bytes.
+	// Just like a coin output, its state is either liquid (unspent), locked or spent.
+	BlockStakeOutputInfo struct {
+		ID           types.BlockStakeOutputID   `json:"id"`
+		UnlockHash   types.UnlockHash           `json:"unlockhash"`
+		Value        types.Currency             `json:"value"`
+		State        CoinOutputState            `json:"state"`
+		LockType     LockType                   `json:"lockType"`
+		LockValue    LockValue                  `json:"lockValue"`
+		Condition    types.UnlockConditionProxy `json:"condition"`
+		RawCondition types.ByteSlice            `json:"rawCondition"`
+		// UnknownCondition is true if the type of the condition isn't defined by Rivine (see CoinOutputInfo).
+		UnknownCondition bool `json:"unknownCondition,omitempty"`
+	}
+)
+
+// String implements Stringer.String
+func (bso DatabaseBlockStakeOutput) String() string {
+	return FormatStringers(csvSeperator, bso.State, bso.UnlockHash, bso.Value, bso.LockType, bso.LockValue, bso.RawCondition)
+}
+
+// LoadString implements StringLoader.LoadString
+func (bso *DatabaseBlockStakeOutput) LoadString(str string) error {
+	return ParseStringLoaders(str, csvSeperator, &bso.State, &bso.UnlockHash, &bso.Value, &bso.LockType, &bso.LockValue, &bso.RawCondition)
+}
+
+// decodeCondition decodes the raw condition of the block stake output,
+// flagging the block stake output as protected by an unknown condition.
+func (info *BlockStakeOutputInfo) decodeCondition() (err error) {
+	info.Condition, info.UnknownCondition, err = decodeCondition(info.RawCondition)
+	return err
+}
+
+// applyBlockStakes spends the block stake outputs consumed by the block stake inputs of the given transaction,
+// and adds the block stake outputs it created, in case the database supports it.
+// Block stake outputs which aren't stored were created prior to the dataset indexing them, and are skipped.
+func (explorer *Explorer) applyBlockStakes(tx types.Transaction) {
+	bsdb, ok := explorer.db.(BlockStakeDatabase)
+	if !ok {
+		return
+	}
+	for _, bsi := range tx.BlockStakeInputs {
+		_, _, err := bsdb.SpendBlockStakeOutput(bsi.ParentID)
+		if err != nil && err != ErrNotFound {
+			panic(fmt.Sprintf("failed to spend block stake output %s: %v", bsi.ParentID.String(), err))
+		}
+	}
+	for i, bso := range tx.BlockStakeOutputs {
+		id := tx.BlockStakeOutputID(uint64(i))
+		var err error
+		if lt, lockValue, locked := explorer.outputLock(bso.Condition); locked {
+			err = bsdb.AddLockedBlockStakeOutput(id, bso, lt, lockValue)
+		} else {
+			err = bsdb.AddBlockStakeOutput(id, bso)
+		}
+		if err != nil {
+			panic(fmt.Sprintf("failed to add block stake output %s from %s: %v",
+				id.String(), bso.Condition.UnlockHash().String(), err))
+		}
+	}
+}
+
+// revertBlockStakes reverts the block stake inputs and outputs of the given transaction,
+// in case the database supports it, skipping the block stake outputs which aren't stored (see applyBlockStakes).
+func (explorer *Explorer) revertBlockStakes(tx types.Transaction) {
+	bsdb, ok := explorer.db.(BlockStakeDatabase)
+	if !ok {
+		return
+	}
+	for _, bsi := range tx.BlockStakeInputs {
+		_, _, err := bsdb.RevertBlockStakeInput(bsi.ParentID)
+		if err != nil && err != ErrNotFound {
+			panic(fmt.Sprintf("failed to revert block stake input %s: %v", bsi.ParentID.String(), err))
+		}
+	}
+	for i := range tx.BlockStakeOutputs {
+		id := tx.BlockStakeOutputID(uint64(i))
+		_, err := bsdb.RevertBlockStakeOutput(id)
+		if err != nil && err != ErrNotFound {
+			panic(fmt.Sprintf("failed to revert block stake output %s: %v", id.String(), err))
+		}
+	}
+}
+
+// applyBlockStakeOutputLocks unlocks the locked block stake outputs which can be spent
+// as of the current block height and time, in case the database supports it.
+func (explorer *Explorer) applyBlockStakeOutputLocks() {
+	bsdb, ok := explorer.db.(BlockStakeDatabase)
+	if !ok {
+		return
+	}
+	err := bsdb.ApplyBlockStakeOutputLocks(explorer.stats.BlockHeight, explorer.stats.Timestamp)
+	if err != nil {
+		panic(fmt.Sprintf("failed to unlock block stake outputs at height=%d and time=%d: %v",
+			explorer.stats.BlockHeight, explorer.stats.Timestamp, err))
+	}
+}
+
+// revertBlockStakeOutputLocks locks the unlocked block stake outputs which cannot be spent (yet)
+// as of the current block height and time, in case the database supports it.
+func (explorer *Explorer) revertBlockStakeOutputLocks() {
+	bsdb, ok := explorer.db.(BlockStakeDatabase)
+	if !ok {
+		return
+	}
+	err := bsdb.RevertBlockStakeOutputLocks(explorer.stats.BlockHeight, explorer.stats.Timestamp)
+	if err != nil {
+		panic(fmt.Sprintf("failed to lock block stake outputs at height=%d and time=%d: %v",
+			explorer.stats.BlockHeight, explorer.stats.Timestamp, err))
+	}
+}
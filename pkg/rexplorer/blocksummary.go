@@ -0,0 +1,145 @@
+package rexplorer
+
+import (
+	"fmt"
+
+	"github.com/rivine/rivine/types"
+)
+
+type (
+	// BlockSummary summarizes the coin outputs created by a single block,
+	// such that anomalies (e.g. sudden bursts of dust creation) can be detected from the summaries alone.
+	// It summarizes the transactions of the block as well, such that a block list can be rendered
+	// without decoding the transactions: their amount, the value they transferred (see DailyStats) and the fees they paid,
+	// together with the total value of the miner payouts and the creator of the block (see BlockCreatorStats),
+	// the nil address if the block has no miner payouts.
+	// The difficulty of the block is the difficulty of the block stake target it had to meet,
+	// being the root depth of the chain divided by that target, such that it can be charted per block.
+	BlockSummary struct {
+		Height           types.BlockHeight    `json:"height"`
+		ID               types.BlockID        `json:"id"`
+		Timestamp        types.Timestamp      `json:"timestamp"`
+		OutputCount      uint64               `json:"outputCount"`
+		OutputValue      types.Currency       `json:"outputValue"`
+		Histogram        OutputValueHistogram `json:"histogram"`
+		TransactionCount uint64               `json:"txCount"`
+		ValueTransferred types.Currency       `json:"valueTransferred"`
+		Fees             types.Currency       `json:"fees"`
+		MinerPayouts     types.Currency       `json:"minerPayouts"`
+		Creator          types.UnlockHash     `json:"creator"`
+		Difficulty       types.Difficulty     `json:"difficulty"`
+	}
+
+	// OutputValueHistogram counts the coin outputs per value range,
+	// see OutputValueHistogramLabels for the value range of each bucket.
+	OutputValueHistogram [6]uint64
+)
+
+// OutputValueHistogramLabels returns the (human-readable) value range of each bucket of an OutputValueHistogram,
+// expressed in coins.
+func OutputValueHistogramLabels() []string {
+	return []string{"<0.001", "<1", "<100", "<10k", "<1M", ">=1M"}
+}
+
+// outputValueHistogramBucket returns the bucket of an OutputValueHistogram the given value belongs to.
+func outputValueHistogramBucket(value, oneCoin types.Currency) int {
+	bounds := []types.Currency{
+		oneCoin.Div64(1000),
+		oneCoin,
+		oneCoin.Mul64(100),
+		oneCoin.Mul64(10000),
+		oneCoin.Mul64(1000000),
+	}
+	for bucket, bound := range bounds {
+		if value.Cmp(bound) < 0 {
+			return bucket
+		}
+	}
+	return len(bounds)
+}
+
+// newBlockSummary summarizes all coin outputs (miner payouts included) and transactions of the given block,
+// which had to meet the given difficulty.
+func newBlockSummary(block types.Block, height types.BlockHeight, difficulty types.Difficulty, oneCoin types.Currency) BlockSummary {
+	summary := BlockSummary{
+		Height:           height,
+		ID:               block.ID(),
+		Timestamp:        block.Timestamp,
+		TransactionCount: uint64(len(block.Transactions)),
+		ValueTransferred: types.ZeroCurrency,
+		Fees:             types.ZeroCurrency,
+		MinerPayouts:     types.ZeroCurrency,
+		Difficulty:       difficulty,
+	}
+	addOutput := func(value types.Currency) {
+		summary.OutputCount++
+		summary.OutputValue = summary.OutputValue.Add(value)
+		summary.Histogram[outputValueHistogramBucket(value, oneCoin)]++
+	}
+	for _, mp := range block.MinerPayouts {
+		addOutput(mp.Value)
+		summary.MinerPayouts = summary.MinerPayouts.Add(mp.Value)
+	}
+	for _, tx := range block.Transactions {
+		for _, co := range tx.CoinOutputs {
+			addOutput(co.Value)
+		}
+		summary.ValueTransferred = summary.ValueTransferred.Add(transactionValue(tx))
+		summary.Fees = summary.Fees.Add(transactionFee(tx))
+	}
+	if creator, _, ok := blockCreator(block); ok {
+		summary.Creator = creator
+	}
+	return summary
+}
+
+// blockDifficulty returns the difficulty the given block had to meet,
+// being the difficulty of the target defined by the consensus set for the children of its parent,
+// or the difficulty of the root target for the genesis block.
+func (explorer *Explorer) blockDifficulty(block types.Block) types.Difficulty {
+	if block.ParentID == (types.BlockID{}) {
+		return explorer.chainCts.RootTarget().Difficulty(explorer.chainCts.RootDepth)
+	}
+	target, ok := explorer.cs.ChildTarget(block.ParentID)
+	if !ok {
+		panic(fmt.Sprintf("failed to get target of block %s: parent %s is unknown to the consensus set",
+			block.ID().String(), block.ParentID.String()))
+	}
+	return target.Difficulty(explorer.chainCts.RootDepth)
+}
+
+// childDifficulty returns the difficulty the children of the given (applied) block have to meet,
+// being the difficulty of the target defined by the consensus set for them.
+func (explorer *Explorer) childDifficulty(block types.Block) types.Difficulty {
+	target, ok := explorer.cs.ChildTarget(block.ID())
+	if !ok {
+		panic(fmt.Sprintf("failed to get target of the children of block %s: block is unknown to the consensus set",
+			block.ID().String()))
+	}
+	return target.Difficulty(explorer.chainCts.RootDepth)
+}
+
+// parentTimestamp returns the timestamp of the parent of the given block,
+// or 0 for the genesis block, being the timestamp of the network stats prior to applying it.
+func (explorer *Explorer) parentTimestamp(block types.Block) types.Timestamp {
+	if block.ParentID == (types.BlockID{}) {
+		return 0
+	}
+	parent, ok := explorer.cs.FindParentBlock(block, 1)
+	if !ok {
+		panic(fmt.Sprintf("failed to get parent of block %s: parent %s is unknown to the consensus set",
+			block.ID().String(), block.ParentID.String()))
+	}
+	return parent.Timestamp
+}
+
+// difficultyLoader loads a difficulty from its (base 10) string representation,
+// such that it can be loaded as a StringLoader.
+type difficultyLoader struct {
+	*types.Difficulty
+}
+
+// LoadString implements StringLoader.LoadString
+func (dl difficultyLoader) LoadString(str string) error {
+	return dl.UnmarshalJSON([]byte(str))
+}
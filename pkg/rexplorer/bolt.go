@@ -0,0 +1,1077 @@
+package rexplorer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	bolt "github.com/rivine/bbolt"
+	"github.com/rivine/rivine/types"
+)
+
+type (
+	// BoltDatabase is an embedded Database implementation, using BoltDB (bbolt),
+	// storing all wallets and coin outputs in buckets of a single file.
+	//
+	// As it implements TransactionalDatabase, all changes of a consensus change are applied
+	// within a single (write) transaction, such that a consensus change is either applied completely or not at all.
+	//
+	// Only one process can open the database file at a time, meaning that the database
+	// cannot be read by other processes (including the commands of rexplorer itself) while the daemon is running.
+	//
+	// Following buckets are used by this Database implementation:
+	//
+	//	  meta							(encoded) internal state, network info, chain parameters, stats and health
+	//	  wallets						address -> (encoded) Wallet, for all unique addresses
+	//	  coinoutputs					coin output ID -> (CSV) DatabaseCoinOutput, for all coin outputs
+	//	  locks.height.locked			lock height+ID of all coin outputs locked by block height
+	//	  locks.height.unlocked			lock height+ID of all coin outputs unlocked by block height
+	//	  locks.time.locked				lock timestamp+ID of all coin outputs locked by time
+	//	  locks.time.unlocked			lock timestamp+ID of all coin outputs unlocked by time
+	//	  counterparties				address+counterparty -> (encoded) AddressCounterparty
+	//	  flows							total|<YYYY-MM-DD> -> (encoded) WalletGroupFlows
+	//	  multisig.spends				address+coinOutputID -> (encoded) signers of a spent multisig coin output
+	//	  multisig.signers				address+signer -> (encoded) MultisigSignerStats
+	//	  blocksummaries				block height -> (encoded) BlockSummary
+	//
+	// Addresses and IDs are used as keys in their Rivine-defined hex-encoded string format.
+	// Contrary to the RedisDatabase, all counterparties of an address are stored.
+	BoltDatabase struct {
+		db *bolt.DB
+		// the write transaction of the consensus change currently being applied, if any
+		tx *bolt.Tx
+
+		// encoder used to encode all (structured) values
+		encoder Encoder
+
+		blockFrequency LockValue
+
+		// cached version of the chain stats
+		networkBlockHeight types.BlockHeight
+		networkTime        types.Timestamp
+	}
+)
+
+var (
+	_ TransactionalDatabase = (*BoltDatabase)(nil)
+)
+
+var (
+	boltBucketMeta                = []byte("meta")
+	boltBucketWallets             = []byte("wallets")
+	boltBucketCoinOutputs         = []byte("coinoutputs")
+	boltBucketHeightLocksLocked   = []byte("locks.height.locked")
+	boltBucketHeightLocksUnlocked = []byte("locks.height.unlocked")
+	boltBucketTimeLocksLocked     = []byte("locks.time.locked")
+	boltBucketTimeLocksUnlocked   = []byte("locks.time.unlocked")
+	boltBucketCounterparties      = []byte("counterparties")
+	boltBucketFlows               = []byte("flows")
+	boltBucketMultisigSpends      = []byte("multisig.spends")
+	boltBucketMultisigSigners     = []byte("multisig.signers")
+	boltBucketBlockSummaries      = []byte("blocksummaries")
+
+	boltKeyState   = []byte("state")
+	boltKeyNetwork = []byte("network")
+	boltKeyStats   = []byte("stats")
+	boltKeyHealth  = []byte("health")
+	boltKeyParams  = []byte("chainparams")
+	boltKeyAliases = []byte("aliases")
+)
+
+func init() {
+	RegisterDatabaseDriver("bolt", func(cfg DatabaseConfig) (Database, error) {
+		path := cfg.Address
+		if path == "" {
+			path = "rexplorer.db"
+		}
+		return NewBoltDatabase(path, cfg.BlockchainInfo, cfg.ChainConstants)
+	})
+}
+
+// NewBoltDatabase creates (or opens) a BoltDB Database, stored in a file at the given path,
+// see BoltDatabase for more information.
+func NewBoltDatabase(path string, bcInfo types.BlockchainInfo, chainCts types.ChainConstants) (*BoltDatabase, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bolt database at %s: %v", path, err)
+	}
+	bdb := BoltDatabase{
+		db:             db,
+		encoder:        jsonEncoder{},
+		blockFrequency: LockValue(chainCts.BlockFrequency),
+	}
+	// ensure all buckets exist, and ensure the network info is as expected (or register if this is a fresh db)
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{
+			boltBucketMeta, boltBucketWallets, boltBucketCoinOutputs,
+			boltBucketHeightLocksLocked, boltBucketHeightLocksUnlocked,
+			boltBucketTimeLocksLocked, boltBucketTimeLocksUnlocked,
+			boltBucketCounterparties, boltBucketFlows,
+			boltBucketMultisigSpends, boltBucketMultisigSigners, boltBucketBlockSummaries,
+		} {
+			_, err := tx.CreateBucketIfNotExists(name)
+			if err != nil {
+				return fmt.Errorf("failed to create bucket %s: %v", name, err)
+			}
+		}
+		return bdb.registerOrValidateNetworkInfo(tx, bcInfo)
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &bdb, nil
+}
+
+// Begin implements TransactionalDatabase.Begin
+func (bdb *BoltDatabase) Begin() error {
+	if bdb.tx != nil {
+		return errors.New("bolt: a transaction is already in progress")
+	}
+	tx, err := bdb.db.Begin(true)
+	if err != nil {
+		return fmt.Errorf("bolt: failed to begin transaction: %v", err)
+	}
+	bdb.tx = tx
+	return nil
+}
+
+// Commit implements TransactionalDatabase.Commit
+func (bdb *BoltDatabase) Commit() error {
+	if bdb.tx == nil {
+		return errors.New("bolt: no transaction in progress")
+	}
+	err := bdb.tx.Commit()
+	bdb.tx = nil
+	if err != nil {
+		return fmt.Errorf("bolt: failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// Close implements Database.Close
+//
+// rolls back the transaction in progress (if any), and closes the database file
+func (bdb *BoltDatabase) Close() error {
+	if bdb.tx != nil {
+		bdb.tx.Rollback()
+		bdb.tx = nil
+	}
+	err := bdb.db.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close bolt database: %v", err)
+	}
+	return nil
+}
+
+// update applies the given function within the transaction in progress,
+// or within its own write transaction should no transaction be in progress.
+func (bdb *BoltDatabase) update(fn func(tx *bolt.Tx) error) error {
+	if bdb.tx != nil {
+		return fn(bdb.tx)
+	}
+	return bdb.db.Update(fn)
+}
+
+// view applies the given function within the transaction in progress,
+// or within its own read-only transaction should no transaction be in progress.
+func (bdb *BoltDatabase) view(fn func(tx *bolt.Tx) error) error {
+	if bdb.tx != nil {
+		return fn(bdb.tx)
+	}
+	return bdb.db.View(fn)
+}
+
+// getValue gets and decodes a value from the given bucket,
+// returning ErrNotFound if it isn't stored.
+func (bdb *BoltDatabase) getValue(tx *bolt.Tx, bucket, key []byte, v interface{}) error {
+	b := tx.Bucket(bucket).Get(key)
+	if b == nil {
+		return ErrNotFound
+	}
+	return bdb.encoder.Unmarshal(b, v)
+}
+
+// putValue encodes and stores a value in the given bucket.
+func (bdb *BoltDatabase) putValue(tx *bolt.Tx, bucket, key []byte, v interface{}) error {
+	return tx.Bucket(bucket).Put(key, MustMarshal(bdb.encoder, v))
+}
+
+// registerOrValidateNetworkInfo registeres the network name and chain name if it doesn't exist yet,
+// otherwise it ensures that the returned network info matches the expected network info.
+func (bdb *BoltDatabase) registerOrValidateNetworkInfo(tx *bolt.Tx, bcInfo types.BlockchainInfo) error {
+	networkInfo := NetworkInfo{
+		ChainName:   bcInfo.Name,
+		NetworkName: bcInfo.NetworkName,
+	}
+	var receivedNetworkInfo NetworkInfo
+	switch err := bdb.getValue(tx, boltBucketMeta, boltKeyNetwork, &receivedNetworkInfo); err {
+	case nil:
+	case ErrNotFound:
+		err = bdb.putValue(tx, boltBucketMeta, boltKeyNetwork, networkInfo)
+		if err != nil {
+			return fmt.Errorf("failed to register network info: %v", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("failed to validate network info: %v", err)
+	}
+	if receivedNetworkInfo != networkInfo {
+		return fmt.Errorf("cannot store data for chain %s/%s: db has already data for chain %s/%s stored",
+			networkInfo.ChainName, networkInfo.NetworkName,
+			receivedNetworkInfo.ChainName, receivedNetworkInfo.NetworkName)
+	}
+	return nil
+}
+
+// GetExplorerState implements Database.GetExplorerState
+func (bdb *BoltDatabase) GetExplorerState() (state ExplorerState, err error) {
+	err = bdb.view(func(tx *bolt.Tx) error {
+		return bdb.getValue(tx, boltBucketMeta, boltKeyState, &state)
+	})
+	switch err {
+	case nil:
+		return state, nil
+	case ErrNotFound:
+		// default to fresh explorer state if not stored yet
+		return NewExplorerState(), nil
+	default:
+		return ExplorerState{}, err
+	}
+}
+
+// SetExplorerState implements Database.SetExplorerState
+func (bdb *BoltDatabase) SetExplorerState(state ExplorerState) error {
+	return bdb.update(func(tx *bolt.Tx) error {
+		return bdb.putValue(tx, boltBucketMeta, boltKeyState, state)
+	})
+}
+
+// GetNetworkStats implements Database.GetNetworkStats
+func (bdb *BoltDatabase) GetNetworkStats() (stats NetworkStats, err error) {
+	err = bdb.view(func(tx *bolt.Tx) error {
+		return bdb.getValue(tx, boltBucketMeta, boltKeyStats, &stats)
+	})
+	switch err {
+	case nil:
+		err = upgradeNetworkStats(&stats)
+		if err != nil {
+			return NetworkStats{}, fmt.Errorf("bolt: failed to upgrade network stats: %v", err)
+		}
+	case ErrNotFound:
+		// default to fresh network stats if not stored yet
+		stats = NewNetworkStats()
+	default:
+		return NetworkStats{}, err
+	}
+	bdb.networkTime, bdb.networkBlockHeight = stats.Timestamp, stats.BlockHeight
+	return stats, nil
+}
+
+// SetNetworkStats implements Database.SetNetworkStats
+func (bdb *BoltDatabase) SetNetworkStats(stats NetworkStats) error {
+	err := bdb.update(func(tx *bolt.Tx) error {
+		return bdb.putValue(tx, boltBucketMeta, boltKeyStats, stats)
+	})
+	if err != nil {
+		return err
+	}
+	bdb.networkTime, bdb.networkBlockHeight = stats.Timestamp, stats.BlockHeight
+	return nil
+}
+
+// SetChainHealth implements Database.SetChainHealth
+func (bdb *BoltDatabase) SetChainHealth(health ChainHealth) error {
+	return bdb.update(func(tx *bolt.Tx) error {
+		return bdb.putValue(tx, boltBucketMeta, boltKeyHealth, health)
+	})
+}
+
+// GetChainParametersHistory implements Database.GetChainParametersHistory
+func (bdb *BoltDatabase) GetChainParametersHistory() (history []ChainParametersRecord, err error) {
+	err = bdb.view(func(tx *bolt.Tx) error {
+		return bdb.getValue(tx, boltBucketMeta, boltKeyParams, &history)
+	})
+	switch err {
+	case nil, ErrNotFound:
+		// no history is stored yet for a fresh database
+		return history, nil
+	default:
+		return nil, err
+	}
+}
+
+// SetChainParametersHistory implements Database.SetChainParametersHistory
+func (bdb *BoltDatabase) SetChainParametersHistory(history []ChainParametersRecord) error {
+	return bdb.update(func(tx *bolt.Tx) error {
+		return bdb.putValue(tx, boltBucketMeta, boltKeyParams, history)
+	})
+}
+
+// GetAddressAliases implements Database.GetAddressAliases
+func (bdb *BoltDatabase) GetAddressAliases() (aliases []AddressAlias, err error) {
+	err = bdb.view(func(tx *bolt.Tx) error {
+		return bdb.getValue(tx, boltBucketMeta, boltKeyAliases, &aliases)
+	})
+	switch err {
+	case nil, ErrNotFound:
+		// no aliases are recorded yet
+		return aliases, nil
+	default:
+		return nil, err
+	}
+}
+
+// SetAddressAliases implements Database.SetAddressAliases
+func (bdb *BoltDatabase) SetAddressAliases(aliases []AddressAlias) error {
+	return bdb.update(func(tx *bolt.Tx) error {
+		return bdb.putValue(tx, boltBucketMeta, boltKeyAliases, aliases)
+	})
+}
+
+// getCoinOutput gets a stored coin output, returning ErrNotFound if it isn't stored.
+func (bdb *BoltDatabase) getCoinOutput(tx *bolt.Tx, id types.CoinOutputID) (co DatabaseCoinOutput, err error) {
+	b := tx.Bucket(boltBucketCoinOutputs).Get([]byte(id.String()))
+	if b == nil {
+		return DatabaseCoinOutput{}, ErrNotFound
+	}
+	err = co.LoadString(string(b))
+	return
+}
+
+// putCoinOutput stores a coin output.
+func (bdb *BoltDatabase) putCoinOutput(tx *bolt.Tx, id types.CoinOutputID, co DatabaseCoinOutput) error {
+	return tx.Bucket(boltBucketCoinOutputs).Put([]byte(id.String()), []byte(co.String()))
+}
+
+// updateWallet updates the wallet of the given address using the given update function,
+// creating the wallet if it doesn't exist yet.
+func (bdb *BoltDatabase) updateWallet(tx *bolt.Tx, address types.UnlockHash, update func(*Wallet) error) error {
+	key := []byte(address.String())
+	var wallet Wallet
+	err := bdb.getValue(tx, boltBucketWallets, key, &wallet)
+	if err != nil && err != ErrNotFound {
+		return fmt.Errorf("bolt: failed to get wallet for %s: %v", address.String(), err)
+	}
+	err = update(&wallet)
+	if err != nil {
+		return fmt.Errorf("bolt: failed to update wallet for %s: %v", address.String(), err)
+	}
+	wallet.Balance.Locked.updateHorizons(bdb.networkTime)
+	err = bdb.putValue(tx, boltBucketWallets, key, wallet)
+	if err != nil {
+		return fmt.Errorf("bolt: failed to set wallet for %s: %v", address.String(), err)
+	}
+	return nil
+}
+
+// AddCoinOutput implements Database.AddCoinOutput
+func (bdb *BoltDatabase) AddCoinOutput(id types.CoinOutputID, co CoinOutput) error {
+	uh := co.Condition.UnlockHash()
+	return bdb.update(func(tx *bolt.Tx) error {
+		err := bdb.putCoinOutput(tx, id, DatabaseCoinOutput{
+			UnlockHash:   uh,
+			CoinValue:    co.Value,
+			State:        CoinOutputStateLiquid,
+			LockType:     LockTypeNone,
+			LockValue:    0,
+			Description:  co.Description,
+			RawCondition: EncodeCondition(co.Condition),
+		})
+		if err != nil {
+			return fmt.Errorf("bolt: failed to add coin output %s: %v", id.String(), err)
+		}
+		return bdb.updateWallet(tx, uh, func(wallet *Wallet) error {
+			wallet.Balance.Unlocked = wallet.Balance.Unlocked.Add(co.Value)
+			return nil
+		})
+	})
+}
+
+// AddLockedCoinOutput implements Database.AddLockedCoinOutput
+func (bdb *BoltDatabase) AddLockedCoinOutput(id types.CoinOutputID, co CoinOutput, lt LockType, lockValue LockValue) error {
+	uh := co.Condition.UnlockHash()
+	return bdb.update(func(tx *bolt.Tx) error {
+		err := bdb.putCoinOutput(tx, id, DatabaseCoinOutput{
+			UnlockHash:   uh,
+			CoinValue:    co.Value,
+			State:        CoinOutputStateLocked,
+			LockType:     lt,
+			LockValue:    lockValue,
+			Description:  co.Description,
+			RawCondition: EncodeCondition(co.Condition),
+		})
+		if err != nil {
+			return fmt.Errorf("bolt: failed to add coin output %s: %v", id.String(), err)
+		}
+		lockedBucket, _ := boltLockBuckets(tx, lt)
+		err = lockedBucket.Put(boltLockKey(lockValue, id), nil)
+		if err != nil {
+			return fmt.Errorf("bolt: failed to add lock of coin output %s: %v", id.String(), err)
+		}
+		return bdb.updateWallet(tx, uh, func(wallet *Wallet) error {
+			return wallet.Balance.Locked.AddLockedCoinOutput(id, WalletLockedOutput{
+				Amount:      co.Value,
+				LockedUntil: bdb.lockValueAsLockTime(lt, lockValue),
+				Description: co.Description,
+				Reason:      CoinOutputLockReason(lt, co.Description),
+			})
+		})
+	})
+}
+
+// SpendCoinOutput implements Database.SpendCoinOutput
+func (bdb *BoltDatabase) SpendCoinOutput(id types.CoinOutputID) (uh types.UnlockHash, value types.Currency, err error) {
+	err = bdb.update(func(tx *bolt.Tx) (err error) {
+		uh, value, err = bdb.updateCoinOutputState(tx, id, CoinOutputStateLiquid, CoinOutputStateSpent)
+		if err != nil {
+			return fmt.Errorf("bolt: failed to spend coin output: %v", err)
+		}
+		return bdb.updateWallet(tx, uh, func(wallet *Wallet) error {
+			wallet.Balance.Unlocked = wallet.Balance.Unlocked.Sub(value)
+			return nil
+		})
+	})
+	return
+}
+
+// RevertCoinInput implements Database.RevertCoinInput
+// more or less a reverse process of SpendCoinOutput
+func (bdb *BoltDatabase) RevertCoinInput(id types.CoinOutputID) (uh types.UnlockHash, value types.Currency, err error) {
+	err = bdb.update(func(tx *bolt.Tx) (err error) {
+		uh, value, err = bdb.updateCoinOutputState(tx, id, CoinOutputStateSpent, CoinOutputStateLiquid)
+		if err != nil {
+			return fmt.Errorf("bolt: failed to revert coin input: %v", err)
+		}
+		return bdb.updateWallet(tx, uh, func(wallet *Wallet) error {
+			wallet.Balance.Unlocked = wallet.Balance.Unlocked.Add(value)
+			return nil
+		})
+	})
+	return
+}
+
+// updateCoinOutputState updates the state of a coin output,
+// returning an error in case the coin output isn't in the expected state.
+func (bdb *BoltDatabase) updateCoinOutputState(tx *bolt.Tx, id types.CoinOutputID, from, to CoinOutputState) (types.UnlockHash, types.Currency, error) {
+	co, err := bdb.getCoinOutput(tx, id)
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf("cannot get coin output %s: %v", id.String(), err)
+	}
+	if co.State != from {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf(
+			"cannot update coin output %s: unexpected state %d", id.String(), co.State)
+	}
+	co.State = to
+	err = bdb.putCoinOutput(tx, id, co)
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf("cannot update coin output %s: %v", id.String(), err)
+	}
+	return co.UnlockHash, co.CoinValue, nil
+}
+
+// RevertCoinOutput implements Database.RevertCoinOutput
+func (bdb *BoltDatabase) RevertCoinOutput(id types.CoinOutputID) (state CoinOutputState, err error) {
+	err = bdb.update(func(tx *bolt.Tx) error {
+		co, err := bdb.getCoinOutput(tx, id)
+		if err != nil {
+			return fmt.Errorf("bolt: failed to revert coin output: cannot get coin output %s: %v", id.String(), err)
+		}
+		err = tx.Bucket(boltBucketCoinOutputs).Delete([]byte(id.String()))
+		if err != nil {
+			return fmt.Errorf("bolt: failed to revert coin output: cannot drop coin output %s: %v", id.String(), err)
+		}
+		// always remove lock properties if a lock is used, no matter the state
+		if co.LockType != LockTypeNone {
+			key := boltLockKey(co.LockValue, id)
+			lockedBucket, unlockedBucket := boltLockBuckets(tx, co.LockType)
+			if err = lockedBucket.Delete(key); err == nil {
+				err = unlockedBucket.Delete(key)
+			}
+			if err != nil {
+				return fmt.Errorf("bolt: failed to revert coin output: cannot drop lock of coin output %s: %v", id.String(), err)
+			}
+		}
+		state = co.State
+		switch co.State {
+		case CoinOutputStateLiquid:
+			return bdb.updateWallet(tx, co.UnlockHash, func(wallet *Wallet) error {
+				wallet.Balance.Unlocked = wallet.Balance.Unlocked.Sub(co.CoinValue)
+				return nil
+			})
+		case CoinOutputStateLocked:
+			return bdb.updateWallet(tx, co.UnlockHash, func(wallet *Wallet) error {
+				return wallet.Balance.Locked.SubLockedCoinOutput(id)
+			})
+		default:
+			return nil
+		}
+	})
+	if err != nil {
+		return CoinOutputStateNil, err
+	}
+	return state, nil
+}
+
+// ApplyCoinOutputLocks implements Database.ApplyCoinOutputLocks
+func (bdb *BoltDatabase) ApplyCoinOutputLocks(height types.BlockHeight, time types.Timestamp) (n uint64, coins types.Currency, err error) {
+	bdb.networkTime, bdb.networkBlockHeight = time, height
+	err = bdb.update(func(tx *bolt.Tx) error {
+		for _, lock := range []struct {
+			Type  LockType
+			Value LockValue
+		}{
+			{LockTypeHeight, LockValue(height)},
+			{LockTypeTime, LockValue(time)},
+		} {
+			lockedBucket, unlockedBucket := boltLockBuckets(tx, lock.Type)
+			// collect all locked outputs which can be unlocked
+			var keys [][]byte
+			c := lockedBucket.Cursor()
+			for k, _ := c.First(); k != nil && boltLockKeyValue(k) <= lock.Value; k, _ = c.Next() {
+				keys = append(keys, append([]byte(nil), k...))
+			}
+			// locked -> unlocked
+			for _, key := range keys {
+				id := boltLockKeyCoinOutputID(key)
+				uh, value, err := bdb.updateCoinOutputState(tx, id, CoinOutputStateLocked, CoinOutputStateLiquid)
+				if err != nil {
+					return fmt.Errorf("bolt: failed to unlock coin output: %v", err)
+				}
+				if err = lockedBucket.Delete(key); err == nil {
+					err = unlockedBucket.Put(key, nil)
+				}
+				if err != nil {
+					return fmt.Errorf("bolt: failed to move lock of coin output %s: %v", id.String(), err)
+				}
+				err = bdb.updateWallet(tx, uh, func(wallet *Wallet) error {
+					err := wallet.Balance.Locked.SubLockedCoinOutput(id)
+					if err != nil {
+						return err
+					}
+					wallet.Balance.Unlocked = wallet.Balance.Unlocked.Add(value)
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+				coins = coins.Add(value)
+				n++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, types.Currency{}, err
+	}
+	return n, coins, nil
+}
+
+// RevertCoinOutputLocks implements Database.RevertCoinOutputLocks
+func (bdb *BoltDatabase) RevertCoinOutputLocks(height types.BlockHeight, time types.Timestamp) (n uint64, coins types.Currency, err error) {
+	bdb.networkTime, bdb.networkBlockHeight = time, height
+	err = bdb.update(func(tx *bolt.Tx) error {
+		for _, lock := range []struct {
+			Type  LockType
+			Value LockValue
+		}{
+			{LockTypeHeight, LockValue(height)},
+			{LockTypeTime, LockValue(time)},
+		} {
+			lockedBucket, unlockedBucket := boltLockBuckets(tx, lock.Type)
+			// collect all unlocked outputs which have to be locked again
+			var keys [][]byte
+			c := unlockedBucket.Cursor()
+			for k, _ := c.Last(); k != nil && boltLockKeyValue(k) > lock.Value; k, _ = c.Prev() {
+				keys = append(keys, append([]byte(nil), k...))
+			}
+			// unlocked -> locked
+			for _, key := range keys {
+				id := boltLockKeyCoinOutputID(key)
+				co, err := bdb.getCoinOutput(tx, id)
+				if err != nil {
+					return fmt.Errorf("bolt: failed to lock coin output: cannot get coin output %s: %v", id.String(), err)
+				}
+				_, _, err = bdb.updateCoinOutputState(tx, id, CoinOutputStateLiquid, CoinOutputStateLocked)
+				if err != nil {
+					return fmt.Errorf("bolt: failed to lock coin output: %v", err)
+				}
+				if err = unlockedBucket.Delete(key); err == nil {
+					err = lockedBucket.Put(key, nil)
+				}
+				if err != nil {
+					return fmt.Errorf("bolt: failed to move lock of coin output %s: %v", id.String(), err)
+				}
+				err = bdb.updateWallet(tx, co.UnlockHash, func(wallet *Wallet) error {
+					wallet.Balance.Unlocked = wallet.Balance.Unlocked.Sub(co.CoinValue)
+					return wallet.Balance.Locked.AddLockedCoinOutput(id, WalletLockedOutput{
+						Amount:      co.CoinValue,
+						LockedUntil: bdb.lockValueAsLockTime(co.LockType, co.LockValue),
+						Description: co.Description,
+						Reason:      CoinOutputLockReason(co.LockType, co.Description),
+					})
+				})
+				if err != nil {
+					return err
+				}
+				coins = coins.Add(co.CoinValue)
+				n++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, types.Currency{}, err
+	}
+	return n, coins, nil
+}
+
+// SetMultisigAddresses implements Database.SetMultisigAddresses
+func (bdb *BoltDatabase) SetMultisigAddresses(address types.UnlockHash, owners []types.UnlockHash, signaturesRequired uint64) error {
+	return bdb.update(func(tx *bolt.Tx) error {
+		var known bool
+		err := bdb.updateWallet(tx, address, func(wallet *Wallet) error {
+			if len(wallet.MultiSignData.Owners) > 0 {
+				known = true
+				return nil // nothing to do
+			}
+			wallet.MultiSignData.SignaturesRequired = signaturesRequired
+			wallet.MultiSignData.Owners = make([]types.UnlockHash, len(owners))
+			copy(wallet.MultiSignData.Owners[:], owners[:])
+			return nil
+		})
+		if err != nil || known {
+			return err
+		}
+		// link the multisig address to all its owners
+		for _, owner := range owners {
+			err = bdb.updateWallet(tx, owner, func(wallet *Wallet) error {
+				for _, uh := range wallet.MultiSignAddresses {
+					if uh == address {
+						return nil // nothing to do
+					}
+				}
+				wallet.MultiSignAddresses = append(wallet.MultiSignAddresses, address)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ApplyCounterpartyTransfer implements Database.ApplyCounterpartyTransfer
+func (bdb *BoltDatabase) ApplyCounterpartyTransfer(from, to types.UnlockHash, value types.Currency) error {
+	return bdb.update(func(tx *bolt.Tx) error {
+		err := bdb.updateCounterparty(tx, from, to, func(cp *AddressCounterparty) {
+			cp.TransactionCount++
+			cp.Sent = cp.Sent.Add(value)
+		})
+		if err != nil {
+			return err
+		}
+		return bdb.updateCounterparty(tx, to, from, func(cp *AddressCounterparty) {
+			cp.TransactionCount++
+			cp.Received = cp.Received.Add(value)
+		})
+	})
+}
+
+// RevertCounterpartyTransfer implements Database.RevertCounterpartyTransfer
+func (bdb *BoltDatabase) RevertCounterpartyTransfer(from, to types.UnlockHash, value types.Currency) error {
+	return bdb.update(func(tx *bolt.Tx) error {
+		err := bdb.updateCounterparty(tx, from, to, func(cp *AddressCounterparty) {
+			cp.TransactionCount--
+			cp.Sent = subCurrencyOrZero(cp.Sent, value)
+		})
+		if err != nil {
+			return err
+		}
+		return bdb.updateCounterparty(tx, to, from, func(cp *AddressCounterparty) {
+			cp.TransactionCount--
+			cp.Received = subCurrencyOrZero(cp.Received, value)
+		})
+	})
+}
+
+// updateCounterparty updates the counterparty of an address, using the given update function.
+func (bdb *BoltDatabase) updateCounterparty(tx *bolt.Tx, address, counterparty types.UnlockHash, update func(*AddressCounterparty)) error {
+	key := []byte(address.String() + counterparty.String())
+	var cp AddressCounterparty
+	err := bdb.getValue(tx, boltBucketCounterparties, key, &cp)
+	if err != nil && err != ErrNotFound {
+		return fmt.Errorf("bolt: failed to get counterparty %s of %s: %v",
+			counterparty.String(), address.String(), err)
+	}
+	update(&cp)
+	if cp.TransactionCount == 0 {
+		err = tx.Bucket(boltBucketCounterparties).Delete(key)
+	} else {
+		err = bdb.putValue(tx, boltBucketCounterparties, key, cp)
+	}
+	if err != nil {
+		return fmt.Errorf("bolt: failed to update counterparty %s of %s: %v",
+			counterparty.String(), address.String(), err)
+	}
+	return nil
+}
+
+// ApplyWalletGroupFlows implements Database.ApplyWalletGroupFlows
+func (bdb *BoltDatabase) ApplyWalletGroupFlows(timestamp types.Timestamp, flows WalletGroupFlows) error {
+	return bdb.updateWalletGroupFlows(timestamp, func(current WalletGroupFlows) WalletGroupFlows {
+		return current.Add(flows)
+	})
+}
+
+// RevertWalletGroupFlows implements Database.RevertWalletGroupFlows
+func (bdb *BoltDatabase) RevertWalletGroupFlows(timestamp types.Timestamp, flows WalletGroupFlows) error {
+	return bdb.updateWalletGroupFlows(timestamp, func(current WalletGroupFlows) WalletGroupFlows {
+		return current.Sub(flows)
+	})
+}
+
+// updateWalletGroupFlows updates both the total and the daily wallet group flows,
+// removing the daily flows once they're no longer used.
+func (bdb *BoltDatabase) updateWalletGroupFlows(timestamp types.Timestamp, update func(WalletGroupFlows) WalletGroupFlows) error {
+	return bdb.update(func(tx *bolt.Tx) error {
+		for _, day := range []string{flowsFieldTotal, walletGroupFlowsDay(timestamp)} {
+			var flows WalletGroupFlows
+			err := bdb.getValue(tx, boltBucketFlows, []byte(day), &flows)
+			if err != nil && err != ErrNotFound {
+				return fmt.Errorf("bolt: failed to get wallet group flows of %s: %v", day, err)
+			}
+			flows = update(flows)
+			if flows.IsZero() && day != flowsFieldTotal {
+				err = tx.Bucket(boltBucketFlows).Delete([]byte(day))
+			} else {
+				err = bdb.putValue(tx, boltBucketFlows, []byte(day), flows)
+			}
+			if err != nil {
+				return fmt.Errorf("bolt: failed to update wallet group flows of %s: %v", day, err)
+			}
+		}
+		return nil
+	})
+}
+
+// GetWalletGroupFlows implements Database.GetWalletGroupFlows
+func (bdb *BoltDatabase) GetWalletGroupFlows() (total WalletGroupFlows, daily map[string]WalletGroupFlows, err error) {
+	daily = make(map[string]WalletGroupFlows)
+	err = bdb.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucketFlows).ForEach(func(k, v []byte) error {
+			var flows WalletGroupFlows
+			err := bdb.encoder.Unmarshal(v, &flows)
+			if err != nil {
+				return fmt.Errorf("bolt: failed to decode wallet group flows of %s: %v", k, err)
+			}
+			if day := string(k); day == flowsFieldTotal {
+				total = flows
+			} else {
+				daily[day] = flows
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return WalletGroupFlows{}, nil, err
+	}
+	return total, daily, nil
+}
+
+// ApplyMultisigSpend implements Database.ApplyMultisigSpend
+func (bdb *BoltDatabase) ApplyMultisigSpend(spend MultisigSpend) error {
+	return bdb.update(func(tx *bolt.Tx) error {
+		key := []byte(spend.Address.String() + spend.CoinOutputID.String())
+		err := bdb.putValue(tx, boltBucketMultisigSpends, key, spend.Signers)
+		if err != nil {
+			return fmt.Errorf("bolt: failed to store signers of multisig spend %s: %v", spend.CoinOutputID.String(), err)
+		}
+		for _, signer := range spend.Signers {
+			err = bdb.updateMultisigSignerStats(tx, spend.Address, signer, func(stats *MultisigSignerStats) {
+				stats.SpendCount++
+				stats.Value = stats.Value.Add(spend.Value)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RevertMultisigSpend implements Database.RevertMultisigSpend
+func (bdb *BoltDatabase) RevertMultisigSpend(spend MultisigSpend) error {
+	return bdb.update(func(tx *bolt.Tx) error {
+		key := []byte(spend.Address.String() + spend.CoinOutputID.String())
+		err := tx.Bucket(boltBucketMultisigSpends).Delete(key)
+		if err != nil {
+			return fmt.Errorf("bolt: failed to remove signers of multisig spend %s: %v", spend.CoinOutputID.String(), err)
+		}
+		for _, signer := range spend.Signers {
+			err = bdb.updateMultisigSignerStats(tx, spend.Address, signer, func(stats *MultisigSignerStats) {
+				stats.SpendCount--
+				stats.Value = subCurrencyOrZero(stats.Value, spend.Value)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// updateMultisigSignerStats updates the signing stats of an owner of a multisig wallet,
+// using the given update function, removing the stats once the owner no longer signed any spend.
+func (bdb *BoltDatabase) updateMultisigSignerStats(tx *bolt.Tx, address, signer types.UnlockHash, update func(*MultisigSignerStats)) error {
+	key := []byte(address.String() + signer.String())
+	var stats MultisigSignerStats
+	err := bdb.getValue(tx, boltBucketMultisigSigners, key, &stats)
+	if err != nil && err != ErrNotFound {
+		return fmt.Errorf("bolt: failed to get signing stats of %s for %s: %v",
+			signer.String(), address.String(), err)
+	}
+	update(&stats)
+	if stats.SpendCount == 0 {
+		err = tx.Bucket(boltBucketMultisigSigners).Delete(key)
+	} else {
+		err = bdb.putValue(tx, boltBucketMultisigSigners, key, stats)
+	}
+	if err != nil {
+		return fmt.Errorf("bolt: failed to update signing stats of %s for %s: %v",
+			signer.String(), address.String(), err)
+	}
+	return nil
+}
+
+// GetMultisigSpends implements Database.GetMultisigSpends
+func (bdb *BoltDatabase) GetMultisigSpends(address types.UnlockHash) (map[types.CoinOutputID][]types.UnlockHash, error) {
+	spends := make(map[types.CoinOutputID][]types.UnlockHash)
+	err := bdb.view(func(tx *bolt.Tx) error {
+		return boltForEachWithPrefix(tx.Bucket(boltBucketMultisigSpends), []byte(address.String()), func(suffix, v []byte) error {
+			var (
+				id      types.CoinOutputID
+				signers []types.UnlockHash
+			)
+			err := id.LoadString(string(suffix))
+			if err == nil {
+				err = bdb.encoder.Unmarshal(v, &signers)
+			}
+			if err != nil {
+				return fmt.Errorf("bolt: failed to decode multisig spend %s of %s: %v", suffix, address.String(), err)
+			}
+			spends[id] = signers
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return spends, nil
+}
+
+// GetMultisigSignerStats implements Database.GetMultisigSignerStats
+func (bdb *BoltDatabase) GetMultisigSignerStats(address types.UnlockHash) (map[types.UnlockHash]MultisigSignerStats, error) {
+	signers := make(map[types.UnlockHash]MultisigSignerStats)
+	err := bdb.view(func(tx *bolt.Tx) error {
+		return boltForEachWithPrefix(tx.Bucket(boltBucketMultisigSigners), []byte(address.String()), func(suffix, v []byte) error {
+			var (
+				signer types.UnlockHash
+				stats  MultisigSignerStats
+			)
+			err := signer.LoadString(string(suffix))
+			if err == nil {
+				err = bdb.encoder.Unmarshal(v, &stats)
+			}
+			if err != nil {
+				return fmt.Errorf("bolt: failed to decode signing stats of %s for %s: %v", suffix, address.String(), err)
+			}
+			signers[signer] = stats
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return signers, nil
+}
+
+// SetBlockSummary implements Database.SetBlockSummary
+func (bdb *BoltDatabase) SetBlockSummary(summary BlockSummary) error {
+	return bdb.update(func(tx *bolt.Tx) error {
+		return bdb.putValue(tx, boltBucketBlockSummaries, boltHeightKey(summary.Height), summary)
+	})
+}
+
+// RevertBlockSummary implements Database.RevertBlockSummary
+func (bdb *BoltDatabase) RevertBlockSummary(height types.BlockHeight) error {
+	return bdb.update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucketBlockSummaries).Delete(boltHeightKey(height))
+	})
+}
+
+// GetBlockSummary implements Database.GetBlockSummary
+func (bdb *BoltDatabase) GetBlockSummary(height types.BlockHeight) (summary BlockSummary, err error) {
+	err = bdb.view(func(tx *bolt.Tx) error {
+		return bdb.getValue(tx, boltBucketBlockSummaries, boltHeightKey(height), &summary)
+	})
+	switch err {
+	case nil:
+		return summary, nil
+	case ErrNotFound:
+		return BlockSummary{}, ErrNotFound
+	default:
+		return BlockSummary{}, fmt.Errorf("bolt: failed to get summary of block %d: %v", height, err)
+	}
+}
+
+// GetWallet implements Database.GetWallet
+func (bdb *BoltDatabase) GetWallet(address types.UnlockHash) (wallet Wallet, err error) {
+	err = bdb.view(func(tx *bolt.Tx) error {
+		return bdb.getValue(tx, boltBucketWallets, []byte(address.String()), &wallet)
+	})
+	switch err {
+	case nil:
+		err = upgradeWallet(&wallet, bdb.networkTime)
+		if err != nil {
+			return Wallet{}, fmt.Errorf("bolt: failed to upgrade wallet for %s: %v", address.String(), err)
+		}
+		return wallet, nil
+	case ErrNotFound:
+		return Wallet{}, ErrNotFound
+	default:
+		return Wallet{}, fmt.Errorf("bolt: failed to get wallet for %s: %v", address.String(), err)
+	}
+}
+
+// SampleAddresses implements Database.SampleAddresses
+//
+// As BoltDB has no native random sampling, all addresses are iterated,
+// using reservoir sampling to select the sampled addresses.
+func (bdb *BoltDatabase) SampleAddresses(n int) (addresses []types.UnlockHash, err error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	err = bdb.view(func(tx *bolt.Tx) error {
+		var i int
+		return tx.Bucket(boltBucketWallets).ForEach(func(k, _ []byte) error {
+			var uh types.UnlockHash
+			err := uh.LoadString(string(k))
+			if err != nil {
+				return fmt.Errorf("bolt: failed to load address %q: %v", k, err)
+			}
+			if i < n {
+				addresses = append(addresses, uh)
+			} else if j := rnd.Intn(i + 1); j < n {
+				addresses[j] = uh
+			}
+			i++
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return addresses, nil
+}
+
+// GetCoinOutput implements Database.GetCoinOutput
+func (bdb *BoltDatabase) GetCoinOutput(id types.CoinOutputID) (info CoinOutputInfo, err error) {
+	var co DatabaseCoinOutput
+	err = bdb.view(func(tx *bolt.Tx) (err error) {
+		co, err = bdb.getCoinOutput(tx, id)
+		return
+	})
+	switch err {
+	case nil:
+	case ErrNotFound:
+		return CoinOutputInfo{}, ErrNotFound
+	default:
+		return CoinOutputInfo{}, fmt.Errorf("bolt: failed to get coin output %s: %v", id.String(), err)
+	}
+	info = CoinOutputInfo{
+		ID:           id,
+		UnlockHash:   co.UnlockHash,
+		Value:        co.CoinValue,
+		State:        co.State,
+		LockType:     co.LockType,
+		LockValue:    co.LockValue,
+		Description:  co.Description,
+		RawCondition: co.RawCondition,
+	}
+	err = info.decodeCondition()
+	if err != nil {
+		return CoinOutputInfo{}, fmt.Errorf(
+			"bolt: failed to decode raw condition of coin output %s: %v", id.String(), err)
+	}
+	return info, nil
+}
+
+func (bdb *BoltDatabase) lockValueAsLockTime(lt LockType, value LockValue) LockValue {
+	switch lt {
+	case LockTypeTime:
+		return value
+	case LockTypeHeight:
+		return LockValue(bdb.networkTime) + (value-LockValue(bdb.networkBlockHeight))*bdb.blockFrequency
+	default:
+		panic(fmt.Sprintf("invalid lock type %d", lt))
+	}
+}
+
+// boltLockBuckets returns the buckets used to store the locks of the given type,
+// of the coin outputs which are still locked and of those that are unlocked.
+func boltLockBuckets(tx *bolt.Tx, lt LockType) (locked, unlocked *bolt.Bucket) {
+	switch lt {
+	case LockTypeHeight:
+		return tx.Bucket(boltBucketHeightLocksLocked), tx.Bucket(boltBucketHeightLocksUnlocked)
+	case LockTypeTime:
+		return tx.Bucket(boltBucketTimeLocksLocked), tx.Bucket(boltBucketTimeLocksUnlocked)
+	default:
+		panic(fmt.Sprintf("invalid lock type %d", lt))
+	}
+}
+
+// boltLockKey creates the key of a lock, ordered by the lock value,
+// as to be able to iterate over all locks within a given range.
+func boltLockKey(lockValue LockValue, id types.CoinOutputID) []byte {
+	key := make([]byte, 8+len(id))
+	binary.BigEndian.PutUint64(key, uint64(lockValue))
+	copy(key[8:], id[:])
+	return key
+}
+
+// boltForEachWithPrefix calls the given function for each key-value pair of the bucket
+// of which the key starts with the given prefix, passing the key without that prefix.
+func boltForEachWithPrefix(bucket *bolt.Bucket, prefix []byte, fn func(suffix, v []byte) error) error {
+	c := bucket.Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		err := fn(k[len(prefix):], v)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// boltHeightKey creates the key of a block height, ordered by height.
+func boltHeightKey(height types.BlockHeight) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(height))
+	return key
+}
+
+// boltLockKeyValue returns the lock value of a lock key.
+func boltLockKeyValue(key []byte) LockValue {
+	return LockValue(binary.BigEndian.Uint64(key[:8]))
+}
+
+// boltLockKeyCoinOutputID returns the coin output ID of a lock key.
+func boltLockKeyCoinOutputID(key []byte) (id types.CoinOutputID) {
+	copy(id[:], key[8:])
+	return
+}
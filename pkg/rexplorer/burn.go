@@ -0,0 +1,54 @@
+package rexplorer
+
+import (
+	"github.com/rivine/rivine/types"
+)
+
+// isUnspendable returns true if the given condition can provably never be fulfilled,
+// meaning that the coins of an output protected by it are burned. This is the case for
+// an unlock hash condition targeting an unlock hash which cannot be fulfilled by any fulfillment
+// (e.g. the nil unlock hash), for a multisig condition requiring more signatures than it has owners,
+// and for a time lock condition wrapping such a condition.
+//
+// The nil condition (used by outputs without a condition) isn't unspendable,
+// as it can be fulfilled by anyone signing the input.
+func isUnspendable(condition types.UnlockConditionProxy) bool {
+	switch condition.ConditionType() {
+	case types.ConditionTypeUnlockHash:
+		uh := condition.UnlockHash()
+		return uh.Type != types.UnlockTypePubKey && uh.Type != types.UnlockTypeAtomicSwap
+	case types.ConditionTypeMultiSignature:
+		// the Go-type of a multisig condition differs per network (see getMultisigProperties)
+		type multisigCondition interface {
+			types.UnlockHashSliceGetter
+			GetMinimumSignatureCount() uint64
+		}
+		c, ok := condition.Condition.(multisigCondition)
+		return ok && c.GetMinimumSignatureCount() > uint64(len(c.UnlockHashSlice()))
+	case types.ConditionTypeTimeLock:
+		cg, ok := condition.Condition.(types.MarshalableUnlockConditionGetter)
+		return ok && isUnspendable(types.NewCondition(cg.GetMarshalableUnlockCondition()))
+	default:
+		return false
+	}
+}
+
+// applyBurnedCoins adds the value of a created coin output to the burned coins of the network stats,
+// in case it is protected by an unspendable condition.
+func (stats *NetworkStats) applyBurnedCoins(condition types.UnlockConditionProxy, value types.Currency) {
+	if !isUnspendable(condition) {
+		return
+	}
+	stats.BurnedCoinOutputCount++
+	stats.BurnedCoins = stats.BurnedCoins.Add(value)
+}
+
+// revertBurnedCoins subtracts the value of a reverted coin output from the burned coins of the network stats,
+// in case it is protected by an unspendable condition.
+func (stats *NetworkStats) revertBurnedCoins(condition types.UnlockConditionProxy, value types.Currency) {
+	if !isUnspendable(condition) {
+		return
+	}
+	stats.BurnedCoinOutputCount = stats.decUntracked("burned coin output count", stats.BurnedCoinOutputCount, 1)
+	stats.BurnedCoins = stats.subUntracked("burned coins", stats.BurnedCoins, value)
+}
@@ -0,0 +1,120 @@
+package rexplorer
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/rivine/rivine/types"
+)
+
+type (
+	// ChainParameters collects the consensus-relevant constants of the chain the explorer is exploring,
+	// such that changes to them can be detected between restarts of the explorer.
+	ChainParameters struct {
+		GenesisBlockID            types.BlockID            `json:"genesisBlockID"`
+		GenesisTimestamp          types.Timestamp          `json:"genesisTimestamp"`
+		BlockFrequency            types.BlockHeight        `json:"blockFrequency"`
+		MaturityDelay             types.BlockHeight        `json:"maturityDelay"`
+		MedianTimestampWindow     uint64                   `json:"medianTimestampWindow"`
+		TargetWindow              types.BlockHeight        `json:"targetWindow"`
+		BlockCreatorFee           types.Currency           `json:"blockCreatorFee"`
+		MinimumTransactionFee     types.Currency           `json:"minimumTransactionFee"`
+		DefaultTransactionVersion types.TransactionVersion `json:"defaultTransactionVersion"`
+		OneCoin                   types.Currency           `json:"oneCoin"`
+	}
+	// ChainParametersRecord records a parameter set of the chain,
+	// as well as the block height at which the explorer started using it.
+	ChainParametersRecord struct {
+		BlockHeight types.BlockHeight `json:"blockHeight"`
+		Parameters  ChainParameters   `json:"parameters"`
+	}
+)
+
+// NewChainParameters collects the consensus-relevant parameters from the given chain constants.
+func NewChainParameters(chainCts types.ChainConstants) ChainParameters {
+	return ChainParameters{
+		GenesisBlockID:            chainCts.GenesisBlockID(),
+		GenesisTimestamp:          chainCts.GenesisTimestamp,
+		BlockFrequency:            chainCts.BlockFrequency,
+		MaturityDelay:             chainCts.MaturityDelay,
+		MedianTimestampWindow:     chainCts.MedianTimestampWindow,
+		TargetWindow:              chainCts.TargetWindow,
+		BlockCreatorFee:           chainCts.BlockCreatorFee,
+		MinimumTransactionFee:     chainCts.MinimumTransactionFee,
+		DefaultTransactionVersion: chainCts.DefaultTransactionVersion,
+		OneCoin:                   chainCts.CurrencyUnits.OneCoin,
+	}
+}
+
+// Equals returns true if both parameter sets are equal.
+func (params ChainParameters) Equals(other ChainParameters) bool {
+	return params.GenesisBlockID == other.GenesisBlockID &&
+		params.GenesisTimestamp == other.GenesisTimestamp &&
+		params.BlockFrequency == other.BlockFrequency &&
+		params.MaturityDelay == other.MaturityDelay &&
+		params.MedianTimestampWindow == other.MedianTimestampWindow &&
+		params.TargetWindow == other.TargetWindow &&
+		params.BlockCreatorFee.Equals(other.BlockCreatorFee) &&
+		params.MinimumTransactionFee.Equals(other.MinimumTransactionFee) &&
+		params.DefaultTransactionVersion == other.DefaultTransactionVersion &&
+		params.OneCoin.Equals(other.OneCoin)
+}
+
+// CompatibleWith returns an error if data explored using the other parameter set
+// cannot be mixed with data explored using this parameter set.
+//
+// A different genesis block means a different chain altogether, while a different maturity delay
+// invalidates the lock heights of the stored miner payouts. A change to any of the other parameters
+// (e.g. a fee change introduced by a hard fork) only affects blocks yet to be explored.
+func (params ChainParameters) CompatibleWith(other ChainParameters) error {
+	if params.GenesisBlockID != other.GenesisBlockID {
+		return fmt.Errorf("genesis block %s doesn't match genesis block %s",
+			params.GenesisBlockID.String(), other.GenesisBlockID.String())
+	}
+	if params.MaturityDelay != other.MaturityDelay {
+		return fmt.Errorf("maturity delay %d doesn't match maturity delay %d",
+			params.MaturityDelay, other.MaturityDelay)
+	}
+	return nil
+}
+
+// RegisterChainParameters compares the parameters of the given chain constants with the last parameter set
+// recorded in the given Database, recording them as a new parameter set in case they changed.
+// An error is returned if the parameters are incompatible with the recorded ones,
+// such that data of incompatible parameter sets is never mixed.
+func RegisterChainParameters(db Database, chainCts types.ChainConstants) error {
+	history, err := db.GetChainParametersHistory()
+	if err != nil {
+		return fmt.Errorf("failed to get chain parameters history: %v", err)
+	}
+	params := NewChainParameters(chainCts)
+	if n := len(history); n > 0 {
+		last := history[n-1]
+		if last.Parameters.Equals(params) {
+			return nil // nothing to do
+		}
+		err = params.CompatibleWith(last.Parameters)
+		if err != nil {
+			return fmt.Errorf(
+				"chain parameters are incompatible with the parameters recorded at block height %d: %v",
+				last.BlockHeight, err)
+		}
+	}
+	stats, err := db.GetNetworkStats()
+	if err != nil {
+		return fmt.Errorf("failed to get network stats: %v", err)
+	}
+	if len(history) > 0 {
+		log.Printf("chain parameters changed since block height %d, recording new parameters at block height %d...",
+			history[len(history)-1].BlockHeight, stats.BlockHeight)
+	}
+	history = append(history, ChainParametersRecord{
+		BlockHeight: stats.BlockHeight,
+		Parameters:  params,
+	})
+	err = db.SetChainParametersHistory(history)
+	if err != nil {
+		return fmt.Errorf("failed to store chain parameters history: %v", err)
+	}
+	return nil
+}
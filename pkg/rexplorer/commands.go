@@ -0,0 +1,1878 @@
+package rexplorer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"path"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rivine/rivine/crypto"
+	"github.com/rivine/rivine/modules"
+	"github.com/rivine/rivine/modules/consensus"
+	"github.com/rivine/rivine/modules/gateway"
+	"github.com/rivine/rivine/types"
+	"github.com/spf13/cobra"
+)
+
+type Commands struct {
+	BlockchainInfo types.BlockchainInfo
+	ChainConstants types.ChainConstants
+	BootstrapPeers []modules.NetAddress
+
+	// the host:port to listen for RPC calls
+	RPCaddr string
+	// the host:port to serve the (read-only) HTTP API on, disabled if empty
+	APIaddr string
+	// the host:port to serve the (read-only) gRPC service on, disabled if empty
+	GRPCaddr string
+	// the file defining the API keys required by the HTTP API and gRPC service, not required if empty
+	APIKeysFile string
+	// the maximum amount of requests per second made to the HTTP API and gRPC service
+	// by a single client IP, and using a single API key, 0 for no limit
+	APIRateLimit    int
+	APIKeyRateLimit int
+	// the origins allowed to make cross-origin requests to the HTTP API, disallowed if empty,
+	// and the request headers they can use besides the Authorization and Content-Type headers
+	APICORSOrigins []string
+	APICORSHeaders []string
+
+	// database info
+	DatabaseDriver   string
+	DatabaseAddress  string
+	DatabaseSlot     int
+	DatabasePassword string
+	DatabaseTLS      bool
+	// maximum amount of commands per second issued to the database, 0 for no limit
+	DatabaseCommandRate int
+	// maximum amount of writes pipelined at once to the database while syncing
+	DatabaseBatchSize int
+	// prefix of all keys, such that multiple networks can share a single database
+	DatabaseKeyPrefix string
+	// encoding of the values stored in a fresh database, see EncodingType
+	DatabaseEncoding string
+	// publish the events of every consensus change to the consumers of the database
+	DatabasePublishEvents bool
+	// move spent coin outputs to a separate keyspace, rather than keeping them in place
+	DatabaseArchiveSpentOutputs bool
+
+	// secondary database info, all calls made to the database are mirrored onto it if a driver is defined
+	MirrorDatabaseDriver  string
+	MirrorDatabaseAddress string
+	MirrorDatabaseSlot    int
+
+	// startup self-check config
+	SkipSelfCheck       bool
+	SelfCheckSampleSize int
+
+	// labeled exchange/custodian wallet addresses
+	HotWallets  []string
+	ColdWallets []string
+
+	// external commands and HTTP endpoints invoked at lifecycle events
+	Hooks []string
+
+	// the Kafka brokers the events of every consensus change are produced to, disabled if empty,
+	// and the topics the block, transaction and balance events are produced to
+	KafkaBrokers          []string
+	KafkaBlockTopic       string
+	KafkaTransactionTopic string
+	KafkaBalanceTopic     string
+	// the URL of the NATS server the events of every consensus change are published to, disabled if empty,
+	// the prefix of the subjects they are published to, and the subjects overwriting the default subject of an event
+	NATSURL           string
+	NATSSubjectPrefix string
+	NATSSubjects      []string
+
+	// the OTLP/HTTP collector the traces of the processed consensus changes are exported to, disabled if empty,
+	// the headers added to every export request, and the ratio of the consensus changes which are traced
+	OTLPEndpoint     string
+	OTLPHeaders      []string
+	TraceSampleRatio float64
+	// created by the root command, tracing the database calls as well
+	tracer *Tracer
+
+	// the interval (in blocks) at which the balance of all wallets is snapshotted, 0 if disabled
+	SnapshotInterval uint64
+	// the amount of workers decoding consensus changes concurrently while the consensus set isn't synced,
+	// 0 if consensus changes are processed sequentially
+	SyncWorkers int
+	// the interval (in blocks and time) at which the explorer state and network stats are stored
+	// while the consensus set isn't synced, both 0 if they are stored for every consensus change,
+	// only supported by database drivers supporting checkpoints (see CheckpointDatabase)
+	StateFlushBlocks   uint64
+	StateFlushInterval time.Duration
+	// the amount of (top) balance changes reported by the diff command
+	DiffTop int
+
+	// present the merged view of an address and its aliases
+	MergeAliases bool
+	// verify the stats of the reported address prefixes against their wallets
+	VerifyPrefixes bool
+	// look up arbitrary data by its hex-encoded prefix, or by its (hex-encoded) hash
+	ArbitraryDataHex  bool
+	ArbitraryDataHash bool
+	// the height at which the unspent coin outputs are exported, -1 for the current height,
+	// and the file they are exported to, the standard output if empty
+	ExportHeight int64
+	ExportFile   string
+	// store the network stats recomputed by the recompute-stats command, instead of only reporting their divergences
+	RecomputeApply bool
+
+	// the parent directory where the individual module
+	// directories will be created
+	RootPersistentDir string
+}
+
+func (cmd *Commands) Root(_ *cobra.Command, args []string) (cmdErr error) {
+	log.Println("starting rexplorer v" + version.String() + "...")
+
+	walletGroups, err := NewWalletGroups(cmd.HotWallets, cmd.ColdWallets)
+	if err != nil {
+		return err
+	}
+
+	hooks, err := NewHooks(cmd.Hooks, cmd.BlockchainInfo)
+	if err != nil {
+		return err
+	}
+	// closed last, such that all events are delivered prior to exiting
+	defer hooks.Close()
+
+	cmd.tracer, err = cmd.newTracer()
+	if err != nil {
+		return err
+	}
+	// closed after the explorer, such that the spans of all processed consensus changes are exported
+	defer cmd.tracer.Close()
+
+	// create database
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	// ensure the stored data doesn't have to be migrated first
+	err = CheckSchemaVersion(db)
+	if err != nil {
+		db.Close()
+		return fmt.Errorf("refusing to start: %v", err)
+	}
+	if cmd.MirrorDatabaseDriver != "" {
+		db, err = cmd.openMirrorDatabase(db)
+		if err != nil {
+			return err
+		}
+	}
+
+	// ensure the stored data was explored using compatible chain parameters,
+	// recording the current parameters should they have changed
+	err = RegisterChainParameters(db, cmd.ChainConstants)
+	if err != nil {
+		db.Close()
+		return fmt.Errorf("refusing to start: %v", err)
+	}
+
+	if _, ok := db.(BalanceSnapshotDatabase); cmd.SnapshotInterval > 0 && !ok {
+		db.Close()
+		return fmt.Errorf("database driver %s does not support balance snapshots", cmd.DatabaseDriver)
+	}
+	// storing the state can only be deferred if the data stored since can be restored after a crash
+	if _, ok := checkpointDatabase(db); (cmd.StateFlushBlocks > 0 || cmd.StateFlushInterval > 0) && !ok {
+		db.Close()
+		return fmt.Errorf("database driver %s does not support checkpoints, required to defer storing the state", cmd.DatabaseDriver)
+	}
+
+	// verify the stored data, before subscribing to the consensus set
+	if cmd.SkipSelfCheck {
+		log.Println("skipping self-check of stored data...")
+	} else {
+		log.Println("running self-check of stored data...")
+		err = SelfCheck(db, cmd.SelfCheckSampleSize)
+		if err != nil {
+			data := VerifyFailedHookData{Error: err.Error()}
+			if scErr, ok := err.(SelfCheckError); ok {
+				data.Problems = scErr.Problems
+			}
+			hooks.Fire(HookEventVerifyFailed, data)
+			db.Close()
+			return fmt.Errorf("refusing to start (pass --skip-selfcheck to start regardless): %v", err)
+		}
+	}
+
+	// load all modules
+
+	log.Println("loading rivine gateway module (1/3)...")
+	gateway, err := gateway.New(
+		cmd.RPCaddr, true, cmd.perDir("gateway"),
+		cmd.BlockchainInfo, cmd.ChainConstants, cmd.BootstrapPeers)
+	if err != nil {
+		return fmt.Errorf("failed to create gateway module: %v", err)
+	}
+	defer func() {
+		log.Println("Closing gateway module...")
+		err := gateway.Close()
+		if err != nil {
+			cmdErr = err
+			log.Println("[ERROR] Closing gateway module resulted in an error: ", err)
+		}
+	}()
+
+	log.Println("loading rivine consensus module (2/3)...")
+	cs, err := consensus.New(
+		gateway, true, cmd.perDir("consensus"),
+		cmd.BlockchainInfo, cmd.ChainConstants)
+	if err != nil {
+		return fmt.Errorf("failed to create consensus module: %v", err)
+	}
+	defer func() {
+		log.Println("Closing consensus module...")
+		err := cs.Close()
+		if err != nil {
+			cmdErr = err
+			log.Println("[ERROR] Closing consensus module resulted in an error: ", err)
+		}
+	}()
+
+	var sinks []ChangeSink
+	if len(cmd.KafkaBrokers) > 0 {
+		log.Println("connecting to Kafka brokers " + strings.Join(cmd.KafkaBrokers, ",") + "...")
+		sink, err := NewKafkaSink(KafkaConfig{
+			Brokers:          cmd.KafkaBrokers,
+			BlockTopic:       cmd.KafkaBlockTopic,
+			TransactionTopic: cmd.KafkaTransactionTopic,
+			BalanceTopic:     cmd.KafkaBalanceTopic,
+		})
+		if err != nil {
+			return err
+		}
+		// closed once the explorer is closed
+		defer sink.Close()
+		sinks = append(sinks, sink)
+	}
+	if cmd.NATSURL != "" {
+		subjects := make(map[EventType]string, len(cmd.NATSSubjects))
+		for _, str := range cmd.NATSSubjects {
+			typ, subject, err := ParseNATSSubject(str)
+			if err != nil {
+				return err
+			}
+			subjects[typ] = subject
+		}
+		log.Println("connecting to NATS server...")
+		sink, err := NewNATSSink(NATSConfig{
+			URL:           cmd.NATSURL,
+			SubjectPrefix: cmd.NATSSubjectPrefix,
+			Subjects:      subjects,
+		})
+		if err != nil {
+			return err
+		}
+		// closed once the explorer is closed
+		defer sink.Close()
+		sinks = append(sinks, sink)
+	}
+
+	var keys *APIKeys
+	if cmd.APIKeysFile != "" {
+		keys, err = LoadAPIKeys(cmd.APIKeysFile)
+		if err != nil {
+			return err
+		}
+		log.Printf("loaded %d API keys from %s", keys.Len(), cmd.APIKeysFile)
+	}
+	limiter := NewRateLimiter(cmd.APIRateLimit, cmd.APIKeyRateLimit)
+
+	log.Println("loading internal explorer module (3/3)...")
+	explorer, err := NewExplorer(
+		db, cs, gateway, cmd.BlockchainInfo, cmd.ChainConstants, walletGroups,
+		types.BlockHeight(cmd.SnapshotInterval), cmd.SyncWorkers,
+		types.BlockHeight(cmd.StateFlushBlocks), cmd.StateFlushInterval, hooks, cmd.tracer, sinks...)
+	if err != nil {
+		return fmt.Errorf("failed to create explorer module: %v", err)
+	}
+	defer func() {
+		log.Println("Closing explorer module...")
+		err := explorer.Close()
+		if err != nil {
+			cmdErr = err
+			log.Println("[ERROR] Closing explorer module resulted in an error: ", err)
+		}
+	}()
+
+	if cmd.APIaddr != "" {
+		log.Println("serving HTTP API on " + cmd.APIaddr + "...")
+		api := explorer.API()
+		api.RequireAPIKeys(keys)
+		api.LimitRate(limiter)
+		if len(cmd.APICORSOrigins) > 0 {
+			api.AllowCORS(&CORSPolicy{
+				AllowedOrigins: cmd.APICORSOrigins,
+				AllowedHeaders: cmd.APICORSHeaders,
+			})
+		}
+		server, err := ServeAPI(cmd.APIaddr, api)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			log.Println("Closing HTTP API server...")
+			err := server.Close()
+			if err != nil {
+				cmdErr = err
+				log.Println("[ERROR] Closing HTTP API server resulted in an error: ", err)
+			}
+		}()
+	}
+	if cmd.GRPCaddr != "" {
+		log.Println("serving gRPC service on " + cmd.GRPCaddr + "...")
+		svc := explorer.GRPCService()
+		svc.RequireAPIKeys(keys)
+		svc.LimitRate(limiter)
+		server, err := ServeGRPC(cmd.GRPCaddr, svc)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			log.Println("Closing gRPC server...")
+			err := server.Close()
+			if err != nil {
+				cmdErr = err
+				log.Println("[ERROR] Closing gRPC server resulted in an error: ", err)
+			}
+		}()
+	}
+
+	// stop the server if a kill signal is caught
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, os.Kill)
+
+	log.Println("rexplorer is up and running...")
+
+	// wait for server to be killed or the process to be done
+	select {
+	case <-sigChan:
+		log.Println("\r\nCaught stop signal, quitting...")
+	case <-context.Background().Done():
+		log.Println("\r\nBackground context is done, quitting...")
+	}
+	log.Println("Goodbye!")
+	return
+}
+
+func (cmd *Commands) openDatabase() (Database, error) {
+	password := cmd.DatabasePassword
+	if password == "" {
+		// allows the password to be configured without exposing it in the process list
+		password = os.Getenv(DatabasePasswordEnvVar)
+	}
+	var encoding EncodingType
+	if cmd.DatabaseEncoding != "" {
+		err := encoding.LoadString(cmd.DatabaseEncoding)
+		if err != nil {
+			return nil, err
+		}
+	}
+	db, err := OpenDatabase(cmd.DatabaseDriver, DatabaseConfig{
+		Address:             cmd.DatabaseAddress,
+		Slot:                cmd.DatabaseSlot,
+		Password:            password,
+		TLS:                 cmd.DatabaseTLS,
+		CommandRate:         cmd.DatabaseCommandRate,
+		BatchSize:           cmd.DatabaseBatchSize,
+		KeyPrefix:           cmd.DatabaseKeyPrefix,
+		Encoding:            encoding,
+		PublishEvents:       cmd.DatabasePublishEvents,
+		ArchiveSpentOutputs: cmd.DatabaseArchiveSpentOutputs,
+		Tracer:              cmd.tracer,
+		BlockchainInfo:      cmd.BlockchainInfo,
+		ChainConstants:      cmd.ChainConstants,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create db client: %v", err)
+	}
+	return db, nil
+}
+
+// newTracer creates the tracer exporting the traces of the processed consensus changes
+// to the configured OTLP endpoint, nil if no endpoint is configured.
+func (cmd *Commands) newTracer() (*Tracer, error) {
+	endpoint := cmd.OTLPEndpoint
+	if endpoint == "" {
+		// the endpoint (and headers) can be configured the same way as for any OpenTelemetry SDK
+		endpoint = os.Getenv(OTLPEndpointEnvVar)
+	}
+	if endpoint == "" {
+		return nil, nil
+	}
+	headers := make(map[string]string)
+	err := ParseOTLPHeaders(os.Getenv(OTLPHeadersEnvVar), headers)
+	if err != nil {
+		return nil, err
+	}
+	for _, header := range cmd.OTLPHeaders {
+		err = ParseOTLPHeaders(header, headers)
+		if err != nil {
+			return nil, err
+		}
+	}
+	log.Println("exporting traces to " + endpoint + "...")
+	return NewTracer(TracerConfig{
+		Endpoint:       endpoint,
+		Headers:        headers,
+		SampleRatio:    cmd.TraceSampleRatio,
+		BlockchainInfo: cmd.BlockchainInfo,
+	})
+}
+
+// openMirrorDatabase opens the secondary database,
+// returning a database which mirrors all calls made to the given (primary) database onto it.
+func (cmd *Commands) openMirrorDatabase(primary Database) (Database, error) {
+	secondary, err := OpenDatabase(cmd.MirrorDatabaseDriver, DatabaseConfig{
+		Address:        cmd.MirrorDatabaseAddress,
+		Slot:           cmd.MirrorDatabaseSlot,
+		BlockchainInfo: cmd.BlockchainInfo,
+		ChainConstants: cmd.ChainConstants,
+	})
+	if err != nil {
+		primary.Close()
+		return nil, fmt.Errorf("failed to create mirror db client: %v", err)
+	}
+	log.Printf("mirroring all database calls onto the %s database...", cmd.MirrorDatabaseDriver)
+	return NewMirrorDatabase(primary, secondary, cmd.MirrorDatabaseDriver), nil
+}
+
+func (cmd *Commands) perDir(module string) string {
+	return path.Join(
+		cmd.RootPersistentDir,
+		cmd.BlockchainInfo.Name, cmd.BlockchainInfo.NetworkName,
+		module)
+}
+
+func (cmd *Commands) Output(_ *cobra.Command, args []string) error {
+	var id types.CoinOutputID
+	err := id.LoadString(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid coin output ID %q: %v", args[0], err)
+	}
+
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	info, err := getCoinOutputDetails(db, id)
+	if err != nil {
+		return fmt.Errorf("failed to get coin output %s: %v", id.String(), err)
+	}
+	b, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to JSON-encode coin output %s: %v", id.String(), err)
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+func (cmd *Commands) BlockStakeOutput(_ *cobra.Command, args []string) error {
+	var id types.BlockStakeOutputID
+	err := id.LoadString(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid block stake output ID %q: %v", args[0], err)
+	}
+
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	bsdb, ok := db.(BlockStakeDatabase)
+	if !ok {
+		return fmt.Errorf("database driver %s does not support block stake outputs", cmd.DatabaseDriver)
+	}
+
+	info, err := bsdb.GetBlockStakeOutput(id)
+	if err != nil {
+		return fmt.Errorf("failed to get block stake output %s: %v", id.String(), err)
+	}
+	b, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to JSON-encode block stake output %s: %v", id.String(), err)
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+func (cmd *Commands) Block(_ *cobra.Command, args []string) error {
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	bdb, ok := db.(BlockDatabase)
+	if !ok {
+		return fmt.Errorf("database driver %s does not support block records", cmd.DatabaseDriver)
+	}
+
+	var record BlockRecord
+	if height, perr := strconv.ParseUint(args[0], 10, 64); perr == nil {
+		record, err = bdb.GetBlockRecord(types.BlockHeight(height))
+	} else {
+		var id types.BlockID
+		err = (*crypto.Hash)(&id).LoadString(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid block height or ID %q: %v", args[0], err)
+		}
+		record, err = bdb.GetBlockRecordByID(id)
+	}
+	if err == ErrNotFound {
+		return fmt.Errorf("no record stored for block %s", args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get record of block %s: %v", args[0], err)
+	}
+	b, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to JSON-encode record of block %s: %v", args[0], err)
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+func (cmd *Commands) Transaction(_ *cobra.Command, args []string) error {
+	var id types.TransactionID
+	err := id.LoadString(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid tx ID %q: %v", args[0], err)
+	}
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	txdb, ok := db.(TransactionDatabase)
+	if !ok {
+		return fmt.Errorf("database driver %s does not support transaction records", cmd.DatabaseDriver)
+	}
+
+	record, err := txdb.GetTransactionRecord(id)
+	if err == ErrNotFound {
+		return fmt.Errorf("no record stored for tx %s", args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get record of tx %s: %v", args[0], err)
+	}
+	b, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to JSON-encode record of tx %s: %v", args[0], err)
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+func (cmd *Commands) AtomicSwap(_ *cobra.Command, args []string) error {
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	asdb, ok := db.(AtomicSwapDatabase)
+	if !ok {
+		return fmt.Errorf("database driver %s does not support atomic swap contracts", cmd.DatabaseDriver)
+	}
+
+	// an address lists all contracts it sent or received, a coin output ID shows a single contract
+	var ids []types.CoinOutputID
+	var address types.UnlockHash
+	if address.LoadString(args[0]) == nil {
+		ids, err = asdb.GetAtomicSwapContracts(address)
+		if err != nil {
+			return fmt.Errorf("failed to get atomic swap contracts of %s: %v", args[0], err)
+		}
+	} else {
+		var id types.CoinOutputID
+		err = id.LoadString(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid address or contract ID %q: %v", args[0], err)
+		}
+		ids = append(ids, id)
+	}
+	for _, id := range ids {
+		contract, err := asdb.GetAtomicSwapContract(id)
+		if err == ErrNotFound {
+			return fmt.Errorf("no atomic swap contract stored for %s", id.String())
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get atomic swap contract %s: %v", id.String(), err)
+		}
+		b, err := json.MarshalIndent(contract, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to JSON-encode atomic swap contract %s: %v", id.String(), err)
+		}
+		fmt.Println(string(b))
+	}
+	return nil
+}
+
+func (cmd *Commands) ArbitraryData(_ *cobra.Command, args []string) error {
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	addb, ok := db.(ArbitraryDataDatabase)
+	if !ok {
+		return fmt.Errorf("database driver %s does not support arbitrary data", cmd.DatabaseDriver)
+	}
+
+	if cmd.ArbitraryDataHash {
+		var hash crypto.Hash
+		err = hash.LoadString(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid arbitrary data hash %q: %v", args[0], err)
+		}
+		ids, err := addb.GetArbitraryDataTransactions(hash)
+		if err != nil {
+			return fmt.Errorf("failed to get transactions of arbitrary data %s: %v", args[0], err)
+		}
+		for _, id := range ids {
+			fmt.Println(id.String())
+		}
+		return nil
+	}
+
+	prefix := []byte(args[0])
+	if cmd.ArbitraryDataHex {
+		var bs types.ByteSlice
+		err = bs.LoadString(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid hex-encoded arbitrary data prefix %q: %v", args[0], err)
+		}
+		prefix = bs
+	}
+	if len(prefix) == 0 {
+		return errors.New("arbitrary data prefix cannot be empty")
+	}
+	txs, err := addb.GetArbitraryDataByPrefix(prefix)
+	if err != nil {
+		return fmt.Errorf("failed to get arbitrary data with prefix %q: %v", args[0], err)
+	}
+	for _, tx := range txs {
+		fmt.Printf("%s %q\n", tx.ID.String(), string(tx.Data))
+	}
+	return nil
+}
+
+func (cmd *Commands) Preview(_ *cobra.Command, args []string) error {
+	var (
+		b   []byte
+		err error
+	)
+	if len(args) == 0 || args[0] == "-" {
+		b, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		b, err = ioutil.ReadFile(args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read transaction: %v", err)
+	}
+	var tx types.Transaction
+	err = json.Unmarshal(b, &tx)
+	if err != nil {
+		return fmt.Errorf("failed to JSON-decode transaction: %v", err)
+	}
+
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	preview, err := PreviewTransaction(db, tx)
+	if err != nil {
+		return fmt.Errorf("failed to preview transaction %s: %v", tx.ID().String(), err)
+	}
+	b, err = json.MarshalIndent(preview, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to JSON-encode preview of transaction %s: %v", tx.ID().String(), err)
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+func (cmd *Commands) Flows(_ *cobra.Command, args []string) error {
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	total, daily, err := db.GetWalletGroupFlows()
+	if err != nil {
+		return fmt.Errorf("failed to get wallet group flows: %v", err)
+	}
+	days := make([]string, 0, len(daily))
+	for day := range daily {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "day\tsweeps\tswept\trefills\trefilled")
+	for _, day := range days {
+		flows := daily[day]
+		fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%s\n", day,
+			flows.Sweeps.TransactionCount, flows.Sweeps.Value.String(),
+			flows.Refills.TransactionCount, flows.Refills.Value.String())
+	}
+	fmt.Fprintf(w, "total\t%d\t%s\t%d\t%s\n",
+		total.Sweeps.TransactionCount, total.Sweeps.Value.String(),
+		total.Refills.TransactionCount, total.Refills.Value.String())
+	return w.Flush()
+}
+
+func (cmd *Commands) Blocks(_ *cobra.Command, args []string) error {
+	from, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid start height %q: %v", args[0], err)
+	}
+	to := from
+	if len(args) == 2 {
+		to, err = strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid end height %q: %v", args[1], err)
+		}
+		if to < from {
+			return fmt.Errorf("end height %d is lower than start height %d", to, from)
+		}
+	}
+
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "height\ttimestamp\tdifficulty\ttxs\ttransferred\tfees\tpayouts\tcreator\toutputs\tvalue\t%s\n",
+		strings.Join(OutputValueHistogramLabels(), "\t"))
+	for height := from; height <= to; height++ {
+		summary, err := db.GetBlockSummary(types.BlockHeight(height))
+		if err == ErrNotFound {
+			continue // block not (yet) explored, or explored before summaries were stored
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get summary of block %d: %v", height, err)
+		}
+		fmt.Fprintf(w, "%d\t%d\t%s\t%d\t%s\t%s\t%s\t%s\t%d\t%s", summary.Height, summary.Timestamp,
+			summary.Difficulty.String(), summary.TransactionCount, summary.ValueTransferred.String(), summary.Fees.String(),
+			summary.MinerPayouts.String(), summary.Creator.String(),
+			summary.OutputCount, summary.OutputValue.String())
+		for _, count := range summary.Histogram {
+			fmt.Fprintf(w, "\t%d", count)
+		}
+		fmt.Fprintln(w)
+	}
+	return w.Flush()
+}
+
+func (cmd *Commands) Daily(_ *cobra.Command, args []string) error {
+	from, err := time.Parse("2006-01-02", args[0])
+	if err != nil {
+		return fmt.Errorf("invalid start date %q: %v", args[0], err)
+	}
+	to := from
+	if len(args) == 2 {
+		to, err = time.Parse("2006-01-02", args[1])
+		if err != nil {
+			return fmt.Errorf("invalid end date %q: %v", args[1], err)
+		}
+		if to.Before(from) {
+			return fmt.Errorf("end date %s is before start date %s", args[1], args[0])
+		}
+	}
+
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	dsdb, ok := db.(DailyStatsDatabase)
+	if !ok {
+		return fmt.Errorf("database driver %s does not support daily stats", cmd.DatabaseDriver)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "date\tblocks\ttxs\tvalue\tfees\tavg fee\tp50 fee\tp90 fee\tnew addresses\tactive addresses\tactive (30d)\tvelocity")
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		stats, err := dsdb.GetDailyStats(date)
+		if err == ErrNotFound {
+			continue // no block explored on that day
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get stats of %s: %v", date, err)
+		}
+		fees := stats.FeeStats
+		if fees == nil {
+			fees = &FeeStats{Average: types.ZeroCurrency, P50: types.ZeroCurrency, P90: types.ZeroCurrency}
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\t%.6f\n", stats.Date, stats.BlockCount, stats.TransactionCount,
+			stats.ValueTransferred.String(), stats.Fees.String(), fees.Average.String(), fees.P50.String(), fees.P90.String(),
+			stats.NewAddresses, stats.ActiveAddresses, stats.ActiveAddresses30d, stats.Velocity)
+	}
+	return w.Flush()
+}
+
+func (cmd *Commands) StatsHistory(_ *cobra.Command, args []string) error {
+	from, err := time.Parse("2006-01-02", args[0])
+	if err != nil {
+		return fmt.Errorf("invalid start date %q: %v", args[0], err)
+	}
+	to := from
+	if len(args) == 2 {
+		to, err = time.Parse("2006-01-02", args[1])
+		if err != nil {
+			return fmt.Errorf("invalid end date %q: %v", args[1], err)
+		}
+		if to.Before(from) {
+			return fmt.Errorf("end date %s is before start date %s", args[1], args[0])
+		}
+	}
+
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	nshdb, ok := db.(NetworkStatsHistoryDatabase)
+	if !ok {
+		return fmt.Errorf("database driver %s does not support the network stats history", cmd.DatabaseDriver)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "date\theight\ttxs\tcoins\tliquid\tlocked\tburned\tlocked outputs")
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		snapshot, err := nshdb.GetNetworkStatsSnapshot(date)
+		if err == ErrNotFound {
+			continue // no block explored on that day
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get network stats snapshot of %s: %v", date, err)
+		}
+		stats := snapshot.Stats
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t%d\n", snapshot.Date, stats.BlockHeight, stats.TransactionCount,
+			stats.Coins.String(), stats.Supply.Liquid.String(), stats.LockedCoins.String(), stats.BurnedCoins.String(),
+			stats.LockedCointOutputCount)
+	}
+	return w.Flush()
+}
+
+func (cmd *Commands) Whales(_ *cobra.Command, args []string) error {
+	day, err := time.Parse("2006-01-02", args[0])
+	if err != nil {
+		return fmt.Errorf("invalid date %q: %v", args[0], err)
+	}
+	date := day.Format("2006-01-02")
+	n := DailyTopAddressCount
+	if len(args) == 2 {
+		v, err := strconv.Atoi(args[1])
+		if err != nil || v <= 0 {
+			return fmt.Errorf("invalid amount of addresses %q: expected a positive integer", args[1])
+		}
+		n = v
+	}
+
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	dtdb, ok := db.(DailyTopAddressesDatabase)
+	if !ok {
+		return fmt.Errorf("database driver %s does not support daily top addresses", cmd.DatabaseDriver)
+	}
+
+	senders, err := dtdb.GetDailyTopSenders(date, n)
+	if err != nil {
+		return fmt.Errorf("failed to get top senders of %s: %v", date, err)
+	}
+	receivers, err := dtdb.GetDailyTopReceivers(date, n)
+	if err != nil {
+		return fmt.Errorf("failed to get top receivers of %s: %v", date, err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "rank\tsender\tsent\treceiver\treceived")
+	for i := 0; i < len(senders) || i < len(receivers); i++ {
+		fmt.Fprintf(w, "%d", i+1)
+		for _, entries := range [][]DailyTopAddress{senders, receivers} {
+			if i < len(entries) {
+				fmt.Fprintf(w, "\t%s\t%s", entries[i].Address.String(), entries[i].Value.String())
+			} else {
+				fmt.Fprint(w, "\t\t")
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	return w.Flush()
+}
+
+func (cmd *Commands) Rolling(_ *cobra.Command, _ []string) error {
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	rsdb, ok := db.(RollingStatsDatabase)
+	if !ok {
+		return fmt.Errorf("database driver %s does not support rolling stats", cmd.DatabaseDriver)
+	}
+
+	rolling, err := rsdb.GetRollingStats()
+	if err != nil {
+		return fmt.Errorf("failed to get rolling stats: %v", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "window\tsince\tblocks\ttxs\tvalue\tfees\tnew addresses")
+	for _, stats := range rolling {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\t%s\t%d\n", stats.Window, stats.Since, stats.BlockCount, stats.TransactionCount,
+			stats.ValueTransferred.String(), stats.Fees.String(), stats.NewAddresses)
+	}
+	return w.Flush()
+}
+
+func (cmd *Commands) Signers(_ *cobra.Command, args []string) error {
+	var address types.UnlockHash
+	err := address.LoadString(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid multisig address %q: %v", args[0], err)
+	}
+
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	wallet, err := db.GetWallet(address)
+	if err != nil {
+		return fmt.Errorf("failed to get wallet %s: %v", address.String(), err)
+	}
+	if len(wallet.MultiSignData.Owners) == 0 {
+		return fmt.Errorf("%s is not a (known) multisig address", address.String())
+	}
+	signers, err := db.GetMultisigSignerStats(address)
+	if err != nil {
+		return fmt.Errorf("failed to get signing stats of %s: %v", address.String(), err)
+	}
+	spends, err := db.GetMultisigSpends(address)
+	if err != nil {
+		return fmt.Errorf("failed to get multisig spends of %s: %v", address.String(), err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "%d-of-%d multisig wallet %s\n\n",
+		wallet.MultiSignData.SignaturesRequired, len(wallet.MultiSignData.Owners), address.String())
+	fmt.Fprintln(w, "owner\tspends signed\tvalue signed")
+	// owners that never signed are reported as well, as they are just as relevant to an audit
+	for _, owner := range wallet.MultiSignData.Owners {
+		stats := signers[owner]
+		fmt.Fprintf(w, "%s\t%d\t%s\n", owner.String(), stats.SpendCount, stats.Value.String())
+	}
+	ids := make([]types.CoinOutputID, 0, len(spends))
+	for id := range spends {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].String() < ids[j].String()
+	})
+	fmt.Fprintln(w, "\ncoin output\tsigners")
+	for _, id := range ids {
+		addresses := make([]string, 0, len(spends[id]))
+		for _, signer := range spends[id] {
+			addresses = append(addresses, signer.String())
+		}
+		sort.Strings(addresses)
+		fmt.Fprintf(w, "%s\t%s\n", id.String(), strings.Join(addresses, ","))
+	}
+	return w.Flush()
+}
+
+func (cmd *Commands) Wallet(_ *cobra.Command, args []string) error {
+	var address types.UnlockHash
+	err := address.LoadString(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %v", args[0], err)
+	}
+
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// the unlock horizons of the locked balance are recomputed as of the current network time
+	stats, err := db.GetNetworkStats()
+	if err != nil {
+		return fmt.Errorf("failed to get network stats: %v", err)
+	}
+
+	var wallet interface{}
+	if cmd.MergeAliases {
+		aliases, err := db.GetAddressAliases()
+		if err != nil {
+			return fmt.Errorf("failed to get address aliases: %v", err)
+		}
+		merged, err := GetMergedWallet(db, aliases, address)
+		if err != nil {
+			return fmt.Errorf("failed to get merged wallet %s: %v", address.String(), err)
+		}
+		merged.Wallet.Balance.Locked.updateHorizons(stats.Timestamp)
+		wallet = merged
+	} else {
+		wallet, err = getWalletDetails(db, address, stats.Timestamp)
+		if err != nil {
+			return fmt.Errorf("failed to get wallet %s: %v", address.String(), err)
+		}
+	}
+	b, err := json.MarshalIndent(wallet, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to JSON-encode wallet %s: %v", address.String(), err)
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+// getWalletDetails gets the wallet of the given address, with its unlock horizons recomputed as of the given network time,
+// together with its activity, block creator stats and totals, in case the database supports them.
+// ErrNotFound is returned as is if the wallet doesn't exist.
+func getWalletDetails(db Database, address types.UnlockHash, now types.Timestamp) (Wallet, error) {
+	wallet, err := db.GetWallet(address)
+	if err != nil {
+		return Wallet{}, err
+	}
+	wallet.Balance.Locked.updateHorizons(now)
+	if adb, ok := db.(AddressActivityDatabase); ok {
+		activity, err := adb.GetAddressActivity(address)
+		if err != nil && err != ErrNotFound {
+			return Wallet{}, fmt.Errorf("failed to get activity of %s: %v", address.String(), err)
+		}
+		if err == nil {
+			wallet.Activity = &activity
+		}
+	}
+	if bcdb, ok := db.(BlockCreatorDatabase); ok {
+		stats, err := bcdb.GetBlockCreatorStats(address)
+		if err != nil && err != ErrNotFound {
+			return Wallet{}, fmt.Errorf("failed to get block creator stats of %s: %v", address.String(), err)
+		}
+		if err == nil {
+			wallet.BlockCreator = &stats
+		}
+	}
+	if atdb, ok := db.(AddressTotalsDatabase); ok {
+		totals, err := atdb.GetAddressTotals(address)
+		if err != nil && err != ErrNotFound {
+			return Wallet{}, fmt.Errorf("failed to get totals of %s: %v", address.String(), err)
+		}
+		if err == nil {
+			wallet.Totals = &totals
+		}
+	}
+	return wallet, nil
+}
+
+// getCoinOutputDetails gets the info of the given coin output,
+// together with its provenance, in case the database supports it.
+// ErrNotFound is returned as is if the coin output doesn't exist.
+func getCoinOutputDetails(db Database, id types.CoinOutputID) (CoinOutputInfo, error) {
+	info, err := db.GetCoinOutput(id)
+	if err != nil {
+		return CoinOutputInfo{}, err
+	}
+	if pdb, ok := db.(CoinOutputProvenanceDatabase); ok {
+		provenance, err := pdb.GetCoinOutputProvenance(id)
+		if err != nil && err != ErrNotFound {
+			return CoinOutputInfo{}, fmt.Errorf("failed to get provenance of coin output %s: %v", id.String(), err)
+		}
+		if err == nil {
+			info.Provenance = &provenance
+		}
+	}
+	return info, nil
+}
+
+func (cmd *Commands) Alias(_ *cobra.Command, args []string) error {
+	var alias, address types.UnlockHash
+	err := alias.LoadString(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid alias address %q: %v", args[0], err)
+	}
+	err = address.LoadString(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %v", args[1], err)
+	}
+	return cmd.updateAddressAliases(func(aliases []AddressAlias) ([]AddressAlias, error) {
+		return AddAddressAlias(aliases, alias, address)
+	})
+}
+
+func (cmd *Commands) Unalias(_ *cobra.Command, args []string) error {
+	var alias types.UnlockHash
+	err := alias.LoadString(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid alias address %q: %v", args[0], err)
+	}
+	return cmd.updateAddressAliases(func(aliases []AddressAlias) ([]AddressAlias, error) {
+		return RemoveAddressAlias(aliases, alias)
+	})
+}
+
+func (cmd *Commands) updateAddressAliases(update func([]AddressAlias) ([]AddressAlias, error)) error {
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	aliases, err := db.GetAddressAliases()
+	if err != nil {
+		return fmt.Errorf("failed to get address aliases: %v", err)
+	}
+	aliases, err = update(aliases)
+	if err != nil {
+		return err
+	}
+	err = db.SetAddressAliases(aliases)
+	if err != nil {
+		return fmt.Errorf("failed to store address aliases: %v", err)
+	}
+	return nil
+}
+
+func (cmd *Commands) Aliases(_ *cobra.Command, args []string) error {
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	aliases, err := db.GetAddressAliases()
+	if err != nil {
+		return fmt.Errorf("failed to get address aliases: %v", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "alias\taddress\tresolved address")
+	for _, record := range aliases {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", record.Alias.String(), record.Address.String(),
+			ResolveAddressAlias(aliases, record.Address).String())
+	}
+	return w.Flush()
+}
+
+func (cmd *Commands) Watch(_ *cobra.Command, args []string) error {
+	var address types.UnlockHash
+	err := address.LoadString(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %v", args[0], err)
+	}
+	var webhook Webhook
+	err = cmd.updateWebhooks(func(webhooks []Webhook) ([]Webhook, error) {
+		webhooks, webhook, err = AddWebhook(webhooks, address, args[1])
+		return webhooks, err
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("watching %s, notifying %s\n", webhook.Address.String(), webhook.URL)
+	fmt.Printf("secret: %s\n", webhook.Secret)
+	return nil
+}
+
+func (cmd *Commands) Unwatch(_ *cobra.Command, args []string) error {
+	var address types.UnlockHash
+	err := address.LoadString(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %v", args[0], err)
+	}
+	var rawURL string
+	if len(args) > 1 {
+		rawURL = args[1]
+	}
+	return cmd.updateWebhooks(func(webhooks []Webhook) ([]Webhook, error) {
+		return RemoveWebhook(webhooks, address, rawURL)
+	})
+}
+
+func (cmd *Commands) updateWebhooks(update func([]Webhook) ([]Webhook, error)) error {
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	wdb, ok := db.(WebhookDatabase)
+	if !ok {
+		return fmt.Errorf("database driver %s does not support webhooks", cmd.DatabaseDriver)
+	}
+
+	webhooks, err := wdb.GetWebhooks()
+	if err != nil {
+		return fmt.Errorf("failed to get webhooks: %v", err)
+	}
+	webhooks, err = update(webhooks)
+	if err != nil {
+		return err
+	}
+	err = wdb.SetWebhooks(webhooks)
+	if err != nil {
+		return fmt.Errorf("failed to store webhooks: %v", err)
+	}
+	return nil
+}
+
+func (cmd *Commands) Webhooks(_ *cobra.Command, args []string) error {
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	wdb, ok := db.(WebhookDatabase)
+	if !ok {
+		return fmt.Errorf("database driver %s does not support webhooks", cmd.DatabaseDriver)
+	}
+
+	webhooks, err := wdb.GetWebhooks()
+	if err != nil {
+		return fmt.Errorf("failed to get webhooks: %v", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "address	url")
+	for _, webhook := range webhooks {
+		fmt.Fprintf(w, "%s\t%s\n", webhook.Address.String(), webhook.URL)
+	}
+	return w.Flush()
+}
+
+func (cmd *Commands) Prefixes(_ *cobra.Command, args []string) error {
+	for _, prefix := range args {
+		if len(prefix) != AddressPrefixLength {
+			return fmt.Errorf("invalid address prefix %q: expected %d characters", prefix, AddressPrefixLength)
+		}
+	}
+
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	pdb, ok := db.(AddressPrefixDatabase)
+	if !ok {
+		return fmt.Errorf("database driver %s does not roll up wallets per address prefix", cmd.DatabaseDriver)
+	}
+
+	stats, err := pdb.GetAddressPrefixStats()
+	if err != nil {
+		return fmt.Errorf("failed to get address prefix stats: %v", err)
+	}
+	prefixes := args
+	if len(prefixes) == 0 {
+		for prefix := range stats {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	sort.Strings(prefixes)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "prefix\twallets\tunlocked\tlocked")
+	var total AddressPrefixStats
+	for _, prefix := range prefixes {
+		s := stats[prefix]
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", prefix, s.Wallets, s.Unlocked.String(), s.Locked.String())
+		total.Wallets += s.Wallets
+		total.Unlocked = total.Unlocked.Add(s.Unlocked)
+		total.Locked = total.Locked.Add(s.Locked)
+	}
+	fmt.Fprintf(w, "total\t%d\t%s\t%s\n", total.Wallets, total.Unlocked.String(), total.Locked.String())
+	err = w.Flush()
+	if err != nil || !cmd.VerifyPrefixes {
+		return err
+	}
+
+	problems, err := VerifyAddressPrefixes(pdb, prefixes)
+	if err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			log.Printf("[ERROR] %s", problem)
+		}
+		return fmt.Errorf("%d of %d address prefix(es) are inconsistent", len(problems), len(prefixes))
+	}
+	fmt.Printf("all %d address prefix(es) are consistent\n", len(prefixes))
+	return nil
+}
+
+func (cmd *Commands) Snapshots(_ *cobra.Command, args []string) error {
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	bdb, ok := db.(BalanceSnapshotDatabase)
+	if !ok {
+		return fmt.Errorf("database driver %s does not support balance snapshots", cmd.DatabaseDriver)
+	}
+
+	heights, err := bdb.GetBalanceSnapshotHeights()
+	if err != nil {
+		return fmt.Errorf("failed to get balance snapshot heights: %v", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "height\ttimestamp\tcoins\tlocked coins")
+	for _, height := range heights {
+		snapshot, err := bdb.GetBalanceSnapshot(height)
+		if err != nil {
+			return fmt.Errorf("failed to get balance snapshot at height %d: %v", height, err)
+		}
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\n", height, snapshot.Stats.Timestamp,
+			snapshot.Stats.Coins.String(), snapshot.Stats.LockedCoins.String())
+	}
+	return w.Flush()
+}
+
+func (cmd *Commands) MintConditions(_ *cobra.Command, args []string) error {
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	mcdb, ok := db.(MintConditionDatabase)
+	if !ok {
+		return fmt.Errorf("database driver %s does not support the mint condition history", cmd.DatabaseDriver)
+	}
+
+	history, err := mcdb.GetMintConditionHistory()
+	if err != nil {
+		return fmt.Errorf("failed to get mint condition history: %v", err)
+	}
+	if len(args) == 1 {
+		height, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid height %q: %v", args[0], err)
+		}
+		change, ok := ActiveMintCondition(history, types.BlockHeight(height))
+		if !ok {
+			return fmt.Errorf("no mint condition change recorded at or prior to height %d", height)
+		}
+		b, err := json.MarshalIndent(change, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to JSON-encode mint condition change of tx %s: %v", change.TransactionID.String(), err)
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "height	timestamp	tx	mint condition")
+	for _, change := range history {
+		fmt.Fprintf(w, "%d	%d	%s	%s\n", change.BlockHeight, change.Timestamp,
+			change.TransactionID.String(), change.UnlockHash.String())
+	}
+	return w.Flush()
+}
+
+func (cmd *Commands) ThreeBot(_ *cobra.Command, args []string) error {
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	tbdb, ok := db.(ThreeBotDatabase)
+	if !ok {
+		return fmt.Errorf("database driver %s does not support 3Bot records", cmd.DatabaseDriver)
+	}
+
+	// a number is a bot ID, anything else is a name
+	var id BotID
+	if n, err := strconv.ParseUint(args[0], 10, 32); err == nil {
+		id = BotID(n)
+	} else {
+		id, err = tbdb.GetBotIDByName(args[0])
+		if err == ErrNotFound {
+			return fmt.Errorf("no 3Bot stored with name %s", args[0])
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get 3Bot ID of name %s: %v", args[0], err)
+		}
+	}
+	record, err := tbdb.GetBotRecord(id)
+	if err == ErrNotFound {
+		return fmt.Errorf("no 3Bot stored with ID %d", id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get 3Bot record %d: %v", id, err)
+	}
+	b, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to JSON-encode 3Bot record %d: %v", id, err)
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+func (cmd *Commands) Genesis(_ *cobra.Command, args []string) error {
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	gdb, ok := db.(GenesisDatabase)
+	if !ok {
+		return fmt.Errorf("database driver %s does not support the genesis allocation", cmd.DatabaseDriver)
+	}
+
+	allocation, err := gdb.GetGenesisAllocation()
+	if err == ErrNotFound {
+		return errors.New("no genesis allocation stored, as the genesis block isn't explored yet")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get genesis allocation: %v", err)
+	}
+	b, err := json.MarshalIndent(allocation, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to JSON-encode genesis allocation: %v", err)
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+func (cmd *Commands) ERC20(_ *cobra.Command, args []string) error {
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	edb, ok := db.(ERC20Database)
+	if !ok {
+		return fmt.Errorf("database driver %s does not support the ERC20 bridge", cmd.DatabaseDriver)
+	}
+
+	if len(args) == 0 {
+		stats, err := edb.GetERC20BridgeStats()
+		if err != nil {
+			return fmt.Errorf("failed to get ERC20 bridge stats: %v", err)
+		}
+		b, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to JSON-encode ERC20 bridge stats: %v", err)
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+	// a TFT address shows its registered ERC20 address, anything else is an ERC20 address
+	var uh types.UnlockHash
+	if uh.LoadString(args[0]) == nil {
+		address, err := edb.GetERC20Address(uh)
+		if err == ErrNotFound {
+			return fmt.Errorf("no ERC20 address registered for %s", args[0])
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get ERC20 address of %s: %v", args[0], err)
+		}
+		fmt.Println(address)
+		return nil
+	}
+	uh, err = edb.GetERC20UnlockHash(normalizeERC20Address(args[0]))
+	if err == ErrNotFound {
+		return fmt.Errorf("no TFT address registered for ERC20 address %s", args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get TFT address of ERC20 address %s: %v", args[0], err)
+	}
+	fmt.Println(uh.String())
+	return nil
+}
+
+func (cmd *Commands) RichList(_ *cobra.Command, args []string) error {
+	n := DefaultRichListSize
+	if len(args) == 1 {
+		v, err := strconv.Atoi(args[0])
+		if err != nil || v <= 0 {
+			return fmt.Errorf("invalid amount of addresses %q: expected a positive integer", args[0])
+		}
+		n = v
+	}
+
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	rldb, ok := db.(RichListDatabase)
+	if !ok {
+		return fmt.Errorf("database driver %s does not support the rich list", cmd.DatabaseDriver)
+	}
+
+	entries, err := rldb.GetRichList(n)
+	if err != nil {
+		return fmt.Errorf("failed to get rich list: %v", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "rank\taddress\tunlocked\tlocked\ttotal")
+	for i, entry := range entries {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", i+1, entry.Address.String(),
+			entry.Balance.Unlocked.String(), entry.Balance.Locked.String(), entry.Balance.Total().String())
+	}
+	return w.Flush()
+}
+
+func (cmd *Commands) Distribution(_ *cobra.Command, _ []string) error {
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	bddb, ok := db.(BalanceDistributionDatabase)
+	if !ok {
+		return fmt.Errorf("database driver %s does not support the balance distribution", cmd.DatabaseDriver)
+	}
+
+	dist, err := bddb.GetBalanceDistribution()
+	if err != nil {
+		return fmt.Errorf("failed to get balance distribution: %v", err)
+	}
+	total := dist.Total()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "balance\taddresses\tshare")
+	for bucket, label := range BalanceDistributionLabels() {
+		var share float64
+		if total > 0 {
+			share = float64(dist[bucket]) * 100 / float64(total)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%.2f%%\n", label, dist[bucket], share)
+	}
+	fmt.Fprintf(w, "total\t%d\t\n", total)
+	return w.Flush()
+}
+
+func (cmd *Commands) BlockCreators(_ *cobra.Command, args []string) error {
+	n := DefaultBlockCreatorCount
+	if len(args) == 1 {
+		v, err := strconv.Atoi(args[0])
+		if err != nil || v <= 0 {
+			return fmt.Errorf("invalid amount of block creators %q: expected a positive integer", args[0])
+		}
+		n = v
+	}
+
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	bcdb, ok := db.(BlockCreatorDatabase)
+	if !ok {
+		return fmt.Errorf("database driver %s does not support block creator stats", cmd.DatabaseDriver)
+	}
+
+	entries, err := bcdb.GetBlockCreators(n)
+	if err != nil {
+		return fmt.Errorf("failed to get block creators: %v", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "rank\taddress\tblocks\tpayouts")
+	for i, entry := range entries {
+		fmt.Fprintf(w, "%d\t%s\t%d\t%s\n", i+1, entry.Address.String(), entry.Stats.BlockCount, entry.Stats.Payouts.String())
+	}
+	return w.Flush()
+}
+
+func (cmd *Commands) MinerPayouts(_ *cobra.Command, args []string) error {
+	var address types.UnlockHash
+	err := address.LoadString(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %v", args[0], err)
+	}
+
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	mpdb, ok := db.(MinerPayoutDatabase)
+	if !ok {
+		return fmt.Errorf("database driver %s does not support the miner payout history", cmd.DatabaseDriver)
+	}
+
+	payouts, err := mpdb.GetMinerPayouts(address)
+	if err != nil {
+		return fmt.Errorf("failed to get miner payouts of %s: %v", address.String(), err)
+	}
+	total := types.ZeroCurrency
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "height	id	value")
+	for _, payout := range payouts {
+		fmt.Fprintf(w, "%d\t%s\t%s\n", payout.BlockHeight, payout.ID.String(), payout.Value.String())
+		total = total.Add(payout.Value)
+	}
+	fmt.Fprintf(w, "total\t%d payouts\t%s\n", len(payouts), total.String())
+	return w.Flush()
+}
+
+func (cmd *Commands) History(_ *cobra.Command, args []string) error {
+	var address types.UnlockHash
+	err := address.LoadString(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %v", args[0], err)
+	}
+
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ahdb, ok := db.(AddressHistoryDatabase)
+	if !ok {
+		return fmt.Errorf("database driver %s does not support the address history", cmd.DatabaseDriver)
+	}
+
+	history, err := ahdb.GetAddressHistory(address)
+	if err != nil {
+		return fmt.Errorf("failed to get history of %s: %v", address.String(), err)
+	}
+	sent, received := types.ZeroCurrency, types.ZeroCurrency
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "height\ttx\tsent\treceived\tsenders")
+	for _, record := range history {
+		senders := make([]fmt.Stringer, 0, len(record.Senders))
+		for _, sender := range record.Senders {
+			senders = append(senders, sender)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", record.BlockHeight, record.TransactionID.String(),
+			record.Sent.String(), record.Received.String(), FormatStringers(",", senders...))
+		sent, received = sent.Add(record.Sent), received.Add(record.Received)
+	}
+	fmt.Fprintf(w, "total\t%d txs\t%s\t%s\t\n", len(history), sent.String(), received.String())
+	return w.Flush()
+}
+
+func (cmd *Commands) Diff(_ *cobra.Command, args []string) error {
+	var heights [2]types.BlockHeight
+	for i, arg := range args {
+		height, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid height %q: %v", arg, err)
+		}
+		heights[i] = types.BlockHeight(height)
+	}
+	if heights[1] < heights[0] {
+		return fmt.Errorf("end height %d is lower than start height %d", heights[1], heights[0])
+	}
+
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	bdb, ok := db.(BalanceSnapshotDatabase)
+	if !ok {
+		return fmt.Errorf("database driver %s does not support balance snapshots", cmd.DatabaseDriver)
+	}
+
+	var snapshots [2]BalanceSnapshot
+	for i, height := range heights {
+		snapshots[i], err = bdb.GetBalanceSnapshot(height)
+		if err == ErrNotFound {
+			return fmt.Errorf("no balance snapshot stored at height %d (see the snapshots command)", height)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get balance snapshot at height %d: %v", height, err)
+		}
+	}
+	diff := DiffBalanceSnapshots(snapshots[0], snapshots[1])
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "\theight %d\theight %d\tchange\n", diff.From.BlockHeight, diff.To.BlockHeight)
+	fmt.Fprintf(w, "timestamp\t%d\t%d\t%+d\n", diff.From.Timestamp, diff.To.Timestamp,
+		int64(diff.To.Timestamp)-int64(diff.From.Timestamp))
+	for _, row := range []struct {
+		label    string
+		from, to types.Currency
+	}{
+		{"coins", diff.From.Coins, diff.To.Coins},
+		{"locked coins", diff.From.LockedCoins, diff.To.LockedCoins},
+		{"maturity locked coins", diff.From.MaturityLockedCoins, diff.To.MaturityLockedCoins},
+		{"unlocked coins", subCurrencyOrZero(diff.From.Coins, diff.From.LockedCoins),
+			subCurrencyOrZero(diff.To.Coins, diff.To.LockedCoins)},
+		{"miner payouts", diff.From.MinerPayouts, diff.To.MinerPayouts},
+		{"tx fees", diff.From.TransactionFees, diff.To.TransactionFees},
+		{"miner fees", diff.From.MinerFees, diff.To.MinerFees},
+		{"burned coins", diff.From.BurnedCoins, diff.To.BurnedCoins},
+	} {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%+d\n", row.label, row.from.String(), row.to.String(), currencyDelta(row.from, row.to))
+	}
+	fmt.Fprintf(w, "locked outputs\t%d\t%d\t%+d\n", diff.From.LockedCointOutputCount, diff.To.LockedCointOutputCount,
+		int64(diff.To.LockedCointOutputCount)-int64(diff.From.LockedCointOutputCount))
+	fmt.Fprintf(w, "wallets\t%d\t%d\t%+d\n", len(snapshots[0].Balances), len(snapshots[1].Balances),
+		len(snapshots[1].Balances)-len(snapshots[0].Balances))
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+
+	changes := diff.Changes
+	if cmd.DiffTop > 0 && len(changes) > cmd.DiffTop {
+		changes = changes[:cmd.DiffTop]
+	}
+	fmt.Printf("\ntop %d of %d changed wallets:\n", len(changes), len(diff.Changes))
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "address\tunlocked\tlocked\ttotal\tbalance")
+	for _, change := range changes {
+		fmt.Fprintf(w, "%s\t%+d\t%+d\t%+d\t%s\n", change.Address.String(),
+			change.UnlockedDelta(), change.LockedDelta(), change.TotalDelta(), change.After.Total().String())
+	}
+	return w.Flush()
+}
+
+func (cmd *Commands) ExportUTXO(_ *cobra.Command, args []string) (cmdErr error) {
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	csdb, ok := db.(CoinOutputSetDatabase)
+	if !ok {
+		return fmt.Errorf("database driver %s does not support exporting the coin outputs", cmd.DatabaseDriver)
+	}
+
+	var height types.BlockHeight
+	if cmd.ExportHeight < 0 {
+		stats, err := db.GetNetworkStats()
+		if err != nil {
+			return fmt.Errorf("failed to get network stats: %v", err)
+		}
+		height = stats.BlockHeight
+	} else {
+		height = types.BlockHeight(cmd.ExportHeight)
+	}
+
+	out := os.Stdout
+	if cmd.ExportFile != "" {
+		out, err = os.Create(cmd.ExportFile)
+		if err != nil {
+			return fmt.Errorf("failed to create export file: %v", err)
+		}
+		defer func() {
+			err := out.Close()
+			if cmdErr == nil && err != nil {
+				cmdErr = fmt.Errorf("failed to close export file: %v", err)
+			}
+			if cmdErr != nil {
+				// don't leave a partial export behind
+				os.Remove(cmd.ExportFile)
+			}
+		}()
+	}
+	w := bufio.NewWriter(out)
+	n, value, err := ExportUnspentCoinOutputs(csdb, height, w)
+	if err != nil {
+		return fmt.Errorf("failed to export unspent coin outputs at height %d: %v", height, err)
+	}
+	err = w.Flush()
+	if err != nil {
+		return fmt.Errorf("failed to write unspent coin outputs: %v", err)
+	}
+	log.Printf("exported %d unspent coin output(s) at height %d, worth %s", n, height, value.String())
+	return nil
+}
+
+func (cmd *Commands) Migrate(_ *cobra.Command, args []string) error {
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	sdb, ok := db.(SchemaDatabase)
+	if !ok {
+		return fmt.Errorf("database driver %s does not support schema migrations", cmd.DatabaseDriver)
+	}
+
+	version, err := sdb.GetSchemaVersion()
+	if err != nil {
+		return fmt.Errorf("failed to get schema version: %v", err)
+	}
+	latest := sdb.LatestSchemaVersion()
+	if version == latest {
+		fmt.Printf("stored data already uses the latest schema version %d\n", latest)
+		return nil
+	}
+	err = sdb.Migrate(func(version uint64, description string) {
+		fmt.Printf("migrating to schema version %d: %s...\n", version, description)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("stored data upgraded from schema version %d to %d\n", version, latest)
+	return nil
+}
+
+func (cmd *Commands) RecomputeStats(_ *cobra.Command, args []string) error {
+	db, err := cmd.openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	csdb, ok := db.(CoinOutputSetDatabase)
+	if !ok {
+		return fmt.Errorf("database driver %s does not support recomputing the network stats", cmd.DatabaseDriver)
+	}
+
+	stats, divergences, err := RecomputeNetworkStats(csdb, cmd.ChainConstants)
+	if err != nil {
+		return err
+	}
+	if len(divergences) == 0 {
+		fmt.Println("stored network stats match the stored coin outputs")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "stat\tstored\trecomputed")
+	for _, divergence := range divergences {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", divergence.Stat, divergence.Stored, divergence.Recomputed)
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	if !cmd.RecomputeApply {
+		fmt.Printf("\n%d stat(s) diverge, run with --apply to store the recomputed network stats\n", len(divergences))
+		return nil
+	}
+	err = StoreRecomputedNetworkStats(db, stats)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nstored the recomputed network stats, repairing %d stat(s)\n", len(divergences))
+	return nil
+}
+
+func (cmd *Commands) Version(_ *cobra.Command, args []string) {
+	fmt.Printf("Tool version            v%s\n", version.String())
+	fmt.Printf("TFChain Daemon version  v%s\n", cmd.BlockchainInfo.ChainVersion.String())
+	fmt.Printf("Rivine protocol version v%s\n", cmd.BlockchainInfo.ProtocolVersion.String())
+	fmt.Println()
+	fmt.Printf("Go Version   v%s\n", runtime.Version()[2:])
+	fmt.Printf("GOOS         %s\n", runtime.GOOS)
+	fmt.Printf("GOARCH       %s\n", runtime.GOARCH)
+
+}
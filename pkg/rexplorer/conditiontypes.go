@@ -0,0 +1,86 @@
+package rexplorer
+
+import (
+	"github.com/rivine/rivine/encoding"
+	"github.com/rivine/rivine/types"
+)
+
+// ConditionTypeStats counts the coin outputs created by transactions per type of the (outer) condition protecting them,
+// such that the network-wide adoption of multisig wallets and time locks can be charted. A time lock is counted
+// as such, regardless of the condition it wraps. Condition types not defined by Rivine are counted as unknown.
+// Miner payouts aren't counted, as they are all time locked by the protocol until they reach maturity.
+type ConditionTypeStats struct {
+	Nil            uint64 `json:"nil"`
+	UnlockHash     uint64 `json:"unlockHash"`
+	AtomicSwap     uint64 `json:"atomicSwap"`
+	TimeLock       uint64 `json:"timeLock"`
+	MultiSignature uint64 `json:"multiSignature"`
+	Unknown        uint64 `json:"unknown"`
+}
+
+// Total returns the amount of coin outputs counted, no matter their condition type.
+func (cts ConditionTypeStats) Total() uint64 {
+	return cts.Nil + cts.UnlockHash + cts.AtomicSwap + cts.TimeLock + cts.MultiSignature + cts.Unknown
+}
+
+// IsZero returns true if no coin output is counted.
+func (cts ConditionTypeStats) IsZero() bool {
+	return cts == ConditionTypeStats{}
+}
+
+// isKnownConditionType returns true if the given condition type is defined by Rivine.
+func isKnownConditionType(ct types.ConditionType) bool {
+	switch ct {
+	case types.ConditionTypeNil, types.ConditionTypeUnlockHash, types.ConditionTypeAtomicSwap,
+		types.ConditionTypeTimeLock, types.ConditionTypeMultiSignature:
+		return true
+	default:
+		return false
+	}
+}
+
+// decodeCondition decodes the given binary-encoded condition (see EncodeCondition),
+// flagging it as unknown in case its type isn't defined by Rivine. An unknown condition which cannot be decoded,
+// e.g. as its type was introduced by a chain upgrade this explorer isn't aware of, is left undefined,
+// as only its raw bytes are stored. An empty raw condition means the condition wasn't stored,
+// in which case it is left undefined and flagged as unknown as well.
+func decodeCondition(raw types.ByteSlice) (condition types.UnlockConditionProxy, unknown bool, err error) {
+	if len(raw) == 0 {
+		return types.UnlockConditionProxy{}, true, nil
+	}
+	err = encoding.Unmarshal(raw, &condition)
+	unknown = !isKnownConditionType(types.ConditionType(raw[0]))
+	if err != nil && unknown {
+		return types.UnlockConditionProxy{}, true, nil
+	}
+	return condition, unknown, err
+}
+
+// counter returns the counter of the given condition type.
+func (cts *ConditionTypeStats) counter(ct types.ConditionType) *uint64 {
+	switch ct {
+	case types.ConditionTypeNil:
+		return &cts.Nil
+	case types.ConditionTypeUnlockHash:
+		return &cts.UnlockHash
+	case types.ConditionTypeAtomicSwap:
+		return &cts.AtomicSwap
+	case types.ConditionTypeTimeLock:
+		return &cts.TimeLock
+	case types.ConditionTypeMultiSignature:
+		return &cts.MultiSignature
+	default:
+		return &cts.Unknown
+	}
+}
+
+// applyConditionType counts a coin output created by a transaction, protected by the given condition.
+func (stats *NetworkStats) applyConditionType(condition types.UnlockConditionProxy) {
+	*stats.ConditionTypes.counter(condition.ConditionType())++
+}
+
+// revertConditionType uncounts a coin output created by a reverted transaction, protected by the given condition.
+func (stats *NetworkStats) revertConditionType(condition types.UnlockConditionProxy) {
+	counter := stats.ConditionTypes.counter(condition.ConditionType())
+	*counter = stats.decUntracked("condition type count", *counter, 1)
+}
@@ -0,0 +1,80 @@
+package rexplorer
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The methods and request headers allowed for all cross-origin requests,
+// being all methods served by the API, and the headers required to pass an API key and a request body.
+const (
+	corsAllowedMethods = "GET, HEAD, POST, DELETE"
+	corsAllowedHeaders = "Authorization, Content-Type"
+)
+
+// corsMaxAge is the duration the response to a preflight request can be cached by browsers.
+const corsMaxAge = 10 * time.Minute
+
+// CORSPolicy defines the cross-origin requests allowed by the API (see https://fetch.spec.whatwg.org/#http-cors-protocol),
+// such that browser-based explorers can consume the API directly, rather than having to proxy it.
+type CORSPolicy struct {
+	// AllowedOrigins are the origins (e.g. "https://explorer.example.com") allowed to make requests,
+	// "*" allowing any origin.
+	AllowedOrigins []string
+	// AllowedHeaders are the request headers allowed besides the Authorization and Content-Type headers,
+	// "*" allowing any header.
+	AllowedHeaders []string
+}
+
+// allowsOrigin returns true if the given origin is allowed to make requests.
+func (policy *CORSPolicy) allowsOrigin(origin string) bool {
+	for _, allowed := range policy.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedHeaders returns the request headers allowed for a request which is about to be made using the given headers.
+func (policy *CORSPolicy) allowedHeaders(requested string) string {
+	headers := corsAllowedHeaders
+	for _, allowed := range policy.AllowedHeaders {
+		if allowed == "*" {
+			if requested == "" {
+				return headers
+			}
+			return requested
+		}
+		headers += ", " + allowed
+	}
+	return headers
+}
+
+// handle adds the CORS headers to the response of a request from an allowed origin,
+// responding to it directly if it is a preflight request, in which case true is returned.
+// Requests from other origins are left untouched, such that browsers block them.
+func (policy *CORSPolicy) handle(w http.ResponseWriter, r *http.Request) bool {
+	if policy == nil {
+		return false
+	}
+	origin := r.Header.Get("Origin")
+	// the response depends on the origin, as the allowed origin is echoed
+	w.Header().Add("Vary", "Origin")
+	if origin == "" || !policy.allowsOrigin(origin) {
+		return false
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+		// the rate limit responses define when to retry
+		w.Header().Set("Access-Control-Expose-Headers", "Retry-After")
+		return false
+	}
+	w.Header().Set("Access-Control-Allow-Methods", corsAllowedMethods)
+	w.Header().Set("Access-Control-Allow-Headers", policy.allowedHeaders(r.Header.Get("Access-Control-Request-Headers")))
+	w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(corsMaxAge.Seconds())))
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
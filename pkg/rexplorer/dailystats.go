@@ -0,0 +1,165 @@
+package rexplorer
+
+import (
+	"fmt"
+
+	"github.com/rivine/rivine/types"
+)
+
+// activeAddressesWindow is the rolling window (in seconds) over which the distinct active addresses are counted,
+// see DailyStats.
+const activeAddressesWindow = 30 * 24 * 60 * 60
+
+// DailyStats rolls up the transactions, the value transferred, the fees and the new and active addresses of all blocks
+// created on a single UTC day, such that the network activity can be charted without processing the whole dataset.
+// The value transferred is the total value of the coin outputs created by transactions (miner payouts excluded),
+// and the fees are the miner fees defined by those transactions. An address is new on the day it first became active
+// (see AddressActivity), and active on each day it was involved in at least one block, each address counted only once per day.
+// ActiveAddresses30d counts the distinct addresses active within the 30 days up to the latest block of the day.
+// The addresses are only known to databases implementing AddressActivityDatabase.
+// FeeStats summarizes the fees paid by the transactions of the day, nil if none of them defines miner fees.
+// The Velocity of the day is the value transferred divided by the CirculatingSupply as of the latest block of the day.
+type DailyStats struct {
+	Date               string         `json:"date"`
+	BlockCount         uint64         `json:"blockCount"`
+	TransactionCount   uint64         `json:"txCount"`
+	ValueTransferred   types.Currency `json:"valueTransferred"`
+	Fees               types.Currency `json:"fees"`
+	NewAddresses       uint64         `json:"newAddresses"`
+	ActiveAddresses    uint64         `json:"activeAddresses"`
+	ActiveAddresses30d uint64         `json:"activeAddresses30d"`
+	FeeStats           *FeeStats      `json:"feeStats,omitempty"`
+	CirculatingSupply  types.Currency `json:"circulatingSupply"`
+	Velocity           float64        `json:"velocity"`
+}
+
+// Add returns the sum of both daily stats, keeping the date of the given stats.
+// As the rolling count of active addresses and the circulating supply aren't additive,
+// the ones of the other (latest) stats are taken.
+func (ds DailyStats) Add(other DailyStats) DailyStats {
+	return DailyStats{
+		Date:               ds.Date,
+		BlockCount:         ds.BlockCount + other.BlockCount,
+		TransactionCount:   ds.TransactionCount + other.TransactionCount,
+		ValueTransferred:   ds.ValueTransferred.Add(other.ValueTransferred),
+		Fees:               ds.Fees.Add(other.Fees),
+		NewAddresses:       ds.NewAddresses + other.NewAddresses,
+		ActiveAddresses:    ds.ActiveAddresses + other.ActiveAddresses,
+		ActiveAddresses30d: other.ActiveAddresses30d,
+		FeeStats:           addFeeStats(ds.FeeStats, other.FeeStats),
+		CirculatingSupply:  other.CirculatingSupply,
+	}.withVelocity()
+}
+
+// Sub returns the given stats minus the other stats, keeping the date of the given stats.
+// The counters and values never drop below zero. As the rolling count of active addresses and the circulating supply
+// aren't additive, the ones of the other stats are taken, which are expected to be the ones prior to adding them.
+func (ds DailyStats) Sub(other DailyStats) DailyStats {
+	sub := func(a, b uint64) uint64 {
+		if a < b {
+			return 0
+		}
+		return a - b
+	}
+	return DailyStats{
+		Date:               ds.Date,
+		BlockCount:         sub(ds.BlockCount, other.BlockCount),
+		TransactionCount:   sub(ds.TransactionCount, other.TransactionCount),
+		ValueTransferred:   subCurrencyOrZero(ds.ValueTransferred, other.ValueTransferred),
+		Fees:               subCurrencyOrZero(ds.Fees, other.Fees),
+		NewAddresses:       sub(ds.NewAddresses, other.NewAddresses),
+		ActiveAddresses:    sub(ds.ActiveAddresses, other.ActiveAddresses),
+		ActiveAddresses30d: other.ActiveAddresses30d,
+		FeeStats:           subFeeStats(ds.FeeStats, other.FeeStats),
+		CirculatingSupply:  other.CirculatingSupply,
+	}.withVelocity()
+}
+
+// withVelocity returns the daily stats with their velocity recomputed.
+func (ds DailyStats) withVelocity() DailyStats {
+	ds.Velocity = coinVelocity(ds.ValueTransferred, ds.CirculatingSupply)
+	return ds
+}
+
+// IsZero returns true if no block is rolled up.
+func (ds DailyStats) IsZero() bool {
+	return ds.BlockCount == 0 && ds.TransactionCount == 0 && ds.ValueTransferred.IsZero() &&
+		ds.Fees.IsZero() && ds.NewAddresses == 0 && ds.ActiveAddresses == 0 && ds.FeeStats == nil
+}
+
+// newBlockDailyStats rolls up the given block, in which the given addresses are active.
+// The new and active addresses are counted using the activity of the addresses, in case the database records it,
+// and thus it has to be called prior to applying the activity of the block.
+func (explorer *Explorer) newBlockDailyStats(block types.Block, active map[types.UnlockHash]struct{}) DailyStats {
+	stats := DailyStats{
+		Date:             walletGroupFlowsDay(block.Timestamp),
+		BlockCount:       1,
+		TransactionCount: uint64(len(block.Transactions)),
+		ValueTransferred: types.ZeroCurrency,
+		Fees:             types.ZeroCurrency,
+		FeeStats:         newFeeStats(block.Transactions),
+	}
+	for _, tx := range block.Transactions {
+		stats.ValueTransferred = stats.ValueTransferred.Add(transactionValue(tx))
+		stats.Fees = stats.Fees.Add(transactionFee(tx))
+	}
+	adb, ok := explorer.db.(AddressActivityDatabase)
+	if !ok {
+		return stats
+	}
+	for _, uh := range sortedAddresses(active) {
+		activity, err := adb.GetAddressActivity(uh)
+		switch err {
+		case nil:
+			// only count the address the first time it is active on this day
+			if walletGroupFlowsDay(activity.LastActive.Timestamp) != stats.Date {
+				stats.ActiveAddresses++
+			}
+		case ErrNotFound:
+			stats.NewAddresses++
+			stats.ActiveAddresses++
+		default:
+			panic(fmt.Sprintf("failed to get activity of %s: %v", uh.String(), err))
+		}
+	}
+	return stats
+}
+
+// applyDailyStats rolls the given stats of the block applied at the current block height up into the stats of its day,
+// in case the database supports it. It has to be called after applying the transactions and the activity of the block,
+// as to take the circulating supply and the addresses active within the rolling window ending at the block.
+func (explorer *Explorer) applyDailyStats(stats DailyStats) {
+	dsdb, ok := explorer.db.(DailyStatsDatabase)
+	if !ok {
+		return
+	}
+	stats.CirculatingSupply = explorer.stats.CirculatingSupply()
+	if adb, ok := explorer.db.(AddressActivityDatabase); ok {
+		var since types.Timestamp
+		if explorer.stats.Timestamp > activeAddressesWindow {
+			since = explorer.stats.Timestamp - activeAddressesWindow
+		}
+		n, err := adb.CountActiveAddresses(since)
+		if err != nil {
+			panic(fmt.Sprintf("failed to count addresses active since %d: %v", since, err))
+		}
+		stats.ActiveAddresses30d = n
+	}
+	err := dsdb.ApplyDailyStats(explorer.stats.BlockHeight, stats)
+	if err != nil {
+		panic(fmt.Sprintf("failed to apply block %d to the daily stats: %v", explorer.stats.BlockHeight, err))
+	}
+}
+
+// revertDailyStats withdraws the block reverted at the current block height from the stats of its day,
+// in case the database supports it.
+func (explorer *Explorer) revertDailyStats() {
+	dsdb, ok := explorer.db.(DailyStatsDatabase)
+	if !ok {
+		return
+	}
+	err := dsdb.RevertDailyStats(explorer.stats.BlockHeight)
+	if err != nil {
+		panic(fmt.Sprintf("failed to revert block %d from the daily stats: %v", explorer.stats.BlockHeight, err))
+	}
+}
@@ -0,0 +1,76 @@
+package rexplorer
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rivine/rivine/types"
+)
+
+// DailyTopAddressCount is the amount of addresses kept in the leaderboards of the top senders and receivers of a UTC day,
+// once that day is over, see DailyTopAddressesDatabase.
+const DailyTopAddressCount = 100
+
+// DailyTopAddress is a single address of the leaderboard of the top senders or receivers of a UTC day,
+// together with the value of the coins it sent or received that day (see AddressTotals).
+type DailyTopAddress struct {
+	Address types.UnlockHash `json:"address"`
+	Value   types.Currency   `json:"value"`
+}
+
+// sortDailyTopAddresses orders the given entries by their value, highest first,
+// ordering entries with an equal value by their address, such that the order is deterministic.
+func sortDailyTopAddresses(entries []DailyTopAddress) {
+	sort.Slice(entries, func(i, j int) bool {
+		if c := entries[i].Value.Cmp(entries[j].Value); c != 0 {
+			return c > 0
+		}
+		return entries[i].Address.Cmp(entries[j].Address) < 0
+	})
+}
+
+// applyDailyTopAddresses adds the coins received and sent within the given block, applied at the current block height,
+// to the leaderboards of the day it was created on, in case the database supports it.
+// The leaderboards of the day of the previous block, created at the given time, are trimmed
+// to the top DailyTopAddressCount addresses in case the given block is the first block of a new day.
+func (explorer *Explorer) applyDailyTopAddresses(block types.Block, previousTime types.Timestamp, delta addressTotalsDelta) {
+	dtdb, ok := explorer.db.(DailyTopAddressesDatabase)
+	if !ok {
+		return
+	}
+	date := walletGroupFlowsDay(block.Timestamp)
+	if previousTime != 0 {
+		if previous := walletGroupFlowsDay(previousTime); previous != date {
+			err := dtdb.TrimDailyTopAddresses(previous, DailyTopAddressCount)
+			if err != nil {
+				panic(fmt.Sprintf("failed to trim the top addresses of %s: %v", previous, err))
+			}
+		}
+	}
+	for _, uh := range delta.addresses() {
+		err := dtdb.ApplyDailyAddressTotals(date, uh, *delta[uh])
+		if err != nil {
+			panic(fmt.Sprintf("failed to apply totals of block %d to the top addresses of %s: %v",
+				explorer.stats.BlockHeight, date, err))
+		}
+	}
+}
+
+// revertDailyTopAddresses subtracts the coins received and sent within the given block, reverted at the current block height,
+// from the leaderboards of the day it was created on, in case the database supports it.
+// Addresses trimmed from those leaderboards already are not restored, hence the leaderboards of a day
+// are approximate once a reorg reverted blocks of that day after it was over.
+func (explorer *Explorer) revertDailyTopAddresses(block types.Block, delta addressTotalsDelta) {
+	dtdb, ok := explorer.db.(DailyTopAddressesDatabase)
+	if !ok {
+		return
+	}
+	date := walletGroupFlowsDay(block.Timestamp)
+	for _, uh := range delta.addresses() {
+		err := dtdb.RevertDailyAddressTotals(date, uh, *delta[uh])
+		if err != nil {
+			panic(fmt.Sprintf("failed to revert totals of block %d from the top addresses of %s: %v",
+				explorer.stats.BlockHeight, date, err))
+		}
+	}
+}
@@ -0,0 +1,5015 @@
+package rexplorer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rivine/rivine/crypto"
+	"github.com/rivine/rivine/encoding"
+	"github.com/rivine/rivine/types"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/threefoldfoundation/rexplorer/pkg/rediscluster"
+	"github.com/threefoldfoundation/rexplorer/pkg/redispool"
+	"github.com/threefoldfoundation/rexplorer/pkg/redissentinel"
+)
+
+// Database represents the interface of a Database (client) as used by the Explorer module of this binary.
+type Database interface {
+	GetExplorerState() (ExplorerState, error)
+	SetExplorerState(state ExplorerState) error
+
+	GetNetworkStats() (NetworkStats, error)
+	SetNetworkStats(stats NetworkStats) error
+
+	SetChainHealth(health ChainHealth) error
+
+	GetChainParametersHistory() ([]ChainParametersRecord, error)
+	SetChainParametersHistory(history []ChainParametersRecord) error
+
+	GetAddressAliases() ([]AddressAlias, error)
+	SetAddressAliases(aliases []AddressAlias) error
+
+	AddCoinOutput(id types.CoinOutputID, co CoinOutput) error
+	AddLockedCoinOutput(id types.CoinOutputID, co CoinOutput, lt LockType, lockValue LockValue) error
+	SpendCoinOutput(id types.CoinOutputID) (owner types.UnlockHash, value types.Currency, err error)
+	RevertCoinInput(id types.CoinOutputID) (owner types.UnlockHash, value types.Currency, err error)
+	RevertCoinOutput(id types.CoinOutputID) (oldState CoinOutputState, err error)
+
+	ApplyCoinOutputLocks(height types.BlockHeight, time types.Timestamp) (n uint64, coins types.Currency, err error)
+	RevertCoinOutputLocks(height types.BlockHeight, time types.Timestamp) (n uint64, coins types.Currency, err error)
+
+	SetMultisigAddresses(address types.UnlockHash, owners []types.UnlockHash, signaturesRequired uint64) error
+
+	ApplyCounterpartyTransfer(from, to types.UnlockHash, value types.Currency) error
+	RevertCounterpartyTransfer(from, to types.UnlockHash, value types.Currency) error
+
+	ApplyWalletGroupFlows(timestamp types.Timestamp, flows WalletGroupFlows) error
+	RevertWalletGroupFlows(timestamp types.Timestamp, flows WalletGroupFlows) error
+	GetWalletGroupFlows() (total WalletGroupFlows, daily map[string]WalletGroupFlows, err error)
+
+	ApplyMultisigSpend(spend MultisigSpend) error
+	RevertMultisigSpend(spend MultisigSpend) error
+	GetMultisigSpends(address types.UnlockHash) (map[types.CoinOutputID][]types.UnlockHash, error)
+	GetMultisigSignerStats(address types.UnlockHash) (map[types.UnlockHash]MultisigSignerStats, error)
+
+	SetBlockSummary(summary BlockSummary) error
+	RevertBlockSummary(height types.BlockHeight) error
+	GetBlockSummary(height types.BlockHeight) (BlockSummary, error)
+
+	GetWallet(address types.UnlockHash) (Wallet, error)
+	SampleAddresses(n int) ([]types.UnlockHash, error)
+	GetCoinOutput(id types.CoinOutputID) (CoinOutputInfo, error)
+
+	Close() error
+}
+
+// TransactionalDatabase is an optional interface which can be implemented by a Database,
+// such that all changes of a single consensus change are applied atomically (or at least batched).
+// Begin is called prior to applying a consensus change, and Commit once it has been applied completely.
+type TransactionalDatabase interface {
+	Database
+
+	Begin() error
+	Commit() error
+}
+
+// SnapshotDatabase is an optional interface which can be implemented by a Database,
+// such that external clients can request the explorer to pause the application of blocks,
+// in order to read a logically consistent snapshot of the stored data.
+// GetSnapshotHold returns the token of the hold currently requested, an empty string if there is none,
+// and AcknowledgeSnapshotHold acknowledges to the client that the explorer paused for the hold of the given token.
+type SnapshotDatabase interface {
+	Database
+
+	GetSnapshotHold() (token string, err error)
+	AcknowledgeSnapshotHold(token string) error
+}
+
+// AddressPrefixDatabase is an optional interface which can be implemented by a Database,
+// which rolls up the wallets stored per address prefix (see AddressPrefixStats).
+// GetAddressPrefixStats returns the rolled up stats of all prefixes,
+// while ComputeAddressPrefixStats recomputes the stats of a single prefix from the wallets stored for it.
+type AddressPrefixDatabase interface {
+	Database
+
+	GetAddressPrefixStats() (map[string]AddressPrefixStats, error)
+	ComputeAddressPrefixStats(prefix string) (AddressPrefixStats, error)
+}
+
+// BalanceSnapshotDatabase is an optional interface which can be implemented by a Database,
+// such that the balance of all wallets can be snapshotted at regular block heights (see BalanceSnapshot).
+// StoreBalanceSnapshot snapshots the current balance of all wallets, together with the given network stats,
+// at the block height of those stats, while DeleteBalanceSnapshot deletes the snapshot of the given height, if any.
+// GetBalanceSnapshot returns ErrNotFound in case no snapshot was stored for the given height.
+type BalanceSnapshotDatabase interface {
+	Database
+
+	StoreBalanceSnapshot(stats NetworkStats) error
+	DeleteBalanceSnapshot(height types.BlockHeight) error
+	GetBalanceSnapshotHeights() ([]types.BlockHeight, error)
+	GetBalanceSnapshot(height types.BlockHeight) (BalanceSnapshot, error)
+}
+
+// BlockDatabase is an optional interface which can be implemented by a Database,
+// storing a record of each block (see BlockRecord), retrievable by height and by ID.
+// RevertBlockRecord deletes the record of the block with the given ID, stored at the given height.
+// GetBlockRecord and GetBlockRecordByID return ErrNotFound in case no record was stored for the given block.
+type BlockDatabase interface {
+	Database
+
+	SetBlockRecord(record BlockRecord) error
+	RevertBlockRecord(height types.BlockHeight, id types.BlockID) error
+	GetBlockRecord(height types.BlockHeight) (BlockRecord, error)
+	GetBlockRecordByID(id types.BlockID) (BlockRecord, error)
+}
+
+// BlockStakeDatabase is an optional interface which can be implemented by a Database,
+// indexing all block stake outputs, the same way as coin outputs are indexed:
+// a block stake output is added as liquid (unspent) or locked, and marked as spent by a block stake input.
+// Locked block stake outputs are unlocked (and locked again on revert) at the given height and time,
+// just like locked coin outputs. RevertBlockStakeInput marks a spent block stake output as liquid again,
+// while RevertBlockStakeOutput drops a block stake output, returning the state it had.
+// The unlocked and locked block stake balance of the wallets is updated accordingly (see WalletBlockStakeBalance).
+// SpendBlockStakeOutput, RevertBlockStakeInput, RevertBlockStakeOutput and GetBlockStakeOutput
+// return ErrNotFound in case the block stake output isn't stored,
+// which is the case for block stake outputs created prior to the dataset indexing them.
+type BlockStakeDatabase interface {
+	Database
+
+	AddBlockStakeOutput(id types.BlockStakeOutputID, bso types.BlockStakeOutput) error
+	AddLockedBlockStakeOutput(id types.BlockStakeOutputID, bso types.BlockStakeOutput, lt LockType, lockValue LockValue) error
+	ApplyBlockStakeOutputLocks(height types.BlockHeight, time types.Timestamp) error
+	RevertBlockStakeOutputLocks(height types.BlockHeight, time types.Timestamp) error
+	SpendBlockStakeOutput(id types.BlockStakeOutputID) (owner types.UnlockHash, value types.Currency, err error)
+	RevertBlockStakeInput(id types.BlockStakeOutputID) (owner types.UnlockHash, value types.Currency, err error)
+	RevertBlockStakeOutput(id types.BlockStakeOutputID) (oldState CoinOutputState, err error)
+	GetBlockStakeOutput(id types.BlockStakeOutputID) (BlockStakeOutputInfo, error)
+}
+
+// TransactionDatabase is an optional interface which can be implemented by a Database,
+// storing a record of each transaction (see TransactionRecord), retrievable by ID.
+// GetTransactionRecord returns ErrNotFound in case no record was stored for the given transaction.
+type TransactionDatabase interface {
+	Database
+
+	SetTransactionRecord(record TransactionRecord) error
+	RevertTransactionRecord(id types.TransactionID) error
+	GetTransactionRecord(id types.TransactionID) (TransactionRecord, error)
+}
+
+// AtomicSwapDatabase is an optional interface which can be implemented by a Database,
+// storing all atomic swap contracts (see AtomicSwapContract), linked from the wallets of both their sender and receiver.
+// SetAtomicSwapContract adds a contract, or updates the state of a stored contract,
+// while RevertAtomicSwapContract drops a contract, unlinking it from the wallets of its sender and receiver.
+// GetAtomicSwapContract returns ErrNotFound in case the contract isn't stored,
+// and GetAtomicSwapContracts returns the IDs of all contracts linked from the wallet of the given address.
+type AtomicSwapDatabase interface {
+	Database
+
+	SetAtomicSwapContract(contract AtomicSwapContract) error
+	RevertAtomicSwapContract(contract AtomicSwapContract) error
+	GetAtomicSwapContract(id types.CoinOutputID) (AtomicSwapContract, error)
+	GetAtomicSwapContracts(address types.UnlockHash) ([]types.CoinOutputID, error)
+}
+
+// ArbitraryDataDatabase is an optional interface which can be implemented by a Database,
+// indexing the (non-empty) arbitrary data of transactions, such that applications embedding references
+// (e.g. payment IDs or messages) in transactions can look them up. Arbitrary data is indexed both by its (blake2b) hash
+// and by its prefix (see ArbitraryDataPrefixLength). GetArbitraryDataTransactions returns the IDs of the transactions
+// of which the arbitrary data has the given hash, while GetArbitraryDataByPrefix returns the transactions
+// of which the arbitrary data starts with the given prefix, which is either at least ArbitraryDataPrefixLength bytes long,
+// or otherwise only matches the arbitrary data equal to it. Both return the transactions ordered by ID.
+type ArbitraryDataDatabase interface {
+	Database
+
+	ApplyArbitraryData(id types.TransactionID, data []byte) error
+	RevertArbitraryData(id types.TransactionID, data []byte) error
+	GetArbitraryDataTransactions(hash crypto.Hash) ([]types.TransactionID, error)
+	GetArbitraryDataByPrefix(prefix []byte) ([]ArbitraryDataTransaction, error)
+}
+
+// MintConditionDatabase is an optional interface which can be implemented by a Database,
+// recording the full history of the mint condition (see MintConditionChange), as changed by minter definition transactions,
+// such that it can be audited who controlled the creation of coins at any height (see ActiveMintCondition).
+// RevertMintConditionChange drops the change made by the given transaction from the history,
+// while GetMintConditionHistory returns all changes, in the order they were applied.
+type MintConditionDatabase interface {
+	Database
+
+	ApplyMintConditionChange(change MintConditionChange) error
+	RevertMintConditionChange(id types.TransactionID) error
+	GetMintConditionHistory() ([]MintConditionChange, error)
+}
+
+// ThreeBotDatabase is an optional interface which can be implemented by a Database,
+// storing the record of each 3Bot (see BotRecord), as well as a reverse index from each name to the 3Bot owning it.
+// SetBotRecord stores a new state of a 3Bot record, while RevertBotRecord drops its latest state,
+// returning the previous state (or the zero record if the 3Bot is no longer registered).
+// GetBotRecord and GetBotIDByName return ErrNotFound in case the 3Bot or name isn't stored,
+// and GetBotCount returns the amount of registered 3Bots, being the ID of the last registered 3Bot.
+type ThreeBotDatabase interface {
+	Database
+
+	SetBotRecord(record BotRecord) error
+	RevertBotRecord(id BotID) (previous BotRecord, err error)
+	GetBotRecord(id BotID) (BotRecord, error)
+	GetBotCount() (BotID, error)
+	SetBotName(name string, id BotID) error
+	DeleteBotName(name string) error
+	GetBotIDByName(name string) (BotID, error)
+}
+
+// ERC20Database is an optional interface which can be implemented by a Database,
+// indexing the ERC20 bridge transactions: storing the mapping between ERC20 addresses and the TFT addresses
+// they were registered for (in both directions), as well as the bridged volumes (see ERC20BridgeStats).
+// GetERC20UnlockHash and GetERC20Address return ErrNotFound in case no registration was stored for the given address,
+// while GetERC20BridgeStats returns zero stats in case no ERC20 bridge transaction was applied yet.
+type ERC20Database interface {
+	Database
+
+	ApplyERC20AddressRegistration(address string, uh types.UnlockHash) error
+	RevertERC20AddressRegistration(address string, uh types.UnlockHash) error
+	GetERC20UnlockHash(address string) (types.UnlockHash, error)
+	GetERC20Address(uh types.UnlockHash) (string, error)
+	SetERC20BridgeStats(stats ERC20BridgeStats) error
+	GetERC20BridgeStats() (ERC20BridgeStats, error)
+}
+
+// CoinOutputProvenanceDatabase is an optional interface which can be implemented by a Database,
+// storing the provenance of each coin output (see CoinOutputProvenance), being the block and transaction which created it,
+// and once spent, the height and transaction which spent it. RevertCoinOutputProvenance drops the provenance of a coin output,
+// while GetCoinOutputProvenance returns ErrNotFound in case no provenance is stored for the given coin output.
+type CoinOutputProvenanceDatabase interface {
+	Database
+
+	SetCoinOutputProvenance(id types.CoinOutputID, provenance CoinOutputProvenance) error
+	RevertCoinOutputProvenance(id types.CoinOutputID) error
+	GetCoinOutputProvenance(id types.CoinOutputID) (CoinOutputProvenance, error)
+}
+
+// AddressActivityDatabase is an optional interface which can be implemented by a Database,
+// storing the activity of each address (see AddressActivity). ApplyAddressActivity records the given addresses
+// as active in the block at the given height, remembering their previous activity, such that RevertAddressActivity
+// can restore the activity of all addresses active in the block at the given height.
+// GetAddressActivity returns ErrNotFound in case no activity is stored for the given address.
+// CountActiveAddresses returns the amount of addresses last active at or after the given time.
+type AddressActivityDatabase interface {
+	Database
+
+	ApplyAddressActivity(height types.BlockHeight, timestamp types.Timestamp, addresses []types.UnlockHash) error
+	RevertAddressActivity(height types.BlockHeight) error
+	GetAddressActivity(address types.UnlockHash) (AddressActivity, error)
+	CountActiveAddresses(since types.Timestamp) (uint64, error)
+}
+
+// MinerPayoutDatabase is an optional interface which can be implemented by a Database,
+// storing the history of the miner payouts received by each address (see MinerPayoutRecord),
+// such that block creators can list their rewards. RevertMinerPayouts drops all miner payouts
+// the given address received in the block at the given height. GetMinerPayouts returns the miner payouts
+// received by the given address, oldest first, and an empty history in case it never received any.
+type MinerPayoutDatabase interface {
+	Database
+
+	ApplyMinerPayout(address types.UnlockHash, payout MinerPayoutRecord) error
+	RevertMinerPayouts(address types.UnlockHash, height types.BlockHeight) error
+	GetMinerPayouts(address types.UnlockHash) ([]MinerPayoutRecord, error)
+}
+
+// AddressHistoryDatabase is an optional interface which can be implemented by a Database,
+// storing the history of the transactions in which each address sent or received coins (see AddressHistoryRecord),
+// such that the senders of the coins received by an address can be listed. RevertAddressHistory drops all transactions
+// of the block at the given height from the history of the given address. GetAddressHistory returns the history
+// of the given address, oldest first, and an empty history in case it never sent or received any coins.
+type AddressHistoryDatabase interface {
+	Database
+
+	ApplyAddressHistory(address types.UnlockHash, record AddressHistoryRecord) error
+	RevertAddressHistory(address types.UnlockHash, height types.BlockHeight) error
+	GetAddressHistory(address types.UnlockHash) ([]AddressHistoryRecord, error)
+}
+
+// AddressTotalsDatabase is an optional interface which can be implemented by a Database,
+// storing the total value of the coins received and sent by each address (see AddressTotals).
+// ApplyAddressTotals adds the given coins received and sent to the totals of the given address,
+// while RevertAddressTotals subtracts them again. GetAddressTotals returns ErrNotFound
+// in case no totals are stored for the given address.
+type AddressTotalsDatabase interface {
+	Database
+
+	ApplyAddressTotals(address types.UnlockHash, delta AddressTotals) error
+	RevertAddressTotals(address types.UnlockHash, delta AddressTotals) error
+	GetAddressTotals(address types.UnlockHash) (AddressTotals, error)
+}
+
+// DailyStatsDatabase is an optional interface which can be implemented by a Database,
+// storing the transactions, value transferred, fees and new addresses rolled up per UTC day (see DailyStats).
+// ApplyDailyStats adds the given stats of the block at the given height to the stats of their date,
+// remembering them (and the rolling count of active addresses and circulating supply they replace) such that RevertDailyStats
+// can subtract them again. GetDailyStats returns ErrNotFound
+// in case no block is rolled up for the given date (formatted as YYYY-MM-DD).
+type DailyStatsDatabase interface {
+	Database
+
+	ApplyDailyStats(height types.BlockHeight, stats DailyStats) error
+	RevertDailyStats(height types.BlockHeight) error
+	GetDailyStats(date string) (DailyStats, error)
+}
+
+// RollingStatsDatabase is an optional interface which can be implemented by a Database,
+// counting the network activity within rolling windows (e.g. the last 24 hours) up to the latest block (see RollingStats).
+// ApplyRollingStats adds the given stats of the block at the given height, created at the given timestamp,
+// subtracting the blocks which fell out of each window, and stores a record of the block such that RevertRollingStats
+// can restore the rolling stats it replaced. GetRollingStats returns the rolling stats of each window, shortest window first.
+type RollingStatsDatabase interface {
+	Database
+
+	ApplyRollingStats(height types.BlockHeight, timestamp types.Timestamp, stats RollingStats) error
+	RevertRollingStats(height types.BlockHeight) error
+	GetRollingStats() ([]RollingStats, error)
+}
+
+// NetworkStatsHistoryDatabase is an optional interface which can be implemented by a Database,
+// storing a snapshot of the network stats as of the latest block of each UTC day (see NetworkStatsSnapshot).
+// SetNetworkStatsSnapshot stores (or replaces) the snapshot of its date, while DeleteNetworkStatsSnapshot
+// deletes the snapshot of the given date, if any. GetNetworkStatsSnapshot returns ErrNotFound
+// in case no snapshot is stored for the given date (formatted as YYYY-MM-DD).
+type NetworkStatsHistoryDatabase interface {
+	Database
+
+	SetNetworkStatsSnapshot(snapshot NetworkStatsSnapshot) error
+	DeleteNetworkStatsSnapshot(date string) error
+	GetNetworkStatsSnapshot(date string) (NetworkStatsSnapshot, error)
+}
+
+// DailyTopAddressesDatabase is an optional interface which can be implemented by a Database,
+// storing per UTC day the leaderboards of the addresses which sent and received the most coins that day (see DailyTopAddress).
+// ApplyDailyAddressTotals adds the given coins received and sent by an address within a block created on the given date
+// (formatted as YYYY-MM-DD) to the leaderboards of that date, while RevertDailyAddressTotals subtracts them again,
+// dropping the address from a leaderboard once it no longer sent or received any coins that day.
+// TrimDailyTopAddresses drops all but the n top senders and receivers from the leaderboards of the given date.
+// GetDailyTopSenders and GetDailyTopReceivers return the (at most) n top addresses of the given date, highest value first.
+type DailyTopAddressesDatabase interface {
+	Database
+
+	ApplyDailyAddressTotals(date string, address types.UnlockHash, delta AddressTotals) error
+	RevertDailyAddressTotals(date string, address types.UnlockHash, delta AddressTotals) error
+	TrimDailyTopAddresses(date string, n int) error
+	GetDailyTopSenders(date string, n int) ([]DailyTopAddress, error)
+	GetDailyTopReceivers(date string, n int) ([]DailyTopAddress, error)
+}
+
+// GenesisDatabase is an optional interface which can be implemented by a Database,
+// storing the allocation of the genesis block (see GenesisAllocation), such that the initial distribution
+// of the coins and block stakes remains queryable once the genesis outputs are spent.
+// GetGenesisAllocation returns ErrNotFound in case the genesis block wasn't applied yet.
+type GenesisDatabase interface {
+	Database
+
+	SetGenesisAllocation(allocation GenesisAllocation) error
+	DeleteGenesisAllocation() error
+	GetGenesisAllocation() (GenesisAllocation, error)
+}
+
+// BlockCreatorDatabase is an optional interface which can be implemented by a Database,
+// storing the stats of each block creator (see BlockCreatorStats). ApplyCreatedBlock credits a block,
+// and the given payouts received for it, to the given creator, while RevertCreatedBlock withdraws them again.
+// GetBlockCreatorStats returns ErrNotFound in case the given address didn't create any block.
+// GetBlockCreators returns the (at most) n block creators which created the most blocks, ordered by their block count.
+type BlockCreatorDatabase interface {
+	Database
+
+	ApplyCreatedBlock(creator types.UnlockHash, payouts types.Currency) error
+	RevertCreatedBlock(creator types.UnlockHash, payouts types.Currency) error
+	GetBlockCreatorStats(address types.UnlockHash) (BlockCreatorStats, error)
+	GetBlockCreators(n int) ([]BlockCreatorEntry, error)
+}
+
+// RichListDatabase is an optional interface which can be implemented by a Database,
+// ranking all addresses with a non-zero coin balance by their total (unlocked and locked) balance,
+// such that the top holders can be listed without scanning all wallets.
+// GetRichList returns (at most) the given amount of addresses with the highest balance, highest balance first.
+type RichListDatabase interface {
+	Database
+
+	GetRichList(n int) ([]RichListEntry, error)
+}
+
+// BalanceDistributionDatabase is an optional interface which can be implemented by a Database,
+// counting the addresses with a non-zero coin balance per balance range, updated each time the balance of an address changes,
+// such that the distribution of the wealth can be charted without scanning all wallets.
+// GetBalanceDistribution returns the current count of each balance range (see BalanceDistribution).
+type BalanceDistributionDatabase interface {
+	Database
+
+	GetBalanceDistribution() (BalanceDistribution, error)
+}
+
+// CoinOutputSetDatabase is an optional interface which can be implemented by a Database,
+// such that all stored coin outputs can be iterated, e.g. to export the unspent coin outputs (see ExportUnspentCoinOutputs).
+// IterateCoinOutputs calls the given function for each stored (spent and unspent) coin output, ordered by ID,
+// stopping at (and returning) the first error returned by that function.
+type CoinOutputSetDatabase interface {
+	Database
+
+	IterateCoinOutputs(fn func(CoinOutputInfo) error) error
+}
+
+// SchemaDatabase is an optional interface which can be implemented by a Database,
+// which versions the layout of the data it stores, such that existing datasets can be upgraded
+// when that layout changes (see Migrate), instead of having to be explored again starting from the genesis block.
+// GetSchemaVersion returns the version of the stored data, while LatestSchemaVersion returns the version written by the driver.
+// Migrate upgrades the stored data to the latest version, one version at a time,
+// calling the given (optional) progress function prior to each upgrade.
+type SchemaDatabase interface {
+	Database
+
+	GetSchemaVersion() (uint64, error)
+	LatestSchemaVersion() uint64
+	Migrate(progress func(version uint64, description string)) error
+}
+
+// CheckpointDatabase is an optional interface which can be implemented by a Database,
+// such that the explorer resumes from its last checkpoint after a crash, rather than from a stored state
+// the other stored data may be ahead of (as storing the state is deferred during the initial sync, see NewExplorer),
+// which is why deferring storing the state is only supported by a database implementing it.
+// The database tracks the values changed since the last checkpoint (its dirty set), together with their value as of that checkpoint.
+// SetCheckpoint is called once the explorer state and network stats are stored, as the last change of a consensus change,
+// storing the given checkpoint and clearing the dirty set. RestoreCheckpoint is called when the explorer is created,
+// restoring all values of the dirty set, as well as the state and stats of the checkpoint,
+// returning the checkpoint and the amount of values restored, or ErrNotFound if no checkpoint is stored.
+// SupportsCheckpoints returns false if checkpoints aren't supported by the database as configured,
+// in which case neither SetCheckpoint nor RestoreCheckpoint is called.
+type CheckpointDatabase interface {
+	Database
+
+	SupportsCheckpoints() bool
+	SetCheckpoint(checkpoint Checkpoint) error
+	RestoreCheckpoint() (Checkpoint, int, error)
+}
+
+// checkpointDatabase returns the given database as a CheckpointDatabase,
+// and whether or not it supports checkpoints as configured.
+func checkpointDatabase(db Database) (CheckpointDatabase, bool) {
+	cdb, ok := db.(CheckpointDatabase)
+	return cdb, ok && cdb.SupportsCheckpoints()
+}
+
+// EventPublisherDatabase is an optional interface which can be implemented by a Database,
+// publishing the events of every block applied and reverted (see Event) to the consumers of the database,
+// such that they can react to chain activity without polling the stored data.
+// PublishesEvents returns false if publishing is disabled, in which case PublishEvents isn't called.
+// PublishEvents is called once all changes of a consensus change are stored, with all events derived from it.
+type EventPublisherDatabase interface {
+	Database
+
+	PublishesEvents() bool
+	PublishEvents(events []Event) error
+}
+
+// public function parameter data structures
+type (
+	// CoinOutput redefines a regular Rivine CoinOutput, adding a description field to it.
+	// The description field is usually taken directly from the ArbitraryData field,
+	// it is however hardcoded for tx fees and block creator rewards.
+	CoinOutput struct {
+		Value       types.Currency             `json:"value"`
+		Condition   types.UnlockConditionProxy `json:"condition"`
+		Description types.ByteSlice            `json:"description"`
+	}
+)
+
+var (
+	// ErrNotFound is returned by a Database getter,
+	// in case the requested value is not stored in the Database.
+	ErrNotFound = errors.New("not found")
+)
+
+// internal data structures
+type (
+	// Checkpoint is the last consistent state of the explorer, see CheckpointDatabase.
+	Checkpoint struct {
+		State ExplorerState `json:"state"`
+		Stats NetworkStats  `json:"stats"`
+	}
+	// NetworkInfo defines the info of the chain network data is dumped from,
+	// used as to prevent name colissions.
+	NetworkInfo struct {
+		ChainName   string `json:"chainName"`
+		NetworkName string `json:"networkName"`
+	}
+)
+
+// public data structures
+type (
+	// Wallet collects all data for an address in a simple format,
+	// focussing on its balance and multisign properties.
+	Wallet struct {
+		// Version is the version of the payload, see WalletVersion,
+		// always encoded as the latest version, as the wallet is encoded in the latest structure.
+		Version uint64 `json:"version"`
+		// Balance is optional and defines the balance the wallet currently has.
+		Balance WalletBalance `json:"balance"`
+		// MultiSignAddresses is optional and is only defined if the wallet is part of
+		// one or multiple multisign wallets.
+		MultiSignAddresses []types.UnlockHash `json:"multisignaddresses"`
+		// MultiSignData is optional and is only defined if the wallet is a multisign wallet.
+		MultiSignData WalletMultiSignData `json:"multisign"`
+		// BlockStakes is optional and defines the block stake balance the wallet currently has,
+		// only tracked by databases implementing BlockStakeDatabase.
+		BlockStakes WalletBlockStakeBalance `json:"blockstakes"`
+		// Activity is optional and defines the first and last block the address was active in,
+		// only tracked by databases implementing AddressActivityDatabase, and stored separately.
+		Activity *AddressActivity `json:"activity,omitempty"`
+		// BlockCreator is optional and defines the blocks created by the address,
+		// only tracked by databases implementing BlockCreatorDatabase, and stored separately.
+		BlockCreator *BlockCreatorStats `json:"blockCreator,omitempty"`
+		// Totals is optional and defines the total value of the coins received and sent by the address,
+		// only tracked by databases implementing AddressTotalsDatabase, and stored separately.
+		Totals *AddressTotals `json:"totals,omitempty"`
+	}
+	// WalletBalance contains the unlocked and/or locked balance of a wallet.
+	WalletBalance struct {
+		Unlocked types.Currency      `json:"unlocked,omitemtpy"`
+		Locked   WalletLockedBalance `json:"locked,omitemtpy"`
+	}
+	// WalletLockedBalance contains the locked balance of a wallet,
+	// defining the total amount of coins as well as all the outputs that are locked.
+	WalletLockedBalance struct {
+		Total   types.Currency        `json:"total"`
+		Outputs WalletLockedOutputMap `json:"outputs"`
+		// Horizons is optional and breaks the total down by the time remaining until the outputs unlock.
+		Horizons *LockHorizons `json:"horizons,omitempty"`
+	}
+	// WalletLockedOutputMap defines the mapping between a coin output ID and its walletLockedOutput data
+	WalletLockedOutputMap map[types.CoinOutputID]WalletLockedOutput
+	// WalletLockedOutput defines a locked output targetted at a wallet.
+	WalletLockedOutput struct {
+		Amount      types.Currency `json:"amount"`
+		LockedUntil LockValue      `json:"lockedUntil"`
+		Description []byte         `json:"description,omitemtpy"`
+		// Reason is only defined for locks imposed by the protocol, see CoinOutputLockReason
+		Reason LockReason `json:"reason,omitempty"`
+	}
+	// WalletBlockStakeBalance contains the unlocked and locked block stake balance of a wallet.
+	WalletBlockStakeBalance struct {
+		Unlocked types.Currency `json:"unlocked"`
+		Locked   types.Currency `json:"locked"`
+	}
+	// WalletMultiSignData defines the extra data defined for a MultiSignWallet,
+	// its signatures required and owners defining it as an m-of-n multisig wallet.
+	WalletMultiSignData struct {
+		Owners             []types.UnlockHash `json:"owners"`
+		SignaturesRequired uint64             `json:"signaturesRequired"`
+	}
+
+	// AddressCounterparty defines the interactions of an address with one of its counterparties,
+	// from the point of view of that address.
+	AddressCounterparty struct {
+		TransactionCount uint64         `json:"txCount"`
+		Sent             types.Currency `json:"sent"`
+		Received         types.Currency `json:"received"`
+	}
+
+	// CoinOutputInfo collects all stored data of a single coin output,
+	// exposing the condition both as a decoded condition tree and as the raw (binary-encoded) bytes,
+	// such that integrators can verify exactly which condition protects the funds of that output.
+	CoinOutputInfo struct {
+		ID           types.CoinOutputID         `json:"id"`
+		UnlockHash   types.UnlockHash           `json:"unlockhash"`
+		Value        types.Currency             `json:"value"`
+		State        CoinOutputState            `json:"state"`
+		LockType     LockType                   `json:"lockType"`
+		LockValue    LockValue                  `json:"lockValue"`
+		Description  types.ByteSlice            `json:"description"`
+		Condition    types.UnlockConditionProxy `json:"condition"`
+		RawCondition types.ByteSlice            `json:"rawCondition"`
+		// Burned is true if the condition can provably never be fulfilled, meaning the coin output can never be spent.
+		Burned bool `json:"burned,omitempty"`
+		// UnknownCondition is true if the type of the condition isn't defined by Rivine,
+		// in which case the condition is undefined if this explorer cannot decode it, and only the raw condition is exposed.
+		// It is also true if the condition wasn't stored, as is the case for coin outputs created prior to upgrading
+		// an existing database, in which case both the condition and the raw condition are undefined.
+		UnknownCondition bool `json:"unknownCondition,omitempty"`
+		// Provenance is optional and only defined by databases implementing CoinOutputProvenanceDatabase.
+		Provenance *CoinOutputProvenance `json:"provenance,omitempty"`
+	}
+)
+
+// Specialised Wallet Structures to prevent the decoding of data which isn't required
+type (
+	// WalletFocusBalance decodes only the balance property
+	//
+	// See Wallet for more information about all properties.
+	WalletFocusBalance struct {
+		Balance            WalletBalance   `json:"balance"`
+		MultiSignAddresses json.RawMessage `json:"multisignaddresses"`
+		MultiSignData      json.RawMessage `json:"multisign"`
+		BlockStakes        json.RawMessage `json:"blockstakes"`
+	}
+	// WalletFocusUnlockedBalance decodes only the unlocked balance property
+	//
+	// See Wallet for more information about all properties.
+	WalletFocusUnlockedBalance struct {
+		Balance            WalletBalanceFocusUnlocked `json:"balance"`
+		MultiSignAddresses json.RawMessage            `json:"multisignaddresses"`
+		MultiSignData      json.RawMessage            `json:"multisign"`
+		BlockStakes        json.RawMessage            `json:"blockstakes"`
+	}
+	// WalletBalanceFocusUnlocked decodes only the unlocked property
+	//
+	// See WalletBalance for more information about all properties.
+	WalletBalanceFocusUnlocked struct {
+		Unlocked types.Currency  `json:"unlocked,omitemtpy"`
+		Locked   json.RawMessage `json:"locked,omitemtpy"`
+	}
+	// WalletFocusMultiSignAddresses decodes only the MultiSignAddresses property
+	//
+	// See Wallet for more information  about all properties.
+	WalletFocusMultiSignAddresses struct {
+		Balance            json.RawMessage    `json:"balance"`
+		MultiSignAddresses []types.UnlockHash `json:"multisignaddresses"`
+		MultiSignData      json.RawMessage    `json:"multisign"`
+		BlockStakes        json.RawMessage    `json:"blockstakes"`
+	}
+	// WalletFocusMultiSignData decodes only the MultiSignData property
+	//
+	// See Wallet for more information  about all properties.
+	WalletFocusMultiSignData struct {
+		Balance            json.RawMessage     `json:"balance"`
+		MultiSignAddresses json.RawMessage     `json:"multisignaddresses"`
+		MultiSignData      WalletMultiSignData `json:"multisign"`
+		BlockStakes        json.RawMessage     `json:"blockstakes"`
+	}
+)
+
+// AddUniqueMultisignAddress adds the given multisign address to the wallet's list of
+// multisign addresses which reference this wallet's address.
+// It only adds it however if the given multisign address is not known yet.
+func (w *WalletFocusMultiSignAddresses) AddUniqueMultisignAddress(address types.UnlockHash) bool {
+	for _, uh := range w.MultiSignAddresses {
+		if uh.Cmp(address) == 0 {
+			return false // nothing to do
+		}
+	}
+	w.MultiSignAddresses = append(w.MultiSignAddresses, address)
+	return true
+}
+
+// IsZero returns true if this wallet is Zero
+func (wb *WalletBalance) IsZero() bool {
+	return wb.Unlocked.IsZero() && wb.Locked.Total.IsZero()
+}
+
+// IsZero returns true if the wallet has neither unlocked nor locked block stakes.
+func (wbsb *WalletBlockStakeBalance) IsZero() bool {
+	return wbsb.Unlocked.IsZero() && wbsb.Locked.IsZero()
+}
+
+// AddLockedCoinOutput adds the unique locked coin output to the wallet's map of locked outputs
+// as well as adds the coin output's value to the total amount of locked coins registered for this wallet.
+func (wlb *WalletLockedBalance) AddLockedCoinOutput(id types.CoinOutputID, co WalletLockedOutput) error {
+	if len(wlb.Outputs) == 0 {
+		wlb.Outputs = make(WalletLockedOutputMap)
+	} else if _, exists := wlb.Outputs[id]; exists {
+		return fmt.Errorf("trying to add existing locked coin output %s", id.String())
+	}
+	wlb.Outputs[id] = co
+	wlb.Total = wlb.Total.Add(co.Amount)
+	return nil
+}
+
+// SubLockedCoinOutput removes the unique existing locked coin output from the wallet's map of locked outputs,
+// as well as subtract the coin output's value from the total amount of locked coins registered for this wallet.
+func (wlb *WalletLockedBalance) SubLockedCoinOutput(id types.CoinOutputID) error {
+	if len(wlb.Outputs) == 0 {
+		return fmt.Errorf("trying to remove non-existing locked coin output %s", id.String())
+	}
+	co, exists := wlb.Outputs[id]
+	if !exists {
+		return fmt.Errorf("trying to remove non-existing locked coin output %s", id.String())
+	}
+	delete(wlb.Outputs, id)
+	wlb.Total = wlb.Total.Sub(co.Amount)
+	return nil
+}
+
+// StringLoader loads a string and uses it as the (parsed) value.
+type StringLoader interface {
+	LoadString(string) error
+}
+
+// FormatStringers formats the given stringers into one string using the given seperator
+func FormatStringers(seperator string, stringers ...fmt.Stringer) string {
+	n := len(stringers)
+	if n == 0 {
+		return ""
+	}
+	ss := make([]string, n)
+	for i, stringer := range stringers {
+		ss[i] = stringer.String()
+	}
+	return strings.Join(ss, seperator)
+}
+
+// ParseStringLoaders splits the given string into the given seperator
+// and loads each part into a given string loader.
+func ParseStringLoaders(csv, seperator string, stringLoaders ...StringLoader) (err error) {
+	n := len(stringLoaders)
+	parts := strings.SplitN(csv, seperator, n)
+	if m := len(parts); n != m {
+		return fmt.Errorf("CSV record has incorrect amount of records, expected %d but received %d", n, m)
+	}
+	for i, sl := range stringLoaders {
+		err = sl.LoadString(parts[i])
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// LockType represents the type of a lock, used to lock a (coin) output.
+type LockType uint8
+
+// The different types of locks used to lock (coin) outputs.
+const (
+	LockTypeNone LockType = iota
+	LockTypeHeight
+	LockTypeTime
+)
+
+// String implements Stringer.String
+func (lt LockType) String() string {
+	return strconv.FormatUint(uint64(lt), 10)
+}
+
+// LoadString implements StringLoader.LoadString
+func (lt *LockType) LoadString(str string) error {
+	v, err := strconv.ParseUint(str, 10, 8)
+	if err != nil {
+		return err
+	}
+	nlt := LockType(v)
+	if nlt > LockTypeTime {
+		return fmt.Errorf("invalid lock type %d", nlt)
+	}
+	*lt = nlt
+	return nil
+}
+
+// LockReason defines why a (coin) output is locked,
+// such that locks imposed by the protocol can be told apart from the locks defined by the creator of an output.
+type LockReason string
+
+// The different reasons a (coin) output can be locked for.
+const (
+	// LockReasonCondition is the reason of a lock defined by the condition of the output itself,
+	// e.g. a vesting lock defined by the creator of the output.
+	LockReasonCondition LockReason = ""
+	// LockReasonMaturity is the reason of the lock of a miner payout (block reward or tx fee),
+	// synthesized by the explorer as miner payouts can only be spent once the maturity delay has passed.
+	LockReasonMaturity LockReason = "maturity"
+)
+
+// CoinOutputLockReason returns the reason a coin output of the given lock type and description is locked.
+// Miner payouts are the only (height) locked coin outputs of which the description is synthesized by the explorer,
+// see minerPayoutDescription.
+func CoinOutputLockReason(lt LockType, description []byte) LockReason {
+	if lt == LockTypeHeight && isMinerPayoutDescription(description) {
+		return LockReasonMaturity
+	}
+	return LockReasonCondition
+}
+
+// LockValue represents a LockValue,
+// representing either a timestamp or a block height
+type LockValue uint64
+
+// String implements Stringer.String
+func (lv LockValue) String() string {
+	return strconv.FormatUint(uint64(lv), 10)
+}
+
+// LoadString implements StringLoader.LoadString
+func (lv *LockValue) LoadString(str string) error {
+	v, err := strconv.ParseUint(str, 10, 64)
+	if err != nil {
+		return err
+	}
+	*lv = LockValue(v)
+	return nil
+}
+
+// CoinOutputState represents the state of a coin output.
+type CoinOutputState uint8
+
+// The different states a coin output can be in.
+const (
+	CoinOutputStateNil CoinOutputState = iota
+	CoinOutputStateLiquid
+	CoinOutputStateLocked
+	CoinOutputStateSpent
+)
+
+// String implements Stringer.String
+func (cos CoinOutputState) String() string {
+	return strconv.FormatUint(uint64(cos), 10)
+}
+
+// LoadString implements StringLoader.LoadString
+func (cos *CoinOutputState) LoadString(str string) error {
+	v, err := strconv.ParseUint(str, 10, 8)
+	if err != nil {
+		return err
+	}
+	ncos := CoinOutputState(v)
+	if ncos == CoinOutputStateNil || ncos > CoinOutputStateSpent {
+		return fmt.Errorf("invalid coin output state %d", ncos)
+	}
+	*cos = ncos
+	return nil
+}
+
+type (
+	// RedisDatabase is a Database (client) implementation for Redis, using github.com/gomodule/redigo.
+	//
+	// Note that the state as stored in this redis database will be corrupt when an error (due to a code bug) occurs.
+	// This is however not a problem, as that bug should be fixed, and the redis database can be repopulated aftwards.
+	// Because of this it is probably wise to reserve a Redis database (slot), used only by this explorer.
+	//
+	// Note as well that this implementation will break as soon as you have multiple clients writing to the database.
+	// Many clients are allowed to read from the redis database, only this explorer module (and only as one instance) should write to
+	// the redis database (slot) used. Multiple writers are NOT supported! You've been warned.
+	//
+	// The same implementation is used for a Redis Cluster (see NewRedisClusterDatabase),
+	// for which reason no (Lua-scripted) command ever accesses keys of different hash slots.
+	//
+	// Unless connected to a Redis Cluster, it implements CheckpointDatabase: the value (as of the last checkpoint)
+	// of every key written by a batch since is dumped into the undo HASH, as part of the MULTI/EXEC transaction of that batch,
+	// such that the values written since can be restored after a crash. As the undo HASH is written together
+	// with the keys of the batch, checkpoints aren't supported when connected to a Redis Cluster.
+	//
+	// Following key (templates) are reserved by this Redis database implementation,
+	// all of them prefixed with the (optional) key prefix given when creating the database, e.g. "tft:standard:",
+	// such that multiple networks can share a single Redis database:
+	//
+	//	  internal keys:
+	//	  <prefix>state												(JSON) used for internal state of this explorer, in JSON format
+	//	  <prefix>checkpoint											(JSON) last checkpoint of the explorer state and network stats
+	//	  <prefix>undo												(HASH) dumped value (as of the last checkpoint) of every key written since
+	//	  <prefix>cos													(custom) all coin outputs
+	//	  <prefix>spent:<coinOutputIDHex[:4]>							(custom) spent coin outputs and their spend height, if archived
+	//	  <prefix>bs:<blockStakeOutputIDHex[:4]>						(custom) all block stake outputs
+	//	  <prefix>bs.locks.height										(ZSET) block stake outputs locked by height, scored by lock height
+	//	  <prefix>bs.locks.time										(ZSET) block stake outputs locked by time, scored by lock timestamp
+	//
+	//	  public keys:
+	//	  <prefix>stats												(JSON) used for global network statistics
+	//	  <prefix>health												(JSON) used for the chain health (score), suitable for status pages
+	//	  <prefix>genesis												(JSON(GenesisAllocation)) coin and block stake outputs allocated by the genesis block
+	//	  <prefix>addresses											(SET) set of unique wallet addresses used (even if reverted) in the network
+	//	  <prefix>addresses.count										(integer) amount of unique wallet addresses stored in the addresses SET
+	//	  <prefix>lcos.locked.height									(ZSET) coin outputs still locked by height, scored by unlock height
+	//	  <prefix>lcos.locked.time										(ZSET) coin outputs still locked by time, scored by unlock timestamp
+	//	  <prefix>lcos.unlocked.height								(ZSET) coin outputs locked by height which unlocked already, scored by unlock height
+	//	  <prefix>lcos.unlocked.time									(ZSET) coin outputs locked by time which unlocked already, scored by unlock timestamp
+	//	  <prefix>a.stats												(mapping prefix->JSON(AddressPrefixStats))
+	//																					balance rolled up per address prefix (a:<prefix> bucket)
+	//	  <prefix>richlist											(ZSET) addresses with a non-zero coin balance, scored by their total balance
+	//	  <prefix>richlist.distribution								(mapping range->integer) amount of ranked addresses per balance range
+	//	  <prefix>stats.blockcreators									(mapping address->JSON(BlockCreatorStats)) blocks created by each block creator
+	//	  <prefix>stats.blockcreators.rank							(ZSET) block creators, scored by the amount of blocks they created
+	//    <prefix>address:<unlockHashHex>:balance						(JSON) used by all wallet addresses
+	//    <prefix>address:<unlockHashHex>:outputs.locked				(mapping id->JSON(output))
+	//																					used to store locked (by time or blockHeight) outputs destined for an address
+	//    <prefix>address:<unlockHashHex>:multisig.addresses			(SET) used in both directions for multisig (wallet) addresses
+	//    <prefix>activity											(mapping address->JSON(AddressActivity)) first and last block each address was active in
+	//    <prefix>totals												(mapping address->JSON(AddressTotals)) total coins received and sent by each address
+	//    <prefix>activity.last										(ZSET) addresses, scored by the timestamp of the last block they were active in
+	//    <prefix>activity.undo										(mapping height->JSON(address->AddressActivity))
+	//																					previous activity of the addresses active in each block, null if first seen
+	//    <prefix>stats:day:<YYYY-MM-DD>								(mapping blocks|txCount|...|velocity->JSON(value))
+	//																					blocks, transactions, value transferred, fees (stats), new and active addresses and velocity of a day
+	//    <prefix>stats:day.undo										(mapping height->JSON(DailyStats)) stats rolled up for each block
+	//    <prefix>stats:rolling										(mapping 24h|7d->JSON(RollingStats)) network activity within each rolling window
+	//    <prefix>stats:rolling.blocks								(mapping height->JSON) stats, timestamp and replaced rolling stats of each block
+	//    <prefix>stats:history										(mapping YYYY-MM-DD->JSON(NetworkStatsSnapshot)) network stats as of the latest block of each day
+	//    <prefix>stats:day.senders:<YYYY-MM-DD>						(ZSET) top addresses of a day, scored by the coins they sent that day
+	//    <prefix>stats:day.receivers:<YYYY-MM-DD>					(ZSET) top addresses of a day, scored by the coins they received that day
+	//    <prefix>minerpayouts:<unlockHashHex>						(ZSET) JSON(MinerPayoutRecord) of each miner payout received by an address,
+	//																					scored by block height
+	//    <prefix>history:<unlockHashHex>							(ZSET) JSON(AddressHistoryRecord) of each tx in which an address sent or received coins,
+	//																					scored by block height
+	//    <prefix>counterparties:<unlockHashHex>						(ZSET) most frequent counterparties of an address, scored by tx count
+	//    <prefix>counterparties.totals:<unlockHashHex>				(mapping counterparty->JSON(AddressCounterparty))
+	//    <prefix>flows												(mapping total|<YYYY-MM-DD>->JSON(WalletGroupFlows))
+	//																					sweeps and refills between the labeled hot and cold wallets
+	//    <prefix>multisig.spends:<unlockHashHex>					(mapping coinOutputID->JSON([]UnlockHash))
+	//																					the owners that signed each spent coin output of a multisig wallet
+	//    <prefix>multisig.signers:<unlockHashHex>					(mapping owner->JSON(MultisigSignerStats))
+	//																					the signing activity of each owner of a multisig wallet
+	//    <prefix>blocksummaries										(mapping height->JSON(BlockSummary))
+	//																					output count, value and value histogram of each block
+	//    <prefix>blocks												(mapping height->JSON(BlockRecord))
+	//																					ID, parent, timestamp, miner payouts and tx IDs of each block
+	//    <prefix>blocks.ids											(mapping blockID->height) height of each block stored in the blocks HASH
+	//    <prefix>tx:<txID>											(JSON(TransactionRecord)) record of each transaction
+	//    <prefix>provenance:<coinOutputIDHex[:4]>					(mapping coinOutputIDHex[4:]->JSON(CoinOutputProvenance))
+	//																					the block and tx which created and spent each coin output
+	//    <prefix>atomicswap:<coinOutputID>							(JSON(AtomicSwapContract)) details and state of each atomic swap contract
+	//    <prefix>atomicswaps:<unlockHashHex>						(SET) IDs of the atomic swap contracts sent or received by an address
+	//    <prefix>data:<hash>										(SET) IDs of the transactions of which the arbitrary data has the given hash
+	//    <prefix>data.prefix:<prefixHex>							(mapping txID->data) arbitrary data of the transactions, by its prefix
+	//    <prefix>mintconditions										(LIST) JSON(MintConditionChange) of each change of the mint condition, oldest first
+	//    <prefix>bots												(mapping botID->JSON([]BotRecord))
+	//																					all states of each 3Bot record, latest last
+	//    <prefix>bots.names											(mapping name->botID) the 3Bot owning each name
+	//    <prefix>erc20.addresses									(mapping erc20Address->unlockHash) TFT address registered for each ERC20 address
+	//    <prefix>erc20.unlockhashes									(mapping unlockHash->erc20Address) ERC20 address registered for each TFT address
+	//    <prefix>erc20.stats										(JSON(ERC20BridgeStats)) volumes bridged from and to ERC20 tokens
+	//    <prefix>balancesnapshots									(mapping height->JSON(NetworkStats))
+	//																					network stats of each balance snapshot
+	//    <prefix>balancesnapshot:<height>							(mapping address->JSON(SnapshotBalance))
+	//																					balance of all wallets (with a non-zero balance) at a snapshotted height
+	//
+	// If enabled (see DatabaseConfig.PublishEvents), the following (pub/sub) channels are published to,
+	// prefixed with the same key prefix, each message being a JSON(Event) published once a consensus change is stored:
+	//
+	//	  <prefix>events:block										block-applied and block-reverted events, for every block
+	//	  <prefix>events:output										output-created and output-spent events, for every block applied
+	//	  <prefix>events:wallet:<unlockHashHex>						balance-changed events, for every consensus change involving the address
+	//
+	// Rivine Value Encodings:
+	//	 + addresses are Hex-encoded and the exact format (and how it is created) is described in:
+	//     https://github.com/rivine/rivine/blob/master/doc/transactions/unlockhash.md#textstring-encoding
+	//   + currencies are encoded as described in https://godoc.org/math/big#Int.Text
+	//     using base 10, and using the smallest coin unit as value (e.g. 10^-9 TFT)
+	//   + coin outputs are stored in the Rivine-defined JSON format, described in:
+	//     https://github.com/rivine/rivine/blob/master/doc/transactions/transaction.md#json-encoding-of-outputs-in-v0-transactions (v0 tx) and
+	//     https://github.com/rivine/rivine/blob/master/doc/transactions/transaction.md#json-encoding-of-outputs-in-v1-transactions (v1 tx)
+	//
+	// JSON formats of value types defined by this module:
+	//
+	//  example of global stats (stored under <prefix>stats):
+	//    { // see: NetworkStats
+	//    	"timestamp": 1533714154,
+	//    	"blockHeight": 77185,
+	//    	"txCount": 77501,
+	//    	"valueTxCount": 317,
+	//    	"coinOutputCount": 78637,
+	//    	"lockedCoinOutputCount": 743,
+	//    	"coinInputCount": 356,
+	//    	"minerPayoutCount": 77424,
+	//    	"minerPayouts": "77216500000001",
+	//    	"coins": "695176216500000001",
+	//    	"lockedCoins": "4899281850000000"
+	//    }
+	//
+	//  example of wallet balance (stored under <prefix>address:<unlockHashHex>:balance)
+	//    { // see: AddressBalance
+	//        "locked": "0",
+	//        "unlocked": "250000000000"
+	//    }
+	RedisDatabase struct {
+		// The redis connection, no time out
+		conn redis.Conn
+		// prefix of all keys, empty if not used
+		keyPrefix string
+		// pipeline is the connection wrapped by conn, batching all writes of a consensus change,
+		// see Begin and Commit for more information
+		pipeline *pipelinedConn
+		// set when an address was added to the addresses SET by a batch which isn't committed yet
+		addressesAdded bool
+		// addresses of which the counterparties are to be trimmed once the batch in progress is committed
+		trimAddresses map[types.UnlockHash]struct{}
+		// addresses of which the coin balance changed, to be ranked in the rich list once the batch in progress is committed
+		rankAddresses map[types.UnlockHash]struct{}
+
+		// encoder used to encode all (structured) values
+		encoder Encoder
+
+		blockFrequency LockValue
+		// value of a single coin, used to bucket the ranked addresses by balance (see BalanceDistribution)
+		oneCoin types.Currency
+
+		// whether or not the events of every consensus change are published, see PublishEvents
+		publishEvents bool
+		// whether or not spent coin outputs are moved to the spent coin outputs, see SpendCoinOutput
+		archiveSpentOutputs bool
+
+		// cached version of the chain stats
+		networkBlockHeight types.BlockHeight
+		networkTime        types.Timestamp
+
+		// All Lua scripts used by this redis client implementation, updating a wallet and the address prefix stats,
+		// see redisscripts.go. Loaded when creating the client, and using the script's SHA1 (EVALSHA) afterwards.
+		// Each script only accesses the single key passed to it, such that it can be used in a Redis Cluster as well.
+		// As the reply of a script is never required, scripts can be part of a transaction.
+		walletScript, addressPrefixStatsScript *redis.Script
+		// The Lua scripts trimming counterparties, ranking an address in the rich list and updating the address count,
+		// which access multiple keys, and are thus only loaded (and used) if transactional, see Commit.
+		counterpartiesScript, richListScript, addressCountScript *redis.Script
+
+		// deltas of the address prefix stats, not stored yet
+		prefixDeltas map[string]*addressPrefixDelta
+	}
+)
+
+var (
+	_ SnapshotDatabase             = (*RedisDatabase)(nil)
+	_ TransactionalDatabase        = (*RedisDatabase)(nil)
+	_ BatchDatabase                = (*RedisDatabase)(nil)
+	_ AddressPrefixDatabase        = (*RedisDatabase)(nil)
+	_ BalanceSnapshotDatabase      = (*RedisDatabase)(nil)
+	_ BlockDatabase                = (*RedisDatabase)(nil)
+	_ TransactionDatabase          = (*RedisDatabase)(nil)
+	_ BlockStakeDatabase           = (*RedisDatabase)(nil)
+	_ AtomicSwapDatabase           = (*RedisDatabase)(nil)
+	_ ArbitraryDataDatabase        = (*RedisDatabase)(nil)
+	_ MintConditionDatabase        = (*RedisDatabase)(nil)
+	_ ThreeBotDatabase             = (*RedisDatabase)(nil)
+	_ ERC20Database                = (*RedisDatabase)(nil)
+	_ CoinOutputProvenanceDatabase = (*RedisDatabase)(nil)
+	_ AddressActivityDatabase      = (*RedisDatabase)(nil)
+	_ RichListDatabase             = (*RedisDatabase)(nil)
+	_ BalanceDistributionDatabase  = (*RedisDatabase)(nil)
+	_ CoinOutputSetDatabase        = (*RedisDatabase)(nil)
+	_ MinerPayoutDatabase          = (*RedisDatabase)(nil)
+	_ BlockCreatorDatabase         = (*RedisDatabase)(nil)
+	_ GenesisDatabase              = (*RedisDatabase)(nil)
+	_ AddressHistoryDatabase       = (*RedisDatabase)(nil)
+	_ AddressTotalsDatabase        = (*RedisDatabase)(nil)
+	_ DailyStatsDatabase           = (*RedisDatabase)(nil)
+	_ RollingStatsDatabase         = (*RedisDatabase)(nil)
+	_ NetworkStatsHistoryDatabase  = (*RedisDatabase)(nil)
+	_ DailyTopAddressesDatabase    = (*RedisDatabase)(nil)
+	_ WebhookDatabase              = (*RedisDatabase)(nil)
+	_ WalletOutputsDatabase        = (*RedisDatabase)(nil)
+	_ CheckpointDatabase           = (*RedisDatabase)(nil)
+)
+
+type (
+	// AddressBalance is used to store the locked/unlocked balance of a wallet (linked to an address)
+	AddressBalance struct {
+		Locked   types.Currency `json:"locked,omitempty"`
+		Unlocked types.Currency `json:"unlocked,omitempty"`
+	}
+	// DatabaseCoinOutput is used to store all spent/unspent coin outputs in the custom CSV format (used internally only)
+	DatabaseCoinOutput struct {
+		UnlockHash   types.UnlockHash
+		CoinValue    types.Currency
+		State        CoinOutputState
+		LockType     LockType
+		LockValue    LockValue
+		Description  types.ByteSlice
+		RawCondition types.ByteSlice
+	}
+	// DatabaseCoinOutputLock is used to store the lock value and a reference to its parent CoinOutput,
+	// as to store the lock in a scoped bucket, as done prior to schema version 3.
+	DatabaseCoinOutputLock struct {
+		CoinOutputID types.CoinOutputID
+		LockValue    LockValue
+	}
+	// DatabaseCoinOutputResult is returned when updating/marking a CoinOutput.
+	DatabaseCoinOutputResult struct {
+		CoinOutputID types.CoinOutputID
+		UnlockHash   types.UnlockHash
+		CoinValue    types.Currency
+		LockType     LockType
+		LockValue    LockValue
+		Description  types.ByteSlice
+		RawCondition types.ByteSlice
+	}
+)
+
+const csvSeperator = ","
+
+// String implements Stringer.String
+func (co DatabaseCoinOutput) String() string {
+	str := FormatStringers(csvSeperator, co.State, co.UnlockHash, co.CoinValue, co.LockType, co.LockValue, co.Description, co.RawCondition)
+	return str
+}
+
+// Info returns all stored data of the coin output, identified by the given ID,
+// decoding its raw condition.
+func (co DatabaseCoinOutput) Info(id types.CoinOutputID) (CoinOutputInfo, error) {
+	info := CoinOutputInfo{
+		ID:           id,
+		UnlockHash:   co.UnlockHash,
+		Value:        co.CoinValue,
+		State:        co.State,
+		LockType:     co.LockType,
+		LockValue:    co.LockValue,
+		Description:  co.Description,
+		RawCondition: co.RawCondition,
+	}
+	err := info.decodeCondition()
+	if err != nil {
+		return CoinOutputInfo{}, err
+	}
+	return info, nil
+}
+
+// result returns the coin output, identified by the given ID, as the result of updating it.
+func (co DatabaseCoinOutput) result(id types.CoinOutputID) DatabaseCoinOutputResult {
+	return DatabaseCoinOutputResult{
+		CoinOutputID: id,
+		UnlockHash:   co.UnlockHash,
+		CoinValue:    co.CoinValue,
+		LockType:     co.LockType,
+		LockValue:    co.LockValue,
+		Description:  co.Description,
+		RawCondition: co.RawCondition,
+	}
+}
+
+// decodeCondition decodes the raw condition of the coin output,
+// flagging the coin output as burned, or as protected by an unknown condition.
+func (info *CoinOutputInfo) decodeCondition() (err error) {
+	info.Condition, info.UnknownCondition, err = decodeCondition(info.RawCondition)
+	if err != nil {
+		return err
+	}
+	info.Burned = isUnspendable(info.Condition)
+	return nil
+}
+
+// LoadString implements StringLoader.LoadString
+func (co *DatabaseCoinOutput) LoadString(str string) error {
+	return parseCoinOutputStringLoaders(str, &co.RawCondition, &co.State, &co.UnlockHash, &co.CoinValue, &co.LockType, &co.LockValue, &co.Description)
+}
+
+// parseCoinOutputStringLoaders parses a coin output CSV record using ParseStringLoaders,
+// the raw condition being the last field. Coin outputs stored prior to storing their raw condition
+// lack that field, in which case the raw condition is left empty, meaning the condition is unknown.
+func parseCoinOutputStringLoaders(csv string, rawCondition *types.ByteSlice, stringLoaders ...StringLoader) error {
+	if strings.Count(csv, csvSeperator) == len(stringLoaders)-1 {
+		*rawCondition = nil
+		return ParseStringLoaders(csv, csvSeperator, stringLoaders...)
+	}
+	return ParseStringLoaders(csv, csvSeperator, append(stringLoaders, rawCondition)...)
+}
+
+// String implements Stringer.String
+func (col DatabaseCoinOutputLock) String() string {
+	return FormatStringers(csvSeperator, col.CoinOutputID, col.LockValue)
+}
+
+// LoadString implements StringLoader.LoadString
+func (col *DatabaseCoinOutputLock) LoadString(str string) error {
+	return ParseStringLoaders(str, csvSeperator, &col.CoinOutputID, &col.LockValue)
+}
+
+// String implements Stringer.String
+func (cor DatabaseCoinOutputResult) String() string {
+	return FormatStringers(csvSeperator, cor.CoinOutputID, cor.UnlockHash, cor.CoinValue, cor.LockType, cor.LockValue, cor.Description, cor.RawCondition)
+}
+
+// LoadString implements StringLoader.LoadString
+func (cor *DatabaseCoinOutputResult) LoadString(str string) error {
+	return parseCoinOutputStringLoaders(str, &cor.RawCondition, &cor.CoinOutputID, &cor.UnlockHash, &cor.CoinValue, &cor.LockType, &cor.LockValue, &cor.Description)
+}
+
+const (
+	internalKey          = "internal"
+	internalFieldState   = "state"
+	internalFieldNetwork = "network"
+	internalFieldParams  = "chainparams"
+	internalFieldAliases = "aliases"
+	// the webhooks of the watched addresses, see WebhookDatabase
+	internalFieldWebhooks = "webhooks"
+	internalFieldSchema   = "schema"
+	// the encoding of all (structured) values, see EncodingType
+	internalFieldEncoding = "encoding"
+
+	statsKey = "stats"
+
+	checkpointKey     = "checkpoint"
+	checkpointUndoKey = "undo"
+
+	blockEventsChannel        = "events:block"
+	outputEventsChannel       = "events:output"
+	walletEventsChannelPrefix = "events:wallet:"
+
+	healthKey = "health"
+
+	addressesKey      = "addresses"
+	addressesCountKey = "addresses.count"
+
+	addressPrefixStatsKey = "a.stats"
+
+	richListKey            = "richlist"
+	balanceDistributionKey = "richlist.distribution"
+
+	addressActivityKey     = "activity"
+	addressActivityUndoKey = "activity.undo"
+	addressActivityLastKey = "activity.last"
+
+	addressTotalsKey = "totals"
+
+	dailyStatsKey     = "stats:day"
+	dailyStatsUndoKey = "stats:day.undo"
+
+	rollingStatsKey       = "stats:rolling"
+	rollingStatsBlocksKey = "stats:rolling.blocks"
+
+	networkStatsHistoryKey = "stats:history"
+
+	dailySendersKey   = "stats:day.senders"
+	dailyReceiversKey = "stats:day.receivers"
+
+	minerPayoutsKey = "minerpayouts"
+
+	addressHistoryKey = "history"
+
+	walletLockedOutputsKey = "lockedoutputs"
+
+	blockCreatorsKey     = "stats.blockcreators"
+	blockCreatorsRankKey = "stats.blockcreators.rank"
+
+	counterpartiesKey       = "counterparties"
+	counterpartiesTotalsKey = "counterparties.totals"
+	// the maximum amount of (most frequent) counterparties tracked per address
+	maxCounterparties = 100
+
+	flowsKey        = "flows"
+	flowsFieldTotal = "total"
+
+	multisigSpendsKey  = "multisig.spends"
+	multisigSignersKey = "multisig.signers"
+
+	blockSummariesKey = "blocksummaries"
+
+	blocksKey   = "blocks"
+	blockIDsKey = "blocks.ids"
+
+	transactionKey = "tx"
+
+	coinOutputProvenanceKey = "provenance"
+
+	atomicSwapContractKey  = "atomicswap"
+	atomicSwapContractsKey = "atomicswaps"
+
+	arbitraryDataHashKey   = "data"
+	arbitraryDataPrefixKey = "data.prefix"
+
+	mintConditionsKey = "mintconditions"
+
+	botsKey     = "bots"
+	botNamesKey = "bots.names"
+
+	erc20AddressesKey    = "erc20.addresses"
+	erc20UnlockHashesKey = "erc20.unlockhashes"
+	erc20StatsKey        = "erc20.stats"
+
+	genesisKey = "genesis"
+
+	balanceSnapshotsKey = "balancesnapshots"
+	balanceSnapshotKey  = "balancesnapshot"
+
+	snapshotHoldKey = "snapshot.hold"
+	snapshotAckKey  = "snapshot.ack"
+	// the amount of seconds an acknowledgement of a snapshot hold is kept
+	snapshotAckTTL = 60
+
+	lockedCoinOutputsByHeightKey      = "lcos.locked.height"
+	lockedCoinOutputsByTimestampKey   = "lcos.locked.time"
+	unlockedCoinOutputsByHeightKey    = "lcos.unlocked.height"
+	unlockedCoinOutputsByTimestampKey = "lcos.unlocked.time"
+	// the lists of locked coin outputs, bucketed by lock height and lock timestamp range, prior to schema version 3
+	legacyLockedByHeightOutputsKey    = "lcos.height"
+	legacyLockedByTimestampOutputsKey = "lcos.time"
+
+	spentCoinOutputsKey = "spent"
+
+	blockStakeOutputsKey            = "bs"
+	lockedByHeightBlockStakesKey    = "bs.locks.height"
+	lockedByTimestampBlockStakesKey = "bs.locks.time"
+)
+
+func init() {
+	RegisterDatabaseDriver("redis", func(cfg DatabaseConfig) (Database, error) {
+		address := cfg.Address
+		if address == "" {
+			address = ":6379"
+		}
+		return configureRedisDatabase(cfg)(
+			NewRedisDatabase(address, cfg.Slot, cfg.KeyPrefix, cfg.Encoding, cfg.BlockchainInfo, cfg.ChainConstants, redisDialOptions(cfg)...))
+	})
+	RegisterDatabaseDriver("redis-cluster", func(cfg DatabaseConfig) (Database, error) {
+		if cfg.Slot != 0 {
+			return nil, fmt.Errorf("redis: database slot %d is not supported by Redis Cluster, only slot 0 is", cfg.Slot)
+		}
+		address := cfg.Address
+		if address == "" {
+			address = ":6379"
+		}
+		return configureRedisDatabase(cfg)(
+			NewRedisClusterDatabase(strings.Split(address, ","), cfg.KeyPrefix, cfg.Encoding, cfg.BlockchainInfo, cfg.ChainConstants, redisDialOptions(cfg)...))
+	})
+	RegisterDatabaseDriver("redis-sentinel", func(cfg DatabaseConfig) (Database, error) {
+		// address format: [<masterName>@]<sentinel>[,<sentinel>...]
+		masterName, address := "mymaster", cfg.Address
+		if idx := strings.LastIndex(address, "@"); idx >= 0 {
+			masterName, address = address[:idx], address[idx+1:]
+		}
+		if address == "" {
+			address = ":26379"
+		}
+		return configureRedisDatabase(cfg)(
+			NewRedisSentinelDatabase(masterName, strings.Split(address, ","), cfg.Slot, cfg.KeyPrefix, cfg.Encoding, cfg.BlockchainInfo, cfg.ChainConstants, redisDialOptions(cfg)...))
+	})
+}
+
+// redisDialOptions returns the dial options defined by the given config, shared by all Redis drivers.
+func redisDialOptions(cfg DatabaseConfig) []redis.DialOption {
+	var options []redis.DialOption
+	if cfg.Password != "" {
+		options = append(options, redis.DialPassword(cfg.Password))
+	}
+	if cfg.TLS {
+		options = append(options, redis.DialUseTLS(true))
+	}
+	return options
+}
+
+// configureRedisDatabase returns a function which applies the batch size, command rate, tracer and event and archive options
+// defined by the given config (if any) to an opened Redis Database, such that it can wrap any of the Redis constructors.
+func configureRedisDatabase(cfg DatabaseConfig) func(*RedisDatabase, error) (Database, error) {
+	return func(rdb *RedisDatabase, err error) (Database, error) {
+		if err != nil {
+			return nil, err
+		}
+		if cfg.BatchSize > 0 {
+			rdb.pipeline.batchSize = cfg.BatchSize
+		}
+		if cfg.CommandRate > 0 {
+			// limit the wrapped connection, such that deferred writes are limited as well
+			rdb.pipeline.Conn = newRateLimitedConn(rdb.pipeline.Conn, cfg.CommandRate)
+		}
+		if cfg.Tracer != nil {
+			// trace the wrapped connection, such that the round trips of deferred writes are traced as well
+			rdb.pipeline.Conn = newTracedConn(rdb.pipeline.Conn, cfg.Tracer)
+		}
+		rdb.publishEvents = cfg.PublishEvents
+		rdb.archiveSpentOutputs = cfg.ArchiveSpentOutputs
+		return rdb, nil
+	}
+}
+
+// NewRedisDatabase creates a new Redis Database client, used by the internal explorer module,
+// see RedisDatabase for more information.
+//
+// Should the connection fail, it is replaced by a new connection to the same address,
+// retrying with an exponential backoff, such that transient failures don't interrupt the explorer.
+// All keys are prefixed with the given key prefix, if not empty.
+// The given encoding is used to encode all (structured) values of a fresh dataset,
+// an existing dataset using the encoding it was created with.
+// Optional dial options can be given, e.g. to authenticate using a password or to connect using TLS.
+func NewRedisDatabase(address string, db int, keyPrefix string, encoding EncodingType, bcInfo types.BlockchainInfo, chainCts types.ChainConstants, options ...redis.DialOption) (*RedisDatabase, error) {
+	// take a (reconnecting) connection from a pool of TCP connections
+	conn, err := redispool.Dial(address, append(options, redis.DialDatabase(db))...)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to dial a Redis connection to tcp://%s@%d: %v", address, db, err)
+	}
+	return newRedisDatabase(conn, true, keyPrefix, encoding, bcInfo, chainCts)
+}
+
+// NewRedisClusterDatabase creates a new Redis Database client for a Redis Cluster,
+// using the given addresses as startup nodes. See RedisDatabase for more information.
+//
+// The keys used are the same as for a single Redis node, each command being routed
+// to the node serving the hash slot of its key.
+// As such the key prefix (if not empty) shouldn't contain a hash tag, as that would map all keys to a single hash slot.
+// The optional dial options are used to dial each node of the cluster.
+func NewRedisClusterDatabase(addresses []string, keyPrefix string, encoding EncodingType, bcInfo types.BlockchainInfo, chainCts types.ChainConstants, options ...redis.DialOption) (*RedisDatabase, error) {
+	conn, err := rediscluster.Dial(addresses, options...)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to dial a Redis Cluster connection to %s: %v", strings.Join(addresses, ","), err)
+	}
+	// a transaction cannot span the keys of multiple hash slots, hence the writes are only batched
+	return newRedisDatabase(conn, false, keyPrefix, encoding, bcInfo, chainCts)
+}
+
+// NewRedisSentinelDatabase creates a new Redis Database client for the master of a group monitored by Redis Sentinel,
+// using the given sentinel addresses to discover that master. See RedisDatabase for more information.
+//
+// Should the master fail over, the new master is discovered using the sentinels. Commands not yet replied to
+// are not resent to it, failing the consensus change being applied instead, as they might have been applied already.
+// All keys are prefixed with the given key prefix, if not empty.
+// The optional dial options are used to dial the master, not the sentinels.
+func NewRedisSentinelDatabase(masterName string, sentinels []string, db int, keyPrefix string, encoding EncodingType, bcInfo types.BlockchainInfo, chainCts types.ChainConstants, options ...redis.DialOption) (*RedisDatabase, error) {
+	conn, err := redissentinel.Dial(masterName, sentinels, append(options, redis.DialDatabase(db))...)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to dial a Redis Sentinel connection to master %s@%d via %s: %v",
+			masterName, db, strings.Join(sentinels, ","), err)
+	}
+	return newRedisDatabase(conn, true, keyPrefix, encoding, bcInfo, chainCts)
+}
+
+// newRedisDatabase creates a new Redis Database client, using the given connection,
+// applying each batch atomically (using MULTI/EXEC) if transactional, and prefixing all keys with the given key prefix.
+// The given encoding is only used if the dataset is fresh, see NewRedisDatabase.
+func newRedisDatabase(conn redis.Conn, transactional bool, keyPrefix string, encoding EncodingType, bcInfo types.BlockchainInfo, chainCts types.ChainConstants) (*RedisDatabase, error) {
+	// compute all keys and return the RedisDatabase instance
+	encoder, err := NewEncoder(encoding)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("redis: %v", err)
+	}
+	pipeline := newPipelinedConn(conn, DefaultRedisBatchSize, transactional)
+	rdb := RedisDatabase{
+		conn:           pipeline,
+		keyPrefix:      keyPrefix,
+		pipeline:       pipeline,
+		encoder:        encoder,
+		blockFrequency: LockValue(chainCts.BlockFrequency),
+		oneCoin:        chainCts.CurrencyUnits.OneCoin,
+		prefixDeltas:   make(map[string]*addressPrefixDelta),
+		trimAddresses:  make(map[types.UnlockHash]struct{}),
+		rankAddresses:  make(map[types.UnlockHash]struct{}),
+	}
+	// track the writes of every batch since the last checkpoint, if they are applied atomically (see SetCheckpoint)
+	if transactional {
+		pipeline.undoKey = rdb.key(checkpointUndoKey)
+	}
+	// ensure the network info is as expected (or register if this is a fresh db)
+	err = rdb.registerOrValidateNetworkInfo(bcInfo)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// use the encoding the stored values were encoded with
+	err = rdb.loadEncoding()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// create and load scripts
+	err = rdb.createAndLoadScripts()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create/load a lua script: %v", err)
+	}
+	return &rdb, nil
+}
+
+// Close implements Database.Close
+//
+// closes the internal redis db client connection
+func (rdb *RedisDatabase) Close() error {
+	err := rdb.conn.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close redis db client connection: %v", err)
+	}
+	return nil
+}
+
+// Begin implements TransactionalDatabase.Begin
+//
+// Starts a batch, pipelining all writes until the batch is committed (or the batch size is reached),
+// such that a consensus change doesn't require a round trip per write.
+// Unless connected to a Redis Cluster, all writes of a batch are applied atomically using MULTI/EXEC,
+// such that a crash never leaves a consensus change (usually a single block) partially applied.
+func (rdb *RedisDatabase) Begin() error {
+	if rdb.pipeline.batching {
+		return errors.New("redis: a batch is already in progress")
+	}
+	rdb.pipeline.Begin()
+	return nil
+}
+
+// Commit implements TransactionalDatabase.Commit
+//
+// Stores the address prefix stats updated by the batch as part of it, and flushes (or executes) all writes of the batch.
+// The counterparties updated by the batch are trimmed, the addresses of which the balance changed are ranked
+// in the rich list, and the address count is updated if addresses were added. As these read the data written
+// by the batch, they are updated by scripts as part of the MULTI/EXEC transaction if transactional,
+// or once the batch is flushed otherwise (when connected to a Redis Cluster).
+func (rdb *RedisDatabase) Commit() error {
+	if !rdb.pipeline.batching {
+		return errors.New("redis: no batch in progress")
+	}
+	err := rdb.storeAddressPrefixStats()
+	if err != nil {
+		return err
+	}
+	if rdb.pipeline.transactional {
+		err = rdb.updateBatchDerivedData()
+		if err != nil {
+			return err
+		}
+	}
+	err = rdb.pipeline.Commit()
+	if err != nil {
+		return fmt.Errorf("redis: failed to commit batch: %v", err)
+	}
+	if rdb.pipeline.transactional {
+		return nil
+	}
+	return rdb.updateBatchDerivedData()
+}
+
+// updateBatchDerivedData trims the counterparties updated by the batch, ranks the addresses of which the balance
+// changed in the rich list, and updates the address count if addresses were added, see Commit.
+func (rdb *RedisDatabase) updateBatchDerivedData() error {
+	for address := range rdb.trimAddresses {
+		err := rdb.trimCounterparties(address)
+		if err != nil {
+			return err
+		}
+		delete(rdb.trimAddresses, address)
+	}
+	for address := range rdb.rankAddresses {
+		err := rdb.rankAddress(address)
+		if err != nil {
+			return err
+		}
+		delete(rdb.rankAddresses, address)
+	}
+	if !rdb.addressesAdded {
+		return nil
+	}
+	rdb.addressesAdded = false
+	return rdb.updateAddressCount()
+}
+
+// internal logic to create and load scripts usd for advanced lua-script-driven logic
+func (rdb *RedisDatabase) createAndLoadScripts() (err error) {
+	rdb.walletScript, err = rdb.createAndLoadScript(walletScriptSource)
+	if err != nil {
+		return
+	}
+	rdb.addressPrefixStatsScript, err = rdb.createAndLoadScript(addressPrefixStatsScriptSource)
+	if err != nil {
+		return
+	}
+	if rdb.pipeline.transactional {
+		rdb.counterpartiesScript, err = rdb.createAndLoadScript(counterpartiesScriptSource)
+		if err != nil {
+			return
+		}
+		rdb.richListScript, err = rdb.createAndLoadScript(richListScriptSource)
+		if err != nil {
+			return
+		}
+		rdb.addressCountScript, err = rdb.createAndLoadScript(addressCountScriptSource)
+		if err != nil {
+			return
+		}
+	}
+
+	// all scripts loaded successfully
+	return nil
+}
+
+// createAndLoadScript creates and loads a script, of which the keys are given when it is evaluated (using EVALSHA),
+// prepending the functions used to decode and encode values using the encoding of the database.
+func (rdb *RedisDatabase) createAndLoadScript(src string) (*redis.Script, error) {
+	script := redis.NewScript(-1, luaCodecFunctions(rdb.encoder.Type())+src)
+	err := script.Load(rdb.conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Lua-Script: %v", err)
+	}
+	return script, nil
+}
+
+// ensureAddressCount sets the address count to the cardinality of the addresses SET,
+// in case no address count has been stored yet.
+func (rdb *RedisDatabase) ensureAddressCount() error {
+	n, err := redis.Uint64(rdb.conn.Do("SCARD", rdb.key(addressesKey)))
+	if err != nil {
+		return fmt.Errorf("failed to get the amount of unique addresses: %v", err)
+	}
+	err = RedisError(rdb.conn.Do("SETNX", rdb.key(addressesCountKey), n))
+	if err != nil {
+		return fmt.Errorf("failed to ensure the address count is stored: %v", err)
+	}
+	return nil
+}
+
+// ensureAddressPrefixStats computes the stats of all address prefixes used by the addresses SET,
+// in case no address prefix stats have been stored yet while addresses have.
+func (rdb *RedisDatabase) ensureAddressPrefixStats() error {
+	n, err := redis.Int(rdb.conn.Do("HLEN", rdb.key(addressPrefixStatsKey)))
+	if err != nil {
+		return fmt.Errorf("failed to get the amount of address prefix stats: %v", err)
+	}
+	if n > 0 {
+		return nil
+	}
+	strs, err := redis.Strings(rdb.conn.Do("SMEMBERS", rdb.key(addressesKey)))
+	if err != nil {
+		return fmt.Errorf("failed to get the unique addresses: %v", err)
+	}
+	prefixes := make(map[string]struct{})
+	for _, str := range strs {
+		if len(str) >= AddressPrefixLength {
+			prefixes[str[:AddressPrefixLength]] = struct{}{}
+		}
+	}
+	if len(prefixes) == 0 {
+		return nil
+	}
+	log.Printf("computing the stats of %d address prefixes...", len(prefixes))
+	for prefix := range prefixes {
+		stats, err := rdb.ComputeAddressPrefixStats(prefix)
+		if err != nil {
+			return fmt.Errorf("failed to compute the stats of address prefix %s: %v", prefix, err)
+		}
+		stats.Wallets = 0 // not rolled up, see GetAddressPrefixStats
+		err = RedisError(rdb.conn.Do("HSET", rdb.key(addressPrefixStatsKey), prefix, MustMarshal(rdb.encoder, stats)))
+		if err != nil {
+			return fmt.Errorf("failed to store the stats of address prefix %s: %v", prefix, err)
+		}
+	}
+	return nil
+}
+
+// ensureRichList ranks all wallets with a non-zero coin balance in the rich list,
+// in case no address has been ranked yet.
+func (rdb *RedisDatabase) ensureRichList() error {
+	n, err := redis.Int(rdb.conn.Do("ZCARD", rdb.key(richListKey)))
+	if err != nil {
+		return fmt.Errorf("failed to get the amount of ranked addresses: %v", err)
+	}
+	if n > 0 {
+		return nil
+	}
+	strs, err := redis.Strings(rdb.conn.Do("SMEMBERS", rdb.key(addressesKey)))
+	if err != nil {
+		return fmt.Errorf("failed to get the unique addresses: %v", err)
+	}
+	prefixes := make(map[string]struct{})
+	for _, str := range strs {
+		if len(str) >= AddressPrefixLength {
+			prefixes[str[:AddressPrefixLength]] = struct{}{}
+		}
+	}
+	if len(prefixes) == 0 {
+		return nil
+	}
+	log.Printf("ranking the wallets of %d address prefixes...", len(prefixes))
+	for prefix := range prefixes {
+		key := rdb.key("a:" + prefix)
+		values, err := redis.StringMap(rdb.conn.Do("HGETALL", key))
+		if err != nil {
+			return fmt.Errorf("failed to get wallets of %s: %v", key, err)
+		}
+		for field, value := range values {
+			var wallet WalletFocusBalance
+			err = rdb.encoder.Unmarshal([]byte(value), &wallet)
+			if err != nil {
+				return fmt.Errorf("failed to decode wallet at %s#%s: %v", key, field, err)
+			}
+			total := wallet.Balance.Unlocked.Add(wallet.Balance.Locked.Total)
+			if total.IsZero() {
+				continue
+			}
+			err = RedisError(rdb.conn.Do("ZADD", rdb.key(richListKey), total.String(), prefix+field))
+			if err != nil {
+				return fmt.Errorf("failed to rank %s%s in the rich list: %v", prefix, field, err)
+			}
+		}
+	}
+	return nil
+}
+
+// indexWalletLockedOutputs indexes the locked outputs of all wallets, see indexWalletLockedOutput.
+// As the members of the ZSETs are unique, outputs which are indexed already are left untouched.
+func (rdb *RedisDatabase) indexWalletLockedOutputs() error {
+	strs, err := redis.Strings(rdb.conn.Do("SMEMBERS", rdb.key(addressesKey)))
+	if err != nil {
+		return fmt.Errorf("failed to get the unique addresses: %v", err)
+	}
+	prefixes := make(map[string]struct{})
+	for _, str := range strs {
+		if len(str) >= AddressPrefixLength {
+			prefixes[str[:AddressPrefixLength]] = struct{}{}
+		}
+	}
+	if len(prefixes) == 0 {
+		return nil
+	}
+	log.Printf("indexing the locked outputs of the wallets of %d address prefixes...", len(prefixes))
+	for prefix := range prefixes {
+		key := rdb.key("a:" + prefix)
+		values, err := redis.StringMap(rdb.conn.Do("HGETALL", key))
+		if err != nil {
+			return fmt.Errorf("failed to get wallets of %s: %v", key, err)
+		}
+		for field, value := range values {
+			var address types.UnlockHash
+			err = address.LoadString(prefix + field)
+			if err != nil {
+				return fmt.Errorf("invalid address at %s#%s: %v", key, field, err)
+			}
+			var wallet Wallet
+			err = rdb.encoder.Unmarshal([]byte(value), &wallet)
+			if err != nil {
+				return fmt.Errorf("failed to decode wallet at %s#%s: %v", key, field, err)
+			}
+			for id, output := range wallet.Balance.Locked.Outputs {
+				err = RedisError(rdb.conn.Do("ZADD", rdb.getWalletLockedOutputsKey(address), 0, id.String()+JSONMarshal(output)))
+				if err != nil {
+					return fmt.Errorf("failed to index locked output %s of %s: %v", id.String(), address.String(), err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ensureBalanceDistribution (re)counts the addresses ranked in the rich list per balance range,
+// replacing the balance distribution stored so far, if any.
+func (rdb *RedisDatabase) ensureBalanceDistribution() error {
+	values, err := redis.Strings(rdb.conn.Do("ZRANGE", rdb.key(richListKey), 0, -1, "WITHSCORES"))
+	if err != nil {
+		return fmt.Errorf("failed to get the ranked addresses: %v", err)
+	}
+	var dist BalanceDistribution
+	for i := 1; i < len(values); i += 2 {
+		bucket, err := rdb.balanceDistributionBucket(values[i])
+		if err != nil {
+			return err
+		}
+		dist[bucket]++
+	}
+	for bucket, label := range BalanceDistributionLabels() {
+		err = RedisError(rdb.conn.Do("HSET", rdb.key(balanceDistributionKey), label, dist[bucket]))
+		if err != nil {
+			return fmt.Errorf("failed to store the balance distribution of %s: %v", label, err)
+		}
+	}
+	return nil
+}
+
+// upgradeLegacyCoinOutputs rewrites the coin outputs stored prior to storing their raw condition
+// in the CSV layout used since, their raw condition being left empty as it cannot be recovered
+// from the stored data, meaning their condition is unknown (see CoinOutputInfo.UnknownCondition).
+// Coin outputs stored using the protobuf encoding always define their raw condition.
+func (rdb *RedisDatabase) upgradeLegacyCoinOutputs() error {
+	if rdb.encoder.Type() == EncodingTypeProtobuf {
+		return nil
+	}
+	log.Printf("upgrading the coin outputs stored without their raw condition...")
+	seperators := strings.Count(DatabaseCoinOutput{}.String(), csvSeperator)
+	for bucket := 0; bucket <= 0xffff; bucket++ {
+		key := rdb.key(fmt.Sprintf("c:%04x", bucket))
+		values, err := redis.StringMap(rdb.conn.Do("HGETALL", key))
+		if err != nil {
+			return fmt.Errorf("failed to get coin outputs of %s: %v", key, err)
+		}
+		for field, value := range values {
+			if strings.Count(value, csvSeperator) >= seperators {
+				continue // stored in the current layout already
+			}
+			var co DatabaseCoinOutput
+			err = co.LoadString(value)
+			if err != nil {
+				return fmt.Errorf("failed to decode coin output at %s#%s: %v", key, field, err)
+			}
+			err = RedisError(rdb.conn.Do("HSET", key, field, co.String()))
+			if err != nil {
+				return fmt.Errorf("failed to upgrade coin output at %s#%s: %v", key, field, err)
+			}
+		}
+	}
+	return nil
+}
+
+// indexCoinOutputLocks indexes all coin outputs locked by a lock type in the ZSETs of locked and unlocked coin outputs,
+// depending on their state, deleting the lists these coin outputs were bucketed in prior to schema version 3.
+func (rdb *RedisDatabase) indexCoinOutputLocks() error {
+	log.Printf("indexing the locks of all coin outputs...")
+	return rdb.IterateCoinOutputs(func(info CoinOutputInfo) error {
+		var bucketKey string
+		switch info.LockType {
+		case LockTypeHeight:
+			bucketKey = rdb.key(legacyLockedByHeightOutputsKey) + ":" + info.LockValue.String()
+		case LockTypeTime:
+			bucketKey = rdb.key(legacyLockedByTimestampOutputsKey) + ":" + (info.LockValue - info.LockValue%7200).String()
+		default:
+			return nil
+		}
+		key := rdb.getCoinOutputLocksKey(info.LockType, info.State == CoinOutputStateLocked)
+		err := RedisError(rdb.conn.Do("ZADD", key, uint64(info.LockValue), info.ID.String()))
+		if err != nil {
+			return fmt.Errorf("failed to index lock of coin output %s at %s: %v", info.ID.String(), key, err)
+		}
+		err = RedisError(rdb.conn.Do("DEL", bucketKey))
+		if err != nil {
+			return fmt.Errorf("failed to delete lock bucket %s: %v", bucketKey, err)
+		}
+		return nil
+	})
+}
+
+// registerOrValidateNetworkInfo registeres the network name and chain name if it doesn't exist yet,
+// together with the latest schema version and the encoding used, otherwise it ensures that the returned network info matches the expected network info.
+func (rdb *RedisDatabase) registerOrValidateNetworkInfo(bcInfo types.BlockchainInfo) error {
+	networkInfo := NetworkInfo{
+		ChainName:   bcInfo.Name,
+		NetworkName: bcInfo.NetworkName,
+	}
+	rdb.conn.Send("HSETNX", rdb.key(internalKey), internalFieldNetwork, JSONMarshal(networkInfo))
+	rdb.conn.Send("HGET", rdb.key(internalKey), internalFieldNetwork)
+	replies, err := redis.Values(RedisFlushAndReceive(rdb.conn, 2))
+	if err != nil {
+		return fmt.Errorf("failed to register/validate network info: %v", err)
+	}
+	if len(replies) != 2 {
+		return errors.New("failed to register/validate network info: unexpected amount of replies received")
+	}
+	var receivedNetworkInfo NetworkInfo
+	err = RedisJSONValue(&receivedNetworkInfo)(replies[1], err)
+	if err != nil {
+		return fmt.Errorf("failed to validate network info: %v", err)
+	}
+	if receivedNetworkInfo != networkInfo {
+		return fmt.Errorf("cannot store data for chain %s/%s: db has already data for chain %s/%s stored",
+			networkInfo.ChainName, networkInfo.NetworkName,
+			receivedNetworkInfo.ChainName, receivedNetworkInfo.NetworkName)
+	}
+	if registered, _ := redis.Bool(replies[0], nil); !registered {
+		return nil
+	}
+	// a fresh dataset, which doesn't require any migration
+	err = RedisError(rdb.conn.Do("HSET", rdb.key(internalKey), internalFieldEncoding, rdb.encoder.Type().String()))
+	if err != nil {
+		return fmt.Errorf("failed to register encoding: %v", err)
+	}
+	return rdb.setSchemaVersion(rdb.LatestSchemaVersion())
+}
+
+// loadEncoding loads the encoding used to encode the stored values,
+// defaulting to JSON for datasets created prior to the encoding being configurable.
+func (rdb *RedisDatabase) loadEncoding() error {
+	str, err := redis.String(rdb.conn.Do("HGET", rdb.key(internalKey), internalFieldEncoding))
+	if err == redis.ErrNil {
+		str, err = EncodingTypeJSON.String(), nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get encoding: %v", err)
+	}
+	var et EncodingType
+	err = et.LoadString(str)
+	if err != nil {
+		return fmt.Errorf("failed to load encoding: %v", err)
+	}
+	if et != rdb.encoder.Type() {
+		log.Printf("using the %s encoding the stored values were encoded with, instead of the configured %s encoding",
+			et.String(), rdb.encoder.Type().String())
+		rdb.encoder, _ = NewEncoder(et)
+	}
+	return nil
+}
+
+// GetExplorerState implements Database.GetExplorerState
+func (rdb *RedisDatabase) GetExplorerState() (ExplorerState, error) {
+	var state ExplorerState
+	switch err := RedisValue(rdb.encoder, &state)(rdb.conn.Do("HGET", rdb.key(internalKey), internalFieldState)); err {
+	case nil:
+		return state, nil
+	case redis.ErrNil:
+		// default to fresh explorer state if not stored yet
+		return NewExplorerState(), nil
+	default:
+		return ExplorerState{}, err
+	}
+}
+
+// SetExplorerState implements Database.SetExplorerState
+func (rdb *RedisDatabase) SetExplorerState(state ExplorerState) error {
+	return rdb.pipeline.Write("HSET", rdb.key(internalKey), internalFieldState, MustMarshal(rdb.encoder, state))
+}
+
+// SupportsCheckpoints implements CheckpointDatabase.SupportsCheckpoints
+//
+// Checkpoints are only supported if all writes of a batch are applied atomically, i.e. unless connected to a Redis Cluster.
+func (rdb *RedisDatabase) SupportsCheckpoints() bool {
+	return rdb.pipeline.transactional
+}
+
+// SetCheckpoint implements CheckpointDatabase.SetCheckpoint
+//
+// clears the undo hash within the batch in progress, which is therefore applied without storing any value in it
+func (rdb *RedisDatabase) SetCheckpoint(checkpoint Checkpoint) error {
+	err := rdb.pipeline.Checkpoint()
+	if err != nil {
+		return fmt.Errorf("redis: failed to store checkpoint: %v", err)
+	}
+	return rdb.pipeline.Write("SET", rdb.key(checkpointKey), MustMarshal(rdb.encoder, checkpoint))
+}
+
+// RestoreCheckpoint implements CheckpointDatabase.RestoreCheckpoint
+//
+// restores the dumped values of the undo hash, and clears it, using a single MULTI/EXEC transaction
+func (rdb *RedisDatabase) RestoreCheckpoint() (Checkpoint, int, error) {
+	if rdb.pipeline.batching {
+		return Checkpoint{}, 0, errors.New("redis: cannot restore a checkpoint while a batch is in progress")
+	}
+	var checkpoint Checkpoint
+	switch err := RedisValue(rdb.encoder, &checkpoint)(rdb.conn.Do("GET", rdb.key(checkpointKey))); err {
+	case nil:
+	case redis.ErrNil:
+		return Checkpoint{}, 0, ErrNotFound
+	default:
+		return Checkpoint{}, 0, fmt.Errorf("redis: failed to get checkpoint: %v", err)
+	}
+	undo, err := redis.StringMap(rdb.conn.Do("HGETALL", rdb.key(checkpointUndoKey)))
+	if err != nil {
+		return Checkpoint{}, 0, fmt.Errorf("redis: failed to get undo hash: %v", err)
+	}
+	rdb.pipeline.Begin()
+	for key, value := range undo {
+		if strings.HasPrefix(value, "1") {
+			err = rdb.pipeline.Write("RESTORE", key, 0, value[1:], "REPLACE")
+		} else {
+			err = rdb.pipeline.Write("DEL", key)
+		}
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = rdb.pipeline.Write("HSET", rdb.key(internalKey), internalFieldState, MustMarshal(rdb.encoder, checkpoint.State))
+	}
+	if err == nil {
+		err = rdb.pipeline.Write("SET", rdb.key(statsKey), MustMarshal(rdb.encoder, checkpoint.Stats))
+	}
+	if err == nil {
+		err = rdb.pipeline.Checkpoint()
+	}
+	if cerr := rdb.pipeline.Commit(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return Checkpoint{}, 0, fmt.Errorf("redis: failed to restore %d values: %v", len(undo), err)
+	}
+	return checkpoint, len(undo), nil
+}
+
+// GetNetworkStats implements Database.GetNetworkStats
+func (rdb *RedisDatabase) GetNetworkStats() (NetworkStats, error) {
+	var stats NetworkStats
+	switch err := RedisValue(rdb.encoder, &stats)(rdb.conn.Do("GET", rdb.key(statsKey))); err {
+	case nil:
+		err = upgradeNetworkStats(&stats)
+		if err != nil {
+			return NetworkStats{}, fmt.Errorf("redis: failed to upgrade network stats: %v", err)
+		}
+		rdb.networkTime, rdb.networkBlockHeight = stats.Timestamp, stats.BlockHeight
+		return stats, nil
+	case redis.ErrNil:
+		// default to fresh network stats if not stored yet
+		stats = NewNetworkStats()
+		rdb.networkTime, rdb.networkBlockHeight = stats.Timestamp, stats.BlockHeight
+		return stats, nil
+	default:
+		return NetworkStats{}, err
+	}
+}
+
+// SetNetworkStats implements Database.SetNetworkStats
+func (rdb *RedisDatabase) SetNetworkStats(stats NetworkStats) error {
+	err := rdb.pipeline.Write("SET", rdb.key(statsKey), MustMarshal(rdb.encoder, stats))
+	if err != nil {
+		return err
+	}
+	rdb.networkTime, rdb.networkBlockHeight = stats.Timestamp, stats.BlockHeight
+	return nil
+}
+
+// SetChainHealth implements Database.SetChainHealth
+func (rdb *RedisDatabase) SetChainHealth(health ChainHealth) error {
+	return rdb.pipeline.Write("SET", rdb.key(healthKey), MustMarshal(rdb.encoder, health))
+}
+
+// GetChainParametersHistory implements Database.GetChainParametersHistory
+func (rdb *RedisDatabase) GetChainParametersHistory() ([]ChainParametersRecord, error) {
+	var history []ChainParametersRecord
+	switch err := RedisValue(rdb.encoder, &history)(rdb.conn.Do("HGET", rdb.key(internalKey), internalFieldParams)); err {
+	case nil, redis.ErrNil:
+		// no history is stored yet for a fresh database
+		return history, nil
+	default:
+		return nil, err
+	}
+}
+
+// SetChainParametersHistory implements Database.SetChainParametersHistory
+func (rdb *RedisDatabase) SetChainParametersHistory(history []ChainParametersRecord) error {
+	return RedisError(rdb.conn.Do("HSET", rdb.key(internalKey), internalFieldParams, MustMarshal(rdb.encoder, history)))
+}
+
+// GetAddressAliases implements Database.GetAddressAliases
+func (rdb *RedisDatabase) GetAddressAliases() ([]AddressAlias, error) {
+	var aliases []AddressAlias
+	switch err := RedisValue(rdb.encoder, &aliases)(rdb.conn.Do("HGET", rdb.key(internalKey), internalFieldAliases)); err {
+	case nil, redis.ErrNil:
+		// no aliases are recorded yet
+		return aliases, nil
+	default:
+		return nil, err
+	}
+}
+
+// SetAddressAliases implements Database.SetAddressAliases
+func (rdb *RedisDatabase) SetAddressAliases(aliases []AddressAlias) error {
+	return RedisError(rdb.conn.Do("HSET", rdb.key(internalKey), internalFieldAliases, MustMarshal(rdb.encoder, aliases)))
+}
+
+// GetWebhooks implements WebhookDatabase.GetWebhooks
+func (rdb *RedisDatabase) GetWebhooks() ([]Webhook, error) {
+	var webhooks []Webhook
+	switch err := RedisValue(rdb.encoder, &webhooks)(rdb.conn.Do("HGET", rdb.key(internalKey), internalFieldWebhooks)); err {
+	case nil, redis.ErrNil:
+		// no webhooks are registered yet
+		return webhooks, nil
+	default:
+		return nil, err
+	}
+}
+
+// SetWebhooks implements WebhookDatabase.SetWebhooks
+func (rdb *RedisDatabase) SetWebhooks(webhooks []Webhook) error {
+	return RedisError(rdb.conn.Do("HSET", rdb.key(internalKey), internalFieldWebhooks, MustMarshal(rdb.encoder, webhooks)))
+}
+
+// GetSnapshotHold implements SnapshotDatabase.GetSnapshotHold
+func (rdb *RedisDatabase) GetSnapshotHold() (string, error) {
+	token, err := redis.String(rdb.conn.Do("GET", rdb.key(snapshotHoldKey)))
+	if err == redis.ErrNil {
+		return "", nil
+	}
+	return token, err
+}
+
+// AcknowledgeSnapshotHold implements SnapshotDatabase.AcknowledgeSnapshotHold
+func (rdb *RedisDatabase) AcknowledgeSnapshotHold(token string) error {
+	return RedisError(rdb.conn.Do("SET", rdb.key(snapshotAckKey), token, "EX", snapshotAckTTL))
+}
+
+// AddCoinOutput implements Database.AddCoinOutput
+func (rdb *RedisDatabase) AddCoinOutput(id types.CoinOutputID, co CoinOutput) error {
+	uh := co.Condition.UnlockHash()
+
+	// store output
+	coinOutputKey, coinOutputField := rdb.getCoinOutputKeyAndField(id)
+	err := rdb.pipeline.Write("HSET", coinOutputKey, coinOutputField, rdb.marshalCoinOutput(DatabaseCoinOutput{
+		UnlockHash:   uh,
+		CoinValue:    co.Value,
+		State:        CoinOutputStateLiquid,
+		LockType:     LockTypeNone,
+		LockValue:    0,
+		Description:  co.Description,
+		RawCondition: EncodeCondition(co.Condition),
+	}))
+	if err != nil {
+		return fmt.Errorf("redis: failed to add coinoutput %s: %v", id.String(), err)
+	}
+
+	// increase coin count
+	err = rdb.updateWallet(uh, co.Value.Big(), nil, walletOpAddUnlocked, co.Value.String())
+	if err != nil {
+		return err
+	}
+	return rdb.addAddress(uh)
+}
+
+// AddLockedCoinOutput implements Database.AddLockedCoinOutput
+func (rdb *RedisDatabase) AddLockedCoinOutput(id types.CoinOutputID, co CoinOutput, lt LockType, lockValue LockValue) error {
+	uh := co.Condition.UnlockHash()
+
+	// schedule the unlock of the output
+	err := rdb.pipeline.Write("ZADD", rdb.getCoinOutputLocksKey(lt, true), uint64(lockValue), id.String())
+	// store output
+	coinOutputKey, coinOutputField := rdb.getCoinOutputKeyAndField(id)
+	if err == nil {
+		err = rdb.pipeline.Write("HSET", coinOutputKey, coinOutputField, rdb.marshalCoinOutput(DatabaseCoinOutput{
+			UnlockHash:   uh,
+			CoinValue:    co.Value,
+			State:        CoinOutputStateLocked,
+			LockType:     lt,
+			LockValue:    lockValue,
+			Description:  co.Description,
+			RawCondition: EncodeCondition(co.Condition),
+		}))
+	}
+	if err != nil {
+		return fmt.Errorf("redis: failed to add coinoutput %s: %v", id.String(), err)
+	}
+
+	// add the locked output to the wallet
+	output := WalletLockedOutput{
+		Amount:      co.Value,
+		LockedUntil: rdb.lockValueAsLockTime(lt, lockValue),
+		Description: co.Description,
+		Reason:      CoinOutputLockReason(lt, co.Description),
+	}
+	err = rdb.updateWallet(uh, nil, co.Value.Big(), walletOpLockArgs(id, output)...)
+	if err == nil {
+		err = rdb.indexWalletLockedOutput(uh, id, output)
+	}
+	if err != nil {
+		return err
+	}
+	return rdb.addAddress(uh)
+}
+
+// addAddress adds the given address to the addresses SET, an address never gets deleted.
+//
+// The address count is updated to the cardinality of that SET once the batch in progress is committed,
+// or immediately if no batch is in progress. The addresses SET and the address count are only
+// updated by a single (Lua) script if transactional, as both keys map to different hash slots,
+// which isn't supported by Redis Cluster.
+func (rdb *RedisDatabase) addAddress(uh types.UnlockHash) error {
+	err := rdb.pipeline.Write("SADD", rdb.key(addressesKey), uh.String())
+	if err != nil {
+		return fmt.Errorf("redis: failed to add address to %s: %v", rdb.key(addressesKey), err)
+	}
+	if rdb.pipeline.batching {
+		rdb.addressesAdded = true
+		return nil
+	}
+	return rdb.updateAddressCount()
+}
+
+// updateAddressCount sets the address count to the cardinality of the addresses SET,
+// using the address count script (deferred if a batch is in progress) if transactional.
+func (rdb *RedisDatabase) updateAddressCount() error {
+	if rdb.pipeline.transactional {
+		err := rdb.pipeline.Write("EVALSHA", rdb.addressCountScript.Hash(), 2,
+			rdb.key(addressesKey), rdb.key(addressesCountKey))
+		if err != nil {
+			return fmt.Errorf("redis: failed to update address count at %s: %v", rdb.key(addressesCountKey), err)
+		}
+		return nil
+	}
+	n, err := redis.Uint64(rdb.conn.Do("SCARD", rdb.key(addressesKey)))
+	if err != nil {
+		return fmt.Errorf("redis: failed to get the amount of unique addresses: %v", err)
+	}
+	err = RedisError(rdb.conn.Do("SET", rdb.key(addressesCountKey), n))
+	if err != nil {
+		return fmt.Errorf("redis: failed to update address count at %s: %v", rdb.key(addressesCountKey), err)
+	}
+	return nil
+}
+
+// SpendCoinOutput implements Database.SpendCoinOutput
+//
+// The spent coin output is kept, marked as spent, or moved to the spent coin outputs if archived,
+// together with the height of the block spending it (see DatabaseConfig.ArchiveSpentOutputs).
+func (rdb *RedisDatabase) SpendCoinOutput(id types.CoinOutputID) (types.UnlockHash, types.Currency, error) {
+	var (
+		result DatabaseCoinOutputResult
+		err    error
+	)
+	if rdb.archiveSpentOutputs {
+		result, err = rdb.archiveCoinOutput(id)
+	} else {
+		result, err = rdb.updateCoinOutputState(id, CoinOutputStateLiquid, CoinOutputStateSpent)
+	}
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf(
+			"redis: failed to spend coin output: cannot update coin output %s: %v",
+			id.String(), err)
+	}
+
+	// update unlocked coins
+	err = rdb.updateWallet(result.UnlockHash, negated(result.CoinValue), nil,
+		walletOpSubUnlocked, result.CoinValue.String())
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf(
+			"redis: failed to spend coin output %s: %v", id.String(), err)
+	}
+	return result.UnlockHash, result.CoinValue, nil
+}
+
+// RevertCoinInput implements Database.RevertCoinInput
+// more or less a reverse process of SpendCoinOutput
+//
+// A coin output which isn't stored as spent is restored from the spent coin outputs,
+// regardless of whether or not spent coin outputs are archived (still), as it might have been archived before.
+func (rdb *RedisDatabase) RevertCoinInput(id types.CoinOutputID) (types.UnlockHash, types.Currency, error) {
+	result, err := rdb.updateCoinOutputState(id, CoinOutputStateSpent, CoinOutputStateLiquid)
+	if err == redis.ErrNil {
+		result, err = rdb.restoreCoinOutput(id)
+	}
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf(
+			"redis: failed to revert coin input: cannot update coin output %s: %v",
+			id.String(), err)
+	}
+
+	// update coin count
+	err = rdb.updateWallet(result.UnlockHash, result.CoinValue.Big(), nil,
+		walletOpAddUnlocked, result.CoinValue.String())
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf(
+			"redis: failed to revert coin input %s: %v", id.String(), err)
+	}
+	return result.UnlockHash, result.CoinValue, nil
+}
+
+// RevertCoinOutput implements Database.RevertCoinOutput
+func (rdb *RedisDatabase) RevertCoinOutput(id types.CoinOutputID) (CoinOutputState, error) {
+	coinOutputKey, coinOutputField := rdb.getCoinOutputKeyAndField(id)
+	var co DatabaseCoinOutput
+	err := rdb.coinOutputLoader(&co)(rdb.conn.Do("HGET", coinOutputKey, coinOutputField))
+	if err == nil {
+		err = rdb.pipeline.Write("HDEL", coinOutputKey, coinOutputField)
+	}
+	if err != nil {
+		return CoinOutputStateNil, fmt.Errorf(
+			"redis: failed to revert coin output: cannot drop coin output %s: %v",
+			id.String(), err)
+	}
+	if co.State == CoinOutputStateNil {
+		return CoinOutputStateNil, fmt.Errorf(
+			"redis: failed to revert coin output: nil coin output state %s: %v",
+			id.String(), err)
+	}
+
+	// update the correct balance of the wallet, should this coin output be unspent
+	switch co.State {
+	case CoinOutputStateLiquid:
+		err = rdb.updateWallet(co.UnlockHash, negated(co.CoinValue), nil,
+			walletOpSubUnlocked, co.CoinValue.String())
+	case CoinOutputStateLocked:
+		err = rdb.updateWallet(co.UnlockHash, nil, negated(co.CoinValue),
+			walletOpUnlock, id.String())
+		if err == nil {
+			err = rdb.unindexWalletLockedOutput(co.UnlockHash, id)
+		}
+	}
+	if err != nil {
+		return CoinOutputStateNil, fmt.Errorf(
+			"redis: failed to revert coin output %s: %v", id.String(), err)
+	}
+
+	// always remove lock properties if a lock is used, no matter the state
+	if co.LockType != LockTypeNone {
+		err = rdb.pipeline.Write("ZREM", rdb.getCoinOutputLocksKey(co.LockType, true), id.String())
+		if err == nil {
+			err = rdb.pipeline.Write("ZREM", rdb.getCoinOutputLocksKey(co.LockType, false), id.String())
+		}
+	}
+	if err != nil {
+		return CoinOutputStateNil, fmt.Errorf(
+			"redis: failed to revert coin output %s: failed to remove lock: %v", id.String(), err)
+	}
+
+	return co.State, nil
+}
+
+// AddBlockStakeOutput implements BlockStakeDatabase.AddBlockStakeOutput
+func (rdb *RedisDatabase) AddBlockStakeOutput(id types.BlockStakeOutputID, bso types.BlockStakeOutput) error {
+	uh := bso.Condition.UnlockHash()
+	key, field := rdb.getBlockStakeOutputKeyAndField(id)
+	err := rdb.pipeline.Write("HSET", key, field, DatabaseBlockStakeOutput{
+		UnlockHash:   uh,
+		Value:        bso.Value,
+		State:        CoinOutputStateLiquid,
+		LockType:     LockTypeNone,
+		RawCondition: EncodeCondition(bso.Condition),
+	}.String())
+	if err != nil {
+		return fmt.Errorf("redis: failed to add block stake output %s: %v", id.String(), err)
+	}
+	err = rdb.updateWallet(uh, nil, nil, walletOpAddUnlockedBlockStakes, bso.Value.String())
+	if err != nil {
+		return err
+	}
+	return rdb.addAddress(uh)
+}
+
+// AddLockedBlockStakeOutput implements BlockStakeDatabase.AddLockedBlockStakeOutput
+func (rdb *RedisDatabase) AddLockedBlockStakeOutput(id types.BlockStakeOutputID, bso types.BlockStakeOutput, lt LockType, lockValue LockValue) error {
+	uh := bso.Condition.UnlockHash()
+	key, field := rdb.getBlockStakeOutputKeyAndField(id)
+	err := rdb.pipeline.Write("ZADD", rdb.getBlockStakeLocksKey(lt), uint64(lockValue), id.String())
+	if err == nil {
+		err = rdb.pipeline.Write("HSET", key, field, DatabaseBlockStakeOutput{
+			UnlockHash:   uh,
+			Value:        bso.Value,
+			State:        CoinOutputStateLocked,
+			LockType:     lt,
+			LockValue:    lockValue,
+			RawCondition: EncodeCondition(bso.Condition),
+		}.String())
+	}
+	if err != nil {
+		return fmt.Errorf("redis: failed to add block stake output %s: %v", id.String(), err)
+	}
+	err = rdb.updateWallet(uh, nil, nil, walletOpAddLockedBlockStakes, bso.Value.String())
+	if err != nil {
+		return err
+	}
+	return rdb.addAddress(uh)
+}
+
+// ApplyBlockStakeOutputLocks implements BlockStakeDatabase.ApplyBlockStakeOutputLocks
+func (rdb *RedisDatabase) ApplyBlockStakeOutputLocks(height types.BlockHeight, time types.Timestamp) error {
+	return rdb.updateBlockStakeOutputLocks(height, time, true)
+}
+
+// RevertBlockStakeOutputLocks implements BlockStakeDatabase.RevertBlockStakeOutputLocks
+func (rdb *RedisDatabase) RevertBlockStakeOutputLocks(height types.BlockHeight, time types.Timestamp) error {
+	return rdb.updateBlockStakeOutputLocks(height, time, false)
+}
+
+// updateBlockStakeOutputLocks unlocks the locked block stake outputs which can be spent as of the given height and time,
+// or locks the unlocked block stake outputs which cannot be spent (yet) as of the given height and time,
+// moving their value from one block stake balance of their wallet to the other.
+// Block stake outputs remain indexed by their lock until they are reverted, no matter their state,
+// as there are very few of them.
+func (rdb *RedisDatabase) updateBlockStakeOutputLocks(height types.BlockHeight, time types.Timestamp, unlock bool) error {
+	from, to := CoinOutputStateLiquid, CoinOutputStateLocked
+	subOp, addOp := walletOpSubUnlockedBlockStakes, walletOpAddLockedBlockStakes
+	if unlock {
+		from, to = to, from
+		subOp, addOp = walletOpSubLockedBlockStakes, walletOpAddUnlockedBlockStakes
+	}
+	for _, lock := range []struct {
+		Type  LockType
+		Value LockValue
+	}{
+		{LockTypeHeight, LockValue(height)},
+		{LockTypeTime, LockValue(time)},
+	} {
+		key := rdb.getBlockStakeLocksKey(lock.Type)
+		min, max := "-inf", lock.Value.String()
+		if !unlock {
+			min, max = "("+lock.Value.String(), "+inf"
+		}
+		ids, err := redis.Strings(rdb.conn.Do("ZRANGEBYSCORE", key, min, max))
+		if err != nil {
+			return fmt.Errorf("redis: failed to get locked block stake outputs at %s: %v", key, err)
+		}
+		for _, str := range ids {
+			var id types.BlockStakeOutputID
+			err = id.LoadString(str)
+			if err != nil {
+				return fmt.Errorf("redis: invalid block stake output ID %q at %s: %v", str, key, err)
+			}
+			bsoKey, bsoField := rdb.getBlockStakeOutputKeyAndField(id)
+			var bso DatabaseBlockStakeOutput
+			err = RedisStringLoader(&bso)(rdb.conn.Do("HGET", bsoKey, bsoField))
+			if err != nil {
+				return fmt.Errorf("redis: failed to get block stake output %s at %s#%s: %v", str, bsoKey, bsoField, err)
+			}
+			if bso.State != from {
+				continue
+			}
+			bso.State = to
+			err = rdb.pipeline.Write("HSET", bsoKey, bsoField, bso.String())
+			if err != nil {
+				return fmt.Errorf("redis: failed to update block stake output %s: %v", str, err)
+			}
+			err = rdb.updateWallet(bso.UnlockHash, nil, nil, subOp, bso.Value.String(), addOp, bso.Value.String())
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SpendBlockStakeOutput implements BlockStakeDatabase.SpendBlockStakeOutput
+func (rdb *RedisDatabase) SpendBlockStakeOutput(id types.BlockStakeOutputID) (types.UnlockHash, types.Currency, error) {
+	bso, err := rdb.updateBlockStakeOutputState(id, CoinOutputStateLiquid, CoinOutputStateSpent)
+	if err == ErrNotFound {
+		return types.UnlockHash{}, types.Currency{}, ErrNotFound
+	}
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf("redis: failed to spend block stake output: %v", err)
+	}
+	err = rdb.updateWallet(bso.UnlockHash, nil, nil, walletOpSubUnlockedBlockStakes, bso.Value.String())
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf(
+			"redis: failed to spend block stake output %s: %v", id.String(), err)
+	}
+	return bso.UnlockHash, bso.Value, nil
+}
+
+// RevertBlockStakeInput implements BlockStakeDatabase.RevertBlockStakeInput
+func (rdb *RedisDatabase) RevertBlockStakeInput(id types.BlockStakeOutputID) (types.UnlockHash, types.Currency, error) {
+	bso, err := rdb.updateBlockStakeOutputState(id, CoinOutputStateSpent, CoinOutputStateLiquid)
+	if err == ErrNotFound {
+		return types.UnlockHash{}, types.Currency{}, ErrNotFound
+	}
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf("redis: failed to revert block stake input: %v", err)
+	}
+	err = rdb.updateWallet(bso.UnlockHash, nil, nil, walletOpAddUnlockedBlockStakes, bso.Value.String())
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf(
+			"redis: failed to revert block stake input %s: %v", id.String(), err)
+	}
+	return bso.UnlockHash, bso.Value, nil
+}
+
+// updateBlockStakeOutputState updates the state of a block stake output,
+// returning an error in case the block stake output isn't in the expected state.
+func (rdb *RedisDatabase) updateBlockStakeOutputState(id types.BlockStakeOutputID, from, to CoinOutputState) (DatabaseBlockStakeOutput, error) {
+	key, field := rdb.getBlockStakeOutputKeyAndField(id)
+	var bso DatabaseBlockStakeOutput
+	switch err := RedisStringLoader(&bso)(rdb.conn.Do("HGET", key, field)); err {
+	case nil:
+	case redis.ErrNil:
+		return DatabaseBlockStakeOutput{}, ErrNotFound
+	default:
+		return DatabaseBlockStakeOutput{}, fmt.Errorf(
+			"cannot get block stake output %s at %s#%s: %v", id.String(), key, field, err)
+	}
+	if bso.State != from {
+		return DatabaseBlockStakeOutput{}, fmt.Errorf(
+			"cannot update block stake output %s: unexpected state %d", id.String(), bso.State)
+	}
+	bso.State = to
+	err := rdb.pipeline.Write("HSET", key, field, bso.String())
+	if err != nil {
+		return DatabaseBlockStakeOutput{}, fmt.Errorf("cannot update block stake output %s: %v", id.String(), err)
+	}
+	return bso, nil
+}
+
+// RevertBlockStakeOutput implements BlockStakeDatabase.RevertBlockStakeOutput
+func (rdb *RedisDatabase) RevertBlockStakeOutput(id types.BlockStakeOutputID) (CoinOutputState, error) {
+	key, field := rdb.getBlockStakeOutputKeyAndField(id)
+	var bso DatabaseBlockStakeOutput
+	err := RedisStringLoader(&bso)(rdb.conn.Do("HGET", key, field))
+	if err == redis.ErrNil {
+		return CoinOutputStateNil, ErrNotFound
+	}
+	if err == nil {
+		err = rdb.pipeline.Write("HDEL", key, field)
+	}
+	if err != nil {
+		return CoinOutputStateNil, fmt.Errorf(
+			"redis: failed to revert block stake output: cannot drop block stake output %s: %v",
+			id.String(), err)
+	}
+
+	// update the correct block stake balance of the wallet, should this block stake output be unspent
+	switch bso.State {
+	case CoinOutputStateLiquid:
+		err = rdb.updateWallet(bso.UnlockHash, nil, nil, walletOpSubUnlockedBlockStakes, bso.Value.String())
+	case CoinOutputStateLocked:
+		err = rdb.updateWallet(bso.UnlockHash, nil, nil, walletOpSubLockedBlockStakes, bso.Value.String())
+	}
+	if err == nil && bso.LockType != LockTypeNone {
+		// always remove the lock if a lock is used, no matter the state
+		err = rdb.pipeline.Write("ZREM", rdb.getBlockStakeLocksKey(bso.LockType), id.String())
+	}
+	if err != nil {
+		return CoinOutputStateNil, fmt.Errorf(
+			"redis: failed to revert block stake output %s: %v", id.String(), err)
+	}
+	return bso.State, nil
+}
+
+// GetBlockStakeOutput implements BlockStakeDatabase.GetBlockStakeOutput
+func (rdb *RedisDatabase) GetBlockStakeOutput(id types.BlockStakeOutputID) (BlockStakeOutputInfo, error) {
+	key, field := rdb.getBlockStakeOutputKeyAndField(id)
+	var bso DatabaseBlockStakeOutput
+	switch err := RedisStringLoader(&bso)(rdb.conn.Do("HGET", key, field)); err {
+	case nil:
+	case redis.ErrNil:
+		return BlockStakeOutputInfo{}, ErrNotFound
+	default:
+		return BlockStakeOutputInfo{}, fmt.Errorf(
+			"redis: failed to get block stake output %s at %s#%s: %v", id.String(), key, field, err)
+	}
+	info := BlockStakeOutputInfo{
+		ID:           id,
+		UnlockHash:   bso.UnlockHash,
+		Value:        bso.Value,
+		State:        bso.State,
+		LockType:     bso.LockType,
+		LockValue:    bso.LockValue,
+		RawCondition: bso.RawCondition,
+	}
+	err := info.decodeCondition()
+	if err != nil {
+		return BlockStakeOutputInfo{}, fmt.Errorf(
+			"redis: failed to decode raw condition of block stake output %s: %v", id.String(), err)
+	}
+	return info, nil
+}
+
+// ApplyCoinOutputLocks implements Database.ApplyCoinOutputLocks
+func (rdb *RedisDatabase) ApplyCoinOutputLocks(height types.BlockHeight, time types.Timestamp) (n uint64, coins types.Currency, err error) {
+	rdb.networkTime, rdb.networkBlockHeight = time, height
+	lockedCoinOutputResults, err := rdb.updateCoinOutputLocks(height, time, true)
+	if err != nil {
+		return 0, types.Currency{}, fmt.Errorf("failed to unlock outputs: %v", err)
+	}
+	for _, lcor := range lockedCoinOutputResults {
+		// locked -> unlocked
+		err = rdb.updateWallet(lcor.UnlockHash, lcor.CoinValue.Big(), negated(lcor.CoinValue),
+			walletOpUnlock, lcor.CoinOutputID.String(), walletOpAddUnlocked, lcor.CoinValue.String())
+		if err == nil {
+			err = rdb.unindexWalletLockedOutput(lcor.UnlockHash, lcor.CoinOutputID)
+		}
+		if err != nil {
+			return 0, types.Currency{}, fmt.Errorf(
+				"failed to unlock output %s: %v", lcor.CoinOutputID.String(), err)
+		}
+		coins = coins.Add(lcor.CoinValue)
+		n++
+	}
+	return n, coins, nil
+}
+
+// RevertCoinOutputLocks implements Database.ApplyCoinOutputLocks
+func (rdb *RedisDatabase) RevertCoinOutputLocks(height types.BlockHeight, time types.Timestamp) (n uint64, coins types.Currency, err error) {
+	rdb.networkTime, rdb.networkBlockHeight = time, height
+	unlockedCoinOutputResults, err := rdb.updateCoinOutputLocks(height, time, false)
+	if err != nil {
+		return 0, types.Currency{}, fmt.Errorf("failed to lock outputs: %v", err)
+	}
+	for _, ulcor := range unlockedCoinOutputResults {
+		// unlocked -> locked
+		output := WalletLockedOutput{
+			Amount:      ulcor.CoinValue,
+			LockedUntil: rdb.lockValueAsLockTime(ulcor.LockType, ulcor.LockValue),
+			Description: ulcor.Description,
+			Reason:      CoinOutputLockReason(ulcor.LockType, ulcor.Description),
+		}
+		ops := append([]interface{}{walletOpSubUnlocked, ulcor.CoinValue.String()},
+			walletOpLockArgs(ulcor.CoinOutputID, output)...)
+		err = rdb.updateWallet(ulcor.UnlockHash, negated(ulcor.CoinValue), ulcor.CoinValue.Big(), ops...)
+		if err == nil {
+			err = rdb.indexWalletLockedOutput(ulcor.UnlockHash, ulcor.CoinOutputID, output)
+		}
+		if err != nil {
+			return 0, types.Currency{}, fmt.Errorf(
+				"failed to lock output %s: %v", ulcor.CoinOutputID.String(), err)
+		}
+		coins = coins.Add(ulcor.CoinValue)
+		n++
+	}
+	return n, coins, nil
+}
+
+// updateCoinOutputLocks unlocks the locked coin outputs which can be spent as of the given height and time,
+// or locks the unlocked coin outputs which cannot be spent (yet) as of the given height and time,
+// moving them from the ZSET of locked coin outputs to the ZSET of unlocked coin outputs (or vice versa).
+// As both ZSETs are scored by lock value, only the coin outputs to be updated are read, ordered by lock value (and ID).
+// Only the results of the coin outputs which were updated (those in the expected state) are returned.
+func (rdb *RedisDatabase) updateCoinOutputLocks(height types.BlockHeight, time types.Timestamp, unlock bool) ([]DatabaseCoinOutputResult, error) {
+	from, to := CoinOutputStateLiquid, CoinOutputStateLocked
+	if unlock {
+		from, to = to, from
+	}
+	var results []DatabaseCoinOutputResult
+	for _, lock := range []struct {
+		Type  LockType
+		Value LockValue
+	}{
+		{LockTypeHeight, LockValue(height)},
+		{LockTypeTime, LockValue(time)},
+	} {
+		fromKey, toKey := rdb.getCoinOutputLocksKey(lock.Type, false), rdb.getCoinOutputLocksKey(lock.Type, true)
+		min, max := "("+lock.Value.String(), "+inf"
+		if unlock {
+			fromKey, toKey = toKey, fromKey
+			min, max = "-inf", lock.Value.String()
+		}
+		ids, err := redis.Strings(rdb.conn.Do("ZRANGEBYSCORE", fromKey, min, max))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get coin outputs at %s: %v", fromKey, err)
+		}
+		for _, str := range ids {
+			var id types.CoinOutputID
+			err = id.LoadString(str)
+			if err != nil {
+				return nil, fmt.Errorf("invalid coin output ID %q at %s: %v", str, fromKey, err)
+			}
+			result, err := rdb.updateCoinOutputState(id, from, to)
+			if err == errUnexpectedCoinOutputState {
+				continue // coin output wasn't in the expected state
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to update locked coin output %s: %v", str, err)
+			}
+			err = rdb.pipeline.Write("ZREM", fromKey, str)
+			if err == nil {
+				err = rdb.pipeline.Write("ZADD", toKey, uint64(result.LockValue), str)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to move coin output %s from %s to %s: %v", str, fromKey, toKey, err)
+			}
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// errUnexpectedCoinOutputState is returned by updateCoinOutputState,
+// in case the coin output isn't in the state it is expected to be in.
+var errUnexpectedCoinOutputState = errors.New("coin output isn't in the expected state")
+
+// updateCoinOutputState updates the state of a coin output from one state to another,
+// returning errUnexpectedCoinOutputState if it isn't in the former state.
+//
+// The coin output is read using HGET, such that it is served from memory
+// in case it was written earlier in the same batch, as is required while a transaction is in progress.
+func (rdb *RedisDatabase) updateCoinOutputState(id types.CoinOutputID, from, to CoinOutputState) (DatabaseCoinOutputResult, error) {
+	coinOutputKey, coinOutputField := rdb.getCoinOutputKeyAndField(id)
+	var co DatabaseCoinOutput
+	err := rdb.coinOutputLoader(&co)(rdb.conn.Do("HGET", coinOutputKey, coinOutputField))
+	if err != nil {
+		return DatabaseCoinOutputResult{}, err
+	}
+	if co.State != from {
+		return DatabaseCoinOutputResult{}, errUnexpectedCoinOutputState
+	}
+	co.State = to
+	err = rdb.pipeline.Write("HSET", coinOutputKey, coinOutputField, rdb.marshalCoinOutput(co))
+	if err != nil {
+		return DatabaseCoinOutputResult{}, err
+	}
+	return co.result(id), nil
+}
+
+// archiveCoinOutput moves a liquid coin output to the spent coin outputs, marking it as spent
+// at the current block height (see ApplyCoinOutputLocks), returning errUnexpectedCoinOutputState if it isn't liquid.
+// Both the coin output and the spent coin output are read and written the same way as by updateCoinOutputState.
+func (rdb *RedisDatabase) archiveCoinOutput(id types.CoinOutputID) (DatabaseCoinOutputResult, error) {
+	coinOutputKey, coinOutputField := rdb.getCoinOutputKeyAndField(id)
+	var co DatabaseCoinOutput
+	err := rdb.coinOutputLoader(&co)(rdb.conn.Do("HGET", coinOutputKey, coinOutputField))
+	if err != nil {
+		return DatabaseCoinOutputResult{}, err
+	}
+	if co.State != CoinOutputStateLiquid {
+		return DatabaseCoinOutputResult{}, errUnexpectedCoinOutputState
+	}
+	co.State = CoinOutputStateSpent
+	spentKey, spentField := rdb.getSpentCoinOutputKeyAndField(id)
+	err = rdb.pipeline.Write("HSET", spentKey, spentField, rdb.marshalSpentCoinOutput(rdb.networkBlockHeight, co))
+	if err == nil {
+		err = rdb.pipeline.Write("HDEL", coinOutputKey, coinOutputField)
+	}
+	if err != nil {
+		return DatabaseCoinOutputResult{}, err
+	}
+	return co.result(id), nil
+}
+
+// restoreCoinOutput moves a coin output from the spent coin outputs back to the coin outputs, marking it as liquid,
+// the reverse process of archiveCoinOutput. It returns redis.ErrNil if the coin output wasn't archived.
+func (rdb *RedisDatabase) restoreCoinOutput(id types.CoinOutputID) (DatabaseCoinOutputResult, error) {
+	spentKey, spentField := rdb.getSpentCoinOutputKeyAndField(id)
+	var co DatabaseCoinOutput
+	err := rdb.spentCoinOutputLoader(nil, &co)(rdb.conn.Do("HGET", spentKey, spentField))
+	if err != nil {
+		return DatabaseCoinOutputResult{}, err
+	}
+	co.State = CoinOutputStateLiquid
+	coinOutputKey, coinOutputField := rdb.getCoinOutputKeyAndField(id)
+	err = rdb.pipeline.Write("HSET", coinOutputKey, coinOutputField, rdb.marshalCoinOutput(co))
+	if err == nil {
+		err = rdb.pipeline.Write("HDEL", spentKey, spentField)
+	}
+	if err != nil {
+		return DatabaseCoinOutputResult{}, err
+	}
+	return co.result(id), nil
+}
+
+// ApplyBlock implements BatchDatabase.ApplyBlock
+//
+// Prefetches the coin outputs spent by the block (not created by it) using a single round trip,
+// such that, as all writes are deferred by the batch in progress, applying the changes requires no further round trips.
+func (rdb *RedisDatabase) ApplyBlock(changes BlockChanges) ([]CoinOutputChangeResult, error) {
+	created := make(map[types.CoinOutputID]struct{})
+	var fields [][2]string
+	for _, change := range changes.Changes {
+		if change.Type == CoinOutputChangeCreate {
+			created[change.ID] = struct{}{}
+			continue
+		}
+		if _, ok := created[change.ID]; !ok {
+			key, field := rdb.getCoinOutputKeyAndField(change.ID)
+			fields = append(fields, [2]string{key, field})
+		}
+	}
+	err := rdb.pipeline.Prefetch(fields)
+	if err != nil {
+		return nil, fmt.Errorf("redis: failed to prefetch the coin outputs spent by block %d: %v", changes.Height, err)
+	}
+	return applyCoinOutputChanges(rdb, changes, false)
+}
+
+// RevertBlock implements BatchDatabase.RevertBlock
+//
+// Prefetches all coin outputs created and spent by the block using a single round trip,
+// such that, as all writes are deferred by the batch in progress, reverting the changes requires no further round trips.
+// The spent coin outputs are prefetched from the spent coin outputs as well, as they might be archived.
+func (rdb *RedisDatabase) RevertBlock(changes BlockChanges) ([]CoinOutputChangeResult, error) {
+	fields := make([][2]string, 0, len(changes.Changes))
+	for _, change := range changes.Changes {
+		key, field := rdb.getCoinOutputKeyAndField(change.ID)
+		fields = append(fields, [2]string{key, field})
+		if change.Type == CoinOutputChangeSpend {
+			key, field = rdb.getSpentCoinOutputKeyAndField(change.ID)
+			fields = append(fields, [2]string{key, field})
+		}
+	}
+	err := rdb.pipeline.Prefetch(fields)
+	if err != nil {
+		return nil, fmt.Errorf("redis: failed to prefetch the coin outputs of block %d: %v", changes.Height, err)
+	}
+	return applyCoinOutputChanges(rdb, changes, true)
+}
+
+// SetMultisigAddresses implements Database.SetMultisigAddresses
+func (rdb *RedisDatabase) SetMultisigAddresses(address types.UnlockHash, owners []types.UnlockHash, signaturesRequired uint64) error {
+	// both operations have no effect if already applied, such that they can be deferred without reading the wallets first
+	err := rdb.updateWallet(address, nil, nil, walletOpSetMultisigDataArgs(owners, signaturesRequired)...)
+	if err != nil {
+		return fmt.Errorf("redis: failed to set multisig wallet for %s: %v", address.String(), err)
+	}
+	for _, owner := range owners {
+		err = rdb.updateWallet(owner, nil, nil, walletOpAddMultisigAddress, address.String())
+		if err != nil {
+			return fmt.Errorf("redis: failed to add multisig wallet %s to owner %s: %v",
+				address.String(), owner.String(), err)
+		}
+	}
+	return nil
+}
+
+// ApplyCounterpartyTransfer implements Database.ApplyCounterpartyTransfer
+func (rdb *RedisDatabase) ApplyCounterpartyTransfer(from, to types.UnlockHash, value types.Currency) error {
+	err := rdb.updateCounterparty(from, to, false, func(cp *AddressCounterparty) {
+		cp.TransactionCount++
+		cp.Sent = cp.Sent.Add(value)
+	})
+	if err != nil {
+		return err
+	}
+	return rdb.updateCounterparty(to, from, false, func(cp *AddressCounterparty) {
+		cp.TransactionCount++
+		cp.Received = cp.Received.Add(value)
+	})
+}
+
+// RevertCounterpartyTransfer implements Database.RevertCounterpartyTransfer
+func (rdb *RedisDatabase) RevertCounterpartyTransfer(from, to types.UnlockHash, value types.Currency) error {
+	err := rdb.updateCounterparty(from, to, true, func(cp *AddressCounterparty) {
+		cp.TransactionCount--
+		cp.Sent = subCurrencyOrZero(cp.Sent, value)
+	})
+	if err != nil {
+		return err
+	}
+	return rdb.updateCounterparty(to, from, true, func(cp *AddressCounterparty) {
+		cp.TransactionCount--
+		cp.Received = subCurrencyOrZero(cp.Received, value)
+	})
+}
+
+// ApplyWalletGroupFlows implements Database.ApplyWalletGroupFlows
+func (rdb *RedisDatabase) ApplyWalletGroupFlows(timestamp types.Timestamp, flows WalletGroupFlows) error {
+	return rdb.updateWalletGroupFlows(timestamp, func(current WalletGroupFlows) WalletGroupFlows {
+		return current.Add(flows)
+	})
+}
+
+// RevertWalletGroupFlows implements Database.RevertWalletGroupFlows
+func (rdb *RedisDatabase) RevertWalletGroupFlows(timestamp types.Timestamp, flows WalletGroupFlows) error {
+	return rdb.updateWalletGroupFlows(timestamp, func(current WalletGroupFlows) WalletGroupFlows {
+		return current.Sub(flows)
+	})
+}
+
+// updateWalletGroupFlows updates both the total and the daily wallet group flows,
+// removing the daily flows once they're no longer used.
+func (rdb *RedisDatabase) updateWalletGroupFlows(timestamp types.Timestamp, update func(WalletGroupFlows) WalletGroupFlows) error {
+	for _, field := range []string{flowsFieldTotal, walletGroupFlowsDay(timestamp)} {
+		var flows WalletGroupFlows
+		err := RedisValue(rdb.encoder, &flows)(rdb.conn.Do("HGET", rdb.key(flowsKey), field))
+		if err != nil && err != redis.ErrNil {
+			return fmt.Errorf("redis: failed to get wallet group flows at %s#%s: %v", rdb.key(flowsKey), field, err)
+		}
+		flows = update(flows)
+		if flows.IsZero() && field != flowsFieldTotal {
+			err = rdb.pipeline.Write("HDEL", rdb.key(flowsKey), field)
+		} else {
+			err = rdb.pipeline.Write("HSET", rdb.key(flowsKey), field, MustMarshal(rdb.encoder, flows))
+		}
+		if err != nil {
+			return fmt.Errorf("redis: failed to update wallet group flows at %s#%s: %v", rdb.key(flowsKey), field, err)
+		}
+	}
+	return nil
+}
+
+// GetWalletGroupFlows implements Database.GetWalletGroupFlows
+func (rdb *RedisDatabase) GetWalletGroupFlows() (total WalletGroupFlows, daily map[string]WalletGroupFlows, err error) {
+	values, err := redis.StringMap(rdb.conn.Do("HGETALL", rdb.key(flowsKey)))
+	if err != nil {
+		return WalletGroupFlows{}, nil, fmt.Errorf("redis: failed to get wallet group flows: %v", err)
+	}
+	daily = make(map[string]WalletGroupFlows, len(values))
+	for field, value := range values {
+		var flows WalletGroupFlows
+		err = rdb.encoder.Unmarshal([]byte(value), &flows)
+		if err != nil {
+			return WalletGroupFlows{}, nil, fmt.Errorf(
+				"redis: failed to decode wallet group flows at %s#%s: %v", rdb.key(flowsKey), field, err)
+		}
+		if field == flowsFieldTotal {
+			total = flows
+		} else {
+			daily[field] = flows
+		}
+	}
+	return total, daily, nil
+}
+
+// subCurrencyOrZero subtracts b from a, returning zero instead of panicking in case b is bigger than a,
+// which can happen for counterparties that were trimmed and got tracked again afterwards.
+func subCurrencyOrZero(a, b types.Currency) types.Currency {
+	if a.Cmp(b) <= 0 {
+		return types.Currency{}
+	}
+	return a.Sub(b)
+}
+
+// updateCounterparty updates the counterparty of an address, using the given update function,
+// trimming the counterparties of that address afterwards to the most frequent ones,
+// or once the batch is committed if a batch is in progress, as the updated counterparties cannot be read until then.
+// Counterparties that were trimmed earlier are not updated on revert, as they are no longer tracked.
+func (rdb *RedisDatabase) updateCounterparty(address, counterparty types.UnlockHash, revert bool, update func(*AddressCounterparty)) error {
+	countsKey, totalsKey := rdb.getCounterpartiesKeys(address)
+	field := counterparty.String()
+	var cp AddressCounterparty
+	switch err := RedisValue(rdb.encoder, &cp)(rdb.conn.Do("HGET", totalsKey, field)); err {
+	case nil:
+	case redis.ErrNil:
+		if revert {
+			return nil // trimmed counterparty, nothing to revert
+		}
+	default:
+		return fmt.Errorf(
+			"redis: failed to get counterparty %s of %s: %v", field, address.String(), err)
+	}
+	update(&cp)
+	if cp.TransactionCount == 0 {
+		err := rdb.pipeline.Write("ZREM", countsKey, field)
+		if err == nil {
+			err = rdb.pipeline.Write("HDEL", totalsKey, field)
+		}
+		if err != nil {
+			return fmt.Errorf(
+				"redis: failed to remove counterparty %s of %s: %v", field, address.String(), err)
+		}
+		return nil
+	}
+	err := rdb.pipeline.Write("ZADD", countsKey, cp.TransactionCount, field)
+	if err == nil {
+		err = rdb.pipeline.Write("HSET", totalsKey, field, MustMarshal(rdb.encoder, cp))
+	}
+	if err != nil {
+		return fmt.Errorf(
+			"redis: failed to update counterparty %s of %s: %v", field, address.String(), err)
+	}
+	if rdb.pipeline.batching {
+		rdb.trimAddresses[address] = struct{}{}
+		return nil
+	}
+	return rdb.trimCounterparties(address)
+}
+
+// trimCounterparties trims the counterparties of an address to the most frequent ones,
+// using the counterparties script (deferred if a batch is in progress) if transactional.
+func (rdb *RedisDatabase) trimCounterparties(address types.UnlockHash) error {
+	countsKey, totalsKey := rdb.getCounterpartiesKeys(address)
+	if rdb.pipeline.transactional {
+		err := rdb.pipeline.Write("EVALSHA", rdb.counterpartiesScript.Hash(), 2, countsKey, totalsKey, maxCounterparties)
+		if err != nil {
+			return fmt.Errorf(
+				"redis: failed to trim counterparties of %s: %v", address.String(), err)
+		}
+		return nil
+	}
+	trimmed, err := redis.Values(rdb.conn.Do("ZRANGE", countsKey, 0, -(maxCounterparties + 1)))
+	if err != nil {
+		return fmt.Errorf(
+			"redis: failed to get trimmed counterparties of %s: %v", address.String(), err)
+	}
+	if len(trimmed) == 0 {
+		return nil
+	}
+	err = rdb.pipeline.Write("ZREM", append([]interface{}{countsKey}, trimmed...)...)
+	if err == nil {
+		err = rdb.pipeline.Write("HDEL", append([]interface{}{totalsKey}, trimmed...)...)
+	}
+	if err != nil {
+		return fmt.Errorf(
+			"redis: failed to trim counterparties of %s: %v", address.String(), err)
+	}
+	return nil
+}
+
+// ApplyMultisigSpend implements Database.ApplyMultisigSpend
+func (rdb *RedisDatabase) ApplyMultisigSpend(spend MultisigSpend) error {
+	spendsKey, _ := rdb.getMultisigKeys(spend.Address)
+	err := rdb.pipeline.Write("HSET", spendsKey, spend.CoinOutputID.String(), MustMarshal(rdb.encoder, spend.Signers))
+	if err != nil {
+		return fmt.Errorf("redis: failed to store signers of multisig spend %s at %s: %v",
+			spend.CoinOutputID.String(), spendsKey, err)
+	}
+	for _, signer := range spend.Signers {
+		err = rdb.updateMultisigSignerStats(spend.Address, signer, func(stats *MultisigSignerStats) {
+			stats.SpendCount++
+			stats.Value = stats.Value.Add(spend.Value)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevertMultisigSpend implements Database.RevertMultisigSpend
+func (rdb *RedisDatabase) RevertMultisigSpend(spend MultisigSpend) error {
+	spendsKey, _ := rdb.getMultisigKeys(spend.Address)
+	err := rdb.pipeline.Write("HDEL", spendsKey, spend.CoinOutputID.String())
+	if err != nil {
+		return fmt.Errorf("redis: failed to remove signers of multisig spend %s at %s: %v",
+			spend.CoinOutputID.String(), spendsKey, err)
+	}
+	for _, signer := range spend.Signers {
+		err = rdb.updateMultisigSignerStats(spend.Address, signer, func(stats *MultisigSignerStats) {
+			stats.SpendCount--
+			stats.Value = subCurrencyOrZero(stats.Value, spend.Value)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateMultisigSignerStats updates the signing stats of an owner of a multisig wallet,
+// using the given update function, removing the stats once the owner no longer signed any spend.
+func (rdb *RedisDatabase) updateMultisigSignerStats(address, signer types.UnlockHash, update func(*MultisigSignerStats)) error {
+	_, signersKey := rdb.getMultisigKeys(address)
+	field := signer.String()
+	var stats MultisigSignerStats
+	err := RedisValue(rdb.encoder, &stats)(rdb.conn.Do("HGET", signersKey, field))
+	if err != nil && err != redis.ErrNil {
+		return fmt.Errorf("redis: failed to get signing stats of %s at %s: %v", field, signersKey, err)
+	}
+	update(&stats)
+	if stats.SpendCount == 0 {
+		err = rdb.pipeline.Write("HDEL", signersKey, field)
+	} else {
+		err = rdb.pipeline.Write("HSET", signersKey, field, MustMarshal(rdb.encoder, stats))
+	}
+	if err != nil {
+		return fmt.Errorf("redis: failed to update signing stats of %s at %s: %v", field, signersKey, err)
+	}
+	return nil
+}
+
+// GetMultisigSpends implements Database.GetMultisigSpends
+func (rdb *RedisDatabase) GetMultisigSpends(address types.UnlockHash) (map[types.CoinOutputID][]types.UnlockHash, error) {
+	spendsKey, _ := rdb.getMultisigKeys(address)
+	values, err := redis.StringMap(rdb.conn.Do("HGETALL", spendsKey))
+	if err != nil {
+		return nil, fmt.Errorf("redis: failed to get multisig spends at %s: %v", spendsKey, err)
+	}
+	spends := make(map[types.CoinOutputID][]types.UnlockHash, len(values))
+	for field, value := range values {
+		var (
+			id      types.CoinOutputID
+			signers []types.UnlockHash
+		)
+		err = id.LoadString(field)
+		if err == nil {
+			err = rdb.encoder.Unmarshal([]byte(value), &signers)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("redis: failed to decode multisig spend at %s#%s: %v", spendsKey, field, err)
+		}
+		spends[id] = signers
+	}
+	return spends, nil
+}
+
+// GetMultisigSignerStats implements Database.GetMultisigSignerStats
+func (rdb *RedisDatabase) GetMultisigSignerStats(address types.UnlockHash) (map[types.UnlockHash]MultisigSignerStats, error) {
+	_, signersKey := rdb.getMultisigKeys(address)
+	values, err := redis.StringMap(rdb.conn.Do("HGETALL", signersKey))
+	if err != nil {
+		return nil, fmt.Errorf("redis: failed to get multisig signing stats at %s: %v", signersKey, err)
+	}
+	signers := make(map[types.UnlockHash]MultisigSignerStats, len(values))
+	for field, value := range values {
+		var (
+			signer types.UnlockHash
+			stats  MultisigSignerStats
+		)
+		err = signer.LoadString(field)
+		if err == nil {
+			err = rdb.encoder.Unmarshal([]byte(value), &stats)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("redis: failed to decode multisig signing stats at %s#%s: %v", signersKey, field, err)
+		}
+		signers[signer] = stats
+	}
+	return signers, nil
+}
+
+// SetBlockSummary implements Database.SetBlockSummary
+func (rdb *RedisDatabase) SetBlockSummary(summary BlockSummary) error {
+	return rdb.pipeline.Write("HSET", rdb.key(blockSummariesKey), summary.Height, MustMarshal(rdb.encoder, summary))
+}
+
+// RevertBlockSummary implements Database.RevertBlockSummary
+func (rdb *RedisDatabase) RevertBlockSummary(height types.BlockHeight) error {
+	return rdb.pipeline.Write("HDEL", rdb.key(blockSummariesKey), height)
+}
+
+// GetBlockSummary implements Database.GetBlockSummary
+func (rdb *RedisDatabase) GetBlockSummary(height types.BlockHeight) (BlockSummary, error) {
+	var summary BlockSummary
+	switch err := RedisValue(rdb.encoder, &summary)(rdb.conn.Do("HGET", rdb.key(blockSummariesKey), height)); err {
+	case nil:
+		return summary, nil
+	case redis.ErrNil:
+		return BlockSummary{}, ErrNotFound
+	default:
+		return BlockSummary{}, fmt.Errorf(
+			"redis: failed to get summary of block %d at %s#%d: %v", height, rdb.key(blockSummariesKey), height, err)
+	}
+}
+
+// SetBlockRecord implements BlockDatabase.SetBlockRecord
+func (rdb *RedisDatabase) SetBlockRecord(record BlockRecord) error {
+	err := rdb.pipeline.Write("HSET", rdb.key(blocksKey), record.Height, MustMarshal(rdb.encoder, record))
+	if err != nil {
+		return err
+	}
+	return rdb.pipeline.Write("HSET", rdb.key(blockIDsKey), record.ID.String(), record.Height)
+}
+
+// RevertBlockRecord implements BlockDatabase.RevertBlockRecord
+func (rdb *RedisDatabase) RevertBlockRecord(height types.BlockHeight, id types.BlockID) error {
+	err := rdb.pipeline.Write("HDEL", rdb.key(blocksKey), height)
+	if err != nil {
+		return err
+	}
+	return rdb.pipeline.Write("HDEL", rdb.key(blockIDsKey), id.String())
+}
+
+// GetBlockRecord implements BlockDatabase.GetBlockRecord
+func (rdb *RedisDatabase) GetBlockRecord(height types.BlockHeight) (BlockRecord, error) {
+	var record BlockRecord
+	switch err := RedisValue(rdb.encoder, &record)(rdb.conn.Do("HGET", rdb.key(blocksKey), height)); err {
+	case nil:
+		return record, nil
+	case redis.ErrNil:
+		return BlockRecord{}, ErrNotFound
+	default:
+		return BlockRecord{}, fmt.Errorf(
+			"redis: failed to get record of block %d at %s#%d: %v", height, rdb.key(blocksKey), height, err)
+	}
+}
+
+// GetBlockRecordByID implements BlockDatabase.GetBlockRecordByID
+func (rdb *RedisDatabase) GetBlockRecordByID(id types.BlockID) (BlockRecord, error) {
+	height, err := redis.Uint64(rdb.conn.Do("HGET", rdb.key(blockIDsKey), id.String()))
+	if err == redis.ErrNil {
+		return BlockRecord{}, ErrNotFound
+	}
+	if err != nil {
+		return BlockRecord{}, fmt.Errorf(
+			"redis: failed to get height of block %s at %s#%s: %v", id.String(), rdb.key(blockIDsKey), id.String(), err)
+	}
+	return rdb.GetBlockRecord(types.BlockHeight(height))
+}
+
+// SetTransactionRecord implements TransactionDatabase.SetTransactionRecord
+func (rdb *RedisDatabase) SetTransactionRecord(record TransactionRecord) error {
+	return rdb.pipeline.Write("SET", rdb.getTransactionKey(record.ID), MustMarshal(rdb.encoder, record))
+}
+
+// RevertTransactionRecord implements TransactionDatabase.RevertTransactionRecord
+func (rdb *RedisDatabase) RevertTransactionRecord(id types.TransactionID) error {
+	return rdb.pipeline.Write("DEL", rdb.getTransactionKey(id))
+}
+
+// GetTransactionRecord implements TransactionDatabase.GetTransactionRecord
+func (rdb *RedisDatabase) GetTransactionRecord(id types.TransactionID) (TransactionRecord, error) {
+	var record TransactionRecord
+	key := rdb.getTransactionKey(id)
+	switch err := RedisValue(rdb.encoder, &record)(rdb.conn.Do("GET", key)); err {
+	case nil:
+		return record, nil
+	case redis.ErrNil:
+		return TransactionRecord{}, ErrNotFound
+	default:
+		return TransactionRecord{}, fmt.Errorf(
+			"redis: failed to get record of tx %s at %s: %v", id.String(), key, err)
+	}
+}
+
+// SetAtomicSwapContract implements AtomicSwapDatabase.SetAtomicSwapContract
+func (rdb *RedisDatabase) SetAtomicSwapContract(contract AtomicSwapContract) error {
+	err := rdb.pipeline.Write("SET", rdb.getAtomicSwapContractKey(contract.ID), MustMarshal(rdb.encoder, contract))
+	if err != nil {
+		return err
+	}
+	for _, uh := range []types.UnlockHash{contract.Sender, contract.Receiver} {
+		err = rdb.pipeline.Write("SADD", rdb.getAtomicSwapContractsKey(uh), contract.ID.String())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevertAtomicSwapContract implements AtomicSwapDatabase.RevertAtomicSwapContract
+func (rdb *RedisDatabase) RevertAtomicSwapContract(contract AtomicSwapContract) error {
+	err := rdb.pipeline.Write("DEL", rdb.getAtomicSwapContractKey(contract.ID))
+	if err != nil {
+		return err
+	}
+	for _, uh := range []types.UnlockHash{contract.Sender, contract.Receiver} {
+		err = rdb.pipeline.Write("SREM", rdb.getAtomicSwapContractsKey(uh), contract.ID.String())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAtomicSwapContract implements AtomicSwapDatabase.GetAtomicSwapContract
+func (rdb *RedisDatabase) GetAtomicSwapContract(id types.CoinOutputID) (AtomicSwapContract, error) {
+	var contract AtomicSwapContract
+	key := rdb.getAtomicSwapContractKey(id)
+	switch err := RedisValue(rdb.encoder, &contract)(rdb.conn.Do("GET", key)); err {
+	case nil:
+		return contract, nil
+	case redis.ErrNil:
+		return AtomicSwapContract{}, ErrNotFound
+	default:
+		return AtomicSwapContract{}, fmt.Errorf(
+			"redis: failed to get atomic swap contract %s at %s: %v", id.String(), key, err)
+	}
+}
+
+// GetAtomicSwapContracts implements AtomicSwapDatabase.GetAtomicSwapContracts
+func (rdb *RedisDatabase) GetAtomicSwapContracts(address types.UnlockHash) ([]types.CoinOutputID, error) {
+	key := rdb.getAtomicSwapContractsKey(address)
+	strs, err := redis.Strings(rdb.conn.Do("SMEMBERS", key))
+	if err != nil {
+		return nil, fmt.Errorf("redis: failed to get atomic swap contracts of %s at %s: %v", address.String(), key, err)
+	}
+	sort.Strings(strs)
+	ids := make([]types.CoinOutputID, len(strs))
+	for i, str := range strs {
+		err = ids[i].LoadString(str)
+		if err != nil {
+			return nil, fmt.Errorf("redis: failed to decode atomic swap contract ID at %s: %v", key, err)
+		}
+	}
+	return ids, nil
+}
+
+// ApplyArbitraryData implements ArbitraryDataDatabase.ApplyArbitraryData
+func (rdb *RedisDatabase) ApplyArbitraryData(id types.TransactionID, data []byte) error {
+	hashKey, prefixKey := rdb.getArbitraryDataKeys(data)
+	err := rdb.pipeline.Write("SADD", hashKey, id.String())
+	if err != nil {
+		return err
+	}
+	return rdb.pipeline.Write("HSET", prefixKey, id.String(), data)
+}
+
+// RevertArbitraryData implements ArbitraryDataDatabase.RevertArbitraryData
+func (rdb *RedisDatabase) RevertArbitraryData(id types.TransactionID, data []byte) error {
+	hashKey, prefixKey := rdb.getArbitraryDataKeys(data)
+	err := rdb.pipeline.Write("SREM", hashKey, id.String())
+	if err != nil {
+		return err
+	}
+	return rdb.pipeline.Write("HDEL", prefixKey, id.String())
+}
+
+// GetArbitraryDataTransactions implements ArbitraryDataDatabase.GetArbitraryDataTransactions
+func (rdb *RedisDatabase) GetArbitraryDataTransactions(hash crypto.Hash) ([]types.TransactionID, error) {
+	key := rdb.key(arbitraryDataHashKey) + ":" + hash.String()
+	strs, err := redis.Strings(rdb.conn.Do("SMEMBERS", key))
+	if err != nil {
+		return nil, fmt.Errorf("redis: failed to get transactions of arbitrary data %s at %s: %v", hash.String(), key, err)
+	}
+	sort.Strings(strs)
+	ids := make([]types.TransactionID, len(strs))
+	for i, str := range strs {
+		err = ids[i].LoadString(str)
+		if err != nil {
+			return nil, fmt.Errorf("redis: failed to decode tx ID at %s: %v", key, err)
+		}
+	}
+	return ids, nil
+}
+
+// GetArbitraryDataByPrefix implements ArbitraryDataDatabase.GetArbitraryDataByPrefix
+func (rdb *RedisDatabase) GetArbitraryDataByPrefix(prefix []byte) ([]ArbitraryDataTransaction, error) {
+	key := rdb.getArbitraryDataPrefixKey(arbitraryDataPrefix(prefix))
+	values, err := redis.StringMap(rdb.conn.Do("HGETALL", key))
+	if err != nil {
+		return nil, fmt.Errorf("redis: failed to get arbitrary data at %s: %v", key, err)
+	}
+	var txs []ArbitraryDataTransaction
+	for field, value := range values {
+		if !bytes.HasPrefix([]byte(value), prefix) {
+			continue
+		}
+		tx := ArbitraryDataTransaction{Data: types.ByteSlice(value)}
+		err = tx.ID.LoadString(field)
+		if err != nil {
+			return nil, fmt.Errorf("redis: failed to decode tx ID at %s#%s: %v", key, field, err)
+		}
+		txs = append(txs, tx)
+	}
+	sort.Slice(txs, func(i, j int) bool {
+		return bytes.Compare(txs[i].ID[:], txs[j].ID[:]) < 0
+	})
+	return txs, nil
+}
+
+// ApplyAddressActivity implements AddressActivityDatabase.ApplyAddressActivity
+func (rdb *RedisDatabase) ApplyAddressActivity(height types.BlockHeight, timestamp types.Timestamp, addresses []types.UnlockHash) error {
+	key := rdb.key(addressActivityKey)
+	args := redis.Args{key}
+	for _, uh := range addresses {
+		args = args.Add(uh.String())
+	}
+	values, err := redis.ByteSlices(rdb.conn.Do("HMGET", args...))
+	if err != nil {
+		return fmt.Errorf("redis: failed to get address activity at %s: %v", key, err)
+	}
+	point := ActivityPoint{BlockHeight: height, Timestamp: timestamp}
+	previous := make(map[string]*AddressActivity, len(addresses))
+	for i, uh := range addresses {
+		activity := AddressActivity{FirstSeen: point}
+		if values[i] != nil {
+			err = rdb.encoder.Unmarshal(values[i], &activity)
+			if err != nil {
+				return fmt.Errorf("redis: failed to decode activity of %s at %s: %v", uh.String(), key, err)
+			}
+			old := activity
+			previous[uh.String()] = &old
+		} else {
+			previous[uh.String()] = nil
+		}
+		activity.LastActive = point
+		err = rdb.pipeline.Write("HSET", key, uh.String(), MustMarshal(rdb.encoder, activity))
+		if err != nil {
+			return err
+		}
+		err = rdb.pipeline.Write("ZADD", rdb.key(addressActivityLastKey), uint64(timestamp), uh.String())
+		if err != nil {
+			return err
+		}
+	}
+	return rdb.pipeline.Write("HSET", rdb.key(addressActivityUndoKey), height, MustMarshal(rdb.encoder, previous))
+}
+
+// RevertAddressActivity implements AddressActivityDatabase.RevertAddressActivity
+func (rdb *RedisDatabase) RevertAddressActivity(height types.BlockHeight) error {
+	var previous map[string]*AddressActivity
+	key, undoKey := rdb.key(addressActivityKey), rdb.key(addressActivityUndoKey)
+	switch err := RedisValue(rdb.encoder, &previous)(rdb.conn.Do("HGET", undoKey, height)); err {
+	case nil:
+	case redis.ErrNil:
+		return nil // no address was active in the block
+	default:
+		return fmt.Errorf("redis: failed to get previous address activity of block %d at %s: %v", height, undoKey, err)
+	}
+	lastKey := rdb.key(addressActivityLastKey)
+	for address, activity := range previous {
+		var err error
+		if activity == nil {
+			err = rdb.pipeline.Write("HDEL", key, address)
+			if err == nil {
+				err = rdb.pipeline.Write("ZREM", lastKey, address)
+			}
+		} else {
+			err = rdb.pipeline.Write("HSET", key, address, MustMarshal(rdb.encoder, *activity))
+			if err == nil {
+				err = rdb.pipeline.Write("ZADD", lastKey, uint64(activity.LastActive.Timestamp), address)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return rdb.pipeline.Write("HDEL", undoKey, height)
+}
+
+// GetAddressActivity implements AddressActivityDatabase.GetAddressActivity
+func (rdb *RedisDatabase) GetAddressActivity(address types.UnlockHash) (AddressActivity, error) {
+	var activity AddressActivity
+	key := rdb.key(addressActivityKey)
+	switch err := RedisValue(rdb.encoder, &activity)(rdb.conn.Do("HGET", key, address.String())); err {
+	case nil:
+		return activity, nil
+	case redis.ErrNil:
+		return AddressActivity{}, ErrNotFound
+	default:
+		return AddressActivity{}, fmt.Errorf("redis: failed to get activity of %s at %s: %v", address.String(), key, err)
+	}
+}
+
+// CountActiveAddresses implements AddressActivityDatabase.CountActiveAddresses
+func (rdb *RedisDatabase) CountActiveAddresses(since types.Timestamp) (uint64, error) {
+	key := rdb.key(addressActivityLastKey)
+	n, err := redis.Uint64(rdb.conn.Do("ZCOUNT", key, uint64(since), "+inf"))
+	if err != nil {
+		return 0, fmt.Errorf("redis: failed to count addresses active since %d at %s: %v", since, key, err)
+	}
+	return n, nil
+}
+
+// ApplyAddressTotals implements AddressTotalsDatabase.ApplyAddressTotals
+func (rdb *RedisDatabase) ApplyAddressTotals(address types.UnlockHash, delta AddressTotals) error {
+	totals, err := rdb.GetAddressTotals(address)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	totals.TotalReceived = totals.TotalReceived.Add(delta.TotalReceived)
+	totals.TotalSent = totals.TotalSent.Add(delta.TotalSent)
+	return rdb.setAddressTotals(address, totals)
+}
+
+// RevertAddressTotals implements AddressTotalsDatabase.RevertAddressTotals
+func (rdb *RedisDatabase) RevertAddressTotals(address types.UnlockHash, delta AddressTotals) error {
+	totals, err := rdb.GetAddressTotals(address)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	// not tracked for the blocks applied prior to upgrading an existing database, see subUntracked
+	totals.TotalReceived = subCurrencyOrZero(totals.TotalReceived, delta.TotalReceived)
+	totals.TotalSent = subCurrencyOrZero(totals.TotalSent, delta.TotalSent)
+	return rdb.setAddressTotals(address, totals)
+}
+
+// setAddressTotals stores the totals of the given address, dropping them if the address neither received nor sent coins.
+func (rdb *RedisDatabase) setAddressTotals(address types.UnlockHash, totals AddressTotals) error {
+	key := rdb.key(addressTotalsKey)
+	var err error
+	if totals.IsZero() {
+		err = rdb.pipeline.Write("HDEL", key, address.String())
+	} else {
+		err = rdb.pipeline.Write("HSET", key, address.String(), MustMarshal(rdb.encoder, totals))
+	}
+	if err != nil {
+		return fmt.Errorf("redis: failed to set totals of %s at %s: %v", address.String(), key, err)
+	}
+	return nil
+}
+
+// GetAddressTotals implements AddressTotalsDatabase.GetAddressTotals
+func (rdb *RedisDatabase) GetAddressTotals(address types.UnlockHash) (AddressTotals, error) {
+	var totals AddressTotals
+	key := rdb.key(addressTotalsKey)
+	switch err := RedisValue(rdb.encoder, &totals)(rdb.conn.Do("HGET", key, address.String())); err {
+	case nil:
+		return totals, nil
+	case redis.ErrNil:
+		return AddressTotals{}, ErrNotFound
+	default:
+		return AddressTotals{}, fmt.Errorf("redis: failed to get totals of %s at %s: %v", address.String(), key, err)
+	}
+}
+
+// ApplyDailyStats implements DailyStatsDatabase.ApplyDailyStats
+func (rdb *RedisDatabase) ApplyDailyStats(height types.BlockHeight, stats DailyStats) error {
+	current, err := rdb.GetDailyStats(stats.Date)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	current.Date = stats.Date
+	err = rdb.setDailyStats(current.Add(stats))
+	if err != nil {
+		return err
+	}
+	// remember the rolling count of active addresses and the circulating supply replaced, such that they can be restored
+	stats.ActiveAddresses30d, stats.CirculatingSupply = current.ActiveAddresses30d, current.CirculatingSupply
+	return rdb.pipeline.Write("HSET", rdb.key(dailyStatsUndoKey), height, MustMarshal(rdb.encoder, stats))
+}
+
+// RevertDailyStats implements DailyStatsDatabase.RevertDailyStats
+func (rdb *RedisDatabase) RevertDailyStats(height types.BlockHeight) error {
+	var stats DailyStats
+	undoKey := rdb.key(dailyStatsUndoKey)
+	switch err := RedisValue(rdb.encoder, &stats)(rdb.conn.Do("HGET", undoKey, height)); err {
+	case nil:
+	case redis.ErrNil:
+		return nil // the block was applied prior to upgrading an existing database
+	default:
+		return fmt.Errorf("redis: failed to get daily stats of block %d at %s: %v", height, undoKey, err)
+	}
+	current, err := rdb.GetDailyStats(stats.Date)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	current.Date = stats.Date
+	err = rdb.setDailyStats(current.Sub(stats))
+	if err != nil {
+		return err
+	}
+	return rdb.pipeline.Write("HDEL", undoKey, height)
+}
+
+// dailyStatsFields returns the hash fields in which the given daily stats are stored, by name.
+func dailyStatsFields(stats *DailyStats) []struct {
+	name  string
+	value interface{}
+} {
+	return []struct {
+		name  string
+		value interface{}
+	}{
+		{"blocks", &stats.BlockCount},
+		{"txCount", &stats.TransactionCount},
+		{"valueTransferred", &stats.ValueTransferred},
+		{"fees", &stats.Fees},
+		{"newAddresses", &stats.NewAddresses},
+		{"activeAddresses", &stats.ActiveAddresses},
+		{"activeAddresses30d", &stats.ActiveAddresses30d},
+		{"feeStats", &stats.FeeStats},
+		{"circulatingSupply", &stats.CirculatingSupply},
+		{"velocity", &stats.Velocity},
+	}
+}
+
+// setDailyStats stores the given daily stats, dropping them if no block is rolled up.
+// Each stat is stored in its own hash field, such that a single stat can be charted without decoding the others.
+func (rdb *RedisDatabase) setDailyStats(stats DailyStats) error {
+	key := rdb.getDailyStatsKey(stats.Date)
+	for _, field := range dailyStatsFields(&stats) {
+		var err error
+		if stats.IsZero() {
+			err = rdb.pipeline.Write("HDEL", key, field.name)
+		} else {
+			err = rdb.pipeline.Write("HSET", key, field.name, MustMarshal(rdb.encoder, field.value))
+		}
+		if err != nil {
+			return fmt.Errorf("redis: failed to set daily stats at %s#%s: %v", key, field.name, err)
+		}
+	}
+	return nil
+}
+
+// GetDailyStats implements DailyStatsDatabase.GetDailyStats
+func (rdb *RedisDatabase) GetDailyStats(date string) (DailyStats, error) {
+	stats := DailyStats{Date: date, ValueTransferred: types.ZeroCurrency, Fees: types.ZeroCurrency, CirculatingSupply: types.ZeroCurrency}
+	key := rdb.getDailyStatsKey(date)
+	found := false
+	// each field is read using HGET, such that the fields written by the current batch are observed
+	for _, field := range dailyStatsFields(&stats) {
+		switch err := RedisValue(rdb.encoder, field.value)(rdb.conn.Do("HGET", key, field.name)); err {
+		case nil:
+			found = true
+		case redis.ErrNil:
+		default:
+			return DailyStats{}, fmt.Errorf("redis: failed to get daily stats at %s#%s: %v", key, field.name, err)
+		}
+	}
+	if !found {
+		return DailyStats{}, ErrNotFound
+	}
+	return stats, nil
+}
+
+// ApplyRollingStats implements RollingStatsDatabase.ApplyRollingStats
+func (rdb *RedisDatabase) ApplyRollingStats(height types.BlockHeight, timestamp types.Timestamp, stats RollingStats) error {
+	current, err := rdb.GetRollingStats()
+	if err != nil {
+		return err
+	}
+	block := rollingStatsBlock{Timestamp: timestamp, Stats: stats, Previous: current}
+	rolled, err := rollRollingStats(current, height, block, rdb.getRollingStatsBlock)
+	if err != nil {
+		return fmt.Errorf("redis: %v", err)
+	}
+	err = rdb.setRollingStats(rolled)
+	if err != nil {
+		return err
+	}
+	return rdb.pipeline.Write("HSET", rdb.key(rollingStatsBlocksKey), height, MustMarshal(rdb.encoder, block))
+}
+
+// RevertRollingStats implements RollingStatsDatabase.RevertRollingStats
+func (rdb *RedisDatabase) RevertRollingStats(height types.BlockHeight) error {
+	block, err := rdb.getRollingStatsBlock(height)
+	if err == ErrNotFound {
+		return nil // the block was applied prior to upgrading an existing database
+	}
+	if err != nil {
+		return err
+	}
+	err = rdb.setRollingStats(block.Previous)
+	if err != nil {
+		return err
+	}
+	return rdb.pipeline.Write("HDEL", rdb.key(rollingStatsBlocksKey), height)
+}
+
+// getRollingStatsBlock returns the record stored for the block at the given height, ErrNotFound if none.
+func (rdb *RedisDatabase) getRollingStatsBlock(height types.BlockHeight) (rollingStatsBlock, error) {
+	var block rollingStatsBlock
+	key := rdb.key(rollingStatsBlocksKey)
+	switch err := RedisValue(rdb.encoder, &block)(rdb.conn.Do("HGET", key, height)); err {
+	case nil:
+		return block, nil
+	case redis.ErrNil:
+		return rollingStatsBlock{}, ErrNotFound
+	default:
+		return rollingStatsBlock{}, fmt.Errorf("redis: failed to get rolling stats of block %d at %s: %v", height, key, err)
+	}
+}
+
+// setRollingStats stores the given rolling stats, dropping the stats of a window if no block is counted.
+func (rdb *RedisDatabase) setRollingStats(rolling []RollingStats) error {
+	key := rdb.key(rollingStatsKey)
+	for _, stats := range rolling {
+		var err error
+		if stats.BlockCount == 0 {
+			err = rdb.pipeline.Write("HDEL", key, stats.Window)
+		} else {
+			err = rdb.pipeline.Write("HSET", key, stats.Window, MustMarshal(rdb.encoder, stats))
+		}
+		if err != nil {
+			return fmt.Errorf("redis: failed to set rolling stats at %s#%s: %v", key, stats.Window, err)
+		}
+	}
+	return nil
+}
+
+// GetRollingStats implements RollingStatsDatabase.GetRollingStats
+func (rdb *RedisDatabase) GetRollingStats() ([]RollingStats, error) {
+	rolling := newRollingStats()
+	key := rdb.key(rollingStatsKey)
+	// each window is read using HGET, such that the windows written by the current batch are observed
+	for i := range rolling {
+		err := RedisValue(rdb.encoder, &rolling[i])(rdb.conn.Do("HGET", key, rolling[i].Window))
+		if err != nil && err != redis.ErrNil {
+			return nil, fmt.Errorf("redis: failed to get rolling stats at %s#%s: %v", key, rolling[i].Window, err)
+		}
+	}
+	return rolling, nil
+}
+
+// SetNetworkStatsSnapshot implements NetworkStatsHistoryDatabase.SetNetworkStatsSnapshot
+func (rdb *RedisDatabase) SetNetworkStatsSnapshot(snapshot NetworkStatsSnapshot) error {
+	return rdb.pipeline.Write("HSET", rdb.key(networkStatsHistoryKey), snapshot.Date, MustMarshal(rdb.encoder, snapshot))
+}
+
+// DeleteNetworkStatsSnapshot implements NetworkStatsHistoryDatabase.DeleteNetworkStatsSnapshot
+func (rdb *RedisDatabase) DeleteNetworkStatsSnapshot(date string) error {
+	return rdb.pipeline.Write("HDEL", rdb.key(networkStatsHistoryKey), date)
+}
+
+// GetNetworkStatsSnapshot implements NetworkStatsHistoryDatabase.GetNetworkStatsSnapshot
+func (rdb *RedisDatabase) GetNetworkStatsSnapshot(date string) (NetworkStatsSnapshot, error) {
+	var snapshot NetworkStatsSnapshot
+	key := rdb.key(networkStatsHistoryKey)
+	switch err := RedisValue(rdb.encoder, &snapshot)(rdb.conn.Do("HGET", key, date)); err {
+	case nil:
+		return snapshot, nil
+	case redis.ErrNil:
+		return NetworkStatsSnapshot{}, ErrNotFound
+	default:
+		return NetworkStatsSnapshot{}, fmt.Errorf("redis: failed to get network stats snapshot at %s#%s: %v", key, date, err)
+	}
+}
+
+// ApplyDailyAddressTotals implements DailyTopAddressesDatabase.ApplyDailyAddressTotals
+func (rdb *RedisDatabase) ApplyDailyAddressTotals(date string, address types.UnlockHash, delta AddressTotals) error {
+	sendersKey, receiversKey := rdb.getDailyTopAddressesKeys(date)
+	if !delta.TotalSent.IsZero() {
+		err := rdb.pipeline.Write("ZINCRBY", sendersKey, delta.TotalSent.String(), address.String())
+		if err != nil {
+			return fmt.Errorf("redis: failed to rank sender %s at %s: %v", address.String(), sendersKey, err)
+		}
+	}
+	if !delta.TotalReceived.IsZero() {
+		err := rdb.pipeline.Write("ZINCRBY", receiversKey, delta.TotalReceived.String(), address.String())
+		if err != nil {
+			return fmt.Errorf("redis: failed to rank receiver %s at %s: %v", address.String(), receiversKey, err)
+		}
+	}
+	return nil
+}
+
+// RevertDailyAddressTotals implements DailyTopAddressesDatabase.RevertDailyAddressTotals
+func (rdb *RedisDatabase) RevertDailyAddressTotals(date string, address types.UnlockHash, delta AddressTotals) error {
+	sendersKey, receiversKey := rdb.getDailyTopAddressesKeys(date)
+	for _, ranking := range []struct {
+		key   string
+		value types.Currency
+	}{
+		{sendersKey, delta.TotalSent},
+		{receiversKey, delta.TotalReceived},
+	} {
+		if ranking.value.IsZero() {
+			continue
+		}
+		// an address trimmed from the ranking already ends up with a negative score, dropped together with the depleted ones
+		err := rdb.pipeline.Write("ZINCRBY", ranking.key, "-"+ranking.value.String(), address.String())
+		if err == nil {
+			err = rdb.pipeline.Write("ZREMRANGEBYSCORE", ranking.key, "-inf", 0)
+		}
+		if err != nil {
+			return fmt.Errorf("redis: failed to unrank %s at %s: %v", address.String(), ranking.key, err)
+		}
+	}
+	return nil
+}
+
+// TrimDailyTopAddresses implements DailyTopAddressesDatabase.TrimDailyTopAddresses
+func (rdb *RedisDatabase) TrimDailyTopAddresses(date string, n int) error {
+	sendersKey, receiversKey := rdb.getDailyTopAddressesKeys(date)
+	for _, key := range []string{sendersKey, receiversKey} {
+		err := rdb.pipeline.Write("ZREMRANGEBYRANK", key, 0, -(n + 1))
+		if err != nil {
+			return fmt.Errorf("redis: failed to trim %s to the top %d addresses: %v", key, n, err)
+		}
+	}
+	return nil
+}
+
+// GetDailyTopSenders implements DailyTopAddressesDatabase.GetDailyTopSenders
+func (rdb *RedisDatabase) GetDailyTopSenders(date string, n int) ([]DailyTopAddress, error) {
+	key, _ := rdb.getDailyTopAddressesKeys(date)
+	return rdb.getDailyTopAddresses(key, n)
+}
+
+// GetDailyTopReceivers implements DailyTopAddressesDatabase.GetDailyTopReceivers
+func (rdb *RedisDatabase) GetDailyTopReceivers(date string, n int) ([]DailyTopAddress, error) {
+	_, key := rdb.getDailyTopAddressesKeys(date)
+	return rdb.getDailyTopAddresses(key, n)
+}
+
+// getDailyTopAddresses returns the (at most) n addresses with the highest score in the given ZSET, highest first.
+func (rdb *RedisDatabase) getDailyTopAddresses(key string, n int) ([]DailyTopAddress, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	values, err := redis.Strings(rdb.conn.Do("ZREVRANGE", key, 0, n-1, "WITHSCORES"))
+	if err != nil {
+		return nil, fmt.Errorf("redis: failed to get top %d addresses at %s: %v", n, key, err)
+	}
+	entries := make([]DailyTopAddress, 0, len(values)/2)
+	for i := 0; i+1 < len(values); i += 2 {
+		var entry DailyTopAddress
+		err = entry.Address.LoadString(values[i])
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid address %q at %s: %v", values[i], key, err)
+		}
+		entry.Value, err = redisScoreCurrency(values[i+1])
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid score %q of %s at %s: %v", values[i+1], values[i], key, err)
+		}
+		entries = append(entries, entry)
+	}
+	sortDailyTopAddresses(entries)
+	return entries, nil
+}
+
+// SetCoinOutputProvenance implements CoinOutputProvenanceDatabase.SetCoinOutputProvenance
+func (rdb *RedisDatabase) SetCoinOutputProvenance(id types.CoinOutputID, provenance CoinOutputProvenance) error {
+	key, field := rdb.getCoinOutputProvenanceKeyAndField(id)
+	return rdb.pipeline.Write("HSET", key, field, MustMarshal(rdb.encoder, provenance))
+}
+
+// RevertCoinOutputProvenance implements CoinOutputProvenanceDatabase.RevertCoinOutputProvenance
+func (rdb *RedisDatabase) RevertCoinOutputProvenance(id types.CoinOutputID) error {
+	key, field := rdb.getCoinOutputProvenanceKeyAndField(id)
+	return rdb.pipeline.Write("HDEL", key, field)
+}
+
+// GetCoinOutputProvenance implements CoinOutputProvenanceDatabase.GetCoinOutputProvenance
+func (rdb *RedisDatabase) GetCoinOutputProvenance(id types.CoinOutputID) (CoinOutputProvenance, error) {
+	var provenance CoinOutputProvenance
+	key, field := rdb.getCoinOutputProvenanceKeyAndField(id)
+	switch err := RedisValue(rdb.encoder, &provenance)(rdb.conn.Do("HGET", key, field)); err {
+	case nil:
+		return provenance, nil
+	case redis.ErrNil:
+		return CoinOutputProvenance{}, ErrNotFound
+	default:
+		return CoinOutputProvenance{}, fmt.Errorf(
+			"redis: failed to get provenance of coin output %s at %s#%s: %v", id.String(), key, field, err)
+	}
+}
+
+// ApplyMintConditionChange implements MintConditionDatabase.ApplyMintConditionChange
+func (rdb *RedisDatabase) ApplyMintConditionChange(change MintConditionChange) error {
+	return rdb.pipeline.Write("RPUSH", rdb.key(mintConditionsKey), MustMarshal(rdb.encoder, change))
+}
+
+// RevertMintConditionChange implements MintConditionDatabase.RevertMintConditionChange
+func (rdb *RedisDatabase) RevertMintConditionChange(id types.TransactionID) error {
+	key := rdb.key(mintConditionsKey)
+	values, err := redis.ByteSlices(rdb.conn.Do("LRANGE", key, 0, -1))
+	if err != nil {
+		return fmt.Errorf("redis: failed to get mint condition history at %s: %v", key, err)
+	}
+	// remove the latest change made by the given transaction, by value
+	for i := len(values) - 1; i >= 0; i-- {
+		var change MintConditionChange
+		err = rdb.encoder.Unmarshal(values[i], &change)
+		if err != nil {
+			return fmt.Errorf("redis: failed to decode mint condition change at %s[%d]: %v", key, i, err)
+		}
+		if change.TransactionID == id {
+			return rdb.pipeline.Write("LREM", key, -1, values[i])
+		}
+	}
+	return nil
+}
+
+// GetMintConditionHistory implements MintConditionDatabase.GetMintConditionHistory
+func (rdb *RedisDatabase) GetMintConditionHistory() ([]MintConditionChange, error) {
+	key := rdb.key(mintConditionsKey)
+	values, err := redis.ByteSlices(rdb.conn.Do("LRANGE", key, 0, -1))
+	if err != nil {
+		return nil, fmt.Errorf("redis: failed to get mint condition history at %s: %v", key, err)
+	}
+	history := make([]MintConditionChange, len(values))
+	for i, value := range values {
+		err = rdb.encoder.Unmarshal(value, &history[i])
+		if err != nil {
+			return nil, fmt.Errorf("redis: failed to decode mint condition change at %s[%d]: %v", key, i, err)
+		}
+	}
+	return history, nil
+}
+
+// ApplyMinerPayout implements MinerPayoutDatabase.ApplyMinerPayout
+func (rdb *RedisDatabase) ApplyMinerPayout(address types.UnlockHash, payout MinerPayoutRecord) error {
+	key := rdb.getMinerPayoutsKey(address)
+	err := rdb.pipeline.Write("ZADD", key, uint64(payout.BlockHeight), MustMarshal(rdb.encoder, payout))
+	if err != nil {
+		return fmt.Errorf("redis: failed to add miner payout %s at %s: %v", payout.ID.String(), key, err)
+	}
+	return nil
+}
+
+// RevertMinerPayouts implements MinerPayoutDatabase.RevertMinerPayouts
+func (rdb *RedisDatabase) RevertMinerPayouts(address types.UnlockHash, height types.BlockHeight) error {
+	key := rdb.getMinerPayoutsKey(address)
+	err := rdb.pipeline.Write("ZREMRANGEBYSCORE", key, uint64(height), uint64(height))
+	if err != nil {
+		return fmt.Errorf("redis: failed to remove miner payouts of block %d at %s: %v", height, key, err)
+	}
+	return nil
+}
+
+// GetMinerPayouts implements MinerPayoutDatabase.GetMinerPayouts
+func (rdb *RedisDatabase) GetMinerPayouts(address types.UnlockHash) ([]MinerPayoutRecord, error) {
+	key := rdb.getMinerPayoutsKey(address)
+	values, err := redis.ByteSlices(rdb.conn.Do("ZRANGE", key, 0, -1))
+	if err != nil {
+		return nil, fmt.Errorf("redis: failed to get miner payouts at %s: %v", key, err)
+	}
+	payouts := make([]MinerPayoutRecord, len(values))
+	for i, value := range values {
+		err = rdb.encoder.Unmarshal(value, &payouts[i])
+		if err != nil {
+			return nil, fmt.Errorf("redis: failed to decode miner payout at %s[%d]: %v", key, i, err)
+		}
+	}
+	return payouts, nil
+}
+
+// ApplyAddressHistory implements AddressHistoryDatabase.ApplyAddressHistory
+func (rdb *RedisDatabase) ApplyAddressHistory(address types.UnlockHash, record AddressHistoryRecord) error {
+	key := rdb.getAddressHistoryKey(address)
+	err := rdb.pipeline.Write("ZADD", key, uint64(record.BlockHeight), MustMarshal(rdb.encoder, record))
+	if err != nil {
+		return fmt.Errorf("redis: failed to add tx %s at %s: %v", record.TransactionID.String(), key, err)
+	}
+	return nil
+}
+
+// RevertAddressHistory implements AddressHistoryDatabase.RevertAddressHistory
+func (rdb *RedisDatabase) RevertAddressHistory(address types.UnlockHash, height types.BlockHeight) error {
+	key := rdb.getAddressHistoryKey(address)
+	err := rdb.pipeline.Write("ZREMRANGEBYSCORE", key, uint64(height), uint64(height))
+	if err != nil {
+		return fmt.Errorf("redis: failed to remove txs of block %d at %s: %v", height, key, err)
+	}
+	return nil
+}
+
+// GetAddressHistory implements AddressHistoryDatabase.GetAddressHistory
+func (rdb *RedisDatabase) GetAddressHistory(address types.UnlockHash) ([]AddressHistoryRecord, error) {
+	key := rdb.getAddressHistoryKey(address)
+	values, err := redis.ByteSlices(rdb.conn.Do("ZRANGE", key, 0, -1))
+	if err != nil {
+		return nil, fmt.Errorf("redis: failed to get address history at %s: %v", key, err)
+	}
+	history := make([]AddressHistoryRecord, len(values))
+	for i, value := range values {
+		err = rdb.encoder.Unmarshal(value, &history[i])
+		if err != nil {
+			return nil, fmt.Errorf("redis: failed to decode address history at %s[%d]: %v", key, i, err)
+		}
+	}
+	return history, nil
+}
+
+// ApplyCreatedBlock implements BlockCreatorDatabase.ApplyCreatedBlock
+func (rdb *RedisDatabase) ApplyCreatedBlock(creator types.UnlockHash, payouts types.Currency) error {
+	stats, err := rdb.GetBlockCreatorStats(creator)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	stats.BlockCount++
+	stats.Payouts = stats.Payouts.Add(payouts)
+	return rdb.setBlockCreatorStats(creator, stats)
+}
+
+// RevertCreatedBlock implements BlockCreatorDatabase.RevertCreatedBlock
+func (rdb *RedisDatabase) RevertCreatedBlock(creator types.UnlockHash, payouts types.Currency) error {
+	stats, err := rdb.GetBlockCreatorStats(creator)
+	if err == ErrNotFound {
+		return nil // created prior to the dataset tracking block creators
+	}
+	if err != nil {
+		return err
+	}
+	stats.BlockCount--
+	if stats.BlockCount == 0 {
+		err = rdb.pipeline.Write("HDEL", rdb.key(blockCreatorsKey), creator.String())
+		if err != nil {
+			return fmt.Errorf("redis: failed to delete stats of block creator %s: %v", creator.String(), err)
+		}
+		err = rdb.pipeline.Write("ZREM", rdb.key(blockCreatorsRankKey), creator.String())
+		if err != nil {
+			return fmt.Errorf("redis: failed to unrank block creator %s: %v", creator.String(), err)
+		}
+		return nil
+	}
+	if stats.Payouts.Cmp(payouts) >= 0 {
+		stats.Payouts = stats.Payouts.Sub(payouts)
+	} else {
+		stats.Payouts = types.ZeroCurrency
+	}
+	return rdb.setBlockCreatorStats(creator, stats)
+}
+
+// setBlockCreatorStats stores the given stats of a block creator, ranking it by its block count.
+func (rdb *RedisDatabase) setBlockCreatorStats(creator types.UnlockHash, stats BlockCreatorStats) error {
+	key := rdb.key(blockCreatorsKey)
+	err := rdb.pipeline.Write("HSET", key, creator.String(), MustMarshal(rdb.encoder, stats))
+	if err != nil {
+		return fmt.Errorf("redis: failed to store stats of block creator %s at %s: %v", creator.String(), key, err)
+	}
+	key = rdb.key(blockCreatorsRankKey)
+	err = rdb.pipeline.Write("ZADD", key, stats.BlockCount, creator.String())
+	if err != nil {
+		return fmt.Errorf("redis: failed to rank block creator %s at %s: %v", creator.String(), key, err)
+	}
+	return nil
+}
+
+// GetBlockCreatorStats implements BlockCreatorDatabase.GetBlockCreatorStats
+func (rdb *RedisDatabase) GetBlockCreatorStats(address types.UnlockHash) (BlockCreatorStats, error) {
+	var stats BlockCreatorStats
+	key := rdb.key(blockCreatorsKey)
+	switch err := RedisValue(rdb.encoder, &stats)(rdb.conn.Do("HGET", key, address.String())); err {
+	case nil:
+		return stats, nil
+	case redis.ErrNil:
+		return BlockCreatorStats{}, ErrNotFound
+	default:
+		return BlockCreatorStats{}, fmt.Errorf("redis: failed to get stats of block creator %s at %s: %v", address.String(), key, err)
+	}
+}
+
+// GetBlockCreators implements BlockCreatorDatabase.GetBlockCreators
+func (rdb *RedisDatabase) GetBlockCreators(n int) ([]BlockCreatorEntry, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	strs, err := redis.Strings(rdb.conn.Do("ZREVRANGE", rdb.key(blockCreatorsRankKey), 0, n-1))
+	if err != nil {
+		return nil, fmt.Errorf("redis: failed to get top %d block creators: %v", n, err)
+	}
+	entries := make([]BlockCreatorEntry, 0, len(strs))
+	for _, str := range strs {
+		var entry BlockCreatorEntry
+		err = entry.Address.LoadString(str)
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid address %q in block creator ranking: %v", str, err)
+		}
+		entry.Stats, err = rdb.GetBlockCreatorStats(entry.Address)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	sortBlockCreators(entries)
+	return entries, nil
+}
+
+// getBotRecordHistory returns all states of the given 3Bot record, latest last.
+func (rdb *RedisDatabase) getBotRecordHistory(id BotID) ([]BotRecord, error) {
+	var history []BotRecord
+	key := rdb.key(botsKey)
+	switch err := RedisValue(rdb.encoder, &history)(rdb.conn.Do("HGET", key, id)); err {
+	case nil:
+		return history, nil
+	case redis.ErrNil:
+		return nil, ErrNotFound
+	default:
+		return nil, fmt.Errorf("redis: failed to get 3Bot record %d at %s: %v", id, key, err)
+	}
+}
+
+// SetBotRecord implements ThreeBotDatabase.SetBotRecord
+func (rdb *RedisDatabase) SetBotRecord(record BotRecord) error {
+	history, err := rdb.getBotRecordHistory(record.ID)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	return rdb.pipeline.Write("HSET", rdb.key(botsKey), record.ID, MustMarshal(rdb.encoder, append(history, record)))
+}
+
+// RevertBotRecord implements ThreeBotDatabase.RevertBotRecord
+func (rdb *RedisDatabase) RevertBotRecord(id BotID) (BotRecord, error) {
+	history, err := rdb.getBotRecordHistory(id)
+	if err != nil {
+		return BotRecord{}, err
+	}
+	history = history[:len(history)-1]
+	if len(history) == 0 {
+		return BotRecord{}, rdb.pipeline.Write("HDEL", rdb.key(botsKey), id)
+	}
+	return history[len(history)-1], rdb.pipeline.Write("HSET", rdb.key(botsKey), id, MustMarshal(rdb.encoder, history))
+}
+
+// GetBotRecord implements ThreeBotDatabase.GetBotRecord
+func (rdb *RedisDatabase) GetBotRecord(id BotID) (BotRecord, error) {
+	history, err := rdb.getBotRecordHistory(id)
+	if err != nil {
+		return BotRecord{}, err
+	}
+	return history[len(history)-1], nil
+}
+
+// GetBotCount implements ThreeBotDatabase.GetBotCount
+func (rdb *RedisDatabase) GetBotCount() (BotID, error) {
+	key := rdb.key(botsKey)
+	count, err := redis.Uint64(rdb.conn.Do("HLEN", key))
+	if err != nil {
+		return 0, fmt.Errorf("redis: failed to get 3Bot count at %s: %v", key, err)
+	}
+	return BotID(count), nil
+}
+
+// SetBotName implements ThreeBotDatabase.SetBotName
+func (rdb *RedisDatabase) SetBotName(name string, id BotID) error {
+	return rdb.pipeline.Write("HSET", rdb.key(botNamesKey), name, id)
+}
+
+// DeleteBotName implements ThreeBotDatabase.DeleteBotName
+func (rdb *RedisDatabase) DeleteBotName(name string) error {
+	return rdb.pipeline.Write("HDEL", rdb.key(botNamesKey), name)
+}
+
+// GetBotIDByName implements ThreeBotDatabase.GetBotIDByName
+func (rdb *RedisDatabase) GetBotIDByName(name string) (BotID, error) {
+	key := rdb.key(botNamesKey)
+	id, err := redis.Uint64(rdb.conn.Do("HGET", key, name))
+	switch err {
+	case nil:
+		return BotID(id), nil
+	case redis.ErrNil:
+		return 0, ErrNotFound
+	default:
+		return 0, fmt.Errorf("redis: failed to get 3Bot ID of name %s at %s: %v", name, key, err)
+	}
+}
+
+// ApplyERC20AddressRegistration implements ERC20Database.ApplyERC20AddressRegistration
+func (rdb *RedisDatabase) ApplyERC20AddressRegistration(address string, uh types.UnlockHash) error {
+	err := rdb.pipeline.Write("HSET", rdb.key(erc20AddressesKey), address, uh.String())
+	if err != nil {
+		return err
+	}
+	return rdb.pipeline.Write("HSET", rdb.key(erc20UnlockHashesKey), uh.String(), address)
+}
+
+// RevertERC20AddressRegistration implements ERC20Database.RevertERC20AddressRegistration
+func (rdb *RedisDatabase) RevertERC20AddressRegistration(address string, uh types.UnlockHash) error {
+	err := rdb.pipeline.Write("HDEL", rdb.key(erc20AddressesKey), address)
+	if err != nil {
+		return err
+	}
+	return rdb.pipeline.Write("HDEL", rdb.key(erc20UnlockHashesKey), uh.String())
+}
+
+// GetERC20UnlockHash implements ERC20Database.GetERC20UnlockHash
+func (rdb *RedisDatabase) GetERC20UnlockHash(address string) (types.UnlockHash, error) {
+	key := rdb.key(erc20AddressesKey)
+	str, err := redis.String(rdb.conn.Do("HGET", key, address))
+	switch err {
+	case nil:
+	case redis.ErrNil:
+		return types.UnlockHash{}, ErrNotFound
+	default:
+		return types.UnlockHash{}, fmt.Errorf("redis: failed to get TFT address of ERC20 address %s at %s: %v", address, key, err)
+	}
+	var uh types.UnlockHash
+	err = uh.LoadString(str)
+	if err != nil {
+		return types.UnlockHash{}, fmt.Errorf("redis: failed to decode TFT address at %s#%s: %v", key, address, err)
+	}
+	return uh, nil
+}
+
+// GetERC20Address implements ERC20Database.GetERC20Address
+func (rdb *RedisDatabase) GetERC20Address(uh types.UnlockHash) (string, error) {
+	key := rdb.key(erc20UnlockHashesKey)
+	address, err := redis.String(rdb.conn.Do("HGET", key, uh.String()))
+	switch err {
+	case nil:
+		return address, nil
+	case redis.ErrNil:
+		return "", ErrNotFound
+	default:
+		return "", fmt.Errorf("redis: failed to get ERC20 address of %s at %s: %v", uh.String(), key, err)
+	}
+}
+
+// SetERC20BridgeStats implements ERC20Database.SetERC20BridgeStats
+func (rdb *RedisDatabase) SetERC20BridgeStats(stats ERC20BridgeStats) error {
+	return rdb.pipeline.Write("SET", rdb.key(erc20StatsKey), MustMarshal(rdb.encoder, stats))
+}
+
+// GetERC20BridgeStats implements ERC20Database.GetERC20BridgeStats
+func (rdb *RedisDatabase) GetERC20BridgeStats() (ERC20BridgeStats, error) {
+	var stats ERC20BridgeStats
+	key := rdb.key(erc20StatsKey)
+	switch err := RedisValue(rdb.encoder, &stats)(rdb.conn.Do("GET", key)); err {
+	case nil, redis.ErrNil:
+		return stats, nil
+	default:
+		return ERC20BridgeStats{}, fmt.Errorf("redis: failed to get ERC20 bridge stats at %s: %v", key, err)
+	}
+}
+
+// SetGenesisAllocation implements GenesisDatabase.SetGenesisAllocation
+func (rdb *RedisDatabase) SetGenesisAllocation(allocation GenesisAllocation) error {
+	return rdb.pipeline.Write("SET", rdb.key(genesisKey), MustMarshal(rdb.encoder, allocation))
+}
+
+// DeleteGenesisAllocation implements GenesisDatabase.DeleteGenesisAllocation
+func (rdb *RedisDatabase) DeleteGenesisAllocation() error {
+	return rdb.pipeline.Write("DEL", rdb.key(genesisKey))
+}
+
+// GetGenesisAllocation implements GenesisDatabase.GetGenesisAllocation
+func (rdb *RedisDatabase) GetGenesisAllocation() (GenesisAllocation, error) {
+	var allocation GenesisAllocation
+	key := rdb.key(genesisKey)
+	switch err := RedisValue(rdb.encoder, &allocation)(rdb.conn.Do("GET", key)); err {
+	case nil:
+		return allocation, nil
+	case redis.ErrNil:
+		return GenesisAllocation{}, ErrNotFound
+	default:
+		return GenesisAllocation{}, fmt.Errorf("redis: failed to get genesis allocation at %s: %v", key, err)
+	}
+}
+
+// StoreBalanceSnapshot implements BalanceSnapshotDatabase.StoreBalanceSnapshot
+func (rdb *RedisDatabase) StoreBalanceSnapshot(stats NetworkStats) error {
+	// the addresses SET is used to find the buckets of all wallets,
+	// as the address prefix stats might not include the wallets created by the current batch yet
+	strs, err := redis.Strings(rdb.conn.Do("SMEMBERS", rdb.key(addressesKey)))
+	if err != nil {
+		return fmt.Errorf("redis: failed to get the unique addresses: %v", err)
+	}
+	prefixes := make(map[string]struct{})
+	for _, str := range strs {
+		if len(str) >= AddressPrefixLength {
+			prefixes[str[:AddressPrefixLength]] = struct{}{}
+		}
+	}
+	key := rdb.getBalanceSnapshotKey(stats.BlockHeight)
+	err = rdb.pipeline.Write("DEL", key)
+	if err != nil {
+		return fmt.Errorf("redis: failed to delete balance snapshot %s: %v", key, err)
+	}
+	for prefix := range prefixes {
+		walletsKey := rdb.key("a:" + prefix)
+		values, err := redis.StringMap(rdb.conn.Do("HGETALL", walletsKey))
+		if err != nil {
+			return fmt.Errorf("redis: failed to get wallets of %s: %v", walletsKey, err)
+		}
+		for field, value := range values {
+			var wallet WalletFocusBalance
+			err = rdb.encoder.Unmarshal([]byte(value), &wallet)
+			if err != nil {
+				return fmt.Errorf("redis: failed to decode wallet at %s#%s: %v", walletsKey, field, err)
+			}
+			balance := SnapshotBalance{
+				Unlocked: wallet.Balance.Unlocked,
+				Locked:   wallet.Balance.Locked.Total,
+			}
+			if balance.IsZero() {
+				continue
+			}
+			err = rdb.pipeline.Write("HSET", key, prefix+field, MustMarshal(rdb.encoder, balance))
+			if err != nil {
+				return fmt.Errorf("redis: failed to store balance of %s%s in balance snapshot %s: %v", prefix, field, key, err)
+			}
+		}
+	}
+	return rdb.pipeline.Write("HSET", rdb.key(balanceSnapshotsKey), stats.BlockHeight, MustMarshal(rdb.encoder, stats))
+}
+
+// DeleteBalanceSnapshot implements BalanceSnapshotDatabase.DeleteBalanceSnapshot
+func (rdb *RedisDatabase) DeleteBalanceSnapshot(height types.BlockHeight) error {
+	err := rdb.pipeline.Write("HDEL", rdb.key(balanceSnapshotsKey), height)
+	if err != nil {
+		return err
+	}
+	return rdb.pipeline.Write("DEL", rdb.getBalanceSnapshotKey(height))
+}
+
+// GetBalanceSnapshotHeights implements BalanceSnapshotDatabase.GetBalanceSnapshotHeights
+func (rdb *RedisDatabase) GetBalanceSnapshotHeights() ([]types.BlockHeight, error) {
+	strs, err := redis.Strings(rdb.conn.Do("HKEYS", rdb.key(balanceSnapshotsKey)))
+	if err != nil {
+		return nil, fmt.Errorf("redis: failed to get balance snapshot heights: %v", err)
+	}
+	heights := make([]types.BlockHeight, 0, len(strs))
+	for _, str := range strs {
+		height, err := strconv.ParseUint(str, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid balance snapshot height %q: %v", str, err)
+		}
+		heights = append(heights, types.BlockHeight(height))
+	}
+	sort.Slice(heights, func(i, j int) bool {
+		return heights[i] < heights[j]
+	})
+	return heights, nil
+}
+
+// GetBalanceSnapshot implements BalanceSnapshotDatabase.GetBalanceSnapshot
+func (rdb *RedisDatabase) GetBalanceSnapshot(height types.BlockHeight) (BalanceSnapshot, error) {
+	var snapshot BalanceSnapshot
+	switch err := RedisValue(rdb.encoder, &snapshot.Stats)(rdb.conn.Do("HGET", rdb.key(balanceSnapshotsKey), height)); err {
+	case nil:
+	case redis.ErrNil:
+		return BalanceSnapshot{}, ErrNotFound
+	default:
+		return BalanceSnapshot{}, fmt.Errorf(
+			"redis: failed to get balance snapshot %d at %s#%d: %v", height, rdb.key(balanceSnapshotsKey), height, err)
+	}
+	key := rdb.getBalanceSnapshotKey(height)
+	values, err := redis.StringMap(rdb.conn.Do("HGETALL", key))
+	if err != nil {
+		return BalanceSnapshot{}, fmt.Errorf("redis: failed to get balances of balance snapshot %s: %v", key, err)
+	}
+	snapshot.Balances = make(map[types.UnlockHash]SnapshotBalance, len(values))
+	for field, value := range values {
+		var address types.UnlockHash
+		err = address.LoadString(field)
+		if err != nil {
+			return BalanceSnapshot{}, fmt.Errorf("redis: invalid address %q in balance snapshot %s: %v", field, key, err)
+		}
+		var balance SnapshotBalance
+		err = rdb.encoder.Unmarshal([]byte(value), &balance)
+		if err != nil {
+			return BalanceSnapshot{}, fmt.Errorf("redis: failed to decode balance at %s#%s: %v", key, field, err)
+		}
+		snapshot.Balances[address] = balance
+	}
+	return snapshot, nil
+}
+
+// GetWallet implements Database.GetWallet
+func (rdb *RedisDatabase) GetWallet(address types.UnlockHash) (Wallet, error) {
+	addressKey, addressField := rdb.getAddressKeyAndField(address)
+	var wallet Wallet
+	switch err := RedisValue(rdb.encoder, &wallet)(rdb.conn.Do("HGET", addressKey, addressField)); err {
+	case nil:
+		err = upgradeWallet(&wallet, rdb.networkTime)
+		if err != nil {
+			return Wallet{}, fmt.Errorf("redis: failed to upgrade wallet for %s: %v", address.String(), err)
+		}
+		return wallet, nil
+	case redis.ErrNil:
+		return Wallet{}, ErrNotFound
+	default:
+		return Wallet{}, fmt.Errorf(
+			"redis: failed to get wallet for %s at %s#%s: %v", address.String(), addressKey, addressField, err)
+	}
+}
+
+// GetWalletLockedOutputs implements WalletOutputsDatabase.GetWalletLockedOutputs
+//
+// The locked outputs are read from the ZSET indexing the locked outputs of the address,
+// rather than from the wallet itself, see indexWalletLockedOutput.
+func (rdb *RedisDatabase) GetWalletLockedOutputs(address types.UnlockHash, cursor *types.CoinOutputID, limit int) (WalletLockedOutputPage, error) {
+	addressKey, addressField := rdb.getAddressKeyAndField(address)
+	exists, err := redis.Bool(rdb.conn.Do("HEXISTS", addressKey, addressField))
+	if err != nil {
+		return WalletLockedOutputPage{}, fmt.Errorf(
+			"redis: failed to check existence of wallet for %s at %s#%s: %v", address.String(), addressKey, addressField, err)
+	}
+	if !exists {
+		return WalletLockedOutputPage{}, ErrNotFound
+	}
+	key := rdb.getWalletLockedOutputsKey(address)
+	min := "-"
+	if cursor != nil {
+		// skip all members prefixed by the ID of the cursor
+		min = "(" + cursor.String() + "\xff"
+	}
+	// get one output more than requested, to know whether a next page exists
+	members, err := redis.Strings(rdb.conn.Do("ZRANGEBYLEX", key, min, "+", "LIMIT", 0, limit+1))
+	if err != nil {
+		return WalletLockedOutputPage{}, fmt.Errorf("redis: failed to get locked outputs of %s at %s: %v", address.String(), key, err)
+	}
+	page := WalletLockedOutputPage{Outputs: make([]WalletLockedOutputEntry, 0, len(members))}
+	for i, member := range members {
+		if i == limit {
+			last := page.Outputs[limit-1].ID
+			page.NextCursor = &last
+			break
+		}
+		entry, err := decodeWalletLockedOutputMember(member)
+		if err != nil {
+			return WalletLockedOutputPage{}, fmt.Errorf("redis: invalid locked output of %s at %s: %v", address.String(), key, err)
+		}
+		page.Outputs = append(page.Outputs, entry)
+	}
+	return page, nil
+}
+
+// indexWalletLockedOutput adds the given locked output to the ZSET indexing the locked outputs of the given address,
+// deferred if a batch is in progress. All members have the same score, such that they are ordered lexicographically,
+// each member being the (hex-encoded) ID of the output, followed by the JSON-encoded WalletLockedOutput,
+// such that the locked outputs of a wallet can be listed page by page (see GetWalletLockedOutputs).
+func (rdb *RedisDatabase) indexWalletLockedOutput(uh types.UnlockHash, id types.CoinOutputID, output WalletLockedOutput) error {
+	key := rdb.getWalletLockedOutputsKey(uh)
+	// remove a previous member of the same output first, as the lock time of an output locked again might differ
+	err := rdb.unindexWalletLockedOutput(uh, id)
+	if err == nil {
+		err = rdb.pipeline.Write("ZADD", key, 0, id.String()+JSONMarshal(output))
+	}
+	if err != nil {
+		return fmt.Errorf("redis: failed to index locked output %s at %s: %v", id.String(), key, err)
+	}
+	return nil
+}
+
+// unindexWalletLockedOutput removes the given output from the ZSET indexing the locked outputs of the given address,
+// deferred if a batch is in progress.
+func (rdb *RedisDatabase) unindexWalletLockedOutput(uh types.UnlockHash, id types.CoinOutputID) error {
+	key := rdb.getWalletLockedOutputsKey(uh)
+	err := rdb.pipeline.Write("ZREMRANGEBYLEX", key, "["+id.String(), "["+id.String()+"\xff")
+	if err != nil {
+		return fmt.Errorf("redis: failed to unindex locked output %s at %s: %v", id.String(), key, err)
+	}
+	return nil
+}
+
+// decodeWalletLockedOutputMember decodes a member of the ZSET indexing the locked outputs of an address,
+// see indexWalletLockedOutput.
+func decodeWalletLockedOutputMember(member string) (WalletLockedOutputEntry, error) {
+	const idLength = len(types.CoinOutputID{}) * 2
+	if len(member) < idLength {
+		return WalletLockedOutputEntry{}, fmt.Errorf("member %q is too short", member)
+	}
+	var entry WalletLockedOutputEntry
+	err := entry.ID.LoadString(member[:idLength])
+	if err != nil {
+		return WalletLockedOutputEntry{}, fmt.Errorf("invalid ID of member %q: %v", member, err)
+	}
+	err = json.Unmarshal([]byte(member[idLength:]), &entry.WalletLockedOutput)
+	if err != nil {
+		return WalletLockedOutputEntry{}, fmt.Errorf("failed to decode locked output %s: %v", entry.ID.String(), err)
+	}
+	return entry, nil
+}
+
+// SampleAddresses implements Database.SampleAddresses
+func (rdb *RedisDatabase) SampleAddresses(n int) ([]types.UnlockHash, error) {
+	strs, err := redis.Strings(rdb.conn.Do("SRANDMEMBER", rdb.key(addressesKey), n))
+	if err != nil {
+		return nil, fmt.Errorf("redis: failed to sample %d addresses: %v", n, err)
+	}
+	addresses := make([]types.UnlockHash, len(strs))
+	for i, str := range strs {
+		err = addresses[i].LoadString(str)
+		if err != nil {
+			return nil, fmt.Errorf("redis: failed to load sampled address %q: %v", str, err)
+		}
+	}
+	return addresses, nil
+}
+
+// GetAddressPrefixStats implements AddressPrefixDatabase.GetAddressPrefixStats
+func (rdb *RedisDatabase) GetAddressPrefixStats() (map[string]AddressPrefixStats, error) {
+	values, err := redis.StringMap(rdb.conn.Do("HGETALL", rdb.key(addressPrefixStatsKey)))
+	if err != nil {
+		return nil, fmt.Errorf("redis: failed to get address prefix stats: %v", err)
+	}
+	prefixes := make(map[string]AddressPrefixStats, len(values))
+	for prefix, value := range values {
+		var stats AddressPrefixStats
+		err = rdb.encoder.Unmarshal([]byte(value), &stats)
+		if err != nil {
+			return nil, fmt.Errorf("redis: failed to decode stats of address prefix %s: %v", prefix, err)
+		}
+		prefixes[prefix] = stats
+	}
+	// the wallet count isn't rolled up, as it is simply the size of the bucket of the prefix
+	names := make([]string, 0, len(prefixes))
+	for prefix := range prefixes {
+		names = append(names, prefix)
+		rdb.conn.Send("HLEN", rdb.key("a:"+prefix))
+	}
+	counts, err := redis.Int64s(RedisFlushAndReceive(rdb.conn, len(names)))
+	if err != nil {
+		return nil, fmt.Errorf("redis: failed to get wallet count of address prefixes: %v", err)
+	}
+	for i, prefix := range names {
+		stats := prefixes[prefix]
+		stats.Wallets = uint64(counts[i])
+		prefixes[prefix] = stats
+	}
+	return prefixes, nil
+}
+
+// ComputeAddressPrefixStats implements AddressPrefixDatabase.ComputeAddressPrefixStats
+func (rdb *RedisDatabase) ComputeAddressPrefixStats(prefix string) (AddressPrefixStats, error) {
+	key := rdb.key("a:" + prefix)
+	values, err := redis.StringMap(rdb.conn.Do("HGETALL", key))
+	if err != nil {
+		return AddressPrefixStats{}, fmt.Errorf("redis: failed to get wallets of %s: %v", key, err)
+	}
+	var stats AddressPrefixStats
+	for field, value := range values {
+		var wallet WalletFocusBalance
+		err = rdb.encoder.Unmarshal([]byte(value), &wallet)
+		if err != nil {
+			return AddressPrefixStats{}, fmt.Errorf("redis: failed to decode wallet at %s#%s: %v", key, field, err)
+		}
+		stats.AddWallet(wallet.Balance)
+	}
+	return stats, nil
+}
+
+// GetRichList implements RichListDatabase.GetRichList
+//
+// The rich list ZSET only defines the rank of the addresses, as its (floating point) scores
+// cannot represent all balances exactly. The returned balances are taken from the wallets instead.
+func (rdb *RedisDatabase) GetRichList(n int) ([]RichListEntry, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	strs, err := redis.Strings(rdb.conn.Do("ZREVRANGE", rdb.key(richListKey), 0, n-1))
+	if err != nil {
+		return nil, fmt.Errorf("redis: failed to get top %d addresses of the rich list: %v", n, err)
+	}
+	entries := make([]RichListEntry, 0, len(strs))
+	for _, str := range strs {
+		var entry RichListEntry
+		err = entry.Address.LoadString(str)
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid address %q in rich list: %v", str, err)
+		}
+		key, field := rdb.getAddressKeyAndField(entry.Address)
+		wallet, err := RedisWalletFocusBalance(rdb.encoder)(rdb.conn.Do("HGET", key, field))
+		if err != nil {
+			return nil, fmt.Errorf("redis: failed to get wallet for %s at %s#%s: %v", str, key, field, err)
+		}
+		entry.Balance = SnapshotBalance{
+			Unlocked: wallet.Balance.Unlocked,
+			Locked:   wallet.Balance.Locked.Total,
+		}
+		entries = append(entries, entry)
+	}
+	sortRichList(entries)
+	return entries, nil
+}
+
+// rankAddress updates the score of an address in the rich list to its current (total) coin balance,
+// removing it from the rich list once it no longer has any coins,
+// using the rich list script (deferred if a batch is in progress) if transactional.
+func (rdb *RedisDatabase) rankAddress(address types.UnlockHash) error {
+	key, field := rdb.getAddressKeyAndField(address)
+	if rdb.pipeline.transactional {
+		args := []interface{}{rdb.richListScript.Hash(), 3, key, rdb.key(richListKey), rdb.key(balanceDistributionKey),
+			field, address.String()}
+		labels := BalanceDistributionLabels()
+		for bucket, bound := range balanceDistributionBounds(rdb.oneCoin) {
+			args = append(args, labels[bucket], bound.String())
+		}
+		args = append(args, labels[len(labels)-1])
+		err := rdb.pipeline.Write("EVALSHA", args...)
+		if err != nil {
+			return fmt.Errorf("redis: failed to rank %s in the rich list: %v", address.String(), err)
+		}
+		return nil
+	}
+	wallet, err := RedisWalletFocusBalance(rdb.encoder)(rdb.conn.Do("HGET", key, field))
+	if err != nil {
+		return fmt.Errorf("redis: failed to get wallet for %s at %s#%s: %v", address.String(), key, field, err)
+	}
+	total := wallet.Balance.Unlocked.Add(wallet.Balance.Locked.Total)
+	previous, err := redis.String(rdb.conn.Do("ZSCORE", rdb.key(richListKey), address.String()))
+	if err != nil && err != redis.ErrNil {
+		return fmt.Errorf("redis: failed to get the rank of %s in the rich list: %v", address.String(), err)
+	}
+	if total.IsZero() {
+		err = rdb.pipeline.Write("ZREM", rdb.key(richListKey), address.String())
+	} else {
+		err = rdb.pipeline.Write("ZADD", rdb.key(richListKey), total.String(), address.String())
+	}
+	if err != nil {
+		return fmt.Errorf("redis: failed to rank %s in the rich list: %v", address.String(), err)
+	}
+	// move the address to the bucket of its new balance, if changed
+	oldBucket, newBucket := -1, -1
+	if previous != "" {
+		oldBucket, err = rdb.balanceDistributionBucket(previous)
+		if err != nil {
+			return err
+		}
+	}
+	if !total.IsZero() {
+		newBucket, err = rdb.balanceDistributionBucket(total.String())
+		if err != nil {
+			return err
+		}
+	}
+	if oldBucket == newBucket {
+		return nil
+	}
+	labels := BalanceDistributionLabels()
+	if oldBucket >= 0 {
+		err = rdb.pipeline.Write("HINCRBY", rdb.key(balanceDistributionKey), labels[oldBucket], -1)
+		if err != nil {
+			return fmt.Errorf("redis: failed to update the balance distribution: %v", err)
+		}
+	}
+	if newBucket >= 0 {
+		err = rdb.pipeline.Write("HINCRBY", rdb.key(balanceDistributionKey), labels[newBucket], 1)
+		if err != nil {
+			return fmt.Errorf("redis: failed to update the balance distribution: %v", err)
+		}
+	}
+	return nil
+}
+
+// balanceDistributionBucket returns the bucket of a BalanceDistribution the given rich list score belongs to.
+// As the scores of a ZSET are floating point numbers, the (decimal) balance is bucketed as scored by Redis,
+// such that an address is always moved out of the bucket it was counted in.
+func (rdb *RedisDatabase) balanceDistributionBucket(score string) (int, error) {
+	balance, err := redisScoreCurrency(score)
+	if err != nil {
+		return 0, fmt.Errorf("redis: invalid rich list score %q: %v", score, err)
+	}
+	return balanceDistributionBucket(balance, rdb.oneCoin), nil
+}
+
+// redisScoreCurrency parses a ZSET score, formatted by Redis as a (possibly exponential) floating point number,
+// as a currency. Scores are stored as doubles, hence large currencies are approximated.
+func redisScoreCurrency(score string) (types.Currency, error) {
+	f, _, err := big.ParseFloat(score, 10, 53, big.ToNearestEven)
+	if err != nil {
+		return types.Currency{}, err
+	}
+	i, _ := f.Int(nil)
+	return types.NewCurrency(i), nil
+}
+
+// GetBalanceDistribution implements BalanceDistributionDatabase.GetBalanceDistribution
+func (rdb *RedisDatabase) GetBalanceDistribution() (BalanceDistribution, error) {
+	counts, err := redis.Int64Map(rdb.conn.Do("HGETALL", rdb.key(balanceDistributionKey)))
+	if err != nil {
+		return BalanceDistribution{}, fmt.Errorf("redis: failed to get the balance distribution: %v", err)
+	}
+	var dist BalanceDistribution
+	for bucket, label := range BalanceDistributionLabels() {
+		if n := counts[label]; n > 0 {
+			dist[bucket] = uint64(n)
+		}
+	}
+	return dist, nil
+}
+
+// GetCoinOutput implements Database.GetCoinOutput
+//
+// A coin output which isn't stored as is, is looked up in the spent coin outputs, as it might have been archived.
+func (rdb *RedisDatabase) GetCoinOutput(id types.CoinOutputID) (CoinOutputInfo, error) {
+	coinOutputKey, coinOutputField := rdb.getCoinOutputKeyAndField(id)
+	var co DatabaseCoinOutput
+	err := rdb.coinOutputLoader(&co)(rdb.conn.Do("HGET", coinOutputKey, coinOutputField))
+	if err == redis.ErrNil {
+		coinOutputKey, coinOutputField = rdb.getSpentCoinOutputKeyAndField(id)
+		err = rdb.spentCoinOutputLoader(nil, &co)(rdb.conn.Do("HGET", coinOutputKey, coinOutputField))
+	}
+	switch err {
+	case nil:
+	case redis.ErrNil:
+		return CoinOutputInfo{}, ErrNotFound
+	default:
+		return CoinOutputInfo{}, fmt.Errorf(
+			"redis: failed to get coin output %s at %s#%s: %v", id.String(), coinOutputKey, coinOutputField, err)
+	}
+	info, err := co.Info(id)
+	if err != nil {
+		return CoinOutputInfo{}, fmt.Errorf(
+			"redis: failed to decode raw condition of coin output %s: %v", id.String(), err)
+	}
+	return info, nil
+}
+
+// IterateCoinOutputs implements CoinOutputSetDatabase.IterateCoinOutputs
+//
+// As coin outputs are bucketed by the first 4 hex characters of their ID (see getCoinOutputKeyAndField),
+// all possible buckets are read one by one, in order, such that only a single bucket is kept in memory.
+// The spent coin outputs archived in a bucket are read together with the other coin outputs of that bucket.
+func (rdb *RedisDatabase) IterateCoinOutputs(fn func(CoinOutputInfo) error) error {
+	for bucket := 0; bucket <= 0xffff; bucket++ {
+		prefix := fmt.Sprintf("%04x", bucket)
+		key, spentKey := rdb.key("c:"+prefix), rdb.key(spentCoinOutputsKey+":"+prefix)
+		values, err := redis.StringMap(rdb.conn.Do("HGETALL", key))
+		if err != nil {
+			return fmt.Errorf("redis: failed to get coin outputs of %s: %v", key, err)
+		}
+		spentValues, err := redis.StringMap(rdb.conn.Do("HGETALL", spentKey))
+		if err != nil {
+			return fmt.Errorf("redis: failed to get spent coin outputs of %s: %v", spentKey, err)
+		}
+		fields := make([]string, 0, len(values)+len(spentValues))
+		for field := range values {
+			fields = append(fields, field)
+		}
+		for field := range spentValues {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			var id types.CoinOutputID
+			err = id.LoadString(prefix + field)
+			if err != nil {
+				return fmt.Errorf("redis: invalid coin output ID at %s#%s: %v", key, field, err)
+			}
+			var co DatabaseCoinOutput
+			if value, ok := values[field]; ok {
+				err = rdb.coinOutputLoader(&co)(value, nil)
+			} else {
+				err = rdb.spentCoinOutputLoader(nil, &co)(spentValues[field], nil)
+			}
+			if err != nil {
+				return fmt.Errorf("redis: failed to decode coin output at %s#%s: %v", key, field, err)
+			}
+			info, err := co.Info(id)
+			if err != nil {
+				return fmt.Errorf("redis: failed to decode raw condition of coin output %s: %v", id.String(), err)
+			}
+			err = fn(info)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (rdb *RedisDatabase) lockValueAsLockTime(lt LockType, value LockValue) LockValue {
+	switch lt {
+	case LockTypeTime:
+		return value
+	case LockTypeHeight:
+		return LockValue(rdb.networkTime) + (value-LockValue(rdb.networkBlockHeight))*rdb.blockFrequency
+	default:
+		panic(fmt.Sprintf("invalid lock type %d", lt))
+	}
+}
+
+// PublishesEvents implements EventPublisherDatabase.PublishesEvents
+func (rdb *RedisDatabase) PublishesEvents() bool {
+	return rdb.publishEvents
+}
+
+// PublishEvents implements EventPublisherDatabase.PublishEvents
+//
+// Publishes each event as JSON to the channel of its type (see RedisDatabase), regardless of the encoding
+// used to store values, pipelining all of them (using a single transaction if supported).
+func (rdb *RedisDatabase) PublishEvents(events []Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	err := rdb.Begin()
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		var channel string
+		switch event.Type {
+		case EventTypeBlockApplied, EventTypeBlockReverted:
+			channel = rdb.key(blockEventsChannel)
+		case EventTypeOutputCreated, EventTypeOutputSpent:
+			channel = rdb.key(outputEventsChannel)
+		case EventTypeBalanceChanged:
+			channel = rdb.key(walletEventsChannelPrefix + event.Data.(BalanceEventData).Address.String())
+		default:
+			continue
+		}
+		b, err := json.Marshal(event)
+		if err != nil {
+			rdb.pipeline.Commit()
+			return fmt.Errorf("redis: failed to JSON-encode %s event: %v", event.Type, err)
+		}
+		err = rdb.pipeline.Write("PUBLISH", channel, b)
+		if err != nil {
+			rdb.pipeline.Commit()
+			return fmt.Errorf("redis: failed to publish %s event: %v", event.Type, err)
+		}
+	}
+	err = rdb.pipeline.Commit()
+	if err != nil {
+		return fmt.Errorf("redis: failed to publish events: %v", err)
+	}
+	return nil
+}
+
+// key returns the given key, prefixed with the key prefix of this database (if any),
+// such that multiple networks can share a single Redis database.
+func (rdb *RedisDatabase) key(name string) string {
+	return rdb.keyPrefix + name
+}
+
+func (rdb *RedisDatabase) getAddressKeyAndField(uh types.UnlockHash) (key, field string) {
+	str := uh.String()
+	key, field = rdb.key("a:"+str[:6]), str[6:]
+	return
+}
+
+func (rdb *RedisDatabase) getBalanceSnapshotKey(height types.BlockHeight) string {
+	return rdb.key(balanceSnapshotKey) + ":" + strconv.FormatUint(uint64(height), 10)
+}
+
+func (rdb *RedisDatabase) getTransactionKey(id types.TransactionID) string {
+	return rdb.key(transactionKey) + ":" + id.String()
+}
+
+func (rdb *RedisDatabase) getAtomicSwapContractKey(id types.CoinOutputID) string {
+	return rdb.key(atomicSwapContractKey) + ":" + id.String()
+}
+
+func (rdb *RedisDatabase) getAtomicSwapContractsKey(uh types.UnlockHash) string {
+	return rdb.key(atomicSwapContractsKey) + ":" + uh.String()
+}
+
+func (rdb *RedisDatabase) getArbitraryDataKeys(data []byte) (hashKey, prefixKey string) {
+	hashKey = rdb.key(arbitraryDataHashKey) + ":" + arbitraryDataHash(data).String()
+	prefixKey = rdb.getArbitraryDataPrefixKey(arbitraryDataPrefix(data))
+	return
+}
+
+func (rdb *RedisDatabase) getArbitraryDataPrefixKey(prefix []byte) string {
+	return rdb.key(arbitraryDataPrefixKey) + ":" + types.ByteSlice(prefix).String()
+}
+
+func (rdb *RedisDatabase) getMinerPayoutsKey(uh types.UnlockHash) string {
+	return rdb.key(minerPayoutsKey) + ":" + uh.String()
+}
+
+func (rdb *RedisDatabase) getAddressHistoryKey(uh types.UnlockHash) string {
+	return rdb.key(addressHistoryKey) + ":" + uh.String()
+}
+
+// getWalletLockedOutputsKey returns the key of the ZSET indexing the locked outputs of the given address,
+// see indexWalletLockedOutput.
+func (rdb *RedisDatabase) getWalletLockedOutputsKey(uh types.UnlockHash) string {
+	return rdb.key(walletLockedOutputsKey) + ":" + uh.String()
+}
+
+func (rdb *RedisDatabase) getDailyStatsKey(date string) string {
+	return rdb.key(dailyStatsKey) + ":" + date
+}
+
+func (rdb *RedisDatabase) getDailyTopAddressesKeys(date string) (sendersKey, receiversKey string) {
+	sendersKey, receiversKey = rdb.key(dailySendersKey)+":"+date, rdb.key(dailyReceiversKey)+":"+date
+	return
+}
+
+func (rdb *RedisDatabase) getCounterpartiesKeys(uh types.UnlockHash) (countsKey, totalsKey string) {
+	str := uh.String()
+	countsKey, totalsKey = rdb.key(counterpartiesKey)+":"+str, rdb.key(counterpartiesTotalsKey)+":"+str
+	return
+}
+
+func (rdb *RedisDatabase) getMultisigKeys(uh types.UnlockHash) (spendsKey, signersKey string) {
+	str := uh.String()
+	spendsKey, signersKey = rdb.key(multisigSpendsKey)+":"+str, rdb.key(multisigSignersKey)+":"+str
+	return
+}
+
+func (rdb *RedisDatabase) getCoinOutputKeyAndField(id types.CoinOutputID) (key, field string) {
+	str := id.String()
+	key, field = rdb.key("c:"+str[:4]), str[4:]
+	return
+}
+
+// marshalCoinOutput encodes the given coin output in the custom CSV format,
+// or as a StoredCoinOutput message when using the protobuf encoding (see protobufEncoder).
+func (rdb *RedisDatabase) marshalCoinOutput(co DatabaseCoinOutput) interface{} {
+	if rdb.encoder.Type() == EncodingTypeProtobuf {
+		return MustMarshal(rdb.encoder, co)
+	}
+	return co.String()
+}
+
+// coinOutputLoader creates a function that can be used to decode a coin output,
+// as encoded by marshalCoinOutput, into the given (reference) coin output.
+func (rdb *RedisDatabase) coinOutputLoader(co *DatabaseCoinOutput) func(interface{}, error) error {
+	if rdb.encoder.Type() == EncodingTypeProtobuf {
+		return RedisValue(rdb.encoder, co)
+	}
+	return RedisStringLoader(co)
+}
+
+func (rdb *RedisDatabase) getSpentCoinOutputKeyAndField(id types.CoinOutputID) (key, field string) {
+	str := id.String()
+	key, field = rdb.key(spentCoinOutputsKey+":"+str[:4]), str[4:]
+	return
+}
+
+// marshalSpentCoinOutput encodes the given spent coin output the same way as marshalCoinOutput,
+// prefixed by the height at which it was spent and the CSV seperator.
+func (rdb *RedisDatabase) marshalSpentCoinOutput(height types.BlockHeight, co DatabaseCoinOutput) string {
+	return fmt.Sprintf("%d%s%s", height, csvSeperator, rdb.marshalCoinOutput(co))
+}
+
+// spentCoinOutputLoader creates a function that can be used to decode a spent coin output,
+// as well as the height at which it was spent, unless the given height is nil.
+func (rdb *RedisDatabase) spentCoinOutputLoader(height *types.BlockHeight, co *DatabaseCoinOutput) func(interface{}, error) error {
+	return func(reply interface{}, err error) error {
+		b, err := redis.Bytes(reply, err)
+		if err != nil {
+			return err
+		}
+		parts := bytes.SplitN(b, []byte(csvSeperator), 2)
+		if len(parts) != 2 {
+			return errors.New("spent coin output lacks its spend height")
+		}
+		h, err := strconv.ParseUint(string(parts[0]), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid spend height: %v", err)
+		}
+		if height != nil {
+			*height = types.BlockHeight(h)
+		}
+		return rdb.coinOutputLoader(co)(parts[1], nil)
+	}
+}
+
+func (rdb *RedisDatabase) getCoinOutputProvenanceKeyAndField(id types.CoinOutputID) (key, field string) {
+	str := id.String()
+	key, field = rdb.key(coinOutputProvenanceKey+":"+str[:4]), str[4:]
+	return
+}
+
+func (rdb *RedisDatabase) getBlockStakeOutputKeyAndField(id types.BlockStakeOutputID) (key, field string) {
+	str := id.String()
+	key, field = rdb.key(blockStakeOutputsKey+":"+str[:4]), str[4:]
+	return
+}
+
+// getBlockStakeLocksKey returns the key of the ZSET indexing the block stake outputs locked by the given lock type.
+func (rdb *RedisDatabase) getBlockStakeLocksKey(lt LockType) string {
+	if lt == LockTypeTime {
+		return rdb.key(lockedByTimestampBlockStakesKey)
+	}
+	return rdb.key(lockedByHeightBlockStakesKey)
+}
+
+// getCoinOutputLocksKey returns the key of the ZSET indexing the coin outputs locked by the given lock type,
+// which are either still locked, or unlocked already (no matter whether they have been spent since).
+func (rdb *RedisDatabase) getCoinOutputLocksKey(lt LockType, locked bool) string {
+	switch {
+	case lt == LockTypeTime && locked:
+		return rdb.key(lockedCoinOutputsByTimestampKey)
+	case lt == LockTypeTime:
+		return rdb.key(unlockedCoinOutputsByTimestampKey)
+	case locked:
+		return rdb.key(lockedCoinOutputsByHeightKey)
+	default:
+		return rdb.key(unlockedCoinOutputsByHeightKey)
+	}
+}
+
+// Encoding Helper Functions
+
+// EncodeCondition binary-encodes the given condition,
+// using the Rivine binary encoding.
+func EncodeCondition(condition types.UnlockConditionProxy) types.ByteSlice {
+	return types.ByteSlice(encoding.Marshal(condition))
+}
+
+// JSON Helper Functions
+
+// JSONMarshal marshals the given value and panics if that fails.
+func JSONMarshal(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+// Redis Helper Functions
+
+// RedisError ignores the reply from a Redis server,
+// and returns only the returned error.
+func RedisError(_ interface{}, err error) error {
+	return err
+}
+
+// RedisSumInt64s interprets the reply as an int64 slice,
+// and sums all those values into a single int64.
+func RedisSumInt64s(reply interface{}, err error) (int64, error) {
+	xs, err := redis.Int64s(reply, err)
+	if err != nil {
+		return 0, err
+	}
+	var sum int64
+	for _, x := range xs {
+		sum += x
+	}
+	return sum, nil
+}
+
+// RedisJSONValue creates a function that can be used to unmarshal a string/byte-slice
+// as a JSON value into the given (reference) value (v).
+func RedisJSONValue(v interface{}) func(interface{}, error) error {
+	return RedisValue(jsonEncoder{}, v)
+}
+
+// RedisValue creates a function that can be used to unmarshal a string/byte-slice
+// as a value encoded using the given encoder into the given (reference) value (v).
+func RedisValue(encoder Encoder, v interface{}) func(interface{}, error) error {
+	return func(reply interface{}, err error) error {
+		b, err := redis.Bytes(reply, err)
+		if err != nil {
+			return err
+		}
+		return encoder.Unmarshal(b, v)
+	}
+}
+
+// RedisStringLoader creates a function that can be used to unmarshal a string value
+// as a (custom) StringLoader value into the given (reference) value (v).
+func RedisStringLoader(sl StringLoader) func(interface{}, error) error {
+	return func(reply interface{}, err error) error {
+		s, err := redis.String(reply, err)
+		if err != nil {
+			return err
+		}
+		return sl.LoadString(s)
+	}
+}
+
+// RedisWallet creates a function that unmarshals an encoded address (wallet) value,
+// but creates a fresh wallet if no wallet was created yet for that address.
+func RedisWallet(encoder Encoder) func(interface{}, error) (Wallet, error) {
+	return func(r interface{}, e error) (wallet Wallet, err error) {
+		err = RedisValue(encoder, &wallet)(r, e)
+		if err == redis.ErrNil {
+			err = nil
+			wallet = Wallet{}
+		}
+		return
+	}
+}
+
+// RedisWalletFocusUnlockedBalance creates a function that unmarshals an encoded address (wallet) value,
+// but creates a fresh wallet if no wallet was created yet for that address.
+func RedisWalletFocusUnlockedBalance(encoder Encoder) func(interface{}, error) (WalletFocusUnlockedBalance, error) {
+	return func(r interface{}, e error) (wallet WalletFocusUnlockedBalance, err error) {
+		err = RedisValue(encoder, &wallet)(r, e)
+		if err == redis.ErrNil {
+			err = nil
+			wallet = WalletFocusUnlockedBalance{}
+		}
+		return
+	}
+}
+
+// RedisWalletFocusBalance creates a function that unmarshals an encoded address (wallet) value,
+// but creates a fresh wallet if no wallet was created yet for that address.
+func RedisWalletFocusBalance(encoder Encoder) func(interface{}, error) (WalletFocusBalance, error) {
+	return func(r interface{}, e error) (wallet WalletFocusBalance, err error) {
+		err = RedisValue(encoder, &wallet)(r, e)
+		if err == redis.ErrNil {
+			err = nil
+			wallet = WalletFocusBalance{}
+		}
+		return
+	}
+}
+
+// RedisWalletFocusMultiSignAddresses creates a function that unmarshals an encoded address (wallet) value,
+// but creates a fresh wallet if no wallet was created yet for that address.
+func RedisWalletFocusMultiSignAddresses(encoder Encoder) func(interface{}, error) (WalletFocusMultiSignAddresses, error) {
+	return func(r interface{}, e error) (wallet WalletFocusMultiSignAddresses, err error) {
+		err = RedisValue(encoder, &wallet)(r, e)
+		if err == redis.ErrNil {
+			err = nil
+			wallet = WalletFocusMultiSignAddresses{}
+		}
+		return
+	}
+}
+
+// RedisWalletFocusMultiSignData creates a function that unmarshals an encoded address (wallet) value,
+// but creates a fresh wallet if no wallet was created yet for that address.
+func RedisWalletFocusMultiSignData(encoder Encoder) func(interface{}, error) (WalletFocusMultiSignData, error) {
+	return func(r interface{}, e error) (wallet WalletFocusMultiSignData, err error) {
+		err = RedisValue(encoder, &wallet)(r, e)
+		if err == redis.ErrNil {
+			err = nil
+			wallet = WalletFocusMultiSignData{}
+		}
+		return
+	}
+}
+
+// RedisCoinOutputResults returns all CoinOutputResults found for a given []string redis reply,
+// only used in combination with `(*RedisDatabase).UpdateLockedCoinOutputs`, see that method for more information.
+func RedisCoinOutputResults(reply interface{}, err error) ([]DatabaseCoinOutputResult, error) {
+	strings, err := redis.Strings(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]DatabaseCoinOutputResult, len(strings))
+	for i, str := range strings {
+		err = results[i].LoadString(str)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// RedisFlushAndReceive is used to flush all buffered commands (using SEND),
+// and receiving all exepcted replies.
+func RedisFlushAndReceive(conn redis.Conn, n int) (interface{}, error) {
+	err := conn.Flush()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, n)
+	for i := 0; i < n; i++ {
+		values[i], err = conn.Receive()
+		if err != nil {
+			return values, err
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,63 @@
+package rexplorer
+
+import (
+	"testing"
+
+	"github.com/rivine/rivine/types"
+)
+
+func TestDatabaseCoinOutputLoadString(t *testing.T) {
+	var uh types.UnlockHash
+	uh.Type, uh.Hash[0] = types.UnlockTypePubKey, 1
+	co := DatabaseCoinOutput{
+		State:        CoinOutputStateLiquid,
+		UnlockHash:   uh,
+		CoinValue:    types.NewCurrency64(42),
+		LockType:     LockTypeNone,
+		Description:  types.ByteSlice("foo"),
+		RawCondition: EncodeCondition(types.NewCondition(types.NewUnlockHashCondition(uh))),
+	}
+
+	var loaded DatabaseCoinOutput
+	err := loaded.LoadString(co.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.String() != co.String() {
+		t.Fatalf("expected %q, loaded %q", co.String(), loaded.String())
+	}
+	info, err := loaded.Info(types.CoinOutputID{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.UnknownCondition || info.Condition.ConditionType() != types.ConditionTypeUnlockHash {
+		t.Fatalf("unexpected condition %v (unknown: %v)", info.Condition, info.UnknownCondition)
+	}
+
+	// coin outputs stored prior to storing their raw condition lack the last field
+	legacy := FormatStringers(csvSeperator, co.State, co.UnlockHash, co.CoinValue, co.LockType, co.LockValue, co.Description)
+	loaded = DatabaseCoinOutput{RawCondition: types.ByteSlice{1}}
+	err = loaded.LoadString(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.UnlockHash != uh || !loaded.CoinValue.Equals(co.CoinValue) || string(loaded.Description) != "foo" || len(loaded.RawCondition) != 0 {
+		t.Fatalf("unexpected coin output loaded from %q: %v", legacy, loaded)
+	}
+	info, err = loaded.Info(types.CoinOutputID{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.UnknownCondition || info.Burned || info.Condition.Condition != nil {
+		t.Fatalf("expected unknown condition, got %v (unknown: %v, burned: %v)", info.Condition, info.UnknownCondition, info.Burned)
+	}
+
+	var result DatabaseCoinOutputResult
+	err = result.LoadString(types.CoinOutputID{}.String() + csvSeperator + legacy[len(co.State.String())+1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.UnlockHash != uh || len(result.RawCondition) != 0 {
+		t.Fatalf("unexpected coin output result: %v", result)
+	}
+}
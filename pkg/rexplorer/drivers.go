@@ -0,0 +1,106 @@
+package rexplorer
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/rivine/rivine/types"
+)
+
+// DatabasePasswordEnvVar is the environment variable used as the database password,
+// in case no password is defined using the --db-password flag.
+const DatabasePasswordEnvVar = "REXPLORER_DB_PASSWORD"
+
+// DatabaseConfig collects all configuration used to open a Database,
+// using one of the registered database drivers.
+type DatabaseConfig struct {
+	// Address of the database, the format of which depends on the driver used
+	// (e.g. a TCP address or a file path). Each driver defines its own default address,
+	// which is used in case no address is defined.
+	Address string
+	// Slot of the database, only used by drivers which support multiple databases per server.
+	Slot int
+	// Password used to authenticate to the database server, only used by the Redis drivers,
+	// other drivers define their credentials as part of their address.
+	Password string
+	// TLS defines whether or not the connection to the database server is secured using TLS,
+	// only used by the Redis drivers, other drivers define it as part of their address.
+	TLS bool
+	// CommandRate is the maximum amount of commands per second issued to the database server,
+	// 0 for no limit. Only used by the Redis drivers.
+	CommandRate int
+	// BatchSize is the maximum amount of writes pipelined at once while applying a consensus change,
+	// 0 for the default (DefaultRedisBatchSize). Only used by the redis-cluster driver,
+	// as the other Redis drivers send all writes of a consensus change as a single transaction.
+	BatchSize int
+	// KeyPrefix is prepended to all keys, such that multiple networks can share a single database (e.g. "tft:standard:").
+	// Only used by the Redis drivers.
+	KeyPrefix string
+	// Encoding used to encode all (structured) values of a fresh dataset, JSON by default.
+	// An existing dataset keeps using the encoding it was created with. Only used by the Redis drivers.
+	Encoding EncodingType
+	// PublishEvents defines whether or not the events of every consensus change are published to the consumers
+	// of the database, see EventPublisherDatabase. Only used by the Redis drivers, publishing them to pub/sub channels.
+	PublishEvents bool
+	// ArchiveSpentOutputs defines whether or not spent coin outputs are moved to a separate keyspace,
+	// together with the height at which they were spent, rather than being kept in place, marked as spent.
+	// Only used by the Redis drivers, other drivers always keep spent coin outputs in place.
+	ArchiveSpentOutputs bool
+	// Tracer (if not nil) traces the calls made to the database as part of a traced consensus change.
+	// Only used by the Redis drivers, tracing every round trip to the Redis server.
+	Tracer *Tracer
+
+	BlockchainInfo types.BlockchainInfo
+	ChainConstants types.ChainConstants
+}
+
+// DatabaseDriver opens a Database, using the given config.
+type DatabaseDriver func(cfg DatabaseConfig) (Database, error)
+
+var (
+	databaseDriversMu sync.RWMutex
+	databaseDrivers   = make(map[string]DatabaseDriver)
+)
+
+// RegisterDatabaseDriver makes a database driver available by the given name.
+// It is meant to be called from the init function of the file implementing the driver,
+// and panics if called twice for the same name, or if the driver is nil.
+func RegisterDatabaseDriver(name string, driver DatabaseDriver) {
+	databaseDriversMu.Lock()
+	defer databaseDriversMu.Unlock()
+	if driver == nil {
+		panic("RegisterDatabaseDriver: driver is nil")
+	}
+	if _, dup := databaseDrivers[name]; dup {
+		panic("RegisterDatabaseDriver: called twice for driver " + name)
+	}
+	databaseDrivers[name] = driver
+}
+
+// DatabaseDriverNames returns a sorted list of the names of all registered database drivers.
+func DatabaseDriverNames() []string {
+	databaseDriversMu.RLock()
+	defer databaseDriversMu.RUnlock()
+	names := make([]string, 0, len(databaseDrivers))
+	for name := range databaseDrivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OpenDatabase opens a Database using the database driver registered by the given name.
+func OpenDatabase(name string, cfg DatabaseConfig) (Database, error) {
+	databaseDriversMu.RLock()
+	driver, ok := databaseDrivers[name]
+	databaseDriversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown database driver %q (forgotten import?), has to be one of %v", name, DatabaseDriverNames())
+	}
+	db, err := driver(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s database: %v", name, err)
+	}
+	return db, nil
+}
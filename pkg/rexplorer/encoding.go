@@ -0,0 +1,164 @@
+package rexplorer
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/threefoldfoundation/rexplorer/pkg/msgpack"
+)
+
+// Encoder defines the interface used to encode and decode the (structured) values stored in a Database,
+// such that the storage logic of a Database is decoupled from the encoding of the values it stores.
+type Encoder interface {
+	// Type returns the EncodingType of this Encoder.
+	Type() EncodingType
+	// Marshal encodes the given value.
+	Marshal(v interface{}) ([]byte, error)
+	// Unmarshal decodes the given data into the given (reference) value.
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// EncodingType defines the type of an encoding, used to encode values stored in a Database.
+type EncodingType uint8
+
+// The different encoding types supported.
+const (
+	EncodingTypeJSON EncodingType = iota
+	EncodingTypeMsgPack
+	EncodingTypeProtobuf
+)
+
+// String implements Stringer.String
+func (et EncodingType) String() string {
+	switch et {
+	case EncodingTypeJSON:
+		return "json"
+	case EncodingTypeMsgPack:
+		return "msgpack"
+	case EncodingTypeProtobuf:
+		return "protobuf"
+	default:
+		return fmt.Sprintf("EncodingType(%d)", et)
+	}
+}
+
+// LoadString implements StringLoader.LoadString
+func (et *EncodingType) LoadString(str string) error {
+	switch str {
+	case "json":
+		*et = EncodingTypeJSON
+	case "msgpack":
+		*et = EncodingTypeMsgPack
+	case "protobuf":
+		*et = EncodingTypeProtobuf
+	default:
+		return fmt.Errorf("unknown encoding type %q", str)
+	}
+	return nil
+}
+
+// NewEncoder creates a new Encoder for the given encoding type.
+func NewEncoder(et EncodingType) (Encoder, error) {
+	switch et {
+	case EncodingTypeJSON:
+		return jsonEncoder{}, nil
+	case EncodingTypeMsgPack:
+		return msgpackEncoder{}, nil
+	case EncodingTypeProtobuf:
+		return protobufEncoder{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported encoding type %s", et.String())
+	}
+}
+
+// jsonEncoder is the default Encoder, encoding all values as JSON.
+type jsonEncoder struct{}
+
+// Type implements Encoder.Type
+func (jsonEncoder) Type() EncodingType { return EncodingTypeJSON }
+
+// Marshal implements Encoder.Marshal
+func (jsonEncoder) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+// Unmarshal implements Encoder.Unmarshal
+func (jsonEncoder) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// msgpackEncoder encodes all values as MessagePack, using their JSON representation,
+// resulting in smaller values than the jsonEncoder, see the msgpack package for more information.
+type msgpackEncoder struct{}
+
+// Type implements Encoder.Type
+func (msgpackEncoder) Type() EncodingType { return EncodingTypeMsgPack }
+
+// Marshal implements Encoder.Marshal
+func (msgpackEncoder) Marshal(v interface{}) ([]byte, error) { return msgpack.Marshal(v) }
+
+// Unmarshal implements Encoder.Unmarshal
+func (msgpackEncoder) Unmarshal(data []byte, v interface{}) error { return msgpack.Unmarshal(data, v) }
+
+// protobufEncoder encodes wallets and coin outputs as protobuf messages (see the StoredWallet and StoredCoinOutput
+// messages of rexplorer.proto), which are smaller and cheaper to encode and decode than their JSON representation,
+// and encodes all other values as MessagePack, the same as the msgpackEncoder.
+type protobufEncoder struct {
+	msgpackEncoder
+}
+
+// Type implements Encoder.Type
+func (protobufEncoder) Type() EncodingType { return EncodingTypeProtobuf }
+
+// Marshal implements Encoder.Marshal
+func (e protobufEncoder) Marshal(v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case Wallet:
+		return protoStoredWallet(v).Encoded(), nil
+	case *Wallet:
+		return protoStoredWallet(*v).Encoded(), nil
+	case DatabaseCoinOutput:
+		return protoStoredCoinOutput(v).Encoded(), nil
+	case *DatabaseCoinOutput:
+		return protoStoredCoinOutput(*v).Encoded(), nil
+	default:
+		return e.msgpackEncoder.Marshal(v)
+	}
+}
+
+// Unmarshal implements Encoder.Unmarshal
+//
+// Wallets can be decoded as a Wallet, or as any of the specialised wallet structures (e.g. WalletFocusBalance),
+// of which the raw (undecoded) properties are left undefined.
+func (e protobufEncoder) Unmarshal(data []byte, v interface{}) error {
+	var wallet Wallet
+	switch v := v.(type) {
+	case *Wallet:
+		return decodeStoredWallet(data, v)
+	case *WalletFocusBalance:
+		err := decodeStoredWallet(data, &wallet)
+		*v = WalletFocusBalance{Balance: wallet.Balance}
+		return err
+	case *WalletFocusUnlockedBalance:
+		err := decodeStoredWallet(data, &wallet)
+		*v = WalletFocusUnlockedBalance{Balance: WalletBalanceFocusUnlocked{Unlocked: wallet.Balance.Unlocked}}
+		return err
+	case *WalletFocusMultiSignAddresses:
+		err := decodeStoredWallet(data, &wallet)
+		*v = WalletFocusMultiSignAddresses{MultiSignAddresses: wallet.MultiSignAddresses}
+		return err
+	case *WalletFocusMultiSignData:
+		err := decodeStoredWallet(data, &wallet)
+		*v = WalletFocusMultiSignData{MultiSignData: wallet.MultiSignData}
+		return err
+	case *DatabaseCoinOutput:
+		return decodeStoredCoinOutput(data, v)
+	default:
+		return e.msgpackEncoder.Unmarshal(data, v)
+	}
+}
+
+// MustMarshal marshals the given value using the given encoder, and panics if that fails.
+func MustMarshal(encoder Encoder, v interface{}) []byte {
+	b, err := encoder.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -0,0 +1,170 @@
+package rexplorer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rivine/rivine/types"
+)
+
+// The versions of the ERC20 bridge transactions, as defined by tfchain,
+// supported on the networks of which the transaction controllers register them.
+const (
+	// TransactionVersionERC20Conversion defines the transaction version of an ERC20 conversion transaction,
+	// converting coins into ERC20 tokens, sent to an ERC20 address. The converted coins leave the chain.
+	TransactionVersionERC20Conversion types.TransactionVersion = 208
+	// TransactionVersionERC20CoinCreation defines the transaction version of an ERC20 coin creation transaction,
+	// converting ERC20 tokens back into coins, created for the TFT address registered for the ERC20 address.
+	TransactionVersionERC20CoinCreation types.TransactionVersion = 209
+	// TransactionVersionERC20AddressRegistration defines the transaction version of an ERC20 address registration transaction,
+	// registering the ERC20 address linked to the TFT address of a public key.
+	TransactionVersionERC20AddressRegistration types.TransactionVersion = 210
+)
+
+type (
+	// ERC20BridgeStats collects the volumes bridged between the chain and ERC20 tokens,
+	// as well as the amount of registered ERC20 addresses.
+	ERC20BridgeStats struct {
+		ConvertedCoins    types.Currency `json:"convertedCoins"`
+		ConversionCount   uint64         `json:"conversionCount"`
+		CreatedCoins      types.Currency `json:"createdCoins"`
+		CreationCount     uint64         `json:"creationCount"`
+		RegistrationCount uint64         `json:"registrationCount"`
+	}
+
+	// erc20Conversion defines the data of an ERC20 conversion transaction.
+	erc20Conversion struct {
+		Address string         `json:"address"`
+		Value   types.Currency `json:"value"`
+	}
+
+	// erc20CoinCreation defines the data of an ERC20 coin creation transaction.
+	erc20CoinCreation struct {
+		Address types.UnlockHash `json:"address"`
+		Value   types.Currency   `json:"value"`
+	}
+
+	// erc20AddressRegistration defines the data of an ERC20 address registration transaction.
+	erc20AddressRegistration struct {
+		PublicKey    types.SiaPublicKey `json:"pubkey"`
+		ERC20Address string             `json:"erc20address"`
+	}
+)
+
+// normalizeERC20Address returns the given ERC20 address as lowercase hex, prefixed with 0x,
+// the form in which ERC20 addresses are stored.
+func normalizeERC20Address(address string) string {
+	return "0x" + strings.TrimPrefix(strings.ToLower(address), "0x")
+}
+
+// convertedCoins returns the coins converted into ERC20 tokens by the given transaction,
+// in case it is an ERC20 conversion transaction, and zero otherwise.
+// Converted coins leave the chain, and are as such no longer part of the coin supply.
+func convertedCoins(tx types.Transaction) (types.Currency, error) {
+	if tx.Version != TransactionVersionERC20Conversion {
+		return types.ZeroCurrency, nil
+	}
+	var data erc20Conversion
+	err := unmarshalTransactionData(tx, &data)
+	if err != nil {
+		return types.Currency{}, err
+	}
+	return data.Value, nil
+}
+
+// updateERC20Bridge indexes (or unindexes when reverting) the given ERC20 bridge transaction,
+// in case the database supports it: registering the ERC20 address of a TFT address,
+// and updating the bridged volumes.
+func (explorer *Explorer) updateERC20Bridge(tx types.Transaction, revert bool) error {
+	edb, ok := explorer.db.(ERC20Database)
+	if !ok {
+		return nil
+	}
+	if tx.Version != TransactionVersionERC20Conversion && tx.Version != TransactionVersionERC20CoinCreation &&
+		tx.Version != TransactionVersionERC20AddressRegistration {
+		return nil
+	}
+	stats, err := edb.GetERC20BridgeStats()
+	if err != nil {
+		return err
+	}
+	switch tx.Version {
+	case TransactionVersionERC20Conversion:
+		var data erc20Conversion
+		err = unmarshalTransactionData(tx, &data)
+		if err != nil {
+			return err
+		}
+		if revert {
+			stats.ConversionCount--
+			stats.ConvertedCoins = stats.ConvertedCoins.Sub(data.Value)
+		} else {
+			stats.ConversionCount++
+			stats.ConvertedCoins = stats.ConvertedCoins.Add(data.Value)
+		}
+	case TransactionVersionERC20CoinCreation:
+		var data erc20CoinCreation
+		err = unmarshalTransactionData(tx, &data)
+		if err != nil {
+			return err
+		}
+		if revert {
+			stats.CreationCount--
+			stats.CreatedCoins = stats.CreatedCoins.Sub(data.Value)
+		} else {
+			stats.CreationCount++
+			stats.CreatedCoins = stats.CreatedCoins.Add(data.Value)
+		}
+	case TransactionVersionERC20AddressRegistration:
+		var data erc20AddressRegistration
+		err = unmarshalTransactionData(tx, &data)
+		if err != nil {
+			return err
+		}
+		if data.ERC20Address == "" {
+			return fmt.Errorf("no ERC20 address defined")
+		}
+		uh := types.NewPubKeyUnlockHash(data.PublicKey)
+		address := normalizeERC20Address(data.ERC20Address)
+		if revert {
+			stats.RegistrationCount--
+			err = edb.RevertERC20AddressRegistration(address, uh)
+		} else {
+			stats.RegistrationCount++
+			err = edb.ApplyERC20AddressRegistration(address, uh)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return edb.SetERC20BridgeStats(stats)
+}
+
+// applyERC20Transaction removes the coins converted into ERC20 tokens by the given transaction from the coin supply,
+// and indexes it in case it is an ERC20 bridge transaction and the database supports it.
+// The coins created by ERC20 coin creation transactions are added to the coin supply as part of createdCoins.
+func (explorer *Explorer) applyERC20Transaction(tx types.Transaction) {
+	coins, err := convertedCoins(tx)
+	if err != nil {
+		panic(fmt.Sprintf("failed to get coins converted by ERC20 conversion tx %s: %v", tx.ID().String(), err))
+	}
+	explorer.stats.Coins = explorer.stats.Coins.Sub(coins)
+	err = explorer.updateERC20Bridge(tx, false)
+	if err != nil {
+		panic(fmt.Sprintf("failed to index ERC20 bridge tx %s: %v", tx.ID().String(), err))
+	}
+}
+
+// revertERC20Transaction adds the coins converted into ERC20 tokens by the given transaction to the coin supply again,
+// and unindexes it in case it is an ERC20 bridge transaction and the database supports it.
+func (explorer *Explorer) revertERC20Transaction(tx types.Transaction) {
+	coins, err := convertedCoins(tx)
+	if err != nil {
+		panic(fmt.Sprintf("failed to get coins converted by ERC20 conversion tx %s: %v", tx.ID().String(), err))
+	}
+	explorer.stats.Coins = explorer.stats.Coins.Add(coins)
+	err = explorer.updateERC20Bridge(tx, true)
+	if err != nil {
+		panic(fmt.Sprintf("failed to unindex ERC20 bridge tx %s: %v", tx.ID().String(), err))
+	}
+}
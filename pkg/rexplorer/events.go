@@ -0,0 +1,372 @@
+package rexplorer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/rivine/rivine/types"
+)
+
+// EventType defines the type of an event published by the explorer, see EventFeed.
+type EventType string
+
+// All event types published by the explorer, or derived from its events.
+const (
+	// EventTypeBlockApplied is published for every block applied while the consensus set is synced,
+	// once that block has been stored.
+	EventTypeBlockApplied EventType = "block-applied"
+	// EventTypeBlockReverted is published for every block reverted while the consensus set is synced,
+	// once that revert has been stored.
+	EventTypeBlockReverted EventType = "block-reverted"
+	// EventTypeOutputCreated is published for every coin output created by a block applied while the consensus set is synced,
+	// once that block has been stored. Outputs removed by a reverted block are implied by its EventTypeBlockReverted event.
+	EventTypeOutputCreated EventType = "output-created"
+	// EventTypeOutputSpent is published for every coin output spent by a block applied while the consensus set is synced,
+	// once that block has been stored. Spends undone by a reverted block are implied by its EventTypeBlockReverted event.
+	EventTypeOutputSpent EventType = "output-spent"
+	// EventTypeBalanceChanged is derived from the block events for the addresses a client subscribed to,
+	// each time the balance of such an address changed, see balanceTracker.
+	EventTypeBalanceChanged EventType = "balance-changed"
+	// EventTypeTransactionApplied is delivered to the change sinks for every transaction of an applied block.
+	EventTypeTransactionApplied EventType = "tx-applied"
+	// EventTypeTransactionReverted is delivered to the change sinks for every transaction of a reverted block.
+	EventTypeTransactionReverted EventType = "tx-reverted"
+	// EventTypeBalanceDelta is delivered to the change sinks for every address which received or sent coins
+	// within an applied or reverted block.
+	EventTypeBalanceDelta EventType = "balance-delta"
+)
+
+// EventSubscriptionBuffer is the amount of events buffered for a single subscription of an EventFeed,
+// the subscription being dropped once its buffer is full.
+const EventSubscriptionBuffer = 1024
+
+// MaxBalanceSubscriptions is the maximum amount of addresses a single client can subscribe to the balance of.
+const MaxBalanceSubscriptions = 100
+
+// errTooManyBalanceSubscriptions is returned when a client subscribes to more than MaxBalanceSubscriptions addresses.
+var errTooManyBalanceSubscriptions = fmt.Errorf("cannot subscribe to the balance of more than %d addresses", MaxBalanceSubscriptions)
+
+type (
+	// Event is a single event published by the explorer, or derived from its events.
+	Event struct {
+		Type EventType   `json:"event"`
+		Data interface{} `json:"data"`
+	}
+
+	// BlockEventData defines the data of an EventTypeBlockApplied or EventTypeBlockReverted event.
+	BlockEventData struct {
+		Height           types.BlockHeight `json:"height"`
+		ID               types.BlockID     `json:"id"`
+		Timestamp        types.Timestamp   `json:"timestamp"`
+		TransactionCount int               `json:"txCount"`
+	}
+
+	// OutputCreatedEventData defines the data of an EventTypeOutputCreated event,
+	// being the created coin output, its owner and value, whether or not it is locked,
+	// and the transaction (applied at the given height) creating it, undefined for miner payouts.
+	OutputCreatedEventData struct {
+		ID            types.CoinOutputID   `json:"id"`
+		Address       types.UnlockHash     `json:"address"`
+		Value         types.Currency       `json:"value"`
+		Locked        bool                 `json:"locked,omitempty"`
+		TransactionID *types.TransactionID `json:"txid,omitempty"`
+		Height        types.BlockHeight    `json:"height"`
+	}
+
+	// OutputSpentEventData defines the data of an EventTypeOutputSpent event,
+	// being the spent coin output, its owner and value, and the transaction (applied at the given height) spending it.
+	OutputSpentEventData struct {
+		ID            types.CoinOutputID  `json:"id"`
+		Address       types.UnlockHash    `json:"address"`
+		Value         types.Currency      `json:"value"`
+		TransactionID types.TransactionID `json:"txid"`
+		Height        types.BlockHeight   `json:"height"`
+	}
+
+	// BalanceEventData defines the data of an EventTypeBalanceChanged event,
+	// being the coin balance of an address as of the given block height.
+	BalanceEventData struct {
+		Address types.UnlockHash  `json:"address"`
+		Height  types.BlockHeight `json:"height"`
+		Balance WalletBalance     `json:"balance"`
+	}
+
+	// TransactionEventData defines the data of an EventTypeTransactionApplied or EventTypeTransactionReverted event,
+	// being the transaction and the block applied or reverted at the given height it is part of.
+	TransactionEventData struct {
+		ID          types.TransactionID `json:"id"`
+		BlockID     types.BlockID       `json:"blockId"`
+		Height      types.BlockHeight   `json:"height"`
+		Transaction types.Transaction   `json:"transaction"`
+	}
+
+	// BalanceDeltaEventData defines the data of an EventTypeBalanceDelta event,
+	// being the coins received and sent by an address within the block applied at the given height,
+	// or, if reverted, the coins no longer received and sent as that block was reverted.
+	BalanceDeltaEventData struct {
+		Address  types.UnlockHash  `json:"address"`
+		Height   types.BlockHeight `json:"height"`
+		Received types.Currency    `json:"received"`
+		Sent     types.Currency    `json:"sent"`
+		Reverted bool              `json:"reverted,omitempty"`
+	}
+)
+
+// newBlockEvent creates an event of the given type for the given block, applied or reverted at the given height.
+func newBlockEvent(typ EventType, block types.Block, height types.BlockHeight) Event {
+	return Event{
+		Type: typ,
+		Data: BlockEventData{
+			Height:           height,
+			ID:               block.ID(),
+			Timestamp:        block.Timestamp,
+			TransactionCount: len(block.Transactions),
+		},
+	}
+}
+
+// newOutputCreatedEvent creates an EventTypeOutputCreated event for the given coin output,
+// created by the given transaction (nil for miner payouts) at the given height.
+func newOutputCreatedEvent(id types.CoinOutputID, co types.CoinOutput, txID *types.TransactionID, height types.BlockHeight, locked bool) Event {
+	return Event{
+		Type: EventTypeOutputCreated,
+		Data: OutputCreatedEventData{
+			ID:            id,
+			Address:       co.Condition.UnlockHash(),
+			Value:         co.Value,
+			Locked:        locked,
+			TransactionID: txID,
+			Height:        height,
+		},
+	}
+}
+
+// newTransactionEvent creates an event of the given type for the given transaction,
+// part of the given block, applied or reverted at the given height.
+func newTransactionEvent(typ EventType, tx types.Transaction, blockID types.BlockID, height types.BlockHeight) Event {
+	return Event{
+		Type: typ,
+		Data: TransactionEventData{
+			ID:          tx.ID(),
+			BlockID:     blockID,
+			Height:      height,
+			Transaction: tx,
+		},
+	}
+}
+
+// newBalanceDeltaEvents creates an EventTypeBalanceDelta event for each address of the given totals,
+// collected for the block applied or reverted at the given height.
+func newBalanceDeltaEvents(delta addressTotalsDelta, height types.BlockHeight, reverted bool) []Event {
+	events := make([]Event, 0, len(delta))
+	for _, address := range delta.addresses() {
+		totals := delta[address]
+		events = append(events, Event{
+			Type: EventTypeBalanceDelta,
+			Data: BalanceDeltaEventData{
+				Address:  address,
+				Height:   height,
+				Received: totals.TotalReceived,
+				Sent:     totals.TotalSent,
+				Reverted: reverted,
+			},
+		})
+	}
+	return events
+}
+
+// EventFeed broadcasts the events published by the explorer to all its subscriptions,
+// such that they can be streamed to clients in real time.
+// Publishing never blocks: a subscription which doesn't keep up with the published events is dropped.
+type EventFeed struct {
+	mu            sync.Mutex
+	subscriptions map[*EventSubscription]struct{}
+	closed        bool
+}
+
+// EventSubscription receives the events published by an EventFeed, in the order they're published.
+type EventSubscription struct {
+	feed    *EventFeed
+	events  chan Event
+	dropped bool
+}
+
+// NewEventFeed creates an EventFeed without subscriptions.
+func NewEventFeed() *EventFeed {
+	return &EventFeed{subscriptions: make(map[*EventSubscription]struct{})}
+}
+
+// Subscribe to all events published from now on.
+// The events channel of the subscription is closed immediately in case the feed is closed already.
+func (feed *EventFeed) Subscribe() *EventSubscription {
+	sub := &EventSubscription{
+		feed:   feed,
+		events: make(chan Event, EventSubscriptionBuffer),
+	}
+	feed.mu.Lock()
+	defer feed.mu.Unlock()
+	if feed.closed {
+		close(sub.events)
+		return sub
+	}
+	feed.subscriptions[sub] = struct{}{}
+	return sub
+}
+
+// Publish the given events to all subscriptions, dropping the subscriptions which have no room left for them.
+// Events published to a nil (or closed) feed are discarded.
+func (feed *EventFeed) Publish(events ...Event) {
+	if feed == nil || len(events) == 0 {
+		return
+	}
+	feed.mu.Lock()
+	defer feed.mu.Unlock()
+	for sub := range feed.subscriptions {
+		if len(events) > cap(sub.events)-len(sub.events) {
+			sub.dropped = true
+			delete(feed.subscriptions, sub)
+			close(sub.events)
+			continue
+		}
+		for _, event := range events {
+			sub.events <- event
+		}
+	}
+}
+
+// Close the feed, closing the events channel of all its subscriptions.
+func (feed *EventFeed) Close() {
+	if feed == nil {
+		return
+	}
+	feed.mu.Lock()
+	defer feed.mu.Unlock()
+	if feed.closed {
+		return
+	}
+	feed.closed = true
+	for sub := range feed.subscriptions {
+		delete(feed.subscriptions, sub)
+		close(sub.events)
+	}
+}
+
+// Events returns the channel the events are received on,
+// closed when the subscription is closed, dropped or when its feed is closed.
+func (sub *EventSubscription) Events() <-chan Event {
+	return sub.events
+}
+
+// Dropped returns true if the subscription was dropped, as it didn't keep up with the published events.
+func (sub *EventSubscription) Dropped() bool {
+	sub.feed.mu.Lock()
+	defer sub.feed.mu.Unlock()
+	return sub.dropped
+}
+
+// Close the subscription, such that it no longer receives events.
+func (sub *EventSubscription) Close() {
+	sub.feed.mu.Lock()
+	defer sub.feed.mu.Unlock()
+	if _, ok := sub.feed.subscriptions[sub]; ok {
+		delete(sub.feed.subscriptions, sub)
+		close(sub.events)
+	}
+}
+
+// balanceTracker tracks the coin balance of the addresses a client subscribed to,
+// deriving an EventTypeBalanceChanged event each time the balance of such an address changed.
+// The unlock horizons of the locked balance are not tracked, as they change over time without any coins moving.
+type balanceTracker struct {
+	db  Database
+	mut sync.Locker
+	// the JSON-encoded balance last reported for each subscribed address
+	balances map[types.UnlockHash][]byte
+}
+
+// newBalanceTracker creates a balanceTracker reading the balances from the given database,
+// holding the given locker (if not nil) while doing so.
+func newBalanceTracker(db Database, mut sync.Locker) *balanceTracker {
+	return &balanceTracker{
+		db:       db,
+		mut:      mut,
+		balances: make(map[types.UnlockHash][]byte),
+	}
+}
+
+// subscribe to the balance of the given addresses, returning their current balance,
+// or errTooManyBalanceSubscriptions if more than MaxBalanceSubscriptions addresses would be subscribed to.
+func (bt *balanceTracker) subscribe(addresses []types.UnlockHash) ([]Event, error) {
+	var added []types.UnlockHash
+	for _, address := range addresses {
+		if _, ok := bt.balances[address]; !ok {
+			added = append(added, address)
+		}
+	}
+	if len(bt.balances)+len(added) > MaxBalanceSubscriptions {
+		return nil, errTooManyBalanceSubscriptions
+	}
+	for _, address := range added {
+		bt.balances[address] = nil
+	}
+	return bt.changes(added)
+}
+
+// unsubscribe from the balance of the given addresses.
+func (bt *balanceTracker) unsubscribe(addresses []types.UnlockHash) {
+	for _, address := range addresses {
+		delete(bt.balances, address)
+	}
+}
+
+// update returns an EventTypeBalanceChanged event for each subscribed address of which the balance changed
+// since it was last reported.
+func (bt *balanceTracker) update() ([]Event, error) {
+	addresses := make([]types.UnlockHash, 0, len(bt.balances))
+	for address := range bt.balances {
+		addresses = append(addresses, address)
+	}
+	return bt.changes(addresses)
+}
+
+func (bt *balanceTracker) changes(addresses []types.UnlockHash) ([]Event, error) {
+	if len(addresses) == 0 {
+		return nil, nil
+	}
+	if bt.mut != nil {
+		bt.mut.Lock()
+		defer bt.mut.Unlock()
+	}
+	stats, err := bt.db.GetNetworkStats()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get network stats: %v", err)
+	}
+	var events []Event
+	for _, address := range addresses {
+		wallet, err := bt.db.GetWallet(address)
+		if err != nil && err != ErrNotFound {
+			return nil, fmt.Errorf("failed to get wallet %s: %v", address.String(), err)
+		}
+		balance := wallet.Balance
+		balance.Locked.Horizons = nil
+		b, err := json.Marshal(balance)
+		if err != nil {
+			return nil, fmt.Errorf("failed to JSON-encode balance of %s: %v", address.String(), err)
+		}
+		if previous := bt.balances[address]; previous != nil && bytes.Equal(previous, b) {
+			continue
+		}
+		bt.balances[address] = b
+		events = append(events, Event{
+			Type: EventTypeBalanceChanged,
+			Data: BalanceEventData{
+				Address: address,
+				Height:  stats.BlockHeight,
+				Balance: balance,
+			},
+		})
+	}
+	return events, nil
+}
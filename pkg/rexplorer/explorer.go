@@ -0,0 +1,1077 @@
+// Package rexplorer implements the rexplorer indexer as a library,
+// such that it can be embedded into other daemons, rather than only being used through the rexplorer binary.
+//
+// The Explorer applies/reverts the consensus changes of a (tfchain) consensus set it subscribes to
+// into a Database, opened using one of the registered database drivers (see OpenDatabase),
+// or implemented and registered by the embedding code (see RegisterDatabaseDriver).
+// The Commands type implements the commands of the rexplorer binary, and can be reused as such.
+package rexplorer
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rivine/rivine/crypto"
+	"github.com/rivine/rivine/modules"
+	"github.com/rivine/rivine/types"
+)
+
+type (
+	// ExplorerState collects the (internal) state for the explorer.
+	ExplorerState struct {
+		CurrentChangeID modules.ConsensusChangeID `json:"currentchangeid"`
+		// StatsChecksum is the checksum of the network stats stored for the current change,
+		// used to validate the stored network stats at startup.
+		StatsChecksum crypto.Hash `json:"statschecksum"`
+	}
+	// NetworkStats collects the global statistics for the blockchain.
+	NetworkStats struct {
+		// Version is the version of the payload, see NetworkStatsVersion,
+		// not part of the stats checksum as it doesn't define any stat
+		Version uint64 `json:"version"`
+		// FromGenesis is true if the stats track all blocks, the dataset being explored from the genesis block onwards
+		// by a version of rexplorer tracking all stats (see subUntracked), not part of the stats checksum either.
+		// It has to be cleared by upgradeNetworkStats, should a new version introduce a stat not tracked for older blocks.
+		FromGenesis            bool              `json:"fromGenesis,omitempty"`
+		Timestamp              types.Timestamp   `json:"timestamp"`
+		BlockHeight            types.BlockHeight `json:"blockHeight"`
+		TransactionCount       uint64            `json:"txCount"`
+		ValueTransactionCount  uint64            `json:"valueTxCount"`
+		CointOutputCount       uint64            `json:"coinOutputCount"`
+		LockedCointOutputCount uint64            `json:"lockedCoinOutputCount"`
+		CointInputCount        uint64            `json:"coinInputCount"`
+		MinerPayoutCount       uint64            `json:"minerPayoutCount"`
+		TransactionFeeCount    uint64            `json:"txFeeCount"`
+		MinerPayouts           types.Currency    `json:"minerPayouts"`
+		TransactionFees        types.Currency    `json:"txFees"`
+		Coins                  types.Currency    `json:"coins"`
+		LockedCoins            types.Currency    `json:"lockedCoins"`
+		// the part of the locked coin outputs (and coins) which are miner payouts, locked until they reach maturity,
+		// as opposed to the coin outputs locked by their own condition
+		MaturityLockedCoinOutputCount uint64         `json:"maturityLockedCoinOutputCount"`
+		MaturityLockedCoins           types.Currency `json:"maturityLockedCoins"`
+		// the miner fees defined by all transactions, no matter if (and how) they are paid out to the block creators
+		MinerFeeCount uint64         `json:"minerFeeCount"`
+		MinerFees     types.Currency `json:"minerFees"`
+		// the part of the coin outputs (and coins) which are provably unspendable, and thus burned,
+		// such that the circulating supply equals the coins minus the burned coins
+		BurnedCoinOutputCount uint64         `json:"burnedCoinOutputCount"`
+		BurnedCoins           types.Currency `json:"burnedCoins"`
+		// the coin outputs created by transactions, counted per condition type
+		ConditionTypes ConditionTypeStats `json:"conditionTypes"`
+		// the transactions counted per transaction version
+		TransactionVersions TransactionVersionStats `json:"txVersions,omitempty"`
+		// the fees paid by the transactions defining miner fees, nil if none
+		FeeStats *FeeStats `json:"feeStats,omitempty"`
+		// the total value of the coin outputs created by transactions (miner payouts excluded),
+		// and the coin velocity, being that value divided by the circulating supply,
+		// the latter derived from the other stats and thus not part of the stats checksum
+		ValueTransferred types.Currency `json:"valueTransferred"`
+		Velocity         float64        `json:"velocity"`
+		// the part of the locked coins locked by time and by block height, and the coins still held
+		// by the unspent coin outputs of the genesis block, as well as the supply breakdown derived from the stats
+		TimeLockedCoins   types.Currency  `json:"timeLockedCoins"`
+		HeightLockedCoins types.Currency  `json:"heightLockedCoins"`
+		GenesisCoins      types.Currency  `json:"genesisCoins"`
+		Supply            SupplyBreakdown `json:"supply"`
+		// the difficulty of the block stake target the next block has to meet,
+		// zero for stats stored prior to tracking it, until the next block is applied
+		Difficulty types.Difficulty `json:"difficulty"`
+	}
+)
+
+// NewExplorerState creates a nil (fresh) explorer state.
+func NewExplorerState() ExplorerState {
+	return ExplorerState{
+		CurrentChangeID: modules.ConsensusChangeBeginning,
+	}
+}
+
+// NewNetworkStats creates a nil (fresh) network state.
+func NewNetworkStats() NetworkStats {
+	return NetworkStats{Version: NetworkStatsVersion, FromGenesis: true}
+}
+
+// Explorer defines the custom (internal) explorer module,
+// used to dump the data of a tfchain network in a meaningful way.
+type Explorer struct {
+	db     Database
+	state  ExplorerState
+	stats  NetworkStats
+	health healthTracker
+
+	walletGroups WalletGroups
+	// the IDs of the coin outputs allocated by the genesis block, computed when first used
+	genesisCoinOutputs map[types.CoinOutputID]struct{}
+	// the interval (in blocks) at which the balance of all wallets is snapshotted, 0 if disabled
+	snapshotInterval types.BlockHeight
+
+	hooks *Hooks
+	// traces the processing of consensus changes, nil if disabled
+	tracer *Tracer
+	// the external systems the events of every consensus change are delivered to
+	sinks []ChangeSink
+	// notifies the webhooks of the watched addresses, nil if not supported by the database
+	webhooks *webhookNotifier
+	// processes the consensus changes received while the consensus set isn't synced, nil if disabled
+	pipeline *syncPipeline
+	// the interval (in blocks and time) at which the state and stats are stored while the consensus set isn't synced,
+	// both 0 if they are stored for every consensus change
+	flushBlocks types.BlockHeight
+	flushPeriod time.Duration
+	// whether the state and stats changed since they were last stored,
+	// the amount of blocks applied since and the time at which they were last stored
+	unflushed       bool
+	unflushedBlocks types.BlockHeight
+	flushedAt       time.Time
+	// the events published while the consensus set is synced
+	feed *EventFeed
+	// whether or not the consensus set was synced as of the last processed change,
+	// used to detect the completion of a sync
+	synced bool
+
+	cs      modules.ConsensusSet
+	gateway modules.Gateway
+
+	bcInfo   types.BlockchainInfo
+	chainCts types.ChainConstants
+
+	// closed when the explorer is closed, stopping all background goroutines
+	closing    chan struct{}
+	background sync.WaitGroup
+
+	mut sync.Mutex
+}
+
+// NewExplorer creates a new custom intenral explorer module.
+// See Explorer for more information.
+//
+// Optionally hot and cold wallet groups can be given,
+// in which case the sweeps and refills between both groups are tracked as well.
+// The balance of all wallets is snapshotted every snapshotInterval blocks (see BalanceSnapshot),
+// if not 0 and supported by the database.
+// While the consensus set isn't synced (e.g. during the initial sync), consensus changes are decoded
+// by syncWorkers workers concurrently, while the decoded consensus changes are stored in order (see syncPipeline),
+// if syncWorkers isn't 0. Otherwise all consensus changes are decoded and stored one by one.
+// While the consensus set isn't synced, the explorer state and network stats are only stored once flushBlocks blocks
+// are applied or flushPeriod elapsed since they were last stored, if either is not 0, as well as when it is closed.
+// Otherwise they are stored for every consensus change. Deferring is only supported by a database which can restore
+// the data stored since after a crash (see CheckpointDatabase), an error being returned otherwise.
+// The explorer checkpoints its state every time it is stored, resuming from the last checkpoint when created, if supported by the database.
+// The given hooks (if not nil) are invoked for the lifecycle events of the explorer, and are not closed by it.
+// The processing of consensus changes is traced using the given tracer (if not nil), which isn't closed by it either.
+// The events of every consensus change are delivered to the given sinks, which are not closed by it either.
+func NewExplorer(db Database, cs modules.ConsensusSet, gateway modules.Gateway, bcInfo types.BlockchainInfo, chainCts types.ChainConstants, walletGroups WalletGroups, snapshotInterval types.BlockHeight, syncWorkers int, flushBlocks types.BlockHeight, flushPeriod time.Duration, hooks *Hooks, tracer *Tracer, sinks ...ChangeSink) (*Explorer, error) {
+	// resume from the last checkpoint, restoring the values changed since, if supported by the database
+	cdb, ok := checkpointDatabase(db)
+	if !ok && (flushBlocks > 0 || flushPeriod > 0) {
+		return nil, errors.New("deferring storing the explorer state requires a database which supports checkpoints")
+	}
+	if ok {
+		checkpoint, restored, err := cdb.RestoreCheckpoint()
+		switch {
+		case err == ErrNotFound:
+			// no checkpoint is stored yet
+		case err != nil:
+			return nil, fmt.Errorf("failed to restore checkpoint from db: %v", err)
+		case restored > 0:
+			log.Printf("restored %d values changed since the checkpoint at block height %d",
+				restored, checkpoint.Stats.BlockHeight)
+		}
+	}
+	state, err := db.GetExplorerState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get explorer state from db: %v", err)
+	}
+	stats, err := db.GetNetworkStats()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get network stats from db: %v", err)
+	}
+	explorer := &Explorer{
+		db:               db,
+		state:            state,
+		stats:            stats,
+		walletGroups:     walletGroups,
+		snapshotInterval: snapshotInterval,
+		flushBlocks:      flushBlocks,
+		flushPeriod:      flushPeriod,
+		flushedAt:        time.Now(),
+		hooks:            hooks,
+		tracer:           tracer,
+		sinks:            sinks,
+		feed:             NewEventFeed(),
+		cs:               cs,
+		gateway:          gateway,
+		bcInfo:           bcInfo,
+		chainCts:         chainCts,
+		closing:          make(chan struct{}),
+	}
+	// store the genesis allocation of datasets created prior to storing it, if supported by the database
+	err = ensureGenesisAllocation(db, state, chainCts)
+	if err != nil {
+		return nil, fmt.Errorf("explorer: failed to ensure genesis allocation is stored: %v", err)
+	}
+	// notify the webhooks registered for watched addresses, if supported by the database
+	if wdb, ok := db.(WebhookDatabase); ok {
+		explorer.webhooks = newWebhookNotifier(wdb, bcInfo)
+	}
+	// honor snapshot holds requested by external clients, if supported by the database
+	if sdb, ok := db.(SnapshotDatabase); ok {
+		explorer.background.Add(1)
+		go explorer.holdSnapshots(sdb)
+	}
+	// decode and store the consensus changes received while the consensus set isn't synced concurrently, if enabled
+	if syncWorkers > 0 {
+		explorer.pipeline = newSyncPipeline(explorer, syncWorkers)
+	}
+	err = cs.ConsensusSetSubscribe(explorer, state.CurrentChangeID)
+	if err != nil {
+		close(explorer.closing)
+		explorer.background.Wait()
+		explorer.webhooks.close()
+		return nil, fmt.Errorf("explorer: failed to subscribe to consensus set: %v", err)
+	}
+	// ensure the consensus changes received while subscribing are stored prior to returning
+	if explorer.pipeline != nil {
+		explorer.pipeline.flush()
+	}
+	return explorer, nil
+}
+
+// Close the Explorer module.
+func (explorer *Explorer) Close() error {
+	explorer.feed.Close()
+	close(explorer.closing)
+	explorer.background.Wait()
+	explorer.mut.Lock()
+	defer explorer.mut.Unlock()
+	explorer.cs.Unsubscribe(explorer)
+	explorer.webhooks.close()
+	// store the state and stats of the last consensus change processed, if deferred
+	err := explorer.flushDeferredState()
+	if err != nil {
+		explorer.db.Close()
+		return err
+	}
+	return explorer.db.Close()
+}
+
+// ProcessConsensusChange implements modules.ConsensusSetSubscriber,
+// used to apply/revert blocks to/from our Redis-stored data.
+//
+// While the consensus set isn't synced, consensus changes are handed over to the sync pipeline (if enabled),
+// returning as soon as they are queued. Once synced, all queued consensus changes are stored first,
+// after which the consensus change is stored before returning.
+func (explorer *Explorer) ProcessConsensusChange(css modules.ConsensusChange) {
+	if explorer.pipeline != nil {
+		if !css.Synced {
+			explorer.pipeline.submit(css)
+			return
+		}
+		if !explorer.pipeline.flush() {
+			// the explorer is closing, and queued consensus changes were dropped
+			return
+		}
+	}
+	explorer.mut.Lock()
+	defer explorer.mut.Unlock()
+	explorer.processConsensusChange(css, nil)
+	if explorer.pipeline != nil {
+		explorer.pipeline.reset(explorer.stats.BlockHeight)
+	}
+}
+
+// processConsensusChange applies/reverts the blocks of the given consensus change, using the given coin output changes
+// of the applied blocks if decoded already (see syncPipeline), and deriving them otherwise.
+// The explorer has to be locked by the caller.
+func (explorer *Explorer) processConsensusChange(css modules.ConsensusChange, decoded []BlockChanges) {
+	// trace the processing of this consensus change, if enabled and sampled
+	trace := explorer.tracer.startTrace("ProcessConsensusChange",
+		otlpAttribute("rexplorer.consensus_change.id", hex.EncodeToString(css.ID[:])),
+		otlpAttribute("rexplorer.consensus_change.reverted_blocks", len(css.RevertedBlocks)),
+		otlpAttribute("rexplorer.consensus_change.applied_blocks", len(css.AppliedBlocks)),
+		otlpAttribute("rexplorer.synced", css.Synced))
+	defer trace.finishDeferred()
+
+	var err error
+
+	// apply all changes of this consensus change atomically, if supported by the database
+	tdb, transactional := explorer.db.(TransactionalDatabase)
+	if transactional {
+		span := trace.child("Database.Begin")
+		err = tdb.Begin()
+		span.finish(err)
+		if err != nil {
+			panic("failed to begin db transaction: " + err.Error())
+		}
+	}
+
+	// the events published once all changes are stored, only while the consensus set is synced
+	var events []Event
+	// the events of every block applied and reverted, published by the database once all changes are stored,
+	// if it publishes events, together with the balance of the addresses involved in these blocks
+	publisher, publishing := explorer.db.(EventPublisherDatabase)
+	publishing = publishing && publisher.PublishesEvents()
+	var published []Event
+	involved := make(map[types.UnlockHash]struct{})
+	// the events of every block applied and reverted, delivered to the sinks prior to storing the new state
+	sinking := len(explorer.sinks) > 0
+	var delivered []Event
+	deliver := func(events ...Event) {
+		if sinking {
+			delivered = append(delivered, events...)
+		}
+	}
+	emit := func(synced bool, event Event) {
+		if synced {
+			events = append(events, event)
+		}
+		if publishing {
+			published = append(published, event)
+		}
+		deliver(event)
+	}
+
+	// update reverted blocks
+	for _, block := range css.RevertedBlocks {
+		blockSpan := trace.child("RevertBlock")
+		blockSpan.setBlockAttributes(block, explorer.stats.BlockHeight)
+		// revert balance snapshot, rolling and daily stats and address activity
+		explorer.revertBalanceSnapshot()
+		explorer.revertRollingStats()
+		explorer.revertDailyStats()
+		explorer.revertAddressActivity()
+		// revert block summary
+		err = explorer.db.RevertBlockSummary(explorer.stats.BlockHeight)
+		if err != nil {
+			panic(fmt.Sprintf("failed to revert summary of block %d: %v", explorer.stats.BlockHeight, err))
+		}
+		// revert block record, the provenance and history of its miner payouts, and the stats of its creator
+		explorer.revertBlockRecord(block)
+		explorer.revertMinerPayoutProvenance(block)
+		explorer.revertMinerPayoutHistory(block)
+		explorer.revertBlockCreator(block)
+		// the coins received and sent by the addresses involved in this block
+		totals := make(addressTotalsDelta)
+		// revert the coin outputs created and spent by this block, all at once,
+		// the results being consumed in the order of the changes
+		span := blockSpan.child("Database.RevertBlock")
+		results, err := applyBlockChanges(explorer.db, explorer.blockChanges(block), true)
+		span.finish(err)
+		if err != nil {
+			panic(fmt.Sprintf("failed to revert coin outputs of block %d: %v", explorer.stats.BlockHeight, err))
+		}
+		// revert miner payouts
+		for i, mp := range block.MinerPayouts {
+			explorer.stats.CointOutputCount--
+			totals.receive(mp.UnlockHash, mp.Value)
+			if i == 0 {
+				// only the first miner payout is newly created money
+				explorer.stats.MinerPayoutCount--
+				explorer.stats.Coins = explorer.stats.Coins.Sub(mp.Value)
+				explorer.stats.MinerPayouts = explorer.stats.MinerPayouts.Sub(mp.Value)
+			} else {
+				explorer.stats.TransactionFeeCount--
+				explorer.stats.TransactionFees = explorer.stats.TransactionFees.Sub(mp.Value)
+			}
+			state := results[0].State
+			results = results[1:]
+			explorer.stats.revertBurnedCoins(types.NewCondition(types.NewUnlockHashCondition(mp.UnlockHash)), mp.Value)
+			if state == CoinOutputStateLocked {
+				explorer.stats.LockedCointOutputCount--
+				explorer.stats.LockedCoins = explorer.stats.LockedCoins.Sub(mp.Value)
+				explorer.stats.unlockCoins(LockTypeHeight, mp.Value)
+				explorer.stats.unlockMaturity(1, mp.Value)
+			}
+		}
+		// revert txs
+		for _, tx := range block.Transactions {
+			deliver(newTransactionEvent(EventTypeTransactionReverted, tx, block.ID(), explorer.stats.BlockHeight))
+			explorer.stats.TransactionCount--
+			explorer.stats.revertTransactionVersion(tx)
+			explorer.stats.revertValueTransferred(tx)
+			// revert miner fees
+			explorer.stats.revertMinerFees(tx)
+			// revert the coins created by a coin creation transaction, and the mint condition defined by a minter definition transaction
+			explorer.stats.Coins = explorer.stats.Coins.Sub(createdCoins(tx))
+			explorer.revertMintCondition(tx)
+			// revert the 3Bot records registered or updated by a 3Bot transaction
+			explorer.revertBotRecords(tx)
+			// revert the coins converted into ERC20 tokens, and the index of the ERC20 bridge transactions
+			explorer.revertERC20Transaction(tx)
+			// revert tx record and arbitrary data index
+			explorer.revertTransactionRecord(tx)
+			explorer.revertArbitraryData(tx)
+			if len(tx.CoinInputs) > 0 || len(tx.BlockStakeOutputs) > 1 {
+				explorer.stats.ValueTransactionCount--
+			}
+			// revert coin inputs
+			senders := make(map[types.UnlockHash]struct{}, len(tx.CoinInputs))
+			for _, ci := range tx.CoinInputs {
+				explorer.stats.CointInputCount--
+				owner, value := results[0].Owner, results[0].Value
+				results = results[1:]
+				if explorer.isGenesisCoinOutput(ci.ParentID) {
+					explorer.stats.GenesisCoins = explorer.stats.GenesisCoins.Add(value)
+				}
+				senders[owner] = struct{}{}
+				totals.send(owner, value)
+				// revert multisig spend authorization
+				if signers := getMultisigSigners(ci.Fulfillment); len(signers) > 0 {
+					err := explorer.db.RevertMultisigSpend(MultisigSpend{
+						Address:      owner,
+						CoinOutputID: ci.ParentID,
+						Value:        value,
+						Signers:      signers,
+					})
+					if err != nil {
+						panic(fmt.Sprintf("failed to revert multisig spend of coin output %s: %v", ci.ParentID.String(), err))
+					}
+				}
+			}
+			// revert counterparty transfers
+			for _, transfer := range getCounterpartyTransfers(senders, tx.CoinOutputs) {
+				err := explorer.db.RevertCounterpartyTransfer(transfer.From, transfer.To, transfer.Value)
+				if err != nil {
+					panic(fmt.Sprintf("failed to revert counterparty transfer from %s to %s: %v",
+						transfer.From.String(), transfer.To.String(), err))
+				}
+			}
+			// revert hot/cold wallet flows
+			if flows := getWalletGroupFlows(explorer.walletGroups, senders, tx.CoinOutputs); !flows.IsZero() {
+				err := explorer.db.RevertWalletGroupFlows(block.Timestamp, flows)
+				if err != nil {
+					panic(fmt.Sprintf("failed to revert wallet group flows of tx %s: %v", tx.ID().String(), err))
+				}
+			}
+			// revert the history of the senders and receivers
+			explorer.revertAddressHistory(tx, senders)
+			// revert block stake inputs and outputs
+			explorer.revertBlockStakes(tx)
+			// revert atomic swap contracts and coin output provenance
+			explorer.revertAtomicSwapContracts(tx)
+			explorer.revertCoinOutputProvenance(tx)
+			// revert coin outputs
+			for _, co := range tx.CoinOutputs {
+				explorer.stats.CointOutputCount--
+				totals.receive(co.Condition.UnlockHash(), co.Value)
+				state := results[0].State
+				results = results[1:]
+				explorer.stats.revertBurnedCoins(co.Condition, co.Value)
+				explorer.stats.revertConditionType(co.Condition)
+				if state == CoinOutputStateLocked {
+					explorer.stats.LockedCointOutputCount--
+					explorer.stats.LockedCoins = explorer.stats.LockedCoins.Sub(co.Value)
+					explorer.stats.unlockCoins(conditionLockType(co.Condition), co.Value)
+				}
+				if block.ParentID == (types.BlockID{}) {
+					explorer.stats.GenesisCoins = explorer.stats.subUntracked("genesis coins", explorer.stats.GenesisCoins, co.Value)
+				}
+			}
+		}
+		// revert the totals of the addresses involved, and the top addresses of the day
+		explorer.revertAddressTotals(totals)
+		explorer.revertDailyTopAddresses(block, totals)
+
+		revertedHeight := explorer.stats.BlockHeight
+		emit(css.Synced, newBlockEvent(EventTypeBlockReverted, block, revertedHeight))
+		deliver(newBalanceDeltaEvents(totals, revertedHeight, true)...)
+		for address := range totals {
+			involved[address] = struct{}{}
+		}
+		if block.ParentID != (types.BlockID{}) {
+			explorer.stats.BlockHeight--
+		} else {
+			explorer.revertGenesisAllocation()
+		}
+		explorer.health.RevertBlock()
+		explorer.stats.Timestamp = explorer.parentTimestamp(block)
+
+		// returns the total amount of coins that have been locked by block height
+		n, _, err := explorer.revertCoinLocks(revertedHeight)
+		if err != nil {
+			panic(fmt.Sprintf("failed to lock coin outputs at height=%d and time=%d: %v",
+				explorer.stats.BlockHeight, explorer.stats.Timestamp, err))
+		}
+		if n > 0 {
+			explorer.stats.lockMaturity(explorer.maturedMinerPayouts(explorer.stats.BlockHeight))
+		}
+		explorer.revertBlockStakeOutputLocks()
+		// restore the difficulty as of the parent block, being the difficulty this block had to meet,
+		// prior to restoring the network stats snapshot of the day of this block
+		if block.ParentID != (types.BlockID{}) {
+			explorer.stats.Difficulty = explorer.blockDifficulty(block)
+		} else {
+			explorer.stats.Difficulty = types.Difficulty{}
+		}
+		explorer.revertNetworkStatsSnapshot(block, revertedHeight)
+		blockSpan.finish(nil)
+	}
+
+	if n := len(css.RevertedBlocks); n > 0 {
+		explorer.health.RegisterReorg(explorer.stats.BlockHeight, uint64(n))
+	}
+
+	// update applied blocks
+	var appliedBlocks []BlockAppliedHookData
+	for blockIndex, block := range css.AppliedBlocks {
+		isGenesisBlock := block.ParentID == (types.BlockID{})
+		if !isGenesisBlock {
+			explorer.stats.BlockHeight++
+		}
+		blockSpan := trace.child("ApplyBlock")
+		blockSpan.setBlockAttributes(block, explorer.stats.BlockHeight)
+		if css.Synced {
+			appliedBlocks = append(appliedBlocks, BlockAppliedHookData{
+				Height:           explorer.stats.BlockHeight,
+				ID:               block.ID(),
+				Timestamp:        block.Timestamp,
+				TransactionCount: len(block.Transactions),
+			})
+		}
+		emit(css.Synced, newBlockEvent(EventTypeBlockApplied, block, explorer.stats.BlockHeight))
+		previousTime := explorer.stats.Timestamp
+		explorer.stats.Timestamp = block.Timestamp
+		explorer.health.ApplyBlock(block)
+		// returns the total amount of coins that have been unlocked by block height
+		n, _, err := explorer.applyCoinLocks(previousTime)
+		if err != nil {
+			panic(fmt.Sprintf("failed to unlock coin outputs at height=%d and time=%d: %v",
+				explorer.stats.BlockHeight, explorer.stats.Timestamp, err))
+		}
+		if n > 0 {
+			explorer.stats.unlockMaturity(explorer.maturedMinerPayouts(explorer.stats.BlockHeight))
+		}
+		explorer.applyBlockStakeOutputLocks()
+
+		// apply block summary
+		err = explorer.db.SetBlockSummary(newBlockSummary(
+			block, explorer.stats.BlockHeight, explorer.blockDifficulty(block), explorer.chainCts.CurrencyUnits.OneCoin))
+		if err != nil {
+			panic(fmt.Sprintf("failed to set summary of block %d: %v", explorer.stats.BlockHeight, err))
+		}
+		// apply block record, the provenance and history of its miner payouts, and the stats of its creator
+		explorer.storeBlockRecord(block)
+		explorer.applyMinerPayoutProvenance(block)
+		explorer.applyMinerPayoutHistory(block)
+		explorer.applyBlockCreator(block)
+		if isGenesisBlock {
+			explorer.storeGenesisAllocation(block)
+		}
+
+		// the addresses involved in this block, and the coins they received and sent
+		active := make(map[types.UnlockHash]struct{})
+		totals := make(addressTotalsDelta)
+
+		// apply the coin outputs created and spent by this block, all at once,
+		// the results being consumed in the order of the changes
+		var changes BlockChanges
+		if blockIndex < len(decoded) && decoded[blockIndex].Height == explorer.stats.BlockHeight {
+			changes = decoded[blockIndex]
+		} else {
+			changes = explorer.blockChanges(block)
+		}
+		span := blockSpan.child("Database.ApplyBlock")
+		results, err := applyBlockChanges(explorer.db, changes, false)
+		span.finish(err)
+		if err != nil {
+			panic(fmt.Sprintf("failed to apply coin outputs of block %d: %v", explorer.stats.BlockHeight, err))
+		}
+		next := func() (CoinOutputChange, CoinOutputChangeResult) {
+			change, result := changes.Changes[0], results[0]
+			changes.Changes, results = changes.Changes[1:], results[1:]
+			return change, result
+		}
+
+		// apply miner payouts
+		for i, mp := range block.MinerPayouts {
+			explorer.stats.CointOutputCount++
+			active[mp.UnlockHash] = struct{}{}
+			totals.receive(mp.UnlockHash, mp.Value)
+			if i == 0 {
+				// only the first miner payout is newly created money
+				explorer.stats.MinerPayoutCount++
+				explorer.stats.Coins = explorer.stats.Coins.Add(mp.Value)
+				explorer.stats.MinerPayouts = explorer.stats.MinerPayouts.Add(mp.Value)
+			} else {
+				explorer.stats.TransactionFeeCount++
+				explorer.stats.TransactionFees = explorer.stats.TransactionFees.Add(mp.Value)
+			}
+			change, result := next()
+			co := types.CoinOutput{Value: mp.Value, Condition: change.Output.Condition}
+			id, locked := change.ID, result.State == CoinOutputStateLocked
+			emit(css.Synced, newOutputCreatedEvent(id, co, nil, explorer.stats.BlockHeight, locked))
+			explorer.stats.applyBurnedCoins(co.Condition, co.Value)
+			if locked {
+				explorer.stats.LockedCointOutputCount++
+				explorer.stats.LockedCoins = explorer.stats.LockedCoins.Add(mp.Value)
+				explorer.stats.lockCoins(LockTypeHeight, mp.Value)
+				explorer.stats.lockMaturity(1, mp.Value)
+			}
+		}
+		// apply txs
+		for _, tx := range block.Transactions {
+			explorer.stats.TransactionCount++
+			explorer.stats.applyTransactionVersion(tx)
+			explorer.stats.applyValueTransferred(tx)
+			// apply miner fees
+			explorer.stats.applyMinerFees(tx)
+			// apply the coins created by a coin creation transaction, and the mint condition defined by a minter definition transaction
+			explorer.stats.Coins = explorer.stats.Coins.Add(createdCoins(tx))
+			explorer.applyMintCondition(tx)
+			// apply the 3Bot records registered or updated by a 3Bot transaction
+			explorer.applyBotRecords(tx)
+			// apply the coins converted into ERC20 tokens, and the index of the ERC20 bridge transactions
+			explorer.applyERC20Transaction(tx)
+			if len(tx.CoinInputs) > 0 || len(tx.BlockStakeOutputs) > 1 {
+				explorer.stats.ValueTransactionCount++
+			}
+			// apply coin inputs
+			senders := make(map[types.UnlockHash]struct{}, len(tx.CoinInputs))
+			inputs := make([]TransactionRecordCoinInput, 0, len(tx.CoinInputs))
+			for _, ci := range tx.CoinInputs {
+				explorer.stats.CointInputCount++
+				_, result := next()
+				owner, value := result.Owner, result.Value
+				if explorer.isGenesisCoinOutput(ci.ParentID) {
+					explorer.stats.GenesisCoins = explorer.stats.subUntracked("genesis coins", explorer.stats.GenesisCoins, value)
+				}
+				senders[owner] = struct{}{}
+				active[owner] = struct{}{}
+				totals.send(owner, value)
+				emit(css.Synced, Event{
+					Type: EventTypeOutputSpent,
+					Data: OutputSpentEventData{
+						ID:            ci.ParentID,
+						Address:       owner,
+						Value:         value,
+						TransactionID: tx.ID(),
+						Height:        explorer.stats.BlockHeight,
+					},
+				})
+				inputs = append(inputs, TransactionRecordCoinInput{
+					ParentID:    ci.ParentID,
+					Fulfillment: ci.Fulfillment,
+					UnlockHash:  owner,
+					Value:       value,
+				})
+				// apply multisig spend authorization
+				if signers := getMultisigSigners(ci.Fulfillment); len(signers) > 0 {
+					err := explorer.db.ApplyMultisigSpend(MultisigSpend{
+						Address:      owner,
+						CoinOutputID: ci.ParentID,
+						Value:        value,
+						Signers:      signers,
+					})
+					if err != nil {
+						panic(fmt.Sprintf("failed to apply multisig spend of coin output %s: %v", ci.ParentID.String(), err))
+					}
+				}
+			}
+			// apply counterparty transfers
+			for _, transfer := range getCounterpartyTransfers(senders, tx.CoinOutputs) {
+				err := explorer.db.ApplyCounterpartyTransfer(transfer.From, transfer.To, transfer.Value)
+				if err != nil {
+					panic(fmt.Sprintf("failed to apply counterparty transfer from %s to %s: %v",
+						transfer.From.String(), transfer.To.String(), err))
+				}
+			}
+			// apply hot/cold wallet flows
+			if flows := getWalletGroupFlows(explorer.walletGroups, senders, tx.CoinOutputs); !flows.IsZero() {
+				err := explorer.db.ApplyWalletGroupFlows(block.Timestamp, flows)
+				if err != nil {
+					panic(fmt.Sprintf("failed to apply wallet group flows of tx %s: %v", tx.ID().String(), err))
+				}
+			}
+			// apply the history of the senders and receivers
+			explorer.applyAddressHistory(tx, inputs)
+			// apply coin outputs
+			for _, co := range tx.CoinOutputs {
+				explorer.stats.CointOutputCount++
+				active[co.Condition.UnlockHash()] = struct{}{}
+				totals.receive(co.Condition.UnlockHash(), co.Value)
+				change, result := next()
+				id, locked := change.ID, result.State == CoinOutputStateLocked
+				explorer.setMultisigAddresses(co.Condition)
+				txID := tx.ID()
+				emit(css.Synced, newOutputCreatedEvent(id, co, &txID, explorer.stats.BlockHeight, locked))
+				explorer.stats.applyBurnedCoins(co.Condition, co.Value)
+				explorer.stats.applyConditionType(co.Condition)
+				// only count coins of outputs for genesis block,
+				// as it is the only place coins can be created besides miner payouts and coin creation transactions
+				if isGenesisBlock {
+					explorer.stats.Coins = explorer.stats.Coins.Add(co.Value)
+					explorer.stats.GenesisCoins = explorer.stats.GenesisCoins.Add(co.Value)
+				}
+				// if it is locked, we'll always add it to the locked output
+				if locked {
+					explorer.stats.LockedCointOutputCount++
+					explorer.stats.LockedCoins = explorer.stats.LockedCoins.Add(co.Value)
+					explorer.stats.lockCoins(conditionLockType(co.Condition), co.Value)
+				}
+			}
+			// apply block stake inputs and outputs
+			explorer.applyBlockStakes(tx)
+			// apply atomic swap contracts and coin output provenance
+			explorer.applyAtomicSwapContracts(tx)
+			explorer.applyCoinOutputProvenance(tx, block.ID())
+			// apply tx record and arbitrary data index
+			explorer.storeTransactionRecord(tx, block.ID(), inputs)
+			explorer.applyArbitraryData(tx)
+			deliver(newTransactionEvent(EventTypeTransactionApplied, tx, block.ID(), explorer.stats.BlockHeight))
+		}
+		// apply daily and rolling stats, address activity, totals and the top addresses of the day
+		daily := explorer.newBlockDailyStats(block, active)
+		explorer.applyAddressActivity(active)
+		for address := range active {
+			involved[address] = struct{}{}
+		}
+		deliver(newBalanceDeltaEvents(totals, explorer.stats.BlockHeight, false)...)
+		explorer.applyDailyStats(daily)
+		explorer.applyRollingStats(block.Timestamp, daily)
+		explorer.applyAddressTotals(totals)
+		explorer.applyDailyTopAddresses(block, previousTime, totals)
+		// snapshot the network stats as of this block for its day,
+		// the difficulty being the difficulty the children of this block have to meet
+		explorer.stats.Difficulty = explorer.childDifficulty(block)
+		explorer.applyNetworkStatsSnapshot(block)
+		blockSpan.finish(nil)
+	}
+
+	// update state, the difficulty being the difficulty the next block has to meet
+	explorer.stats.Difficulty = css.ChildTarget.Difficulty(explorer.chainCts.RootDepth)
+	explorer.stats.updateVelocity()
+	explorer.stats.updateSupply()
+	explorer.state.CurrentChangeID = css.ID
+	explorer.state.StatsChecksum = networkStatsChecksum(explorer.stats)
+	trace.setAttributes(otlpAttribute("rexplorer.block_height", explorer.stats.BlockHeight))
+
+	// deliver the events to the sinks prior to storing the latest state,
+	// such that this consensus change is processed again should the delivery fail
+	if sinking {
+		span := trace.child("DeliverChange", otlpAttribute("rexplorer.events", len(delivered)))
+		explorer.deliverChange(css.ID, delivered)
+		span.finish(nil)
+	}
+
+	// recompute and store the chain health
+	span := trace.child("Database.SetChainHealth")
+	err = explorer.db.SetChainHealth(explorer.health.ChainHealth(
+		explorer.stats, explorer.chainCts.BlockFrequency, peerCount(explorer.gateway)))
+	span.finish(err)
+	if err != nil {
+		panic("failed to store chain health in db: " + err.Error())
+	}
+
+	// store latest state and stats (and checkpoint them), unless deferred while the consensus set isn't synced
+	explorer.unflushed = true
+	explorer.unflushedBlocks += types.BlockHeight(len(css.AppliedBlocks))
+	if explorer.shouldFlushState(css.Synced) {
+		err = explorer.flushState(trace)
+		if err != nil {
+			panic(err.Error())
+		}
+	}
+
+	if transactional {
+		span = trace.child("Database.Commit")
+		err = tdb.Commit()
+		span.finish(err)
+		if err != nil {
+			panic("failed to commit db transaction: " + err.Error())
+		}
+	}
+
+	// snapshot the balance of all wallets, now that all blocks are applied and committed
+	if len(css.AppliedBlocks) > 0 {
+		span = trace.child("StoreBalanceSnapshot")
+		explorer.storeBalanceSnapshot()
+		span.finish(nil)
+	}
+
+	// invoke the hooks, now that all changes are stored,
+	// blocks applied during the initial sync are not reported individually
+	for _, data := range appliedBlocks {
+		explorer.hooks.Fire(HookEventBlockApplied, data)
+	}
+	if css.Synced && !explorer.synced {
+		explorer.hooks.Fire(HookEventSyncCompleted, SyncCompletedHookData{
+			Height:    explorer.stats.BlockHeight,
+			Timestamp: explorer.stats.Timestamp,
+		})
+	}
+	explorer.synced = css.Synced
+
+	// publish the events, now that all changes are stored
+	explorer.feed.Publish(events...)
+	if publishing {
+		span = trace.child("PublishEvents", otlpAttribute("rexplorer.events", len(published)))
+		explorer.publishEvents(publisher, published, involved)
+		span.finish(nil)
+	}
+	// notify the webhooks of the watched addresses, only while the consensus set is synced
+	if explorer.webhooks != nil && css.Synced {
+		span = trace.child("NotifyWebhooks")
+		err = explorer.webhooks.notify(explorer.stats.BlockHeight, involved)
+		span.finish(err)
+		if err != nil {
+			log.Println("[ERROR] failed to notify webhooks:", err)
+		}
+	}
+}
+
+// shouldFlushState returns true if the state and stats have to be stored for the consensus change processed,
+// which is the case for every consensus change unless a flush interval is configured and the consensus set isn't synced.
+func (explorer *Explorer) shouldFlushState(synced bool) bool {
+	if synced || (explorer.flushBlocks == 0 && explorer.flushPeriod == 0) {
+		return true
+	}
+	if explorer.flushBlocks > 0 && explorer.unflushedBlocks >= explorer.flushBlocks {
+		return true
+	}
+	return explorer.flushPeriod > 0 && time.Since(explorer.flushedAt) >= explorer.flushPeriod
+}
+
+// flushState stores the current state and stats, and checkpoints them if supported by the database,
+// which has to be the last change of a consensus change (see CheckpointDatabase).
+func (explorer *Explorer) flushState(trace *traceSpan) error {
+	span := trace.child("Database.SetExplorerState")
+	err := explorer.db.SetExplorerState(explorer.state)
+	span.finish(err)
+	if err != nil {
+		return fmt.Errorf("failed to store explorer state in db: %v", err)
+	}
+	span = trace.child("Database.SetNetworkStats")
+	err = explorer.db.SetNetworkStats(explorer.stats)
+	span.finish(err)
+	if err != nil {
+		return fmt.Errorf("failed to store network stats in db: %v", err)
+	}
+	if cdb, ok := checkpointDatabase(explorer.db); ok {
+		span = trace.child("Database.SetCheckpoint")
+		err = cdb.SetCheckpoint(Checkpoint{State: explorer.state, Stats: explorer.stats})
+		span.finish(err)
+		if err != nil {
+			return fmt.Errorf("failed to store checkpoint in db: %v", err)
+		}
+	}
+	explorer.unflushed, explorer.unflushedBlocks, explorer.flushedAt = false, 0, time.Now()
+	return nil
+}
+
+// flushDeferredState stores the current state and stats if they weren't stored yet,
+// atomically if supported by the database.
+func (explorer *Explorer) flushDeferredState() error {
+	if !explorer.unflushed {
+		return nil
+	}
+	tdb, transactional := explorer.db.(TransactionalDatabase)
+	if transactional {
+		err := tdb.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin db transaction: %v", err)
+		}
+	}
+	err := explorer.flushState(nil)
+	if err != nil {
+		return err
+	}
+	if transactional {
+		err = tdb.Commit()
+		if err != nil {
+			return fmt.Errorf("failed to commit db transaction: %v", err)
+		}
+	}
+	return nil
+}
+
+// publishEvents publishes the given events using the given database,
+// followed by the (current) balance of the given addresses. As the changes are stored already,
+// failing to publish the events is logged rather than considered fatal.
+func (explorer *Explorer) publishEvents(publisher EventPublisherDatabase, events []Event, addresses map[types.UnlockHash]struct{}) {
+	for _, address := range sortedAddresses(addresses) {
+		wallet, err := explorer.db.GetWallet(address)
+		if err != nil && err != ErrNotFound {
+			log.Printf("[ERROR] failed to get wallet %s to publish its balance: %v", address.String(), err)
+			return
+		}
+		// the unlock horizons change over time without any coins moving, hence they're not published
+		balance := wallet.Balance
+		balance.Locked.Horizons = nil
+		events = append(events, Event{
+			Type: EventTypeBalanceChanged,
+			Data: BalanceEventData{
+				Address: address,
+				Height:  explorer.stats.BlockHeight,
+				Balance: balance,
+			},
+		})
+	}
+	err := publisher.PublishEvents(events)
+	if err != nil {
+		log.Println("[ERROR] failed to publish events:", err)
+	}
+}
+
+// The prefixes of the descriptions synthesized for miner payouts.
+const (
+	blockRewardDescriptionPrefix = "block reward for "
+	txFeeDescriptionPrefix       = "tx fee for tx"
+)
+
+// minerPayoutDescription synthesizes the description of a miner payout,
+// as miner payouts have no arbitrary data to take their description from.
+func minerPayoutDescription(prefix, id string) types.ByteSlice {
+	return types.ByteSlice(prefix + id)
+}
+
+// isMinerPayoutDescription returns true if the given description was synthesized for a miner payout.
+func isMinerPayoutDescription(description []byte) bool {
+	return bytes.HasPrefix(description, []byte(blockRewardDescriptionPrefix)) ||
+		bytes.HasPrefix(description, []byte(txFeeDescriptionPrefix))
+}
+
+func getTransactionIDForMinerPayout(block types.Block, index uint64) types.TransactionID {
+	var i uint64
+	for _, tx := range block.Transactions {
+		n := uint64(len(tx.MinerFees))
+		if i+n <= index {
+			i += n
+			continue
+		}
+		return tx.ID()
+	}
+	panic(fmt.Sprintf("couldn't find tx id for miner payout in block %s at index %d", block.ID().String(), index))
+}
+
+// counterpartyTransfer defines the coins received by one address,
+// in a transaction funded (partly) by another address.
+type counterpartyTransfer struct {
+	From, To types.UnlockHash
+	Value    types.Currency
+}
+
+// getCounterpartyTransfers returns the transfers between all sender and receiver addresses of a transaction,
+// ignoring change outputs (outputs sent back to one of the senders).
+// In case a transaction has multiple senders, the full value received is attributed to each of the senders.
+// The transfers are returned in a deterministic order, such that apply and revert are symmetric.
+func getCounterpartyTransfers(senders map[types.UnlockHash]struct{}, outputs []types.CoinOutput) (transfers []counterpartyTransfer) {
+	if len(senders) == 0 {
+		return nil
+	}
+	var receivers []types.UnlockHash
+	received := make(map[types.UnlockHash]types.Currency, len(outputs))
+	for _, co := range outputs {
+		uh := co.Condition.UnlockHash()
+		if _, ok := senders[uh]; ok {
+			continue // change output
+		}
+		value, ok := received[uh]
+		if !ok {
+			receivers = append(receivers, uh)
+		}
+		received[uh] = value.Add(co.Value)
+	}
+	orderedSenders := make([]types.UnlockHash, 0, len(senders))
+	for uh := range senders {
+		orderedSenders = append(orderedSenders, uh)
+	}
+	sort.Slice(orderedSenders, func(i, j int) bool {
+		return orderedSenders[i].Cmp(orderedSenders[j]) < 0
+	})
+	for _, from := range orderedSenders {
+		for _, to := range receivers {
+			transfers = append(transfers, counterpartyTransfer{
+				From:  from,
+				To:    to,
+				Value: received[to],
+			})
+		}
+	}
+	return transfers
+}
+
+// setMultisigAddresses tracks the multisig address of an output protected by the given condition, if any,
+// linking it to the owner addresses as well as storing the owner addresses themself for the multisig wallet.
+func (explorer *Explorer) setMultisigAddresses(condition types.UnlockConditionProxy) {
+	ownerAddresses, signaturesRequired := getMultisigProperties(condition)
+	if len(ownerAddresses) == 0 {
+		return
+	}
+	multiSigAddress := condition.UnlockHash()
+	err := explorer.db.SetMultisigAddresses(multiSigAddress, ownerAddresses, signaturesRequired)
+	if err != nil {
+		panic(fmt.Sprintf("failed to set multisig addresses for multisig wallet %q: %v",
+			multiSigAddress.String(), err))
+	}
+}
+
+// outputLock returns the lock of an output protected by the given condition,
+// in case it cannot be fulfilled as of the current block height and time.
+func (explorer *Explorer) outputLock(condition types.UnlockConditionProxy) (lt LockType, lockValue LockValue, locked bool) {
+	return outputLockAt(condition, explorer.stats.BlockHeight, explorer.stats.Timestamp)
+}
+
+// outputLockAt returns the lock of an output protected by the given condition,
+// in case it cannot be fulfilled as of the given block height and time.
+func outputLockAt(condition types.UnlockConditionProxy, height types.BlockHeight, timestamp types.Timestamp) (lt LockType, lockValue LockValue, locked bool) {
+	isFulfillable := condition.Fulfillable(types.FulfillableContext{
+		BlockHeight: height,
+		BlockTime:   timestamp,
+	})
+	if isFulfillable {
+		return LockTypeNone, 0, false
+	}
+	// only a TimeLockedCondition can be locked for now, other conditions which cannot be fulfilled
+	// (e.g. of a type introduced by a chain upgrade) cannot be scheduled to unlock, and are stored as unlocked,
+	// flagged by their condition type (see CoinOutputInfo.UnknownCondition)
+	tlc, ok := condition.Condition.(*types.TimeLockCondition)
+	if !ok {
+		return LockTypeNone, 0, false
+	}
+	return conditionLockType(condition), LockValue(tlc.LockTime), true
+}
+
+// getMultisigOwnerAddresses gets the owner addresses (= internal addresses of a multisig condition)
+// from either a MultiSignatureCondition or a MultiSignatureCondition used as the internal condition of a TimeLockCondition.
+func getMultisigProperties(condition types.UnlockConditionProxy) (owners []types.UnlockHash, signaturesRequired uint64) {
+	ct := condition.ConditionType()
+	if ct == types.ConditionTypeTimeLock {
+		cg, ok := condition.Condition.(types.MarshalableUnlockConditionGetter)
+		if !ok {
+			panic(fmt.Sprintf("unexpected Go-type for TimeLockCondition: %T", condition))
+		}
+		return getMultisigProperties(types.NewCondition(cg.GetMarshalableUnlockCondition()))
+	}
+
+	type multisigCondition interface {
+		types.UnlockHashSliceGetter
+		GetMinimumSignatureCount() uint64
+	}
+	switch c := condition.Condition.(type) {
+	case multisigCondition:
+		return dedupOwnerAddresses(c.UnlockHashSlice()), c.GetMinimumSignatureCount()
+	default:
+		return nil, 0
+	}
+}
+func dedupOwnerAddresses(addresses []types.UnlockHash) (deduped []types.UnlockHash) {
+	n := len(addresses)
+	if n == 0 {
+		return
+	}
+	encountered := make(map[types.UnlockHash]struct{}, n)
+	for _, addr := range addresses {
+		encountered[addr] = struct{}{}
+	}
+	for addr := range encountered {
+		deduped = append(deduped, addr)
+	}
+	return
+}
@@ -0,0 +1,200 @@
+package rexplorer
+
+import (
+	"testing"
+
+	"github.com/rivine/rivine/modules"
+	"github.com/rivine/rivine/types"
+	tfcfg "github.com/threefoldfoundation/tfchain/pkg/config"
+)
+
+// stubConsensusSet is a consensus set which only knows the blocks it is given,
+// all of them having to meet the root target.
+type stubConsensusSet struct {
+	modules.ConsensusSet
+
+	chainCts types.ChainConstants
+	blocks   map[types.BlockID]types.Block
+}
+
+func (cs *stubConsensusSet) ConsensusSetSubscribe(modules.ConsensusSetSubscriber, modules.ConsensusChangeID) error {
+	return nil
+}
+
+func (cs *stubConsensusSet) Unsubscribe(modules.ConsensusSetSubscriber) {}
+
+func (cs *stubConsensusSet) ChildTarget(id types.BlockID) (types.Target, bool) {
+	_, ok := cs.blocks[id]
+	return cs.chainCts.RootTarget(), ok
+}
+
+func (cs *stubConsensusSet) FindParentBlock(b types.Block, depth types.BlockHeight) (types.Block, bool) {
+	for ; depth > 0; depth-- {
+		var ok bool
+		b, ok = cs.blocks[b.ParentID]
+		if !ok {
+			return types.Block{}, false
+		}
+	}
+	return b, true
+}
+
+// addBlock adds a block with the given timestamp and transactions on top of the given parent,
+// paying out the miner to the given address.
+func (cs *stubConsensusSet) addBlock(parent types.BlockID, timestamp types.Timestamp, miner types.UnlockHash, txns ...types.Transaction) types.Block {
+	block := types.Block{
+		ParentID:     parent,
+		Timestamp:    timestamp,
+		MinerPayouts: []types.MinerPayout{{Value: cs.chainCts.BlockCreatorFee, UnlockHash: miner}},
+		Transactions: txns,
+	}
+	cs.blocks[block.ID()] = block
+	return block
+}
+
+func TestExplorerRevertSymmetry(t *testing.T) {
+	chainCts := tfcfg.GetTestnetGenesis()
+	bcInfo := tfcfg.GetBlockchainInfo()
+	genesis := chainCts.GenesisBlock()
+	cs := &stubConsensusSet{
+		chainCts: chainCts,
+		blocks:   map[types.BlockID]types.Block{genesis.ID(): genesis},
+	}
+
+	db, err := NewMemoryDatabase(bcInfo, chainCts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	explorer, err := NewExplorer(db, cs, nil, bcInfo, chainCts, nil, 0, 0, 0, 0, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer explorer.Close()
+
+	var miner, receiver types.UnlockHash
+	miner.Type, miner.Hash[0] = types.UnlockTypePubKey, 1
+	receiver.Type, receiver.Hash[0] = types.UnlockTypePubKey, 2
+
+	// the first block creates an output locked until the time of the second block,
+	// both blocks being created on the same day as the genesis block
+	locked := types.Transaction{
+		Version: chainCts.DefaultTransactionVersion,
+		CoinOutputs: []types.CoinOutput{{
+			Value: chainCts.CurrencyUnits.OneCoin,
+			Condition: types.NewCondition(types.NewTimeLockCondition(
+				uint64(genesis.Timestamp+240), types.NewUnlockHashCondition(receiver))),
+		}},
+	}
+	first := cs.addBlock(genesis.ID(), genesis.Timestamp+120, miner, locked)
+	second := cs.addBlock(first.ID(), genesis.Timestamp+240, miner)
+
+	var changeID modules.ConsensusChangeID
+	change := func(synced bool, reverted, applied []types.Block) modules.ConsensusChange {
+		changeID[0]++
+		return modules.ConsensusChange{
+			ID:             changeID,
+			RevertedBlocks: reverted,
+			AppliedBlocks:  applied,
+			ChildTarget:    chainCts.RootTarget(),
+			Synced:         synced,
+		}
+	}
+	explorer.ProcessConsensusChange(change(false, nil, []types.Block{genesis, first}))
+
+	err = db.VerifySymmetry(func() {
+		explorer.ProcessConsensusChange(change(true, nil, []types.Block{second}))
+	}, func() {
+		explorer.ProcessConsensusChange(change(true, []types.Block{second}, nil))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExplorerBurnedLockedSupply(t *testing.T) {
+	chainCts := tfcfg.GetTestnetGenesis()
+	bcInfo := tfcfg.GetBlockchainInfo()
+	genesis := chainCts.GenesisBlock()
+	cs := &stubConsensusSet{
+		chainCts: chainCts,
+		blocks:   map[types.BlockID]types.Block{genesis.ID(): genesis},
+	}
+
+	db, err := NewMemoryDatabase(bcInfo, chainCts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	explorer, err := NewExplorer(db, cs, nil, bcInfo, chainCts, nil, 0, 0, 0, 0, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer explorer.Close()
+
+	var changeID modules.ConsensusChangeID
+	change := func(reverted, applied []types.Block) modules.ConsensusChange {
+		changeID[0]++
+		return modules.ConsensusChange{
+			ID:             changeID,
+			RevertedBlocks: reverted,
+			AppliedBlocks:  applied,
+			ChildTarget:    chainCts.RootTarget(),
+			Synced:         true,
+		}
+	}
+	explorer.ProcessConsensusChange(change(nil, []types.Block{genesis}))
+
+	// find a liquid genesis coin output
+	var (
+		parentID types.CoinOutputID
+		value    types.Currency
+	)
+	for _, tx := range genesis.Transactions {
+		for i, co := range tx.CoinOutputs {
+			info, err := db.GetCoinOutput(tx.CoinOutputID(uint64(i)))
+			if err == nil && info.State == CoinOutputStateLiquid && value.IsZero() {
+				parentID, value = tx.CoinOutputID(uint64(i)), co.Value
+			}
+		}
+	}
+	if value.IsZero() {
+		t.Fatal("no liquid genesis coin output found")
+	}
+
+	// the block spends the genesis coin output to a time locked nil unlock hash,
+	// paying out the miner (maturity locked) to the nil unlock hash as well, burning both
+	burn := types.Transaction{
+		Version:    chainCts.DefaultTransactionVersion,
+		CoinInputs: []types.CoinInput{{ParentID: parentID}},
+		CoinOutputs: []types.CoinOutput{{
+			Value: value,
+			Condition: types.NewCondition(types.NewTimeLockCondition(
+				uint64(genesis.Timestamp+3600), types.NewUnlockHashCondition(types.NilUnlockHash))),
+		}},
+	}
+	block := cs.addBlock(genesis.ID(), genesis.Timestamp+120, types.NilUnlockHash, burn)
+
+	before := explorer.stats.Supply
+	err = db.VerifySymmetry(func() {
+		explorer.ProcessConsensusChange(change(nil, []types.Block{block}))
+		after := explorer.stats.Supply
+		burned := value.Add(chainCts.BlockCreatorFee)
+		if !after.Liquid.Equals(before.Liquid.Sub(value)) {
+			t.Errorf("expected %s liquid coins, got %s", before.Liquid.Sub(value).String(), after.Liquid.String())
+		}
+		if !after.TimeLocked.Equals(before.TimeLocked) || !after.HeightLocked.Equals(before.HeightLocked) {
+			t.Errorf("expected %s time locked and %s height locked coins, got %s and %s",
+				before.TimeLocked.String(), before.HeightLocked.String(), after.TimeLocked.String(), after.HeightLocked.String())
+		}
+		if !after.Burned.Equals(before.Burned.Add(burned)) {
+			t.Errorf("expected %s burned coins, got %s", before.Burned.Add(burned).String(), after.Burned.String())
+		}
+	}, func() {
+		explorer.ProcessConsensusChange(change([]types.Block{block}, nil))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after := explorer.stats.Supply; !after.Liquid.Equals(before.Liquid) || !after.Burned.Equals(before.Burned) {
+		t.Fatalf("expected supply %v after reverting, got %v", before, after)
+	}
+}
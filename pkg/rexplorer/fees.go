@@ -0,0 +1,191 @@
+package rexplorer
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/rivine/rivine/types"
+)
+
+// transactionFee returns the total of the miner fees defined by the given transaction.
+func transactionFee(tx types.Transaction) (fee types.Currency) {
+	for _, mf := range tx.MinerFees {
+		fee = fee.Add(mf)
+	}
+	return fee
+}
+
+// applyMinerFees adds the miner fees defined by the given transaction to the network stats.
+func (stats *NetworkStats) applyMinerFees(tx types.Transaction) {
+	stats.MinerFeeCount += uint64(len(tx.MinerFees))
+	stats.MinerFees = stats.MinerFees.Add(transactionFee(tx))
+	stats.FeeStats = addFeeStats(stats.FeeStats, newFeeStats([]types.Transaction{tx}))
+}
+
+// revertMinerFees subtracts the miner fees defined by the given transaction from the network stats.
+func (stats *NetworkStats) revertMinerFees(tx types.Transaction) {
+	stats.MinerFeeCount = stats.decUntracked("miner fee count", stats.MinerFeeCount, uint64(len(tx.MinerFees)))
+	stats.MinerFees = stats.subUntracked("miner fees", stats.MinerFees, transactionFee(tx))
+	stats.FeeStats = subFeeStats(stats.FeeStats, newFeeStats([]types.Transaction{tx}))
+}
+
+type (
+	// FeeStats summarizes the fees paid by transactions: the amount of transactions defining miner fees,
+	// the total and average fee, and the fee paid by at least half (p50) and 90 percent (p90) of those transactions,
+	// such that wallets can recommend sensible fees. The summary is derived from the distribution of the fees,
+	// which counts the transactions per fee paid, ordered by fee. The fees are binned on a log scale (see feeBin),
+	// bounding the size of the distribution, hence the percentiles are rounded down to the bin of the fee.
+	FeeStats struct {
+		Count        uint64         `json:"count"`
+		Total        types.Currency `json:"total"`
+		Average      types.Currency `json:"average"`
+		P50          types.Currency `json:"p50"`
+		P90          types.Currency `json:"p90"`
+		Distribution []FeeCount     `json:"distribution"`
+	}
+
+	// FeeCount is the amount of transactions which paid a fee within the bin starting at the given fee.
+	FeeCount struct {
+		Fee   types.Currency `json:"fee"`
+		Count uint64         `json:"count"`
+	}
+)
+
+// Percentile returns the lowest fee paid by at least the given percentage of the transactions,
+// using the nearest-rank method, or zero if no transactions are counted.
+func (fs *FeeStats) Percentile(p uint64) types.Currency {
+	rank := (fs.Count*p + 99) / 100
+	if rank == 0 {
+		rank = 1
+	}
+	var seen uint64
+	for _, fc := range fs.Distribution {
+		seen += fc.Count
+		if seen >= rank {
+			return fc.Fee
+		}
+	}
+	return types.ZeroCurrency
+}
+
+// feeSignificantDigits is the amount of significant digits of the fees kept by the bins of the fee distribution,
+// e.g. a fee of 0.1234 TFT is counted as 0.12 TFT. It results in at most 90 bins per order of magnitude,
+// while the round fees paid by most transactions are counted exactly.
+const feeSignificantDigits = 2
+
+// feeBin returns the bin of the given fee, the fee rounded down to feeSignificantDigits significant digits.
+func feeBin(fee types.Currency) types.Currency {
+	digits := len(fee.Big().String())
+	if digits <= feeSignificantDigits {
+		return fee
+	}
+	unit := types.NewCurrency(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits-feeSignificantDigits)), nil))
+	return fee.Div(unit).Mul(unit)
+}
+
+// search returns the index of the given fee in the distribution, or the index at which it would have to be inserted.
+func (fs *FeeStats) search(fee types.Currency) int {
+	return sort.Search(len(fs.Distribution), func(i int) bool {
+		return fs.Distribution[i].Fee.Cmp(fee) >= 0
+	})
+}
+
+// add counts the given amount of transactions paying a fee within the bin of the given fee,
+// leaving the total fee to the caller, as a bin doesn't define the exact fees paid.
+func (fs *FeeStats) add(fee types.Currency, n uint64) {
+	fee = feeBin(fee)
+	i := fs.search(fee)
+	if i < len(fs.Distribution) && fs.Distribution[i].Fee.Equals(fee) {
+		fs.Distribution[i].Count += n
+	} else {
+		fs.Distribution = append(fs.Distribution, FeeCount{})
+		copy(fs.Distribution[i+1:], fs.Distribution[i:])
+		fs.Distribution[i] = FeeCount{Fee: fee, Count: n}
+	}
+	fs.Count += n
+}
+
+// sub uncounts (at most) the given amount of transactions paying a fee within the bin of the given fee,
+// the reverse process of add.
+func (fs *FeeStats) sub(fee types.Currency, n uint64) {
+	fee = feeBin(fee)
+	i := fs.search(fee)
+	if i >= len(fs.Distribution) || !fs.Distribution[i].Fee.Equals(fee) {
+		return
+	}
+	if fc := &fs.Distribution[i]; n < fc.Count {
+		fc.Count -= n
+	} else {
+		n = fc.Count
+		fs.Distribution = append(fs.Distribution[:i], fs.Distribution[i+1:]...)
+	}
+	fs.Count -= n
+}
+
+// summarize recomputes the average and percentiles from the distribution.
+func (fs *FeeStats) summarize() {
+	fs.Average, fs.P50, fs.P90 = types.ZeroCurrency, fs.Percentile(50), fs.Percentile(90)
+	if fs.Count > 0 {
+		fs.Average = fs.Total.Div64(fs.Count)
+	}
+}
+
+// addFeeStats returns the given fee stats with the other fee stats counted as well, nil if none are counted.
+func addFeeStats(fs, other *FeeStats) *FeeStats {
+	sum := copyFeeStats(fs)
+	if other != nil {
+		for _, fc := range other.Distribution {
+			sum.add(fc.Fee, fc.Count)
+		}
+		sum.Total = sum.Total.Add(other.Total)
+	}
+	return sum.orNil()
+}
+
+// subFeeStats returns the given fee stats with the other fee stats uncounted, nil if none remain counted.
+func subFeeStats(fs, other *FeeStats) *FeeStats {
+	diff := copyFeeStats(fs)
+	if other != nil {
+		for _, fc := range other.Distribution {
+			diff.sub(fc.Fee, fc.Count)
+		}
+		diff.Total = subCurrencyOrZero(diff.Total, other.Total)
+	}
+	return diff.orNil()
+}
+
+// copyFeeStats returns a deep copy of the given fee stats, empty stats if nil.
+// The distribution is binned again, as the fee stats stored by older versions count every distinct fee paid.
+func copyFeeStats(fs *FeeStats) *FeeStats {
+	c := &FeeStats{Total: types.ZeroCurrency}
+	if fs == nil {
+		return c
+	}
+	c.Total = fs.Total
+	for _, fc := range fs.Distribution {
+		c.add(fc.Fee, fc.Count)
+	}
+	return c
+}
+
+// orNil returns the summarized fee stats, or nil if no transactions are counted.
+func (fs *FeeStats) orNil() *FeeStats {
+	if fs.Count == 0 {
+		return nil
+	}
+	fs.summarize()
+	return fs
+}
+
+// newFeeStats returns the fee stats of the given transactions, nil if none of them defines miner fees.
+func newFeeStats(txs []types.Transaction) *FeeStats {
+	fs := copyFeeStats(nil)
+	for _, tx := range txs {
+		if len(tx.MinerFees) > 0 {
+			fee := transactionFee(tx)
+			fs.add(fee, 1)
+			fs.Total = fs.Total.Add(fee)
+		}
+	}
+	return fs.orNil()
+}
@@ -0,0 +1,180 @@
+package rexplorer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rivine/rivine/types"
+)
+
+// WalletGroup defines the group an (exchange/custodian) wallet address is labeled with.
+type WalletGroup uint8
+
+// The different wallet groups which can be labeled.
+const (
+	WalletGroupNone WalletGroup = iota
+	WalletGroupHot
+	WalletGroupCold
+)
+
+// String implements Stringer.String
+func (wg WalletGroup) String() string {
+	switch wg {
+	case WalletGroupNone:
+		return "none"
+	case WalletGroupHot:
+		return "hot"
+	case WalletGroupCold:
+		return "cold"
+	default:
+		return fmt.Sprintf("WalletGroup(%d)", wg)
+	}
+}
+
+// LoadString implements StringLoader.LoadString
+func (wg *WalletGroup) LoadString(str string) error {
+	switch str {
+	case "none":
+		*wg = WalletGroupNone
+	case "hot":
+		*wg = WalletGroupHot
+	case "cold":
+		*wg = WalletGroupCold
+	default:
+		return fmt.Errorf("unknown wallet group %q", str)
+	}
+	return nil
+}
+
+// WalletGroups maps labeled wallet addresses to the group they belong to.
+type WalletGroups map[types.UnlockHash]WalletGroup
+
+// NewWalletGroups creates the wallet groups from the given (string-encoded) hot and cold wallet addresses.
+func NewWalletGroups(hot, cold []string) (WalletGroups, error) {
+	groups := make(WalletGroups, len(hot)+len(cold))
+	for _, labeled := range []struct {
+		Group     WalletGroup
+		Addresses []string
+	}{
+		{WalletGroupHot, hot},
+		{WalletGroupCold, cold},
+	} {
+		for _, str := range labeled.Addresses {
+			var uh types.UnlockHash
+			err := uh.LoadString(str)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s wallet address %q: %v", labeled.Group.String(), str, err)
+			}
+			if group, ok := groups[uh]; ok && group != labeled.Group {
+				return nil, fmt.Errorf("wallet address %s cannot be labeled both %s and %s",
+					str, group.String(), labeled.Group.String())
+			}
+			groups[uh] = labeled.Group
+		}
+	}
+	return groups, nil
+}
+
+// Group returns the group the given address is labeled with, WalletGroupNone if it isn't labeled.
+func (groups WalletGroups) Group(uh types.UnlockHash) WalletGroup {
+	return groups[uh]
+}
+
+type (
+	// WalletGroupFlows collects the flows between the hot and cold wallet groups.
+	WalletGroupFlows struct {
+		// Sweeps are transfers from hot wallets to cold wallets.
+		Sweeps WalletGroupFlow `json:"sweeps"`
+		// Refills are transfers from cold wallets to hot wallets.
+		Refills WalletGroupFlow `json:"refills"`
+	}
+	// WalletGroupFlow collects the transactions and value of a single flow direction.
+	WalletGroupFlow struct {
+		TransactionCount uint64         `json:"txCount"`
+		Value            types.Currency `json:"value"`
+	}
+)
+
+// IsZero returns true if no flow was recorded.
+func (flows WalletGroupFlows) IsZero() bool {
+	return flows.Sweeps.TransactionCount == 0 && flows.Refills.TransactionCount == 0
+}
+
+// Add returns the sum of both flows.
+func (flows WalletGroupFlows) Add(other WalletGroupFlows) WalletGroupFlows {
+	return WalletGroupFlows{
+		Sweeps:  flows.Sweeps.Add(other.Sweeps),
+		Refills: flows.Refills.Add(other.Refills),
+	}
+}
+
+// Sub returns the difference of both flows, used to revert a flow.
+func (flows WalletGroupFlows) Sub(other WalletGroupFlows) WalletGroupFlows {
+	return WalletGroupFlows{
+		Sweeps:  flows.Sweeps.Sub(other.Sweeps),
+		Refills: flows.Refills.Sub(other.Refills),
+	}
+}
+
+// Add returns the sum of both flows.
+func (flow WalletGroupFlow) Add(other WalletGroupFlow) WalletGroupFlow {
+	return WalletGroupFlow{
+		TransactionCount: flow.TransactionCount + other.TransactionCount,
+		Value:            flow.Value.Add(other.Value),
+	}
+}
+
+// Sub returns the difference of both flows, used to revert a flow.
+func (flow WalletGroupFlow) Sub(other WalletGroupFlow) WalletGroupFlow {
+	return WalletGroupFlow{
+		TransactionCount: flow.TransactionCount - other.TransactionCount,
+		Value:            subCurrencyOrZero(flow.Value, other.Value),
+	}
+}
+
+// getWalletGroupFlows returns the flows between the hot and cold wallet groups of a single transaction.
+// A transaction funded by a hot wallet, sending coins to a cold wallet, is a sweep,
+// while a transaction funded by a cold wallet, sending coins to a hot wallet, is a refill.
+// Change outputs (outputs sent back to one of the senders) are ignored.
+func getWalletGroupFlows(groups WalletGroups, senders map[types.UnlockHash]struct{}, outputs []types.CoinOutput) (flows WalletGroupFlows) {
+	if len(groups) == 0 || len(senders) == 0 {
+		return
+	}
+	var fromHot, fromCold bool
+	for uh := range senders {
+		switch groups.Group(uh) {
+		case WalletGroupHot:
+			fromHot = true
+		case WalletGroupCold:
+			fromCold = true
+		}
+	}
+	if !fromHot && !fromCold {
+		return
+	}
+	for _, co := range outputs {
+		uh := co.Condition.UnlockHash()
+		if _, ok := senders[uh]; ok {
+			continue // change output
+		}
+		switch groups.Group(uh) {
+		case WalletGroupCold:
+			if fromHot {
+				flows.Sweeps.Value = flows.Sweeps.Value.Add(co.Value)
+				flows.Sweeps.TransactionCount = 1
+			}
+		case WalletGroupHot:
+			if fromCold {
+				flows.Refills.Value = flows.Refills.Value.Add(co.Value)
+				flows.Refills.TransactionCount = 1
+			}
+		}
+	}
+	return
+}
+
+// walletGroupFlowsDay returns the (UTC) day, formatted as YYYY-MM-DD,
+// used to aggregate the wallet group flows over time.
+func walletGroupFlowsDay(timestamp types.Timestamp) string {
+	return time.Unix(int64(timestamp), 0).UTC().Format("2006-01-02")
+}
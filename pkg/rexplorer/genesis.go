@@ -0,0 +1,108 @@
+package rexplorer
+
+import (
+	"fmt"
+
+	"github.com/rivine/rivine/modules"
+	"github.com/rivine/rivine/types"
+)
+
+type (
+	// GenesisAllocation records the initial distribution of the coins and block stakes, as allocated by the genesis block,
+	// such that it remains queryable once the genesis outputs are spent.
+	GenesisAllocation struct {
+		BlockID           types.BlockID             `json:"blockID"`
+		Timestamp         types.Timestamp           `json:"timestamp"`
+		Coins             types.Currency            `json:"coins"`
+		BlockStakes       types.Currency            `json:"blockStakes"`
+		CoinOutputs       []GenesisCoinOutput       `json:"coinOutputs"`
+		BlockStakeOutputs []GenesisBlockStakeOutput `json:"blockStakeOutputs"`
+	}
+
+	// GenesisCoinOutput is a single coin output allocated by the genesis block.
+	GenesisCoinOutput struct {
+		ID         types.CoinOutputID         `json:"id"`
+		UnlockHash types.UnlockHash           `json:"unlockhash"`
+		Value      types.Currency             `json:"value"`
+		Condition  types.UnlockConditionProxy `json:"condition"`
+	}
+
+	// GenesisBlockStakeOutput is a single block stake output allocated by the genesis block.
+	GenesisBlockStakeOutput struct {
+		ID         types.BlockStakeOutputID   `json:"id"`
+		UnlockHash types.UnlockHash           `json:"unlockhash"`
+		Value      types.Currency             `json:"value"`
+		Condition  types.UnlockConditionProxy `json:"condition"`
+	}
+)
+
+// newGenesisAllocation records the coin and block stake outputs allocated by the given genesis block.
+func newGenesisAllocation(block types.Block) GenesisAllocation {
+	allocation := GenesisAllocation{
+		BlockID:           block.ID(),
+		Timestamp:         block.Timestamp,
+		Coins:             types.ZeroCurrency,
+		BlockStakes:       types.ZeroCurrency,
+		CoinOutputs:       []GenesisCoinOutput{},
+		BlockStakeOutputs: []GenesisBlockStakeOutput{},
+	}
+	for _, tx := range block.Transactions {
+		for i, co := range tx.CoinOutputs {
+			allocation.Coins = allocation.Coins.Add(co.Value)
+			allocation.CoinOutputs = append(allocation.CoinOutputs, GenesisCoinOutput{
+				ID:         tx.CoinOutputID(uint64(i)),
+				UnlockHash: co.Condition.UnlockHash(),
+				Value:      co.Value,
+				Condition:  co.Condition,
+			})
+		}
+		for i, bso := range tx.BlockStakeOutputs {
+			allocation.BlockStakes = allocation.BlockStakes.Add(bso.Value)
+			allocation.BlockStakeOutputs = append(allocation.BlockStakeOutputs, GenesisBlockStakeOutput{
+				ID:         tx.BlockStakeOutputID(uint64(i)),
+				UnlockHash: bso.Condition.UnlockHash(),
+				Value:      bso.Value,
+				Condition:  bso.Condition,
+			})
+		}
+	}
+	return allocation
+}
+
+// storeGenesisAllocation stores the allocation of the given genesis block, in case the database supports it.
+func (explorer *Explorer) storeGenesisAllocation(block types.Block) {
+	gdb, ok := explorer.db.(GenesisDatabase)
+	if !ok {
+		return
+	}
+	err := gdb.SetGenesisAllocation(newGenesisAllocation(block))
+	if err != nil {
+		panic(fmt.Sprintf("failed to store genesis allocation: %v", err))
+	}
+}
+
+// revertGenesisAllocation drops the stored allocation of the genesis block, in case the database supports it.
+func (explorer *Explorer) revertGenesisAllocation() {
+	gdb, ok := explorer.db.(GenesisDatabase)
+	if !ok {
+		return
+	}
+	err := gdb.DeleteGenesisAllocation()
+	if err != nil {
+		panic(fmt.Sprintf("failed to delete genesis allocation: %v", err))
+	}
+}
+
+// ensureGenesisAllocation stores the allocation of the genesis block of the given chain,
+// in case the database supports it, and the genesis block was applied prior to the dataset storing its allocation.
+func ensureGenesisAllocation(db Database, state ExplorerState, chainCts types.ChainConstants) error {
+	gdb, ok := db.(GenesisDatabase)
+	if !ok || state.CurrentChangeID == modules.ConsensusChangeBeginning {
+		return nil
+	}
+	_, err := gdb.GetGenesisAllocation()
+	if err != ErrNotFound {
+		return err
+	}
+	return gdb.SetGenesisAllocation(newGenesisAllocation(chainCts.GenesisBlock()))
+}
@@ -0,0 +1,805 @@
+package rexplorer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// This file implements the subset of GraphQL (see https://spec.graphql.org) required to serve read-only queries:
+// a document of query operations, selecting fields (with aliases and arguments) and nested selection sets,
+// using variables. Mutations, subscriptions, fragments, directives and introspection are not supported.
+//
+// Rather than defining every field of every type, an object type can expose the (JSON-encoded) fields
+// of a Go value as its remaining fields, such that only the fields linking objects have to be defined explicitly.
+// Such JSON-encoded fields can be selected entirely, or be projected using a nested selection set.
+
+// graphqlMaxDepth is the maximum depth of the objects selected by a query.
+const graphqlMaxDepth = 10
+
+type (
+	// graphqlObject is an object type of a GraphQL schema.
+	graphqlObject struct {
+		name   string
+		fields map[string]*graphqlField
+		// the Go value exposing the remaining fields of the object, as its JSON-encoded fields, nil if none
+		json func(parent interface{}) interface{}
+	}
+
+	// graphqlField is a field of a graphqlObject, resolved from the value of its parent object.
+	graphqlField struct {
+		// the (string) arguments of the field, all of them being required
+		args []string
+		// the (string) arguments of the field which can be omitted
+		optionalArgs []string
+		// the object type of the value of the field (or of its elements if it's a slice), nil for a JSON-encoded value
+		object  *graphqlObject
+		resolve func(parent interface{}, args map[string]string) (interface{}, error)
+	}
+
+	// graphqlRequest is a GraphQL request, as sent over HTTP.
+	graphqlRequest struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+
+	// graphqlResponse is the response to a graphqlRequest,
+	// the data being undefined if any error occurred, as partial results are not supported.
+	graphqlResponse struct {
+		Data   interface{}    `json:"data"`
+		Errors []graphqlError `json:"errors,omitempty"`
+	}
+	graphqlError struct {
+		Message string `json:"message"`
+	}
+)
+
+// executeGraphQL executes the given request against the given query type.
+func executeGraphQL(query *graphqlObject, request graphqlRequest) graphqlResponse {
+	data, err := func() (interface{}, error) {
+		doc, err := parseGraphQL(request.Query)
+		if err != nil {
+			return nil, err
+		}
+		op, err := doc.operation(request.OperationName)
+		if err != nil {
+			return nil, err
+		}
+		ex := &graphqlExecutor{variables: make(map[string]interface{})}
+		for _, def := range op.variables {
+			value, ok := request.Variables[def.name]
+			if !ok {
+				if !def.hasDefault {
+					return nil, fmt.Errorf("variable $%s is not defined", def.name)
+				}
+				value = def.defaultValue
+			}
+			ex.variables[def.name] = value
+		}
+		return ex.executeObject(query, nil, op.selections, 0)
+	}()
+	if err != nil {
+		return graphqlResponse{Errors: []graphqlError{{Message: err.Error()}}}
+	}
+	return graphqlResponse{Data: data}
+}
+
+// graphqlExecutor executes a single operation.
+type graphqlExecutor struct {
+	variables map[string]interface{}
+}
+
+func (ex *graphqlExecutor) executeObject(obj *graphqlObject, parent interface{}, selections []*graphqlSelection, depth int) (graphqlOrderedObject, error) {
+	if depth > graphqlMaxDepth {
+		return nil, fmt.Errorf("query exceeds the maximum depth of %d", graphqlMaxDepth)
+	}
+	var fields map[string]json.RawMessage
+	var result graphqlOrderedObject
+	for _, sel := range selections {
+		key := sel.responseKey()
+		if result.has(key) {
+			continue
+		}
+		if sel.name == "__typename" {
+			result = append(result, graphqlOrderedField{key, obj.name})
+			continue
+		}
+		var value interface{}
+		if field, ok := obj.fields[sel.name]; ok {
+			args, err := ex.arguments(field, sel)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %v", obj.name, sel.name, err)
+			}
+			resolved, err := field.resolve(parent, args)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %v", obj.name, sel.name, err)
+			}
+			value, err = ex.completeValue(field.object, resolved, sel, depth)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			if fields == nil && obj.json != nil {
+				var err error
+				fields, err = decodeGraphQLFields(obj.json(parent))
+				if err != nil {
+					return nil, fmt.Errorf("%s: %v", obj.name, err)
+				}
+			}
+			raw, ok := fields[sel.name]
+			if !ok {
+				return nil, fmt.Errorf("cannot query field %q on type %s", sel.name, obj.name)
+			}
+			if len(sel.args) > 0 {
+				return nil, fmt.Errorf("%s.%s: field takes no arguments", obj.name, sel.name)
+			}
+			var err error
+			value, err = projectGraphQLJSON(raw, sel)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%v", obj.name, err)
+			}
+		}
+		result = append(result, graphqlOrderedField{key, value})
+	}
+	return result, nil
+}
+
+// completeValue completes the value of a field, resolving the selection of the given object type on it (or on its elements),
+// or projecting it as a JSON-encoded value if no object type is given.
+func (ex *graphqlExecutor) completeValue(obj *graphqlObject, value interface{}, sel *graphqlSelection, depth int) (interface{}, error) {
+	if obj == nil {
+		b, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to JSON-encode %s: %v", sel.name, err)
+		}
+		return projectGraphQLJSON(b, sel)
+	}
+	if sel.selections == nil {
+		return nil, fmt.Errorf("field %q of type %s must have a selection of subfields", sel.name, obj.name)
+	}
+	rv := reflect.ValueOf(value)
+	switch {
+	case value == nil || (rv.Kind() == reflect.Ptr && rv.IsNil()):
+		return nil, nil
+	case rv.Kind() == reflect.Slice:
+		list := make([]interface{}, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			v, err := ex.executeObject(obj, rv.Index(i).Interface(), sel.selections, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	default:
+		return ex.executeObject(obj, value, sel.selections, depth+1)
+	}
+}
+
+// arguments resolves the arguments of the given field, as selected.
+func (ex *graphqlExecutor) arguments(field *graphqlField, sel *graphqlSelection) (map[string]string, error) {
+	args := make(map[string]string, len(sel.args))
+	for _, arg := range sel.args {
+		if !stringInSlice(arg.name, field.args) && !stringInSlice(arg.name, field.optionalArgs) {
+			return nil, fmt.Errorf("unknown argument %q", arg.name)
+		}
+		value := arg.value
+		if ref, ok := value.(graphqlVariableRef); ok {
+			v, ok := ex.variables[string(ref)]
+			if !ok {
+				return nil, fmt.Errorf("variable $%s is not defined", ref)
+			}
+			value = v
+		}
+		var str string
+		switch value := value.(type) {
+		case string:
+			str = value
+		case json.Number:
+			// integer arguments are passed as their decimal string
+			str = value.String()
+		case float64:
+			str = strconv.FormatFloat(value, 'f', -1, 64)
+		case nil:
+			if stringInSlice(arg.name, field.optionalArgs) {
+				continue
+			}
+			return nil, fmt.Errorf("argument %q must be a string", arg.name)
+		default:
+			return nil, fmt.Errorf("argument %q must be a string", arg.name)
+		}
+		args[arg.name] = str
+	}
+	for _, name := range field.args {
+		if _, ok := args[name]; !ok {
+			return nil, fmt.Errorf("argument %q is required", name)
+		}
+	}
+	return args, nil
+}
+
+func stringInSlice(str string, slice []string) bool {
+	for _, s := range slice {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeGraphQLFields returns the JSON-encoded fields of the given value.
+func decodeGraphQLFields(v interface{}) (map[string]json.RawMessage, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to JSON-encode value: %v", err)
+	}
+	fields := make(map[string]json.RawMessage)
+	if !bytes.Equal(b, []byte("null")) {
+		err = json.Unmarshal(b, &fields)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode JSON-encoded fields: %v", err)
+		}
+	}
+	return fields, nil
+}
+
+// projectGraphQLJSON projects the given JSON-encoded value using the selection set of the given selection,
+// returning the value as is if no selection set is given.
+// Selected fields which aren't defined by the value (e.g. as they're omitted when empty) resolve to null.
+func projectGraphQLJSON(raw json.RawMessage, sel *graphqlSelection) (interface{}, error) {
+	if sel.selections == nil {
+		return raw, nil
+	}
+	raw = bytes.TrimSpace(raw)
+	switch {
+	case bytes.Equal(raw, []byte("null")):
+		return nil, nil
+	case bytes.HasPrefix(raw, []byte("[")):
+		var elements []json.RawMessage
+		err := json.Unmarshal(raw, &elements)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", sel.name, err)
+		}
+		list := make([]interface{}, 0, len(elements))
+		for _, element := range elements {
+			v, err := projectGraphQLJSON(element, sel)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case bytes.HasPrefix(raw, []byte("{")):
+		var fields map[string]json.RawMessage
+		err := json.Unmarshal(raw, &fields)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", sel.name, err)
+		}
+		var result graphqlOrderedObject
+		for _, sub := range sel.selections {
+			key := sub.responseKey()
+			if result.has(key) {
+				continue
+			}
+			if len(sub.args) > 0 {
+				return nil, fmt.Errorf("%s.%s: field takes no arguments", sel.name, sub.name)
+			}
+			var value interface{}
+			if field, ok := fields[sub.name]; ok {
+				value, err = projectGraphQLJSON(field, sub)
+				if err != nil {
+					return nil, fmt.Errorf("%s.%v", sel.name, err)
+				}
+			}
+			result = append(result, graphqlOrderedField{key, value})
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("%s: field has no subfields to select", sel.name)
+	}
+}
+
+// graphqlOrderedObject is a JSON object of which the fields are encoded in order,
+// as GraphQL requires the fields of a response to be ordered as they are selected.
+type graphqlOrderedObject []graphqlOrderedField
+
+type graphqlOrderedField struct {
+	key   string
+	value interface{}
+}
+
+func (obj graphqlOrderedObject) has(key string) bool {
+	for _, field := range obj {
+		if field.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON implements json.Marshaler.MarshalJSON
+func (obj graphqlOrderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range obj {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(field.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+type (
+	// graphqlDocument is a parsed GraphQL document.
+	graphqlDocument struct {
+		operations []*graphqlOperation
+	}
+	graphqlOperation struct {
+		name       string
+		variables  []graphqlVariableDefinition
+		selections []*graphqlSelection
+	}
+	graphqlVariableDefinition struct {
+		name         string
+		defaultValue interface{}
+		hasDefault   bool
+	}
+	graphqlSelection struct {
+		alias, name string
+		args        []graphqlArgument
+		// nil if the field has no selection set
+		selections []*graphqlSelection
+	}
+	graphqlArgument struct {
+		name string
+		// a string, json.Number, bool, nil, graphqlVariableRef, []interface{} or map[string]interface{}
+		value interface{}
+	}
+	// graphqlVariableRef is a value referencing a variable, by its name.
+	graphqlVariableRef string
+)
+
+func (sel *graphqlSelection) responseKey() string {
+	if sel.alias != "" {
+		return sel.alias
+	}
+	return sel.name
+}
+
+// operation returns the operation with the given name, or the only operation if no name is given.
+func (doc *graphqlDocument) operation(name string) (*graphqlOperation, error) {
+	if name == "" {
+		if len(doc.operations) != 1 {
+			return nil, errors.New("an operation name is required for a document defining multiple operations")
+		}
+		return doc.operations[0], nil
+	}
+	for _, op := range doc.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+// parseGraphQL parses the given GraphQL document.
+func parseGraphQL(src string) (*graphqlDocument, error) {
+	p := &graphqlParser{lexer: graphqlLexer{src: src}}
+	err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	doc := new(graphqlDocument)
+	for p.token.kind != graphqlTokenEOF {
+		op, err := p.parseOperation()
+		if err != nil {
+			return nil, err
+		}
+		doc.operations = append(doc.operations, op)
+	}
+	if len(doc.operations) == 0 {
+		return nil, errors.New("document defines no operation")
+	}
+	return doc, nil
+}
+
+type graphqlParser struct {
+	lexer graphqlLexer
+	token graphqlToken
+}
+
+func (p *graphqlParser) next() (err error) {
+	p.token, err = p.lexer.next()
+	return
+}
+
+// is returns true if the current token is the given punctuator or name.
+func (p *graphqlParser) is(kind graphqlTokenKind, value string) bool {
+	return p.token.kind == kind && p.token.value == value
+}
+
+// expect consumes the given punctuator.
+func (p *graphqlParser) expect(punctuator string) error {
+	if !p.is(graphqlTokenPunctuator, punctuator) {
+		return p.unexpected("expected " + punctuator)
+	}
+	return p.next()
+}
+
+func (p *graphqlParser) unexpected(expected string) error {
+	if p.token.kind == graphqlTokenEOF {
+		return fmt.Errorf("syntax error: unexpected end of document, %s", expected)
+	}
+	return fmt.Errorf("syntax error at position %d: unexpected %q, %s", p.token.pos, p.token.value, expected)
+}
+
+func (p *graphqlParser) parseName() (string, error) {
+	if p.token.kind != graphqlTokenName {
+		return "", p.unexpected("expected a name")
+	}
+	name := p.token.value
+	return name, p.next()
+}
+
+func (p *graphqlParser) parseOperation() (*graphqlOperation, error) {
+	op := new(graphqlOperation)
+	if p.token.kind == graphqlTokenName {
+		switch p.token.value {
+		case "query":
+		case "mutation", "subscription":
+			return nil, fmt.Errorf("%s operations are not supported, only queries are", p.token.value)
+		case "fragment":
+			return nil, errors.New("fragments are not supported")
+		default:
+			return nil, p.unexpected("expected an operation")
+		}
+		err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if p.token.kind == graphqlTokenName {
+			op.name = p.token.value
+			err = p.next()
+			if err != nil {
+				return nil, err
+			}
+		}
+		if p.is(graphqlTokenPunctuator, "(") {
+			op.variables, err = p.parseVariableDefinitions()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	var err error
+	op.selections, err = p.parseSelectionSet()
+	return op, err
+}
+
+func (p *graphqlParser) parseVariableDefinitions() ([]graphqlVariableDefinition, error) {
+	err := p.expect("(")
+	if err != nil {
+		return nil, err
+	}
+	var defs []graphqlVariableDefinition
+	for !p.is(graphqlTokenPunctuator, ")") {
+		err = p.expect("$")
+		if err != nil {
+			return nil, err
+		}
+		var def graphqlVariableDefinition
+		def.name, err = p.parseName()
+		if err == nil {
+			err = p.expect(":")
+		}
+		if err == nil {
+			err = p.skipType()
+		}
+		if err != nil {
+			return nil, err
+		}
+		if p.is(graphqlTokenPunctuator, "=") {
+			err = p.next()
+			if err != nil {
+				return nil, err
+			}
+			def.defaultValue, err = p.parseValue(true)
+			if err != nil {
+				return nil, err
+			}
+			def.hasDefault = true
+		}
+		defs = append(defs, def)
+	}
+	return defs, p.next()
+}
+
+// skipType skips a type reference, as the types of variables are not validated.
+func (p *graphqlParser) skipType() error {
+	var err error
+	if p.is(graphqlTokenPunctuator, "[") {
+		err = p.next()
+		if err == nil {
+			err = p.skipType()
+		}
+		if err == nil {
+			err = p.expect("]")
+		}
+	} else {
+		_, err = p.parseName()
+	}
+	if err == nil && p.is(graphqlTokenPunctuator, "!") {
+		err = p.next()
+	}
+	return err
+}
+
+func (p *graphqlParser) parseSelectionSet() ([]*graphqlSelection, error) {
+	err := p.expect("{")
+	if err != nil {
+		return nil, err
+	}
+	selections := []*graphqlSelection{}
+	for !p.is(graphqlTokenPunctuator, "}") {
+		if p.is(graphqlTokenPunctuator, "...") {
+			return nil, errors.New("fragments are not supported")
+		}
+		sel, err := p.parseField()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, sel)
+	}
+	if len(selections) == 0 {
+		return nil, p.unexpected("expected a field")
+	}
+	return selections, p.next()
+}
+
+func (p *graphqlParser) parseField() (*graphqlSelection, error) {
+	sel := new(graphqlSelection)
+	name, err := p.parseName()
+	if err != nil {
+		return nil, err
+	}
+	if p.is(graphqlTokenPunctuator, ":") {
+		err = p.next()
+		if err != nil {
+			return nil, err
+		}
+		sel.alias = name
+		name, err = p.parseName()
+		if err != nil {
+			return nil, err
+		}
+	}
+	sel.name = name
+	if p.is(graphqlTokenPunctuator, "(") {
+		err = p.next()
+		if err != nil {
+			return nil, err
+		}
+		for !p.is(graphqlTokenPunctuator, ")") {
+			var arg graphqlArgument
+			arg.name, err = p.parseName()
+			if err == nil {
+				err = p.expect(":")
+			}
+			if err == nil {
+				arg.value, err = p.parseValue(false)
+			}
+			if err != nil {
+				return nil, err
+			}
+			sel.args = append(sel.args, arg)
+		}
+		err = p.next()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if p.is(graphqlTokenPunctuator, "@") {
+		return nil, errors.New("directives are not supported")
+	}
+	if p.is(graphqlTokenPunctuator, "{") {
+		sel.selections, err = p.parseSelectionSet()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sel, nil
+}
+
+// parseValue parses a value, which has to be constant (not referencing any variable) if const is true.
+func (p *graphqlParser) parseValue(constant bool) (interface{}, error) {
+	token := p.token
+	switch token.kind {
+	case graphqlTokenString:
+		return token.value, p.next()
+	case graphqlTokenNumber:
+		return json.Number(token.value), p.next()
+	case graphqlTokenName:
+		var value interface{}
+		switch token.value {
+		case "true":
+			value = true
+		case "false":
+			value = false
+		case "null":
+			value = nil
+		default:
+			// enum values are not used by the schema, and are passed as strings
+			value = token.value
+		}
+		return value, p.next()
+	case graphqlTokenPunctuator:
+		switch token.value {
+		case "$":
+			if constant {
+				return nil, p.unexpected("expected a constant value")
+			}
+			err := p.next()
+			if err != nil {
+				return nil, err
+			}
+			name, err := p.parseName()
+			return graphqlVariableRef(name), err
+		case "[":
+			err := p.next()
+			if err != nil {
+				return nil, err
+			}
+			list := []interface{}{}
+			for !p.is(graphqlTokenPunctuator, "]") {
+				value, err := p.parseValue(constant)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, value)
+			}
+			return list, p.next()
+		case "{":
+			err := p.next()
+			if err != nil {
+				return nil, err
+			}
+			obj := make(map[string]interface{})
+			for !p.is(graphqlTokenPunctuator, "}") {
+				name, err := p.parseName()
+				if err == nil {
+					err = p.expect(":")
+				}
+				if err != nil {
+					return nil, err
+				}
+				obj[name], err = p.parseValue(constant)
+				if err != nil {
+					return nil, err
+				}
+			}
+			return obj, p.next()
+		}
+	}
+	return nil, p.unexpected("expected a value")
+}
+
+type graphqlTokenKind uint8
+
+const (
+	graphqlTokenEOF graphqlTokenKind = iota
+	graphqlTokenPunctuator
+	graphqlTokenName
+	graphqlTokenNumber
+	graphqlTokenString
+)
+
+type graphqlToken struct {
+	kind  graphqlTokenKind
+	value string
+	pos   int
+}
+
+// graphqlLexer splits a GraphQL document into tokens, skipping whitespace, commas and comments.
+type graphqlLexer struct {
+	src string
+	pos int
+}
+
+func (l *graphqlLexer) next() (graphqlToken, error) {
+	// skip ignored tokens
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if c == '#' {
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' && c != ',' && !strings.HasPrefix(l.src[l.pos:], "\ufeff") {
+			break
+		}
+		if c < utf8.RuneSelf {
+			l.pos++
+		} else {
+			l.pos += len("\ufeff")
+		}
+	}
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return graphqlToken{kind: graphqlTokenEOF, pos: start}, nil
+	}
+	c := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.pos += 3
+		return graphqlToken{kind: graphqlTokenPunctuator, value: "...", pos: start}, nil
+	case strings.IndexByte("!$():=@[]{}|", c) >= 0:
+		l.pos++
+		return graphqlToken{kind: graphqlTokenPunctuator, value: string(c), pos: start}, nil
+	case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		for l.pos < len(l.src) && isGraphQLNameChar(l.src[l.pos]) {
+			l.pos++
+		}
+		return graphqlToken{kind: graphqlTokenName, value: l.src[start:l.pos], pos: start}, nil
+	case c == '-' || (c >= '0' && c <= '9'):
+		l.pos++
+		for l.pos < len(l.src) && strings.IndexByte("0123456789.eE+-", l.src[l.pos]) >= 0 {
+			l.pos++
+		}
+		value := l.src[start:l.pos]
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return graphqlToken{}, fmt.Errorf("syntax error at position %d: invalid number %q", start, value)
+		}
+		return graphqlToken{kind: graphqlTokenNumber, value: value, pos: start}, nil
+	case c == '"':
+		if strings.HasPrefix(l.src[l.pos:], `"""`) {
+			return graphqlToken{}, fmt.Errorf("syntax error at position %d: block strings are not supported", start)
+		}
+		// find the closing quote, skipping escaped characters, and decode the string as JSON,
+		// as the escape sequences of GraphQL strings are a subset of those of JSON strings
+		l.pos++
+		for l.pos < len(l.src) && l.src[l.pos] != '"' {
+			if l.src[l.pos] == '\n' || l.src[l.pos] == '\r' {
+				break
+			}
+			if l.src[l.pos] == '\\' {
+				l.pos++
+			}
+			l.pos++
+		}
+		if l.pos >= len(l.src) || l.src[l.pos] != '"' {
+			return graphqlToken{}, fmt.Errorf("syntax error at position %d: unterminated string", start)
+		}
+		l.pos++
+		var value string
+		err := json.Unmarshal([]byte(l.src[start:l.pos]), &value)
+		if err != nil {
+			return graphqlToken{}, fmt.Errorf("syntax error at position %d: invalid string: %v", start, err)
+		}
+		return graphqlToken{kind: graphqlTokenString, value: value, pos: start}, nil
+	default:
+		return graphqlToken{}, fmt.Errorf("syntax error at position %d: unexpected character %q", start, c)
+	}
+}
+
+func isGraphQLNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,308 @@
+package rexplorer
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/rivine/rivine/types"
+)
+
+// graphqlWallet is the value of a Wallet object of the GraphQL schema.
+type graphqlWallet struct {
+	address types.UnlockHash
+	wallet  Wallet
+}
+
+// newGraphQLSchema creates the query type of the GraphQL schema exposing the data stored in the given database:
+//
+//	type Query {
+//		stats: NetworkStats
+//		wallet(address: String!): Wallet
+//		output(id: String!): Output
+//		transaction(id: String!): Transaction
+//	}
+//	type Wallet {
+//		address: String
+//		multisigWallets: [Wallet]  # the multisig wallets this wallet is an owner of
+//		owners: [Wallet]           # the owners of this wallet, if it is a multisig wallet
+//		lockedOutputs(first: Int, after: String): WalletLockedOutputPage  # a page of the locked outputs, see WalletLockedOutputPage
+//		...                        # the fields of the wallet, see Wallet
+//	}
+//	type Output {
+//		wallet: Wallet             # the wallet owning the output
+//		transaction: Transaction   # the transaction creating the output, if any
+//		spendTransaction: Transaction
+//		...                        # the fields of the output, see CoinOutputInfo
+//	}
+//	type Transaction {
+//		coinInputs: [CoinInput]
+//		coinOutputs: [Output]
+//		...                        # the remaining fields of the transaction, see TransactionRecord
+//	}
+//	type CoinInput {
+//		output: Output             # the spent output
+//		wallet: Wallet             # the wallet owning the spent output
+//		...                        # the remaining fields of the input, see TransactionRecordCoinInput
+//	}
+//
+// Unknown wallets, outputs and transactions resolve to null, except for the wallets listed by another wallet,
+// which resolve to an empty wallet. Transactions are only defined by databases implementing TransactionDatabase.
+// A new schema is to be created for every query, as the network stats are only read once per schema.
+func newGraphQLSchema(db Database) *graphqlObject {
+	var (
+		stats       *NetworkStats
+		getNetStats = func() (NetworkStats, error) {
+			if stats == nil {
+				s, err := db.GetNetworkStats()
+				if err != nil {
+					return NetworkStats{}, fmt.Errorf("failed to get network stats: %v", err)
+				}
+				stats = &s
+			}
+			return *stats, nil
+		}
+	)
+	getWallet := func(address types.UnlockHash) (*graphqlWallet, error) {
+		// the unlock horizons of the locked balance are computed as of the current network time
+		stats, err := getNetStats()
+		if err != nil {
+			return nil, err
+		}
+		wallet, err := getWalletDetails(db, address, stats.Timestamp)
+		if err == ErrNotFound {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get wallet %s: %v", address.String(), err)
+		}
+		return &graphqlWallet{address: address, wallet: wallet}, nil
+	}
+	getWallets := func(addresses []types.UnlockHash) ([]*graphqlWallet, error) {
+		wallets := make([]*graphqlWallet, 0, len(addresses))
+		for _, address := range addresses {
+			wallet, err := getWallet(address)
+			if err != nil {
+				return nil, err
+			}
+			if wallet == nil {
+				wallet = &graphqlWallet{address: address}
+			}
+			wallets = append(wallets, wallet)
+		}
+		return wallets, nil
+	}
+	getCoinOutput := func(id types.CoinOutputID) (*CoinOutputInfo, error) {
+		info, err := getCoinOutputDetails(db, id)
+		if err == ErrNotFound {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get coin output %s: %v", id.String(), err)
+		}
+		return &info, nil
+	}
+	getTransaction := func(id *types.TransactionID) (*TransactionRecord, error) {
+		tdb, ok := db.(TransactionDatabase)
+		if !ok {
+			return nil, errors.New("the database does not support transaction records")
+		}
+		if id == nil {
+			return nil, nil
+		}
+		record, err := tdb.GetTransactionRecord(*id)
+		if err == ErrNotFound {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get transaction %s: %v", id.String(), err)
+		}
+		return &record, nil
+	}
+
+	wallet := &graphqlObject{
+		name: "Wallet",
+		json: func(parent interface{}) interface{} {
+			return parent.(*graphqlWallet).wallet
+		},
+	}
+	output := &graphqlObject{
+		name: "Output",
+		json: func(parent interface{}) interface{} {
+			return parent
+		},
+	}
+	transaction := &graphqlObject{
+		name: "Transaction",
+		json: func(parent interface{}) interface{} {
+			return parent
+		},
+	}
+	coinInput := &graphqlObject{
+		name: "CoinInput",
+		json: func(parent interface{}) interface{} {
+			return parent
+		},
+	}
+
+	wallet.fields = map[string]*graphqlField{
+		"address": {
+			resolve: func(parent interface{}, _ map[string]string) (interface{}, error) {
+				return parent.(*graphqlWallet).address, nil
+			},
+		},
+		"multisigWallets": {
+			object: wallet,
+			resolve: func(parent interface{}, _ map[string]string) (interface{}, error) {
+				return getWallets(parent.(*graphqlWallet).wallet.MultiSignAddresses)
+			},
+		},
+		"owners": {
+			object: wallet,
+			resolve: func(parent interface{}, _ map[string]string) (interface{}, error) {
+				return getWallets(parent.(*graphqlWallet).wallet.MultiSignData.Owners)
+			},
+		},
+		"lockedOutputs": {
+			optionalArgs: []string{"first", "after"},
+			resolve: func(parent interface{}, args map[string]string) (interface{}, error) {
+				address := parent.(*graphqlWallet).address
+				var limit int
+				if str, ok := args["first"]; ok {
+					var err error
+					limit, err = strconv.Atoi(str)
+					if err != nil || limit <= 0 {
+						return nil, fmt.Errorf("invalid first %q: expected a positive integer", str)
+					}
+				}
+				cursor, err := parseWalletOutputsCursor(args["after"])
+				if err != nil {
+					return nil, err
+				}
+				page, err := getWalletLockedOutputs(db, address, cursor, limit)
+				if err == ErrNotFound {
+					return WalletLockedOutputPage{Outputs: []WalletLockedOutputEntry{}}, nil
+				}
+				if err != nil {
+					return nil, fmt.Errorf("failed to get locked outputs of wallet %s: %v", address.String(), err)
+				}
+				return page, nil
+			},
+		},
+	}
+	output.fields = map[string]*graphqlField{
+		"wallet": {
+			object: wallet,
+			resolve: func(parent interface{}, _ map[string]string) (interface{}, error) {
+				return getWallet(parent.(*CoinOutputInfo).UnlockHash)
+			},
+		},
+		"transaction": {
+			object: transaction,
+			resolve: func(parent interface{}, _ map[string]string) (interface{}, error) {
+				provenance := parent.(*CoinOutputInfo).Provenance
+				if provenance == nil {
+					return getTransaction(nil)
+				}
+				return getTransaction(provenance.TransactionID)
+			},
+		},
+		"spendTransaction": {
+			object: transaction,
+			resolve: func(parent interface{}, _ map[string]string) (interface{}, error) {
+				provenance := parent.(*CoinOutputInfo).Provenance
+				if provenance == nil {
+					return getTransaction(nil)
+				}
+				return getTransaction(provenance.SpendTransactionID)
+			},
+		},
+	}
+	transaction.fields = map[string]*graphqlField{
+		"coinInputs": {
+			object: coinInput,
+			resolve: func(parent interface{}, _ map[string]string) (interface{}, error) {
+				return parent.(*TransactionRecord).CoinInputs, nil
+			},
+		},
+		"coinOutputs": {
+			object: output,
+			resolve: func(parent interface{}, _ map[string]string) (interface{}, error) {
+				record := parent.(*TransactionRecord)
+				outputs := make([]*CoinOutputInfo, 0, len(record.CoinOutputs))
+				for _, co := range record.CoinOutputs {
+					info, err := getCoinOutput(co.ID)
+					if err != nil {
+						return nil, err
+					}
+					if info == nil {
+						return nil, fmt.Errorf("coin output %s of transaction %s not found", co.ID.String(), record.ID.String())
+					}
+					outputs = append(outputs, info)
+				}
+				return outputs, nil
+			},
+		},
+	}
+	coinInput.fields = map[string]*graphqlField{
+		"output": {
+			object: output,
+			resolve: func(parent interface{}, _ map[string]string) (interface{}, error) {
+				return getCoinOutput(parent.(TransactionRecordCoinInput).ParentID)
+			},
+		},
+		"wallet": {
+			object: wallet,
+			resolve: func(parent interface{}, _ map[string]string) (interface{}, error) {
+				return getWallet(parent.(TransactionRecordCoinInput).UnlockHash)
+			},
+		},
+	}
+
+	return &graphqlObject{
+		name: "Query",
+		fields: map[string]*graphqlField{
+			"stats": {
+				resolve: func(interface{}, map[string]string) (interface{}, error) {
+					return getNetStats()
+				},
+			},
+			"wallet": {
+				args:   []string{"address"},
+				object: wallet,
+				resolve: func(_ interface{}, args map[string]string) (interface{}, error) {
+					var address types.UnlockHash
+					err := address.LoadString(args["address"])
+					if err != nil {
+						return nil, fmt.Errorf("invalid address %q: %v", args["address"], err)
+					}
+					return getWallet(address)
+				},
+			},
+			"output": {
+				args:   []string{"id"},
+				object: output,
+				resolve: func(_ interface{}, args map[string]string) (interface{}, error) {
+					var id types.CoinOutputID
+					err := id.LoadString(args["id"])
+					if err != nil {
+						return nil, fmt.Errorf("invalid coin output ID %q: %v", args["id"], err)
+					}
+					return getCoinOutput(id)
+				},
+			},
+			"transaction": {
+				args:   []string{"id"},
+				object: transaction,
+				resolve: func(_ interface{}, args map[string]string) (interface{}, error) {
+					var id types.TransactionID
+					err := id.LoadString(args["id"])
+					if err != nil {
+						return nil, fmt.Errorf("invalid transaction ID %q: %v", args["id"], err)
+					}
+					return getTransaction(&id)
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,662 @@
+package rexplorer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rivine/rivine/types"
+)
+
+// GRPCService serves the data stored in a Database, as well as the events published by an EventFeed,
+// as the rexplorer.Explorer gRPC service defined in rexplorer.proto, such that typed clients can be generated
+// for any language supported by gRPC, rather than consumers having to read the database directly.
+// It implements gRPC over HTTP/2 itself, supporting unary and server streaming methods,
+// without compression, as no other methods are defined.
+//
+// Once API keys are required (see RequireAPIKeys), every call has to pass a key as bearer token
+// using the authorization metadata, granting APIScopeStats for GetStats, and APIScopeWallets for all other methods.
+// Calls exceeding the rate of their client (see LimitRate) fail with status RESOURCE_EXHAUSTED.
+type GRPCService struct {
+	db      Database
+	mut     sync.Locker
+	feed    *EventFeed
+	keys    *APIKeys
+	limiter *RateLimiter
+}
+
+// grpcMaxMessageSize is the maximum size of a message received from a client.
+const grpcMaxMessageSize = 64 * 1024
+
+// The gRPC status codes returned by the service.
+const (
+	grpcStatusOK                = 0
+	grpcStatusInvalidArgument   = 3
+	grpcStatusNotFound          = 5
+	grpcStatusPermissionDenied  = 7
+	grpcStatusResourceExhausted = 8
+	grpcStatusUnimplemented     = 12
+	grpcStatusInternal          = 13
+	grpcStatusUnavailable       = 14
+	grpcStatusUnauthenticated   = 16
+)
+
+// grpcError is an error returned by a method of the GRPCService, with the status code to return it with.
+type grpcError struct {
+	code    int
+	message string
+}
+
+func grpcErrorf(code int, format string, args ...interface{}) error {
+	return &grpcError{code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// Error implements error.Error
+func (err *grpcError) Error() string {
+	return err.message
+}
+
+// NewGRPCService creates a GRPCService serving the data stored in the given database, as well as the events published by the given feed.
+// The given locker (if not nil) is held while reading the database,
+// such that the service only observes the data stored by entire consensus changes.
+// Events can't be streamed if no feed is given.
+func NewGRPCService(db Database, mut sync.Locker, feed *EventFeed) *GRPCService {
+	return &GRPCService{
+		db:   db,
+		mut:  mut,
+		feed: feed,
+	}
+}
+
+// GRPCService creates a GRPCService serving the data stored and the events published by this explorer,
+// reading the database in between the consensus changes it processes.
+func (explorer *Explorer) GRPCService() *GRPCService {
+	return NewGRPCService(explorer.db, &explorer.mut, explorer.feed)
+}
+
+// RequireAPIKeys requires every call to pass one of the given keys, granting the scope of the called method.
+func (svc *GRPCService) RequireAPIKeys(keys *APIKeys) {
+	svc.keys = keys
+}
+
+// LimitRate limits the rate of the calls of every client using the given limiter, which can be shared with an API.
+func (svc *GRPCService) LimitRate(limiter *RateLimiter) {
+	svc.limiter = limiter
+}
+
+// ServeHTTP implements http.Handler.ServeHTTP,
+// serving a single gRPC call, of which the status is returned in the trailers of the response.
+func (svc *GRPCService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.ProtoMajor != 2 {
+		http.Error(w, "gRPC requires HTTP/2", http.StatusHTTPVersionNotSupported)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	if ct := r.Header.Get("Content-Type"); ct != "application/grpc" &&
+		!strings.HasPrefix(ct, "application/grpc+proto") && !strings.HasPrefix(ct, "application/grpc;") {
+		http.Error(w, fmt.Sprintf("unsupported content type %q", ct), http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	w.WriteHeader(http.StatusOK)
+
+	err := svc.authorize(r)
+	if err == nil {
+		switch r.URL.Path {
+		case "/rexplorer.Explorer/GetStats":
+			err = svc.unary(w, r, svc.getStats)
+		case "/rexplorer.Explorer/GetWallet":
+			err = svc.unary(w, r, svc.getWallet)
+		case "/rexplorer.Explorer/GetWalletLockedOutputs":
+			err = svc.unary(w, r, svc.getWalletLockedOutputs)
+		case "/rexplorer.Explorer/GetOutput":
+			err = svc.unary(w, r, svc.getCoinOutput)
+		case "/rexplorer.Explorer/StreamEvents":
+			err = svc.streamEvents(w, r)
+		default:
+			err = grpcErrorf(grpcStatusUnimplemented, "unknown method %s", r.URL.Path)
+		}
+	}
+	code, message := grpcStatusOK, ""
+	if err != nil {
+		code, message = grpcStatusInternal, err.Error()
+		if gerr, ok := err.(*grpcError); ok {
+			code = gerr.code
+		}
+	}
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	if message != "" {
+		w.Header().Set("Grpc-Message", grpcPercentEncode(message))
+	}
+}
+
+// authorize authorizes the given call for the scope of the called method (see APIKeys),
+// unless the client exceeded its rate.
+func (svc *GRPCService) authorize(r *http.Request) error {
+	if !svc.limiter.allowRequest(r, svc.keys) {
+		return grpcErrorf(grpcStatusResourceExhausted, "rate limit exceeded")
+	}
+	scope := APIScopeWallets
+	if r.URL.Path == "/rexplorer.Explorer/GetStats" {
+		scope = APIScopeStats
+	}
+	switch status, err := svc.keys.authorizeRequest(r, scope); {
+	case err == nil:
+		return nil
+	case status == http.StatusUnauthorized:
+		return grpcErrorf(grpcStatusUnauthenticated, "%v", err)
+	default:
+		return grpcErrorf(grpcStatusPermissionDenied, "%v", err)
+	}
+}
+
+// unary serves a unary method, reading the single request message of the call and writing the response message.
+func (svc *GRPCService) unary(w http.ResponseWriter, r *http.Request, method func([]byte) (*protoEncoder, error)) error {
+	request, err := readGRPCMessage(r.Body)
+	if err != nil {
+		return err
+	}
+	svc.lock()
+	response, err := method(request)
+	svc.unlock()
+	if err != nil {
+		return err
+	}
+	return writeGRPCMessage(w, response)
+}
+
+func (svc *GRPCService) getStats([]byte) (*protoEncoder, error) {
+	stats, err := svc.db.GetNetworkStats()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get network stats: %v", err)
+	}
+	return protoNetworkStats(stats), nil
+}
+
+func (svc *GRPCService) getWallet(request []byte) (*protoEncoder, error) {
+	var address types.UnlockHash
+	str, err := decodeProtoString(request, 1)
+	if err != nil {
+		return nil, grpcErrorf(grpcStatusInvalidArgument, "invalid request: %v", err)
+	}
+	err = address.LoadString(str)
+	if err != nil {
+		return nil, grpcErrorf(grpcStatusInvalidArgument, "invalid address %q: %v", str, err)
+	}
+	wallet, err := svc.db.GetWallet(address)
+	switch err {
+	case nil:
+		return protoWallet(address, wallet), nil
+	case ErrNotFound:
+		return nil, grpcErrorf(grpcStatusNotFound, "wallet %s not found", address.String())
+	default:
+		return nil, fmt.Errorf("failed to get wallet %s: %v", address.String(), err)
+	}
+}
+
+func (svc *GRPCService) getWalletLockedOutputs(request []byte) (*protoEncoder, error) {
+	var address types.UnlockHash
+	str, err := decodeProtoString(request, 1)
+	if err != nil {
+		return nil, grpcErrorf(grpcStatusInvalidArgument, "invalid request: %v", err)
+	}
+	err = address.LoadString(str)
+	if err != nil {
+		return nil, grpcErrorf(grpcStatusInvalidArgument, "invalid address %q: %v", str, err)
+	}
+	str, err = decodeProtoString(request, 2)
+	if err != nil {
+		return nil, grpcErrorf(grpcStatusInvalidArgument, "invalid request: %v", err)
+	}
+	cursor, err := parseWalletOutputsCursor(str)
+	if err != nil {
+		return nil, grpcErrorf(grpcStatusInvalidArgument, "%v", err)
+	}
+	limit, err := decodeProtoUint64(request, 3)
+	if err != nil {
+		return nil, grpcErrorf(grpcStatusInvalidArgument, "invalid request: %v", err)
+	}
+	if limit > MaxWalletOutputsPageSize {
+		limit = MaxWalletOutputsPageSize
+	}
+	page, err := getWalletLockedOutputs(svc.db, address, cursor, int(limit))
+	switch err {
+	case nil:
+		e := new(protoEncoder)
+		for _, entry := range page.Outputs {
+			e.Message(1, protoLockedOutput(entry.ID.String(), entry.WalletLockedOutput))
+		}
+		if page.NextCursor != nil {
+			e.String(2, page.NextCursor.String())
+		}
+		return e, nil
+	case ErrNotFound:
+		return nil, grpcErrorf(grpcStatusNotFound, "wallet %s not found", address.String())
+	default:
+		return nil, fmt.Errorf("failed to get locked outputs of wallet %s: %v", address.String(), err)
+	}
+}
+
+func (svc *GRPCService) getCoinOutput(request []byte) (*protoEncoder, error) {
+	var id types.CoinOutputID
+	str, err := decodeProtoString(request, 1)
+	if err != nil {
+		return nil, grpcErrorf(grpcStatusInvalidArgument, "invalid request: %v", err)
+	}
+	err = id.LoadString(str)
+	if err != nil {
+		return nil, grpcErrorf(grpcStatusInvalidArgument, "invalid coin output ID %q: %v", str, err)
+	}
+	info, err := svc.db.GetCoinOutput(id)
+	switch err {
+	case nil:
+		return protoCoinOutput(info), nil
+	case ErrNotFound:
+		return nil, grpcErrorf(grpcStatusNotFound, "coin output %s not found", id.String())
+	default:
+		return nil, fmt.Errorf("failed to get coin output %s: %v", id.String(), err)
+	}
+}
+
+// streamEvents streams all events published from now on, together with the balance of the requested addresses:
+// initially, and afterwards each time that balance changed, the same as the events streamed by the API.
+// The call ends (with status UNAVAILABLE) when the client doesn't keep up with the published events,
+// as well as when the explorer is closed.
+func (svc *GRPCService) streamEvents(w http.ResponseWriter, r *http.Request) error {
+	if svc.feed == nil {
+		return grpcErrorf(grpcStatusUnimplemented, "events are not published")
+	}
+	request, err := readGRPCMessage(r.Body)
+	if err != nil {
+		return err
+	}
+	var addresses []types.UnlockHash
+	strs, err := decodeProtoStrings(request, 1)
+	if err != nil {
+		return grpcErrorf(grpcStatusInvalidArgument, "invalid request: %v", err)
+	}
+	for _, str := range strs {
+		var address types.UnlockHash
+		err = address.LoadString(str)
+		if err != nil {
+			return grpcErrorf(grpcStatusInvalidArgument, "invalid address %q: %v", str, err)
+		}
+		addresses = append(addresses, address)
+	}
+	sub := svc.feed.Subscribe()
+	defer sub.Close()
+	tracker := newBalanceTracker(svc.db, svc.mut)
+	initial, err := tracker.subscribe(addresses)
+	if err == errTooManyBalanceSubscriptions {
+		return grpcErrorf(grpcStatusInvalidArgument, "%v", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	send := func(events []Event) error {
+		for _, event := range events {
+			msg, err := protoEvent(event)
+			if err != nil {
+				return err
+			}
+			err = writeGRPCMessage(w, msg)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	err = send(initial)
+	if err != nil {
+		return err
+	}
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				if sub.Dropped() {
+					return grpcErrorf(grpcStatusUnavailable, "too slow to receive the events")
+				}
+				return grpcErrorf(grpcStatusUnavailable, "explorer is closing")
+			}
+			err = send([]Event{event})
+			if err != nil {
+				return err
+			}
+			// balances only change when blocks are applied or reverted,
+			// and are only compared once all events published so far are sent
+			if len(sub.Events()) == 0 {
+				events, err := tracker.update()
+				if err != nil {
+					log.Println("[ERROR] failed to track the subscribed balances of a gRPC client:", err)
+					return fmt.Errorf("failed to track the subscribed balances")
+				}
+				err = send(events)
+				if err != nil {
+					return err
+				}
+			}
+		case <-r.Context().Done():
+			return r.Context().Err()
+		}
+	}
+}
+
+func (svc *GRPCService) lock() {
+	if svc.mut != nil {
+		svc.mut.Lock()
+	}
+}
+
+func (svc *GRPCService) unlock() {
+	if svc.mut != nil {
+		svc.mut.Unlock()
+	}
+}
+
+// readGRPCMessage reads a single length-prefixed message.
+func readGRPCMessage(r io.Reader) ([]byte, error) {
+	var prefix [5]byte
+	_, err := io.ReadFull(r, prefix[:])
+	if err != nil {
+		return nil, grpcErrorf(grpcStatusInvalidArgument, "failed to read request message: %v", err)
+	}
+	if prefix[0] != 0 {
+		return nil, grpcErrorf(grpcStatusUnimplemented, "compressed messages are not supported")
+	}
+	length := binary.BigEndian.Uint32(prefix[1:])
+	if length > grpcMaxMessageSize {
+		return nil, grpcErrorf(grpcStatusResourceExhausted, "request message exceeds %d bytes", grpcMaxMessageSize)
+	}
+	msg := make([]byte, length)
+	_, err = io.ReadFull(r, msg)
+	if err != nil {
+		return nil, grpcErrorf(grpcStatusInvalidArgument, "failed to read request message: %v", err)
+	}
+	return msg, nil
+}
+
+// writeGRPCMessage writes (and flushes) a single length-prefixed message.
+func writeGRPCMessage(w http.ResponseWriter, msg *protoEncoder) error {
+	b := msg.Encoded()
+	prefix := [5]byte{}
+	binary.BigEndian.PutUint32(prefix[1:], uint32(len(b)))
+	_, err := w.Write(prefix[:])
+	if err == nil {
+		_, err = w.Write(b)
+	}
+	if err != nil {
+		return err
+	}
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// grpcPercentEncode percent-encodes a status message, as required for the grpc-message trailer.
+func grpcPercentEncode(message string) string {
+	var sb strings.Builder
+	for i := 0; i < len(message); i++ {
+		c := message[i]
+		if c < 0x20 || c > 0x7E || c == '%' {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		} else {
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// decodeProtoString decodes the (last) value of the given string field of a message.
+func decodeProtoString(msg []byte, field int) (string, error) {
+	strs, err := decodeProtoStrings(msg, field)
+	if err != nil || len(strs) == 0 {
+		return "", err
+	}
+	return strs[len(strs)-1], nil
+}
+
+// decodeProtoUint64 decodes the (last) value of the given varint field of a message, 0 if it isn't defined.
+func decodeProtoUint64(msg []byte, field int) (uint64, error) {
+	var v uint64
+	d := protoDecoder{buf: msg}
+	for {
+		f, wireType, ok, err := d.Next()
+		if err != nil || !ok {
+			return v, err
+		}
+		if f != field || wireType != protoWireVarint {
+			err = d.Skip(wireType)
+			if err != nil {
+				return 0, err
+			}
+			continue
+		}
+		v, err = d.Varint()
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// decodeProtoStrings decodes all values of the given (repeated) string field of a message, ignoring all other fields.
+func decodeProtoStrings(msg []byte, field int) ([]string, error) {
+	var strs []string
+	d := protoDecoder{buf: msg}
+	for {
+		f, wireType, ok, err := d.Next()
+		if err != nil || !ok {
+			return strs, err
+		}
+		if f != field || wireType != protoWireBytes {
+			err = d.Skip(wireType)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		b, err := d.LengthDelimited()
+		if err != nil {
+			return nil, err
+		}
+		strs = append(strs, string(b))
+	}
+}
+
+// protoNetworkStats encodes the given stats as a rexplorer.NetworkStats message.
+func protoNetworkStats(stats NetworkStats) *protoEncoder {
+	e := new(protoEncoder)
+	e.Uint64(1, uint64(stats.Timestamp))
+	e.Uint64(2, uint64(stats.BlockHeight))
+	e.Uint64(3, stats.TransactionCount)
+	e.Uint64(4, stats.ValueTransactionCount)
+	e.Uint64(5, stats.CointOutputCount)
+	e.Uint64(6, stats.LockedCointOutputCount)
+	e.Uint64(7, stats.CointInputCount)
+	e.Uint64(8, stats.MinerPayoutCount)
+	e.Uint64(9, stats.TransactionFeeCount)
+	e.String(10, stats.MinerPayouts.String())
+	e.String(11, stats.TransactionFees.String())
+	e.String(12, stats.Coins.String())
+	e.String(13, stats.LockedCoins.String())
+	e.Uint64(14, stats.MaturityLockedCoinOutputCount)
+	e.String(15, stats.MaturityLockedCoins.String())
+	e.Uint64(16, stats.MinerFeeCount)
+	e.String(17, stats.MinerFees.String())
+	e.Uint64(18, stats.BurnedCoinOutputCount)
+	e.String(19, stats.BurnedCoins.String())
+	cts := new(protoEncoder)
+	cts.Uint64(1, stats.ConditionTypes.Nil)
+	cts.Uint64(2, stats.ConditionTypes.UnlockHash)
+	cts.Uint64(3, stats.ConditionTypes.AtomicSwap)
+	cts.Uint64(4, stats.ConditionTypes.TimeLock)
+	cts.Uint64(5, stats.ConditionTypes.MultiSignature)
+	cts.Uint64(6, stats.ConditionTypes.Unknown)
+	e.Message(20, cts)
+	e.String(21, stats.ValueTransferred.String())
+	e.Double(22, stats.Velocity)
+	e.String(23, stats.TimeLockedCoins.String())
+	e.String(24, stats.HeightLockedCoins.String())
+	e.String(25, stats.GenesisCoins.String())
+	supply := new(protoEncoder)
+	supply.String(1, stats.Supply.Liquid.String())
+	supply.String(2, stats.Supply.TimeLocked.String())
+	supply.String(3, stats.Supply.HeightLocked.String())
+	supply.String(4, stats.Supply.Burned.String())
+	supply.String(5, stats.Supply.Genesis.String())
+	e.Message(26, supply)
+	e.String(27, stats.Difficulty.Big().String())
+	return e
+}
+
+// protoWallet encodes the given wallet of the given address as a rexplorer.Wallet message.
+func protoWallet(address types.UnlockHash, wallet Wallet) *protoEncoder {
+	e := new(protoEncoder)
+	e.String(1, address.String())
+	e.Message(2, protoWalletBalance(wallet.Balance))
+	e.Strings(3, unlockHashStrings(wallet.MultiSignAddresses))
+	if len(wallet.MultiSignData.Owners) > 0 {
+		multisig := new(protoEncoder)
+		multisig.Strings(1, unlockHashStrings(wallet.MultiSignData.Owners))
+		multisig.Uint64(2, wallet.MultiSignData.SignaturesRequired)
+		e.Message(4, multisig)
+	}
+	blockStakes := new(protoEncoder)
+	blockStakes.String(1, wallet.BlockStakes.Unlocked.String())
+	blockStakes.String(2, wallet.BlockStakes.Locked.String())
+	e.Message(5, blockStakes)
+	return e
+}
+
+// protoWalletBalance encodes the given balance as a rexplorer.WalletBalance message,
+// the locked outputs being sorted by ID.
+func protoWalletBalance(balance WalletBalance) *protoEncoder {
+	e := new(protoEncoder)
+	e.String(1, balance.Unlocked.String())
+	e.String(2, balance.Locked.Total.String())
+	ids := make([]string, 0, len(balance.Locked.Outputs))
+	outputs := make(map[string]WalletLockedOutput, len(balance.Locked.Outputs))
+	for id, output := range balance.Locked.Outputs {
+		ids = append(ids, id.String())
+		outputs[id.String()] = output
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		e.Message(3, protoLockedOutput(id, outputs[id]))
+	}
+	return e
+}
+
+// protoLockedOutput encodes the given locked output as a rexplorer.LockedOutput message.
+func protoLockedOutput(id string, output WalletLockedOutput) *protoEncoder {
+	e := new(protoEncoder)
+	e.String(1, id)
+	e.String(2, output.Amount.String())
+	e.Uint64(3, uint64(output.LockedUntil))
+	e.Bytes(4, output.Description)
+	e.String(5, string(output.Reason))
+	return e
+}
+
+// protoCoinOutput encodes the given coin output as a rexplorer.CoinOutput message.
+func protoCoinOutput(info CoinOutputInfo) *protoEncoder {
+	e := new(protoEncoder)
+	e.String(1, info.ID.String())
+	e.String(2, info.UnlockHash.String())
+	e.String(3, info.Value.String())
+	e.Uint64(4, uint64(info.State))
+	e.Uint64(5, uint64(info.LockType))
+	e.Uint64(6, uint64(info.LockValue))
+	e.Bytes(7, info.Description)
+	e.Bytes(8, info.RawCondition)
+	e.Bool(9, info.Burned)
+	e.Bool(10, info.UnknownCondition)
+	return e
+}
+
+// protoEvent encodes the given event as a rexplorer.Event message.
+func protoEvent(event Event) (*protoEncoder, error) {
+	e := new(protoEncoder)
+	data := new(protoEncoder)
+	switch d := event.Data.(type) {
+	case BlockEventData:
+		data.Uint64(1, uint64(d.Height))
+		data.String(2, d.ID.String())
+		data.Uint64(3, uint64(d.Timestamp))
+		data.Uint64(4, uint64(d.TransactionCount))
+		switch event.Type {
+		case EventTypeBlockApplied:
+			e.Message(1, data)
+		case EventTypeBlockReverted:
+			e.Message(2, data)
+		default:
+			return nil, fmt.Errorf("unexpected data for %s event", event.Type)
+		}
+	case OutputCreatedEventData:
+		data.String(1, d.ID.String())
+		data.String(2, d.Address.String())
+		data.String(3, d.Value.String())
+		data.Bool(4, d.Locked)
+		if d.TransactionID != nil {
+			data.String(5, d.TransactionID.String())
+		}
+		data.Uint64(6, uint64(d.Height))
+		e.Message(5, data)
+	case OutputSpentEventData:
+		data.String(1, d.ID.String())
+		data.String(2, d.Address.String())
+		data.String(3, d.Value.String())
+		data.String(4, d.TransactionID.String())
+		data.Uint64(5, uint64(d.Height))
+		e.Message(3, data)
+	case BalanceEventData:
+		data.String(1, d.Address.String())
+		data.Uint64(2, uint64(d.Height))
+		data.Message(3, protoWalletBalance(d.Balance))
+		e.Message(4, data)
+	default:
+		return nil, fmt.Errorf("cannot encode %s event", event.Type)
+	}
+	return e, nil
+}
+
+func unlockHashStrings(uhs []types.UnlockHash) []string {
+	strs := make([]string, 0, len(uhs))
+	for _, uh := range uhs {
+		strs = append(strs, uh.String())
+	}
+	return strs
+}
+
+// ServeGRPC starts serving the given gRPC service on the given (TCP) address in the background,
+// using unencrypted HTTP/2 (h2c), returning the server such that it can be closed once no longer required.
+// An error is only returned if the address cannot be listened on.
+func ServeGRPC(addr string, svc *GRPCService) (*http.Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on gRPC address %s: %v", addr, err)
+	}
+	server := &http.Server{Handler: svc, Protocols: new(http.Protocols)}
+	server.Protocols.SetUnencryptedHTTP2(true)
+	go func() {
+		err := server.Serve(ln)
+		if err != nil && err != http.ErrServerClosed {
+			log.Println("[ERROR] gRPC server stopped unexpectedly:", err)
+		}
+	}()
+	return server, nil
+}
@@ -0,0 +1,167 @@
+package rexplorer
+
+import (
+	"math"
+
+	"github.com/rivine/rivine/modules"
+	"github.com/rivine/rivine/types"
+)
+
+// ChainHealth collects a simple health score of the chain,
+// as well as the metrics that score is aggregated from,
+// in a format suitable to be exposed as-is on public status pages.
+//
+// It is recomputed for every consensus change applied by the explorer.
+// The mempool depth is not taken into account, as rexplorer doesn't run a transaction pool.
+type ChainHealth struct {
+	Timestamp   types.Timestamp   `json:"timestamp"`
+	BlockHeight types.BlockHeight `json:"blockHeight"`
+
+	// Score is a value in the range [0,100], where 100 is perfectly healthy.
+	Score  uint8        `json:"score"`
+	Status HealthStatus `json:"status"`
+
+	// block time statistics (in seconds) over the last (up to) healthBlockWindow blocks
+	BlockTimeTarget   uint64  `json:"blockTimeTarget"`
+	BlockTimeMean     float64 `json:"blockTimeMean"`
+	BlockTimeVariance float64 `json:"blockTimeVariance"`
+	BlockTimeStdDev   float64 `json:"blockTimeStdDev"`
+
+	// reorgs seen within the last healthBlockWindow blocks
+	ReorgCount         uint64 `json:"reorgCount"`
+	RevertedBlockCount uint64 `json:"revertedBlockCount"`
+
+	PeerCount int `json:"peerCount"`
+}
+
+// HealthStatus defines the human-readable status of the chain,
+// as derived from the health score.
+type HealthStatus string
+
+// The different health statuses a chain can be in.
+const (
+	HealthStatusHealthy   HealthStatus = "healthy"
+	HealthStatusDegraded  HealthStatus = "degraded"
+	HealthStatusUnhealthy HealthStatus = "unhealthy"
+)
+
+// the amount of most recent blocks the chain health is computed over
+const healthBlockWindow = 144
+
+type (
+	// healthTracker keeps track of the (in-memory) samples required to compute the chain health.
+	// Samples are not persisted, and thus the metrics are only computed over the blocks
+	// seen since the explorer was started.
+	healthTracker struct {
+		blockTimes []types.Timestamp
+		reorgs     []healthReorg
+	}
+	healthReorg struct {
+		height   types.BlockHeight
+		reverted uint64
+	}
+)
+
+// ApplyBlock registers the timestamp of an applied block.
+func (ht *healthTracker) ApplyBlock(block types.Block) {
+	ht.blockTimes = append(ht.blockTimes, block.Timestamp)
+	if len(ht.blockTimes) > healthBlockWindow+1 {
+		ht.blockTimes = ht.blockTimes[1:]
+	}
+}
+
+// RevertBlock unregisters the timestamp of the last applied block.
+func (ht *healthTracker) RevertBlock() {
+	if n := len(ht.blockTimes); n > 0 {
+		ht.blockTimes = ht.blockTimes[:n-1]
+	}
+}
+
+// RegisterReorg registers a reorg (of at least one reverted block) at the given height.
+func (ht *healthTracker) RegisterReorg(height types.BlockHeight, reverted uint64) {
+	ht.reorgs = append(ht.reorgs, healthReorg{
+		height:   height,
+		reverted: reverted,
+	})
+}
+
+// ChainHealth computes the chain health for the given stats,
+// using the samples collected until now.
+func (ht *healthTracker) ChainHealth(stats NetworkStats, blockFrequency types.BlockHeight, peerCount int) ChainHealth {
+	health := ChainHealth{
+		Timestamp:       stats.Timestamp,
+		BlockHeight:     stats.BlockHeight,
+		BlockTimeTarget: uint64(blockFrequency),
+		PeerCount:       peerCount,
+	}
+
+	// drop all reorgs which fell out of our window, and count the others
+	var windowStart types.BlockHeight
+	if stats.BlockHeight > healthBlockWindow {
+		windowStart = stats.BlockHeight - healthBlockWindow
+	}
+	reorgs := ht.reorgs[:0]
+	for _, reorg := range ht.reorgs {
+		if reorg.height < windowStart {
+			continue
+		}
+		reorgs = append(reorgs, reorg)
+		health.ReorgCount++
+		health.RevertedBlockCount += reorg.reverted
+	}
+	ht.reorgs = reorgs
+
+	// compute the mean and variance of the block times
+	if n := len(ht.blockTimes) - 1; n > 0 {
+		deltas := make([]float64, n)
+		var sum float64
+		for i := range deltas {
+			deltas[i] = float64(ht.blockTimes[i+1]) - float64(ht.blockTimes[i])
+			sum += deltas[i]
+		}
+		health.BlockTimeMean = sum / float64(n)
+		for _, delta := range deltas {
+			health.BlockTimeVariance += (delta - health.BlockTimeMean) * (delta - health.BlockTimeMean)
+		}
+		health.BlockTimeVariance /= float64(n)
+		health.BlockTimeStdDev = math.Sqrt(health.BlockTimeVariance)
+	}
+
+	// compute the score, by subtracting penalties for each metric from a perfect score
+	penalty := 0.0
+	if target := float64(blockFrequency); target > 0 && len(ht.blockTimes) > 1 {
+		// up to 30 points for a mean block time which deviates from the target
+		penalty += math.Min(30, math.Abs(health.BlockTimeMean-target)/target*30)
+		// up to 30 points for a block time which varies a lot
+		penalty += math.Min(30, health.BlockTimeStdDev/target*15)
+	}
+	// up to 20 points for reorgs
+	penalty += math.Min(20, float64(health.ReorgCount)*5)
+	// up to 20 points for being (almost) disconnected from the network
+	switch {
+	case peerCount == 0:
+		penalty += 20
+	case peerCount < 3:
+		penalty += 10
+	}
+	health.Score = uint8(math.Max(0, 100-math.Ceil(penalty)))
+
+	switch {
+	case health.Score >= 80:
+		health.Status = HealthStatusHealthy
+	case health.Score >= 50:
+		health.Status = HealthStatusDegraded
+	default:
+		health.Status = HealthStatusUnhealthy
+	}
+	return health
+}
+
+// peerCount returns the amount of peers the gateway is currently connected to,
+// returning 0 in case no gateway is defined.
+func peerCount(gateway modules.Gateway) int {
+	if gateway == nil {
+		return 0
+	}
+	return len(gateway.Peers())
+}
@@ -0,0 +1,222 @@
+package rexplorer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rivine/rivine/types"
+)
+
+// HookEvent defines a lifecycle point of the explorer at which hooks are invoked.
+type HookEvent string
+
+// All events hooks can be configured for.
+const (
+	// HookEventBlockApplied is sent for every block applied while the consensus set is synced,
+	// once that block has been stored.
+	HookEventBlockApplied HookEvent = "block-applied"
+	// HookEventSyncCompleted is sent each time the consensus set becomes synced.
+	HookEventSyncCompleted HookEvent = "sync-completed"
+	// HookEventVerifyFailed is sent when the startup self-check finds the stored data to be corrupt.
+	HookEventVerifyFailed HookEvent = "verify-failed"
+)
+
+// HookEvents returns all events hooks can be configured for.
+func HookEvents() []HookEvent {
+	return []HookEvent{HookEventBlockApplied, HookEventSyncCompleted, HookEventVerifyFailed}
+}
+
+type (
+	// HookPayload defines the JSON payload every hook is invoked with.
+	HookPayload struct {
+		Event     HookEvent   `json:"event"`
+		Chain     string      `json:"chain"`
+		Network   string      `json:"network"`
+		Timestamp int64       `json:"timestamp"`
+		Data      interface{} `json:"data"`
+	}
+
+	// BlockAppliedHookData defines the data of a HookEventBlockApplied payload.
+	BlockAppliedHookData struct {
+		Height           types.BlockHeight `json:"height"`
+		ID               types.BlockID     `json:"id"`
+		Timestamp        types.Timestamp   `json:"timestamp"`
+		TransactionCount int               `json:"txCount"`
+	}
+
+	// SyncCompletedHookData defines the data of a HookEventSyncCompleted payload.
+	SyncCompletedHookData struct {
+		Height    types.BlockHeight `json:"height"`
+		Timestamp types.Timestamp   `json:"timestamp"`
+	}
+
+	// VerifyFailedHookData defines the data of a HookEventVerifyFailed payload.
+	VerifyFailedHookData struct {
+		Error    string   `json:"error"`
+		Problems []string `json:"problems,omitempty"`
+	}
+)
+
+// Hook defines an external command or HTTP(S) endpoint,
+// invoked with the JSON-encoded HookPayload for each occurrence of its event.
+type Hook struct {
+	Event  HookEvent
+	Target string
+}
+
+// ParseHook parses a hook from its "<event>=<command|URL>" string format.
+func ParseHook(str string) (Hook, error) {
+	parts := strings.SplitN(str, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+		return Hook{}, fmt.Errorf("invalid hook %q: expected format <event>=<command|URL>", str)
+	}
+	hook := Hook{
+		Event:  HookEvent(strings.TrimSpace(parts[0])),
+		Target: strings.TrimSpace(parts[1]),
+	}
+	for _, event := range HookEvents() {
+		if hook.Event == event {
+			return hook, nil
+		}
+	}
+	return Hook{}, fmt.Errorf("invalid hook %q: unknown event %q", str, hook.Event)
+}
+
+// IsHTTP returns true if the hook posts its payload to an HTTP(S) endpoint,
+// rather than running an external command.
+func (hook Hook) IsHTTP() bool {
+	return strings.HasPrefix(hook.Target, "http://") || strings.HasPrefix(hook.Target, "https://")
+}
+
+const (
+	// hookTimeout is the maximum duration a single hook invocation can take
+	hookTimeout = 30 * time.Second
+	// hookQueueSize is the amount of payloads that can be queued,
+	// before payloads are dropped in order not to block the explorer
+	hookQueueSize = 256
+)
+
+// Hooks invokes the configured hooks in the background, in the order their events occur,
+// such that a slow or failing hook never blocks (or breaks) the explorer.
+// Failed invocations are logged and not retried.
+type Hooks struct {
+	hooks  map[HookEvent][]Hook
+	bcInfo types.BlockchainInfo
+	client *http.Client
+
+	queue chan HookPayload
+	done  sync.WaitGroup
+	once  sync.Once
+}
+
+// NewHooks creates the hooks from their "<event>=<command|URL>" string format.
+func NewHooks(hooks []string, bcInfo types.BlockchainInfo) (*Hooks, error) {
+	h := &Hooks{
+		hooks:  make(map[HookEvent][]Hook),
+		bcInfo: bcInfo,
+		client: &http.Client{Timeout: hookTimeout},
+		queue:  make(chan HookPayload, hookQueueSize),
+	}
+	for _, str := range hooks {
+		hook, err := ParseHook(str)
+		if err != nil {
+			return nil, err
+		}
+		h.hooks[hook.Event] = append(h.hooks[hook.Event], hook)
+	}
+	h.done.Add(1)
+	go h.run()
+	return h, nil
+}
+
+// Enabled returns true if at least one hook is configured for the given event.
+// No hooks are enabled for nil Hooks.
+func (h *Hooks) Enabled(event HookEvent) bool {
+	return h != nil && len(h.hooks[event]) > 0
+}
+
+// Fire queues the invocation of all hooks configured for the given event.
+// The payload is dropped (and logged as such) in case the queue is full.
+func (h *Hooks) Fire(event HookEvent, data interface{}) {
+	if !h.Enabled(event) {
+		return
+	}
+	payload := HookPayload{
+		Event:     event,
+		Chain:     h.bcInfo.Name,
+		Network:   h.bcInfo.NetworkName,
+		Timestamp: time.Now().Unix(),
+		Data:      data,
+	}
+	select {
+	case h.queue <- payload:
+	default:
+		log.Printf("[ERROR] hook queue is full, dropping %s event", event)
+	}
+}
+
+// Close the hooks, waiting until all queued payloads have been delivered.
+func (h *Hooks) Close() {
+	if h == nil {
+		return
+	}
+	h.once.Do(func() {
+		close(h.queue)
+		h.done.Wait()
+	})
+}
+
+func (h *Hooks) run() {
+	defer h.done.Done()
+	for payload := range h.queue {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			log.Printf("[ERROR] failed to JSON-encode %s hook payload: %v", payload.Event, err)
+			continue
+		}
+		for _, hook := range h.hooks[payload.Event] {
+			err = h.invoke(hook, b)
+			if err != nil {
+				log.Printf("[ERROR] %s hook %q failed: %v", hook.Event, hook.Target, err)
+			}
+		}
+	}
+}
+
+func (h *Hooks) invoke(hook Hook, payload []byte) error {
+	if hook.IsHTTP() {
+		resp, err := h.client.Post(hook.Target, "application/json", bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("unexpected status: %s", resp.Status)
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	args := strings.Fields(hook.Target)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), "REXPLORER_EVENT="+string(hook.Event))
+	cmd.Stdin = bytes.NewReader(payload)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if len(output) > 0 {
+			return fmt.Errorf("%v: %s", err, bytes.TrimSpace(output))
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package rexplorer
+
+import (
+	"github.com/rivine/rivine/types"
+)
+
+// The unlock horizons of the locked balance of a wallet (see LockHorizons), in seconds.
+const (
+	lockHorizonDay   = 24 * 60 * 60
+	lockHorizonMonth = 30 * lockHorizonDay
+	lockHorizonYear  = 365 * lockHorizonDay
+)
+
+// LockHorizons breaks the locked balance of a wallet down by the time remaining until its outputs unlock,
+// as of the AsOf network time: within a day, within 30 days (but not within a day), within a year
+// (but not within 30 days), or later, such that a vesting schedule can be shown without iterating all locked outputs.
+// The unlock time of an output locked by block height is estimated using the block frequency of the network.
+//
+// The horizons are recomputed whenever the wallet is updated, and are thus only as recent as the AsOf network time.
+// The MongoDB and SQL drivers, which don't store the horizons, compute them whenever the wallet is read instead.
+type LockHorizons struct {
+	AsOf  types.Timestamp `json:"asOf"`
+	Day   types.Currency  `json:"day"`
+	Month types.Currency  `json:"month"`
+	Year  types.Currency  `json:"year"`
+	Later types.Currency  `json:"later"`
+}
+
+// bucket returns the horizon in which an output, locked until the given (estimated) time, unlocks.
+func (lh *LockHorizons) bucket(lockedUntil LockValue) *types.Currency {
+	remaining := int64(lockedUntil) - int64(lh.AsOf)
+	switch {
+	case remaining < lockHorizonDay:
+		return &lh.Day
+	case remaining < lockHorizonMonth:
+		return &lh.Month
+	case remaining < lockHorizonYear:
+		return &lh.Year
+	default:
+		return &lh.Later
+	}
+}
+
+// updateHorizons recomputes the unlock horizons of the locked balance as of the given network time,
+// dropping them if no outputs are locked.
+func (wlb *WalletLockedBalance) updateHorizons(now types.Timestamp) {
+	if len(wlb.Outputs) == 0 {
+		wlb.Horizons = nil
+		return
+	}
+	horizons := LockHorizons{
+		AsOf:  now,
+		Day:   types.ZeroCurrency,
+		Month: types.ZeroCurrency,
+		Year:  types.ZeroCurrency,
+		Later: types.ZeroCurrency,
+	}
+	for _, output := range wlb.Outputs {
+		bucket := horizons.bucket(output.LockedUntil)
+		*bucket = bucket.Add(output.Amount)
+	}
+	wlb.Horizons = &horizons
+}
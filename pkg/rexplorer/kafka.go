@@ -0,0 +1,781 @@
+package rexplorer
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The default topics the events are produced to by a KafkaSink.
+const (
+	DefaultKafkaBlockTopic       = "rexplorer.blocks"
+	DefaultKafkaTransactionTopic = "rexplorer.transactions"
+	DefaultKafkaBalanceTopic     = "rexplorer.balances"
+)
+
+// DefaultKafkaClientID is the client ID a KafkaSink identifies itself with, unless configured otherwise.
+const DefaultKafkaClientID = "rexplorer"
+
+// The headers of the records produced by a KafkaSink.
+const (
+	// KafkaHeaderChangeID is the (hex-encoded) ID of the consensus change the event of a record is part of.
+	KafkaHeaderChangeID = "consensus-change-id"
+	// KafkaHeaderEvent is the type of the event of a record.
+	KafkaHeaderEvent = "event"
+)
+
+// KafkaConfig configures a KafkaSink.
+type KafkaConfig struct {
+	// the host:port addresses of the brokers used to discover the Kafka cluster
+	Brokers []string
+	// the topics the block, transaction and balance events are produced to,
+	// the events of a kind not being produced if its topic is empty
+	BlockTopic       string
+	TransactionTopic string
+	BalanceTopic     string
+	// the client ID sent to the brokers, DefaultKafkaClientID if empty
+	ClientID string
+}
+
+// KafkaSink is a ChangeSink producing the events of every consensus change to Kafka topics,
+// implemented using the Kafka protocol directly (Metadata v4 and Produce v3 requests, v2 record batches):
+//
+//	block-applied, block-reverted: the block topic, keyed by the block height
+//	tx-applied, tx-reverted:       the transaction topic, keyed by the transaction ID
+//	balance-delta:                 the balance topic, keyed by the address
+//
+// Other events are not produced. The value of each record is the JSON-encoded Event,
+// and its headers define the ID of its consensus change (KafkaHeaderChangeID) and the type of its event (KafkaHeaderEvent).
+// Records are assigned to a partition by hashing their key the same way the default partitioner of
+// the Java client does, such that the events of a single block (height), transaction or address are ordered.
+//
+// Records are produced with acks=all, retrying failed produce requests (refreshing the cluster metadata first),
+// and a consensus change is only considered delivered once all its records are acknowledged.
+// As the explorer stores a consensus change only once it is delivered, the ConsensusChangeID is the resumable offset
+// of the sink: a restarted explorer resumes producing the events of the first consensus change not stored yet,
+// meaning the records of the consensus change being processed during a crash can be produced twice,
+// which consumers can detect using the KafkaHeaderChangeID header.
+type KafkaSink struct {
+	cfg KafkaConfig
+
+	// the address of each broker, by node ID, and the open connections to them
+	brokers map[int32]string
+	conns   map[int32]net.Conn
+	// the partitions of each topic, by index, and the node ID of their leader
+	partitions map[string][]kafkaPartition
+
+	correlationID int32
+
+	mut sync.Mutex
+}
+
+type (
+	kafkaPartition struct {
+		ID     int32
+		Leader int32
+	}
+
+	kafkaTopicPartition struct {
+		Topic     string
+		Partition int32
+	}
+
+	kafkaRecord struct {
+		Key     []byte
+		Value   []byte
+		Headers []kafkaHeader
+	}
+
+	kafkaHeader struct {
+		Key   string
+		Value []byte
+	}
+)
+
+var (
+	_ ChangeSink = (*KafkaSink)(nil)
+)
+
+// The API keys and versions of the Kafka requests made by a KafkaSink.
+const (
+	kafkaAPIProduce         = 0
+	kafkaAPIProduceVersion  = 3
+	kafkaAPIMetadata        = 3
+	kafkaAPIMetadataVersion = 4
+)
+
+const (
+	// the maximum (approximate) size of a single record batch, well below the default
+	// maximum message size of the brokers (1MiB), a batch containing at least one record regardless of its size
+	kafkaMaxBatchSize = 512 << 10
+	// the maximum size of a response accepted from a broker
+	kafkaMaxResponseSize = 64 << 20
+	// the timeouts of connecting to a broker, and of a single request
+	kafkaDialTimeout    = 10 * time.Second
+	kafkaRequestTimeout = 30 * time.Second
+	// the time the brokers wait for the replication of the produced records
+	kafkaProduceTimeout = 20 * time.Second
+)
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// NewKafkaSink creates a KafkaSink, fetching the metadata of the configured topics from the brokers,
+// as to fail early should the Kafka cluster not be reachable.
+func NewKafkaSink(cfg KafkaConfig) (*KafkaSink, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, errors.New("no Kafka brokers defined")
+	}
+	if cfg.ClientID == "" {
+		cfg.ClientID = DefaultKafkaClientID
+	}
+	sink := &KafkaSink{
+		cfg:     cfg,
+		brokers: make(map[int32]string),
+		conns:   make(map[int32]net.Conn),
+	}
+	err := sink.refreshMetadata()
+	if err != nil {
+		sink.Close()
+		return nil, err
+	}
+	return sink, nil
+}
+
+// DeliverChange implements ChangeSink.DeliverChange,
+// returning once all records of the given consensus change are acknowledged.
+func (sink *KafkaSink) DeliverChange(change ChangeEvents) error {
+	sink.mut.Lock()
+	defer sink.mut.Unlock()
+
+	// encode the records, by topic
+	var topics []string
+	records := make(map[string][]kafkaRecord)
+	changeID := []byte(hex.EncodeToString(change.ID[:]))
+	for _, event := range change.Events {
+		topic, key := sink.route(event)
+		if topic == "" {
+			continue
+		}
+		value, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to JSON-encode %s event: %v", event.Type, err)
+		}
+		if _, ok := records[topic]; !ok {
+			topics = append(topics, topic)
+		}
+		records[topic] = append(records[topic], kafkaRecord{
+			Key:   key,
+			Value: value,
+			Headers: []kafkaHeader{
+				{Key: KafkaHeaderChangeID, Value: changeID},
+				{Key: KafkaHeaderEvent, Value: []byte(event.Type)},
+			},
+		})
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	return retryDelivery("Kafka", func() error {
+		return sink.produce(topics, records)
+	}, func() {
+		// the connections might be broken and the leaders might have moved
+		sink.closeConns()
+		err := sink.refreshMetadata()
+		if err != nil {
+			log.Println("[ERROR] failed to refresh Kafka metadata:", err)
+		}
+	})
+}
+
+// route returns the topic and key of the record of the given event, the topic being empty if it isn't produced.
+func (sink *KafkaSink) route(event Event) (topic string, key []byte) {
+	switch data := event.Data.(type) {
+	case BlockEventData:
+		return sink.cfg.BlockTopic, []byte(strconv.FormatUint(uint64(data.Height), 10))
+	case TransactionEventData:
+		return sink.cfg.TransactionTopic, []byte(data.ID.String())
+	case BalanceDeltaEventData:
+		return sink.cfg.BalanceTopic, []byte(data.Address.String())
+	default:
+		return "", nil
+	}
+}
+
+// produce the given records, by topic, removing them from the given map once acknowledged,
+// such that a retry only produces the records not acknowledged yet.
+// The records of a single partition are produced in order, one batch at a time.
+func (sink *KafkaSink) produce(topics []string, records map[string][]kafkaRecord) error {
+	// assign the records to the partitions of their topic, and encode them as batches
+	batches := make(map[kafkaTopicPartition][][]byte)
+	var order []kafkaTopicPartition
+	for _, topic := range topics {
+		partitions := sink.partitions[topic]
+		if len(records[topic]) == 0 {
+			continue
+		}
+		if len(partitions) == 0 {
+			return fmt.Errorf("no partitions known for Kafka topic %s", topic)
+		}
+		assigned := make(map[int32][]kafkaRecord)
+		for _, record := range records[topic] {
+			partition := partitions[int(kafkaMurmur2(record.Key)&0x7fffffff)%len(partitions)]
+			assigned[partition.ID] = append(assigned[partition.ID], record)
+		}
+		for _, partition := range partitions {
+			if len(assigned[partition.ID]) == 0 {
+				continue
+			}
+			tp := kafkaTopicPartition{Topic: topic, Partition: partition.ID}
+			order = append(order, tp)
+			batches[tp] = encodeKafkaRecordBatches(assigned[partition.ID], time.Now())
+		}
+	}
+
+	// produce the first pending batch of every partition, until all batches are produced
+	for len(batches) > 0 {
+		requests := make(map[int32][]kafkaTopicPartition)
+		var leaders []int32
+		for _, tp := range order {
+			if _, ok := batches[tp]; !ok {
+				continue
+			}
+			leader, ok := sink.leader(tp)
+			if !ok {
+				return fmt.Errorf("no leader known for partition %d of Kafka topic %s", tp.Partition, tp.Topic)
+			}
+			if _, ok := requests[leader]; !ok {
+				leaders = append(leaders, leader)
+			}
+			requests[leader] = append(requests[leader], tp)
+		}
+		var failed error
+		for _, leader := range leaders {
+			tps := requests[leader]
+			first := make(map[kafkaTopicPartition][]byte, len(tps))
+			for _, tp := range tps {
+				first[tp] = batches[tp][0]
+			}
+			errs, err := sink.produceBatches(leader, tps, first)
+			if err != nil {
+				return err
+			}
+			for _, tp := range tps {
+				if errs[tp] != 0 {
+					// the other partitions might have acknowledged their batch
+					if failed == nil {
+						failed = fmt.Errorf("failed to produce to partition %d of Kafka topic %s: %s",
+							tp.Partition, tp.Topic, kafkaErrorString(errs[tp]))
+					}
+					continue
+				}
+				// the batch is acknowledged, remove it and its records
+				n := kafkaRecordBatchCount(batches[tp][0])
+				records[tp.Topic] = removeKafkaRecords(records[tp.Topic], sink.partitions[tp.Topic], tp.Partition, n)
+				batches[tp] = batches[tp][1:]
+				if len(batches[tp]) == 0 {
+					delete(batches, tp)
+				}
+			}
+		}
+		if failed != nil {
+			return failed
+		}
+	}
+	return nil
+}
+
+// leader returns the node ID of the leader of the given partition.
+func (sink *KafkaSink) leader(tp kafkaTopicPartition) (int32, bool) {
+	for _, partition := range sink.partitions[tp.Topic] {
+		if partition.ID == tp.Partition {
+			return partition.Leader, partition.Leader >= 0
+		}
+	}
+	return 0, false
+}
+
+// removeKafkaRecords removes the first n records assigned to the given partition from the given records.
+func removeKafkaRecords(records []kafkaRecord, partitions []kafkaPartition, partition int32, n int) []kafkaRecord {
+	kept := records[:0]
+	for _, record := range records {
+		if n > 0 && partitions[int(kafkaMurmur2(record.Key)&0x7fffffff)%len(partitions)].ID == partition {
+			n--
+			continue
+		}
+		kept = append(kept, record)
+	}
+	return kept
+}
+
+// produceBatches sends a single produce request to the given broker, producing the given batch to each partition,
+// and returns the error code returned for each partition.
+func (sink *KafkaSink) produceBatches(node int32, tps []kafkaTopicPartition, batches map[kafkaTopicPartition][]byte) (map[kafkaTopicPartition]int16, error) {
+	var topics []string
+	partitions := make(map[string][]int32)
+	for _, tp := range tps {
+		if _, ok := partitions[tp.Topic]; !ok {
+			topics = append(topics, tp.Topic)
+		}
+		partitions[tp.Topic] = append(partitions[tp.Topic], tp.Partition)
+	}
+
+	e := new(kafkaEncoder)
+	e.Int16(-1) // transactional ID (null)
+	e.Int16(-1) // acks=all
+	e.Int32(int32(kafkaProduceTimeout / time.Millisecond))
+	e.Int32(int32(len(topics)))
+	for _, topic := range topics {
+		e.String(topic)
+		e.Int32(int32(len(partitions[topic])))
+		for _, partition := range partitions[topic] {
+			e.Int32(partition)
+			e.Bytes(batches[kafkaTopicPartition{Topic: topic, Partition: partition}])
+		}
+	}
+	resp, err := sink.request(node, kafkaAPIProduce, kafkaAPIProduceVersion, e.buf)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &kafkaDecoder{buf: resp}
+	errs := make(map[kafkaTopicPartition]int16, len(tps))
+	for i, n := 0, d.ArrayLength(); i < n && d.err == nil; i++ {
+		topic := d.String()
+		for j, m := 0, d.ArrayLength(); j < m && d.err == nil; j++ {
+			tp := kafkaTopicPartition{Topic: topic, Partition: d.Int32()}
+			errs[tp] = d.Int16()
+			d.Int64() // base offset
+			d.Int64() // log append time
+		}
+	}
+	d.Int32() // throttle time
+	if d.err != nil {
+		return nil, fmt.Errorf("invalid Kafka produce response: %v", d.err)
+	}
+	for _, tp := range tps {
+		if _, ok := errs[tp]; !ok {
+			return nil, fmt.Errorf("Kafka produce response misses partition %d of topic %s", tp.Partition, tp.Topic)
+		}
+	}
+	return errs, nil
+}
+
+// refreshMetadata fetches the brokers and the partitions of the configured topics
+// from the first broker which responds, creating the topics if the cluster allows it.
+func (sink *KafkaSink) refreshMetadata() error {
+	var topics []string
+	for _, topic := range []string{sink.cfg.BlockTopic, sink.cfg.TransactionTopic, sink.cfg.BalanceTopic} {
+		if topic != "" {
+			topics = append(topics, topic)
+		}
+	}
+	e := new(kafkaEncoder)
+	e.Int32(int32(len(topics)))
+	for _, topic := range topics {
+		e.String(topic)
+	}
+	e.Int8(1) // allow auto topic creation
+
+	var err error
+	for _, addr := range sink.cfg.Brokers {
+		var conn net.Conn
+		conn, err = net.DialTimeout("tcp", addr, kafkaDialTimeout)
+		if err != nil {
+			continue
+		}
+		var resp []byte
+		resp, err = sink.roundTrip(conn, kafkaAPIMetadata, kafkaAPIMetadataVersion, e.buf)
+		conn.Close()
+		if err != nil {
+			continue
+		}
+		err = sink.decodeMetadata(resp)
+		if err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("failed to fetch Kafka metadata: %v", err)
+}
+
+func (sink *KafkaSink) decodeMetadata(resp []byte) error {
+	d := &kafkaDecoder{buf: resp}
+	d.Int32() // throttle time
+	brokers := make(map[int32]string)
+	for i, n := 0, d.ArrayLength(); i < n && d.err == nil; i++ {
+		id := d.Int32()
+		host := d.String()
+		port := d.Int32()
+		d.NullableString() // rack
+		brokers[id] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	d.NullableString() // cluster ID
+	d.Int32()          // controller ID
+	partitions := make(map[string][]kafkaPartition)
+	var topicErr error
+	for i, n := 0, d.ArrayLength(); i < n && d.err == nil; i++ {
+		code := d.Int16()
+		topic := d.String()
+		d.Int8() // is internal
+		for j, m := 0, d.ArrayLength(); j < m && d.err == nil; j++ {
+			d.Int16() // partition error code, the leader being -1 if not available
+			partition := kafkaPartition{ID: d.Int32(), Leader: d.Int32()}
+			d.Int32Array() // replicas
+			d.Int32Array() // in-sync replicas
+			partitions[topic] = append(partitions[topic], partition)
+		}
+		if code != 0 && topicErr == nil {
+			topicErr = fmt.Errorf("Kafka topic %s: %s", topic, kafkaErrorString(code))
+		}
+	}
+	if d.err != nil {
+		return fmt.Errorf("invalid Kafka metadata response: %v", d.err)
+	}
+	if topicErr != nil {
+		return topicErr
+	}
+	// the partitions are assigned by their index, which has to match their ID
+	for topic, ps := range partitions {
+		byID := make([]kafkaPartition, len(ps))
+		for _, partition := range ps {
+			if partition.ID < 0 || int(partition.ID) >= len(ps) {
+				return fmt.Errorf("Kafka topic %s has unexpected partition %d", topic, partition.ID)
+			}
+			byID[partition.ID] = partition
+		}
+		partitions[topic] = byID
+	}
+	sink.brokers = brokers
+	sink.partitions = partitions
+	return nil
+}
+
+// request sends a request to the broker with the given node ID, connecting to it if not connected yet,
+// and returns the body of its response. The connection is closed should the request fail.
+func (sink *KafkaSink) request(node int32, apiKey, apiVersion int16, body []byte) ([]byte, error) {
+	conn, ok := sink.conns[node]
+	if !ok {
+		addr, ok := sink.brokers[node]
+		if !ok {
+			return nil, fmt.Errorf("unknown Kafka broker %d", node)
+		}
+		var err error
+		conn, err = net.DialTimeout("tcp", addr, kafkaDialTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to Kafka broker %s: %v", addr, err)
+		}
+		sink.conns[node] = conn
+	}
+	resp, err := sink.roundTrip(conn, apiKey, apiVersion, body)
+	if err != nil {
+		conn.Close()
+		delete(sink.conns, node)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// roundTrip sends a single request over the given connection, and returns the body of its response.
+func (sink *KafkaSink) roundTrip(conn net.Conn, apiKey, apiVersion int16, body []byte) ([]byte, error) {
+	sink.correlationID++
+	e := new(kafkaEncoder)
+	e.Int32(0) // size, set once encoded
+	e.Int16(apiKey)
+	e.Int16(apiVersion)
+	e.Int32(sink.correlationID)
+	e.String(sink.cfg.ClientID)
+	e.buf = append(e.buf, body...)
+	binary.BigEndian.PutUint32(e.buf, uint32(len(e.buf)-4))
+
+	conn.SetDeadline(time.Now().Add(kafkaRequestTimeout))
+	_, err := conn.Write(e.buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send Kafka request: %v", err)
+	}
+	var header [8]byte
+	_, err = io.ReadFull(conn, header[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive Kafka response: %v", err)
+	}
+	size := binary.BigEndian.Uint32(header[:4])
+	if size < 4 || size > kafkaMaxResponseSize {
+		return nil, fmt.Errorf("invalid Kafka response size %d", size)
+	}
+	if correlationID := int32(binary.BigEndian.Uint32(header[4:])); correlationID != sink.correlationID {
+		return nil, fmt.Errorf("unexpected Kafka correlation ID %d, expected %d", correlationID, sink.correlationID)
+	}
+	resp := make([]byte, size-4)
+	_, err = io.ReadFull(conn, resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive Kafka response: %v", err)
+	}
+	return resp, nil
+}
+
+func (sink *KafkaSink) closeConns() {
+	for node, conn := range sink.conns {
+		conn.Close()
+		delete(sink.conns, node)
+	}
+}
+
+// Close implements ChangeSink.Close, closing all connections to the brokers.
+func (sink *KafkaSink) Close() error {
+	sink.mut.Lock()
+	defer sink.mut.Unlock()
+	sink.closeConns()
+	return nil
+}
+
+// encodeKafkaRecordBatches encodes the given records as (v2) record batches,
+// each batch containing as many records as fit within kafkaMaxBatchSize.
+func encodeKafkaRecordBatches(records []kafkaRecord, timestamp time.Time) [][]byte {
+	var batches [][]byte
+	for len(records) > 0 {
+		var encoded [][]byte
+		size := 0
+		for _, record := range records {
+			b := encodeKafkaRecord(record, len(encoded))
+			if len(encoded) > 0 && size+len(b) > kafkaMaxBatchSize {
+				break
+			}
+			encoded = append(encoded, b)
+			size += len(b)
+		}
+		records = records[len(encoded):]
+		batches = append(batches, encodeKafkaRecordBatch(encoded, timestamp))
+	}
+	return batches
+}
+
+// encodeKafkaRecordBatch encodes a single record batch, containing the given (encoded) records,
+// the first one having offset delta 0.
+func encodeKafkaRecordBatch(records [][]byte, timestamp time.Time) []byte {
+	ms := timestamp.UnixNano() / int64(time.Millisecond)
+	e := new(kafkaEncoder)
+	e.Int64(0)  // base offset
+	e.Int32(0)  // batch length, set once encoded
+	e.Int32(-1) // partition leader epoch
+	e.Int8(2)   // magic
+	e.Int32(0)  // CRC, set once encoded
+	e.Int16(0)  // attributes: no compression, create time, not transactional
+	e.Int32(int32(len(records) - 1))
+	e.Int64(ms) // first timestamp
+	e.Int64(ms) // max timestamp
+	e.Int64(-1) // producer ID
+	e.Int16(-1) // producer epoch
+	e.Int32(-1) // base sequence
+	e.Int32(int32(len(records)))
+	for _, record := range records {
+		e.buf = append(e.buf, record...)
+	}
+	binary.BigEndian.PutUint32(e.buf[8:], uint32(len(e.buf)-12))
+	binary.BigEndian.PutUint32(e.buf[17:], crc32.Checksum(e.buf[21:], crc32c))
+	return e.buf
+}
+
+// kafkaRecordBatchCount returns the amount of records of an encoded record batch.
+func kafkaRecordBatchCount(batch []byte) int {
+	return int(binary.BigEndian.Uint32(batch[57:]))
+}
+
+// encodeKafkaRecord encodes a single record of a (v2) record batch.
+func encodeKafkaRecord(record kafkaRecord, offsetDelta int) []byte {
+	e := new(kafkaEncoder)
+	e.Int8(0)   // attributes
+	e.Varint(0) // timestamp delta
+	e.Varint(int64(offsetDelta))
+	e.VarBytes(record.Key)
+	e.VarBytes(record.Value)
+	e.Varint(int64(len(record.Headers)))
+	for _, header := range record.Headers {
+		e.VarBytes([]byte(header.Key))
+		e.VarBytes(header.Value)
+	}
+	return append(binary.AppendVarint(nil, int64(len(e.buf))), e.buf...)
+}
+
+// kafkaMurmur2 is the murmur2 hash used by the default partitioner of the Java client.
+func kafkaMurmur2(data []byte) int32 {
+	const (
+		seed uint32 = 0x9747b28c
+		m    uint32 = 0x5bd1e995
+		r           = 24
+	)
+	length := len(data)
+	h := seed ^ uint32(length)
+	for i := 0; i+4 <= length; i += 4 {
+		k := binary.LittleEndian.Uint32(data[i:])
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+	tail := data[length&^3:]
+	switch len(tail) {
+	case 3:
+		h ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(tail[0])
+		h *= m
+	}
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return int32(h)
+}
+
+// kafkaErrorString returns a readable description of a Kafka error code.
+func kafkaErrorString(code int16) string {
+	switch code {
+	case 3:
+		return "unknown topic or partition"
+	case 5:
+		return "leader not available"
+	case 6:
+		return "not leader for partition"
+	case 7:
+		return "request timed out"
+	case 10:
+		return "message too large"
+	case 19:
+		return "not enough replicas"
+	case 20:
+		return "not enough replicas after append"
+	case 29:
+		return "topic authorization failed"
+	default:
+		return fmt.Sprintf("error code %d", code)
+	}
+}
+
+// kafkaEncoder encodes the (big-endian) primitive types of the Kafka protocol.
+type kafkaEncoder struct {
+	buf []byte
+}
+
+func (e *kafkaEncoder) Int8(v int8)   { e.buf = append(e.buf, byte(v)) }
+func (e *kafkaEncoder) Int16(v int16) { e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(v)) }
+func (e *kafkaEncoder) Int32(v int32) { e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(v)) }
+func (e *kafkaEncoder) Int64(v int64) { e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(v)) }
+
+// Varint encodes a zigzag-encoded varint, as used by the records of a record batch.
+func (e *kafkaEncoder) Varint(v int64) { e.buf = binary.AppendVarint(e.buf, v) }
+
+func (e *kafkaEncoder) String(s string) {
+	e.Int16(int16(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *kafkaEncoder) Bytes(b []byte) {
+	e.Int32(int32(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+// VarBytes encodes varint-prefixed bytes, as used by the records of a record batch, nil being encoded as null.
+func (e *kafkaEncoder) VarBytes(b []byte) {
+	if b == nil {
+		e.Varint(-1)
+		return
+	}
+	e.Varint(int64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+// kafkaDecoder decodes the (big-endian) primitive types of the Kafka protocol,
+// recording the first error, after which all decoded values are zero.
+type kafkaDecoder struct {
+	buf []byte
+	err error
+}
+
+func (d *kafkaDecoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(d.buf) {
+		d.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *kafkaDecoder) Int8() int8 {
+	if b := d.next(1); b != nil {
+		return int8(b[0])
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) Int16() int16 {
+	if b := d.next(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) Int32() int32 {
+	if b := d.next(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) Int64() int64 {
+	if b := d.next(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) String() string {
+	return string(d.next(int(d.Int16())))
+}
+
+// NullableString decodes a nullable string, null being decoded as an empty string.
+func (d *kafkaDecoder) NullableString() string {
+	n := d.Int16()
+	if n == -1 {
+		return ""
+	}
+	return string(d.next(int(n)))
+}
+
+// ArrayLength decodes the length of an array, a null array being decoded as an empty one.
+func (d *kafkaDecoder) ArrayLength() int {
+	n := d.Int32()
+	if n < 0 {
+		return 0
+	}
+	// every element is encoded using at least a single byte
+	if int(n) > len(d.buf) {
+		d.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	return int(n)
+}
+
+func (d *kafkaDecoder) Int32Array() []int32 {
+	n := d.ArrayLength()
+	values := make([]int32, 0, n)
+	for i := 0; i < n && d.err == nil; i++ {
+		values = append(values, d.Int32())
+	}
+	return values
+}
@@ -0,0 +1,1183 @@
+//go:build leveldb
+// +build leveldb
+
+package rexplorer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/rivine/rivine/types"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+type (
+	// LevelDBDatabase is an embedded Database implementation, using LevelDB (goleveldb),
+	// tuned for sequential block ingestion, as to speed up the initial sync of large chains.
+	//
+	// As it implements TransactionalDatabase, all changes of a consensus change are buffered in memory,
+	// and written as a single batch once the consensus change has been applied completely.
+	// Reads made while applying a consensus change see the buffered (not yet written) changes.
+	//
+	// As it implements CheckpointDatabase, the value of every key changed by a batch since the last checkpoint
+	// is stored (once) as an undo record, such that the values changed since can be restored after a crash.
+	//
+	// Only one process can open the database directory at a time, meaning that the database
+	// cannot be read by other processes (including the commands of rexplorer itself) while the daemon is running.
+	//
+	// All data is stored in a single keyspace, using following key prefixes:
+	//
+	//	  m:<name>									(encoded) internal state, network info, chain parameters, stats, health and checkpoint
+	//	  w:<address>								(encoded) Wallet, for all unique addresses
+	//	  c:<coinOutputID>							(CSV) DatabaseCoinOutput, for all coin outputs
+	//	  l:h:L:<lockHeight><coinOutputID>			all coin outputs locked by block height
+	//	  l:h:U:<lockHeight><coinOutputID>			all coin outputs unlocked by block height
+	//	  l:t:L:<lockTimestamp><coinOutputID>		all coin outputs locked by time
+	//	  l:t:U:<lockTimestamp><coinOutputID>		all coin outputs unlocked by time
+	//	  p:<address>:<counterparty>				(encoded) AddressCounterparty
+	//	  f:total|<YYYY-MM-DD>						(encoded) WalletGroupFlows
+	//	  ms:<address>:<coinOutputID>				(encoded) signers of a spent multisig coin output
+	//	  mo:<address>:<signer>						(encoded) MultisigSignerStats
+	//	  b:<blockHeight>							(encoded) BlockSummary
+	//	  u:<key>									value of the key as of the last checkpoint, prefixed by 1 if stored and 0 if not
+	//
+	// Addresses and IDs are used as keys in their Rivine-defined hex-encoded string format,
+	// while lock values (and the IDs following them) are binary-encoded, such that locks are ordered by value.
+	// Contrary to the RedisDatabase, all counterparties of an address are stored.
+	LevelDBDatabase struct {
+		db *leveldb.DB
+		// the buffered changes of the consensus change currently being applied, nil if none
+		pending map[string]levelDBPendingValue
+		// the keys changed since the last checkpoint (the dirty set), for which an undo record is stored
+		dirty map[string]struct{}
+
+		// encoder used to encode all (structured) values
+		encoder Encoder
+
+		blockFrequency LockValue
+
+		// cached version of the chain stats
+		networkBlockHeight types.BlockHeight
+		networkTime        types.Timestamp
+	}
+
+	// levelDBPendingValue is a buffered change, either storing or deleting a value.
+	levelDBPendingValue struct {
+		Value   []byte
+		Deleted bool
+	}
+)
+
+var (
+	_ TransactionalDatabase = (*LevelDBDatabase)(nil)
+	_ CheckpointDatabase    = (*LevelDBDatabase)(nil)
+)
+
+var (
+	levelDBPrefixMeta                = []byte("m:")
+	levelDBPrefixWallets             = []byte("w:")
+	levelDBPrefixCoinOutputs         = []byte("c:")
+	levelDBPrefixHeightLocksLocked   = []byte("l:h:L:")
+	levelDBPrefixHeightLocksUnlocked = []byte("l:h:U:")
+	levelDBPrefixTimeLocksLocked     = []byte("l:t:L:")
+	levelDBPrefixTimeLocksUnlocked   = []byte("l:t:U:")
+	levelDBPrefixCounterparties      = []byte("p:")
+	levelDBPrefixFlows               = []byte("f:")
+	levelDBPrefixMultisigSpends      = []byte("ms:")
+	levelDBPrefixMultisigSigners     = []byte("mo:")
+	levelDBPrefixBlockSummaries      = []byte("b:")
+	levelDBPrefixUndo                = []byte("u:")
+
+	levelDBKeyState   = levelDBKey(levelDBPrefixMeta, "state")
+	levelDBKeyNetwork = levelDBKey(levelDBPrefixMeta, "network")
+	levelDBKeyParams  = levelDBKey(levelDBPrefixMeta, "chainparams")
+	levelDBKeyAliases = levelDBKey(levelDBPrefixMeta, "aliases")
+	levelDBKeyStats   = levelDBKey(levelDBPrefixMeta, "stats")
+	levelDBKeyHealth  = levelDBKey(levelDBPrefixMeta, "health")
+
+	levelDBKeyCheckpoint = levelDBKey(levelDBPrefixMeta, "checkpoint")
+)
+
+func init() {
+	RegisterDatabaseDriver("leveldb", func(cfg DatabaseConfig) (Database, error) {
+		path := cfg.Address
+		if path == "" {
+			path = "rexplorer.leveldb"
+		}
+		return NewLevelDBDatabase(path, cfg.BlockchainInfo, cfg.ChainConstants)
+	})
+}
+
+// NewLevelDBDatabase creates (or opens) a LevelDB Database, stored in the directory at the given path,
+// see LevelDBDatabase for more information.
+func NewLevelDBDatabase(path string, bcInfo types.BlockchainInfo, chainCts types.ChainConstants) (*LevelDBDatabase, error) {
+	db, err := leveldb.OpenFile(path, &opt.Options{
+		// larger buffers and tables, as to reduce the amount of compactions while syncing
+		WriteBuffer:         64 * opt.MiB,
+		BlockCacheCapacity:  64 * opt.MiB,
+		CompactionTableSize: 8 * opt.MiB,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open leveldb database at %s: %v", path, err)
+	}
+	ldb := LevelDBDatabase{
+		db:             db,
+		dirty:          make(map[string]struct{}),
+		encoder:        jsonEncoder{},
+		blockFrequency: LockValue(chainCts.BlockFrequency),
+	}
+	// load the dirty set, restored once the explorer resumes (see RestoreCheckpoint)
+	it := db.NewIterator(util.BytesPrefix(levelDBPrefixUndo), nil)
+	for it.Next() {
+		ldb.dirty[string(it.Key()[len(levelDBPrefixUndo):])] = struct{}{}
+	}
+	it.Release()
+	if err = it.Error(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to load dirty set of leveldb database at %s: %v", path, err)
+	}
+	// ensure the network info is as expected (or register if this is a fresh db)
+	err = ldb.registerOrValidateNetworkInfo(bcInfo)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &ldb, nil
+}
+
+// Begin implements TransactionalDatabase.Begin
+func (ldb *LevelDBDatabase) Begin() error {
+	if ldb.pending != nil {
+		return errors.New("leveldb: a batch is already in progress")
+	}
+	ldb.pending = make(map[string]levelDBPendingValue)
+	return nil
+}
+
+// Commit implements TransactionalDatabase.Commit
+//
+// writes all buffered changes as a single batch
+func (ldb *LevelDBDatabase) Commit() error {
+	if ldb.pending == nil {
+		return errors.New("leveldb: no batch in progress")
+	}
+	var batch leveldb.Batch
+	for key, pv := range ldb.pending {
+		if pv.Deleted {
+			batch.Delete([]byte(key))
+		} else {
+			batch.Put([]byte(key), pv.Value)
+		}
+	}
+	ldb.pending = nil
+	err := ldb.db.Write(&batch, nil)
+	if err != nil {
+		return fmt.Errorf("leveldb: failed to write batch of %d changes: %v", batch.Len(), err)
+	}
+	return nil
+}
+
+// Close implements Database.Close
+//
+// drops the batch in progress (if any), and closes the database
+func (ldb *LevelDBDatabase) Close() error {
+	ldb.pending = nil
+	err := ldb.db.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close leveldb database: %v", err)
+	}
+	return nil
+}
+
+// get gets a raw value, taking into account the buffered changes,
+// returning ErrNotFound if it isn't stored.
+func (ldb *LevelDBDatabase) get(key []byte) ([]byte, error) {
+	if pv, ok := ldb.pending[string(key)]; ok {
+		if pv.Deleted {
+			return nil, ErrNotFound
+		}
+		return pv.Value, nil
+	}
+	b, err := ldb.db.Get(key, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	return b, err
+}
+
+// put stores a raw value, buffering it if a batch is in progress.
+func (ldb *LevelDBDatabase) put(key, value []byte) error {
+	if ldb.pending != nil {
+		err := ldb.track(key)
+		if err != nil {
+			return err
+		}
+		ldb.pending[string(key)] = levelDBPendingValue{Value: value}
+		return nil
+	}
+	return ldb.db.Put(key, value, nil)
+}
+
+// delete deletes a raw value, buffering it if a batch is in progress.
+func (ldb *LevelDBDatabase) delete(key []byte) error {
+	if ldb.pending != nil {
+		err := ldb.track(key)
+		if err != nil {
+			return err
+		}
+		ldb.pending[string(key)] = levelDBPendingValue{Deleted: true}
+		return nil
+	}
+	return ldb.db.Delete(key, nil)
+}
+
+// track adds the given key to the dirty set, buffering its undo record,
+// unless the key was changed since the last checkpoint already. Only the changes made by a batch are tracked.
+func (ldb *LevelDBDatabase) track(key []byte) error {
+	if _, ok := ldb.dirty[string(key)]; ok {
+		return nil
+	}
+	undo := []byte{0}
+	switch b, err := ldb.get(key); err {
+	case nil:
+		undo = append([]byte{1}, b...)
+	case ErrNotFound:
+	default:
+		return fmt.Errorf("leveldb: failed to get value of %q to track it: %v", key, err)
+	}
+	ldb.pending[string(levelDBKey(levelDBPrefixUndo, string(key)))] = levelDBPendingValue{Value: undo}
+	ldb.dirty[string(key)] = struct{}{}
+	return nil
+}
+
+// keys returns all (sorted) keys within the given range, taking into account the buffered changes.
+func (ldb *LevelDBDatabase) keys(r *util.Range) ([][]byte, error) {
+	set := make(map[string]struct{})
+	it := ldb.db.NewIterator(r, nil)
+	for it.Next() {
+		set[string(it.Key())] = struct{}{}
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	for key, pv := range ldb.pending {
+		if bytes.Compare([]byte(key), r.Start) < 0 || (r.Limit != nil && bytes.Compare([]byte(key), r.Limit) >= 0) {
+			continue
+		}
+		if pv.Deleted {
+			delete(set, key)
+		} else {
+			set[key] = struct{}{}
+		}
+	}
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([][]byte, len(keys))
+	for i, key := range keys {
+		result[i] = []byte(key)
+	}
+	return result, nil
+}
+
+// getValue gets and decodes a value, returning ErrNotFound if it isn't stored.
+func (ldb *LevelDBDatabase) getValue(key []byte, v interface{}) error {
+	b, err := ldb.get(key)
+	if err != nil {
+		return err
+	}
+	return ldb.encoder.Unmarshal(b, v)
+}
+
+// putValue encodes and stores a value.
+func (ldb *LevelDBDatabase) putValue(key []byte, v interface{}) error {
+	return ldb.put(key, MustMarshal(ldb.encoder, v))
+}
+
+// SupportsCheckpoints implements CheckpointDatabase.SupportsCheckpoints
+func (ldb *LevelDBDatabase) SupportsCheckpoints() bool {
+	return true
+}
+
+// SetCheckpoint implements CheckpointDatabase.SetCheckpoint
+//
+// deletes the undo records of the dirty set within the batch in progress (if any)
+func (ldb *LevelDBDatabase) SetCheckpoint(checkpoint Checkpoint) error {
+	if ldb.pending == nil {
+		return errors.New("leveldb: a checkpoint can only be stored within a batch")
+	}
+	for key := range ldb.dirty {
+		ldb.pending[string(levelDBKey(levelDBPrefixUndo, key))] = levelDBPendingValue{Deleted: true}
+	}
+	ldb.pending[string(levelDBKeyCheckpoint)] = levelDBPendingValue{Value: MustMarshal(ldb.encoder, checkpoint)}
+	ldb.dirty = make(map[string]struct{})
+	return nil
+}
+
+// RestoreCheckpoint implements CheckpointDatabase.RestoreCheckpoint
+//
+// restores the values of all undo records, and deletes them, as a single batch
+func (ldb *LevelDBDatabase) RestoreCheckpoint() (Checkpoint, int, error) {
+	if ldb.pending != nil {
+		return Checkpoint{}, 0, errors.New("leveldb: cannot restore a checkpoint while a batch is in progress")
+	}
+	var checkpoint Checkpoint
+	err := ldb.getValue(levelDBKeyCheckpoint, &checkpoint)
+	if err != nil {
+		return Checkpoint{}, 0, err
+	}
+	var batch leveldb.Batch
+	restored := 0
+	it := ldb.db.NewIterator(util.BytesPrefix(levelDBPrefixUndo), nil)
+	for it.Next() {
+		key, undo := it.Key()[len(levelDBPrefixUndo):], it.Value()
+		if len(undo) > 0 && undo[0] == 1 {
+			batch.Put(key, undo[1:])
+		} else {
+			batch.Delete(key)
+		}
+		batch.Delete(it.Key())
+		restored++
+	}
+	it.Release()
+	if err = it.Error(); err != nil {
+		return Checkpoint{}, 0, fmt.Errorf("leveldb: failed to read undo records: %v", err)
+	}
+	batch.Put(levelDBKeyState, MustMarshal(ldb.encoder, checkpoint.State))
+	batch.Put(levelDBKeyStats, MustMarshal(ldb.encoder, checkpoint.Stats))
+	err = ldb.db.Write(&batch, nil)
+	if err != nil {
+		return Checkpoint{}, 0, fmt.Errorf("leveldb: failed to restore %d values: %v", restored, err)
+	}
+	ldb.dirty = make(map[string]struct{})
+	return checkpoint, restored, nil
+}
+
+// registerOrValidateNetworkInfo registeres the network name and chain name if it doesn't exist yet,
+// otherwise it ensures that the returned network info matches the expected network info.
+func (ldb *LevelDBDatabase) registerOrValidateNetworkInfo(bcInfo types.BlockchainInfo) error {
+	networkInfo := NetworkInfo{
+		ChainName:   bcInfo.Name,
+		NetworkName: bcInfo.NetworkName,
+	}
+	var receivedNetworkInfo NetworkInfo
+	switch err := ldb.getValue(levelDBKeyNetwork, &receivedNetworkInfo); err {
+	case nil:
+	case ErrNotFound:
+		err = ldb.putValue(levelDBKeyNetwork, networkInfo)
+		if err != nil {
+			return fmt.Errorf("failed to register network info: %v", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("failed to validate network info: %v", err)
+	}
+	if receivedNetworkInfo != networkInfo {
+		return fmt.Errorf("cannot store data for chain %s/%s: db has already data for chain %s/%s stored",
+			networkInfo.ChainName, networkInfo.NetworkName,
+			receivedNetworkInfo.ChainName, receivedNetworkInfo.NetworkName)
+	}
+	return nil
+}
+
+// GetExplorerState implements Database.GetExplorerState
+func (ldb *LevelDBDatabase) GetExplorerState() (ExplorerState, error) {
+	var state ExplorerState
+	switch err := ldb.getValue(levelDBKeyState, &state); err {
+	case nil:
+		return state, nil
+	case ErrNotFound:
+		// default to fresh explorer state if not stored yet
+		return NewExplorerState(), nil
+	default:
+		return ExplorerState{}, err
+	}
+}
+
+// SetExplorerState implements Database.SetExplorerState
+func (ldb *LevelDBDatabase) SetExplorerState(state ExplorerState) error {
+	return ldb.putValue(levelDBKeyState, state)
+}
+
+// GetNetworkStats implements Database.GetNetworkStats
+func (ldb *LevelDBDatabase) GetNetworkStats() (NetworkStats, error) {
+	var stats NetworkStats
+	switch err := ldb.getValue(levelDBKeyStats, &stats); err {
+	case nil:
+		err = upgradeNetworkStats(&stats)
+		if err != nil {
+			return NetworkStats{}, fmt.Errorf("leveldb: failed to upgrade network stats: %v", err)
+		}
+	case ErrNotFound:
+		// default to fresh network stats if not stored yet
+		stats = NewNetworkStats()
+	default:
+		return NetworkStats{}, err
+	}
+	ldb.networkTime, ldb.networkBlockHeight = stats.Timestamp, stats.BlockHeight
+	return stats, nil
+}
+
+// SetNetworkStats implements Database.SetNetworkStats
+func (ldb *LevelDBDatabase) SetNetworkStats(stats NetworkStats) error {
+	err := ldb.putValue(levelDBKeyStats, stats)
+	if err != nil {
+		return err
+	}
+	ldb.networkTime, ldb.networkBlockHeight = stats.Timestamp, stats.BlockHeight
+	return nil
+}
+
+// SetChainHealth implements Database.SetChainHealth
+func (ldb *LevelDBDatabase) SetChainHealth(health ChainHealth) error {
+	return ldb.putValue(levelDBKeyHealth, health)
+}
+
+// GetChainParametersHistory implements Database.GetChainParametersHistory
+func (ldb *LevelDBDatabase) GetChainParametersHistory() ([]ChainParametersRecord, error) {
+	var history []ChainParametersRecord
+	switch err := ldb.getValue(levelDBKeyParams, &history); err {
+	case nil, ErrNotFound:
+		// no history is stored yet for a fresh database
+		return history, nil
+	default:
+		return nil, err
+	}
+}
+
+// SetChainParametersHistory implements Database.SetChainParametersHistory
+func (ldb *LevelDBDatabase) SetChainParametersHistory(history []ChainParametersRecord) error {
+	return ldb.putValue(levelDBKeyParams, history)
+}
+
+// GetAddressAliases implements Database.GetAddressAliases
+func (ldb *LevelDBDatabase) GetAddressAliases() ([]AddressAlias, error) {
+	var aliases []AddressAlias
+	switch err := ldb.getValue(levelDBKeyAliases, &aliases); err {
+	case nil, ErrNotFound:
+		// no aliases are recorded yet
+		return aliases, nil
+	default:
+		return nil, err
+	}
+}
+
+// SetAddressAliases implements Database.SetAddressAliases
+func (ldb *LevelDBDatabase) SetAddressAliases(aliases []AddressAlias) error {
+	return ldb.putValue(levelDBKeyAliases, aliases)
+}
+
+// getCoinOutput gets a stored coin output, returning ErrNotFound if it isn't stored.
+func (ldb *LevelDBDatabase) getCoinOutput(id types.CoinOutputID) (co DatabaseCoinOutput, err error) {
+	b, err := ldb.get(levelDBKey(levelDBPrefixCoinOutputs, id.String()))
+	if err != nil {
+		return DatabaseCoinOutput{}, err
+	}
+	err = co.LoadString(string(b))
+	return
+}
+
+// putCoinOutput stores a coin output.
+func (ldb *LevelDBDatabase) putCoinOutput(id types.CoinOutputID, co DatabaseCoinOutput) error {
+	return ldb.put(levelDBKey(levelDBPrefixCoinOutputs, id.String()), []byte(co.String()))
+}
+
+// updateWallet updates the wallet of the given address using the given update function,
+// creating the wallet if it doesn't exist yet.
+func (ldb *LevelDBDatabase) updateWallet(address types.UnlockHash, update func(*Wallet) error) error {
+	key := levelDBKey(levelDBPrefixWallets, address.String())
+	var wallet Wallet
+	err := ldb.getValue(key, &wallet)
+	if err != nil && err != ErrNotFound {
+		return fmt.Errorf("leveldb: failed to get wallet for %s: %v", address.String(), err)
+	}
+	err = update(&wallet)
+	if err != nil {
+		return fmt.Errorf("leveldb: failed to update wallet for %s: %v", address.String(), err)
+	}
+	wallet.Balance.Locked.updateHorizons(ldb.networkTime)
+	err = ldb.putValue(key, wallet)
+	if err != nil {
+		return fmt.Errorf("leveldb: failed to set wallet for %s: %v", address.String(), err)
+	}
+	return nil
+}
+
+// AddCoinOutput implements Database.AddCoinOutput
+func (ldb *LevelDBDatabase) AddCoinOutput(id types.CoinOutputID, co CoinOutput) error {
+	uh := co.Condition.UnlockHash()
+	err := ldb.putCoinOutput(id, DatabaseCoinOutput{
+		UnlockHash:   uh,
+		CoinValue:    co.Value,
+		State:        CoinOutputStateLiquid,
+		LockType:     LockTypeNone,
+		LockValue:    0,
+		Description:  co.Description,
+		RawCondition: EncodeCondition(co.Condition),
+	})
+	if err != nil {
+		return fmt.Errorf("leveldb: failed to add coin output %s: %v", id.String(), err)
+	}
+	return ldb.updateWallet(uh, func(wallet *Wallet) error {
+		wallet.Balance.Unlocked = wallet.Balance.Unlocked.Add(co.Value)
+		return nil
+	})
+}
+
+// AddLockedCoinOutput implements Database.AddLockedCoinOutput
+func (ldb *LevelDBDatabase) AddLockedCoinOutput(id types.CoinOutputID, co CoinOutput, lt LockType, lockValue LockValue) error {
+	uh := co.Condition.UnlockHash()
+	err := ldb.putCoinOutput(id, DatabaseCoinOutput{
+		UnlockHash:   uh,
+		CoinValue:    co.Value,
+		State:        CoinOutputStateLocked,
+		LockType:     lt,
+		LockValue:    lockValue,
+		Description:  co.Description,
+		RawCondition: EncodeCondition(co.Condition),
+	})
+	if err != nil {
+		return fmt.Errorf("leveldb: failed to add coin output %s: %v", id.String(), err)
+	}
+	lockedPrefix, _ := levelDBLockPrefixes(lt)
+	err = ldb.put(levelDBLockKey(lockedPrefix, lockValue, id), []byte{})
+	if err != nil {
+		return fmt.Errorf("leveldb: failed to add lock of coin output %s: %v", id.String(), err)
+	}
+	return ldb.updateWallet(uh, func(wallet *Wallet) error {
+		return wallet.Balance.Locked.AddLockedCoinOutput(id, WalletLockedOutput{
+			Amount:      co.Value,
+			LockedUntil: ldb.lockValueAsLockTime(lt, lockValue),
+			Description: co.Description,
+			Reason:      CoinOutputLockReason(lt, co.Description),
+		})
+	})
+}
+
+// SpendCoinOutput implements Database.SpendCoinOutput
+func (ldb *LevelDBDatabase) SpendCoinOutput(id types.CoinOutputID) (types.UnlockHash, types.Currency, error) {
+	uh, value, err := ldb.updateCoinOutputState(id, CoinOutputStateLiquid, CoinOutputStateSpent)
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf("leveldb: failed to spend coin output: %v", err)
+	}
+	err = ldb.updateWallet(uh, func(wallet *Wallet) error {
+		wallet.Balance.Unlocked = wallet.Balance.Unlocked.Sub(value)
+		return nil
+	})
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, err
+	}
+	return uh, value, nil
+}
+
+// RevertCoinInput implements Database.RevertCoinInput
+// more or less a reverse process of SpendCoinOutput
+func (ldb *LevelDBDatabase) RevertCoinInput(id types.CoinOutputID) (types.UnlockHash, types.Currency, error) {
+	uh, value, err := ldb.updateCoinOutputState(id, CoinOutputStateSpent, CoinOutputStateLiquid)
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf("leveldb: failed to revert coin input: %v", err)
+	}
+	err = ldb.updateWallet(uh, func(wallet *Wallet) error {
+		wallet.Balance.Unlocked = wallet.Balance.Unlocked.Add(value)
+		return nil
+	})
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, err
+	}
+	return uh, value, nil
+}
+
+// updateCoinOutputState updates the state of a coin output,
+// returning an error in case the coin output isn't in the expected state.
+func (ldb *LevelDBDatabase) updateCoinOutputState(id types.CoinOutputID, from, to CoinOutputState) (types.UnlockHash, types.Currency, error) {
+	co, err := ldb.getCoinOutput(id)
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf("cannot get coin output %s: %v", id.String(), err)
+	}
+	if co.State != from {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf(
+			"cannot update coin output %s: unexpected state %d", id.String(), co.State)
+	}
+	co.State = to
+	err = ldb.putCoinOutput(id, co)
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf("cannot update coin output %s: %v", id.String(), err)
+	}
+	return co.UnlockHash, co.CoinValue, nil
+}
+
+// RevertCoinOutput implements Database.RevertCoinOutput
+func (ldb *LevelDBDatabase) RevertCoinOutput(id types.CoinOutputID) (CoinOutputState, error) {
+	co, err := ldb.getCoinOutput(id)
+	if err != nil {
+		return CoinOutputStateNil, fmt.Errorf(
+			"leveldb: failed to revert coin output: cannot get coin output %s: %v", id.String(), err)
+	}
+	err = ldb.delete(levelDBKey(levelDBPrefixCoinOutputs, id.String()))
+	if err != nil {
+		return CoinOutputStateNil, fmt.Errorf(
+			"leveldb: failed to revert coin output: cannot drop coin output %s: %v", id.String(), err)
+	}
+	// always remove lock properties if a lock is used, no matter the state
+	if co.LockType != LockTypeNone {
+		lockedPrefix, unlockedPrefix := levelDBLockPrefixes(co.LockType)
+		err = ldb.delete(levelDBLockKey(lockedPrefix, co.LockValue, id))
+		if err == nil {
+			err = ldb.delete(levelDBLockKey(unlockedPrefix, co.LockValue, id))
+		}
+		if err != nil {
+			return CoinOutputStateNil, fmt.Errorf(
+				"leveldb: failed to revert coin output: cannot drop lock of coin output %s: %v", id.String(), err)
+		}
+	}
+	switch co.State {
+	case CoinOutputStateLiquid:
+		err = ldb.updateWallet(co.UnlockHash, func(wallet *Wallet) error {
+			wallet.Balance.Unlocked = wallet.Balance.Unlocked.Sub(co.CoinValue)
+			return nil
+		})
+	case CoinOutputStateLocked:
+		err = ldb.updateWallet(co.UnlockHash, func(wallet *Wallet) error {
+			return wallet.Balance.Locked.SubLockedCoinOutput(id)
+		})
+	}
+	if err != nil {
+		return CoinOutputStateNil, err
+	}
+	return co.State, nil
+}
+
+// ApplyCoinOutputLocks implements Database.ApplyCoinOutputLocks
+func (ldb *LevelDBDatabase) ApplyCoinOutputLocks(height types.BlockHeight, time types.Timestamp) (n uint64, coins types.Currency, err error) {
+	ldb.networkTime, ldb.networkBlockHeight = time, height
+	for _, lock := range []struct {
+		Type  LockType
+		Value LockValue
+	}{
+		{LockTypeHeight, LockValue(height)},
+		{LockTypeTime, LockValue(time)},
+	} {
+		lockedPrefix, unlockedPrefix := levelDBLockPrefixes(lock.Type)
+		// collect all locked outputs which can be unlocked
+		r := util.BytesPrefix(lockedPrefix)
+		if lock.Value < math.MaxUint64 {
+			r.Limit = levelDBLockKeyPrefix(lockedPrefix, lock.Value+1)
+		}
+		var keys [][]byte
+		keys, err = ldb.keys(r)
+		if err != nil {
+			return 0, types.Currency{}, fmt.Errorf("leveldb: failed to collect coin outputs to unlock: %v", err)
+		}
+		// locked -> unlocked
+		for _, key := range keys {
+			lockValue, id := levelDBLockKeyValues(lockedPrefix, key)
+			uh, value, err := ldb.updateCoinOutputState(id, CoinOutputStateLocked, CoinOutputStateLiquid)
+			if err != nil {
+				return 0, types.Currency{}, fmt.Errorf("leveldb: failed to unlock coin output: %v", err)
+			}
+			err = ldb.delete(key)
+			if err == nil {
+				err = ldb.put(levelDBLockKey(unlockedPrefix, lockValue, id), []byte{})
+			}
+			if err != nil {
+				return 0, types.Currency{}, fmt.Errorf("leveldb: failed to move lock of coin output %s: %v", id.String(), err)
+			}
+			err = ldb.updateWallet(uh, func(wallet *Wallet) error {
+				err := wallet.Balance.Locked.SubLockedCoinOutput(id)
+				if err != nil {
+					return err
+				}
+				wallet.Balance.Unlocked = wallet.Balance.Unlocked.Add(value)
+				return nil
+			})
+			if err != nil {
+				return 0, types.Currency{}, err
+			}
+			coins = coins.Add(value)
+			n++
+		}
+	}
+	return n, coins, nil
+}
+
+// RevertCoinOutputLocks implements Database.RevertCoinOutputLocks
+func (ldb *LevelDBDatabase) RevertCoinOutputLocks(height types.BlockHeight, time types.Timestamp) (n uint64, coins types.Currency, err error) {
+	ldb.networkTime, ldb.networkBlockHeight = time, height
+	for _, lock := range []struct {
+		Type  LockType
+		Value LockValue
+	}{
+		{LockTypeHeight, LockValue(height)},
+		{LockTypeTime, LockValue(time)},
+	} {
+		if lock.Value == math.MaxUint64 {
+			continue // no lock can exceed the max value
+		}
+		lockedPrefix, unlockedPrefix := levelDBLockPrefixes(lock.Type)
+		// collect all unlocked outputs which have to be locked again
+		r := util.BytesPrefix(unlockedPrefix)
+		r.Start = levelDBLockKeyPrefix(unlockedPrefix, lock.Value+1)
+		var keys [][]byte
+		keys, err = ldb.keys(r)
+		if err != nil {
+			return 0, types.Currency{}, fmt.Errorf("leveldb: failed to collect coin outputs to lock: %v", err)
+		}
+		// unlocked -> locked
+		for _, key := range keys {
+			lockValue, id := levelDBLockKeyValues(unlockedPrefix, key)
+			co, err := ldb.getCoinOutput(id)
+			if err != nil {
+				return 0, types.Currency{}, fmt.Errorf(
+					"leveldb: failed to lock coin output: cannot get coin output %s: %v", id.String(), err)
+			}
+			_, _, err = ldb.updateCoinOutputState(id, CoinOutputStateLiquid, CoinOutputStateLocked)
+			if err != nil {
+				return 0, types.Currency{}, fmt.Errorf("leveldb: failed to lock coin output: %v", err)
+			}
+			err = ldb.delete(key)
+			if err == nil {
+				err = ldb.put(levelDBLockKey(lockedPrefix, lockValue, id), []byte{})
+			}
+			if err != nil {
+				return 0, types.Currency{}, fmt.Errorf("leveldb: failed to move lock of coin output %s: %v", id.String(), err)
+			}
+			err = ldb.updateWallet(co.UnlockHash, func(wallet *Wallet) error {
+				wallet.Balance.Unlocked = wallet.Balance.Unlocked.Sub(co.CoinValue)
+				return wallet.Balance.Locked.AddLockedCoinOutput(id, WalletLockedOutput{
+					Amount:      co.CoinValue,
+					LockedUntil: ldb.lockValueAsLockTime(co.LockType, co.LockValue),
+					Description: co.Description,
+					Reason:      CoinOutputLockReason(co.LockType, co.Description),
+				})
+			})
+			if err != nil {
+				return 0, types.Currency{}, err
+			}
+			coins = coins.Add(co.CoinValue)
+			n++
+		}
+	}
+	return n, coins, nil
+}
+
+// SetMultisigAddresses implements Database.SetMultisigAddresses
+func (ldb *LevelDBDatabase) SetMultisigAddresses(address types.UnlockHash, owners []types.UnlockHash, signaturesRequired uint64) error {
+	var known bool
+	err := ldb.updateWallet(address, func(wallet *Wallet) error {
+		if len(wallet.MultiSignData.Owners) > 0 {
+			known = true
+			return nil // nothing to do
+		}
+		wallet.MultiSignData.SignaturesRequired = signaturesRequired
+		wallet.MultiSignData.Owners = make([]types.UnlockHash, len(owners))
+		copy(wallet.MultiSignData.Owners[:], owners[:])
+		return nil
+	})
+	if err != nil || known {
+		return err
+	}
+	// link the multisig address to all its owners
+	for _, owner := range owners {
+		err = ldb.updateWallet(owner, func(wallet *Wallet) error {
+			for _, uh := range wallet.MultiSignAddresses {
+				if uh == address {
+					return nil // nothing to do
+				}
+			}
+			wallet.MultiSignAddresses = append(wallet.MultiSignAddresses, address)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyCounterpartyTransfer implements Database.ApplyCounterpartyTransfer
+func (ldb *LevelDBDatabase) ApplyCounterpartyTransfer(from, to types.UnlockHash, value types.Currency) error {
+	err := ldb.updateCounterparty(from, to, func(cp *AddressCounterparty) {
+		cp.TransactionCount++
+		cp.Sent = cp.Sent.Add(value)
+	})
+	if err != nil {
+		return err
+	}
+	return ldb.updateCounterparty(to, from, func(cp *AddressCounterparty) {
+		cp.TransactionCount++
+		cp.Received = cp.Received.Add(value)
+	})
+}
+
+// RevertCounterpartyTransfer implements Database.RevertCounterpartyTransfer
+func (ldb *LevelDBDatabase) RevertCounterpartyTransfer(from, to types.UnlockHash, value types.Currency) error {
+	err := ldb.updateCounterparty(from, to, func(cp *AddressCounterparty) {
+		cp.TransactionCount--
+		cp.Sent = subCurrencyOrZero(cp.Sent, value)
+	})
+	if err != nil {
+		return err
+	}
+	return ldb.updateCounterparty(to, from, func(cp *AddressCounterparty) {
+		cp.TransactionCount--
+		cp.Received = subCurrencyOrZero(cp.Received, value)
+	})
+}
+
+// updateCounterparty updates the counterparty of an address, using the given update function.
+func (ldb *LevelDBDatabase) updateCounterparty(address, counterparty types.UnlockHash, update func(*AddressCounterparty)) error {
+	key := levelDBKey(levelDBPrefixCounterparties, address.String()+":"+counterparty.String())
+	var cp AddressCounterparty
+	err := ldb.getValue(key, &cp)
+	if err != nil && err != ErrNotFound {
+		return fmt.Errorf("leveldb: failed to get counterparty %s of %s: %v",
+			counterparty.String(), address.String(), err)
+	}
+	update(&cp)
+	if cp.TransactionCount == 0 {
+		err = ldb.delete(key)
+	} else {
+		err = ldb.putValue(key, cp)
+	}
+	if err != nil {
+		return fmt.Errorf("leveldb: failed to update counterparty %s of %s: %v",
+			counterparty.String(), address.String(), err)
+	}
+	return nil
+}
+
+// ApplyWalletGroupFlows implements Database.ApplyWalletGroupFlows
+func (ldb *LevelDBDatabase) ApplyWalletGroupFlows(timestamp types.Timestamp, flows WalletGroupFlows) error {
+	return ldb.updateWalletGroupFlows(timestamp, func(current WalletGroupFlows) WalletGroupFlows {
+		return current.Add(flows)
+	})
+}
+
+// RevertWalletGroupFlows implements Database.RevertWalletGroupFlows
+func (ldb *LevelDBDatabase) RevertWalletGroupFlows(timestamp types.Timestamp, flows WalletGroupFlows) error {
+	return ldb.updateWalletGroupFlows(timestamp, func(current WalletGroupFlows) WalletGroupFlows {
+		return current.Sub(flows)
+	})
+}
+
+// updateWalletGroupFlows updates both the total and the daily wallet group flows,
+// removing the daily flows once they're no longer used.
+func (ldb *LevelDBDatabase) updateWalletGroupFlows(timestamp types.Timestamp, update func(WalletGroupFlows) WalletGroupFlows) error {
+	for _, day := range []string{flowsFieldTotal, walletGroupFlowsDay(timestamp)} {
+		key := levelDBKey(levelDBPrefixFlows, day)
+		var flows WalletGroupFlows
+		err := ldb.getValue(key, &flows)
+		if err != nil && err != ErrNotFound {
+			return fmt.Errorf("leveldb: failed to get wallet group flows of %s: %v", day, err)
+		}
+		flows = update(flows)
+		if flows.IsZero() && day != flowsFieldTotal {
+			err = ldb.delete(key)
+		} else {
+			err = ldb.putValue(key, flows)
+		}
+		if err != nil {
+			return fmt.Errorf("leveldb: failed to update wallet group flows of %s: %v", day, err)
+		}
+	}
+	return nil
+}
+
+// GetWalletGroupFlows implements Database.GetWalletGroupFlows
+func (ldb *LevelDBDatabase) GetWalletGroupFlows() (total WalletGroupFlows, daily map[string]WalletGroupFlows, err error) {
+	keys, err := ldb.keys(util.BytesPrefix(levelDBPrefixFlows))
+	if err != nil {
+		return WalletGroupFlows{}, nil, fmt.Errorf("leveldb: failed to collect wallet group flows: %v", err)
+	}
+	daily = make(map[string]WalletGroupFlows, len(keys))
+	for _, key := range keys {
+		day := string(key[len(levelDBPrefixFlows):])
+		var flows WalletGroupFlows
+		err = ldb.getValue(key, &flows)
+		if err != nil {
+			return WalletGroupFlows{}, nil, fmt.Errorf("leveldb: failed to get wallet group flows of %s: %v", day, err)
+		}
+		if day == flowsFieldTotal {
+			total = flows
+		} else {
+			daily[day] = flows
+		}
+	}
+	return total, daily, nil
+}
+
+// ApplyMultisigSpend implements Database.ApplyMultisigSpend
+func (ldb *LevelDBDatabase) ApplyMultisigSpend(spend MultisigSpend) error {
+	key := levelDBKey(levelDBPrefixMultisigSpends, spend.Address.String()+":"+spend.CoinOutputID.String())
+	err := ldb.putValue(key, spend.Signers)
+	if err != nil {
+		return fmt.Errorf("leveldb: failed to store signers of multisig spend %s: %v", spend.CoinOutputID.String(), err)
+	}
+	for _, signer := range spend.Signers {
+		err = ldb.updateMultisigSignerStats(spend.Address, signer, func(stats *MultisigSignerStats) {
+			stats.SpendCount++
+			stats.Value = stats.Value.Add(spend.Value)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevertMultisigSpend implements Database.RevertMultisigSpend
+func (ldb *LevelDBDatabase) RevertMultisigSpend(spend MultisigSpend) error {
+	key := levelDBKey(levelDBPrefixMultisigSpends, spend.Address.String()+":"+spend.CoinOutputID.String())
+	err := ldb.delete(key)
+	if err != nil {
+		return fmt.Errorf("leveldb: failed to remove signers of multisig spend %s: %v", spend.CoinOutputID.String(), err)
+	}
+	for _, signer := range spend.Signers {
+		err = ldb.updateMultisigSignerStats(spend.Address, signer, func(stats *MultisigSignerStats) {
+			stats.SpendCount--
+			stats.Value = subCurrencyOrZero(stats.Value, spend.Value)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateMultisigSignerStats updates the signing stats of an owner of a multisig wallet,
+// using the given update function, removing the stats once the owner no longer signed any spend.
+func (ldb *LevelDBDatabase) updateMultisigSignerStats(address, signer types.UnlockHash, update func(*MultisigSignerStats)) error {
+	key := levelDBKey(levelDBPrefixMultisigSigners, address.String()+":"+signer.String())
+	var stats MultisigSignerStats
+	err := ldb.getValue(key, &stats)
+	if err != nil && err != ErrNotFound {
+		return fmt.Errorf("leveldb: failed to get signing stats of %s for %s: %v",
+			signer.String(), address.String(), err)
+	}
+	update(&stats)
+	if stats.SpendCount == 0 {
+		err = ldb.delete(key)
+	} else {
+		err = ldb.putValue(key, stats)
+	}
+	if err != nil {
+		return fmt.Errorf("leveldb: failed to update signing stats of %s for %s: %v",
+			signer.String(), address.String(), err)
+	}
+	return nil
+}
+
+// GetMultisigSpends implements Database.GetMultisigSpends
+func (ldb *LevelDBDatabase) GetMultisigSpends(address types.UnlockHash) (map[types.CoinOutputID][]types.UnlockHash, error) {
+	prefix := levelDBKey(levelDBPrefixMultisigSpends, address.String()+":")
+	keys, err := ldb.keys(util.BytesPrefix(prefix))
+	if err != nil {
+		return nil, fmt.Errorf("leveldb: failed to collect multisig spends of %s: %v", address.String(), err)
+	}
+	spends := make(map[types.CoinOutputID][]types.UnlockHash, len(keys))
+	for _, key := range keys {
+		var (
+			id      types.CoinOutputID
+			signers []types.UnlockHash
+		)
+		err = id.LoadString(string(key[len(prefix):]))
+		if err == nil {
+			err = ldb.getValue(key, &signers)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("leveldb: failed to get multisig spend %s of %s: %v",
+				key[len(prefix):], address.String(), err)
+		}
+		spends[id] = signers
+	}
+	return spends, nil
+}
+
+// GetMultisigSignerStats implements Database.GetMultisigSignerStats
+func (ldb *LevelDBDatabase) GetMultisigSignerStats(address types.UnlockHash) (map[types.UnlockHash]MultisigSignerStats, error) {
+	prefix := levelDBKey(levelDBPrefixMultisigSigners, address.String()+":")
+	keys, err := ldb.keys(util.BytesPrefix(prefix))
+	if err != nil {
+		return nil, fmt.Errorf("leveldb: failed to collect multisig signing stats of %s: %v", address.String(), err)
+	}
+	signers := make(map[types.UnlockHash]MultisigSignerStats, len(keys))
+	for _, key := range keys {
+		var (
+			signer types.UnlockHash
+			stats  MultisigSignerStats
+		)
+		err = signer.LoadString(string(key[len(prefix):]))
+		if err == nil {
+			err = ldb.getValue(key, &stats)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("leveldb: failed to get signing stats of %s for %s: %v",
+				key[len(prefix):], address.String(), err)
+		}
+		signers[signer] = stats
+	}
+	return signers, nil
+}
+
+// SetBlockSummary implements Database.SetBlockSummary
+func (ldb *LevelDBDatabase) SetBlockSummary(summary BlockSummary) error {
+	return ldb.putValue(levelDBLockKeyPrefix(levelDBPrefixBlockSummaries, LockValue(summary.Height)), summary)
+}
+
+// RevertBlockSummary implements Database.RevertBlockSummary
+func (ldb *LevelDBDatabase) RevertBlockSummary(height types.BlockHeight) error {
+	return ldb.delete(levelDBLockKeyPrefix(levelDBPrefixBlockSummaries, LockValue(height)))
+}
+
+// GetBlockSummary implements Database.GetBlockSummary
+func (ldb *LevelDBDatabase) GetBlockSummary(height types.BlockHeight) (BlockSummary, error) {
+	var summary BlockSummary
+	switch err := ldb.getValue(levelDBLockKeyPrefix(levelDBPrefixBlockSummaries, LockValue(height)), &summary); err {
+	case nil:
+		return summary, nil
+	case ErrNotFound:
+		return BlockSummary{}, ErrNotFound
+	default:
+		return BlockSummary{}, fmt.Errorf("leveldb: failed to get summary of block %d: %v", height, err)
+	}
+}
+
+// GetWallet implements Database.GetWallet
+func (ldb *LevelDBDatabase) GetWallet(address types.UnlockHash) (Wallet, error) {
+	var wallet Wallet
+	switch err := ldb.getValue(levelDBKey(levelDBPrefixWallets, address.String()), &wallet); err {
+	case nil:
+		err = upgradeWallet(&wallet, ldb.networkTime)
+		if err != nil {
+			return Wallet{}, fmt.Errorf("leveldb: failed to upgrade wallet for %s: %v", address.String(), err)
+		}
+		return wallet, nil
+	case ErrNotFound:
+		return Wallet{}, ErrNotFound
+	default:
+		return Wallet{}, fmt.Errorf("leveldb: failed to get wallet for %s: %v", address.String(), err)
+	}
+}
+
+// SampleAddresses implements Database.SampleAddresses
+//
+// As LevelDB has no native random sampling, all addresses are iterated,
+// using reservoir sampling to select the sampled addresses.
+func (ldb *LevelDBDatabase) SampleAddresses(n int) ([]types.UnlockHash, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	var addresses []types.UnlockHash
+	it := ldb.db.NewIterator(util.BytesPrefix(levelDBPrefixWallets), nil)
+	defer it.Release()
+	for i := 0; it.Next(); i++ {
+		var uh types.UnlockHash
+		err := uh.LoadString(string(it.Key()[len(levelDBPrefixWallets):]))
+		if err != nil {
+			return nil, fmt.Errorf("leveldb: failed to load address %q: %v", it.Key(), err)
+		}
+		if i < n {
+			addresses = append(addresses, uh)
+		} else if j := rnd.Intn(i + 1); j < n {
+			addresses[j] = uh
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, fmt.Errorf("leveldb: failed to iterate over addresses: %v", err)
+	}
+	return addresses, nil
+}
+
+// GetCoinOutput implements Database.GetCoinOutput
+func (ldb *LevelDBDatabase) GetCoinOutput(id types.CoinOutputID) (CoinOutputInfo, error) {
+	co, err := ldb.getCoinOutput(id)
+	switch err {
+	case nil:
+	case ErrNotFound:
+		return CoinOutputInfo{}, ErrNotFound
+	default:
+		return CoinOutputInfo{}, fmt.Errorf("leveldb: failed to get coin output %s: %v", id.String(), err)
+	}
+	info := CoinOutputInfo{
+		ID:           id,
+		UnlockHash:   co.UnlockHash,
+		Value:        co.CoinValue,
+		State:        co.State,
+		LockType:     co.LockType,
+		LockValue:    co.LockValue,
+		Description:  co.Description,
+		RawCondition: co.RawCondition,
+	}
+	err = info.decodeCondition()
+	if err != nil {
+		return CoinOutputInfo{}, fmt.Errorf(
+			"leveldb: failed to decode raw condition of coin output %s: %v", id.String(), err)
+	}
+	return info, nil
+}
+
+func (ldb *LevelDBDatabase) lockValueAsLockTime(lt LockType, value LockValue) LockValue {
+	switch lt {
+	case LockTypeTime:
+		return value
+	case LockTypeHeight:
+		return LockValue(ldb.networkTime) + (value-LockValue(ldb.networkBlockHeight))*ldb.blockFrequency
+	default:
+		panic(fmt.Sprintf("invalid lock type %d", lt))
+	}
+}
+
+// levelDBKey creates a key using the given prefix and (string) suffix.
+func levelDBKey(prefix []byte, suffix string) []byte {
+	key := make([]byte, len(prefix)+len(suffix))
+	copy(key, prefix)
+	copy(key[len(prefix):], suffix)
+	return key
+}
+
+// levelDBLockPrefixes returns the key prefixes used to store the locks of the given type,
+// of the coin outputs which are still locked and of those that are unlocked.
+func levelDBLockPrefixes(lt LockType) (locked, unlocked []byte) {
+	switch lt {
+	case LockTypeHeight:
+		return levelDBPrefixHeightLocksLocked, levelDBPrefixHeightLocksUnlocked
+	case LockTypeTime:
+		return levelDBPrefixTimeLocksLocked, levelDBPrefixTimeLocksUnlocked
+	default:
+		panic(fmt.Sprintf("invalid lock type %d", lt))
+	}
+}
+
+// levelDBLockKeyPrefix creates the prefix of all lock keys of the given lock value.
+func levelDBLockKeyPrefix(prefix []byte, lockValue LockValue) []byte {
+	key := make([]byte, len(prefix)+8)
+	copy(key, prefix)
+	binary.BigEndian.PutUint64(key[len(prefix):], uint64(lockValue))
+	return key
+}
+
+// levelDBLockKey creates the key of a lock, ordered by the lock value,
+// as to be able to iterate over all locks within a given range.
+func levelDBLockKey(prefix []byte, lockValue LockValue, id types.CoinOutputID) []byte {
+	return append(levelDBLockKeyPrefix(prefix, lockValue), id[:]...)
+}
+
+// levelDBLockKeyValues returns the lock value and coin output ID of a lock key.
+func levelDBLockKeyValues(prefix, key []byte) (lockValue LockValue, id types.CoinOutputID) {
+	key = key[len(prefix):]
+	lockValue = LockValue(binary.BigEndian.Uint64(key[:8]))
+	copy(id[:], key[8:])
+	return
+}
@@ -0,0 +1,63 @@
+package rexplorer
+
+import (
+	"fmt"
+
+	"github.com/rivine/rivine/crypto"
+	"github.com/rivine/rivine/types"
+)
+
+// maturedMinerPayouts returns the amount and total value of the miner payouts (un)locked
+// together with the coin outputs locked until the given height, being the miner payouts
+// of the block which reaches maturity at that height.
+//
+// The miner payouts are found using the summary of that block, such that nothing is returned
+// for the blocks applied prior to the introduction of block summaries.
+// Burned miner payouts are skipped, as they are created as unlocked (see newCoinOutputCreation).
+func (explorer *Explorer) maturedMinerPayouts(height types.BlockHeight) (n uint64, coins types.Currency) {
+	delay := explorer.chainCts.MaturityDelay
+	if delay == 0 || height < delay {
+		return 0, types.Currency{}
+	}
+	summary, err := explorer.db.GetBlockSummary(height - delay)
+	if err == ErrNotFound {
+		return 0, types.Currency{}
+	}
+	if err != nil {
+		panic(fmt.Sprintf("failed to get summary of block %d: %v", height-delay, err))
+	}
+	for i := uint64(0); ; i++ {
+		id := types.CoinOutputID(crypto.HashAll(summary.ID, i)) // see types.Block.MinerPayoutID
+		info, err := explorer.db.GetCoinOutput(id)
+		if err == ErrNotFound {
+			return n, coins
+		}
+		if err != nil {
+			panic(fmt.Sprintf("failed to get miner payout %s of block %d: %v", id.String(), height-delay, err))
+		}
+		if info.LockType == LockTypeNone {
+			continue
+		}
+		n++
+		coins = coins.Add(info.Value)
+	}
+}
+
+// lockMaturity registers miner payouts which are locked until they reach maturity.
+func (stats *NetworkStats) lockMaturity(n uint64, coins types.Currency) {
+	stats.MaturityLockedCoinOutputCount += n
+	stats.MaturityLockedCoins = stats.MaturityLockedCoins.Add(coins)
+}
+
+// unlockMaturity unregisters miner payouts which were locked until they reach maturity,
+// clamping the stats to zero, as the miner payouts locked prior to the introduction of these stats aren't registered.
+func (stats *NetworkStats) unlockMaturity(n uint64, coins types.Currency) {
+	if n > stats.MaturityLockedCoinOutputCount {
+		n = stats.MaturityLockedCoinOutputCount
+	}
+	stats.MaturityLockedCoinOutputCount -= n
+	if coins.Cmp(stats.MaturityLockedCoins) > 0 {
+		coins = stats.MaturityLockedCoins
+	}
+	stats.MaturityLockedCoins = stats.MaturityLockedCoins.Sub(coins)
+}
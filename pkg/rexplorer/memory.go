@@ -0,0 +1,2437 @@
+package rexplorer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rivine/rivine/crypto"
+	"github.com/rivine/rivine/types"
+)
+
+type (
+	// MemoryDatabase is an in-memory Database implementation, storing all values JSON-encoded in maps,
+	// such that the Explorer (e.g. Explorer.ProcessConsensusChange) can be unit-tested without a database server.
+	// Nothing is persisted, all data is lost once the database is closed.
+	//
+	// All values are stored by type and key:
+	//
+	//	  state, network, chainparams, aliases, stats, health		internal state, network info, chain parameters, stats and health
+	//	  wallet <address>											Wallet, for all unique addresses
+	//	  balancedistribution <range>								amount of wallets with a non-zero coin balance within the range
+	//	  coinoutput <coinOutputID>									DatabaseCoinOutput, for all coin outputs
+	//	  activity <address>										AddressActivity
+	//	  activityundo <blockHeight>								previous AddressActivity of the addresses active in the block
+	//	  minerpayouts <address>									all MinerPayoutRecord values received by the address, oldest first
+	//	  history <address>											all AddressHistoryRecord values of the address, oldest first
+	//	  totals <address>											AddressTotals
+	//	  dailystats <YYYY-MM-DD>									DailyStats
+	//	  dailystatsundo <blockHeight>								DailyStats rolled up for the block
+	//	  rollingstats <window>										RollingStats
+	//	  rollingstatsblock <blockHeight>							stats, timestamp and replaced RollingStats of the block
+	//	  statshistory <YYYY-MM-DD>									NetworkStatsSnapshot
+	//	  dailytop <YYYY-MM-DD>:sent|received:<address>			coins sent or received by a top address of the day
+	//	  blockcreator <address>									BlockCreatorStats
+	//	  counterparty <address>:<counterparty>						AddressCounterparty
+	//	  flows total|<YYYY-MM-DD>									WalletGroupFlows
+	//	  multisigspend <address>:<coinOutputID>					signers of a spent multisig coin output
+	//	  multisigsigner <address>:<signer>							MultisigSignerStats
+	//	  blocksummary <blockHeight>								BlockSummary
+	//	  block <blockHeight>										BlockRecord
+	//	  blockid <blockID>											height of the block
+	//	  tx <txID>													TransactionRecord
+	//	  provenance <coinOutputID>									CoinOutputProvenance
+	//	  blockstakeoutput <blockStakeOutputID>						DatabaseBlockStakeOutput
+	//	  atomicswap <coinOutputID>									AtomicSwapContract
+	//	  atomicswapaddress <address>:<coinOutputID>				link from the sender and receiver of an atomic swap contract
+	//	  data <hash>:<txID>										link from the hash of the arbitrary data of a transaction
+	//	  dataprefix <prefixHex>:<txID>								arbitrary data of a transaction, by its prefix
+	//	  mintconditions											all MintConditionChange values, oldest first
+	//	  bot <botID>												all states of a BotRecord, latest last
+	//	  botname <name>											ID of the 3Bot owning the name
+	//	  erc20address <erc20Address>								TFT address registered for the ERC20 address
+	//	  erc20unlockhash <address>									ERC20 address registered for the TFT address
+	//	  erc20stats												ERC20BridgeStats
+	//	  genesis													GenesisAllocation
+	//
+	// The stored values can be copied using State, and compared to the current values using Diff,
+	// as to assert that reverting a consensus change restores the values as they were prior to applying it,
+	// see VerifySymmetry. Contrary to the RedisDatabase, all counterparties of an address are stored.
+	MemoryDatabase struct {
+		// name of the driver, used to prefix errors
+		name string
+		// all (JSON-encoded) values, by type and key
+		values MemoryDatabaseState
+		// the lock values of all locked and unlocked coin outputs, by lock type
+		locked, unlocked map[LockType]map[types.CoinOutputID]LockValue
+		// the lock values of all block stake outputs locked by a lock type (no matter their state), by lock type
+		blockStakeLocks map[LockType]map[types.BlockStakeOutputID]LockValue
+		// notified of every change, nil if not used
+		observer func(typ, key string, value json.RawMessage, deleted bool) error
+
+		blockFrequency LockValue
+		// value of a single coin, used to bucket the wallets by balance (see BalanceDistribution)
+		oneCoin types.Currency
+
+		// cached version of the chain stats
+		networkBlockHeight types.BlockHeight
+		networkTime        types.Timestamp
+	}
+
+	// MemoryDatabaseState is a copy of all (JSON-encoded) values of a MemoryDatabase, by type and key.
+	MemoryDatabaseState map[string]map[string]json.RawMessage
+)
+
+const (
+	memoryTypeState          = "state"
+	memoryTypeNetwork        = "network"
+	memoryTypeParams         = "chainparams"
+	memoryTypeAliases        = "aliases"
+	memoryTypeWebhooks       = "webhooks"
+	memoryTypeStats          = "stats"
+	memoryTypeHealth         = "health"
+	memoryTypeWallet         = "wallet"
+	memoryTypeDistribution   = "balancedistribution"
+	memoryTypeCoinOutput     = "coinoutput"
+	memoryTypeMinerPayouts   = "minerpayouts"
+	memoryTypeHistory        = "history"
+	memoryTypeTotals         = "totals"
+	memoryTypeDailyStats     = "dailystats"
+	memoryTypeDailyStatsUndo = "dailystatsundo"
+	memoryTypeRollingStats   = "rollingstats"
+	memoryTypeRollingBlock   = "rollingstatsblock"
+	memoryTypeStatsHistory   = "statshistory"
+	memoryTypeDailyTop       = "dailytop"
+	memoryTypeBlockCreator   = "blockcreator"
+	memoryTypeCounterparty   = "counterparty"
+	memoryTypeActivity       = "activity"
+	memoryTypeActivityUndo   = "activityundo"
+	memoryTypeFlows          = "flows"
+	memoryTypeMultisigSpend  = "multisigspend"
+	memoryTypeMultisigSigner = "multisigsigner"
+	memoryTypeBlockSummary   = "blocksummary"
+	memoryTypeBlock          = "block"
+	memoryTypeBlockID        = "blockid"
+	memoryTypeTransaction    = "tx"
+	memoryTypeProvenance     = "provenance"
+	memoryTypeBlockStake     = "blockstakeoutput"
+	memoryTypeAtomicSwap     = "atomicswap"
+	memoryTypeAtomicSwapLink = "atomicswapaddress"
+	memoryTypeDataHash       = "data"
+	memoryTypeDataPrefix     = "dataprefix"
+	memoryTypeMintCondition  = "mintconditions"
+	memoryTypeBot            = "bot"
+	memoryTypeBotName        = "botname"
+	memoryTypeERC20Address   = "erc20address"
+	memoryTypeERC20UH        = "erc20unlockhash"
+	memoryTypeERC20Stats     = "erc20stats"
+	memoryTypeGenesis        = "genesis"
+)
+
+var (
+	_ BlockDatabase                = (*MemoryDatabase)(nil)
+	_ TransactionDatabase          = (*MemoryDatabase)(nil)
+	_ BlockStakeDatabase           = (*MemoryDatabase)(nil)
+	_ AtomicSwapDatabase           = (*MemoryDatabase)(nil)
+	_ ArbitraryDataDatabase        = (*MemoryDatabase)(nil)
+	_ MintConditionDatabase        = (*MemoryDatabase)(nil)
+	_ ThreeBotDatabase             = (*MemoryDatabase)(nil)
+	_ ERC20Database                = (*MemoryDatabase)(nil)
+	_ CoinOutputProvenanceDatabase = (*MemoryDatabase)(nil)
+	_ AddressActivityDatabase      = (*MemoryDatabase)(nil)
+	_ RichListDatabase             = (*MemoryDatabase)(nil)
+	_ BalanceDistributionDatabase  = (*MemoryDatabase)(nil)
+	_ CoinOutputSetDatabase        = (*MemoryDatabase)(nil)
+	_ MinerPayoutDatabase          = (*MemoryDatabase)(nil)
+	_ BlockCreatorDatabase         = (*MemoryDatabase)(nil)
+	_ GenesisDatabase              = (*MemoryDatabase)(nil)
+	_ AddressHistoryDatabase       = (*MemoryDatabase)(nil)
+	_ AddressTotalsDatabase        = (*MemoryDatabase)(nil)
+	_ DailyStatsDatabase           = (*MemoryDatabase)(nil)
+	_ RollingStatsDatabase         = (*MemoryDatabase)(nil)
+	_ NetworkStatsHistoryDatabase  = (*MemoryDatabase)(nil)
+	_ DailyTopAddressesDatabase    = (*MemoryDatabase)(nil)
+	_ WebhookDatabase              = (*MemoryDatabase)(nil)
+)
+
+func init() {
+	RegisterDatabaseDriver("memory", func(cfg DatabaseConfig) (Database, error) {
+		return NewMemoryDatabase(cfg.BlockchainInfo, cfg.ChainConstants)
+	})
+}
+
+// NewMemoryDatabase creates an empty in-memory Database,
+// see MemoryDatabase for more information.
+func NewMemoryDatabase(bcInfo types.BlockchainInfo, chainCts types.ChainConstants) (*MemoryDatabase, error) {
+	mdb := newMemoryDatabase("memory", chainCts)
+	err := mdb.registerOrValidateNetworkInfo(bcInfo)
+	if err != nil {
+		return nil, err
+	}
+	return mdb, nil
+}
+
+// newMemoryDatabase creates an empty in-memory Database, prefixing its errors with the given driver name.
+func newMemoryDatabase(name string, chainCts types.ChainConstants) *MemoryDatabase {
+	return &MemoryDatabase{
+		name:     name,
+		values:   make(MemoryDatabaseState),
+		locked:   make(map[LockType]map[types.CoinOutputID]LockValue),
+		unlocked: make(map[LockType]map[types.CoinOutputID]LockValue),
+		blockStakeLocks: map[LockType]map[types.BlockStakeOutputID]LockValue{
+			LockTypeHeight: make(map[types.BlockStakeOutputID]LockValue),
+			LockTypeTime:   make(map[types.BlockStakeOutputID]LockValue),
+		},
+		blockFrequency: LockValue(chainCts.BlockFrequency),
+		oneCoin:        chainCts.CurrencyUnits.OneCoin,
+	}
+}
+
+// Close implements Database.Close
+//
+// drops all values
+func (mdb *MemoryDatabase) Close() error {
+	mdb.values = make(MemoryDatabaseState)
+	mdb.locked = make(map[LockType]map[types.CoinOutputID]LockValue)
+	mdb.unlocked = make(map[LockType]map[types.CoinOutputID]LockValue)
+	return nil
+}
+
+// State returns a copy of all stored values.
+func (mdb *MemoryDatabase) State() MemoryDatabaseState {
+	state := make(MemoryDatabaseState, len(mdb.values))
+	for typ, values := range mdb.values {
+		state[typ] = make(map[string]json.RawMessage, len(values))
+		for key, value := range values {
+			state[typ][key] = value // values are replaced, never modified
+		}
+	}
+	return state
+}
+
+// Diff returns the (sorted) "<type>:<key>" identifiers of all values which differ
+// in between the given state and the stored values, ignoring the given types.
+// A missing wallet equals an empty wallet, as wallets are never removed, not even once emptied.
+// The unlock horizons of wallets are ignored, as those are only as recent as the last update of a wallet.
+func (mdb *MemoryDatabase) Diff(state MemoryDatabaseState, ignoredTypes ...string) []string {
+	ignored := make(map[string]struct{}, len(ignoredTypes))
+	for _, typ := range ignoredTypes {
+		ignored[typ] = struct{}{}
+	}
+	var (
+		diff []string
+		seen = make(map[string]struct{})
+	)
+	for _, values := range []MemoryDatabaseState{mdb.values, state} {
+		for typ, values := range values {
+			if _, ok := ignored[typ]; ok {
+				continue
+			}
+			for key := range values {
+				id := typ + ":" + key
+				if _, ok := seen[id]; ok {
+					continue
+				}
+				seen[id] = struct{}{}
+				if !memoryValuesEqual(typ, mdb.values[typ][key], state[typ][key]) {
+					diff = append(diff, id)
+				}
+			}
+		}
+	}
+	sort.Strings(diff)
+	return diff
+}
+
+// memoryValuesEqual returns true if both (JSON-encoded) values of the given type are equal,
+// a missing (nil) wallet being equal to an empty wallet, ignoring the unlock horizons of wallets,
+// and an empty description of a locked output being equal to a missing description.
+func memoryValuesEqual(typ string, a, b json.RawMessage) bool {
+	if typ == memoryTypeWallet {
+		a, b = memoryComparableWallet(a), memoryComparableWallet(b)
+	}
+	return bytes.Equal(a, b)
+}
+
+// memoryComparableWallet re-encodes the given (JSON-encoded) wallet as compared by memoryValuesEqual,
+// returning it as is if it cannot be decoded.
+func memoryComparableWallet(value json.RawMessage) json.RawMessage {
+	var wallet Wallet
+	if value != nil {
+		err := json.Unmarshal(value, &wallet)
+		if err != nil {
+			return value
+		}
+	}
+	wallet.Balance.Locked.Horizons = nil
+	for id, output := range wallet.Balance.Locked.Outputs {
+		if len(output.Description) == 0 {
+			output.Description = nil
+			wallet.Balance.Locked.Outputs[id] = output
+		}
+	}
+	return MustMarshal(jsonEncoder{}, wallet)
+}
+
+// VerifySymmetry verifies that the revert function restores all values changed by the apply function,
+// e.g. by processing a consensus change and the consensus change reverting it,
+// returning an error listing all values which aren't restored.
+//
+// The internal state and chain health are ignored, as those track the (last) processed consensus change.
+// Note that the multisig properties of wallets are never reverted,
+// such that reverting the first spend to or from a multisig wallet is reported as well.
+func (mdb *MemoryDatabase) VerifySymmetry(apply, revert func()) error {
+	state := mdb.State()
+	apply()
+	revert()
+	diff := mdb.Diff(state, memoryTypeState, memoryTypeHealth)
+	if len(diff) > 0 {
+		return fmt.Errorf("%s: %d values not restored by revert: %s", mdb.name, len(diff), strings.Join(diff, ", "))
+	}
+	return nil
+}
+
+// reindexLocks rebuilds the lock indices from the stored coin and block stake outputs.
+func (mdb *MemoryDatabase) reindexLocks() error {
+	for key, value := range mdb.values[memoryTypeCoinOutput] {
+		var (
+			id types.CoinOutputID
+			co DatabaseCoinOutput
+		)
+		err := id.LoadString(key)
+		if err == nil {
+			err = json.Unmarshal(value, &co)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid coin output %s: %v", key, err)
+		}
+		mdb.indexLock(id, co)
+	}
+	for key, value := range mdb.values[memoryTypeBlockStake] {
+		var (
+			id  types.BlockStakeOutputID
+			bso DatabaseBlockStakeOutput
+		)
+		err := id.LoadString(key)
+		if err == nil {
+			err = json.Unmarshal(value, &bso)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid block stake output %s: %v", key, err)
+		}
+		if bso.LockType != LockTypeNone {
+			mdb.blockStakeLocks[bso.LockType][id] = bso.LockValue
+		}
+	}
+	return nil
+}
+
+// observe notifies the observer (if any) of a change.
+func (mdb *MemoryDatabase) observe(typ, key string, value json.RawMessage, deleted bool) error {
+	if mdb.observer == nil {
+		return nil
+	}
+	return mdb.observer(typ, key, value, deleted)
+}
+
+// set updates (or deletes) a value in memory.
+func (mdb *MemoryDatabase) set(typ, key string, value json.RawMessage, deleted bool) {
+	values, ok := mdb.values[typ]
+	if !ok {
+		values = make(map[string]json.RawMessage)
+		mdb.values[typ] = values
+	}
+	if deleted {
+		delete(values, key)
+	} else {
+		values[key] = value
+	}
+}
+
+// getValue decodes a value, returning ErrNotFound if it isn't stored.
+func (mdb *MemoryDatabase) getValue(typ, key string, v interface{}) error {
+	b, ok := mdb.values[typ][key]
+	if !ok {
+		return ErrNotFound
+	}
+	return json.Unmarshal(b, v)
+}
+
+// putValue encodes and stores a value.
+func (mdb *MemoryDatabase) putValue(typ, key string, v interface{}) error {
+	b := MustMarshal(jsonEncoder{}, v)
+	mdb.set(typ, key, b, false)
+	return mdb.observe(typ, key, b, false)
+}
+
+// delete deletes a value, notifying the observer only if the value was stored.
+func (mdb *MemoryDatabase) delete(typ, key string) error {
+	if _, ok := mdb.values[typ][key]; !ok {
+		return nil
+	}
+	mdb.set(typ, key, nil, true)
+	return mdb.observe(typ, key, nil, true)
+}
+
+// keys returns all (sorted) keys of the given type, which start with the given prefix.
+func (mdb *MemoryDatabase) keys(typ, prefix string) []string {
+	var keys []string
+	for key := range mdb.values[typ] {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// registerOrValidateNetworkInfo registeres the network name and chain name if it doesn't exist yet,
+// otherwise it ensures that the returned network info matches the expected network info.
+func (mdb *MemoryDatabase) registerOrValidateNetworkInfo(bcInfo types.BlockchainInfo) error {
+	networkInfo := NetworkInfo{
+		ChainName:   bcInfo.Name,
+		NetworkName: bcInfo.NetworkName,
+	}
+	var receivedNetworkInfo NetworkInfo
+	switch err := mdb.getValue(memoryTypeNetwork, "", &receivedNetworkInfo); err {
+	case nil:
+	case ErrNotFound:
+		err = mdb.putValue(memoryTypeNetwork, "", networkInfo)
+		if err != nil {
+			return fmt.Errorf("failed to register network info: %v", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("failed to validate network info: %v", err)
+	}
+	if receivedNetworkInfo != networkInfo {
+		return fmt.Errorf("cannot store data for chain %s/%s: db has already data for chain %s/%s stored",
+			networkInfo.ChainName, networkInfo.NetworkName,
+			receivedNetworkInfo.ChainName, receivedNetworkInfo.NetworkName)
+	}
+	return nil
+}
+
+// GetExplorerState implements Database.GetExplorerState
+func (mdb *MemoryDatabase) GetExplorerState() (ExplorerState, error) {
+	var state ExplorerState
+	switch err := mdb.getValue(memoryTypeState, "", &state); err {
+	case nil:
+		return state, nil
+	case ErrNotFound:
+		// default to fresh explorer state if not stored yet
+		return NewExplorerState(), nil
+	default:
+		return ExplorerState{}, err
+	}
+}
+
+// SetExplorerState implements Database.SetExplorerState
+func (mdb *MemoryDatabase) SetExplorerState(state ExplorerState) error {
+	return mdb.putValue(memoryTypeState, "", state)
+}
+
+// GetNetworkStats implements Database.GetNetworkStats
+func (mdb *MemoryDatabase) GetNetworkStats() (NetworkStats, error) {
+	var stats NetworkStats
+	switch err := mdb.getValue(memoryTypeStats, "", &stats); err {
+	case nil:
+		err = upgradeNetworkStats(&stats)
+		if err != nil {
+			return NetworkStats{}, fmt.Errorf("%s: failed to upgrade network stats: %v", mdb.name, err)
+		}
+	case ErrNotFound:
+		// default to fresh network stats if not stored yet
+		stats = NewNetworkStats()
+	default:
+		return NetworkStats{}, err
+	}
+	mdb.networkTime, mdb.networkBlockHeight = stats.Timestamp, stats.BlockHeight
+	return stats, nil
+}
+
+// SetNetworkStats implements Database.SetNetworkStats
+func (mdb *MemoryDatabase) SetNetworkStats(stats NetworkStats) error {
+	err := mdb.putValue(memoryTypeStats, "", stats)
+	if err != nil {
+		return err
+	}
+	mdb.networkTime, mdb.networkBlockHeight = stats.Timestamp, stats.BlockHeight
+	return nil
+}
+
+// SetChainHealth implements Database.SetChainHealth
+func (mdb *MemoryDatabase) SetChainHealth(health ChainHealth) error {
+	return mdb.putValue(memoryTypeHealth, "", health)
+}
+
+// GetChainParametersHistory implements Database.GetChainParametersHistory
+func (mdb *MemoryDatabase) GetChainParametersHistory() ([]ChainParametersRecord, error) {
+	var history []ChainParametersRecord
+	switch err := mdb.getValue(memoryTypeParams, "", &history); err {
+	case nil, ErrNotFound:
+		// no history is stored yet for a fresh database
+		return history, nil
+	default:
+		return nil, err
+	}
+}
+
+// SetChainParametersHistory implements Database.SetChainParametersHistory
+func (mdb *MemoryDatabase) SetChainParametersHistory(history []ChainParametersRecord) error {
+	return mdb.putValue(memoryTypeParams, "", history)
+}
+
+// GetAddressAliases implements Database.GetAddressAliases
+func (mdb *MemoryDatabase) GetAddressAliases() ([]AddressAlias, error) {
+	var aliases []AddressAlias
+	switch err := mdb.getValue(memoryTypeAliases, "", &aliases); err {
+	case nil, ErrNotFound:
+		// no aliases are recorded yet
+		return aliases, nil
+	default:
+		return nil, err
+	}
+}
+
+// SetAddressAliases implements Database.SetAddressAliases
+func (mdb *MemoryDatabase) SetAddressAliases(aliases []AddressAlias) error {
+	return mdb.putValue(memoryTypeAliases, "", aliases)
+}
+
+// GetWebhooks implements WebhookDatabase.GetWebhooks
+func (mdb *MemoryDatabase) GetWebhooks() ([]Webhook, error) {
+	var webhooks []Webhook
+	switch err := mdb.getValue(memoryTypeWebhooks, "", &webhooks); err {
+	case nil, ErrNotFound:
+		// no webhooks are registered yet
+		return webhooks, nil
+	default:
+		return nil, err
+	}
+}
+
+// SetWebhooks implements WebhookDatabase.SetWebhooks
+func (mdb *MemoryDatabase) SetWebhooks(webhooks []Webhook) error {
+	return mdb.putValue(memoryTypeWebhooks, "", webhooks)
+}
+
+// getCoinOutput gets a stored coin output, returning ErrNotFound if it isn't stored.
+func (mdb *MemoryDatabase) getCoinOutput(id types.CoinOutputID) (co DatabaseCoinOutput, err error) {
+	err = mdb.getValue(memoryTypeCoinOutput, id.String(), &co)
+	return
+}
+
+// putCoinOutput stores a coin output, indexing its lock (if any).
+func (mdb *MemoryDatabase) putCoinOutput(id types.CoinOutputID, co DatabaseCoinOutput) error {
+	mdb.indexLock(id, co)
+	return mdb.putValue(memoryTypeCoinOutput, id.String(), co)
+}
+
+// indexLock indexes the lock of the given coin output as locked or unlocked (depending on its state),
+// in case the coin output is locked by a lock type.
+func (mdb *MemoryDatabase) indexLock(id types.CoinOutputID, co DatabaseCoinOutput) {
+	if co.LockType == LockTypeNone {
+		return
+	}
+	mdb.unindexLock(id, co.LockType)
+	index := mdb.unlocked
+	if co.State == CoinOutputStateLocked {
+		index = mdb.locked
+	}
+	locks, ok := index[co.LockType]
+	if !ok {
+		locks = make(map[types.CoinOutputID]LockValue)
+		index[co.LockType] = locks
+	}
+	locks[id] = co.LockValue
+}
+
+// unindexLock removes the lock of the given coin output from the lock indices.
+func (mdb *MemoryDatabase) unindexLock(id types.CoinOutputID, lt LockType) {
+	delete(mdb.locked[lt], id)
+	delete(mdb.unlocked[lt], id)
+}
+
+// updateWallet updates the wallet of the given address using the given update function,
+// creating the wallet if it doesn't exist yet.
+func (mdb *MemoryDatabase) updateWallet(address types.UnlockHash, update func(*Wallet) error) error {
+	var wallet Wallet
+	err := mdb.getValue(memoryTypeWallet, address.String(), &wallet)
+	if err != nil && err != ErrNotFound {
+		return fmt.Errorf("%s: failed to get wallet for %s: %v", mdb.name, address.String(), err)
+	}
+	previous := wallet.Balance.Unlocked.Add(wallet.Balance.Locked.Total)
+	err = update(&wallet)
+	if err != nil {
+		return fmt.Errorf("%s: failed to update wallet for %s: %v", mdb.name, address.String(), err)
+	}
+	wallet.Balance.Locked.updateHorizons(mdb.networkTime)
+	err = mdb.putValue(memoryTypeWallet, address.String(), wallet)
+	if err != nil {
+		return fmt.Errorf("%s: failed to set wallet for %s: %v", mdb.name, address.String(), err)
+	}
+	return mdb.updateBalanceDistribution(previous, wallet.Balance.Unlocked.Add(wallet.Balance.Locked.Total))
+}
+
+// updateBalanceDistribution moves a wallet from the bucket of its previous balance to the bucket of its current balance,
+// wallets with a zero balance not being counted.
+func (mdb *MemoryDatabase) updateBalanceDistribution(previous, current types.Currency) error {
+	labels := BalanceDistributionLabels()
+	update := func(balance types.Currency, delta int) error {
+		if balance.IsZero() {
+			return nil
+		}
+		label := labels[balanceDistributionBucket(balance, mdb.oneCoin)]
+		var count int
+		err := mdb.getValue(memoryTypeDistribution, label, &count)
+		if err != nil && err != ErrNotFound {
+			return fmt.Errorf("%s: failed to get balance distribution of %s: %v", mdb.name, label, err)
+		}
+		count += delta
+		if count <= 0 {
+			err = mdb.delete(memoryTypeDistribution, label)
+		} else {
+			err = mdb.putValue(memoryTypeDistribution, label, count)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: failed to update balance distribution of %s: %v", mdb.name, label, err)
+		}
+		return nil
+	}
+	if balanceDistributionBucket(previous, mdb.oneCoin) == balanceDistributionBucket(current, mdb.oneCoin) &&
+		previous.IsZero() == current.IsZero() {
+		return nil
+	}
+	err := update(previous, -1)
+	if err != nil {
+		return err
+	}
+	return update(current, 1)
+}
+
+// GetBalanceDistribution implements BalanceDistributionDatabase.GetBalanceDistribution
+func (mdb *MemoryDatabase) GetBalanceDistribution() (BalanceDistribution, error) {
+	var dist BalanceDistribution
+	for bucket, label := range BalanceDistributionLabels() {
+		err := mdb.getValue(memoryTypeDistribution, label, &dist[bucket])
+		if err != nil && err != ErrNotFound {
+			return BalanceDistribution{}, fmt.Errorf("%s: failed to get balance distribution of %s: %v", mdb.name, label, err)
+		}
+	}
+	return dist, nil
+}
+
+// AddCoinOutput implements Database.AddCoinOutput
+func (mdb *MemoryDatabase) AddCoinOutput(id types.CoinOutputID, co CoinOutput) error {
+	uh := co.Condition.UnlockHash()
+	err := mdb.putCoinOutput(id, DatabaseCoinOutput{
+		UnlockHash:   uh,
+		CoinValue:    co.Value,
+		State:        CoinOutputStateLiquid,
+		LockType:     LockTypeNone,
+		LockValue:    0,
+		Description:  co.Description,
+		RawCondition: EncodeCondition(co.Condition),
+	})
+	if err != nil {
+		return fmt.Errorf("%s: failed to add coin output %s: %v", mdb.name, id.String(), err)
+	}
+	return mdb.updateWallet(uh, func(wallet *Wallet) error {
+		wallet.Balance.Unlocked = wallet.Balance.Unlocked.Add(co.Value)
+		return nil
+	})
+}
+
+// AddLockedCoinOutput implements Database.AddLockedCoinOutput
+func (mdb *MemoryDatabase) AddLockedCoinOutput(id types.CoinOutputID, co CoinOutput, lt LockType, lockValue LockValue) error {
+	uh := co.Condition.UnlockHash()
+	err := mdb.putCoinOutput(id, DatabaseCoinOutput{
+		UnlockHash:   uh,
+		CoinValue:    co.Value,
+		State:        CoinOutputStateLocked,
+		LockType:     lt,
+		LockValue:    lockValue,
+		Description:  co.Description,
+		RawCondition: EncodeCondition(co.Condition),
+	})
+	if err != nil {
+		return fmt.Errorf("%s: failed to add coin output %s: %v", mdb.name, id.String(), err)
+	}
+	return mdb.updateWallet(uh, func(wallet *Wallet) error {
+		return wallet.Balance.Locked.AddLockedCoinOutput(id, WalletLockedOutput{
+			Amount:      co.Value,
+			LockedUntil: mdb.lockValueAsLockTime(lt, lockValue),
+			Description: co.Description,
+			Reason:      CoinOutputLockReason(lt, co.Description),
+		})
+	})
+}
+
+// SpendCoinOutput implements Database.SpendCoinOutput
+func (mdb *MemoryDatabase) SpendCoinOutput(id types.CoinOutputID) (types.UnlockHash, types.Currency, error) {
+	uh, value, err := mdb.updateCoinOutputState(id, CoinOutputStateLiquid, CoinOutputStateSpent)
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf("%s: failed to spend coin output: %v", mdb.name, err)
+	}
+	err = mdb.updateWallet(uh, func(wallet *Wallet) error {
+		wallet.Balance.Unlocked = wallet.Balance.Unlocked.Sub(value)
+		return nil
+	})
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, err
+	}
+	return uh, value, nil
+}
+
+// RevertCoinInput implements Database.RevertCoinInput
+// more or less a reverse process of SpendCoinOutput
+func (mdb *MemoryDatabase) RevertCoinInput(id types.CoinOutputID) (types.UnlockHash, types.Currency, error) {
+	uh, value, err := mdb.updateCoinOutputState(id, CoinOutputStateSpent, CoinOutputStateLiquid)
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf("%s: failed to revert coin input: %v", mdb.name, err)
+	}
+	err = mdb.updateWallet(uh, func(wallet *Wallet) error {
+		wallet.Balance.Unlocked = wallet.Balance.Unlocked.Add(value)
+		return nil
+	})
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, err
+	}
+	return uh, value, nil
+}
+
+// updateCoinOutputState updates the state of a coin output,
+// returning an error in case the coin output isn't in the expected state.
+func (mdb *MemoryDatabase) updateCoinOutputState(id types.CoinOutputID, from, to CoinOutputState) (types.UnlockHash, types.Currency, error) {
+	co, err := mdb.getCoinOutput(id)
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf("cannot get coin output %s: %v", id.String(), err)
+	}
+	if co.State != from {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf(
+			"cannot update coin output %s: unexpected state %d", id.String(), co.State)
+	}
+	co.State = to
+	err = mdb.putCoinOutput(id, co)
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf("cannot update coin output %s: %v", id.String(), err)
+	}
+	return co.UnlockHash, co.CoinValue, nil
+}
+
+// RevertCoinOutput implements Database.RevertCoinOutput
+func (mdb *MemoryDatabase) RevertCoinOutput(id types.CoinOutputID) (CoinOutputState, error) {
+	co, err := mdb.getCoinOutput(id)
+	if err != nil {
+		return CoinOutputStateNil, fmt.Errorf(
+			"%s: failed to revert coin output: cannot get coin output %s: %v", mdb.name, id.String(), err)
+	}
+	err = mdb.delete(memoryTypeCoinOutput, id.String())
+	if err != nil {
+		return CoinOutputStateNil, fmt.Errorf(
+			"%s: failed to revert coin output: cannot drop coin output %s: %v", mdb.name, id.String(), err)
+	}
+	// always remove lock properties if a lock is used, no matter the state
+	if co.LockType != LockTypeNone {
+		mdb.unindexLock(id, co.LockType)
+	}
+	switch co.State {
+	case CoinOutputStateLiquid:
+		err = mdb.updateWallet(co.UnlockHash, func(wallet *Wallet) error {
+			wallet.Balance.Unlocked = wallet.Balance.Unlocked.Sub(co.CoinValue)
+			return nil
+		})
+	case CoinOutputStateLocked:
+		err = mdb.updateWallet(co.UnlockHash, func(wallet *Wallet) error {
+			return wallet.Balance.Locked.SubLockedCoinOutput(id)
+		})
+	}
+	if err != nil {
+		return CoinOutputStateNil, err
+	}
+	return co.State, nil
+}
+
+// AddBlockStakeOutput implements BlockStakeDatabase.AddBlockStakeOutput
+func (mdb *MemoryDatabase) AddBlockStakeOutput(id types.BlockStakeOutputID, bso types.BlockStakeOutput) error {
+	uh := bso.Condition.UnlockHash()
+	err := mdb.putValue(memoryTypeBlockStake, id.String(), DatabaseBlockStakeOutput{
+		UnlockHash:   uh,
+		Value:        bso.Value,
+		State:        CoinOutputStateLiquid,
+		LockType:     LockTypeNone,
+		RawCondition: EncodeCondition(bso.Condition),
+	})
+	if err != nil {
+		return fmt.Errorf("%s: failed to add block stake output %s: %v", mdb.name, id.String(), err)
+	}
+	return mdb.updateWallet(uh, func(wallet *Wallet) error {
+		wallet.BlockStakes.Unlocked = wallet.BlockStakes.Unlocked.Add(bso.Value)
+		return nil
+	})
+}
+
+// AddLockedBlockStakeOutput implements BlockStakeDatabase.AddLockedBlockStakeOutput
+func (mdb *MemoryDatabase) AddLockedBlockStakeOutput(id types.BlockStakeOutputID, bso types.BlockStakeOutput, lt LockType, lockValue LockValue) error {
+	uh := bso.Condition.UnlockHash()
+	err := mdb.putValue(memoryTypeBlockStake, id.String(), DatabaseBlockStakeOutput{
+		UnlockHash:   uh,
+		Value:        bso.Value,
+		State:        CoinOutputStateLocked,
+		LockType:     lt,
+		LockValue:    lockValue,
+		RawCondition: EncodeCondition(bso.Condition),
+	})
+	if err != nil {
+		return fmt.Errorf("%s: failed to add block stake output %s: %v", mdb.name, id.String(), err)
+	}
+	mdb.blockStakeLocks[lt][id] = lockValue
+	return mdb.updateWallet(uh, func(wallet *Wallet) error {
+		wallet.BlockStakes.Locked = wallet.BlockStakes.Locked.Add(bso.Value)
+		return nil
+	})
+}
+
+// ApplyBlockStakeOutputLocks implements BlockStakeDatabase.ApplyBlockStakeOutputLocks
+func (mdb *MemoryDatabase) ApplyBlockStakeOutputLocks(height types.BlockHeight, time types.Timestamp) error {
+	return mdb.updateBlockStakeOutputLocks(height, time, true)
+}
+
+// RevertBlockStakeOutputLocks implements BlockStakeDatabase.RevertBlockStakeOutputLocks
+func (mdb *MemoryDatabase) RevertBlockStakeOutputLocks(height types.BlockHeight, time types.Timestamp) error {
+	return mdb.updateBlockStakeOutputLocks(height, time, false)
+}
+
+// updateBlockStakeOutputLocks unlocks the locked block stake outputs which can be spent as of the given height and time,
+// or locks the unlocked block stake outputs which cannot be spent (yet) as of the given height and time,
+// moving their value from one block stake balance of their wallet to the other.
+func (mdb *MemoryDatabase) updateBlockStakeOutputLocks(height types.BlockHeight, time types.Timestamp, unlock bool) error {
+	from, to := CoinOutputStateLiquid, CoinOutputStateLocked
+	if unlock {
+		from, to = to, from
+	}
+	for _, lock := range []struct {
+		Type  LockType
+		Value LockValue
+	}{
+		{LockTypeHeight, LockValue(height)},
+		{LockTypeTime, LockValue(time)},
+	} {
+		// (un)lock the block stake outputs in a deterministic order
+		var ids []types.BlockStakeOutputID
+		for id, value := range mdb.blockStakeLocks[lock.Type] {
+			if (value <= lock.Value) == unlock {
+				ids = append(ids, id)
+			}
+		}
+		sort.Slice(ids, func(i, j int) bool { return bytes.Compare(ids[i][:], ids[j][:]) < 0 })
+		for _, id := range ids {
+			var bso DatabaseBlockStakeOutput
+			err := mdb.getValue(memoryTypeBlockStake, id.String(), &bso)
+			if err != nil {
+				return fmt.Errorf("%s: failed to get block stake output %s: %v", mdb.name, id.String(), err)
+			}
+			if bso.State != from {
+				continue
+			}
+			bso.State = to
+			err = mdb.putValue(memoryTypeBlockStake, id.String(), bso)
+			if err != nil {
+				return fmt.Errorf("%s: failed to update block stake output %s: %v", mdb.name, id.String(), err)
+			}
+			err = mdb.updateWallet(bso.UnlockHash, func(wallet *Wallet) error {
+				if unlock {
+					wallet.BlockStakes.Locked = wallet.BlockStakes.Locked.Sub(bso.Value)
+					wallet.BlockStakes.Unlocked = wallet.BlockStakes.Unlocked.Add(bso.Value)
+				} else {
+					wallet.BlockStakes.Unlocked = wallet.BlockStakes.Unlocked.Sub(bso.Value)
+					wallet.BlockStakes.Locked = wallet.BlockStakes.Locked.Add(bso.Value)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SpendBlockStakeOutput implements BlockStakeDatabase.SpendBlockStakeOutput
+func (mdb *MemoryDatabase) SpendBlockStakeOutput(id types.BlockStakeOutputID) (types.UnlockHash, types.Currency, error) {
+	bso, err := mdb.updateBlockStakeOutputState(id, CoinOutputStateLiquid, CoinOutputStateSpent)
+	if err == ErrNotFound {
+		return types.UnlockHash{}, types.Currency{}, ErrNotFound
+	}
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf("%s: failed to spend block stake output: %v", mdb.name, err)
+	}
+	err = mdb.updateWallet(bso.UnlockHash, func(wallet *Wallet) error {
+		wallet.BlockStakes.Unlocked = wallet.BlockStakes.Unlocked.Sub(bso.Value)
+		return nil
+	})
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, err
+	}
+	return bso.UnlockHash, bso.Value, nil
+}
+
+// RevertBlockStakeInput implements BlockStakeDatabase.RevertBlockStakeInput
+func (mdb *MemoryDatabase) RevertBlockStakeInput(id types.BlockStakeOutputID) (types.UnlockHash, types.Currency, error) {
+	bso, err := mdb.updateBlockStakeOutputState(id, CoinOutputStateSpent, CoinOutputStateLiquid)
+	if err == ErrNotFound {
+		return types.UnlockHash{}, types.Currency{}, ErrNotFound
+	}
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf("%s: failed to revert block stake input: %v", mdb.name, err)
+	}
+	err = mdb.updateWallet(bso.UnlockHash, func(wallet *Wallet) error {
+		wallet.BlockStakes.Unlocked = wallet.BlockStakes.Unlocked.Add(bso.Value)
+		return nil
+	})
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, err
+	}
+	return bso.UnlockHash, bso.Value, nil
+}
+
+// updateBlockStakeOutputState updates the state of a block stake output,
+// returning an error in case the block stake output isn't in the expected state.
+func (mdb *MemoryDatabase) updateBlockStakeOutputState(id types.BlockStakeOutputID, from, to CoinOutputState) (DatabaseBlockStakeOutput, error) {
+	var bso DatabaseBlockStakeOutput
+	switch err := mdb.getValue(memoryTypeBlockStake, id.String(), &bso); err {
+	case nil:
+	case ErrNotFound:
+		return DatabaseBlockStakeOutput{}, ErrNotFound
+	default:
+		return DatabaseBlockStakeOutput{}, fmt.Errorf("cannot get block stake output %s: %v", id.String(), err)
+	}
+	if bso.State != from {
+		return DatabaseBlockStakeOutput{}, fmt.Errorf(
+			"cannot update block stake output %s: unexpected state %d", id.String(), bso.State)
+	}
+	bso.State = to
+	err := mdb.putValue(memoryTypeBlockStake, id.String(), bso)
+	if err != nil {
+		return DatabaseBlockStakeOutput{}, fmt.Errorf("cannot update block stake output %s: %v", id.String(), err)
+	}
+	return bso, nil
+}
+
+// RevertBlockStakeOutput implements BlockStakeDatabase.RevertBlockStakeOutput
+func (mdb *MemoryDatabase) RevertBlockStakeOutput(id types.BlockStakeOutputID) (CoinOutputState, error) {
+	var bso DatabaseBlockStakeOutput
+	err := mdb.getValue(memoryTypeBlockStake, id.String(), &bso)
+	if err == ErrNotFound {
+		return CoinOutputStateNil, ErrNotFound
+	}
+	if err == nil {
+		err = mdb.delete(memoryTypeBlockStake, id.String())
+	}
+	if err != nil {
+		return CoinOutputStateNil, fmt.Errorf(
+			"%s: failed to revert block stake output: cannot drop block stake output %s: %v", mdb.name, id.String(), err)
+	}
+	// always remove the lock if a lock is used, no matter the state
+	if bso.LockType != LockTypeNone {
+		delete(mdb.blockStakeLocks[bso.LockType], id)
+	}
+	switch bso.State {
+	case CoinOutputStateLiquid:
+		err = mdb.updateWallet(bso.UnlockHash, func(wallet *Wallet) error {
+			wallet.BlockStakes.Unlocked = wallet.BlockStakes.Unlocked.Sub(bso.Value)
+			return nil
+		})
+	case CoinOutputStateLocked:
+		err = mdb.updateWallet(bso.UnlockHash, func(wallet *Wallet) error {
+			wallet.BlockStakes.Locked = wallet.BlockStakes.Locked.Sub(bso.Value)
+			return nil
+		})
+	}
+	if err != nil {
+		return CoinOutputStateNil, err
+	}
+	return bso.State, nil
+}
+
+// GetBlockStakeOutput implements BlockStakeDatabase.GetBlockStakeOutput
+func (mdb *MemoryDatabase) GetBlockStakeOutput(id types.BlockStakeOutputID) (BlockStakeOutputInfo, error) {
+	var bso DatabaseBlockStakeOutput
+	switch err := mdb.getValue(memoryTypeBlockStake, id.String(), &bso); err {
+	case nil:
+	case ErrNotFound:
+		return BlockStakeOutputInfo{}, ErrNotFound
+	default:
+		return BlockStakeOutputInfo{}, fmt.Errorf("%s: failed to get block stake output %s: %v", mdb.name, id.String(), err)
+	}
+	info := BlockStakeOutputInfo{
+		ID:           id,
+		UnlockHash:   bso.UnlockHash,
+		Value:        bso.Value,
+		State:        bso.State,
+		LockType:     bso.LockType,
+		LockValue:    bso.LockValue,
+		RawCondition: bso.RawCondition,
+	}
+	err := info.decodeCondition()
+	if err != nil {
+		return BlockStakeOutputInfo{}, fmt.Errorf(
+			"%s: failed to decode raw condition of block stake output %s: %v", mdb.name, id.String(), err)
+	}
+	return info, nil
+}
+
+// ApplyCoinOutputLocks implements Database.ApplyCoinOutputLocks
+func (mdb *MemoryDatabase) ApplyCoinOutputLocks(height types.BlockHeight, time types.Timestamp) (n uint64, coins types.Currency, err error) {
+	mdb.networkTime, mdb.networkBlockHeight = time, height
+	for _, lock := range []struct {
+		Type  LockType
+		Value LockValue
+	}{
+		{LockTypeHeight, LockValue(height)},
+		{LockTypeTime, LockValue(time)},
+	} {
+		// locked -> unlocked
+		ids := memoryLocks(mdb.locked[lock.Type], func(value LockValue) bool { return value <= lock.Value })
+		for _, id := range ids {
+			uh, value, err := mdb.updateCoinOutputState(id, CoinOutputStateLocked, CoinOutputStateLiquid)
+			if err != nil {
+				return 0, types.Currency{}, fmt.Errorf("%s: failed to unlock coin output: %v", mdb.name, err)
+			}
+			err = mdb.updateWallet(uh, func(wallet *Wallet) error {
+				err := wallet.Balance.Locked.SubLockedCoinOutput(id)
+				if err != nil {
+					return err
+				}
+				wallet.Balance.Unlocked = wallet.Balance.Unlocked.Add(value)
+				return nil
+			})
+			if err != nil {
+				return 0, types.Currency{}, err
+			}
+			coins = coins.Add(value)
+			n++
+		}
+	}
+	return n, coins, nil
+}
+
+// RevertCoinOutputLocks implements Database.RevertCoinOutputLocks
+func (mdb *MemoryDatabase) RevertCoinOutputLocks(height types.BlockHeight, time types.Timestamp) (n uint64, coins types.Currency, err error) {
+	mdb.networkTime, mdb.networkBlockHeight = time, height
+	for _, lock := range []struct {
+		Type  LockType
+		Value LockValue
+	}{
+		{LockTypeHeight, LockValue(height)},
+		{LockTypeTime, LockValue(time)},
+	} {
+		// unlocked -> locked
+		ids := memoryLocks(mdb.unlocked[lock.Type], func(value LockValue) bool { return value > lock.Value })
+		for _, id := range ids {
+			co, err := mdb.getCoinOutput(id)
+			if err != nil {
+				return 0, types.Currency{}, fmt.Errorf(
+					"%s: failed to lock coin output: cannot get coin output %s: %v", mdb.name, id.String(), err)
+			}
+			_, _, err = mdb.updateCoinOutputState(id, CoinOutputStateLiquid, CoinOutputStateLocked)
+			if err != nil {
+				return 0, types.Currency{}, fmt.Errorf("%s: failed to lock coin output: %v", mdb.name, err)
+			}
+			err = mdb.updateWallet(co.UnlockHash, func(wallet *Wallet) error {
+				wallet.Balance.Unlocked = wallet.Balance.Unlocked.Sub(co.CoinValue)
+				return wallet.Balance.Locked.AddLockedCoinOutput(id, WalletLockedOutput{
+					Amount:      co.CoinValue,
+					LockedUntil: mdb.lockValueAsLockTime(co.LockType, co.LockValue),
+					Description: co.Description,
+					Reason:      CoinOutputLockReason(co.LockType, co.Description),
+				})
+			})
+			if err != nil {
+				return 0, types.Currency{}, err
+			}
+			coins = coins.Add(co.CoinValue)
+			n++
+		}
+	}
+	return n, coins, nil
+}
+
+// memoryLocks returns the IDs of the indexed locks of which the value matches the given filter,
+// ordered by lock value (and ID), such that the coin outputs are (un)locked in a deterministic order.
+func memoryLocks(locks map[types.CoinOutputID]LockValue, filter func(LockValue) bool) []types.CoinOutputID {
+	var ids []types.CoinOutputID
+	for id, value := range locks {
+		if filter(value) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if locks[ids[i]] != locks[ids[j]] {
+			return locks[ids[i]] < locks[ids[j]]
+		}
+		return bytes.Compare(ids[i][:], ids[j][:]) < 0
+	})
+	return ids
+}
+
+// SetMultisigAddresses implements Database.SetMultisigAddresses
+func (mdb *MemoryDatabase) SetMultisigAddresses(address types.UnlockHash, owners []types.UnlockHash, signaturesRequired uint64) error {
+	var known bool
+	err := mdb.updateWallet(address, func(wallet *Wallet) error {
+		if len(wallet.MultiSignData.Owners) > 0 {
+			known = true
+			return nil // nothing to do
+		}
+		wallet.MultiSignData.SignaturesRequired = signaturesRequired
+		wallet.MultiSignData.Owners = make([]types.UnlockHash, len(owners))
+		copy(wallet.MultiSignData.Owners[:], owners[:])
+		return nil
+	})
+	if err != nil || known {
+		return err
+	}
+	// link the multisig address to all its owners
+	for _, owner := range owners {
+		err = mdb.updateWallet(owner, func(wallet *Wallet) error {
+			for _, uh := range wallet.MultiSignAddresses {
+				if uh == address {
+					return nil // nothing to do
+				}
+			}
+			wallet.MultiSignAddresses = append(wallet.MultiSignAddresses, address)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyCounterpartyTransfer implements Database.ApplyCounterpartyTransfer
+func (mdb *MemoryDatabase) ApplyCounterpartyTransfer(from, to types.UnlockHash, value types.Currency) error {
+	err := mdb.updateCounterparty(from, to, func(cp *AddressCounterparty) {
+		cp.TransactionCount++
+		cp.Sent = cp.Sent.Add(value)
+	})
+	if err != nil {
+		return err
+	}
+	return mdb.updateCounterparty(to, from, func(cp *AddressCounterparty) {
+		cp.TransactionCount++
+		cp.Received = cp.Received.Add(value)
+	})
+}
+
+// RevertCounterpartyTransfer implements Database.RevertCounterpartyTransfer
+func (mdb *MemoryDatabase) RevertCounterpartyTransfer(from, to types.UnlockHash, value types.Currency) error {
+	err := mdb.updateCounterparty(from, to, func(cp *AddressCounterparty) {
+		cp.TransactionCount--
+		cp.Sent = subCurrencyOrZero(cp.Sent, value)
+	})
+	if err != nil {
+		return err
+	}
+	return mdb.updateCounterparty(to, from, func(cp *AddressCounterparty) {
+		cp.TransactionCount--
+		cp.Received = subCurrencyOrZero(cp.Received, value)
+	})
+}
+
+// updateCounterparty updates the counterparty of an address, using the given update function.
+func (mdb *MemoryDatabase) updateCounterparty(address, counterparty types.UnlockHash, update func(*AddressCounterparty)) error {
+	key := address.String() + ":" + counterparty.String()
+	var cp AddressCounterparty
+	err := mdb.getValue(memoryTypeCounterparty, key, &cp)
+	if err != nil && err != ErrNotFound {
+		return fmt.Errorf("%s: failed to get counterparty %s of %s: %v", mdb.name,
+			counterparty.String(), address.String(), err)
+	}
+	update(&cp)
+	if cp.TransactionCount == 0 {
+		err = mdb.delete(memoryTypeCounterparty, key)
+	} else {
+		err = mdb.putValue(memoryTypeCounterparty, key, cp)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: failed to update counterparty %s of %s: %v", mdb.name,
+			counterparty.String(), address.String(), err)
+	}
+	return nil
+}
+
+// ApplyWalletGroupFlows implements Database.ApplyWalletGroupFlows
+func (mdb *MemoryDatabase) ApplyWalletGroupFlows(timestamp types.Timestamp, flows WalletGroupFlows) error {
+	return mdb.updateWalletGroupFlows(timestamp, func(current WalletGroupFlows) WalletGroupFlows {
+		return current.Add(flows)
+	})
+}
+
+// RevertWalletGroupFlows implements Database.RevertWalletGroupFlows
+func (mdb *MemoryDatabase) RevertWalletGroupFlows(timestamp types.Timestamp, flows WalletGroupFlows) error {
+	return mdb.updateWalletGroupFlows(timestamp, func(current WalletGroupFlows) WalletGroupFlows {
+		return current.Sub(flows)
+	})
+}
+
+// updateWalletGroupFlows updates both the total and the daily wallet group flows,
+// removing the daily flows once they're no longer used.
+func (mdb *MemoryDatabase) updateWalletGroupFlows(timestamp types.Timestamp, update func(WalletGroupFlows) WalletGroupFlows) error {
+	for _, day := range []string{flowsFieldTotal, walletGroupFlowsDay(timestamp)} {
+		var flows WalletGroupFlows
+		err := mdb.getValue(memoryTypeFlows, day, &flows)
+		if err != nil && err != ErrNotFound {
+			return fmt.Errorf("%s: failed to get wallet group flows of %s: %v", mdb.name, day, err)
+		}
+		flows = update(flows)
+		if flows.IsZero() && day != flowsFieldTotal {
+			err = mdb.delete(memoryTypeFlows, day)
+		} else {
+			err = mdb.putValue(memoryTypeFlows, day, flows)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: failed to update wallet group flows of %s: %v", mdb.name, day, err)
+		}
+	}
+	return nil
+}
+
+// GetWalletGroupFlows implements Database.GetWalletGroupFlows
+func (mdb *MemoryDatabase) GetWalletGroupFlows() (total WalletGroupFlows, daily map[string]WalletGroupFlows, err error) {
+	keys := mdb.keys(memoryTypeFlows, "")
+	daily = make(map[string]WalletGroupFlows, len(keys))
+	for _, day := range keys {
+		var flows WalletGroupFlows
+		err = mdb.getValue(memoryTypeFlows, day, &flows)
+		if err != nil {
+			return WalletGroupFlows{}, nil, fmt.Errorf("%s: failed to get wallet group flows of %s: %v", mdb.name, day, err)
+		}
+		if day == flowsFieldTotal {
+			total = flows
+		} else {
+			daily[day] = flows
+		}
+	}
+	return total, daily, nil
+}
+
+// ApplyMultisigSpend implements Database.ApplyMultisigSpend
+func (mdb *MemoryDatabase) ApplyMultisigSpend(spend MultisigSpend) error {
+	key := spend.Address.String() + ":" + spend.CoinOutputID.String()
+	err := mdb.putValue(memoryTypeMultisigSpend, key, spend.Signers)
+	if err != nil {
+		return fmt.Errorf("%s: failed to store signers of multisig spend %s: %v", mdb.name, spend.CoinOutputID.String(), err)
+	}
+	for _, signer := range spend.Signers {
+		err = mdb.updateMultisigSignerStats(spend.Address, signer, func(stats *MultisigSignerStats) {
+			stats.SpendCount++
+			stats.Value = stats.Value.Add(spend.Value)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevertMultisigSpend implements Database.RevertMultisigSpend
+func (mdb *MemoryDatabase) RevertMultisigSpend(spend MultisigSpend) error {
+	key := spend.Address.String() + ":" + spend.CoinOutputID.String()
+	err := mdb.delete(memoryTypeMultisigSpend, key)
+	if err != nil {
+		return fmt.Errorf("%s: failed to remove signers of multisig spend %s: %v", mdb.name, spend.CoinOutputID.String(), err)
+	}
+	for _, signer := range spend.Signers {
+		err = mdb.updateMultisigSignerStats(spend.Address, signer, func(stats *MultisigSignerStats) {
+			stats.SpendCount--
+			stats.Value = subCurrencyOrZero(stats.Value, spend.Value)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateMultisigSignerStats updates the signing stats of an owner of a multisig wallet,
+// using the given update function, removing the stats once the owner no longer signed any spend.
+func (mdb *MemoryDatabase) updateMultisigSignerStats(address, signer types.UnlockHash, update func(*MultisigSignerStats)) error {
+	key := address.String() + ":" + signer.String()
+	var stats MultisigSignerStats
+	err := mdb.getValue(memoryTypeMultisigSigner, key, &stats)
+	if err != nil && err != ErrNotFound {
+		return fmt.Errorf("%s: failed to get signing stats of %s for %s: %v", mdb.name,
+			signer.String(), address.String(), err)
+	}
+	update(&stats)
+	if stats.SpendCount == 0 {
+		err = mdb.delete(memoryTypeMultisigSigner, key)
+	} else {
+		err = mdb.putValue(memoryTypeMultisigSigner, key, stats)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: failed to update signing stats of %s for %s: %v", mdb.name,
+			signer.String(), address.String(), err)
+	}
+	return nil
+}
+
+// GetMultisigSpends implements Database.GetMultisigSpends
+func (mdb *MemoryDatabase) GetMultisigSpends(address types.UnlockHash) (map[types.CoinOutputID][]types.UnlockHash, error) {
+	prefix := address.String() + ":"
+	keys := mdb.keys(memoryTypeMultisigSpend, prefix)
+	spends := make(map[types.CoinOutputID][]types.UnlockHash, len(keys))
+	for _, key := range keys {
+		var (
+			id      types.CoinOutputID
+			signers []types.UnlockHash
+		)
+		err := id.LoadString(key[len(prefix):])
+		if err == nil {
+			err = mdb.getValue(memoryTypeMultisigSpend, key, &signers)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to get multisig spend %s of %s: %v", mdb.name,
+				key[len(prefix):], address.String(), err)
+		}
+		spends[id] = signers
+	}
+	return spends, nil
+}
+
+// GetMultisigSignerStats implements Database.GetMultisigSignerStats
+func (mdb *MemoryDatabase) GetMultisigSignerStats(address types.UnlockHash) (map[types.UnlockHash]MultisigSignerStats, error) {
+	prefix := address.String() + ":"
+	keys := mdb.keys(memoryTypeMultisigSigner, prefix)
+	signers := make(map[types.UnlockHash]MultisigSignerStats, len(keys))
+	for _, key := range keys {
+		var (
+			signer types.UnlockHash
+			stats  MultisigSignerStats
+		)
+		err := signer.LoadString(key[len(prefix):])
+		if err == nil {
+			err = mdb.getValue(memoryTypeMultisigSigner, key, &stats)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to get signing stats of %s for %s: %v", mdb.name,
+				key[len(prefix):], address.String(), err)
+		}
+		signers[signer] = stats
+	}
+	return signers, nil
+}
+
+// SetBlockSummary implements Database.SetBlockSummary
+func (mdb *MemoryDatabase) SetBlockSummary(summary BlockSummary) error {
+	return mdb.putValue(memoryTypeBlockSummary, strconv.FormatUint(uint64(summary.Height), 10), summary)
+}
+
+// RevertBlockSummary implements Database.RevertBlockSummary
+func (mdb *MemoryDatabase) RevertBlockSummary(height types.BlockHeight) error {
+	return mdb.delete(memoryTypeBlockSummary, strconv.FormatUint(uint64(height), 10))
+}
+
+// GetBlockSummary implements Database.GetBlockSummary
+func (mdb *MemoryDatabase) GetBlockSummary(height types.BlockHeight) (BlockSummary, error) {
+	var summary BlockSummary
+	switch err := mdb.getValue(memoryTypeBlockSummary, strconv.FormatUint(uint64(height), 10), &summary); err {
+	case nil:
+		return summary, nil
+	case ErrNotFound:
+		return BlockSummary{}, ErrNotFound
+	default:
+		return BlockSummary{}, fmt.Errorf("%s: failed to get summary of block %d: %v", mdb.name, height, err)
+	}
+}
+
+// SetBlockRecord implements BlockDatabase.SetBlockRecord
+func (mdb *MemoryDatabase) SetBlockRecord(record BlockRecord) error {
+	err := mdb.putValue(memoryTypeBlock, strconv.FormatUint(uint64(record.Height), 10), record)
+	if err != nil {
+		return err
+	}
+	return mdb.putValue(memoryTypeBlockID, record.ID.String(), record.Height)
+}
+
+// RevertBlockRecord implements BlockDatabase.RevertBlockRecord
+func (mdb *MemoryDatabase) RevertBlockRecord(height types.BlockHeight, id types.BlockID) error {
+	err := mdb.delete(memoryTypeBlock, strconv.FormatUint(uint64(height), 10))
+	if err != nil {
+		return err
+	}
+	return mdb.delete(memoryTypeBlockID, id.String())
+}
+
+// GetBlockRecord implements BlockDatabase.GetBlockRecord
+func (mdb *MemoryDatabase) GetBlockRecord(height types.BlockHeight) (BlockRecord, error) {
+	var record BlockRecord
+	switch err := mdb.getValue(memoryTypeBlock, strconv.FormatUint(uint64(height), 10), &record); err {
+	case nil:
+		return record, nil
+	case ErrNotFound:
+		return BlockRecord{}, ErrNotFound
+	default:
+		return BlockRecord{}, fmt.Errorf("%s: failed to get record of block %d: %v", mdb.name, height, err)
+	}
+}
+
+// GetBlockRecordByID implements BlockDatabase.GetBlockRecordByID
+func (mdb *MemoryDatabase) GetBlockRecordByID(id types.BlockID) (BlockRecord, error) {
+	var height types.BlockHeight
+	switch err := mdb.getValue(memoryTypeBlockID, id.String(), &height); err {
+	case nil:
+		return mdb.GetBlockRecord(height)
+	case ErrNotFound:
+		return BlockRecord{}, ErrNotFound
+	default:
+		return BlockRecord{}, fmt.Errorf("%s: failed to get height of block %s: %v", mdb.name, id.String(), err)
+	}
+}
+
+// SetTransactionRecord implements TransactionDatabase.SetTransactionRecord
+func (mdb *MemoryDatabase) SetTransactionRecord(record TransactionRecord) error {
+	return mdb.putValue(memoryTypeTransaction, record.ID.String(), record)
+}
+
+// RevertTransactionRecord implements TransactionDatabase.RevertTransactionRecord
+func (mdb *MemoryDatabase) RevertTransactionRecord(id types.TransactionID) error {
+	return mdb.delete(memoryTypeTransaction, id.String())
+}
+
+// GetTransactionRecord implements TransactionDatabase.GetTransactionRecord
+func (mdb *MemoryDatabase) GetTransactionRecord(id types.TransactionID) (TransactionRecord, error) {
+	var record TransactionRecord
+	switch err := mdb.getValue(memoryTypeTransaction, id.String(), &record); err {
+	case nil:
+		return record, nil
+	case ErrNotFound:
+		return TransactionRecord{}, ErrNotFound
+	default:
+		return TransactionRecord{}, fmt.Errorf("%s: failed to get record of tx %s: %v", mdb.name, id.String(), err)
+	}
+}
+
+// SetAtomicSwapContract implements AtomicSwapDatabase.SetAtomicSwapContract
+func (mdb *MemoryDatabase) SetAtomicSwapContract(contract AtomicSwapContract) error {
+	err := mdb.putValue(memoryTypeAtomicSwap, contract.ID.String(), contract)
+	if err != nil {
+		return err
+	}
+	for _, uh := range []types.UnlockHash{contract.Sender, contract.Receiver} {
+		err = mdb.putValue(memoryTypeAtomicSwapLink, uh.String()+":"+contract.ID.String(), true)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevertAtomicSwapContract implements AtomicSwapDatabase.RevertAtomicSwapContract
+func (mdb *MemoryDatabase) RevertAtomicSwapContract(contract AtomicSwapContract) error {
+	err := mdb.delete(memoryTypeAtomicSwap, contract.ID.String())
+	if err != nil {
+		return err
+	}
+	for _, uh := range []types.UnlockHash{contract.Sender, contract.Receiver} {
+		err = mdb.delete(memoryTypeAtomicSwapLink, uh.String()+":"+contract.ID.String())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAtomicSwapContract implements AtomicSwapDatabase.GetAtomicSwapContract
+func (mdb *MemoryDatabase) GetAtomicSwapContract(id types.CoinOutputID) (AtomicSwapContract, error) {
+	var contract AtomicSwapContract
+	switch err := mdb.getValue(memoryTypeAtomicSwap, id.String(), &contract); err {
+	case nil:
+		return contract, nil
+	case ErrNotFound:
+		return AtomicSwapContract{}, ErrNotFound
+	default:
+		return AtomicSwapContract{}, fmt.Errorf("%s: failed to get atomic swap contract %s: %v", mdb.name, id.String(), err)
+	}
+}
+
+// GetAtomicSwapContracts implements AtomicSwapDatabase.GetAtomicSwapContracts
+func (mdb *MemoryDatabase) GetAtomicSwapContracts(address types.UnlockHash) ([]types.CoinOutputID, error) {
+	prefix := address.String() + ":"
+	keys := mdb.keys(memoryTypeAtomicSwapLink, prefix)
+	ids := make([]types.CoinOutputID, len(keys))
+	for i, key := range keys {
+		err := ids[i].LoadString(key[len(prefix):])
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to decode atomic swap contract ID %q of %s: %v", mdb.name,
+				key[len(prefix):], address.String(), err)
+		}
+	}
+	return ids, nil
+}
+
+// ApplyArbitraryData implements ArbitraryDataDatabase.ApplyArbitraryData
+func (mdb *MemoryDatabase) ApplyArbitraryData(id types.TransactionID, data []byte) error {
+	err := mdb.putValue(memoryTypeDataHash, arbitraryDataHash(data).String()+":"+id.String(), true)
+	if err != nil {
+		return err
+	}
+	return mdb.putValue(memoryTypeDataPrefix,
+		types.ByteSlice(arbitraryDataPrefix(data)).String()+":"+id.String(), types.ByteSlice(data))
+}
+
+// RevertArbitraryData implements ArbitraryDataDatabase.RevertArbitraryData
+func (mdb *MemoryDatabase) RevertArbitraryData(id types.TransactionID, data []byte) error {
+	err := mdb.delete(memoryTypeDataHash, arbitraryDataHash(data).String()+":"+id.String())
+	if err != nil {
+		return err
+	}
+	return mdb.delete(memoryTypeDataPrefix, types.ByteSlice(arbitraryDataPrefix(data)).String()+":"+id.String())
+}
+
+// GetArbitraryDataTransactions implements ArbitraryDataDatabase.GetArbitraryDataTransactions
+func (mdb *MemoryDatabase) GetArbitraryDataTransactions(hash crypto.Hash) ([]types.TransactionID, error) {
+	prefix := hash.String() + ":"
+	keys := mdb.keys(memoryTypeDataHash, prefix)
+	ids := make([]types.TransactionID, len(keys))
+	for i, key := range keys {
+		err := ids[i].LoadString(key[len(prefix):])
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to decode tx ID %q of arbitrary data %s: %v", mdb.name,
+				key[len(prefix):], hash.String(), err)
+		}
+	}
+	return ids, nil
+}
+
+// GetArbitraryDataByPrefix implements ArbitraryDataDatabase.GetArbitraryDataByPrefix
+func (mdb *MemoryDatabase) GetArbitraryDataByPrefix(prefix []byte) ([]ArbitraryDataTransaction, error) {
+	keyPrefix := types.ByteSlice(arbitraryDataPrefix(prefix)).String() + ":"
+	var txs []ArbitraryDataTransaction
+	for _, key := range mdb.keys(memoryTypeDataPrefix, keyPrefix) {
+		var tx ArbitraryDataTransaction
+		err := tx.ID.LoadString(key[len(keyPrefix):])
+		if err == nil {
+			err = mdb.getValue(memoryTypeDataPrefix, key, &tx.Data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to get arbitrary data of tx %s: %v", mdb.name, key[len(keyPrefix):], err)
+		}
+		if bytes.HasPrefix(tx.Data, prefix) {
+			txs = append(txs, tx)
+		}
+	}
+	return txs, nil
+}
+
+// ApplyAddressActivity implements AddressActivityDatabase.ApplyAddressActivity
+func (mdb *MemoryDatabase) ApplyAddressActivity(height types.BlockHeight, timestamp types.Timestamp, addresses []types.UnlockHash) error {
+	point := ActivityPoint{BlockHeight: height, Timestamp: timestamp}
+	previous := make(map[string]*AddressActivity, len(addresses))
+	for _, uh := range addresses {
+		activity, err := mdb.GetAddressActivity(uh)
+		switch err {
+		case nil:
+			old := activity
+			previous[uh.String()] = &old
+		case ErrNotFound:
+			activity.FirstSeen = point
+			previous[uh.String()] = nil
+		default:
+			return err
+		}
+		activity.LastActive = point
+		err = mdb.putValue(memoryTypeActivity, uh.String(), activity)
+		if err != nil {
+			return err
+		}
+	}
+	return mdb.putValue(memoryTypeActivityUndo, strconv.FormatUint(uint64(height), 10), previous)
+}
+
+// RevertAddressActivity implements AddressActivityDatabase.RevertAddressActivity
+func (mdb *MemoryDatabase) RevertAddressActivity(height types.BlockHeight) error {
+	var previous map[string]*AddressActivity
+	switch err := mdb.getValue(memoryTypeActivityUndo, strconv.FormatUint(uint64(height), 10), &previous); err {
+	case nil:
+	case ErrNotFound:
+		return nil // no address was active in the block
+	default:
+		return fmt.Errorf("%s: failed to get previous address activity of block %d: %v", mdb.name, height, err)
+	}
+	for address, activity := range previous {
+		var err error
+		if activity == nil {
+			err = mdb.delete(memoryTypeActivity, address)
+		} else {
+			err = mdb.putValue(memoryTypeActivity, address, *activity)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return mdb.delete(memoryTypeActivityUndo, strconv.FormatUint(uint64(height), 10))
+}
+
+// GetAddressActivity implements AddressActivityDatabase.GetAddressActivity
+func (mdb *MemoryDatabase) GetAddressActivity(address types.UnlockHash) (AddressActivity, error) {
+	var activity AddressActivity
+	switch err := mdb.getValue(memoryTypeActivity, address.String(), &activity); err {
+	case nil:
+		return activity, nil
+	case ErrNotFound:
+		return AddressActivity{}, ErrNotFound
+	default:
+		return AddressActivity{}, fmt.Errorf("%s: failed to get activity of %s: %v", mdb.name, address.String(), err)
+	}
+}
+
+// CountActiveAddresses implements AddressActivityDatabase.CountActiveAddresses
+func (mdb *MemoryDatabase) CountActiveAddresses(since types.Timestamp) (uint64, error) {
+	var n uint64
+	for address, value := range mdb.values[memoryTypeActivity] {
+		var activity AddressActivity
+		err := json.Unmarshal(value, &activity)
+		if err != nil {
+			return 0, fmt.Errorf("%s: failed to decode activity of %s: %v", mdb.name, address, err)
+		}
+		if activity.LastActive.Timestamp >= since {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// GetRichList implements RichListDatabase.GetRichList
+//
+// As no rich list is stored, all wallets are ranked each time the rich list is requested.
+func (mdb *MemoryDatabase) GetRichList(n int) ([]RichListEntry, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	var entries []RichListEntry
+	for _, key := range mdb.keys(memoryTypeWallet, "") {
+		var wallet WalletFocusBalance
+		err := mdb.getValue(memoryTypeWallet, key, &wallet)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to get wallet for %s: %v", mdb.name, key, err)
+		}
+		entry := RichListEntry{
+			Balance: SnapshotBalance{
+				Unlocked: wallet.Balance.Unlocked,
+				Locked:   wallet.Balance.Locked.Total,
+			},
+		}
+		if entry.Balance.IsZero() {
+			continue
+		}
+		err = entry.Address.LoadString(key)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid wallet address %q: %v", mdb.name, key, err)
+		}
+		entries = append(entries, entry)
+	}
+	sortRichList(entries)
+	if len(entries) > n {
+		entries = entries[:n]
+	}
+	return entries, nil
+}
+
+// SetCoinOutputProvenance implements CoinOutputProvenanceDatabase.SetCoinOutputProvenance
+func (mdb *MemoryDatabase) SetCoinOutputProvenance(id types.CoinOutputID, provenance CoinOutputProvenance) error {
+	return mdb.putValue(memoryTypeProvenance, id.String(), provenance)
+}
+
+// RevertCoinOutputProvenance implements CoinOutputProvenanceDatabase.RevertCoinOutputProvenance
+func (mdb *MemoryDatabase) RevertCoinOutputProvenance(id types.CoinOutputID) error {
+	return mdb.delete(memoryTypeProvenance, id.String())
+}
+
+// GetCoinOutputProvenance implements CoinOutputProvenanceDatabase.GetCoinOutputProvenance
+func (mdb *MemoryDatabase) GetCoinOutputProvenance(id types.CoinOutputID) (CoinOutputProvenance, error) {
+	var provenance CoinOutputProvenance
+	switch err := mdb.getValue(memoryTypeProvenance, id.String(), &provenance); err {
+	case nil:
+		return provenance, nil
+	case ErrNotFound:
+		return CoinOutputProvenance{}, ErrNotFound
+	default:
+		return CoinOutputProvenance{}, fmt.Errorf("%s: failed to get provenance of coin output %s: %v", mdb.name, id.String(), err)
+	}
+}
+
+// ApplyMintConditionChange implements MintConditionDatabase.ApplyMintConditionChange
+func (mdb *MemoryDatabase) ApplyMintConditionChange(change MintConditionChange) error {
+	history, err := mdb.GetMintConditionHistory()
+	if err != nil {
+		return err
+	}
+	return mdb.putValue(memoryTypeMintCondition, "", append(history, change))
+}
+
+// RevertMintConditionChange implements MintConditionDatabase.RevertMintConditionChange
+func (mdb *MemoryDatabase) RevertMintConditionChange(id types.TransactionID) error {
+	history, err := mdb.GetMintConditionHistory()
+	if err != nil {
+		return err
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].TransactionID != id {
+			continue
+		}
+		history = append(history[:i], history[i+1:]...)
+		if len(history) == 0 {
+			return mdb.delete(memoryTypeMintCondition, "")
+		}
+		return mdb.putValue(memoryTypeMintCondition, "", history)
+	}
+	return nil
+}
+
+// GetMintConditionHistory implements MintConditionDatabase.GetMintConditionHistory
+func (mdb *MemoryDatabase) GetMintConditionHistory() ([]MintConditionChange, error) {
+	var history []MintConditionChange
+	switch err := mdb.getValue(memoryTypeMintCondition, "", &history); err {
+	case nil, ErrNotFound:
+		return history, nil
+	default:
+		return nil, fmt.Errorf("%s: failed to get mint condition history: %v", mdb.name, err)
+	}
+}
+
+// ApplyMinerPayout implements MinerPayoutDatabase.ApplyMinerPayout
+func (mdb *MemoryDatabase) ApplyMinerPayout(address types.UnlockHash, payout MinerPayoutRecord) error {
+	payouts, err := mdb.GetMinerPayouts(address)
+	if err != nil {
+		return err
+	}
+	return mdb.putValue(memoryTypeMinerPayouts, address.String(), append(payouts, payout))
+}
+
+// RevertMinerPayouts implements MinerPayoutDatabase.RevertMinerPayouts
+func (mdb *MemoryDatabase) RevertMinerPayouts(address types.UnlockHash, height types.BlockHeight) error {
+	payouts, err := mdb.GetMinerPayouts(address)
+	if err != nil {
+		return err
+	}
+	// payouts are reverted in the reverse order they were applied in, and are thus the latest ones
+	n := len(payouts)
+	for n > 0 && payouts[n-1].BlockHeight == height {
+		n--
+	}
+	if n == len(payouts) {
+		return nil
+	}
+	if n == 0 {
+		return mdb.delete(memoryTypeMinerPayouts, address.String())
+	}
+	return mdb.putValue(memoryTypeMinerPayouts, address.String(), payouts[:n])
+}
+
+// GetMinerPayouts implements MinerPayoutDatabase.GetMinerPayouts
+func (mdb *MemoryDatabase) GetMinerPayouts(address types.UnlockHash) ([]MinerPayoutRecord, error) {
+	var payouts []MinerPayoutRecord
+	switch err := mdb.getValue(memoryTypeMinerPayouts, address.String(), &payouts); err {
+	case nil, ErrNotFound:
+		return payouts, nil
+	default:
+		return nil, fmt.Errorf("%s: failed to get miner payouts of %s: %v", mdb.name, address.String(), err)
+	}
+}
+
+// ApplyAddressHistory implements AddressHistoryDatabase.ApplyAddressHistory
+func (mdb *MemoryDatabase) ApplyAddressHistory(address types.UnlockHash, record AddressHistoryRecord) error {
+	history, err := mdb.GetAddressHistory(address)
+	if err != nil {
+		return err
+	}
+	return mdb.putValue(memoryTypeHistory, address.String(), append(history, record))
+}
+
+// RevertAddressHistory implements AddressHistoryDatabase.RevertAddressHistory
+func (mdb *MemoryDatabase) RevertAddressHistory(address types.UnlockHash, height types.BlockHeight) error {
+	history, err := mdb.GetAddressHistory(address)
+	if err != nil {
+		return err
+	}
+	// blocks are reverted in the reverse order they were applied in, and their txs are thus the latest ones
+	n := len(history)
+	for n > 0 && history[n-1].BlockHeight == height {
+		n--
+	}
+	if n == len(history) {
+		return nil
+	}
+	if n == 0 {
+		return mdb.delete(memoryTypeHistory, address.String())
+	}
+	return mdb.putValue(memoryTypeHistory, address.String(), history[:n])
+}
+
+// GetAddressHistory implements AddressHistoryDatabase.GetAddressHistory
+func (mdb *MemoryDatabase) GetAddressHistory(address types.UnlockHash) ([]AddressHistoryRecord, error) {
+	var history []AddressHistoryRecord
+	switch err := mdb.getValue(memoryTypeHistory, address.String(), &history); err {
+	case nil, ErrNotFound:
+		return history, nil
+	default:
+		return nil, fmt.Errorf("%s: failed to get history of %s: %v", mdb.name, address.String(), err)
+	}
+}
+
+// ApplyAddressTotals implements AddressTotalsDatabase.ApplyAddressTotals
+func (mdb *MemoryDatabase) ApplyAddressTotals(address types.UnlockHash, delta AddressTotals) error {
+	totals, err := mdb.GetAddressTotals(address)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	totals.TotalReceived = totals.TotalReceived.Add(delta.TotalReceived)
+	totals.TotalSent = totals.TotalSent.Add(delta.TotalSent)
+	return mdb.setAddressTotals(address, totals)
+}
+
+// RevertAddressTotals implements AddressTotalsDatabase.RevertAddressTotals
+func (mdb *MemoryDatabase) RevertAddressTotals(address types.UnlockHash, delta AddressTotals) error {
+	totals, err := mdb.GetAddressTotals(address)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	// not tracked for the blocks applied prior to upgrading an existing database, see subUntracked
+	totals.TotalReceived = subCurrencyOrZero(totals.TotalReceived, delta.TotalReceived)
+	totals.TotalSent = subCurrencyOrZero(totals.TotalSent, delta.TotalSent)
+	return mdb.setAddressTotals(address, totals)
+}
+
+// setAddressTotals stores the totals of the given address, dropping them if the address neither received nor sent coins.
+func (mdb *MemoryDatabase) setAddressTotals(address types.UnlockHash, totals AddressTotals) error {
+	if totals.IsZero() {
+		return mdb.delete(memoryTypeTotals, address.String())
+	}
+	return mdb.putValue(memoryTypeTotals, address.String(), totals)
+}
+
+// GetAddressTotals implements AddressTotalsDatabase.GetAddressTotals
+func (mdb *MemoryDatabase) GetAddressTotals(address types.UnlockHash) (AddressTotals, error) {
+	var totals AddressTotals
+	switch err := mdb.getValue(memoryTypeTotals, address.String(), &totals); err {
+	case nil:
+		return totals, nil
+	case ErrNotFound:
+		return AddressTotals{}, ErrNotFound
+	default:
+		return AddressTotals{}, fmt.Errorf("%s: failed to get totals of %s: %v", mdb.name, address.String(), err)
+	}
+}
+
+// ApplyDailyStats implements DailyStatsDatabase.ApplyDailyStats
+func (mdb *MemoryDatabase) ApplyDailyStats(height types.BlockHeight, stats DailyStats) error {
+	current, err := mdb.GetDailyStats(stats.Date)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	current.Date = stats.Date
+	err = mdb.setDailyStats(current.Add(stats))
+	if err != nil {
+		return err
+	}
+	// remember the rolling count of active addresses and the circulating supply replaced, such that they can be restored
+	stats.ActiveAddresses30d, stats.CirculatingSupply = current.ActiveAddresses30d, current.CirculatingSupply
+	return mdb.putValue(memoryTypeDailyStatsUndo, strconv.FormatUint(uint64(height), 10), stats)
+}
+
+// RevertDailyStats implements DailyStatsDatabase.RevertDailyStats
+func (mdb *MemoryDatabase) RevertDailyStats(height types.BlockHeight) error {
+	var stats DailyStats
+	switch err := mdb.getValue(memoryTypeDailyStatsUndo, strconv.FormatUint(uint64(height), 10), &stats); err {
+	case nil:
+	case ErrNotFound:
+		return nil // the block was applied prior to upgrading an existing database
+	default:
+		return fmt.Errorf("%s: failed to get daily stats of block %d: %v", mdb.name, height, err)
+	}
+	current, err := mdb.GetDailyStats(stats.Date)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	current.Date = stats.Date
+	err = mdb.setDailyStats(current.Sub(stats))
+	if err != nil {
+		return err
+	}
+	return mdb.delete(memoryTypeDailyStatsUndo, strconv.FormatUint(uint64(height), 10))
+}
+
+// setDailyStats stores the given daily stats, dropping them if no block is rolled up.
+func (mdb *MemoryDatabase) setDailyStats(stats DailyStats) error {
+	if stats.IsZero() {
+		return mdb.delete(memoryTypeDailyStats, stats.Date)
+	}
+	return mdb.putValue(memoryTypeDailyStats, stats.Date, stats)
+}
+
+// GetDailyStats implements DailyStatsDatabase.GetDailyStats
+func (mdb *MemoryDatabase) GetDailyStats(date string) (DailyStats, error) {
+	var stats DailyStats
+	switch err := mdb.getValue(memoryTypeDailyStats, date, &stats); err {
+	case nil:
+		return stats, nil
+	case ErrNotFound:
+		return DailyStats{}, ErrNotFound
+	default:
+		return DailyStats{}, fmt.Errorf("%s: failed to get daily stats of %s: %v", mdb.name, date, err)
+	}
+}
+
+// ApplyRollingStats implements RollingStatsDatabase.ApplyRollingStats
+func (mdb *MemoryDatabase) ApplyRollingStats(height types.BlockHeight, timestamp types.Timestamp, stats RollingStats) error {
+	current, err := mdb.GetRollingStats()
+	if err != nil {
+		return err
+	}
+	block := rollingStatsBlock{Timestamp: timestamp, Stats: stats, Previous: current}
+	rolled, err := rollRollingStats(current, height, block, mdb.getRollingStatsBlock)
+	if err != nil {
+		return fmt.Errorf("%s: %v", mdb.name, err)
+	}
+	err = mdb.setRollingStats(rolled)
+	if err != nil {
+		return err
+	}
+	return mdb.putValue(memoryTypeRollingBlock, strconv.FormatUint(uint64(height), 10), block)
+}
+
+// RevertRollingStats implements RollingStatsDatabase.RevertRollingStats
+func (mdb *MemoryDatabase) RevertRollingStats(height types.BlockHeight) error {
+	block, err := mdb.getRollingStatsBlock(height)
+	if err == ErrNotFound {
+		return nil // the block was applied prior to upgrading an existing database
+	}
+	if err != nil {
+		return err
+	}
+	err = mdb.setRollingStats(block.Previous)
+	if err != nil {
+		return err
+	}
+	return mdb.delete(memoryTypeRollingBlock, strconv.FormatUint(uint64(height), 10))
+}
+
+// getRollingStatsBlock returns the record stored for the block at the given height, ErrNotFound if none.
+func (mdb *MemoryDatabase) getRollingStatsBlock(height types.BlockHeight) (rollingStatsBlock, error) {
+	var block rollingStatsBlock
+	switch err := mdb.getValue(memoryTypeRollingBlock, strconv.FormatUint(uint64(height), 10), &block); err {
+	case nil:
+		return block, nil
+	case ErrNotFound:
+		return rollingStatsBlock{}, ErrNotFound
+	default:
+		return rollingStatsBlock{}, fmt.Errorf("%s: failed to get rolling stats of block %d: %v", mdb.name, height, err)
+	}
+}
+
+// setRollingStats stores the given rolling stats, dropping the stats of a window if no block is counted.
+func (mdb *MemoryDatabase) setRollingStats(rolling []RollingStats) error {
+	for _, stats := range rolling {
+		var err error
+		if stats.BlockCount == 0 {
+			err = mdb.delete(memoryTypeRollingStats, stats.Window)
+		} else {
+			err = mdb.putValue(memoryTypeRollingStats, stats.Window, stats)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: failed to set rolling stats of %s: %v", mdb.name, stats.Window, err)
+		}
+	}
+	return nil
+}
+
+// GetRollingStats implements RollingStatsDatabase.GetRollingStats
+func (mdb *MemoryDatabase) GetRollingStats() ([]RollingStats, error) {
+	rolling := newRollingStats()
+	for i := range rolling {
+		err := mdb.getValue(memoryTypeRollingStats, rolling[i].Window, &rolling[i])
+		if err != nil && err != ErrNotFound {
+			return nil, fmt.Errorf("%s: failed to get rolling stats of %s: %v", mdb.name, rolling[i].Window, err)
+		}
+	}
+	return rolling, nil
+}
+
+// SetNetworkStatsSnapshot implements NetworkStatsHistoryDatabase.SetNetworkStatsSnapshot
+func (mdb *MemoryDatabase) SetNetworkStatsSnapshot(snapshot NetworkStatsSnapshot) error {
+	return mdb.putValue(memoryTypeStatsHistory, snapshot.Date, snapshot)
+}
+
+// DeleteNetworkStatsSnapshot implements NetworkStatsHistoryDatabase.DeleteNetworkStatsSnapshot
+func (mdb *MemoryDatabase) DeleteNetworkStatsSnapshot(date string) error {
+	return mdb.delete(memoryTypeStatsHistory, date)
+}
+
+// GetNetworkStatsSnapshot implements NetworkStatsHistoryDatabase.GetNetworkStatsSnapshot
+func (mdb *MemoryDatabase) GetNetworkStatsSnapshot(date string) (NetworkStatsSnapshot, error) {
+	var snapshot NetworkStatsSnapshot
+	switch err := mdb.getValue(memoryTypeStatsHistory, date, &snapshot); err {
+	case nil:
+		return snapshot, nil
+	case ErrNotFound:
+		return NetworkStatsSnapshot{}, ErrNotFound
+	default:
+		return NetworkStatsSnapshot{}, fmt.Errorf("%s: failed to get network stats snapshot of %s: %v", mdb.name, date, err)
+	}
+}
+
+// ApplyDailyAddressTotals implements DailyTopAddressesDatabase.ApplyDailyAddressTotals
+func (mdb *MemoryDatabase) ApplyDailyAddressTotals(date string, address types.UnlockHash, delta AddressTotals) error {
+	for _, ranking := range []struct {
+		side  string
+		value types.Currency
+	}{
+		{"sent", delta.TotalSent},
+		{"received", delta.TotalReceived},
+	} {
+		if ranking.value.IsZero() {
+			continue
+		}
+		key := date + ":" + ranking.side + ":" + address.String()
+		value := types.ZeroCurrency
+		err := mdb.getValue(memoryTypeDailyTop, key, &value)
+		if err != nil && err != ErrNotFound {
+			return fmt.Errorf("%s: failed to get top address %s: %v", mdb.name, key, err)
+		}
+		err = mdb.putValue(memoryTypeDailyTop, key, value.Add(ranking.value))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevertDailyAddressTotals implements DailyTopAddressesDatabase.RevertDailyAddressTotals
+func (mdb *MemoryDatabase) RevertDailyAddressTotals(date string, address types.UnlockHash, delta AddressTotals) error {
+	for _, ranking := range []struct {
+		side  string
+		value types.Currency
+	}{
+		{"sent", delta.TotalSent},
+		{"received", delta.TotalReceived},
+	} {
+		if ranking.value.IsZero() {
+			continue
+		}
+		key := date + ":" + ranking.side + ":" + address.String()
+		var value types.Currency
+		switch err := mdb.getValue(memoryTypeDailyTop, key, &value); err {
+		case nil:
+		case ErrNotFound:
+			continue // trimmed from the leaderboard already
+		default:
+			return fmt.Errorf("%s: failed to get top address %s: %v", mdb.name, key, err)
+		}
+		var err error
+		if value.Cmp(ranking.value) <= 0 {
+			err = mdb.delete(memoryTypeDailyTop, key)
+		} else {
+			err = mdb.putValue(memoryTypeDailyTop, key, value.Sub(ranking.value))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TrimDailyTopAddresses implements DailyTopAddressesDatabase.TrimDailyTopAddresses
+func (mdb *MemoryDatabase) TrimDailyTopAddresses(date string, n int) error {
+	for _, side := range []string{"sent", "received"} {
+		entries, err := mdb.getDailyTopAddresses(date, side)
+		if err != nil {
+			return err
+		}
+		if len(entries) <= n {
+			continue
+		}
+		for _, entry := range entries[n:] {
+			err = mdb.delete(memoryTypeDailyTop, date+":"+side+":"+entry.Address.String())
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// GetDailyTopSenders implements DailyTopAddressesDatabase.GetDailyTopSenders
+func (mdb *MemoryDatabase) GetDailyTopSenders(date string, n int) ([]DailyTopAddress, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	entries, err := mdb.getDailyTopAddresses(date, "sent")
+	if err != nil || len(entries) <= n {
+		return entries, err
+	}
+	return entries[:n], nil
+}
+
+// GetDailyTopReceivers implements DailyTopAddressesDatabase.GetDailyTopReceivers
+func (mdb *MemoryDatabase) GetDailyTopReceivers(date string, n int) ([]DailyTopAddress, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	entries, err := mdb.getDailyTopAddresses(date, "received")
+	if err != nil || len(entries) <= n {
+		return entries, err
+	}
+	return entries[:n], nil
+}
+
+// getDailyTopAddresses returns all addresses ranked by the coins they sent or received (the given side) on the given date,
+// highest value first.
+func (mdb *MemoryDatabase) getDailyTopAddresses(date, side string) ([]DailyTopAddress, error) {
+	prefix := date + ":" + side + ":"
+	var entries []DailyTopAddress
+	for _, key := range mdb.keys(memoryTypeDailyTop, prefix) {
+		var entry DailyTopAddress
+		err := entry.Address.LoadString(key[len(prefix):])
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid top address %q: %v", mdb.name, key, err)
+		}
+		err = mdb.getValue(memoryTypeDailyTop, key, &entry.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to get top address %s: %v", mdb.name, key, err)
+		}
+		entries = append(entries, entry)
+	}
+	sortDailyTopAddresses(entries)
+	return entries, nil
+}
+
+// ApplyCreatedBlock implements BlockCreatorDatabase.ApplyCreatedBlock
+func (mdb *MemoryDatabase) ApplyCreatedBlock(creator types.UnlockHash, payouts types.Currency) error {
+	stats, err := mdb.GetBlockCreatorStats(creator)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	stats.BlockCount++
+	stats.Payouts = stats.Payouts.Add(payouts)
+	return mdb.putValue(memoryTypeBlockCreator, creator.String(), stats)
+}
+
+// RevertCreatedBlock implements BlockCreatorDatabase.RevertCreatedBlock
+func (mdb *MemoryDatabase) RevertCreatedBlock(creator types.UnlockHash, payouts types.Currency) error {
+	stats, err := mdb.GetBlockCreatorStats(creator)
+	if err == ErrNotFound {
+		return nil // created prior to the dataset tracking block creators
+	}
+	if err != nil {
+		return err
+	}
+	stats.BlockCount--
+	if stats.BlockCount == 0 {
+		return mdb.delete(memoryTypeBlockCreator, creator.String())
+	}
+	if stats.Payouts.Cmp(payouts) >= 0 {
+		stats.Payouts = stats.Payouts.Sub(payouts)
+	} else {
+		stats.Payouts = types.ZeroCurrency
+	}
+	return mdb.putValue(memoryTypeBlockCreator, creator.String(), stats)
+}
+
+// GetBlockCreatorStats implements BlockCreatorDatabase.GetBlockCreatorStats
+func (mdb *MemoryDatabase) GetBlockCreatorStats(address types.UnlockHash) (BlockCreatorStats, error) {
+	var stats BlockCreatorStats
+	switch err := mdb.getValue(memoryTypeBlockCreator, address.String(), &stats); err {
+	case nil:
+		return stats, nil
+	case ErrNotFound:
+		return BlockCreatorStats{}, ErrNotFound
+	default:
+		return BlockCreatorStats{}, fmt.Errorf("%s: failed to get stats of block creator %s: %v", mdb.name, address.String(), err)
+	}
+}
+
+// GetBlockCreators implements BlockCreatorDatabase.GetBlockCreators
+func (mdb *MemoryDatabase) GetBlockCreators(n int) ([]BlockCreatorEntry, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	var entries []BlockCreatorEntry
+	for _, key := range mdb.keys(memoryTypeBlockCreator, "") {
+		var entry BlockCreatorEntry
+		err := entry.Address.LoadString(key)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid block creator address %q: %v", mdb.name, key, err)
+		}
+		entry.Stats, err = mdb.GetBlockCreatorStats(entry.Address)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	sortBlockCreators(entries)
+	if len(entries) > n {
+		entries = entries[:n]
+	}
+	return entries, nil
+}
+
+// getBotRecordHistory returns all states of the given 3Bot record, latest last.
+func (mdb *MemoryDatabase) getBotRecordHistory(id BotID) ([]BotRecord, error) {
+	var history []BotRecord
+	switch err := mdb.getValue(memoryTypeBot, strconv.FormatUint(uint64(id), 10), &history); err {
+	case nil:
+		return history, nil
+	case ErrNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, fmt.Errorf("%s: failed to get 3Bot record %d: %v", mdb.name, id, err)
+	}
+}
+
+// SetBotRecord implements ThreeBotDatabase.SetBotRecord
+func (mdb *MemoryDatabase) SetBotRecord(record BotRecord) error {
+	history, err := mdb.getBotRecordHistory(record.ID)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	return mdb.putValue(memoryTypeBot, strconv.FormatUint(uint64(record.ID), 10), append(history, record))
+}
+
+// RevertBotRecord implements ThreeBotDatabase.RevertBotRecord
+func (mdb *MemoryDatabase) RevertBotRecord(id BotID) (BotRecord, error) {
+	history, err := mdb.getBotRecordHistory(id)
+	if err != nil {
+		return BotRecord{}, err
+	}
+	key := strconv.FormatUint(uint64(id), 10)
+	history = history[:len(history)-1]
+	if len(history) == 0 {
+		return BotRecord{}, mdb.delete(memoryTypeBot, key)
+	}
+	return history[len(history)-1], mdb.putValue(memoryTypeBot, key, history)
+}
+
+// GetBotRecord implements ThreeBotDatabase.GetBotRecord
+func (mdb *MemoryDatabase) GetBotRecord(id BotID) (BotRecord, error) {
+	history, err := mdb.getBotRecordHistory(id)
+	if err != nil {
+		return BotRecord{}, err
+	}
+	return history[len(history)-1], nil
+}
+
+// GetBotCount implements ThreeBotDatabase.GetBotCount
+func (mdb *MemoryDatabase) GetBotCount() (BotID, error) {
+	return BotID(len(mdb.values[memoryTypeBot])), nil
+}
+
+// SetBotName implements ThreeBotDatabase.SetBotName
+func (mdb *MemoryDatabase) SetBotName(name string, id BotID) error {
+	return mdb.putValue(memoryTypeBotName, name, id)
+}
+
+// DeleteBotName implements ThreeBotDatabase.DeleteBotName
+func (mdb *MemoryDatabase) DeleteBotName(name string) error {
+	return mdb.delete(memoryTypeBotName, name)
+}
+
+// GetBotIDByName implements ThreeBotDatabase.GetBotIDByName
+func (mdb *MemoryDatabase) GetBotIDByName(name string) (BotID, error) {
+	var id BotID
+	switch err := mdb.getValue(memoryTypeBotName, name, &id); err {
+	case nil:
+		return id, nil
+	case ErrNotFound:
+		return 0, ErrNotFound
+	default:
+		return 0, fmt.Errorf("%s: failed to get 3Bot ID of name %s: %v", mdb.name, name, err)
+	}
+}
+
+// ApplyERC20AddressRegistration implements ERC20Database.ApplyERC20AddressRegistration
+func (mdb *MemoryDatabase) ApplyERC20AddressRegistration(address string, uh types.UnlockHash) error {
+	err := mdb.putValue(memoryTypeERC20Address, address, uh)
+	if err != nil {
+		return err
+	}
+	return mdb.putValue(memoryTypeERC20UH, uh.String(), address)
+}
+
+// RevertERC20AddressRegistration implements ERC20Database.RevertERC20AddressRegistration
+func (mdb *MemoryDatabase) RevertERC20AddressRegistration(address string, uh types.UnlockHash) error {
+	err := mdb.delete(memoryTypeERC20Address, address)
+	if err != nil {
+		return err
+	}
+	return mdb.delete(memoryTypeERC20UH, uh.String())
+}
+
+// GetERC20UnlockHash implements ERC20Database.GetERC20UnlockHash
+func (mdb *MemoryDatabase) GetERC20UnlockHash(address string) (types.UnlockHash, error) {
+	var uh types.UnlockHash
+	switch err := mdb.getValue(memoryTypeERC20Address, address, &uh); err {
+	case nil:
+		return uh, nil
+	case ErrNotFound:
+		return types.UnlockHash{}, ErrNotFound
+	default:
+		return types.UnlockHash{}, fmt.Errorf("%s: failed to get TFT address of ERC20 address %s: %v", mdb.name, address, err)
+	}
+}
+
+// GetERC20Address implements ERC20Database.GetERC20Address
+func (mdb *MemoryDatabase) GetERC20Address(uh types.UnlockHash) (string, error) {
+	var address string
+	switch err := mdb.getValue(memoryTypeERC20UH, uh.String(), &address); err {
+	case nil:
+		return address, nil
+	case ErrNotFound:
+		return "", ErrNotFound
+	default:
+		return "", fmt.Errorf("%s: failed to get ERC20 address of %s: %v", mdb.name, uh.String(), err)
+	}
+}
+
+// SetERC20BridgeStats implements ERC20Database.SetERC20BridgeStats
+func (mdb *MemoryDatabase) SetERC20BridgeStats(stats ERC20BridgeStats) error {
+	if stats.ConversionCount == 0 && stats.CreationCount == 0 && stats.RegistrationCount == 0 {
+		return mdb.delete(memoryTypeERC20Stats, "")
+	}
+	return mdb.putValue(memoryTypeERC20Stats, "", stats)
+}
+
+// GetERC20BridgeStats implements ERC20Database.GetERC20BridgeStats
+func (mdb *MemoryDatabase) GetERC20BridgeStats() (ERC20BridgeStats, error) {
+	var stats ERC20BridgeStats
+	switch err := mdb.getValue(memoryTypeERC20Stats, "", &stats); err {
+	case nil, ErrNotFound:
+		return stats, nil
+	default:
+		return ERC20BridgeStats{}, fmt.Errorf("%s: failed to get ERC20 bridge stats: %v", mdb.name, err)
+	}
+}
+
+// SetGenesisAllocation implements GenesisDatabase.SetGenesisAllocation
+func (mdb *MemoryDatabase) SetGenesisAllocation(allocation GenesisAllocation) error {
+	return mdb.putValue(memoryTypeGenesis, "", allocation)
+}
+
+// DeleteGenesisAllocation implements GenesisDatabase.DeleteGenesisAllocation
+func (mdb *MemoryDatabase) DeleteGenesisAllocation() error {
+	return mdb.delete(memoryTypeGenesis, "")
+}
+
+// GetGenesisAllocation implements GenesisDatabase.GetGenesisAllocation
+func (mdb *MemoryDatabase) GetGenesisAllocation() (GenesisAllocation, error) {
+	var allocation GenesisAllocation
+	switch err := mdb.getValue(memoryTypeGenesis, "", &allocation); err {
+	case nil:
+		return allocation, nil
+	case ErrNotFound:
+		return GenesisAllocation{}, ErrNotFound
+	default:
+		return GenesisAllocation{}, fmt.Errorf("%s: failed to get genesis allocation: %v", mdb.name, err)
+	}
+}
+
+// GetWallet implements Database.GetWallet
+func (mdb *MemoryDatabase) GetWallet(address types.UnlockHash) (Wallet, error) {
+	var wallet Wallet
+	switch err := mdb.getValue(memoryTypeWallet, address.String(), &wallet); err {
+	case nil:
+		err = upgradeWallet(&wallet, mdb.networkTime)
+		if err != nil {
+			return Wallet{}, fmt.Errorf("%s: failed to upgrade wallet for %s: %v", mdb.name, address.String(), err)
+		}
+		return wallet, nil
+	case ErrNotFound:
+		return Wallet{}, ErrNotFound
+	default:
+		return Wallet{}, fmt.Errorf("%s: failed to get wallet for %s: %v", mdb.name, address.String(), err)
+	}
+}
+
+// SampleAddresses implements Database.SampleAddresses
+//
+// All addresses are iterated, using reservoir sampling to select the sampled addresses.
+func (mdb *MemoryDatabase) SampleAddresses(n int) ([]types.UnlockHash, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	var (
+		addresses []types.UnlockHash
+		i         int
+	)
+	for key := range mdb.values[memoryTypeWallet] {
+		var uh types.UnlockHash
+		err := uh.LoadString(key)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to load address %q: %v", mdb.name, key, err)
+		}
+		if i < n {
+			addresses = append(addresses, uh)
+		} else if j := rnd.Intn(i + 1); j < n {
+			addresses[j] = uh
+		}
+		i++
+	}
+	return addresses, nil
+}
+
+// GetCoinOutput implements Database.GetCoinOutput
+func (mdb *MemoryDatabase) GetCoinOutput(id types.CoinOutputID) (CoinOutputInfo, error) {
+	co, err := mdb.getCoinOutput(id)
+	switch err {
+	case nil:
+	case ErrNotFound:
+		return CoinOutputInfo{}, ErrNotFound
+	default:
+		return CoinOutputInfo{}, fmt.Errorf("%s: failed to get coin output %s: %v", mdb.name, id.String(), err)
+	}
+	info, err := co.Info(id)
+	if err != nil {
+		return CoinOutputInfo{}, fmt.Errorf(
+			"%s: failed to decode raw condition of coin output %s: %v", mdb.name, id.String(), err)
+	}
+	return info, nil
+}
+
+// IterateCoinOutputs implements CoinOutputSetDatabase.IterateCoinOutputs
+func (mdb *MemoryDatabase) IterateCoinOutputs(fn func(CoinOutputInfo) error) error {
+	for _, key := range mdb.keys(memoryTypeCoinOutput, "") {
+		var id types.CoinOutputID
+		err := id.LoadString(key)
+		if err != nil {
+			return fmt.Errorf("%s: invalid coin output ID %q: %v", mdb.name, key, err)
+		}
+		info, err := mdb.GetCoinOutput(id)
+		if err != nil {
+			return err
+		}
+		err = fn(info)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mdb *MemoryDatabase) lockValueAsLockTime(lt LockType, value LockValue) LockValue {
+	switch lt {
+	case LockTypeTime:
+		return value
+	case LockTypeHeight:
+		return LockValue(mdb.networkTime) + (value-LockValue(mdb.networkBlockHeight))*mdb.blockFrequency
+	default:
+		panic(fmt.Sprintf("invalid lock type %d", lt))
+	}
+}
@@ -0,0 +1,144 @@
+package rexplorer
+
+import (
+	"fmt"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// CheckSchemaVersion ensures the data stored by the given database uses the latest schema version,
+// should the database implement SchemaDatabase. Datasets using an older schema version
+// have to be upgraded first, using the migrate command (see SchemaDatabase.Migrate).
+func CheckSchemaVersion(db Database) error {
+	sdb, ok := db.(SchemaDatabase)
+	if !ok {
+		return nil // layout isn't versioned
+	}
+	version, err := sdb.GetSchemaVersion()
+	if err != nil {
+		return fmt.Errorf("failed to get schema version: %v", err)
+	}
+	switch latest := sdb.LatestSchemaVersion(); {
+	case version < latest:
+		return fmt.Errorf("stored data uses schema version %d, while version %d is required: run the migrate command to upgrade it", version, latest)
+	case version > latest:
+		return fmt.Errorf("stored data uses schema version %d, which is newer than the latest known version %d", version, latest)
+	}
+	return nil
+}
+
+// redisMigration upgrades the data stored by a RedisDatabase to the next schema version.
+type redisMigration struct {
+	description string
+	migrate     func(rdb *RedisDatabase) error
+}
+
+// redisMigrations lists all migrations of the RedisDatabase schema, in order,
+// the migration at index i upgrading the stored data from version i to version i+1.
+// Datasets created prior to the schema being versioned use version 0.
+//
+// Migrations are only ever appended, and should be idempotent,
+// as the stored version is only updated once a migration completed.
+var redisMigrations = []redisMigration{
+	{
+		description: "track the address count and the stats rolled up per address prefix",
+		migrate: func(rdb *RedisDatabase) error {
+			err := rdb.ensureAddressCount()
+			if err != nil {
+				return err
+			}
+			return rdb.ensureAddressPrefixStats()
+		},
+	},
+	{
+		description: "rank all wallets with a non-zero coin balance in the rich list",
+		migrate: func(rdb *RedisDatabase) error {
+			return rdb.ensureRichList()
+		},
+	},
+	{
+		description: "index the coin outputs locked by height or time in sorted sets, scored by their lock value",
+		migrate: func(rdb *RedisDatabase) error {
+			return rdb.indexCoinOutputLocks()
+		},
+	},
+	{
+		description: "count the addresses ranked in the rich list per balance range",
+		migrate: func(rdb *RedisDatabase) error {
+			return rdb.ensureBalanceDistribution()
+		},
+	},
+	{
+		description: "index the locked outputs of all wallets in sorted sets, such that they can be listed page by page",
+		migrate: func(rdb *RedisDatabase) error {
+			return rdb.indexWalletLockedOutputs()
+		},
+	},
+	{
+		description: "rewrite the coin outputs stored without their raw condition in the current layout",
+		migrate: func(rdb *RedisDatabase) error {
+			return rdb.upgradeLegacyCoinOutputs()
+		},
+	},
+}
+
+var (
+	_ SchemaDatabase = (*RedisDatabase)(nil)
+)
+
+// GetSchemaVersion implements SchemaDatabase.GetSchemaVersion
+func (rdb *RedisDatabase) GetSchemaVersion() (uint64, error) {
+	version, err := redis.Uint64(rdb.conn.Do("HGET", rdb.key(internalKey), internalFieldSchema))
+	if err == redis.ErrNil {
+		return 0, nil // created prior to the schema being versioned
+	}
+	if err != nil {
+		return 0, fmt.Errorf("redis: failed to get schema version: %v", err)
+	}
+	return version, nil
+}
+
+// LatestSchemaVersion implements SchemaDatabase.LatestSchemaVersion
+func (rdb *RedisDatabase) LatestSchemaVersion() uint64 {
+	return uint64(len(redisMigrations))
+}
+
+// Migrate implements SchemaDatabase.Migrate
+//
+// applies all migrations the stored data hasn't been upgraded with yet,
+// storing the upgraded schema version after each migration,
+// such that an interrupted migration can be resumed.
+func (rdb *RedisDatabase) Migrate(progress func(version uint64, description string)) error {
+	version, err := rdb.GetSchemaVersion()
+	if err != nil {
+		return err
+	}
+	latest := rdb.LatestSchemaVersion()
+	if version > latest {
+		return fmt.Errorf("redis: stored data uses schema version %d, which is newer than the latest known version %d", version, latest)
+	}
+	for ; version < latest; version++ {
+		migration := redisMigrations[version]
+		if progress != nil {
+			progress(version+1, migration.description)
+		}
+		err = migration.migrate(rdb)
+		if err != nil {
+			return fmt.Errorf("redis: failed to migrate to schema version %d: %v", version+1, err)
+		}
+		err = rdb.setSchemaVersion(version + 1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setSchemaVersion stores the schema version used by the stored data.
+func (rdb *RedisDatabase) setSchemaVersion(version uint64) error {
+	err := RedisError(rdb.conn.Do("HSET", rdb.key(internalKey), internalFieldSchema, version))
+	if err != nil {
+		return fmt.Errorf("redis: failed to store schema version %d: %v", version, err)
+	}
+	return nil
+}
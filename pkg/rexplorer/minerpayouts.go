@@ -0,0 +1,55 @@
+package rexplorer
+
+import (
+	"fmt"
+
+	"github.com/rivine/rivine/types"
+)
+
+// MinerPayoutRecord records a single miner payout received by an address, being either the block reward
+// or a transaction fee paid to the creator of a block, as well as the ID of the coin output it created.
+type MinerPayoutRecord struct {
+	BlockHeight types.BlockHeight  `json:"height"`
+	ID          types.CoinOutputID `json:"id"`
+	Value       types.Currency     `json:"value"`
+}
+
+// applyMinerPayoutHistory records the miner payouts of the given block, applied at the current block height,
+// in the payout history of the addresses receiving them, in case the database supports it.
+func (explorer *Explorer) applyMinerPayoutHistory(block types.Block) {
+	mpdb, ok := explorer.db.(MinerPayoutDatabase)
+	if !ok {
+		return
+	}
+	for i, mp := range block.MinerPayouts {
+		err := mpdb.ApplyMinerPayout(mp.UnlockHash, MinerPayoutRecord{
+			BlockHeight: explorer.stats.BlockHeight,
+			ID:          types.CoinOutputID(block.MinerPayoutID(uint64(i))),
+			Value:       mp.Value,
+		})
+		if err != nil {
+			panic(fmt.Sprintf("failed to apply miner payout of %s to %s: %v",
+				mp.Value.String(), mp.UnlockHash.String(), err))
+		}
+	}
+}
+
+// revertMinerPayoutHistory drops the miner payouts of the given block, reverted at the current block height,
+// from the payout history of the addresses which received them, in case the database supports it.
+func (explorer *Explorer) revertMinerPayoutHistory(block types.Block) {
+	mpdb, ok := explorer.db.(MinerPayoutDatabase)
+	if !ok {
+		return
+	}
+	reverted := make(map[types.UnlockHash]struct{}, len(block.MinerPayouts))
+	for _, mp := range block.MinerPayouts {
+		reverted[mp.UnlockHash] = struct{}{}
+	}
+	for _, uh := range sortedAddresses(reverted) {
+		err := mpdb.RevertMinerPayouts(uh, explorer.stats.BlockHeight)
+		if err != nil {
+			panic(fmt.Sprintf("failed to revert miner payouts of block %d to %s: %v",
+				explorer.stats.BlockHeight, uh.String(), err))
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package rexplorer
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/rivine/rivine/types"
+)
+
+// The versions of the minting transactions, as defined by tfchain,
+// supported on the networks of which the transaction controllers register them.
+const (
+	// TransactionVersionMinterDefinition defines the transaction version of a minter definition transaction,
+	// redefining the condition which has to be fulfilled in order to create coins.
+	TransactionVersionMinterDefinition types.TransactionVersion = 128
+	// TransactionVersionCoinCreation defines the transaction version of a coin creation transaction,
+	// creating coins (its coin outputs and miner fees) without spending any coin inputs.
+	TransactionVersionCoinCreation types.TransactionVersion = 129
+)
+
+// MintConditionChange records a change of the mint condition, being the condition which has to be fulfilled
+// in order to create coins, as defined by a minter definition transaction.
+type MintConditionChange struct {
+	BlockHeight   types.BlockHeight          `json:"blockHeight"`
+	Timestamp     types.Timestamp            `json:"timestamp"`
+	TransactionID types.TransactionID        `json:"txID"`
+	UnlockHash    types.UnlockHash           `json:"unlockhash"`
+	Condition     types.UnlockConditionProxy `json:"condition"`
+}
+
+// ActiveMintCondition returns the change which defined the mint condition active at the given block height,
+// given the full (ordered) history of changes, returning false if the mint condition wasn't changed yet at that height.
+func ActiveMintCondition(history []MintConditionChange, height types.BlockHeight) (MintConditionChange, bool) {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].BlockHeight <= height {
+			return history[i], true
+		}
+	}
+	return MintConditionChange{}, false
+}
+
+// unmarshalTransactionData decodes the (version-specific) data of the given transaction into the given value.
+// As some transaction types are defined by tfchain, rather than by Rivine, their data is taken
+// from the JSON encoding of the transaction, as produced by its registered controller.
+func unmarshalTransactionData(tx types.Transaction, v interface{}) error {
+	b, err := json.Marshal(tx)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, &struct {
+		Data interface{} `json:"data"`
+	}{Data: v})
+}
+
+// getMintCondition returns the mint condition defined by the given minter definition transaction.
+func getMintCondition(tx types.Transaction) (types.UnlockConditionProxy, error) {
+	var data struct {
+		MintCondition types.UnlockConditionProxy `json:"mintcondition"`
+	}
+	err := unmarshalTransactionData(tx, &data)
+	if err != nil {
+		return types.UnlockConditionProxy{}, err
+	}
+	if data.MintCondition.Condition == nil {
+		return types.UnlockConditionProxy{}, fmt.Errorf("no mint condition defined")
+	}
+	return data.MintCondition, nil
+}
+
+// applyMintCondition records the mint condition defined by the given transaction,
+// in case it is a minter definition transaction and the database supports it.
+func (explorer *Explorer) applyMintCondition(tx types.Transaction) {
+	mcdb, ok := explorer.db.(MintConditionDatabase)
+	if !ok || tx.Version != TransactionVersionMinterDefinition {
+		return
+	}
+	id := tx.ID()
+	condition, err := getMintCondition(tx)
+	if err != nil {
+		panic(fmt.Sprintf("failed to get mint condition of minter definition tx %s: %v", id.String(), err))
+	}
+	err = mcdb.ApplyMintConditionChange(MintConditionChange{
+		BlockHeight:   explorer.stats.BlockHeight,
+		Timestamp:     explorer.stats.Timestamp,
+		TransactionID: id,
+		UnlockHash:    condition.UnlockHash(),
+		Condition:     condition,
+	})
+	if err != nil {
+		panic(fmt.Sprintf("failed to apply mint condition of minter definition tx %s: %v", id.String(), err))
+	}
+}
+
+// revertMintCondition drops the mint condition defined by the given transaction from the history,
+// in case it is a minter definition transaction and the database supports it.
+func (explorer *Explorer) revertMintCondition(tx types.Transaction) {
+	mcdb, ok := explorer.db.(MintConditionDatabase)
+	if !ok || tx.Version != TransactionVersionMinterDefinition {
+		return
+	}
+	id := tx.ID()
+	err := mcdb.RevertMintConditionChange(id)
+	if err != nil {
+		panic(fmt.Sprintf("failed to revert mint condition of minter definition tx %s: %v", id.String(), err))
+	}
+}
+
+// createdCoins returns the coins created by the given transaction, being the value of its coin outputs and miner fees
+// in case it is a (regular or ERC20) coin creation transaction, and zero otherwise.
+func createdCoins(tx types.Transaction) (coins types.Currency) {
+	if tx.Version != TransactionVersionCoinCreation && tx.Version != TransactionVersionERC20CoinCreation {
+		return types.ZeroCurrency
+	}
+	for _, co := range tx.CoinOutputs {
+		coins = coins.Add(co.Value)
+	}
+	return coins.Add(transactionFee(tx))
+}
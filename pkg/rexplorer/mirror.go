@@ -0,0 +1,386 @@
+package rexplorer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync/atomic"
+
+	"github.com/rivine/rivine/types"
+)
+
+// MirrorDatabase is a Database which replays every call made to it onto a secondary Database,
+// after it has been made to the primary Database, such that a backend can be migrated to (or validated against)
+// another backend in production. Only the results of the primary Database are returned,
+// the secondary Database merely being compared against it: any divergence of the results
+// (or errors) returned by both databases is logged, and never fails the call.
+//
+// The optional interfaces implemented by the primary Database are not exposed by a MirrorDatabase,
+// with the exception of the TransactionalDatabase and BatchDatabase interfaces,
+// which are forwarded to each database implementing them.
+type MirrorDatabase struct {
+	primary, secondary Database
+	// name of the secondary database, used to identify it in the logs
+	name string
+	// amount of divergences logged so far
+	divergences uint64
+}
+
+// NewMirrorDatabase creates a Database which mirrors all calls made to the primary Database
+// onto the secondary Database, identified by the given name in the logs.
+func NewMirrorDatabase(primary, secondary Database, name string) *MirrorDatabase {
+	return &MirrorDatabase{
+		primary:   primary,
+		secondary: secondary,
+		name:      name,
+	}
+}
+
+var (
+	_ Database              = (*MirrorDatabase)(nil)
+	_ TransactionalDatabase = (*MirrorDatabase)(nil)
+	_ BatchDatabase         = (*MirrorDatabase)(nil)
+)
+
+// Divergences returns the amount of divergences in between both databases logged so far.
+func (mdb *MirrorDatabase) Divergences() uint64 {
+	return atomic.LoadUint64(&mdb.divergences)
+}
+
+// compare logs a divergence in case the secondary database returned a different error or result
+// than the primary database, for the call of the given name. Results are compared using their JSON encoding.
+func (mdb *MirrorDatabase) compare(call string, primaryErr, secondaryErr error, primary, secondary interface{}) {
+	switch {
+	case primaryErr == nil && secondaryErr == nil:
+		pb, err := json.Marshal(primary)
+		if err != nil {
+			mdb.diverged(call, "failed to JSON-encode primary result: %v", err)
+			return
+		}
+		sb, err := json.Marshal(secondary)
+		if err != nil {
+			mdb.diverged(call, "failed to JSON-encode secondary result: %v", err)
+			return
+		}
+		if !bytes.Equal(pb, sb) {
+			mdb.diverged(call, "primary returned %s, while secondary returned %s", pb, sb)
+		}
+	case primaryErr == nil:
+		mdb.diverged(call, "succeeded on primary, while secondary failed: %v", secondaryErr)
+	case secondaryErr == nil:
+		mdb.diverged(call, "failed on primary (%v), while secondary succeeded", primaryErr)
+	case (primaryErr == ErrNotFound) != (secondaryErr == ErrNotFound):
+		mdb.diverged(call, "primary failed with %v, while secondary failed with %v", primaryErr, secondaryErr)
+	}
+}
+
+// diverged logs a divergence of the call of the given name.
+func (mdb *MirrorDatabase) diverged(call string, format string, args ...interface{}) {
+	atomic.AddUint64(&mdb.divergences, 1)
+	log.Printf("[MIRROR] %s diverged on %s: %s", call, mdb.name, fmt.Sprintf(format, args...))
+}
+
+// mirrorWrite makes a call of which only the error is returned to both databases, comparing their errors.
+func (mdb *MirrorDatabase) mirrorWrite(call string, fn func(db Database) error) error {
+	err := fn(mdb.primary)
+	mdb.compare(call, err, fn(mdb.secondary), nil, nil)
+	return err
+}
+
+// Begin implements TransactionalDatabase.Begin,
+// beginning a transaction on each database which supports it.
+func (mdb *MirrorDatabase) Begin() error {
+	return mdb.mirrorWrite("Begin", func(db Database) error {
+		if tdb, ok := db.(TransactionalDatabase); ok {
+			return tdb.Begin()
+		}
+		return nil
+	})
+}
+
+// Commit implements TransactionalDatabase.Commit,
+// committing the transaction of each database which supports it.
+func (mdb *MirrorDatabase) Commit() error {
+	return mdb.mirrorWrite("Commit", func(db Database) error {
+		if tdb, ok := db.(TransactionalDatabase); ok {
+			return tdb.Commit()
+		}
+		return nil
+	})
+}
+
+// ApplyBlock implements BatchDatabase.ApplyBlock,
+// applying the changes using a single call on each database which supports it.
+func (mdb *MirrorDatabase) ApplyBlock(changes BlockChanges) ([]CoinOutputChangeResult, error) {
+	return mdb.mirrorBlockChanges("ApplyBlock", changes, false)
+}
+
+// RevertBlock implements BatchDatabase.RevertBlock,
+// reverting the changes using a single call on each database which supports it.
+func (mdb *MirrorDatabase) RevertBlock(changes BlockChanges) ([]CoinOutputChangeResult, error) {
+	return mdb.mirrorBlockChanges("RevertBlock", changes, true)
+}
+
+func (mdb *MirrorDatabase) mirrorBlockChanges(call string, changes BlockChanges, revert bool) ([]CoinOutputChangeResult, error) {
+	results, err := applyBlockChanges(mdb.primary, changes, revert)
+	secondaryResults, secondaryErr := applyBlockChanges(mdb.secondary, changes, revert)
+	mdb.compare(fmt.Sprintf("%s %d", call, changes.Height), err, secondaryErr, results, secondaryResults)
+	return results, err
+}
+
+// GetExplorerState implements Database.GetExplorerState
+func (mdb *MirrorDatabase) GetExplorerState() (ExplorerState, error) {
+	state, err := mdb.primary.GetExplorerState()
+	secondaryState, secondaryErr := mdb.secondary.GetExplorerState()
+	mdb.compare("GetExplorerState", err, secondaryErr, state, secondaryState)
+	return state, err
+}
+
+// SetExplorerState implements Database.SetExplorerState
+func (mdb *MirrorDatabase) SetExplorerState(state ExplorerState) error {
+	return mdb.mirrorWrite("SetExplorerState", func(db Database) error {
+		return db.SetExplorerState(state)
+	})
+}
+
+// GetNetworkStats implements Database.GetNetworkStats
+func (mdb *MirrorDatabase) GetNetworkStats() (NetworkStats, error) {
+	stats, err := mdb.primary.GetNetworkStats()
+	secondaryStats, secondaryErr := mdb.secondary.GetNetworkStats()
+	mdb.compare("GetNetworkStats", err, secondaryErr, stats, secondaryStats)
+	return stats, err
+}
+
+// SetNetworkStats implements Database.SetNetworkStats
+func (mdb *MirrorDatabase) SetNetworkStats(stats NetworkStats) error {
+	return mdb.mirrorWrite("SetNetworkStats", func(db Database) error {
+		return db.SetNetworkStats(stats)
+	})
+}
+
+// SetChainHealth implements Database.SetChainHealth
+func (mdb *MirrorDatabase) SetChainHealth(health ChainHealth) error {
+	return mdb.mirrorWrite("SetChainHealth", func(db Database) error {
+		return db.SetChainHealth(health)
+	})
+}
+
+// GetChainParametersHistory implements Database.GetChainParametersHistory
+func (mdb *MirrorDatabase) GetChainParametersHistory() ([]ChainParametersRecord, error) {
+	history, err := mdb.primary.GetChainParametersHistory()
+	secondaryHistory, secondaryErr := mdb.secondary.GetChainParametersHistory()
+	mdb.compare("GetChainParametersHistory", err, secondaryErr, history, secondaryHistory)
+	return history, err
+}
+
+// SetChainParametersHistory implements Database.SetChainParametersHistory
+func (mdb *MirrorDatabase) SetChainParametersHistory(history []ChainParametersRecord) error {
+	return mdb.mirrorWrite("SetChainParametersHistory", func(db Database) error {
+		return db.SetChainParametersHistory(history)
+	})
+}
+
+// GetAddressAliases implements Database.GetAddressAliases
+func (mdb *MirrorDatabase) GetAddressAliases() ([]AddressAlias, error) {
+	aliases, err := mdb.primary.GetAddressAliases()
+	secondaryAliases, secondaryErr := mdb.secondary.GetAddressAliases()
+	mdb.compare("GetAddressAliases", err, secondaryErr, aliases, secondaryAliases)
+	return aliases, err
+}
+
+// SetAddressAliases implements Database.SetAddressAliases
+func (mdb *MirrorDatabase) SetAddressAliases(aliases []AddressAlias) error {
+	return mdb.mirrorWrite("SetAddressAliases", func(db Database) error {
+		return db.SetAddressAliases(aliases)
+	})
+}
+
+// AddCoinOutput implements Database.AddCoinOutput
+func (mdb *MirrorDatabase) AddCoinOutput(id types.CoinOutputID, co CoinOutput) error {
+	return mdb.mirrorWrite("AddCoinOutput "+id.String(), func(db Database) error {
+		return db.AddCoinOutput(id, co)
+	})
+}
+
+// AddLockedCoinOutput implements Database.AddLockedCoinOutput
+func (mdb *MirrorDatabase) AddLockedCoinOutput(id types.CoinOutputID, co CoinOutput, lt LockType, lockValue LockValue) error {
+	return mdb.mirrorWrite("AddLockedCoinOutput "+id.String(), func(db Database) error {
+		return db.AddLockedCoinOutput(id, co, lt, lockValue)
+	})
+}
+
+// SpendCoinOutput implements Database.SpendCoinOutput
+func (mdb *MirrorDatabase) SpendCoinOutput(id types.CoinOutputID) (types.UnlockHash, types.Currency, error) {
+	owner, value, err := mdb.primary.SpendCoinOutput(id)
+	secondaryOwner, secondaryValue, secondaryErr := mdb.secondary.SpendCoinOutput(id)
+	mdb.compare("SpendCoinOutput "+id.String(), err, secondaryErr,
+		[]interface{}{owner, value}, []interface{}{secondaryOwner, secondaryValue})
+	return owner, value, err
+}
+
+// RevertCoinInput implements Database.RevertCoinInput
+func (mdb *MirrorDatabase) RevertCoinInput(id types.CoinOutputID) (types.UnlockHash, types.Currency, error) {
+	owner, value, err := mdb.primary.RevertCoinInput(id)
+	secondaryOwner, secondaryValue, secondaryErr := mdb.secondary.RevertCoinInput(id)
+	mdb.compare("RevertCoinInput "+id.String(), err, secondaryErr,
+		[]interface{}{owner, value}, []interface{}{secondaryOwner, secondaryValue})
+	return owner, value, err
+}
+
+// RevertCoinOutput implements Database.RevertCoinOutput
+func (mdb *MirrorDatabase) RevertCoinOutput(id types.CoinOutputID) (CoinOutputState, error) {
+	state, err := mdb.primary.RevertCoinOutput(id)
+	secondaryState, secondaryErr := mdb.secondary.RevertCoinOutput(id)
+	mdb.compare("RevertCoinOutput "+id.String(), err, secondaryErr, state, secondaryState)
+	return state, err
+}
+
+// ApplyCoinOutputLocks implements Database.ApplyCoinOutputLocks
+func (mdb *MirrorDatabase) ApplyCoinOutputLocks(height types.BlockHeight, time types.Timestamp) (uint64, types.Currency, error) {
+	n, coins, err := mdb.primary.ApplyCoinOutputLocks(height, time)
+	secondaryN, secondaryCoins, secondaryErr := mdb.secondary.ApplyCoinOutputLocks(height, time)
+	mdb.compare(fmt.Sprintf("ApplyCoinOutputLocks %d", height), err, secondaryErr,
+		[]interface{}{n, coins}, []interface{}{secondaryN, secondaryCoins})
+	return n, coins, err
+}
+
+// RevertCoinOutputLocks implements Database.RevertCoinOutputLocks
+func (mdb *MirrorDatabase) RevertCoinOutputLocks(height types.BlockHeight, time types.Timestamp) (uint64, types.Currency, error) {
+	n, coins, err := mdb.primary.RevertCoinOutputLocks(height, time)
+	secondaryN, secondaryCoins, secondaryErr := mdb.secondary.RevertCoinOutputLocks(height, time)
+	mdb.compare(fmt.Sprintf("RevertCoinOutputLocks %d", height), err, secondaryErr,
+		[]interface{}{n, coins}, []interface{}{secondaryN, secondaryCoins})
+	return n, coins, err
+}
+
+// SetMultisigAddresses implements Database.SetMultisigAddresses
+func (mdb *MirrorDatabase) SetMultisigAddresses(address types.UnlockHash, owners []types.UnlockHash, signaturesRequired uint64) error {
+	return mdb.mirrorWrite("SetMultisigAddresses "+address.String(), func(db Database) error {
+		return db.SetMultisigAddresses(address, owners, signaturesRequired)
+	})
+}
+
+// ApplyCounterpartyTransfer implements Database.ApplyCounterpartyTransfer
+func (mdb *MirrorDatabase) ApplyCounterpartyTransfer(from, to types.UnlockHash, value types.Currency) error {
+	return mdb.mirrorWrite("ApplyCounterpartyTransfer", func(db Database) error {
+		return db.ApplyCounterpartyTransfer(from, to, value)
+	})
+}
+
+// RevertCounterpartyTransfer implements Database.RevertCounterpartyTransfer
+func (mdb *MirrorDatabase) RevertCounterpartyTransfer(from, to types.UnlockHash, value types.Currency) error {
+	return mdb.mirrorWrite("RevertCounterpartyTransfer", func(db Database) error {
+		return db.RevertCounterpartyTransfer(from, to, value)
+	})
+}
+
+// ApplyWalletGroupFlows implements Database.ApplyWalletGroupFlows
+func (mdb *MirrorDatabase) ApplyWalletGroupFlows(timestamp types.Timestamp, flows WalletGroupFlows) error {
+	return mdb.mirrorWrite("ApplyWalletGroupFlows", func(db Database) error {
+		return db.ApplyWalletGroupFlows(timestamp, flows)
+	})
+}
+
+// RevertWalletGroupFlows implements Database.RevertWalletGroupFlows
+func (mdb *MirrorDatabase) RevertWalletGroupFlows(timestamp types.Timestamp, flows WalletGroupFlows) error {
+	return mdb.mirrorWrite("RevertWalletGroupFlows", func(db Database) error {
+		return db.RevertWalletGroupFlows(timestamp, flows)
+	})
+}
+
+// GetWalletGroupFlows implements Database.GetWalletGroupFlows
+func (mdb *MirrorDatabase) GetWalletGroupFlows() (WalletGroupFlows, map[string]WalletGroupFlows, error) {
+	total, daily, err := mdb.primary.GetWalletGroupFlows()
+	secondaryTotal, secondaryDaily, secondaryErr := mdb.secondary.GetWalletGroupFlows()
+	mdb.compare("GetWalletGroupFlows", err, secondaryErr,
+		[]interface{}{total, daily}, []interface{}{secondaryTotal, secondaryDaily})
+	return total, daily, err
+}
+
+// ApplyMultisigSpend implements Database.ApplyMultisigSpend
+func (mdb *MirrorDatabase) ApplyMultisigSpend(spend MultisigSpend) error {
+	return mdb.mirrorWrite("ApplyMultisigSpend", func(db Database) error {
+		return db.ApplyMultisigSpend(spend)
+	})
+}
+
+// RevertMultisigSpend implements Database.RevertMultisigSpend
+func (mdb *MirrorDatabase) RevertMultisigSpend(spend MultisigSpend) error {
+	return mdb.mirrorWrite("RevertMultisigSpend", func(db Database) error {
+		return db.RevertMultisigSpend(spend)
+	})
+}
+
+// GetMultisigSpends implements Database.GetMultisigSpends
+func (mdb *MirrorDatabase) GetMultisigSpends(address types.UnlockHash) (map[types.CoinOutputID][]types.UnlockHash, error) {
+	spends, err := mdb.primary.GetMultisigSpends(address)
+	secondarySpends, secondaryErr := mdb.secondary.GetMultisigSpends(address)
+	mdb.compare("GetMultisigSpends "+address.String(), err, secondaryErr, spends, secondarySpends)
+	return spends, err
+}
+
+// GetMultisigSignerStats implements Database.GetMultisigSignerStats
+func (mdb *MirrorDatabase) GetMultisigSignerStats(address types.UnlockHash) (map[types.UnlockHash]MultisigSignerStats, error) {
+	stats, err := mdb.primary.GetMultisigSignerStats(address)
+	secondaryStats, secondaryErr := mdb.secondary.GetMultisigSignerStats(address)
+	mdb.compare("GetMultisigSignerStats "+address.String(), err, secondaryErr, stats, secondaryStats)
+	return stats, err
+}
+
+// SetBlockSummary implements Database.SetBlockSummary
+func (mdb *MirrorDatabase) SetBlockSummary(summary BlockSummary) error {
+	return mdb.mirrorWrite(fmt.Sprintf("SetBlockSummary %d", summary.Height), func(db Database) error {
+		return db.SetBlockSummary(summary)
+	})
+}
+
+// RevertBlockSummary implements Database.RevertBlockSummary
+func (mdb *MirrorDatabase) RevertBlockSummary(height types.BlockHeight) error {
+	return mdb.mirrorWrite(fmt.Sprintf("RevertBlockSummary %d", height), func(db Database) error {
+		return db.RevertBlockSummary(height)
+	})
+}
+
+// GetBlockSummary implements Database.GetBlockSummary
+func (mdb *MirrorDatabase) GetBlockSummary(height types.BlockHeight) (BlockSummary, error) {
+	summary, err := mdb.primary.GetBlockSummary(height)
+	secondarySummary, secondaryErr := mdb.secondary.GetBlockSummary(height)
+	mdb.compare(fmt.Sprintf("GetBlockSummary %d", height), err, secondaryErr, summary, secondarySummary)
+	return summary, err
+}
+
+// GetWallet implements Database.GetWallet
+func (mdb *MirrorDatabase) GetWallet(address types.UnlockHash) (Wallet, error) {
+	wallet, err := mdb.primary.GetWallet(address)
+	secondaryWallet, secondaryErr := mdb.secondary.GetWallet(address)
+	mdb.compare("GetWallet "+address.String(), err, secondaryErr, wallet, secondaryWallet)
+	return wallet, err
+}
+
+// SampleAddresses implements Database.SampleAddresses,
+// only sampling the primary database, as the sample is random.
+func (mdb *MirrorDatabase) SampleAddresses(n int) ([]types.UnlockHash, error) {
+	return mdb.primary.SampleAddresses(n)
+}
+
+// GetCoinOutput implements Database.GetCoinOutput
+func (mdb *MirrorDatabase) GetCoinOutput(id types.CoinOutputID) (CoinOutputInfo, error) {
+	info, err := mdb.primary.GetCoinOutput(id)
+	secondaryInfo, secondaryErr := mdb.secondary.GetCoinOutput(id)
+	mdb.compare("GetCoinOutput "+id.String(), err, secondaryErr, info, secondaryInfo)
+	return info, err
+}
+
+// Close implements Database.Close, closing both databases.
+func (mdb *MirrorDatabase) Close() error {
+	if n := mdb.Divergences(); n > 0 {
+		log.Printf("[MIRROR] %s diverged %d time(s) from the primary database", mdb.name, n)
+	}
+	err := mdb.primary.Close()
+	secondaryErr := mdb.secondary.Close()
+	if err != nil {
+		return err
+	}
+	return secondaryErr
+}
@@ -0,0 +1,1244 @@
+//go:build mongo
+// +build mongo
+
+package rexplorer
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/rivine/rivine/crypto"
+	"github.com/rivine/rivine/types"
+)
+
+type (
+	// MongoDatabase is a Database implementation for MongoDB, using github.com/globalsign/mgo.
+	//
+	// Contrary to the flat hash schema of the RedisDatabase, wallets and coin outputs are stored
+	// as documents with secondary indexes, such that they can be queried ad-hoc
+	// (e.g. all outputs of an address, or all wallets with a balance above a given value).
+	// All currencies are stored as Decimal128 values, using the smallest coin unit as value.
+	//
+	// Following collections are used by this Database implementation:
+	//
+	//	  meta				internal state, network info, chain parameters, stats and health (as JSON, by _id)
+	//	  wallets			one document per address (_id), see mongoWallet
+	//	  coinoutputs		one document per coin output (_id), see mongoCoinOutput
+	//	  counterparties	one document per address and counterparty, see mongoCounterparty
+	//	  flows				one document for the total (_id: total) and each day (_id: <YYYY-MM-DD>), see mongoWalletGroupFlows
+	//	  multisigspends	one document per spent multisig coin output (_id), see mongoMultisigSpend
+	//	  multisigsigners	one document per multisig address and owner, see mongoMultisigSigner
+	//	  blocksummaries	one document per block height (_id), see mongoBlockSummary
+	//
+	// Addresses and IDs are stored in their Rivine-defined hex-encoded string format.
+	MongoDatabase struct {
+		session *mgo.Session
+		db      *mgo.Database
+
+		blockFrequency LockValue
+
+		// cached version of the chain stats
+		networkBlockHeight types.BlockHeight
+		networkTime        types.Timestamp
+	}
+
+	// mongoMeta is the document used to store a JSON-encoded value in the meta collection.
+	mongoMeta struct {
+		Name  string `bson:"_id"`
+		Value string `bson:"value"`
+	}
+	// mongoWallet is the document used to store a Wallet.
+	mongoWallet struct {
+		Address            string                    `bson:"_id"`
+		Unlocked           bson.Decimal128           `bson:"unlocked"`
+		Locked             bson.Decimal128           `bson:"locked"`
+		LockedOutputs      []mongoWalletLockedOutput `bson:"lockedOutputs,omitempty"`
+		MultiSignAddresses []string                  `bson:"multisigAddresses,omitempty"`
+		MultiSignOwners    []string                  `bson:"multisigOwners,omitempty"`
+		SignaturesRequired uint64                    `bson:"signaturesRequired,omitempty"`
+	}
+	// mongoWalletLockedOutput is the (embedded) document used to store a locked output of a wallet.
+	mongoWalletLockedOutput struct {
+		ID          string          `bson:"id"`
+		Amount      bson.Decimal128 `bson:"amount"`
+		LockedUntil uint64          `bson:"lockedUntil"`
+		Description []byte          `bson:"description,omitempty"`
+		Reason      string          `bson:"reason,omitempty"`
+	}
+	// mongoCoinOutput is the document used to store a coin output.
+	mongoCoinOutput struct {
+		ID           string          `bson:"_id"`
+		UnlockHash   string          `bson:"unlockhash"`
+		Value        bson.Decimal128 `bson:"value"`
+		State        CoinOutputState `bson:"state"`
+		LockType     LockType        `bson:"lockType"`
+		LockValue    uint64          `bson:"lockValue"`
+		Description  []byte          `bson:"description,omitempty"`
+		RawCondition []byte          `bson:"rawCondition"`
+	}
+	// mongoCounterparty is the document used to store the interactions of an address with one of its counterparties.
+	mongoCounterparty struct {
+		ID               string          `bson:"_id"`
+		Address          string          `bson:"address"`
+		Counterparty     string          `bson:"counterparty"`
+		TransactionCount uint64          `bson:"txCount"`
+		Sent             bson.Decimal128 `bson:"sent"`
+		Received         bson.Decimal128 `bson:"received"`
+	}
+	// mongoWalletGroupFlows is the document used to store the (total or daily) wallet group flows.
+	mongoWalletGroupFlows struct {
+		Day         string          `bson:"_id"`
+		SweepCount  uint64          `bson:"sweepCount"`
+		SweepValue  bson.Decimal128 `bson:"sweepValue"`
+		RefillCount uint64          `bson:"refillCount"`
+		RefillValue bson.Decimal128 `bson:"refillValue"`
+	}
+	// mongoMultisigSpend is the document used to store the owners that signed a spent multisig coin output.
+	mongoMultisigSpend struct {
+		ID      string   `bson:"_id"`
+		Address string   `bson:"address"`
+		Signers []string `bson:"signers"`
+	}
+	// mongoMultisigSigner is the document used to store the signing activity of an owner of a multisig address.
+	mongoMultisigSigner struct {
+		ID         string          `bson:"_id"`
+		Address    string          `bson:"address"`
+		Signer     string          `bson:"signer"`
+		SpendCount uint64          `bson:"spendCount"`
+		Value      bson.Decimal128 `bson:"value"`
+	}
+	// mongoBlockSummary is the document used to store the summary of a block.
+	mongoBlockSummary struct {
+		Height      uint64          `bson:"_id"`
+		ID          string          `bson:"id"`
+		Timestamp   uint64          `bson:"timestamp"`
+		OutputCount uint64          `bson:"outputCount"`
+		OutputValue bson.Decimal128 `bson:"outputValue"`
+		Histogram   []uint64        `bson:"histogram"`
+		// transaction summary, absent (and thus zero) for blocks stored prior to adding it
+		TransactionCount uint64          `bson:"txCount"`
+		ValueTransferred bson.Decimal128 `bson:"valueTransferred"`
+		Fees             bson.Decimal128 `bson:"fees"`
+		MinerPayouts     bson.Decimal128 `bson:"minerPayouts"`
+		Creator          string          `bson:"creator,omitempty"`
+		// base 10 difficulty, as it can exceed the precision of a Decimal128,
+		// absent for blocks stored prior to adding it
+		Difficulty string `bson:"difficulty,omitempty"`
+	}
+)
+
+const (
+	mongoCollectionMeta            = "meta"
+	mongoCollectionWallets         = "wallets"
+	mongoCollectionCoinOutputs     = "coinoutputs"
+	mongoCollectionCounterparties  = "counterparties"
+	mongoCollectionFlows           = "flows"
+	mongoCollectionMultisigSpends  = "multisigspends"
+	mongoCollectionMultisigSigners = "multisigsigners"
+	mongoCollectionBlockSummaries  = "blocksummaries"
+
+	mongoMetaState   = "state"
+	mongoMetaNetwork = "network"
+	mongoMetaParams  = "chainparams"
+	mongoMetaAliases = "aliases"
+	mongoMetaStats   = "stats"
+	mongoMetaHealth  = "health"
+)
+
+func init() {
+	RegisterDatabaseDriver("mongo", func(cfg DatabaseConfig) (Database, error) {
+		address := cfg.Address
+		if address == "" {
+			address = "mongodb://localhost:27017/rexplorer"
+		}
+		return NewMongoDatabase(address, cfg.BlockchainInfo, cfg.ChainConstants)
+	})
+}
+
+// NewMongoDatabase creates a new MongoDB Database client, using the database defined in the given URL,
+// see MongoDatabase for more information.
+func NewMongoDatabase(url string, bcInfo types.BlockchainInfo, chainCts types.ChainConstants) (*MongoDatabase, error) {
+	session, err := mgo.Dial(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial a MongoDB session to %s: %v", url, err)
+	}
+	session.SetSafe(&mgo.Safe{})
+	mdb := MongoDatabase{
+		session:        session,
+		db:             session.DB(""),
+		blockFrequency: LockValue(chainCts.BlockFrequency),
+	}
+	err = mdb.ensureIndexes()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	// ensure the network info is as expected (or register if this is a fresh db)
+	err = mdb.registerOrValidateNetworkInfo(bcInfo)
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	return &mdb, nil
+}
+
+// ensureIndexes creates the secondary indexes, if they don't exist yet.
+func (mdb *MongoDatabase) ensureIndexes() error {
+	for _, index := range []struct {
+		Collection string
+		Key        []string
+	}{
+		{mongoCollectionCoinOutputs, []string{"unlockhash"}},
+		{mongoCollectionCoinOutputs, []string{"state", "lockType", "lockValue"}},
+		{mongoCollectionWallets, []string{"multisigOwners"}},
+		{mongoCollectionCounterparties, []string{"address", "-txCount"}},
+		{mongoCollectionMultisigSpends, []string{"address"}},
+		{mongoCollectionMultisigSigners, []string{"address"}},
+	} {
+		err := mdb.db.C(index.Collection).EnsureIndex(mgo.Index{Key: index.Key})
+		if err != nil {
+			return fmt.Errorf("mongo: failed to ensure index %v of collection %s: %v", index.Key, index.Collection, err)
+		}
+	}
+	return nil
+}
+
+// Close implements Database.Close
+//
+// closes the internal MongoDB session
+func (mdb *MongoDatabase) Close() error {
+	mdb.session.Close()
+	return nil
+}
+
+// getMeta gets and decodes a JSON value from the meta collection,
+// returning ErrNotFound if it isn't stored.
+func (mdb *MongoDatabase) getMeta(name string, v interface{}) error {
+	var meta mongoMeta
+	switch err := mdb.db.C(mongoCollectionMeta).FindId(name).One(&meta); err {
+	case nil:
+		return json.Unmarshal([]byte(meta.Value), v)
+	case mgo.ErrNotFound:
+		return ErrNotFound
+	default:
+		return err
+	}
+}
+
+// setMeta encodes and stores a value as JSON in the meta collection.
+func (mdb *MongoDatabase) setMeta(name string, v interface{}) error {
+	_, err := mdb.db.C(mongoCollectionMeta).UpsertId(name, mongoMeta{
+		Name:  name,
+		Value: string(JSONMarshal(v)),
+	})
+	return err
+}
+
+// registerOrValidateNetworkInfo registeres the network name and chain name if it doesn't exist yet,
+// otherwise it ensures that the returned network info matches the expected network info.
+func (mdb *MongoDatabase) registerOrValidateNetworkInfo(bcInfo types.BlockchainInfo) error {
+	networkInfo := NetworkInfo{
+		ChainName:   bcInfo.Name,
+		NetworkName: bcInfo.NetworkName,
+	}
+	var receivedNetworkInfo NetworkInfo
+	switch err := mdb.getMeta(mongoMetaNetwork, &receivedNetworkInfo); err {
+	case nil:
+	case ErrNotFound:
+		err = mdb.setMeta(mongoMetaNetwork, networkInfo)
+		if err != nil {
+			return fmt.Errorf("failed to register network info: %v", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("failed to validate network info: %v", err)
+	}
+	if receivedNetworkInfo != networkInfo {
+		return fmt.Errorf("cannot store data for chain %s/%s: db has already data for chain %s/%s stored",
+			networkInfo.ChainName, networkInfo.NetworkName,
+			receivedNetworkInfo.ChainName, receivedNetworkInfo.NetworkName)
+	}
+	return nil
+}
+
+// GetExplorerState implements Database.GetExplorerState
+func (mdb *MongoDatabase) GetExplorerState() (ExplorerState, error) {
+	var state ExplorerState
+	switch err := mdb.getMeta(mongoMetaState, &state); err {
+	case nil:
+		return state, nil
+	case ErrNotFound:
+		// default to fresh explorer state if not stored yet
+		return NewExplorerState(), nil
+	default:
+		return ExplorerState{}, err
+	}
+}
+
+// SetExplorerState implements Database.SetExplorerState
+func (mdb *MongoDatabase) SetExplorerState(state ExplorerState) error {
+	return mdb.setMeta(mongoMetaState, state)
+}
+
+// GetNetworkStats implements Database.GetNetworkStats
+func (mdb *MongoDatabase) GetNetworkStats() (NetworkStats, error) {
+	var stats NetworkStats
+	switch err := mdb.getMeta(mongoMetaStats, &stats); err {
+	case nil:
+		err = upgradeNetworkStats(&stats)
+		if err != nil {
+			return NetworkStats{}, fmt.Errorf("mongo: failed to upgrade network stats: %v", err)
+		}
+	case ErrNotFound:
+		// default to fresh network stats if not stored yet
+		stats = NewNetworkStats()
+	default:
+		return NetworkStats{}, err
+	}
+	mdb.networkTime, mdb.networkBlockHeight = stats.Timestamp, stats.BlockHeight
+	return stats, nil
+}
+
+// SetNetworkStats implements Database.SetNetworkStats
+func (mdb *MongoDatabase) SetNetworkStats(stats NetworkStats) error {
+	err := mdb.setMeta(mongoMetaStats, stats)
+	if err != nil {
+		return err
+	}
+	mdb.networkTime, mdb.networkBlockHeight = stats.Timestamp, stats.BlockHeight
+	return nil
+}
+
+// SetChainHealth implements Database.SetChainHealth
+func (mdb *MongoDatabase) SetChainHealth(health ChainHealth) error {
+	return mdb.setMeta(mongoMetaHealth, health)
+}
+
+// GetChainParametersHistory implements Database.GetChainParametersHistory
+func (mdb *MongoDatabase) GetChainParametersHistory() ([]ChainParametersRecord, error) {
+	var history []ChainParametersRecord
+	switch err := mdb.getMeta(mongoMetaParams, &history); err {
+	case nil, ErrNotFound:
+		// no history is stored yet for a fresh database
+		return history, nil
+	default:
+		return nil, err
+	}
+}
+
+// SetChainParametersHistory implements Database.SetChainParametersHistory
+func (mdb *MongoDatabase) SetChainParametersHistory(history []ChainParametersRecord) error {
+	return mdb.setMeta(mongoMetaParams, history)
+}
+
+// GetAddressAliases implements Database.GetAddressAliases
+func (mdb *MongoDatabase) GetAddressAliases() ([]AddressAlias, error) {
+	var aliases []AddressAlias
+	switch err := mdb.getMeta(mongoMetaAliases, &aliases); err {
+	case nil, ErrNotFound:
+		// no aliases are recorded yet
+		return aliases, nil
+	default:
+		return nil, err
+	}
+}
+
+// SetAddressAliases implements Database.SetAddressAliases
+func (mdb *MongoDatabase) SetAddressAliases(aliases []AddressAlias) error {
+	return mdb.setMeta(mongoMetaAliases, aliases)
+}
+
+// getCoinOutput gets a stored coin output, returning ErrNotFound if it isn't stored.
+func (mdb *MongoDatabase) getCoinOutput(id types.CoinOutputID) (co mongoCoinOutput, err error) {
+	switch err = mdb.db.C(mongoCollectionCoinOutputs).FindId(id.String()).One(&co); err {
+	case nil:
+		return co, nil
+	case mgo.ErrNotFound:
+		return mongoCoinOutput{}, ErrNotFound
+	default:
+		return mongoCoinOutput{}, err
+	}
+}
+
+// getWallet gets a stored wallet, returning ErrNotFound if it isn't stored.
+func (mdb *MongoDatabase) getWallet(address types.UnlockHash) (Wallet, error) {
+	var doc mongoWallet
+	switch err := mdb.db.C(mongoCollectionWallets).FindId(address.String()).One(&doc); err {
+	case nil:
+		wallet, err := doc.Wallet()
+		if err != nil {
+			return Wallet{}, err
+		}
+		wallet.Balance.Locked.updateHorizons(mdb.networkTime)
+		// the wallet is assembled from the document fields as stored, hence always in the latest structure
+		wallet.Version = WalletVersion
+		return wallet, nil
+	case mgo.ErrNotFound:
+		return Wallet{}, ErrNotFound
+	default:
+		return Wallet{}, err
+	}
+}
+
+// updateWallet updates the wallet of the given address using the given update function,
+// creating the wallet if it doesn't exist yet.
+func (mdb *MongoDatabase) updateWallet(address types.UnlockHash, update func(*Wallet) error) error {
+	wallet, err := mdb.getWallet(address)
+	if err != nil && err != ErrNotFound {
+		return fmt.Errorf("mongo: failed to get wallet for %s: %v", address.String(), err)
+	}
+	err = update(&wallet)
+	if err != nil {
+		return fmt.Errorf("mongo: failed to update wallet for %s: %v", address.String(), err)
+	}
+	_, err = mdb.db.C(mongoCollectionWallets).UpsertId(address.String(), newMongoWallet(address, wallet))
+	if err != nil {
+		return fmt.Errorf("mongo: failed to set wallet for %s: %v", address.String(), err)
+	}
+	return nil
+}
+
+// AddCoinOutput implements Database.AddCoinOutput
+func (mdb *MongoDatabase) AddCoinOutput(id types.CoinOutputID, co CoinOutput) error {
+	uh := co.Condition.UnlockHash()
+	_, err := mdb.db.C(mongoCollectionCoinOutputs).UpsertId(id.String(), mongoCoinOutput{
+		ID:           id.String(),
+		UnlockHash:   uh.String(),
+		Value:        mongoDecimal(co.Value),
+		State:        CoinOutputStateLiquid,
+		LockType:     LockTypeNone,
+		Description:  co.Description,
+		RawCondition: EncodeCondition(co.Condition),
+	})
+	if err != nil {
+		return fmt.Errorf("mongo: failed to add coin output %s: %v", id.String(), err)
+	}
+	return mdb.updateWallet(uh, func(wallet *Wallet) error {
+		wallet.Balance.Unlocked = wallet.Balance.Unlocked.Add(co.Value)
+		return nil
+	})
+}
+
+// AddLockedCoinOutput implements Database.AddLockedCoinOutput
+func (mdb *MongoDatabase) AddLockedCoinOutput(id types.CoinOutputID, co CoinOutput, lt LockType, lockValue LockValue) error {
+	uh := co.Condition.UnlockHash()
+	_, err := mdb.db.C(mongoCollectionCoinOutputs).UpsertId(id.String(), mongoCoinOutput{
+		ID:           id.String(),
+		UnlockHash:   uh.String(),
+		Value:        mongoDecimal(co.Value),
+		State:        CoinOutputStateLocked,
+		LockType:     lt,
+		LockValue:    uint64(lockValue),
+		Description:  co.Description,
+		RawCondition: EncodeCondition(co.Condition),
+	})
+	if err != nil {
+		return fmt.Errorf("mongo: failed to add coin output %s: %v", id.String(), err)
+	}
+	return mdb.updateWallet(uh, func(wallet *Wallet) error {
+		return wallet.Balance.Locked.AddLockedCoinOutput(id, WalletLockedOutput{
+			Amount:      co.Value,
+			LockedUntil: mdb.lockValueAsLockTime(lt, lockValue),
+			Description: co.Description,
+			Reason:      CoinOutputLockReason(lt, co.Description),
+		})
+	})
+}
+
+// SpendCoinOutput implements Database.SpendCoinOutput
+func (mdb *MongoDatabase) SpendCoinOutput(id types.CoinOutputID) (types.UnlockHash, types.Currency, error) {
+	uh, value, err := mdb.updateCoinOutputState(id, CoinOutputStateLiquid, CoinOutputStateSpent)
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf("mongo: failed to spend coin output: %v", err)
+	}
+	err = mdb.updateWallet(uh, func(wallet *Wallet) error {
+		wallet.Balance.Unlocked = wallet.Balance.Unlocked.Sub(value)
+		return nil
+	})
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, err
+	}
+	return uh, value, nil
+}
+
+// RevertCoinInput implements Database.RevertCoinInput
+// more or less a reverse process of SpendCoinOutput
+func (mdb *MongoDatabase) RevertCoinInput(id types.CoinOutputID) (types.UnlockHash, types.Currency, error) {
+	uh, value, err := mdb.updateCoinOutputState(id, CoinOutputStateSpent, CoinOutputStateLiquid)
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf("mongo: failed to revert coin input: %v", err)
+	}
+	err = mdb.updateWallet(uh, func(wallet *Wallet) error {
+		wallet.Balance.Unlocked = wallet.Balance.Unlocked.Add(value)
+		return nil
+	})
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, err
+	}
+	return uh, value, nil
+}
+
+// updateCoinOutputState updates the state of a coin output,
+// returning an error in case the coin output isn't in the expected state.
+func (mdb *MongoDatabase) updateCoinOutputState(id types.CoinOutputID, from, to CoinOutputState) (types.UnlockHash, types.Currency, error) {
+	co, err := mdb.getCoinOutput(id)
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf("cannot get coin output %s: %v", id.String(), err)
+	}
+	if co.State != from {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf(
+			"cannot update coin output %s: unexpected state %d", id.String(), co.State)
+	}
+	err = mdb.db.C(mongoCollectionCoinOutputs).UpdateId(id.String(), bson.M{"$set": bson.M{"state": to}})
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf("cannot update coin output %s: %v", id.String(), err)
+	}
+	var uh types.UnlockHash
+	err = uh.LoadString(co.UnlockHash)
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf("cannot decode owner of coin output %s: %v", id.String(), err)
+	}
+	value, err := mongoCurrency(co.Value)
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf("cannot decode value of coin output %s: %v", id.String(), err)
+	}
+	return uh, value, nil
+}
+
+// RevertCoinOutput implements Database.RevertCoinOutput
+func (mdb *MongoDatabase) RevertCoinOutput(id types.CoinOutputID) (CoinOutputState, error) {
+	info, err := mdb.GetCoinOutput(id)
+	if err != nil {
+		return CoinOutputStateNil, fmt.Errorf("mongo: failed to revert coin output: %v", err)
+	}
+	err = mdb.db.C(mongoCollectionCoinOutputs).RemoveId(id.String())
+	if err != nil {
+		return CoinOutputStateNil, fmt.Errorf(
+			"mongo: failed to revert coin output: cannot drop coin output %s: %v", id.String(), err)
+	}
+	switch info.State {
+	case CoinOutputStateLiquid:
+		err = mdb.updateWallet(info.UnlockHash, func(wallet *Wallet) error {
+			wallet.Balance.Unlocked = wallet.Balance.Unlocked.Sub(info.Value)
+			return nil
+		})
+	case CoinOutputStateLocked:
+		err = mdb.updateWallet(info.UnlockHash, func(wallet *Wallet) error {
+			return wallet.Balance.Locked.SubLockedCoinOutput(id)
+		})
+	}
+	if err != nil {
+		return CoinOutputStateNil, err
+	}
+	return info.State, nil
+}
+
+// ApplyCoinOutputLocks implements Database.ApplyCoinOutputLocks
+func (mdb *MongoDatabase) ApplyCoinOutputLocks(height types.BlockHeight, time types.Timestamp) (n uint64, coins types.Currency, err error) {
+	mdb.networkTime, mdb.networkBlockHeight = time, height
+	for _, lock := range []struct {
+		Type  LockType
+		Value LockValue
+	}{
+		{LockTypeHeight, LockValue(height)},
+		{LockTypeTime, LockValue(time)},
+	} {
+		var ids []types.CoinOutputID
+		ids, err = mdb.findCoinOutputIDs(bson.M{
+			"state":     CoinOutputStateLocked,
+			"lockType":  lock.Type,
+			"lockValue": bson.M{"$lte": uint64(lock.Value)},
+		})
+		if err != nil {
+			return 0, types.Currency{}, fmt.Errorf("mongo: failed to find coin outputs to unlock: %v", err)
+		}
+		// locked -> unlocked
+		for _, id := range ids {
+			uh, value, err := mdb.updateCoinOutputState(id, CoinOutputStateLocked, CoinOutputStateLiquid)
+			if err != nil {
+				return 0, types.Currency{}, fmt.Errorf("mongo: failed to unlock coin output: %v", err)
+			}
+			err = mdb.updateWallet(uh, func(wallet *Wallet) error {
+				err := wallet.Balance.Locked.SubLockedCoinOutput(id)
+				if err != nil {
+					return err
+				}
+				wallet.Balance.Unlocked = wallet.Balance.Unlocked.Add(value)
+				return nil
+			})
+			if err != nil {
+				return 0, types.Currency{}, err
+			}
+			coins = coins.Add(value)
+			n++
+		}
+	}
+	return n, coins, nil
+}
+
+// RevertCoinOutputLocks implements Database.RevertCoinOutputLocks
+func (mdb *MongoDatabase) RevertCoinOutputLocks(height types.BlockHeight, time types.Timestamp) (n uint64, coins types.Currency, err error) {
+	mdb.networkTime, mdb.networkBlockHeight = time, height
+	for _, lock := range []struct {
+		Type  LockType
+		Value LockValue
+	}{
+		{LockTypeHeight, LockValue(height)},
+		{LockTypeTime, LockValue(time)},
+	} {
+		var ids []types.CoinOutputID
+		ids, err = mdb.findCoinOutputIDs(bson.M{
+			"state":     CoinOutputStateLiquid,
+			"lockType":  lock.Type,
+			"lockValue": bson.M{"$gt": uint64(lock.Value)},
+		})
+		if err != nil {
+			return 0, types.Currency{}, fmt.Errorf("mongo: failed to find coin outputs to lock: %v", err)
+		}
+		// unlocked -> locked
+		for _, id := range ids {
+			info, err := mdb.GetCoinOutput(id)
+			if err != nil {
+				return 0, types.Currency{}, fmt.Errorf("mongo: failed to lock coin output: %v", err)
+			}
+			_, _, err = mdb.updateCoinOutputState(id, CoinOutputStateLiquid, CoinOutputStateLocked)
+			if err != nil {
+				return 0, types.Currency{}, fmt.Errorf("mongo: failed to lock coin output: %v", err)
+			}
+			err = mdb.updateWallet(info.UnlockHash, func(wallet *Wallet) error {
+				wallet.Balance.Unlocked = wallet.Balance.Unlocked.Sub(info.Value)
+				return wallet.Balance.Locked.AddLockedCoinOutput(id, WalletLockedOutput{
+					Amount:      info.Value,
+					LockedUntil: mdb.lockValueAsLockTime(info.LockType, info.LockValue),
+					Description: info.Description,
+					Reason:      CoinOutputLockReason(info.LockType, info.Description),
+				})
+			})
+			if err != nil {
+				return 0, types.Currency{}, err
+			}
+			coins = coins.Add(info.Value)
+			n++
+		}
+	}
+	return n, coins, nil
+}
+
+// findCoinOutputIDs returns the IDs of all coin outputs matching the given query.
+func (mdb *MongoDatabase) findCoinOutputIDs(query bson.M) ([]types.CoinOutputID, error) {
+	var ids []types.CoinOutputID
+	iter := mdb.db.C(mongoCollectionCoinOutputs).Find(query).Select(bson.M{"_id": 1}).Iter()
+	var doc struct {
+		ID string `bson:"_id"`
+	}
+	for iter.Next(&doc) {
+		var id types.CoinOutputID
+		err := id.LoadString(doc.ID)
+		if err != nil {
+			iter.Close()
+			return nil, fmt.Errorf("failed to load coin output ID %q: %v", doc.ID, err)
+		}
+		ids = append(ids, id)
+	}
+	err := iter.Close()
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// SetMultisigAddresses implements Database.SetMultisigAddresses
+func (mdb *MongoDatabase) SetMultisigAddresses(address types.UnlockHash, owners []types.UnlockHash, signaturesRequired uint64) error {
+	var known bool
+	err := mdb.updateWallet(address, func(wallet *Wallet) error {
+		if len(wallet.MultiSignData.Owners) > 0 {
+			known = true
+			return nil // nothing to do
+		}
+		wallet.MultiSignData.SignaturesRequired = signaturesRequired
+		wallet.MultiSignData.Owners = make([]types.UnlockHash, len(owners))
+		copy(wallet.MultiSignData.Owners[:], owners[:])
+		return nil
+	})
+	if err != nil || known {
+		return err
+	}
+	// link the multisig address to all its owners
+	for _, owner := range owners {
+		err = mdb.updateWallet(owner, func(wallet *Wallet) error {
+			for _, uh := range wallet.MultiSignAddresses {
+				if uh == address {
+					return nil // nothing to do
+				}
+			}
+			wallet.MultiSignAddresses = append(wallet.MultiSignAddresses, address)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyCounterpartyTransfer implements Database.ApplyCounterpartyTransfer
+func (mdb *MongoDatabase) ApplyCounterpartyTransfer(from, to types.UnlockHash, value types.Currency) error {
+	err := mdb.updateCounterparty(from, to, func(cp *AddressCounterparty) {
+		cp.TransactionCount++
+		cp.Sent = cp.Sent.Add(value)
+	})
+	if err != nil {
+		return err
+	}
+	return mdb.updateCounterparty(to, from, func(cp *AddressCounterparty) {
+		cp.TransactionCount++
+		cp.Received = cp.Received.Add(value)
+	})
+}
+
+// RevertCounterpartyTransfer implements Database.RevertCounterpartyTransfer
+func (mdb *MongoDatabase) RevertCounterpartyTransfer(from, to types.UnlockHash, value types.Currency) error {
+	err := mdb.updateCounterparty(from, to, func(cp *AddressCounterparty) {
+		cp.TransactionCount--
+		cp.Sent = subCurrencyOrZero(cp.Sent, value)
+	})
+	if err != nil {
+		return err
+	}
+	return mdb.updateCounterparty(to, from, func(cp *AddressCounterparty) {
+		cp.TransactionCount--
+		cp.Received = subCurrencyOrZero(cp.Received, value)
+	})
+}
+
+// updateCounterparty updates the counterparty of an address, using the given update function.
+func (mdb *MongoDatabase) updateCounterparty(address, counterparty types.UnlockHash, update func(*AddressCounterparty)) error {
+	id := address.String() + ":" + counterparty.String()
+	collection := mdb.db.C(mongoCollectionCounterparties)
+
+	var doc mongoCounterparty
+	var cp AddressCounterparty
+	switch err := collection.FindId(id).One(&doc); err {
+	case nil:
+		cp.TransactionCount = doc.TransactionCount
+		if cp.Sent, err = mongoCurrency(doc.Sent); err == nil {
+			cp.Received, err = mongoCurrency(doc.Received)
+		}
+		if err != nil {
+			return fmt.Errorf("mongo: failed to decode counterparty %s of %s: %v",
+				counterparty.String(), address.String(), err)
+		}
+	case mgo.ErrNotFound:
+	default:
+		return fmt.Errorf("mongo: failed to get counterparty %s of %s: %v",
+			counterparty.String(), address.String(), err)
+	}
+	update(&cp)
+	var err error
+	if cp.TransactionCount == 0 {
+		err = collection.RemoveId(id)
+		if err == mgo.ErrNotFound {
+			err = nil
+		}
+	} else {
+		_, err = collection.UpsertId(id, mongoCounterparty{
+			ID:               id,
+			Address:          address.String(),
+			Counterparty:     counterparty.String(),
+			TransactionCount: cp.TransactionCount,
+			Sent:             mongoDecimal(cp.Sent),
+			Received:         mongoDecimal(cp.Received),
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("mongo: failed to update counterparty %s of %s: %v",
+			counterparty.String(), address.String(), err)
+	}
+	return nil
+}
+
+// ApplyWalletGroupFlows implements Database.ApplyWalletGroupFlows
+func (mdb *MongoDatabase) ApplyWalletGroupFlows(timestamp types.Timestamp, flows WalletGroupFlows) error {
+	return mdb.updateWalletGroupFlows(timestamp, func(current WalletGroupFlows) WalletGroupFlows {
+		return current.Add(flows)
+	})
+}
+
+// RevertWalletGroupFlows implements Database.RevertWalletGroupFlows
+func (mdb *MongoDatabase) RevertWalletGroupFlows(timestamp types.Timestamp, flows WalletGroupFlows) error {
+	return mdb.updateWalletGroupFlows(timestamp, func(current WalletGroupFlows) WalletGroupFlows {
+		return current.Sub(flows)
+	})
+}
+
+// updateWalletGroupFlows updates both the total and the daily wallet group flows,
+// removing the daily flows once they're no longer used.
+func (mdb *MongoDatabase) updateWalletGroupFlows(timestamp types.Timestamp, update func(WalletGroupFlows) WalletGroupFlows) error {
+	collection := mdb.db.C(mongoCollectionFlows)
+	for _, day := range []string{flowsFieldTotal, walletGroupFlowsDay(timestamp)} {
+		var doc mongoWalletGroupFlows
+		var flows WalletGroupFlows
+		switch err := collection.FindId(day).One(&doc); err {
+		case nil:
+			flows, err = doc.WalletGroupFlows()
+			if err != nil {
+				return fmt.Errorf("mongo: failed to decode wallet group flows of %s: %v", day, err)
+			}
+		case mgo.ErrNotFound:
+		default:
+			return fmt.Errorf("mongo: failed to get wallet group flows of %s: %v", day, err)
+		}
+		flows = update(flows)
+		var err error
+		if flows.IsZero() && day != flowsFieldTotal {
+			err = collection.RemoveId(day)
+			if err == mgo.ErrNotFound {
+				err = nil
+			}
+		} else {
+			_, err = collection.UpsertId(day, newMongoWalletGroupFlows(day, flows))
+		}
+		if err != nil {
+			return fmt.Errorf("mongo: failed to update wallet group flows of %s: %v", day, err)
+		}
+	}
+	return nil
+}
+
+// GetWalletGroupFlows implements Database.GetWalletGroupFlows
+func (mdb *MongoDatabase) GetWalletGroupFlows() (total WalletGroupFlows, daily map[string]WalletGroupFlows, err error) {
+	var docs []mongoWalletGroupFlows
+	err = mdb.db.C(mongoCollectionFlows).Find(nil).All(&docs)
+	if err != nil {
+		return WalletGroupFlows{}, nil, fmt.Errorf("mongo: failed to get wallet group flows: %v", err)
+	}
+	daily = make(map[string]WalletGroupFlows, len(docs))
+	for _, doc := range docs {
+		flows, err := doc.WalletGroupFlows()
+		if err != nil {
+			return WalletGroupFlows{}, nil, fmt.Errorf("mongo: failed to decode wallet group flows of %s: %v", doc.Day, err)
+		}
+		if doc.Day == flowsFieldTotal {
+			total = flows
+		} else {
+			daily[doc.Day] = flows
+		}
+	}
+	return total, daily, nil
+}
+
+// ApplyMultisigSpend implements Database.ApplyMultisigSpend
+func (mdb *MongoDatabase) ApplyMultisigSpend(spend MultisigSpend) error {
+	doc := mongoMultisigSpend{
+		ID:      spend.CoinOutputID.String(),
+		Address: spend.Address.String(),
+	}
+	for _, signer := range spend.Signers {
+		doc.Signers = append(doc.Signers, signer.String())
+	}
+	_, err := mdb.db.C(mongoCollectionMultisigSpends).UpsertId(doc.ID, doc)
+	if err != nil {
+		return fmt.Errorf("mongo: failed to store signers of multisig spend %s: %v", doc.ID, err)
+	}
+	for _, signer := range spend.Signers {
+		err = mdb.updateMultisigSignerStats(spend.Address, signer, func(stats *MultisigSignerStats) {
+			stats.SpendCount++
+			stats.Value = stats.Value.Add(spend.Value)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevertMultisigSpend implements Database.RevertMultisigSpend
+func (mdb *MongoDatabase) RevertMultisigSpend(spend MultisigSpend) error {
+	err := mdb.db.C(mongoCollectionMultisigSpends).RemoveId(spend.CoinOutputID.String())
+	if err != nil && err != mgo.ErrNotFound {
+		return fmt.Errorf("mongo: failed to remove signers of multisig spend %s: %v", spend.CoinOutputID.String(), err)
+	}
+	for _, signer := range spend.Signers {
+		err = mdb.updateMultisigSignerStats(spend.Address, signer, func(stats *MultisigSignerStats) {
+			stats.SpendCount--
+			stats.Value = subCurrencyOrZero(stats.Value, spend.Value)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateMultisigSignerStats updates the signing stats of an owner of a multisig wallet,
+// using the given update function, removing the stats once the owner no longer signed any spend.
+func (mdb *MongoDatabase) updateMultisigSignerStats(address, signer types.UnlockHash, update func(*MultisigSignerStats)) error {
+	id := address.String() + ":" + signer.String()
+	collection := mdb.db.C(mongoCollectionMultisigSigners)
+
+	var doc mongoMultisigSigner
+	var stats MultisigSignerStats
+	switch err := collection.FindId(id).One(&doc); err {
+	case nil:
+		stats, err = doc.MultisigSignerStats()
+		if err != nil {
+			return fmt.Errorf("mongo: failed to decode signing stats of %s for %s: %v",
+				signer.String(), address.String(), err)
+		}
+	case mgo.ErrNotFound:
+	default:
+		return fmt.Errorf("mongo: failed to get signing stats of %s for %s: %v",
+			signer.String(), address.String(), err)
+	}
+	update(&stats)
+	var err error
+	if stats.SpendCount == 0 {
+		err = collection.RemoveId(id)
+		if err == mgo.ErrNotFound {
+			err = nil
+		}
+	} else {
+		_, err = collection.UpsertId(id, mongoMultisigSigner{
+			ID:         id,
+			Address:    address.String(),
+			Signer:     signer.String(),
+			SpendCount: stats.SpendCount,
+			Value:      mongoDecimal(stats.Value),
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("mongo: failed to update signing stats of %s for %s: %v",
+			signer.String(), address.String(), err)
+	}
+	return nil
+}
+
+// GetMultisigSpends implements Database.GetMultisigSpends
+func (mdb *MongoDatabase) GetMultisigSpends(address types.UnlockHash) (map[types.CoinOutputID][]types.UnlockHash, error) {
+	var docs []mongoMultisigSpend
+	err := mdb.db.C(mongoCollectionMultisigSpends).Find(bson.M{"address": address.String()}).All(&docs)
+	if err != nil {
+		return nil, fmt.Errorf("mongo: failed to get multisig spends of %s: %v", address.String(), err)
+	}
+	spends := make(map[types.CoinOutputID][]types.UnlockHash, len(docs))
+	for _, doc := range docs {
+		var id types.CoinOutputID
+		err = id.LoadString(doc.ID)
+		if err == nil {
+			spends[id], err = mongoUnlockHashes(doc.Signers)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("mongo: failed to decode multisig spend %s of %s: %v", doc.ID, address.String(), err)
+		}
+	}
+	return spends, nil
+}
+
+// GetMultisigSignerStats implements Database.GetMultisigSignerStats
+func (mdb *MongoDatabase) GetMultisigSignerStats(address types.UnlockHash) (map[types.UnlockHash]MultisigSignerStats, error) {
+	var docs []mongoMultisigSigner
+	err := mdb.db.C(mongoCollectionMultisigSigners).Find(bson.M{"address": address.String()}).All(&docs)
+	if err != nil {
+		return nil, fmt.Errorf("mongo: failed to get multisig signing stats of %s: %v", address.String(), err)
+	}
+	signers := make(map[types.UnlockHash]MultisigSignerStats, len(docs))
+	for _, doc := range docs {
+		var signer types.UnlockHash
+		err = signer.LoadString(doc.Signer)
+		if err == nil {
+			signers[signer], err = doc.MultisigSignerStats()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("mongo: failed to decode signing stats of %s for %s: %v", doc.Signer, address.String(), err)
+		}
+	}
+	return signers, nil
+}
+
+// SetBlockSummary implements Database.SetBlockSummary
+func (mdb *MongoDatabase) SetBlockSummary(summary BlockSummary) error {
+	_, err := mdb.db.C(mongoCollectionBlockSummaries).UpsertId(uint64(summary.Height), mongoBlockSummary{
+		Height:      uint64(summary.Height),
+		ID:          summary.ID.String(),
+		Timestamp:   uint64(summary.Timestamp),
+		OutputCount: summary.OutputCount,
+		OutputValue: mongoDecimal(summary.OutputValue),
+		Histogram:   summary.Histogram[:],
+
+		TransactionCount: summary.TransactionCount,
+		ValueTransferred: mongoDecimal(summary.ValueTransferred),
+		Fees:             mongoDecimal(summary.Fees),
+		MinerPayouts:     mongoDecimal(summary.MinerPayouts),
+		Creator:          summary.Creator.String(),
+		Difficulty:       summary.Difficulty.String(),
+	})
+	if err != nil {
+		return fmt.Errorf("mongo: failed to set summary of block %d: %v", summary.Height, err)
+	}
+	return nil
+}
+
+// RevertBlockSummary implements Database.RevertBlockSummary
+func (mdb *MongoDatabase) RevertBlockSummary(height types.BlockHeight) error {
+	err := mdb.db.C(mongoCollectionBlockSummaries).RemoveId(uint64(height))
+	if err != nil && err != mgo.ErrNotFound {
+		return fmt.Errorf("mongo: failed to revert summary of block %d: %v", height, err)
+	}
+	return nil
+}
+
+// GetBlockSummary implements Database.GetBlockSummary
+func (mdb *MongoDatabase) GetBlockSummary(height types.BlockHeight) (BlockSummary, error) {
+	var doc mongoBlockSummary
+	switch err := mdb.db.C(mongoCollectionBlockSummaries).FindId(uint64(height)).One(&doc); err {
+	case nil:
+	case mgo.ErrNotFound:
+		return BlockSummary{}, ErrNotFound
+	default:
+		return BlockSummary{}, fmt.Errorf("mongo: failed to get summary of block %d: %v", height, err)
+	}
+	summary := BlockSummary{
+		Height:           height,
+		Timestamp:        types.Timestamp(doc.Timestamp),
+		OutputCount:      doc.OutputCount,
+		TransactionCount: doc.TransactionCount,
+	}
+	copy(summary.Histogram[:], doc.Histogram)
+	err := (*crypto.Hash)(&summary.ID).LoadString(doc.ID)
+	if err == nil {
+		summary.OutputValue, err = mongoCurrency(doc.OutputValue)
+	}
+	if err == nil {
+		summary.ValueTransferred, err = mongoCurrency(doc.ValueTransferred)
+	}
+	if err == nil {
+		summary.Fees, err = mongoCurrency(doc.Fees)
+	}
+	if err == nil {
+		summary.MinerPayouts, err = mongoCurrency(doc.MinerPayouts)
+	}
+	if err == nil {
+		err = summary.Creator.LoadString(doc.Creator)
+	}
+	if err == nil && doc.Difficulty != "" {
+		err = difficultyLoader{&summary.Difficulty}.LoadString(doc.Difficulty)
+	}
+	if err != nil {
+		return BlockSummary{}, fmt.Errorf("mongo: failed to decode summary of block %d: %v", height, err)
+	}
+	return summary, nil
+}
+
+// GetWallet implements Database.GetWallet
+func (mdb *MongoDatabase) GetWallet(address types.UnlockHash) (Wallet, error) {
+	wallet, err := mdb.getWallet(address)
+	switch err {
+	case nil:
+		return wallet, nil
+	case ErrNotFound:
+		return Wallet{}, ErrNotFound
+	default:
+		return Wallet{}, fmt.Errorf("mongo: failed to get wallet for %s: %v", address.String(), err)
+	}
+}
+
+// SampleAddresses implements Database.SampleAddresses
+func (mdb *MongoDatabase) SampleAddresses(n int) ([]types.UnlockHash, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	var docs []struct {
+		Address string `bson:"_id"`
+	}
+	err := mdb.db.C(mongoCollectionWallets).Pipe([]bson.M{
+		{"$sample": bson.M{"size": n}},
+		{"$project": bson.M{"_id": 1}},
+	}).All(&docs)
+	if err != nil {
+		return nil, fmt.Errorf("mongo: failed to sample addresses: %v", err)
+	}
+	addresses := make([]types.UnlockHash, len(docs))
+	for i, doc := range docs {
+		err = addresses[i].LoadString(doc.Address)
+		if err != nil {
+			return nil, fmt.Errorf("mongo: failed to load sampled address %q: %v", doc.Address, err)
+		}
+	}
+	return addresses, nil
+}
+
+// GetCoinOutput implements Database.GetCoinOutput
+func (mdb *MongoDatabase) GetCoinOutput(id types.CoinOutputID) (CoinOutputInfo, error) {
+	co, err := mdb.getCoinOutput(id)
+	switch err {
+	case nil:
+	case ErrNotFound:
+		return CoinOutputInfo{}, ErrNotFound
+	default:
+		return CoinOutputInfo{}, fmt.Errorf("mongo: failed to get coin output %s: %v", id.String(), err)
+	}
+	info := CoinOutputInfo{
+		ID:           id,
+		State:        co.State,
+		LockType:     co.LockType,
+		LockValue:    LockValue(co.LockValue),
+		Description:  co.Description,
+		RawCondition: co.RawCondition,
+	}
+	err = info.UnlockHash.LoadString(co.UnlockHash)
+	if err == nil {
+		info.Value, err = mongoCurrency(co.Value)
+	}
+	if err == nil {
+		err = info.decodeCondition()
+	}
+	if err != nil {
+		return CoinOutputInfo{}, fmt.Errorf("mongo: failed to decode coin output %s: %v", id.String(), err)
+	}
+	return info, nil
+}
+
+func (mdb *MongoDatabase) lockValueAsLockTime(lt LockType, value LockValue) LockValue {
+	switch lt {
+	case LockTypeTime:
+		return value
+	case LockTypeHeight:
+		return LockValue(mdb.networkTime) + (value-LockValue(mdb.networkBlockHeight))*mdb.blockFrequency
+	default:
+		panic(fmt.Sprintf("invalid lock type %d", lt))
+	}
+}
+
+// newMongoWallet creates the document used to store the given wallet.
+func newMongoWallet(address types.UnlockHash, wallet Wallet) mongoWallet {
+	doc := mongoWallet{
+		Address:            address.String(),
+		Unlocked:           mongoDecimal(wallet.Balance.Unlocked),
+		Locked:             mongoDecimal(wallet.Balance.Locked.Total),
+		SignaturesRequired: wallet.MultiSignData.SignaturesRequired,
+	}
+	for id, output := range wallet.Balance.Locked.Outputs {
+		doc.LockedOutputs = append(doc.LockedOutputs, mongoWalletLockedOutput{
+			ID:          id.String(),
+			Amount:      mongoDecimal(output.Amount),
+			LockedUntil: uint64(output.LockedUntil),
+			Description: output.Description,
+			Reason:      string(output.Reason),
+		})
+	}
+	for _, uh := range wallet.MultiSignAddresses {
+		doc.MultiSignAddresses = append(doc.MultiSignAddresses, uh.String())
+	}
+	for _, uh := range wallet.MultiSignData.Owners {
+		doc.MultiSignOwners = append(doc.MultiSignOwners, uh.String())
+	}
+	return doc
+}
+
+// Wallet decodes the stored wallet.
+func (doc mongoWallet) Wallet() (wallet Wallet, err error) {
+	wallet.Balance.Unlocked, err = mongoCurrency(doc.Unlocked)
+	if err != nil {
+		return Wallet{}, err
+	}
+	wallet.Balance.Locked.Total, err = mongoCurrency(doc.Locked)
+	if err != nil {
+		return Wallet{}, err
+	}
+	if len(doc.LockedOutputs) > 0 {
+		wallet.Balance.Locked.Outputs = make(WalletLockedOutputMap, len(doc.LockedOutputs))
+		for _, output := range doc.LockedOutputs {
+			var id types.CoinOutputID
+			err = id.LoadString(output.ID)
+			if err != nil {
+				return Wallet{}, err
+			}
+			amount, err := mongoCurrency(output.Amount)
+			if err != nil {
+				return Wallet{}, err
+			}
+			wallet.Balance.Locked.Outputs[id] = WalletLockedOutput{
+				Amount:      amount,
+				LockedUntil: LockValue(output.LockedUntil),
+				Description: output.Description,
+				Reason:      LockReason(output.Reason),
+			}
+		}
+	}
+	wallet.MultiSignAddresses, err = mongoUnlockHashes(doc.MultiSignAddresses)
+	if err != nil {
+		return Wallet{}, err
+	}
+	wallet.MultiSignData.Owners, err = mongoUnlockHashes(doc.MultiSignOwners)
+	if err != nil {
+		return Wallet{}, err
+	}
+	wallet.MultiSignData.SignaturesRequired = doc.SignaturesRequired
+	return wallet, nil
+}
+
+// newMongoWalletGroupFlows creates the document used to store the given (total or daily) wallet group flows.
+func newMongoWalletGroupFlows(day string, flows WalletGroupFlows) mongoWalletGroupFlows {
+	return mongoWalletGroupFlows{
+		Day:         day,
+		SweepCount:  flows.Sweeps.TransactionCount,
+		SweepValue:  mongoDecimal(flows.Sweeps.Value),
+		RefillCount: flows.Refills.TransactionCount,
+		RefillValue: mongoDecimal(flows.Refills.Value),
+	}
+}
+
+// MultisigSignerStats decodes the stored signing stats.
+func (doc mongoMultisigSigner) MultisigSignerStats() (stats MultisigSignerStats, err error) {
+	stats.SpendCount = doc.SpendCount
+	stats.Value, err = mongoCurrency(doc.Value)
+	return
+}
+
+// WalletGroupFlows decodes the stored wallet group flows.
+func (doc mongoWalletGroupFlows) WalletGroupFlows() (flows WalletGroupFlows, err error) {
+	flows.Sweeps.TransactionCount = doc.SweepCount
+	flows.Sweeps.Value, err = mongoCurrency(doc.SweepValue)
+	if err != nil {
+		return WalletGroupFlows{}, err
+	}
+	flows.Refills.TransactionCount = doc.RefillCount
+	flows.Refills.Value, err = mongoCurrency(doc.RefillValue)
+	if err != nil {
+		return WalletGroupFlows{}, err
+	}
+	return flows, nil
+}
+
+// mongoDecimal converts a currency to a Decimal128 value, as stored by the MongoDatabase.
+func mongoDecimal(c types.Currency) bson.Decimal128 {
+	d, err := bson.ParseDecimal128(c.String())
+	if err != nil {
+		// only happens for currencies exceeding the 34 digits of precision offered by Decimal128
+		panic(fmt.Sprintf("failed to convert currency %s to a decimal: %v", c.String(), err))
+	}
+	return d
+}
+
+// mongoCurrency converts a Decimal128 value, as stored by the MongoDatabase, to a currency.
+func mongoCurrency(d bson.Decimal128) (c types.Currency, err error) {
+	err = c.LoadString(d.String())
+	return
+}
+
+// mongoUnlockHashes decodes the given (string-encoded) addresses.
+func mongoUnlockHashes(strs []string) ([]types.UnlockHash, error) {
+	if len(strs) == 0 {
+		return nil, nil
+	}
+	uhs := make([]types.UnlockHash, len(strs))
+	for i, str := range strs {
+		err := uhs[i].LoadString(str)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return uhs, nil
+}
@@ -0,0 +1,37 @@
+package rexplorer
+
+import (
+	"github.com/rivine/rivine/types"
+)
+
+type (
+	// MultisigSpend defines the spending of a coin output owned by a multisig wallet,
+	// as well as the owners that signed (and thus authorized) that spend.
+	MultisigSpend struct {
+		Address      types.UnlockHash
+		CoinOutputID types.CoinOutputID
+		Value        types.Currency
+		Signers      []types.UnlockHash
+	}
+
+	// MultisigSignerStats defines the signing activity of a single owner of a multisig wallet,
+	// counting the spent coin outputs of that wallet the owner signed, as well as their total value.
+	MultisigSignerStats struct {
+		SpendCount uint64         `json:"spendCount"`
+		Value      types.Currency `json:"value"`
+	}
+)
+
+// getMultisigSigners returns the (deduplicated) addresses of the owners that signed the given fulfillment,
+// nil if the fulfillment isn't a multisig fulfillment.
+func getMultisigSigners(fulfillment types.UnlockFulfillmentProxy) []types.UnlockHash {
+	msf, ok := fulfillment.Fulfillment.(*types.MultiSignatureFulfillment)
+	if !ok {
+		return nil
+	}
+	signers := make([]types.UnlockHash, 0, len(msf.Pairs))
+	for _, pair := range msf.Pairs {
+		signers = append(signers, types.NewPubKeyUnlockHash(pair.PublicKey))
+	}
+	return dedupOwnerAddresses(signers)
+}
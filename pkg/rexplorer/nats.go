@@ -0,0 +1,404 @@
+package rexplorer
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultNATSSubjectPrefix is the prefix of the subjects a NATSSink publishes to, unless configured otherwise.
+const DefaultNATSSubjectPrefix = "rexplorer"
+
+// NATSSubjectAddress is the placeholder of a subject template, replaced by the address of the published event.
+const NATSSubjectAddress = "{address}"
+
+// The headers of the messages published by a NATSSink, if supported by the server.
+const (
+	// NATSHeaderChangeID is the (hex-encoded) ID of the consensus change the event of a message is part of.
+	NATSHeaderChangeID = "Rexplorer-Change-Id"
+	// NATSHeaderMessageID is the unique ID of a message, being the consensus change ID and the index of the event
+	// within that consensus change, such that JetStream streams deduplicate the messages published more than once.
+	NATSHeaderMessageID = "Nats-Msg-Id"
+)
+
+// DefaultNATSSubjects returns the default subject template of every event published by a NATSSink,
+// relative to its subject prefix. The NATSSubjectAddress placeholder is replaced by the address of the event.
+func DefaultNATSSubjects() map[EventType]string {
+	return map[EventType]string{
+		EventTypeBlockApplied:        "block.applied",
+		EventTypeBlockReverted:       "block.reverted",
+		EventTypeTransactionApplied:  "tx.applied",
+		EventTypeTransactionReverted: "tx.reverted",
+		EventTypeOutputCreated:       "output.created." + NATSSubjectAddress,
+		EventTypeOutputSpent:         "output.spent." + NATSSubjectAddress,
+		EventTypeBalanceDelta:        "balance." + NATSSubjectAddress,
+	}
+}
+
+// NATSSubjectEvents returns all events a NATSSink can publish, sorted.
+func NATSSubjectEvents() []EventType {
+	subjects := DefaultNATSSubjects()
+	events := make([]EventType, 0, len(subjects))
+	for typ := range subjects {
+		events = append(events, typ)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i] < events[j] })
+	return events
+}
+
+// ParseNATSSubject parses the subject template of an event from its "<event>=<subject>" string format,
+// the subject being empty if the event isn't published.
+func ParseNATSSubject(str string) (EventType, string, error) {
+	parts := strings.SplitN(str, "=", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("invalid NATS subject %q: expected format <event>=<subject>", str)
+	}
+	typ, subject := EventType(strings.TrimSpace(parts[0])), strings.TrimSpace(parts[1])
+	if _, ok := DefaultNATSSubjects()[typ]; !ok {
+		return "", "", fmt.Errorf("invalid NATS subject %q: unknown event %q", str, typ)
+	}
+	if strings.ContainsAny(subject, " \t\r\n*>") {
+		return "", "", fmt.Errorf("invalid NATS subject %q: subject cannot contain whitespace or wildcards", str)
+	}
+	return typ, subject, nil
+}
+
+// NATSConfig configures a NATSSink.
+type NATSConfig struct {
+	// the URL of the NATS server, in the nats://[user:password@]host:port format,
+	// a user without password being sent as (authentication) token
+	URL string
+	// the prefix of all subjects, DefaultNATSSubjectPrefix if empty
+	SubjectPrefix string
+	// the subject templates overwriting the DefaultNATSSubjects, an empty template disabling the event
+	Subjects map[EventType]string
+	// the name the client identifies itself with, "rexplorer" if empty
+	Name string
+}
+
+// NATSSink is a ChangeSink publishing the events of every consensus change to NATS subjects,
+// implemented using the NATS client protocol directly.
+//
+// Every event is published as a message of which the payload is the JSON-encoded Event,
+// to the subject defined by the configured subject prefix and the subject template of its event (see DefaultNATSSubjects),
+// such that subscribers can use wildcards to subscribe to e.g. all block events (rexplorer.block.*),
+// or all events of a single address (rexplorer.output.*.<address> and rexplorer.balance.<address>).
+// If supported by the server, the messages have a NATSHeaderChangeID and NATSHeaderMessageID header.
+//
+// A consensus change is only considered delivered once the server acknowledged receiving all its messages
+// (by responding to a PING sent after publishing them). As NATS doesn't store messages,
+// subscribers only receive the messages published while they're subscribed, unless the subjects are captured
+// by a JetStream stream, which deduplicates the messages published more than once using their NATSHeaderMessageID header.
+type NATSSink struct {
+	cfg      NATSConfig
+	subjects map[EventType]string
+
+	conn   net.Conn
+	reader *bufio.Reader
+	// whether or not the server supports headers, and the maximum payload it accepts
+	headers    bool
+	maxPayload int
+
+	mut sync.Mutex
+}
+
+var (
+	_ ChangeSink = (*NATSSink)(nil)
+)
+
+const (
+	natsDefaultName    = "rexplorer"
+	natsDefaultPort    = "4222"
+	natsDialTimeout    = 10 * time.Second
+	natsRequestTimeout = 30 * time.Second
+	// the maximum length of a single protocol line (other than a message payload) accepted from the server
+	natsMaxLineLength = 64 << 10
+)
+
+// natsMessage is a single message to publish, the event with the given index within its consensus change.
+type natsMessage struct {
+	Subject string
+	Payload []byte
+	Index   int
+}
+
+// natsServerInfo is the (relevant part of the) INFO the server sends once connected.
+type natsServerInfo struct {
+	Headers     bool `json:"headers"`
+	MaxPayload  int  `json:"max_payload"`
+	TLSRequired bool `json:"tls_required"`
+}
+
+// natsConnect is the CONNECT message the client sends once connected.
+type natsConnect struct {
+	Verbose   bool   `json:"verbose"`
+	Pedantic  bool   `json:"pedantic"`
+	Name      string `json:"name"`
+	Lang      string `json:"lang"`
+	Version   string `json:"version"`
+	Protocol  int    `json:"protocol"`
+	Headers   bool   `json:"headers"`
+	NoEcho    bool   `json:"no_echo"`
+	User      string `json:"user,omitempty"`
+	Password  string `json:"pass,omitempty"`
+	AuthToken string `json:"auth_token,omitempty"`
+}
+
+// NewNATSSink creates a NATSSink, connecting to the NATS server, as to fail early should it not be reachable.
+func NewNATSSink(cfg NATSConfig) (*NATSSink, error) {
+	if cfg.SubjectPrefix == "" {
+		cfg.SubjectPrefix = DefaultNATSSubjectPrefix
+	}
+	if cfg.Name == "" {
+		cfg.Name = natsDefaultName
+	}
+	subjects := DefaultNATSSubjects()
+	for typ, subject := range cfg.Subjects {
+		if subject == "" {
+			delete(subjects, typ)
+			continue
+		}
+		subjects[typ] = subject
+	}
+	sink := &NATSSink{cfg: cfg, subjects: subjects}
+	err := sink.connect()
+	if err != nil {
+		return nil, err
+	}
+	return sink, nil
+}
+
+// DeliverChange implements ChangeSink.DeliverChange,
+// returning once the server acknowledged receiving all messages of the given consensus change.
+func (sink *NATSSink) DeliverChange(change ChangeEvents) error {
+	sink.mut.Lock()
+	defer sink.mut.Unlock()
+
+	var msgs []natsMessage
+	for i, event := range change.Events {
+		subject := sink.subject(event)
+		if subject == "" {
+			continue
+		}
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to JSON-encode %s event: %v", event.Type, err)
+		}
+		msgs = append(msgs, natsMessage{Subject: subject, Payload: payload, Index: i})
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	changeID := hex.EncodeToString(change.ID[:])
+	return retryDelivery("NATS", func() error {
+		if sink.conn == nil {
+			err := sink.connect()
+			if err != nil {
+				return err
+			}
+		}
+		// encoded once connected, as the server might no longer support headers after reconnecting
+		var buf []byte
+		for _, msg := range msgs {
+			if sink.maxPayload > 0 && len(msg.Payload) > sink.maxPayload {
+				return fmt.Errorf("%s event of %d bytes exceeds the maximum NATS payload (%d bytes)",
+					change.Events[msg.Index].Type, len(msg.Payload), sink.maxPayload)
+			}
+			buf = sink.appendMessage(buf, msg, changeID)
+		}
+		err := sink.publish(buf)
+		if err != nil {
+			sink.disconnect()
+		}
+		return err
+	}, sink.disconnect)
+}
+
+// subject returns the subject the given event is published to, empty if it isn't published.
+func (sink *NATSSink) subject(event Event) string {
+	template, ok := sink.subjects[event.Type]
+	if !ok {
+		return ""
+	}
+	if strings.Contains(template, NATSSubjectAddress) {
+		var address string
+		switch data := event.Data.(type) {
+		case OutputCreatedEventData:
+			address = data.Address.String()
+		case OutputSpentEventData:
+			address = data.Address.String()
+		case BalanceDeltaEventData:
+			address = data.Address.String()
+		case BalanceEventData:
+			address = data.Address.String()
+		default:
+			// events without address cannot be published to subjects requiring one
+			return ""
+		}
+		template = strings.Replace(template, NATSSubjectAddress, address, -1)
+	}
+	return sink.cfg.SubjectPrefix + "." + template
+}
+
+// appendMessage appends the PUB (or HPUB, if the server supports headers) protocol message
+// of the given message, part of the consensus change with the given ID, to the given buffer.
+func (sink *NATSSink) appendMessage(buf []byte, msg natsMessage, changeID string) []byte {
+	if !sink.headers {
+		buf = append(buf, "PUB "+msg.Subject+" "+strconv.Itoa(len(msg.Payload))+"\r\n"...)
+		buf = append(buf, msg.Payload...)
+		return append(buf, "\r\n"...)
+	}
+	headers := "NATS/1.0\r\n" +
+		NATSHeaderChangeID + ": " + changeID + "\r\n" +
+		NATSHeaderMessageID + ": " + changeID + "-" + strconv.Itoa(msg.Index) + "\r\n\r\n"
+	buf = append(buf, "HPUB "+msg.Subject+" "+strconv.Itoa(len(headers))+" "+strconv.Itoa(len(headers)+len(msg.Payload))+"\r\n"...)
+	buf = append(buf, headers...)
+	buf = append(buf, msg.Payload...)
+	return append(buf, "\r\n"...)
+}
+
+// publish writes the given messages, followed by a PING, and waits for the PONG of the server,
+// which it only sends once it processed all messages.
+func (sink *NATSSink) publish(msgs []byte) error {
+	sink.conn.SetDeadline(time.Now().Add(natsRequestTimeout))
+	_, err := sink.conn.Write(append(msgs, "PING\r\n"...))
+	if err != nil {
+		return fmt.Errorf("failed to publish NATS messages: %v", err)
+	}
+	return sink.awaitPong()
+}
+
+// awaitPong reads the lines sent by the server until it receives a PONG,
+// responding to the PINGs of the server in the meantime.
+func (sink *NATSSink) awaitPong() error {
+	for {
+		line, err := sink.readLine()
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			_, err = sink.conn.Write([]byte("PONG\r\n"))
+			if err != nil {
+				return fmt.Errorf("failed to respond to NATS PING: %v", err)
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("NATS server error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+		// +OK and (updated) INFO messages are ignored
+	}
+}
+
+func (sink *NATSSink) readLine() (string, error) {
+	line, err := sink.reader.ReadSlice('\n')
+	if err != nil {
+		if err == bufio.ErrBufferFull {
+			return "", errors.New("NATS protocol line too long")
+		}
+		return "", fmt.Errorf("failed to read from NATS server: %v", err)
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+// connect to the server, reading its INFO and sending the CONNECT message,
+// the connection being established once the server responded to the PING following it.
+func (sink *NATSSink) connect() error {
+	u, err := url.Parse(sink.cfg.URL)
+	if err != nil || u.Host == "" {
+		// allow the scheme to be omitted
+		u, err = url.Parse("nats://" + sink.cfg.URL)
+		if err != nil {
+			return fmt.Errorf("invalid NATS URL %q: %v", sink.cfg.URL, err)
+		}
+	}
+	if u.Scheme != "nats" {
+		return fmt.Errorf("invalid NATS URL %q: unsupported scheme %q", sink.cfg.URL, u.Scheme)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), natsDefaultPort)
+	}
+	conn, err := net.DialTimeout("tcp", addr, natsDialTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to NATS server %s: %v", addr, err)
+	}
+	sink.conn, sink.reader = conn, bufio.NewReaderSize(conn, natsMaxLineLength)
+	err = sink.handshake(u)
+	if err != nil {
+		sink.disconnect()
+		return err
+	}
+	return nil
+}
+
+func (sink *NATSSink) handshake(u *url.URL) error {
+	sink.conn.SetDeadline(time.Now().Add(natsRequestTimeout))
+	line, err := sink.readLine()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return fmt.Errorf("unexpected NATS greeting %q", line)
+	}
+	var info natsServerInfo
+	err = json.Unmarshal([]byte(strings.TrimPrefix(line, "INFO ")), &info)
+	if err != nil {
+		return fmt.Errorf("invalid NATS server info: %v", err)
+	}
+	if info.TLSRequired {
+		return errors.New("NATS server requires TLS, which is not supported")
+	}
+	sink.headers, sink.maxPayload = info.Headers, info.MaxPayload
+
+	connect := natsConnect{
+		Name:     sink.cfg.Name,
+		Lang:     "go",
+		Version:  version.String(),
+		Protocol: 1,
+		Headers:  info.Headers,
+		NoEcho:   true,
+	}
+	if u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			connect.User, connect.Password = u.User.Username(), password
+		} else {
+			connect.AuthToken = u.User.Username()
+		}
+	}
+	b, err := json.Marshal(connect)
+	if err != nil {
+		return fmt.Errorf("failed to JSON-encode NATS CONNECT message: %v", err)
+	}
+	_, err = sink.conn.Write([]byte("CONNECT " + string(b) + "\r\nPING\r\n"))
+	if err != nil {
+		return fmt.Errorf("failed to send NATS CONNECT message: %v", err)
+	}
+	return sink.awaitPong()
+}
+
+func (sink *NATSSink) disconnect() {
+	if sink.conn != nil {
+		sink.conn.Close()
+		sink.conn, sink.reader = nil, nil
+	}
+}
+
+// Close implements ChangeSink.Close, closing the connection to the server.
+func (sink *NATSSink) Close() error {
+	sink.mut.Lock()
+	defer sink.mut.Unlock()
+	sink.disconnect()
+	return nil
+}
@@ -0,0 +1,204 @@
+package rexplorer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rivine/rivine/types"
+)
+
+type (
+	// NDJSONDatabase is a file-based Database implementation, appending every change
+	// as newline-delimited JSON, giving a replayable and greppable audit trail,
+	// which can be fed into data pipelines without requiring a database server.
+	//
+	// Each line is a record, storing the new (JSON-encoded) value of a single key, or marking it as deleted:
+	//
+	//	  {"type":"wallet","key":"<address>","value":{...}}
+	//	  {"type":"multisigsigner","key":"<address>:<signer>","deleted":true}
+	//	  {"type":"commit","time":<unixTimestamp>}
+	//
+	// The types and keys are those of the MemoryDatabase, which is used to keep all values in memory,
+	// restored on startup by replaying the file. The commit record type marks the end of a group of records
+	// which is applied atomically. As it implements TransactionalDatabase, the records of a consensus change are buffered,
+	// and appended (followed by a commit record) once the consensus change has been applied completely.
+	// Records not followed by a commit record (e.g. the partially written records of a crash) are ignored,
+	// and truncated from the file on startup. The file is never compacted, and thus keeps growing.
+	NDJSONDatabase struct {
+		*MemoryDatabase
+
+		file *os.File
+		// the buffered records of the consensus change currently being applied, nil if none
+		pending *bytes.Buffer
+	}
+
+	// ndjsonRecord is a single line of the file.
+	ndjsonRecord struct {
+		Type    string          `json:"type"`
+		Key     string          `json:"key,omitempty"`
+		Value   json.RawMessage `json:"value,omitempty"`
+		Deleted bool            `json:"deleted,omitempty"`
+		Time    int64           `json:"time,omitempty"`
+	}
+)
+
+var (
+	_ TransactionalDatabase = (*NDJSONDatabase)(nil)
+)
+
+const ndjsonTypeCommit = "commit"
+
+func init() {
+	RegisterDatabaseDriver("ndjson", func(cfg DatabaseConfig) (Database, error) {
+		path := cfg.Address
+		if path == "" {
+			path = "rexplorer.ndjson"
+		}
+		return NewNDJSONDatabase(path, cfg.BlockchainInfo, cfg.ChainConstants)
+	})
+}
+
+// NewNDJSONDatabase creates (or opens) a NDJSON Database, stored in the file at the given path,
+// see NDJSONDatabase for more information.
+func NewNDJSONDatabase(path string, bcInfo types.BlockchainInfo, chainCts types.ChainConstants) (*NDJSONDatabase, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ndjson database at %s: %v", path, err)
+	}
+	ndb := NDJSONDatabase{
+		MemoryDatabase: newMemoryDatabase("ndjson", chainCts),
+		file:           file,
+	}
+	err = ndb.replay()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to replay ndjson database at %s: %v", path, err)
+	}
+	// record all changes from now on
+	ndb.observer = func(typ, key string, value json.RawMessage, deleted bool) error {
+		return ndb.record(ndjsonRecord{Type: typ, Key: key, Value: value, Deleted: deleted})
+	}
+	// ensure the network info is as expected (or register if this is a fresh db)
+	err = ndb.registerOrValidateNetworkInfo(bcInfo)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &ndb, nil
+}
+
+// replay restores all values by replaying the committed records of the file,
+// truncating the records which weren't committed, such that new records are appended after the last commit record.
+func (ndb *NDJSONDatabase) replay() error {
+	var (
+		reader    = bufio.NewReader(ndb.file)
+		offset    int64
+		committed int64
+		records   []ndjsonRecord
+	)
+	for line := 1; ; line++ {
+		b, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			break // a partially written line is never committed
+		}
+		if err != nil {
+			return err
+		}
+		offset += int64(len(b))
+		var record ndjsonRecord
+		err = json.Unmarshal(b, &record)
+		if err != nil {
+			return fmt.Errorf("invalid record on line %d: %v", line, err)
+		}
+		if record.Type != ndjsonTypeCommit {
+			records = append(records, record)
+			continue
+		}
+		for _, record := range records {
+			ndb.set(record.Type, record.Key, record.Value, record.Deleted)
+		}
+		records, committed = records[:0], offset
+	}
+	err := ndb.file.Truncate(committed)
+	if err == nil {
+		_, err = ndb.file.Seek(committed, io.SeekStart)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to truncate uncommitted records: %v", err)
+	}
+	return ndb.reindexLocks()
+}
+
+// Begin implements TransactionalDatabase.Begin
+func (ndb *NDJSONDatabase) Begin() error {
+	if ndb.pending != nil {
+		return errors.New("ndjson: a batch is already in progress")
+	}
+	ndb.pending = new(bytes.Buffer)
+	return nil
+}
+
+// Commit implements TransactionalDatabase.Commit
+//
+// appends all buffered records, followed by a commit record
+func (ndb *NDJSONDatabase) Commit() error {
+	if ndb.pending == nil {
+		return errors.New("ndjson: no batch in progress")
+	}
+	buf := ndb.pending
+	ndb.pending = nil
+	if buf.Len() == 0 {
+		return nil // nothing to commit
+	}
+	return ndb.write(buf)
+}
+
+// Close implements Database.Close
+//
+// drops the batch in progress (if any), and closes the file
+func (ndb *NDJSONDatabase) Close() error {
+	ndb.pending = nil
+	ndb.MemoryDatabase.Close()
+	err := ndb.file.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close ndjson database: %v", err)
+	}
+	return nil
+}
+
+// write appends the given records followed by a commit record, and syncs the file.
+func (ndb *NDJSONDatabase) write(buf *bytes.Buffer) error {
+	appendNDJSONRecord(buf, ndjsonRecord{Type: ndjsonTypeCommit, Time: time.Now().Unix()})
+	_, err := ndb.file.Write(buf.Bytes())
+	if err == nil {
+		err = ndb.file.Sync()
+	}
+	if err != nil {
+		return fmt.Errorf("ndjson: failed to append records: %v", err)
+	}
+	return nil
+}
+
+// record records a change, buffering it if a batch is in progress,
+// and appending it right away otherwise.
+func (ndb *NDJSONDatabase) record(record ndjsonRecord) error {
+	if ndb.pending != nil {
+		appendNDJSONRecord(ndb.pending, record)
+		return nil
+	}
+	var buf bytes.Buffer
+	appendNDJSONRecord(&buf, record)
+	return ndb.write(&buf)
+}
+
+// appendNDJSONRecord appends a record as a single line to the given buffer.
+func appendNDJSONRecord(buf *bytes.Buffer, record ndjsonRecord) {
+	buf.Write(MustMarshal(jsonEncoder{}, record))
+	buf.WriteByte('\n')
+}
@@ -0,0 +1,52 @@
+package rexplorer
+
+import (
+	"fmt"
+
+	"github.com/rivine/rivine/types"
+)
+
+// The versions of the network stats and wallet payloads, embedded as their version field,
+// such that readers can detect payloads of which the structure differs from the structure they expect.
+// A version is bumped whenever its payload changes in a way existing readers have to be aware of,
+// in which case its up-conversion is added to upgradeNetworkStats or upgradeWallet.
+// Payloads stored prior to being versioned have no version field, and are considered to be version 0.
+const (
+	NetworkStatsVersion = 1
+	WalletVersion       = 1
+)
+
+// upgradeNetworkStats up-converts the given network stats, decoded from a stored payload, to NetworkStatsVersion,
+// returning an error if the payload was stored by a newer version of rexplorer.
+//
+// Version 0 payloads might lack the stats derived from the other stats (the velocity and supply breakdown),
+// which are recomputed.
+func upgradeNetworkStats(stats *NetworkStats) error {
+	if stats.Version > NetworkStatsVersion {
+		return fmt.Errorf("network stats version %d is newer than the latest version %d supported by this rexplorer",
+			stats.Version, NetworkStatsVersion)
+	}
+	if stats.Version < 1 {
+		stats.updateVelocity()
+		stats.updateSupply()
+	}
+	stats.Version = NetworkStatsVersion
+	return nil
+}
+
+// upgradeWallet up-converts the given wallet, decoded from a stored payload, to WalletVersion,
+// returning an error if the payload was stored by a newer version of rexplorer.
+//
+// Version 0 payloads might lack the unlock horizons of the locked balance,
+// which are recomputed as of the given network time.
+func upgradeWallet(wallet *Wallet, now types.Timestamp) error {
+	if wallet.Version > WalletVersion {
+		return fmt.Errorf("wallet version %d is newer than the latest version %d supported by this rexplorer",
+			wallet.Version, WalletVersion)
+	}
+	if wallet.Version < 1 {
+		wallet.Balance.Locked.updateHorizons(now)
+	}
+	wallet.Version = WalletVersion
+	return nil
+}
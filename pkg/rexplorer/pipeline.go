@@ -0,0 +1,158 @@
+package rexplorer
+
+import (
+	"github.com/rivine/rivine/modules"
+	"github.com/rivine/rivine/types"
+)
+
+// syncPipelineDepth is the amount of consensus changes queued per decode worker,
+// bounding the consensus changes held in memory while they wait to be decoded and stored.
+const syncPipelineDepth = 16
+
+// syncPipeline processes the consensus changes received while the consensus set isn't synced (e.g. the initial sync)
+// in two stages connected by channels, such that the consensus set can hand over the next consensus change
+// while the previous ones are being processed:
+//
+//   - a decode stage, in which a pool of workers decodes multiple consensus changes concurrently,
+//     deriving the coin output changes of every block applied (see BlockChanges), which requires hashing
+//     the IDs of the blocks, transactions and coin outputs, and deriving the owner address and lock of every coin output;
+//   - a storage stage, which stores the decoded consensus changes one by one, in the order they were received.
+//
+// As the consensus changes are stored asynchronously, consensus changes which weren't stored yet when the explorer
+// is closed are dropped, to be received again once the explorer resumes from the last consensus change stored.
+type syncPipeline struct {
+	explorer *Explorer
+	// the consensus changes to decode, and all consensus changes (and flushes) in the order they have to be stored
+	decode chan *syncJob
+	store  chan *syncJob
+	// height of the last block of the last consensus change submitted
+	height types.BlockHeight
+}
+
+// syncJob is a consensus change queued by a syncPipeline,
+// or a flush of the pipeline, which is done once all consensus changes queued before it are stored.
+type syncJob struct {
+	css modules.ConsensusChange
+	// the heights at which the applied blocks are applied, and their coin output changes once decoded
+	heights []types.BlockHeight
+	changes []BlockChanges
+	// closed once decoded, or once all jobs queued before it are stored for a flush
+	done chan struct{}
+	// whether this job flushes the pipeline rather than storing a consensus change
+	flush bool
+}
+
+// newSyncPipeline creates a syncPipeline for the given explorer, decoding consensus changes using the given amount
+// of workers, continuing from the current block height of the explorer. The workers and the storage stage are
+// started as background goroutines of the explorer, and thus stopped once the explorer is closed.
+func newSyncPipeline(explorer *Explorer, workers int) *syncPipeline {
+	p := &syncPipeline{
+		explorer: explorer,
+		decode:   make(chan *syncJob, workers*syncPipelineDepth),
+		store:    make(chan *syncJob, workers*syncPipelineDepth),
+		height:   explorer.stats.BlockHeight,
+	}
+	explorer.background.Add(workers + 1)
+	for i := 0; i < workers; i++ {
+		go p.decodeChanges()
+	}
+	go p.storeChanges()
+	return p
+}
+
+// submit queues the given consensus change, returning false if it was dropped as the explorer is closing.
+func (p *syncPipeline) submit(css modules.ConsensusChange) bool {
+	job := &syncJob{
+		css:     css,
+		heights: make([]types.BlockHeight, 0, len(css.AppliedBlocks)),
+		done:    make(chan struct{}),
+	}
+	// the heights are derived the same way as the explorer does when it stores the consensus change
+	for _, block := range css.RevertedBlocks {
+		if block.ParentID != (types.BlockID{}) {
+			p.height--
+		}
+	}
+	for _, block := range css.AppliedBlocks {
+		if block.ParentID != (types.BlockID{}) {
+			p.height++
+		}
+		job.heights = append(job.heights, p.height)
+	}
+	select {
+	case p.store <- job:
+	case <-p.explorer.closing:
+		return false
+	}
+	select {
+	case p.decode <- job:
+		return true
+	case <-p.explorer.closing:
+		return false
+	}
+}
+
+// flush waits until all consensus changes submitted are stored,
+// returning false if the explorer closed before they were all stored.
+func (p *syncPipeline) flush() bool {
+	job := &syncJob{flush: true, done: make(chan struct{})}
+	select {
+	case p.store <- job:
+	case <-p.explorer.closing:
+		return false
+	}
+	select {
+	case <-job.done:
+		return true
+	case <-p.explorer.closing:
+		return false
+	}
+}
+
+// reset continues the pipeline from the given block height,
+// used once the explorer stored consensus changes without the pipeline.
+func (p *syncPipeline) reset(height types.BlockHeight) {
+	p.height = height
+}
+
+// decodeChanges is a worker of the decode stage, decoding the queued consensus changes until the explorer is closed.
+func (p *syncPipeline) decodeChanges() {
+	defer p.explorer.background.Done()
+	for {
+		select {
+		case job := <-p.decode:
+			job.changes = make([]BlockChanges, 0, len(job.heights))
+			for i, block := range job.css.AppliedBlocks {
+				job.changes = append(job.changes,
+					newBlockChanges(block, job.heights[i], block.Timestamp, p.explorer.chainCts.MaturityDelay))
+			}
+			close(job.done)
+		case <-p.explorer.closing:
+			return
+		}
+	}
+}
+
+// storeChanges is the storage stage, storing the decoded consensus changes in order until the explorer is closed.
+func (p *syncPipeline) storeChanges() {
+	defer p.explorer.background.Done()
+	for {
+		select {
+		case job := <-p.store:
+			if job.flush {
+				close(job.done)
+				continue
+			}
+			select {
+			case <-job.done:
+			case <-p.explorer.closing:
+				return
+			}
+			p.explorer.mut.Lock()
+			p.explorer.processConsensusChange(job.css, job.changes)
+			p.explorer.mut.Unlock()
+		case <-p.explorer.closing:
+			return
+		}
+	}
+}
@@ -0,0 +1,27 @@
+//go:build postgres
+// +build postgres
+
+package rexplorer
+
+import (
+	// registers the "postgres" database/sql driver
+	_ "github.com/lib/pq"
+)
+
+// postgresDialect defines the PostgreSQL dialect used by the SQLDatabase.
+var postgresDialect = SQLDialect{
+	Name:                 "postgres",
+	CurrencyType:         "NUMERIC(78, 0)",
+	BytesType:            "BYTEA",
+	NumberedPlaceholders: true,
+}
+
+func init() {
+	RegisterDatabaseDriver("postgres", func(cfg DatabaseConfig) (Database, error) {
+		address := cfg.Address
+		if address == "" {
+			address = "postgres://localhost:5432/rexplorer?sslmode=disable"
+		}
+		return NewSQLDatabase("postgres", address, postgresDialect, cfg.BlockchainInfo, cfg.ChainConstants)
+	})
+}
@@ -0,0 +1,155 @@
+package rexplorer
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rivine/rivine/types"
+)
+
+type (
+	// TransactionPreview reports the effect a transaction would have on the tracked wallets,
+	// would it be applied on top of the current state of the explorer.
+	TransactionPreview struct {
+		TransactionID types.TransactionID `json:"txid"`
+		// chain context the preview was computed for
+		BlockHeight types.BlockHeight `json:"blockHeight"`
+		Timestamp   types.Timestamp   `json:"timestamp"`
+
+		Fees            types.Currency        `json:"fees"`
+		ConsumedOutputs []PreviewCoinOutput   `json:"consumedOutputs"`
+		CreatedOutputs  []PreviewCoinOutput   `json:"createdOutputs"`
+		Wallets         []PreviewWalletImpact `json:"wallets"`
+	}
+	// PreviewCoinOutput defines a coin output consumed or created by a previewed transaction.
+	PreviewCoinOutput struct {
+		ID         types.CoinOutputID `json:"id"`
+		UnlockHash types.UnlockHash   `json:"unlockhash"`
+		Value      types.Currency     `json:"value"`
+		Locked     bool               `json:"locked"`
+		LockType   LockType           `json:"lockType,omitempty"`
+		LockValue  LockValue          `json:"lockValue,omitempty"`
+	}
+	// PreviewWalletImpact defines the impact of a previewed transaction on a single wallet.
+	PreviewWalletImpact struct {
+		Address types.UnlockHash `json:"address"`
+
+		UnlockedBefore types.Currency `json:"unlockedBefore"`
+		LockedBefore   types.Currency `json:"lockedBefore"`
+
+		Spent            types.Currency `json:"spent"`
+		ReceivedUnlocked types.Currency `json:"receivedUnlocked"`
+		ReceivedLocked   types.Currency `json:"receivedLocked"`
+
+		UnlockedAfter types.Currency `json:"unlockedAfter"`
+		LockedAfter   types.Currency `json:"lockedAfter"`
+	}
+)
+
+// PreviewTransaction computes the effect the given (unsigned) transaction
+// would have on the tracked wallets, without committing anything to the given Database.
+//
+// An error is returned in case the transaction spends a coin output which is unknown,
+// already spent or still locked, according to the state stored in the Database.
+func PreviewTransaction(db Database, tx types.Transaction) (TransactionPreview, error) {
+	stats, err := db.GetNetworkStats()
+	if err != nil {
+		return TransactionPreview{}, fmt.Errorf("failed to get network stats: %v", err)
+	}
+	preview := TransactionPreview{
+		TransactionID: tx.ID(),
+		BlockHeight:   stats.BlockHeight,
+		Timestamp:     stats.Timestamp,
+	}
+	for _, fee := range tx.MinerFees {
+		preview.Fees = preview.Fees.Add(fee)
+	}
+
+	wallets := make(map[types.UnlockHash]*PreviewWalletImpact)
+	getWalletImpact := func(uh types.UnlockHash) (*PreviewWalletImpact, error) {
+		if impact, ok := wallets[uh]; ok {
+			return impact, nil
+		}
+		wallet, err := db.GetWallet(uh)
+		if err != nil && err != ErrNotFound {
+			return nil, fmt.Errorf("failed to get wallet %s: %v", uh.String(), err)
+		}
+		impact := &PreviewWalletImpact{
+			Address:        uh,
+			UnlockedBefore: wallet.Balance.Unlocked,
+			LockedBefore:   wallet.Balance.Locked.Total,
+		}
+		wallets[uh] = impact
+		return impact, nil
+	}
+
+	// consume all coin outputs spent by the coin inputs
+	for _, ci := range tx.CoinInputs {
+		info, err := db.GetCoinOutput(ci.ParentID)
+		if err != nil {
+			if err == ErrNotFound {
+				return TransactionPreview{}, fmt.Errorf("coin input spends unknown coin output %s", ci.ParentID.String())
+			}
+			return TransactionPreview{}, fmt.Errorf("failed to get coin output %s: %v", ci.ParentID.String(), err)
+		}
+		switch info.State {
+		case CoinOutputStateSpent:
+			return TransactionPreview{}, fmt.Errorf("coin input spends already spent coin output %s", ci.ParentID.String())
+		case CoinOutputStateLocked:
+			return TransactionPreview{}, fmt.Errorf("coin input spends locked coin output %s", ci.ParentID.String())
+		}
+		preview.ConsumedOutputs = append(preview.ConsumedOutputs, PreviewCoinOutput{
+			ID:         ci.ParentID,
+			UnlockHash: info.UnlockHash,
+			Value:      info.Value,
+		})
+		impact, err := getWalletImpact(info.UnlockHash)
+		if err != nil {
+			return TransactionPreview{}, err
+		}
+		impact.Spent = impact.Spent.Add(info.Value)
+	}
+
+	// create all coin outputs
+	ctx := types.FulfillableContext{
+		BlockHeight: stats.BlockHeight,
+		BlockTime:   stats.Timestamp,
+	}
+	for i, co := range tx.CoinOutputs {
+		pco := PreviewCoinOutput{
+			ID:         tx.CoinOutputID(uint64(i)),
+			UnlockHash: co.Condition.UnlockHash(),
+			Value:      co.Value,
+			Locked:     !co.Condition.Fulfillable(ctx),
+		}
+		if pco.Locked {
+			if tlc, ok := co.Condition.Condition.(*types.TimeLockCondition); ok {
+				pco.LockType, pco.LockValue = LockTypeTime, LockValue(tlc.LockTime)
+				if tlc.LockTime < types.LockTimeMinTimestampValue {
+					pco.LockType = LockTypeHeight
+				}
+			}
+		}
+		preview.CreatedOutputs = append(preview.CreatedOutputs, pco)
+		impact, err := getWalletImpact(pco.UnlockHash)
+		if err != nil {
+			return TransactionPreview{}, err
+		}
+		if pco.Locked {
+			impact.ReceivedLocked = impact.ReceivedLocked.Add(co.Value)
+		} else {
+			impact.ReceivedUnlocked = impact.ReceivedUnlocked.Add(co.Value)
+		}
+	}
+
+	// compute the balances after the transaction, and order the wallets deterministically
+	for _, impact := range wallets {
+		impact.UnlockedAfter = subCurrencyOrZero(impact.UnlockedBefore.Add(impact.ReceivedUnlocked), impact.Spent)
+		impact.LockedAfter = impact.LockedBefore.Add(impact.ReceivedLocked)
+		preview.Wallets = append(preview.Wallets, *impact)
+	}
+	sort.Slice(preview.Wallets, func(i, j int) bool {
+		return preview.Wallets[i].Address.Cmp(preview.Wallets[j].Address) < 0
+	})
+	return preview, nil
+}
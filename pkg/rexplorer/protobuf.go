@@ -0,0 +1,177 @@
+package rexplorer
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// The wire types of the protobuf encoding (see https://protobuf.dev/programming-guides/encoding),
+// of which only the ones used by the messages of this package can be encoded.
+const (
+	protoWireVarint  = 0
+	protoWireFixed64 = 1
+	protoWireBytes   = 2
+	protoWireFixed32 = 5
+)
+
+var errProtoTruncated = errors.New("truncated protobuf message")
+
+// protoEncoder encodes a single protobuf message, field by field, using the proto3 wire format:
+// scalar fields with their default (zero) value are omitted, as proto3 requires.
+type protoEncoder struct {
+	buf []byte
+}
+
+func (e *protoEncoder) tag(field int, wireType int) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(field)<<3|uint64(wireType))
+}
+
+// Uint64 encodes a uint64 (or enum) field.
+func (e *protoEncoder) Uint64(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, protoWireVarint)
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+// Bool encodes a bool field.
+func (e *protoEncoder) Bool(field int, v bool) {
+	if v {
+		e.Uint64(field, 1)
+	}
+}
+
+// Double encodes a double field.
+func (e *protoEncoder) Double(field int, v float64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, protoWireFixed64)
+	e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(v))
+}
+
+// Bytes encodes a bytes field.
+func (e *protoEncoder) Bytes(field int, b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	e.lengthDelimited(field, b)
+}
+
+// String encodes a string field.
+func (e *protoEncoder) String(field int, s string) {
+	if s == "" {
+		return
+	}
+	e.tag(field, protoWireBytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+// Strings encodes a repeated string field.
+func (e *protoEncoder) Strings(field int, strs []string) {
+	for _, s := range strs {
+		e.tag(field, protoWireBytes)
+		e.buf = binary.AppendUvarint(e.buf, uint64(len(s)))
+		e.buf = append(e.buf, s...)
+	}
+}
+
+// Message encodes an embedded message field, which is encoded even if empty,
+// such that it is defined (rather than absent) when decoded.
+// Repeated message fields are encoded by encoding each message using this method.
+func (e *protoEncoder) Message(field int, msg *protoEncoder) {
+	e.lengthDelimited(field, msg.buf)
+}
+
+func (e *protoEncoder) lengthDelimited(field int, b []byte) {
+	e.tag(field, protoWireBytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+// Encoded returns the encoded message.
+func (e *protoEncoder) Encoded() []byte {
+	return e.buf
+}
+
+// protoDecoder decodes a single protobuf message, field by field.
+type protoDecoder struct {
+	buf []byte
+}
+
+// Next decodes the tag of the next field, returning false once all fields are decoded.
+// The value of the field has to be decoded (or skipped) using the method matching its wire type.
+func (d *protoDecoder) Next() (field int, wireType int, ok bool, err error) {
+	if len(d.buf) == 0 {
+		return 0, 0, false, nil
+	}
+	tag, err := d.Varint()
+	if err != nil {
+		return 0, 0, false, err
+	}
+	field, wireType = int(tag>>3), int(tag&7)
+	if field == 0 {
+		return 0, 0, false, errors.New("invalid protobuf field number 0")
+	}
+	return field, wireType, true, nil
+}
+
+// Varint decodes the value of a varint field.
+func (d *protoDecoder) Varint() (uint64, error) {
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		return 0, errProtoTruncated
+	}
+	d.buf = d.buf[n:]
+	return v, nil
+}
+
+// Fixed64 decodes the value of a 64-bit field.
+func (d *protoDecoder) Fixed64() (uint64, error) {
+	if len(d.buf) < 8 {
+		return 0, errProtoTruncated
+	}
+	v := binary.LittleEndian.Uint64(d.buf)
+	d.buf = d.buf[8:]
+	return v, nil
+}
+
+// LengthDelimited decodes the value of a bytes, string or embedded message field,
+// the returned slice referencing the decoded message.
+func (d *protoDecoder) LengthDelimited() ([]byte, error) {
+	length, err := d.Varint()
+	if err != nil {
+		return nil, err
+	}
+	if length > uint64(len(d.buf)) {
+		return nil, errProtoTruncated
+	}
+	b := d.buf[:length]
+	d.buf = d.buf[length:]
+	return b, nil
+}
+
+// Skip the value of a field of the given wire type, as to ignore unknown fields.
+func (d *protoDecoder) Skip(wireType int) error {
+	var err error
+	switch wireType {
+	case protoWireVarint:
+		_, err = d.Varint()
+	case protoWireFixed64:
+		_, err = d.Fixed64()
+	case protoWireBytes:
+		_, err = d.LengthDelimited()
+	case protoWireFixed32:
+		if len(d.buf) < 4 {
+			return errProtoTruncated
+		}
+		d.buf = d.buf[4:]
+	default:
+		err = fmt.Errorf("unsupported protobuf wire type %d", wireType)
+	}
+	return err
+}
@@ -0,0 +1,284 @@
+package rexplorer
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/rivine/rivine/crypto"
+	"github.com/rivine/rivine/types"
+)
+
+// protoStoredWallet encodes the given wallet as a rexplorer.StoredWallet message,
+// the locked outputs being sorted by ID. The (separately stored) optional properties of the wallet are not encoded.
+// The wallet is always encoded as the latest version, the same as its JSON representation (see WalletVersion).
+func protoStoredWallet(wallet Wallet) *protoEncoder {
+	e := new(protoEncoder)
+	e.Uint64(1, WalletVersion)
+	locked := new(protoEncoder)
+	locked.String(1, wallet.Balance.Locked.Total.String())
+	ids := make([]string, 0, len(wallet.Balance.Locked.Outputs))
+	outputs := make(map[string]WalletLockedOutput, len(wallet.Balance.Locked.Outputs))
+	for id, output := range wallet.Balance.Locked.Outputs {
+		ids = append(ids, id.String())
+		outputs[id.String()] = output
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		locked.Message(2, protoLockedOutput(id, outputs[id]))
+	}
+	if horizons := wallet.Balance.Locked.Horizons; horizons != nil {
+		h := new(protoEncoder)
+		h.Uint64(1, uint64(horizons.AsOf))
+		h.String(2, horizons.Day.String())
+		h.String(3, horizons.Month.String())
+		h.String(4, horizons.Year.String())
+		h.String(5, horizons.Later.String())
+		locked.Message(3, h)
+	}
+	balance := new(protoEncoder)
+	balance.String(1, wallet.Balance.Unlocked.String())
+	balance.Message(2, locked)
+	e.Message(2, balance)
+	e.Strings(3, unlockHashStrings(wallet.MultiSignAddresses))
+	if len(wallet.MultiSignData.Owners) > 0 {
+		multisig := new(protoEncoder)
+		multisig.Strings(1, unlockHashStrings(wallet.MultiSignData.Owners))
+		multisig.Uint64(2, wallet.MultiSignData.SignaturesRequired)
+		e.Message(4, multisig)
+	}
+	if !wallet.BlockStakes.Unlocked.IsZero() || !wallet.BlockStakes.Locked.IsZero() {
+		blockStakes := new(protoEncoder)
+		blockStakes.String(1, wallet.BlockStakes.Unlocked.String())
+		blockStakes.String(2, wallet.BlockStakes.Locked.String())
+		e.Message(5, blockStakes)
+	}
+	return e
+}
+
+// decodeStoredWallet decodes a rexplorer.StoredWallet message into the given wallet.
+func decodeStoredWallet(msg []byte, wallet *Wallet) error {
+	*wallet = Wallet{Balance: WalletBalance{Locked: WalletLockedBalance{Outputs: WalletLockedOutputMap{}}}}
+	return decodeProtoFields(msg, func(d *protoDecoder, field, wireType int) (err error) {
+		switch {
+		case field == 1 && wireType == protoWireVarint:
+			wallet.Version, err = d.Varint()
+		case field == 2 && wireType == protoWireBytes:
+			err = decodeProtoMessage(d, func(d *protoDecoder, field, wireType int) error {
+				switch {
+				case field == 1 && wireType == protoWireBytes:
+					return decodeProtoCurrency(d, &wallet.Balance.Unlocked)
+				case field == 2 && wireType == protoWireBytes:
+					return decodeProtoMessage(d, func(d *protoDecoder, field, wireType int) error {
+						return decodeStoredLockedBalanceField(d, field, wireType, &wallet.Balance.Locked)
+					})
+				default:
+					return d.Skip(wireType)
+				}
+			})
+		case field == 3 && wireType == protoWireBytes:
+			var address types.UnlockHash
+			err = decodeProtoUnlockHash(d, &address)
+			wallet.MultiSignAddresses = append(wallet.MultiSignAddresses, address)
+		case field == 4 && wireType == protoWireBytes:
+			err = decodeProtoMessage(d, func(d *protoDecoder, field, wireType int) (err error) {
+				switch {
+				case field == 1 && wireType == protoWireBytes:
+					var owner types.UnlockHash
+					err = decodeProtoUnlockHash(d, &owner)
+					wallet.MultiSignData.Owners = append(wallet.MultiSignData.Owners, owner)
+				case field == 2 && wireType == protoWireVarint:
+					wallet.MultiSignData.SignaturesRequired, err = d.Varint()
+				default:
+					err = d.Skip(wireType)
+				}
+				return
+			})
+		case field == 5 && wireType == protoWireBytes:
+			err = decodeProtoMessage(d, func(d *protoDecoder, field, wireType int) error {
+				switch {
+				case field == 1 && wireType == protoWireBytes:
+					return decodeProtoCurrency(d, &wallet.BlockStakes.Unlocked)
+				case field == 2 && wireType == protoWireBytes:
+					return decodeProtoCurrency(d, &wallet.BlockStakes.Locked)
+				default:
+					return d.Skip(wireType)
+				}
+			})
+		default:
+			err = d.Skip(wireType)
+		}
+		return
+	})
+}
+
+// decodeStoredLockedBalanceField decodes a single field of a rexplorer.StoredLockedBalance message into the given balance.
+func decodeStoredLockedBalanceField(d *protoDecoder, field, wireType int, balance *WalletLockedBalance) error {
+	switch {
+	case field == 1 && wireType == protoWireBytes:
+		return decodeProtoCurrency(d, &balance.Total)
+	case field == 2 && wireType == protoWireBytes:
+		var id types.CoinOutputID
+		var output WalletLockedOutput
+		err := decodeProtoMessage(d, func(d *protoDecoder, field, wireType int) error {
+			switch {
+			case field == 1 && wireType == protoWireBytes:
+				b, err := d.LengthDelimited()
+				if err != nil {
+					return err
+				}
+				return id.LoadString(string(b))
+			case field == 2 && wireType == protoWireBytes:
+				return decodeProtoCurrency(d, &output.Amount)
+			case field == 3 && wireType == protoWireVarint:
+				v, err := d.Varint()
+				output.LockedUntil = LockValue(v)
+				return err
+			case field == 4 && wireType == protoWireBytes:
+				b, err := d.LengthDelimited()
+				output.Description = append([]byte(nil), b...)
+				return err
+			case field == 5 && wireType == protoWireBytes:
+				b, err := d.LengthDelimited()
+				output.Reason = LockReason(b)
+				return err
+			default:
+				return d.Skip(wireType)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		balance.Outputs[id] = output
+		return nil
+	case field == 3 && wireType == protoWireBytes:
+		balance.Horizons = new(LockHorizons)
+		return decodeProtoMessage(d, func(d *protoDecoder, field, wireType int) error {
+			switch {
+			case field == 1 && wireType == protoWireVarint:
+				v, err := d.Varint()
+				balance.Horizons.AsOf = types.Timestamp(v)
+				return err
+			case field == 2 && wireType == protoWireBytes:
+				return decodeProtoCurrency(d, &balance.Horizons.Day)
+			case field == 3 && wireType == protoWireBytes:
+				return decodeProtoCurrency(d, &balance.Horizons.Month)
+			case field == 4 && wireType == protoWireBytes:
+				return decodeProtoCurrency(d, &balance.Horizons.Year)
+			case field == 5 && wireType == protoWireBytes:
+				return decodeProtoCurrency(d, &balance.Horizons.Later)
+			default:
+				return d.Skip(wireType)
+			}
+		})
+	default:
+		return d.Skip(wireType)
+	}
+}
+
+// protoStoredCoinOutput encodes the given coin output as a rexplorer.StoredCoinOutput message.
+func protoStoredCoinOutput(co DatabaseCoinOutput) *protoEncoder {
+	e := new(protoEncoder)
+	e.Bytes(1, append([]byte{byte(co.UnlockHash.Type)}, co.UnlockHash.Hash[:]...))
+	e.Bytes(2, co.CoinValue.Big().Bytes())
+	e.Uint64(3, uint64(co.State))
+	e.Uint64(4, uint64(co.LockType))
+	e.Uint64(5, uint64(co.LockValue))
+	e.Bytes(6, co.Description)
+	e.Bytes(7, co.RawCondition)
+	return e
+}
+
+// decodeStoredCoinOutput decodes a rexplorer.StoredCoinOutput message into the given coin output.
+func decodeStoredCoinOutput(msg []byte, co *DatabaseCoinOutput) error {
+	*co = DatabaseCoinOutput{}
+	err := decodeProtoFields(msg, func(d *protoDecoder, field, wireType int) error {
+		if wireType == protoWireVarint && field >= 3 && field <= 5 {
+			v, err := d.Varint()
+			switch field {
+			case 3:
+				co.State = CoinOutputState(v)
+			case 4:
+				co.LockType = LockType(v)
+			case 5:
+				co.LockValue = LockValue(v)
+			}
+			return err
+		}
+		if wireType != protoWireBytes {
+			return d.Skip(wireType)
+		}
+		b, err := d.LengthDelimited()
+		if err != nil {
+			return err
+		}
+		switch field {
+		case 1:
+			if len(b) != 1+crypto.HashSize {
+				return fmt.Errorf("invalid unlock hash length %d", len(b))
+			}
+			co.UnlockHash.Type = types.UnlockType(b[0])
+			copy(co.UnlockHash.Hash[:], b[1:])
+		case 2:
+			co.CoinValue = types.NewCurrency(new(big.Int).SetBytes(b))
+		case 6:
+			co.Description = append(types.ByteSlice(nil), b...)
+		case 7:
+			co.RawCondition = append(types.ByteSlice(nil), b...)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if co.State == CoinOutputStateNil || co.State > CoinOutputStateSpent {
+		return fmt.Errorf("invalid coin output state %d", co.State)
+	}
+	if co.LockType > LockTypeTime {
+		return fmt.Errorf("invalid lock type %d", co.LockType)
+	}
+	return nil
+}
+
+// decodeProtoFields calls the given function for every field of the given message,
+// which has to decode (or skip) the value of that field.
+func decodeProtoFields(msg []byte, fn func(d *protoDecoder, field, wireType int) error) error {
+	d := protoDecoder{buf: msg}
+	for {
+		field, wireType, ok, err := d.Next()
+		if err != nil || !ok {
+			return err
+		}
+		err = fn(&d, field, wireType)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// decodeProtoMessage decodes the value of an embedded message field, calling the given function for each of its fields.
+func decodeProtoMessage(d *protoDecoder, fn func(d *protoDecoder, field, wireType int) error) error {
+	b, err := d.LengthDelimited()
+	if err != nil {
+		return err
+	}
+	return decodeProtoFields(b, fn)
+}
+
+// decodeProtoCurrency decodes the value of a string field as a (decimal) currency.
+func decodeProtoCurrency(d *protoDecoder, c *types.Currency) error {
+	b, err := d.LengthDelimited()
+	if err != nil {
+		return err
+	}
+	return c.LoadString(string(b))
+}
+
+// decodeProtoUnlockHash decodes the value of a string field as an unlock hash.
+func decodeProtoUnlockHash(d *protoDecoder, uh *types.UnlockHash) error {
+	b, err := d.LengthDelimited()
+	if err != nil {
+		return err
+	}
+	return uh.LoadString(string(b))
+}
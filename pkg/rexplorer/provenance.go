@@ -0,0 +1,123 @@
+package rexplorer
+
+import (
+	"fmt"
+
+	"github.com/rivine/rivine/types"
+)
+
+// CoinOutputProvenance records where a coin output was created, and once spent, where it was spent,
+// linking the coin outputs to the transactions creating and spending them (as a UTXO graph).
+// The transaction ID is undefined for miner payouts, created by the block itself,
+// while the spend properties are only defined once the coin output is spent.
+type CoinOutputProvenance struct {
+	BlockHeight        types.BlockHeight    `json:"blockHeight"`
+	BlockID            types.BlockID        `json:"blockID"`
+	TransactionID      *types.TransactionID `json:"txID,omitempty"`
+	SpendBlockHeight   *types.BlockHeight   `json:"spendBlockHeight,omitempty"`
+	SpendTransactionID *types.TransactionID `json:"spendTxID,omitempty"`
+}
+
+// applyMinerPayoutProvenance records the provenance of the miner payouts of the given block,
+// applied at the current block height, in case the database supports it.
+func (explorer *Explorer) applyMinerPayoutProvenance(block types.Block) {
+	pdb, ok := explorer.db.(CoinOutputProvenanceDatabase)
+	if !ok {
+		return
+	}
+	blockID := block.ID()
+	for i := range block.MinerPayouts {
+		id := types.CoinOutputID(block.MinerPayoutID(uint64(i)))
+		err := pdb.SetCoinOutputProvenance(id, CoinOutputProvenance{
+			BlockHeight: explorer.stats.BlockHeight,
+			BlockID:     blockID,
+		})
+		if err != nil {
+			panic(fmt.Sprintf("failed to set provenance of miner payout %s: %v", id.String(), err))
+		}
+	}
+}
+
+// revertMinerPayoutProvenance drops the provenance of the miner payouts of the given block,
+// in case the database supports it.
+func (explorer *Explorer) revertMinerPayoutProvenance(block types.Block) {
+	pdb, ok := explorer.db.(CoinOutputProvenanceDatabase)
+	if !ok {
+		return
+	}
+	for i := range block.MinerPayouts {
+		id := types.CoinOutputID(block.MinerPayoutID(uint64(i)))
+		err := pdb.RevertCoinOutputProvenance(id)
+		if err != nil {
+			panic(fmt.Sprintf("failed to revert provenance of miner payout %s: %v", id.String(), err))
+		}
+	}
+}
+
+// applyCoinOutputProvenance records the given transaction, applied in the given block at the current block height,
+// as the spender of the coin outputs spent by its coin inputs, and as the creator of its coin outputs,
+// in case the database supports it. Coin outputs created prior to the dataset recording their provenance are skipped.
+func (explorer *Explorer) applyCoinOutputProvenance(tx types.Transaction, blockID types.BlockID) {
+	pdb, ok := explorer.db.(CoinOutputProvenanceDatabase)
+	if !ok {
+		return
+	}
+	txID := tx.ID()
+	height := explorer.stats.BlockHeight
+	for _, ci := range tx.CoinInputs {
+		provenance, err := pdb.GetCoinOutputProvenance(ci.ParentID)
+		if err == ErrNotFound {
+			continue
+		}
+		if err == nil {
+			provenance.SpendBlockHeight = &height
+			provenance.SpendTransactionID = &txID
+			err = pdb.SetCoinOutputProvenance(ci.ParentID, provenance)
+		}
+		if err != nil {
+			panic(fmt.Sprintf("failed to mark provenance of coin output %s as spent: %v", ci.ParentID.String(), err))
+		}
+	}
+	for i := range tx.CoinOutputs {
+		id := tx.CoinOutputID(uint64(i))
+		err := pdb.SetCoinOutputProvenance(id, CoinOutputProvenance{
+			BlockHeight:   height,
+			BlockID:       blockID,
+			TransactionID: &txID,
+		})
+		if err != nil {
+			panic(fmt.Sprintf("failed to set provenance of coin output %s: %v", id.String(), err))
+		}
+	}
+}
+
+// revertCoinOutputProvenance drops the provenance of the coin outputs created by the given transaction,
+// and marks the coin outputs spent by its coin inputs as unspent again, in case the database supports it,
+// skipping the coin outputs of which no provenance is stored (see applyCoinOutputProvenance).
+func (explorer *Explorer) revertCoinOutputProvenance(tx types.Transaction) {
+	pdb, ok := explorer.db.(CoinOutputProvenanceDatabase)
+	if !ok {
+		return
+	}
+	for i := range tx.CoinOutputs {
+		id := tx.CoinOutputID(uint64(i))
+		err := pdb.RevertCoinOutputProvenance(id)
+		if err != nil {
+			panic(fmt.Sprintf("failed to revert provenance of coin output %s: %v", id.String(), err))
+		}
+	}
+	for _, ci := range tx.CoinInputs {
+		provenance, err := pdb.GetCoinOutputProvenance(ci.ParentID)
+		if err == ErrNotFound {
+			continue
+		}
+		if err == nil {
+			provenance.SpendBlockHeight = nil
+			provenance.SpendTransactionID = nil
+			err = pdb.SetCoinOutputProvenance(ci.ParentID, provenance)
+		}
+		if err != nil {
+			panic(fmt.Sprintf("failed to mark provenance of coin output %s as unspent: %v", ci.ParentID.String(), err))
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package rexplorer
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// tokenBucket is a token bucket, refilled at a fixed rate (in tokens per second),
+// allowing bursts of at most one second worth of tokens.
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+	mu     sync.Mutex
+}
+
+// newTokenBucket creates a new (full) token bucket, refilled at the given rate (in tokens per second).
+func newTokenBucket(rate int) *tokenBucket {
+	return &tokenBucket{
+		rate:   float64(rate),
+		tokens: float64(rate),
+		last:   time.Now(),
+	}
+}
+
+// refill the bucket with the tokens accumulated since it was last refilled.
+func (tb *tokenBucket) refill(now time.Time) {
+	tb.tokens += now.Sub(tb.last).Seconds() * tb.rate
+	if tb.tokens > tb.rate {
+		tb.tokens = tb.rate
+	}
+	tb.last = now
+}
+
+// Take a single token from the bucket, blocking until one is available.
+func (tb *tokenBucket) Take() {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.refill(time.Now())
+	tb.tokens--
+	if tb.tokens < 0 {
+		// wait until the missing token is refilled, the bucket being drained (at 0 tokens) afterwards
+		time.Sleep(time.Duration(-tb.tokens / tb.rate * float64(time.Second)))
+		tb.tokens = 0
+		tb.last = time.Now()
+	}
+}
+
+// Allow takes a single token from the bucket if one is available, returning false otherwise.
+func (tb *tokenBucket) Allow() bool {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.refill(time.Now())
+	if tb.tokens < 1 {
+		return false
+	}
+	tb.tokens--
+	return true
+}
+
+// rateLimitedConn is a redis.Conn which limits the rate at which commands are issued,
+// using a token bucket, such that the explorer doesn't starve other tenants of a shared Redis server.
+// Each command (be it sent using Do or Send) takes a single token.
+type rateLimitedConn struct {
+	redis.Conn
+	bucket *tokenBucket
+}
+
+// newRateLimitedConn wraps the given connection,
+// limiting the commands issued using it to the given amount of commands per second.
+func newRateLimitedConn(conn redis.Conn, rate int) redis.Conn {
+	return &rateLimitedConn{
+		Conn:   conn,
+		bucket: newTokenBucket(rate),
+	}
+}
+
+// Do implements redis.Conn.Do
+func (c *rateLimitedConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	// an empty command only flushes and receives the replies of the commands sent earlier
+	if cmd != "" {
+		c.bucket.Take()
+	}
+	return c.Conn.Do(cmd, args...)
+}
+
+// Send implements redis.Conn.Send
+func (c *rateLimitedConn) Send(cmd string, args ...interface{}) error {
+	c.bucket.Take()
+	return c.Conn.Send(cmd, args...)
+}
+
+// rateLimiterSweepInterval is the interval at which the token buckets of idle clients are forgotten,
+// as they are full again (and thus equal to a new bucket) once idle for a second.
+const rateLimiterSweepInterval = time.Minute
+
+// RateLimiter limits the rate of the requests made to the HTTP API and the gRPC service,
+// such that a single misbehaving client can't starve the explorer while it is syncing.
+// Requests passing a known API key (see APIKeys) are limited per key, all other requests per client IP.
+// Each request takes a single token of the token bucket of its client, streams taking a single token as well.
+type RateLimiter struct {
+	perIP, perKey int
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// NewRateLimiter creates a RateLimiter, limiting the requests to the given amount of requests per second
+// per client IP and per API key, 0 meaning no limit. Nil is returned if neither is limited.
+func NewRateLimiter(perIP, perKey int) *RateLimiter {
+	if perIP <= 0 && perKey <= 0 {
+		return nil
+	}
+	return &RateLimiter{
+		perIP:     perIP,
+		perKey:    perKey,
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// allowRequest returns true if the client of the given request didn't exceed its rate yet,
+// identified by its API key if it passes one of the given keys, or by its IP otherwise.
+func (rl *RateLimiter) allowRequest(r *http.Request, keys *APIKeys) bool {
+	if rl == nil {
+		return true
+	}
+	if token, err := requestAPIKey(r); err == nil {
+		if key := keys.lookup(token); key != nil {
+			return rl.allow("key:"+string(key.hash[:]), rl.perKey)
+		}
+	}
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return rl.allow("ip:"+ip, rl.perIP)
+}
+
+// allow takes a single token from the bucket of the given client, refilled at the given rate (0 meaning no limit).
+func (rl *RateLimiter) allow(client string, rate int) bool {
+	if rate <= 0 {
+		return true
+	}
+	rl.mu.Lock()
+	now := time.Now()
+	if now.Sub(rl.lastSweep) >= rateLimiterSweepInterval {
+		for id, bucket := range rl.buckets {
+			bucket.mu.Lock()
+			idle := now.Sub(bucket.last) >= time.Second
+			bucket.mu.Unlock()
+			if idle {
+				delete(rl.buckets, id)
+			}
+		}
+		rl.lastSweep = now
+	}
+	bucket, ok := rl.buckets[client]
+	if !ok {
+		bucket = newTokenBucket(rate)
+		rl.buckets[client] = bucket
+	}
+	rl.mu.Unlock()
+	return bucket.Allow()
+}
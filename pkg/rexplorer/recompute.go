@@ -0,0 +1,116 @@
+package rexplorer
+
+import (
+	"fmt"
+
+	"github.com/rivine/rivine/types"
+)
+
+// StatsDivergence is a single network stat of which the stored value diverges from the value recomputed
+// from the stored coin outputs, see RecomputeNetworkStats.
+type StatsDivergence struct {
+	Stat       string
+	Stored     string
+	Recomputed string
+}
+
+// RecomputeNetworkStats rebuilds the network stats which can be derived from the stored (spent and unspent) coin outputs,
+// returning the stored network stats with those stats replaced by their recomputed values,
+// together with the stats of which the stored value diverges from the recomputed value, if any.
+//
+// The recomputed stats are the amount of coin outputs and coin inputs, the coins (the value of all unspent coin outputs),
+// the locked coin outputs and coins (by lock type), the burned coin outputs and coins, and the genesis coins still unspent.
+// All other stats (e.g. the transaction count) cannot be derived from the coin outputs, and are kept as stored.
+func RecomputeNetworkStats(db CoinOutputSetDatabase, chainCts types.ChainConstants) (NetworkStats, []StatsDivergence, error) {
+	stored, err := db.GetNetworkStats()
+	if err != nil {
+		return NetworkStats{}, nil, fmt.Errorf("failed to get network stats: %v", err)
+	}
+	genesis := make(map[types.CoinOutputID]struct{})
+	for _, co := range newGenesisAllocation(chainCts.GenesisBlock()).CoinOutputs {
+		genesis[co.ID] = struct{}{}
+	}
+
+	stats := stored
+	stats.CointOutputCount, stats.CointInputCount = 0, 0
+	stats.LockedCointOutputCount, stats.BurnedCoinOutputCount = 0, 0
+	stats.Coins, stats.LockedCoins, stats.BurnedCoins = types.ZeroCurrency, types.ZeroCurrency, types.ZeroCurrency
+	stats.TimeLockedCoins, stats.HeightLockedCoins, stats.GenesisCoins = types.ZeroCurrency, types.ZeroCurrency, types.ZeroCurrency
+	err = db.IterateCoinOutputs(func(info CoinOutputInfo) error {
+		stats.CointOutputCount++
+		if info.Burned {
+			stats.BurnedCoinOutputCount++
+			stats.BurnedCoins = stats.BurnedCoins.Add(info.Value)
+		}
+		switch info.State {
+		case CoinOutputStateSpent:
+			stats.CointInputCount++
+			return nil
+		case CoinOutputStateLocked:
+			stats.LockedCointOutputCount++
+			stats.LockedCoins = stats.LockedCoins.Add(info.Value)
+			stats.lockCoins(info.LockType, info.Value)
+		}
+		stats.Coins = stats.Coins.Add(info.Value)
+		if _, ok := genesis[info.ID]; ok {
+			stats.GenesisCoins = stats.GenesisCoins.Add(info.Value)
+		}
+		return nil
+	})
+	if err != nil {
+		return NetworkStats{}, nil, fmt.Errorf("failed to iterate coin outputs: %v", err)
+	}
+	stats.updateVelocity()
+	stats.updateSupply()
+
+	var divergences []StatsDivergence
+	compareCount := func(stat string, stored, recomputed uint64) {
+		if stored != recomputed {
+			divergences = append(divergences, StatsDivergence{
+				Stat:       stat,
+				Stored:     fmt.Sprint(stored),
+				Recomputed: fmt.Sprint(recomputed),
+			})
+		}
+	}
+	compareCoins := func(stat string, stored, recomputed types.Currency) {
+		if stored.Cmp(recomputed) != 0 {
+			divergences = append(divergences, StatsDivergence{
+				Stat:       stat,
+				Stored:     stored.String(),
+				Recomputed: recomputed.String(),
+			})
+		}
+	}
+	compareCount("coin outputs", stored.CointOutputCount, stats.CointOutputCount)
+	compareCount("coin inputs", stored.CointInputCount, stats.CointInputCount)
+	compareCoins("coins", stored.Coins, stats.Coins)
+	compareCount("locked outputs", stored.LockedCointOutputCount, stats.LockedCointOutputCount)
+	compareCoins("locked coins", stored.LockedCoins, stats.LockedCoins)
+	compareCoins("time locked coins", stored.TimeLockedCoins, stats.TimeLockedCoins)
+	compareCoins("height locked coins", stored.HeightLockedCoins, stats.HeightLockedCoins)
+	compareCount("burned outputs", stored.BurnedCoinOutputCount, stats.BurnedCoinOutputCount)
+	compareCoins("burned coins", stored.BurnedCoins, stats.BurnedCoins)
+	compareCoins("genesis coins", stored.GenesisCoins, stats.GenesisCoins)
+	return stats, divergences, nil
+}
+
+// StoreRecomputedNetworkStats replaces the stored network stats with the given (recomputed) stats,
+// updating the checksum stored as part of the explorer state accordingly, such that the startup self-check accepts them.
+// It should only be used while no explorer is processing consensus changes using the same database.
+func StoreRecomputedNetworkStats(db Database, stats NetworkStats) error {
+	state, err := db.GetExplorerState()
+	if err != nil {
+		return fmt.Errorf("failed to get explorer state: %v", err)
+	}
+	state.StatsChecksum = networkStatsChecksum(stats)
+	err = db.SetExplorerState(state)
+	if err != nil {
+		return fmt.Errorf("failed to store explorer state: %v", err)
+	}
+	err = db.SetNetworkStats(stats)
+	if err != nil {
+		return fmt.Errorf("failed to store network stats: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,695 @@
+package rexplorer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// DefaultRedisBatchSize is the default maximum amount of writes the Redis drivers batch,
+// prior to flushing them to the Redis server, see DatabaseConfig.BatchSize.
+const DefaultRedisBatchSize = 1000
+
+// pipelinedConn is a redis.Conn which can defer writes in between a call to Begin and Commit,
+// the replies of these writes only being checked for errors.
+//
+// Deferred writes are sent pipelined with the next command of which the reply is required,
+// or flushed as soon as the batch size is reached, in which case only one round trip is made for the entire batch.
+// Hash fields written (or deleted) by deferred writes are remembered for the remainder of the batch,
+// such that reading such a field (using HGET) doesn't require a round trip at all.
+//
+// An error reply to a deferred write is returned by the next command of which the reply is required,
+// or by Commit, whichever comes first.
+//
+// A transactional pipelinedConn buffers all deferred writes of a batch instead, applying them atomically
+// using a single MULTI/EXEC transaction once the batch is committed, regardless of the batch size.
+// Commands executed meanwhile only observe the committed data, combined with the hash fields remembered,
+// the (RPUSH and LREM) operations buffered for lists (when reading an entire list using LRANGE)
+// and the (ZADD and ZREM) operations buffered for sorted sets (when reading or counting a score range
+// using ZRANGEBYSCORE or ZCOUNT).
+// Reading a key written otherwise by the batch returns an error, as it would observe stale data.
+//
+// Should an undo hash be defined, a transactional pipelinedConn stores the value (as of the last checkpoint)
+// of every key written by a batch since in that hash, as part of the same MULTI/EXEC transaction,
+// such that the values written since can be restored, see Checkpoint.
+type pipelinedConn struct {
+	redis.Conn
+	batchSize     int
+	transactional bool
+
+	batching bool
+	// all commands sent of which the reply wasn't received yet
+	queue    []pendingReply
+	deferred int
+	// first error reply to a deferred write, not yet returned
+	err error
+	// values of the hash fields written by deferred writes of the current batch, nil if deleted
+	hashes map[string]map[string][]byte
+	// values of the hash fields prefetched during the current batch, nil if they don't exist
+	fetched map[string]map[string][]byte
+
+	// transactional only: all writes of the current batch, in order
+	writes []pendingWrite
+	// transactional only: the list operations buffered by the current batch
+	lists map[string][]pendingWrite
+	// transactional only: the sorted set operations buffered by the current batch
+	zsets map[string][]pendingWrite
+	// transactional only: the keys written by the current batch which cannot be read until committed
+	dirty map[string]struct{}
+
+	// transactional only: the hash storing the value (as of the last checkpoint) of every key written since,
+	// empty if not tracked
+	undoKey string
+	// transactional only: the keys of which the value is known to be stored in the undo hash
+	undone map[string]struct{}
+	// transactional only: whether or not the current batch checkpoints, see Checkpoint
+	checkpointing bool
+}
+
+// undoScript stores the value of the key KEYS[1] in the undo hash KEYS[2], unless stored already,
+// dumped and prefixed by 1 if the key exists, and as 0 otherwise (see RESTORE).
+const undoScript = `if redis.call('HEXISTS', KEYS[2], KEYS[1]) == 0 then
+	local value = redis.call('DUMP', KEYS[1])
+	if value then
+		value = '1' .. value
+	else
+		value = '0'
+	end
+	redis.call('HSET', KEYS[2], KEYS[1], value)
+end
+return 0`
+
+// pendingReply is the reply of a command sent using a pipelinedConn, not received yet.
+type pendingReply struct {
+	deferred bool
+}
+
+// pendingWrite is a write buffered by a transactional pipelinedConn, not sent yet.
+type pendingWrite struct {
+	cmd  string
+	args []interface{}
+}
+
+// newPipelinedConn wraps the given connection, batching at most batchSize deferred writes,
+// or applying all writes of a batch atomically should the connection be transactional.
+func newPipelinedConn(conn redis.Conn, batchSize int, transactional bool) *pipelinedConn {
+	return &pipelinedConn{
+		Conn:          conn,
+		batchSize:     batchSize,
+		transactional: transactional,
+	}
+}
+
+// Begin a batch, deferring all writes until the batch is committed (or the batch size is reached).
+func (c *pipelinedConn) Begin() {
+	c.batching = true
+	c.hashes = make(map[string]map[string][]byte)
+	c.fetched = make(map[string]map[string][]byte)
+	if c.transactional {
+		c.lists = make(map[string][]pendingWrite)
+		c.zsets = make(map[string][]pendingWrite)
+		c.dirty = make(map[string]struct{})
+	}
+}
+
+// Checkpoint clears the undo hash as the last write of the current (transactional) batch,
+// as the values written since the last checkpoint (including those written by the batch) no longer have to be restored.
+// The writes of a batch which checkpoints aren't stored in the undo hash, as the batch is applied atomically.
+func (c *pipelinedConn) Checkpoint() error {
+	if !c.transactional || !c.batching || c.undoKey == "" {
+		return errors.New("a checkpoint can only be stored within a transactional batch tracking its writes")
+	}
+	c.checkpointing = true
+	return c.Write("DEL", c.undoKey)
+}
+
+// Commit the current batch, flushing all deferred writes and checking their replies for errors.
+func (c *pipelinedConn) Commit() error {
+	c.batching = false
+	c.hashes, c.fetched, c.lists, c.zsets, c.dirty = nil, nil, nil, nil, nil
+	if c.transactional {
+		return c.exec()
+	}
+	err := c.flushDeferred()
+	if err != nil {
+		return err
+	}
+	return c.takeErr()
+}
+
+// Write executes a command of which the reply is only checked for errors,
+// deferring it if a batch is in progress.
+func (c *pipelinedConn) Write(cmd string, args ...interface{}) error {
+	if !c.batching {
+		_, err := c.Do(cmd, args...)
+		return err
+	}
+	c.rememberWrite(cmd, args)
+	if c.transactional {
+		c.writes = append(c.writes, pendingWrite{cmd: cmd, args: args})
+		return nil
+	}
+	err := c.Conn.Send(cmd, args...)
+	if err != nil {
+		return err
+	}
+	c.queue = append(c.queue, pendingReply{deferred: true})
+	c.deferred++
+	// only flush if no replies are expected by the caller, as not to interfere with their pipeline
+	if c.deferred >= c.batchSize && c.deferred == len(c.queue) {
+		return c.flushDeferred()
+	}
+	return nil
+}
+
+// Prefetch reads the given hash fields (each given as a key and field) while a batch is in progress,
+// using a single round trip (pipelined with the deferred writes sent so far), and remembers their values
+// for the remainder of the batch, such that reading them using HGET doesn't require a round trip.
+// Fields written or prefetched earlier in the batch aren't read again. Nothing is prefetched outside of a batch,
+// nor while replies are expected by the caller, as not to interfere with their pipeline.
+func (c *pipelinedConn) Prefetch(fields [][2]string) error {
+	if !c.batching || c.deferred != len(c.queue) {
+		return nil
+	}
+	var sent [][2]string
+	for _, field := range fields {
+		if _, ok := c.rememberedHashField([]interface{}{field[0], field[1]}); ok {
+			continue
+		}
+		err := c.Send("HGET", field[0], field[1])
+		if err != nil {
+			return err
+		}
+		sent = append(sent, field)
+		// remember the field as prefetched already, such that it's only read once
+		c.rememberFetchedHashField(field[0], field[1], nil)
+	}
+	if len(sent) == 0 {
+		return nil
+	}
+	reply, err := c.do("", nil)
+	if err != nil {
+		return err
+	}
+	replies := reply.([]interface{})
+	for i, field := range sent {
+		switch value := replies[i].(type) {
+		case nil:
+		case []byte:
+			c.rememberFetchedHashField(field[0], field[1], value)
+		case redis.Error:
+			return fmt.Errorf("failed to prefetch %s#%s: %v", field[0], field[1], value)
+		default:
+			return fmt.Errorf("failed to prefetch %s#%s: unexpected reply type %T", field[0], field[1], value)
+		}
+	}
+	return nil
+}
+
+// exec applies all writes buffered by the current batch atomically, using a single MULTI/EXEC transaction,
+// preceded by the undo script for every key written by the batch of which the value isn't stored in the undo hash yet,
+// unless the batch checkpoints.
+func (c *pipelinedConn) exec() error {
+	writes, checkpointing := c.writes, c.checkpointing
+	c.writes, c.checkpointing = nil, false
+	if len(writes) == 0 {
+		return nil
+	}
+	var undone []string
+	if c.undoKey != "" && !checkpointing {
+		undone = c.undoneKeys(writes)
+		undo := make([]pendingWrite, 0, len(undone)+len(writes))
+		for _, key := range undone {
+			undo = append(undo, pendingWrite{cmd: "EVAL", args: []interface{}{undoScript, 2, key, c.undoKey}})
+		}
+		writes = append(undo, writes...)
+	}
+	err := c.Conn.Send("MULTI")
+	for _, write := range writes {
+		if err != nil {
+			break
+		}
+		err = c.Conn.Send(write.cmd, write.args...)
+	}
+	if err == nil {
+		err = c.Conn.Send("EXEC")
+	}
+	if err == nil {
+		err = c.Conn.Flush()
+	}
+	if err != nil {
+		return err
+	}
+	// receive the replies to MULTI and the queued writes first,
+	// an error reply to a queued write causing the entire transaction to be discarded by EXEC
+	var queueErr error
+	for i := 0; i <= len(writes); i++ {
+		_, err = c.Conn.Receive()
+		if rerr, ok := err.(redis.Error); ok {
+			if queueErr == nil {
+				queueErr = rerr
+			}
+		} else if err != nil {
+			return err
+		}
+	}
+	replies, err := redis.Values(c.Conn.Receive())
+	if err != nil {
+		if queueErr != nil {
+			return fmt.Errorf("transaction discarded: %v", queueErr)
+		}
+		return fmt.Errorf("transaction failed: %v", err)
+	}
+	for i, reply := range replies {
+		if rerr, ok := reply.(redis.Error); ok {
+			return fmt.Errorf("transaction write %s failed: %v", writes[i].cmd, rerr)
+		}
+	}
+	if checkpointing {
+		c.undone = nil
+	}
+	for _, key := range undone {
+		if c.undone == nil {
+			c.undone = make(map[string]struct{})
+		}
+		c.undone[key] = struct{}{}
+	}
+	return nil
+}
+
+// undoneKeys returns the (sorted) keys written by the given writes, of which the value isn't stored in the undo hash yet.
+func (c *pipelinedConn) undoneKeys(writes []pendingWrite) []string {
+	keys := make(map[string]struct{})
+	for _, write := range writes {
+		for _, key := range writtenKeys(write.cmd, write.args) {
+			if _, ok := c.undone[key]; !ok && key != c.undoKey {
+				keys[key] = struct{}{}
+			}
+		}
+	}
+	undone := make([]string, 0, len(keys))
+	for key := range keys {
+		undone = append(undone, key)
+	}
+	sort.Strings(undone)
+	return undone
+}
+
+// Do implements redis.Conn.Do
+func (c *pipelinedConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	if strings.EqualFold(cmd, "HGET") && c.deferred == len(c.queue) {
+		if value, ok := c.rememberedHashField(args); ok {
+			return value, c.takeErr()
+		}
+	}
+	if c.transactional && c.batching && cmd != "" {
+		return c.doUncommitted(cmd, args)
+	}
+	return c.do(cmd, args)
+}
+
+// doUncommitted executes a command while the writes of the current (transactional) batch are buffered,
+// replaying the list or sorted set operations buffered for the list or sorted set it reads, if any.
+func (c *pipelinedConn) doUncommitted(cmd string, args []interface{}) (interface{}, error) {
+	key, ok := writtenKey(cmd, args)
+	if !ok {
+		return c.do(cmd, args)
+	}
+	ops, listed := c.lists[key]
+	zops, sorted := c.zsets[key]
+	_, dirty := c.dirty[key]
+	if _, hashed := c.hashes[key]; hashed && !strings.EqualFold(cmd, "HGET") {
+		dirty = true
+	}
+	readsList := strings.EqualFold(cmd, "LRANGE") && len(args) == 3 &&
+		string(redisArgBytes(args[1])) == "0" && string(redisArgBytes(args[2])) == "-1"
+	countsRange := strings.EqualFold(cmd, "ZCOUNT") && len(args) == 3
+	readsRange := strings.EqualFold(cmd, "ZRANGEBYSCORE") && len(args) == 3
+	if dirty || (listed && !readsList) || (sorted && !readsRange && !countsRange) {
+		return nil, fmt.Errorf("cannot %s %s, as it is written by the uncommitted batch", cmd, key)
+	}
+	if sorted {
+		reply, err := c.doUncommittedRange(args, zops)
+		if err != nil || !countsRange {
+			return reply, err
+		}
+		return int64(len(reply.([]interface{}))), nil
+	}
+	reply, err := c.do(cmd, args)
+	if err != nil || !listed {
+		return reply, err
+	}
+	values, err := redis.ByteSlices(reply, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range ops {
+		if strings.EqualFold(op.cmd, "RPUSH") {
+			for _, value := range op.args[1:] {
+				values = append(values, redisArgBytes(value))
+			}
+			continue
+		}
+		// LREM <key> 1 <value>
+		value := redisArgBytes(op.args[2])
+		for i := range values {
+			if bytes.Equal(values[i], value) {
+				values = append(values[:i], values[i+1:]...)
+				break
+			}
+		}
+	}
+	list := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		list = append(list, value)
+	}
+	return list, nil
+}
+
+// doUncommittedRange executes a ZRANGEBYSCORE <key> <min> <max> command,
+// replaying the given (ZADD and ZREM) operations buffered for that sorted set on its reply.
+// Just like Redis, members with the same score are ordered lexicographically.
+func (c *pipelinedConn) doUncommittedRange(args []interface{}, ops []pendingWrite) (interface{}, error) {
+	min, minExclusive, err := parseScoreBound(string(redisArgBytes(args[1])))
+	if err != nil {
+		return nil, err
+	}
+	max, maxExclusive, err := parseScoreBound(string(redisArgBytes(args[2])))
+	if err != nil {
+		return nil, err
+	}
+	inRange := func(score float64) bool {
+		return (score > min || (!minExclusive && score == min)) && (score < max || (!maxExclusive && score == max))
+	}
+	values, err := redis.ByteSlices(c.do("ZRANGEBYSCORE", []interface{}{args[0], args[1], args[2], "WITHSCORES"}))
+	if err != nil {
+		return nil, err
+	}
+	scores := make(map[string]float64, len(values)/2)
+	for i := 0; i+1 < len(values); i += 2 {
+		score, err := strconv.ParseFloat(string(values[i+1]), 64)
+		if err != nil {
+			return nil, err
+		}
+		scores[string(values[i])] = score
+	}
+	for _, op := range ops {
+		if strings.EqualFold(op.cmd, "ZADD") {
+			// ZADD <key> <score> <member>
+			member := string(redisArgBytes(op.args[2]))
+			score, err := strconv.ParseFloat(string(redisArgBytes(op.args[1])), 64)
+			if err != nil {
+				return nil, err
+			}
+			if inRange(score) {
+				scores[member] = score
+			} else {
+				delete(scores, member)
+			}
+			continue
+		}
+		// ZREM <key> <member>...
+		for _, member := range op.args[1:] {
+			delete(scores, string(redisArgBytes(member)))
+		}
+	}
+	members := make([]string, 0, len(scores))
+	for member := range scores {
+		members = append(members, member)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if scores[members[i]] != scores[members[j]] {
+			return scores[members[i]] < scores[members[j]]
+		}
+		return members[i] < members[j]
+	})
+	reply := make([]interface{}, 0, len(members))
+	for _, member := range members {
+		reply = append(reply, []byte(member))
+	}
+	return reply, nil
+}
+
+// parseScoreBound parses the minimum or maximum score of a ZRANGEBYSCORE or ZCOUNT command,
+// being a number or (-/+)inf, optionally prefixed with ( in case the bound is exclusive.
+func parseScoreBound(str string) (score float64, exclusive bool, err error) {
+	if strings.HasPrefix(str, "(") {
+		str, exclusive = str[1:], true
+	}
+	score, err = strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid score bound %q: %v", str, err)
+	}
+	return score, exclusive, nil
+}
+
+// do executes a command, pipelined with all deferred writes sent prior to it.
+func (c *pipelinedConn) do(cmd string, args []interface{}) (interface{}, error) {
+	if cmd != "" {
+		err := c.Send(cmd, args...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := c.Conn.Flush()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		replies  []interface{}
+		replyErr error
+	)
+	for len(c.queue) > 0 {
+		if c.queue[0].deferred {
+			err = c.receiveDeferred()
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		reply, err := c.Receive()
+		if err != nil {
+			rerr, ok := err.(redis.Error)
+			if !ok {
+				return nil, err
+			}
+			if replyErr == nil {
+				replyErr = rerr
+			}
+			reply = rerr
+		}
+		replies = append(replies, reply)
+	}
+	if cmd == "" {
+		return replies, c.takeErr()
+	}
+	if replyErr == nil {
+		replyErr = c.takeErr()
+	}
+	return replies[len(replies)-1], replyErr
+}
+
+// Send implements redis.Conn.Send
+func (c *pipelinedConn) Send(cmd string, args ...interface{}) error {
+	c.forgetWrites(cmd, args)
+	err := c.Conn.Send(cmd, args...)
+	if err != nil {
+		return err
+	}
+	c.queue = append(c.queue, pendingReply{})
+	return nil
+}
+
+// Receive implements redis.Conn.Receive,
+// skipping the replies of deferred writes sent prior to the command of which the reply is received.
+func (c *pipelinedConn) Receive() (interface{}, error) {
+	for len(c.queue) > 0 && c.queue[0].deferred {
+		err := c.receiveDeferred()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(c.queue) > 0 {
+		c.queue = c.queue[1:]
+	}
+	return c.Conn.Receive()
+}
+
+// flushDeferred flushes all commands sent, receiving the replies of the deferred writes sent first.
+func (c *pipelinedConn) flushDeferred() error {
+	err := c.Conn.Flush()
+	if err != nil {
+		return err
+	}
+	for len(c.queue) > 0 && c.queue[0].deferred {
+		err = c.receiveDeferred()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// receiveDeferred receives the reply of the oldest deferred write, remembering it if it's an error reply.
+func (c *pipelinedConn) receiveDeferred() error {
+	c.queue = c.queue[1:]
+	c.deferred--
+	_, err := c.Conn.Receive()
+	if rerr, ok := err.(redis.Error); ok {
+		if c.err == nil {
+			c.err = fmt.Errorf("deferred write failed: %v", rerr)
+		}
+		return nil
+	}
+	return err
+}
+
+// takeErr returns (and clears) the first error reply to a deferred write.
+func (c *pipelinedConn) takeErr() error {
+	err := c.err
+	c.err = nil
+	return err
+}
+
+// rememberWrite remembers the hash field written (or deleted) by the given deferred write,
+// as well as the list or sorted set operation buffered by it (if transactional),
+// forgetting all remembered fields of the key written otherwise.
+func (c *pipelinedConn) rememberWrite(cmd string, args []interface{}) {
+	switch {
+	case strings.EqualFold(cmd, "HSET") && len(args) == 3:
+		c.rememberHashField(args[0], args[1], redisArgBytes(args[2]))
+	case strings.EqualFold(cmd, "HDEL") && len(args) >= 2:
+		for _, field := range args[1:] {
+			c.rememberHashField(args[0], field, nil)
+		}
+	case c.transactional && (strings.EqualFold(cmd, "RPUSH") && len(args) >= 2 ||
+		strings.EqualFold(cmd, "LREM") && len(args) == 3 && string(redisArgBytes(args[1])) == "1"):
+		key := string(redisArgBytes(args[0]))
+		c.lists[key] = append(c.lists[key], pendingWrite{cmd: cmd, args: args})
+	case c.transactional && (strings.EqualFold(cmd, "ZADD") && len(args) == 3 ||
+		strings.EqualFold(cmd, "ZREM") && len(args) >= 2):
+		key := string(redisArgBytes(args[0]))
+		c.zsets[key] = append(c.zsets[key], pendingWrite{cmd: cmd, args: args})
+	default:
+		c.forgetWrites(cmd, args)
+		if key, ok := writtenKey(cmd, args); ok && c.dirty != nil {
+			c.dirty[key] = struct{}{}
+		}
+	}
+}
+
+func (c *pipelinedConn) rememberHashField(key, field interface{}, value []byte) {
+	fields, ok := c.hashes[string(redisArgBytes(key))]
+	if !ok {
+		fields = make(map[string][]byte)
+		c.hashes[string(redisArgBytes(key))] = fields
+	}
+	fields[string(redisArgBytes(field))] = value
+}
+
+func (c *pipelinedConn) rememberFetchedHashField(key, field string, value []byte) {
+	fields, ok := c.fetched[key]
+	if !ok {
+		fields = make(map[string][]byte)
+		c.fetched[key] = fields
+	}
+	fields[field] = value
+}
+
+// rememberedHashField returns the remembered value of the hash field read by the given HGET arguments,
+// written by a deferred write or prefetched.
+func (c *pipelinedConn) rememberedHashField(args []interface{}) (interface{}, bool) {
+	if len(args) != 2 {
+		return nil, false
+	}
+	key, field := string(redisArgBytes(args[0])), string(redisArgBytes(args[1]))
+	value, ok := c.hashes[key][field]
+	if !ok {
+		value, ok = c.fetched[key][field]
+	}
+	if !ok {
+		return nil, false
+	}
+	if value == nil {
+		return nil, true // deleted, nil is what Redis replies for a non-existing field
+	}
+	return value, true
+}
+
+// forgetWrites forgets all remembered (and prefetched) hash fields of the key the given command might write to.
+func (c *pipelinedConn) forgetWrites(cmd string, args []interface{}) {
+	if (len(c.hashes) == 0 && len(c.fetched) == 0) || strings.EqualFold(cmd, "HGET") {
+		return
+	}
+	if key, ok := writtenKey(cmd, args); ok {
+		delete(c.hashes, key)
+		delete(c.fetched, key)
+	}
+}
+
+// writtenKey returns the (first) key the given command accesses, and thus might write to.
+func writtenKey(cmd string, args []interface{}) (string, bool) {
+	if strings.EqualFold(cmd, "SCRIPT") {
+		return "", false
+	}
+	if strings.EqualFold(cmd, "EVAL") || strings.EqualFold(cmd, "EVALSHA") {
+		if len(args) < 3 {
+			return "", false
+		}
+		if n, err := strconv.Atoi(string(redisArgBytes(args[1]))); err != nil || n == 0 {
+			return "", false
+		}
+		args = args[2:]
+	}
+	if len(args) == 0 {
+		return "", false
+	}
+	return string(redisArgBytes(args[0])), true
+}
+
+// writtenKeys returns all keys the given write might write to,
+// being all keys declared by a script, all keys deleted by DEL, and the first key accessed by any other write.
+func writtenKeys(cmd string, args []interface{}) []string {
+	switch {
+	case strings.EqualFold(cmd, "PUBLISH"):
+		return nil
+	case strings.EqualFold(cmd, "EVAL") || strings.EqualFold(cmd, "EVALSHA"):
+		if len(args) < 2 {
+			return nil
+		}
+		n, err := strconv.Atoi(string(redisArgBytes(args[1])))
+		if err != nil || n > len(args)-2 {
+			return nil
+		}
+		args = args[2 : 2+n]
+	case strings.EqualFold(cmd, "DEL"):
+	default:
+		key, ok := writtenKey(cmd, args)
+		if !ok {
+			return nil
+		}
+		return []string{key}
+	}
+	keys := make([]string, 0, len(args))
+	for _, arg := range args {
+		keys = append(keys, string(redisArgBytes(arg)))
+	}
+	return keys
+}
+
+// redisArgBytes returns the bytes of a command argument, as they are sent to (and stored by) Redis.
+func redisArgBytes(arg interface{}) []byte {
+	switch v := arg.(type) {
+	case []byte:
+		return v
+	case string:
+		return []byte(v)
+	default:
+		return []byte(fmt.Sprint(v))
+	}
+}
@@ -0,0 +1,702 @@
+package rexplorer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math/big"
+
+	"github.com/rivine/rivine/types"
+)
+
+// Operations applied to a wallet by the wallet script, each followed by its arguments.
+const (
+	// walletOpAddUnlocked <amount>: add the amount to the unlocked balance
+	walletOpAddUnlocked = "unlocked+"
+	// walletOpSubUnlocked <amount>: subtract the amount from the unlocked balance
+	walletOpSubUnlocked = "unlocked-"
+	// walletOpLock <coinOutputID> <amount> <lockedUntil> <description> <reason>: add a locked output
+	walletOpLock = "lock"
+	// walletOpUnlock <coinOutputID>: remove a locked output
+	walletOpUnlock = "unlock"
+	// walletOpAddMultisigAddress <address>: add a multisig address, unless it is known already
+	walletOpAddMultisigAddress = "msaddress"
+	// walletOpSetMultisigData <signaturesRequired> <owner>...<owner>: set the multisig data, unless it is set already
+	walletOpSetMultisigData = "msdata"
+	// walletOpAddUnlockedBlockStakes <amount>: add the amount to the unlocked block stake balance
+	walletOpAddUnlockedBlockStakes = "bs.unlocked+"
+	// walletOpSubUnlockedBlockStakes <amount>: subtract the amount from the unlocked block stake balance
+	walletOpSubUnlockedBlockStakes = "bs.unlocked-"
+	// walletOpAddLockedBlockStakes <amount>: add the amount to the locked block stake balance
+	walletOpAddLockedBlockStakes = "bs.locked+"
+	// walletOpSubLockedBlockStakes <amount>: subtract the amount from the locked block stake balance
+	walletOpSubLockedBlockStakes = "bs.locked-"
+)
+
+// walletScriptSource is the source of the wallet script, which atomically applies operations
+// to the (encoded) wallet stored under the field (ARGV[1]) of the (a:<prefix>) key it is given,
+// such that readers never observe a partially updated wallet, and a wallet update doesn't require a round trip.
+// ARGV[2] is the current network time, as of which the unlock horizons of the locked balance are recomputed
+// (see LockHorizons), and the remaining arguments define the operations, see walletOpAddUnlocked and friends.
+//
+// Returns the amount of operations which had an effect.
+// The wallet is only stored if at least one operation had an effect. Each operation only has an effect once
+// where that is required to be idempotent (walletOpAddMultisigAddress and walletOpSetMultisigData).
+const walletScriptSource = luaDecimalFunctions + `
+local key, field, now = KEYS[1], ARGV[1], tonumber(ARGV[2])
+
+local wallet = {}
+local value = redis.call("HGET", key, field)
+if value then
+	wallet = decodeWallet(value)
+end
+if type(wallet.balance) ~= "table" then
+	wallet.balance = {}
+end
+local balance = wallet.balance
+if type(balance.unlocked) ~= "string" then
+	balance.unlocked = "0"
+end
+if type(balance.locked) ~= "table" then
+	balance.locked = {}
+end
+local locked = balance.locked
+if type(locked.total) ~= "string" then
+	locked.total = "0"
+end
+if type(locked.outputs) ~= "table" then
+	locked.outputs = {}
+end
+if type(wallet.multisignaddresses) ~= "table" then
+	wallet.multisignaddresses = cjson.null
+end
+if type(wallet.multisign) ~= "table" then
+	wallet.multisign = {owners = cjson.null, signaturesRequired = 0}
+end
+
+-- the block stake balance is only added once a wallet receives block stakes
+local function blockStakes()
+	if type(wallet.blockstakes) ~= "table" then
+		wallet.blockstakes = {}
+	end
+	local bs = wallet.blockstakes
+	if type(bs.unlocked) ~= "string" then
+		bs.unlocked = "0"
+	end
+	if type(bs.locked) ~= "string" then
+		bs.locked = "0"
+	end
+	return bs
+end
+
+-- the unlock horizons (a day, 30 days and a year, in seconds) are recomputed from the locked outputs,
+-- and dropped if no outputs are locked
+local function updateHorizons()
+	local horizons, n = {asOf = now, day = "0", month = "0", year = "0", later = "0"}, 0
+	for _, output in pairs(locked.outputs) do
+		local remaining, horizon = output.lockedUntil - now, "later"
+		if remaining < 86400 then
+			horizon = "day"
+		elseif remaining < 2592000 then
+			horizon = "month"
+		elseif remaining < 31536000 then
+			horizon = "year"
+		end
+		horizons[horizon] = decimalAdd(horizons[horizon], output.amount)
+		n = n + 1
+	end
+	if n > 0 then
+		locked.horizons = horizons
+	else
+		locked.horizons = nil
+	end
+end
+
+local applied = 0
+local i = 3
+while i <= #ARGV do
+	local op = ARGV[i]
+	if op == "unlocked+" then
+		balance.unlocked = decimalAdd(balance.unlocked, ARGV[i+1])
+		applied = applied + 1
+		i = i + 2
+	elseif op == "unlocked-" then
+		local unlocked = decimalSub(balance.unlocked, ARGV[i+1])
+		if not unlocked then
+			return redis.error_reply("unlocked balance " .. balance.unlocked .. " of " .. key .. "#" .. field .. " is less than " .. ARGV[i+1])
+		end
+		balance.unlocked = unlocked
+		applied = applied + 1
+		i = i + 2
+	elseif op == "lock" then
+		local id = ARGV[i+1]
+		if locked.outputs[id] then
+			return redis.error_reply("trying to add existing locked coin output " .. id)
+		end
+		local description = cjson.null
+		if ARGV[i+4] ~= "" then
+			description = walletDescription(ARGV[i+4])
+		end
+		local output = {amount = ARGV[i+2], lockedUntil = tonumber(ARGV[i+3]), description = description}
+		if ARGV[i+5] ~= "" then
+			output.reason = ARGV[i+5]
+		end
+		locked.outputs[id] = output
+		locked.total = decimalAdd(locked.total, ARGV[i+2])
+		applied = applied + 1
+		i = i + 6
+	elseif op == "unlock" then
+		local id = ARGV[i+1]
+		local output = locked.outputs[id]
+		if not output then
+			return redis.error_reply("trying to remove non-existing locked coin output " .. id)
+		end
+		locked.outputs[id] = nil
+		locked.total = decimalSub(locked.total, output.amount) or "0"
+		applied = applied + 1
+		i = i + 2
+	elseif op == "msaddress" then
+		if type(wallet.multisignaddresses) ~= "table" then
+			wallet.multisignaddresses = {}
+		end
+		local known = false
+		for _, address in ipairs(wallet.multisignaddresses) do
+			if address == ARGV[i+1] then
+				known = true
+				break
+			end
+		end
+		if not known then
+			table.insert(wallet.multisignaddresses, ARGV[i+1])
+			applied = applied + 1
+		end
+		i = i + 2
+	elseif op == "msdata" then
+		local n = tonumber(ARGV[i+2])
+		if type(wallet.multisign.owners) ~= "table" or #wallet.multisign.owners == 0 then
+			local owners = {}
+			for j = 1, n do
+				owners[j] = ARGV[i+2+j]
+			end
+			wallet.multisign = {owners = owners, signaturesRequired = tonumber(ARGV[i+1])}
+			applied = applied + 1
+		end
+		i = i + 3 + n
+	elseif op == "bs.unlocked+" or op == "bs.locked+" then
+		local bs, name = blockStakes(), op:sub(4, -2)
+		bs[name] = decimalAdd(bs[name], ARGV[i+1])
+		applied = applied + 1
+		i = i + 2
+	elseif op == "bs.unlocked-" or op == "bs.locked-" then
+		local bs, name = blockStakes(), op:sub(4, -2)
+		local value = decimalSub(bs[name], ARGV[i+1])
+		if not value then
+			return redis.error_reply(name .. " block stake balance " .. bs[name] .. " of " .. key .. "#" .. field .. " is less than " .. ARGV[i+1])
+		end
+		bs[name] = value
+		applied = applied + 1
+		i = i + 2
+	else
+		return redis.error_reply("unknown wallet operation " .. tostring(op))
+	end
+end
+
+if applied > 0 then
+	updateHorizons()
+	-- the wallet is stored in the latest structure, see WalletVersion
+	wallet.version = 1
+	redis.call("HSET", key, field, encodeWallet(wallet))
+end
+return applied
+`
+
+// addressPrefixStatsScriptSource is the source of the address prefix stats script,
+// which applies the (signed) deltas to the (encoded) balance of the address prefixes stored under the key it is given.
+// The arguments are groups of 3, each defining a prefix, and its unlocked and locked delta.
+const addressPrefixStatsScriptSource = luaDecimalFunctions + `
+local key = KEYS[1]
+local n = 0
+for i = 1, #ARGV, 3 do
+	local stats = {unlocked = "0", locked = "0"}
+	local value = redis.call("HGET", key, ARGV[i])
+	if value then
+		stats = decode(value)
+	end
+	stats.unlocked = decimalApply(stats.unlocked or "0", ARGV[i+1])
+	stats.locked = decimalApply(stats.locked or "0", ARGV[i+2])
+	redis.call("HSET", key, ARGV[i], encode(stats))
+	n = n + 1
+end
+return n
+`
+
+// counterpartiesScriptSource is the source of the counterparties script, which trims the counterparties of an address,
+// scored by their transaction count in the sorted set (KEYS[1]) and stored in the hash (KEYS[2]) it is given,
+// to the ARGV[1] most frequent ones. Returns the amount of counterparties trimmed.
+const counterpartiesScriptSource = `
+local trimmed = redis.call("ZRANGE", KEYS[1], 0, -(tonumber(ARGV[1]) + 1))
+if #trimmed > 0 then
+	redis.call("ZREM", KEYS[1], unpack(trimmed))
+	redis.call("HDEL", KEYS[2], unpack(trimmed))
+end
+return #trimmed
+`
+
+// richListScriptSource is the source of the rich list script, which ranks the address (ARGV[2]) of the (encoded) wallet
+// stored under the field (ARGV[1]) of the (a:<prefix>) key (KEYS[1]) it is given in the rich list (KEYS[2]),
+// by its coin balance, and moves it to the bucket of its new balance in the balance distribution (KEYS[3]).
+// The remaining arguments are the label of each bucket, followed by the (exclusive) upper bound of its balance,
+// except for the last bucket. Just like the rich list itself, the buckets are bounded using floating point numbers.
+// Returns 1 if the address is ranked, 0 if it is removed from the rich list as its balance is zero.
+const richListScriptSource = luaDecimalFunctions + `
+local key, field, address = KEYS[1], ARGV[1], ARGV[2]
+
+local function bucket(score)
+	local balance, i = tonumber(score), 3
+	while i < #ARGV do
+		if balance < tonumber(ARGV[i+1]) then
+			return ARGV[i]
+		end
+		i = i + 2
+	end
+	return ARGV[i]
+end
+
+local total = "0"
+local value = redis.call("HGET", key, field)
+if value then
+	local balance = decodeWallet(value).balance
+	if type(balance) == "table" then
+		if type(balance.unlocked) == "string" then
+			total = decimalAdd(total, balance.unlocked)
+		end
+		if type(balance.locked) == "table" and type(balance.locked.total) == "string" then
+			total = decimalAdd(total, balance.locked.total)
+		end
+	end
+end
+
+local previous, newBucket = redis.call("ZSCORE", KEYS[2], address), nil
+if total == "0" then
+	redis.call("ZREM", KEYS[2], address)
+else
+	redis.call("ZADD", KEYS[2], total, address)
+	newBucket = bucket(redis.call("ZSCORE", KEYS[2], address))
+end
+local oldBucket = nil
+if previous then
+	oldBucket = bucket(previous)
+end
+if oldBucket ~= newBucket then
+	if oldBucket then
+		redis.call("HINCRBY", KEYS[3], oldBucket, -1)
+	end
+	if newBucket then
+		redis.call("HINCRBY", KEYS[3], newBucket, 1)
+	end
+end
+if newBucket then
+	return 1
+end
+return 0
+`
+
+// addressCountScriptSource is the source of the address count script,
+// which sets the address count (KEYS[2]) to the cardinality of the addresses SET (KEYS[1]). Returns the address count.
+const addressCountScriptSource = `
+local n = redis.call("SCARD", KEYS[1])
+redis.call("SET", KEYS[2], n)
+return n
+`
+
+// luaCodecFunctions returns the Lua functions used by the scripts to decode and encode the stored values,
+// using the given encoding. The cjson.null values used by the scripts are encoded as MessagePack nil values.
+// Wallets are decoded and encoded using the decodeWallet and encodeWallet functions,
+// and the (base64-encoded) description of a locked output is converted using the walletDescription function.
+func luaCodecFunctions(et EncodingType) string {
+	switch et {
+	case EncodingTypeMsgPack:
+		return `
+local decode, encode = cmsgpack.unpack, cmsgpack.pack
+local decodeWallet, encodeWallet = decode, encode
+local function walletDescription(description) return description end
+`
+	case EncodingTypeProtobuf:
+		return `
+local decode, encode = cmsgpack.unpack, cmsgpack.pack
+` + luaProtobufWalletFunctions
+	default:
+		return `
+local decode, encode = cjson.decode, cjson.encode
+local decodeWallet, encodeWallet = decode, encode
+local function walletDescription(description) return description end
+`
+	}
+}
+
+// luaProtobufWalletFunctions defines the Lua functions used to decode and encode wallets
+// as rexplorer.StoredWallet messages (see protoStoredWallet), into (and from) the same table
+// as the one decoded from their JSON representation, except that the description of a locked output
+// is stored as raw bytes rather than base64-encoded. The unlock horizons are not decoded,
+// as the wallet script recomputes them each time it stores a wallet.
+const luaProtobufWalletFunctions = `
+local function protoVarint(s, pos)
+	local n, mul = 0, 1
+	while true do
+		local b = s:byte(pos)
+		if not b then
+			error("truncated protobuf message")
+		end
+		pos = pos + 1
+		n = n + (b % 128) * mul
+		if b < 128 then
+			return n, pos
+		end
+		mul = mul * 128
+	end
+end
+
+-- protoFields calls fn with the field number and value of each (varint or length-delimited) field of a message
+local function protoFields(s, fn)
+	local pos = 1
+	while pos <= #s do
+		local tag, value, n
+		tag, pos = protoVarint(s, pos)
+		local field, wireType = math.floor(tag / 8), tag % 8
+		if wireType == 0 then
+			value, pos = protoVarint(s, pos)
+		elseif wireType == 2 then
+			n, pos = protoVarint(s, pos)
+			value, pos = s:sub(pos, pos + n - 1), pos + n
+		elseif wireType == 1 then
+			pos = pos + 8
+		elseif wireType == 5 then
+			pos = pos + 4
+		else
+			error("unsupported protobuf wire type " .. wireType)
+		end
+		if value ~= nil then
+			fn(field, value)
+		end
+	end
+end
+
+local function protoEncodeVarint(n)
+	local bytes = {}
+	while n >= 128 do
+		bytes[#bytes+1] = string.char(n % 128 + 128)
+		n = math.floor(n / 128)
+	end
+	bytes[#bytes+1] = string.char(n)
+	return table.concat(bytes)
+end
+
+-- protoAppend appends a field to a message, omitting zero numbers and empty (or non-string) values
+local function protoAppend(msg, field, value)
+	if type(value) == "number" then
+		if value ~= 0 then
+			msg[#msg+1] = protoEncodeVarint(field * 8) .. protoEncodeVarint(value)
+		end
+	elseif type(value) == "string" and value ~= "" then
+		msg[#msg+1] = protoEncodeVarint(field * 8 + 2) .. protoEncodeVarint(#value) .. value
+	end
+end
+
+-- protoAppendMessage appends an embedded message field (even if empty) to a message
+local function protoAppendMessage(msg, field, value)
+	local s = table.concat(value)
+	msg[#msg+1] = protoEncodeVarint(field * 8 + 2) .. protoEncodeVarint(#s) .. s
+end
+
+local function decodeWallet(value)
+	local wallet = {}
+	protoFields(value, function(field, value)
+		if field == 1 then
+			wallet.version = value
+		elseif field == 2 then
+			local balance = {locked = {outputs = {}}}
+			protoFields(value, function(field, value)
+				if field == 1 then
+					balance.unlocked = value
+				elseif field == 2 then
+					protoFields(value, function(field, value)
+						if field == 1 then
+							balance.locked.total = value
+						elseif field == 2 then
+							local id, output = "", {amount = "0", lockedUntil = 0, description = cjson.null}
+							protoFields(value, function(field, value)
+								if field == 1 then
+									id = value
+								elseif field == 2 then
+									output.amount = value
+								elseif field == 3 then
+									output.lockedUntil = value
+								elseif field == 4 then
+									output.description = value
+								elseif field == 5 then
+									output.reason = value
+								end
+							end)
+							balance.locked.outputs[id] = output
+						end
+					end)
+				end
+			end)
+			wallet.balance = balance
+		elseif field == 3 then
+			if type(wallet.multisignaddresses) ~= "table" then
+				wallet.multisignaddresses = {}
+			end
+			table.insert(wallet.multisignaddresses, value)
+		elseif field == 4 then
+			local multisign = {owners = {}, signaturesRequired = 0}
+			protoFields(value, function(field, value)
+				if field == 1 then
+					table.insert(multisign.owners, value)
+				elseif field == 2 then
+					multisign.signaturesRequired = value
+				end
+			end)
+			wallet.multisign = multisign
+		elseif field == 5 then
+			local bs = {}
+			protoFields(value, function(field, value)
+				if field == 1 then
+					bs.unlocked = value
+				elseif field == 2 then
+					bs.locked = value
+				end
+			end)
+			wallet.blockstakes = bs
+		end
+	end)
+	return wallet
+end
+
+local function encodeWallet(wallet)
+	local locked = {}
+	protoAppend(locked, 1, wallet.balance.locked.total)
+	local ids = {}
+	for id in pairs(wallet.balance.locked.outputs) do
+		ids[#ids+1] = id
+	end
+	table.sort(ids)
+	for _, id in ipairs(ids) do
+		local output, msg = wallet.balance.locked.outputs[id], {}
+		protoAppend(msg, 1, id)
+		protoAppend(msg, 2, output.amount)
+		protoAppend(msg, 3, output.lockedUntil)
+		protoAppend(msg, 4, output.description)
+		protoAppend(msg, 5, output.reason)
+		protoAppendMessage(locked, 2, msg)
+	end
+	local horizons = wallet.balance.locked.horizons
+	if type(horizons) == "table" then
+		local msg = {}
+		protoAppend(msg, 1, horizons.asOf)
+		protoAppend(msg, 2, horizons.day)
+		protoAppend(msg, 3, horizons.month)
+		protoAppend(msg, 4, horizons.year)
+		protoAppend(msg, 5, horizons.later)
+		protoAppendMessage(locked, 3, msg)
+	end
+	local balance = {}
+	protoAppend(balance, 1, wallet.balance.unlocked)
+	protoAppendMessage(balance, 2, locked)
+	local msg = {}
+	protoAppend(msg, 1, wallet.version)
+	protoAppendMessage(msg, 2, balance)
+	if type(wallet.multisignaddresses) == "table" then
+		for _, address in ipairs(wallet.multisignaddresses) do
+			protoAppend(msg, 3, address)
+		end
+	end
+	if type(wallet.multisign.owners) == "table" and #wallet.multisign.owners > 0 then
+		local multisign = {}
+		for _, owner in ipairs(wallet.multisign.owners) do
+			protoAppend(multisign, 1, owner)
+		end
+		protoAppend(multisign, 2, wallet.multisign.signaturesRequired)
+		protoAppendMessage(msg, 4, multisign)
+	end
+	if type(wallet.blockstakes) == "table" then
+		local bs = {}
+		protoAppend(bs, 1, wallet.blockstakes.unlocked)
+		protoAppend(bs, 2, wallet.blockstakes.locked)
+		protoAppendMessage(msg, 5, bs)
+	end
+	return table.concat(msg)
+end
+
+local base64Alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+-- walletDescription decodes the base64-encoded description, as it is stored as raw bytes
+local function walletDescription(description)
+	local bytes, bits, n = {}, 0, 0
+	for c in description:gmatch("[^=]") do
+		local v = base64Alphabet:find(c, 1, true)
+		if v then
+			bits, n = bits * 64 + v - 1, n + 6
+			if n >= 8 then
+				n = n - 8
+				local b = math.floor(bits / 2 ^ n)
+				bytes[#bytes+1] = string.char(b)
+				bits = bits - b * 2 ^ n
+			end
+		end
+	end
+	return table.concat(bytes)
+end
+`
+
+// luaDecimalFunctions defines the Lua functions used to compute with currencies,
+// which are (JSON) encoded as (unsigned) decimal strings of arbitrary size, as Lua numbers would lose precision.
+const luaDecimalFunctions = `
+local function decimalTrim(a)
+	a = a:gsub("^0+", "")
+	if a == "" then
+		return "0"
+	end
+	return a
+end
+
+local function decimalAdd(a, b)
+	local digits, carry = {}, 0
+	local i, j = #a, #b
+	while i > 0 or j > 0 or carry > 0 do
+		local d = carry
+		if i > 0 then
+			d = d + a:byte(i) - 48
+			i = i - 1
+		end
+		if j > 0 then
+			d = d + b:byte(j) - 48
+			j = j - 1
+		end
+		carry = 0
+		if d >= 10 then
+			d, carry = d - 10, 1
+		end
+		digits[#digits+1] = d
+	end
+	return decimalTrim(table.concat(digits):reverse())
+end
+
+-- decimalSub returns nil if b is greater than a
+local function decimalSub(a, b)
+	a, b = decimalTrim(a), decimalTrim(b)
+	if #a < #b or (#a == #b and a < b) then
+		return nil
+	end
+	local digits, borrow = {}, 0
+	local j = #b
+	for i = #a, 1, -1 do
+		local d = a:byte(i) - 48 - borrow
+		if j > 0 then
+			d = d - (b:byte(j) - 48)
+			j = j - 1
+		end
+		borrow = 0
+		if d < 0 then
+			d, borrow = d + 10, 1
+		end
+		digits[#digits+1] = d
+	end
+	return decimalTrim(table.concat(digits):reverse())
+end
+
+-- decimalApply applies a signed delta, clamping the result to 0
+local function decimalApply(a, delta)
+	if delta:sub(1, 1) == "-" then
+		return decimalSub(a, delta:sub(2)) or "0"
+	end
+	return decimalAdd(a, delta)
+end
+`
+
+// addressPrefixDelta is the (signed) change of the balance of an address prefix, not stored yet.
+type addressPrefixDelta struct {
+	unlocked, locked big.Int
+}
+
+// walletOpLockArgs returns the arguments of a walletOpLock operation.
+func walletOpLockArgs(id types.CoinOutputID, output WalletLockedOutput) []interface{} {
+	return []interface{}{
+		walletOpLock, id.String(), output.Amount.String(), uint64(output.LockedUntil),
+		base64.StdEncoding.EncodeToString(output.Description), string(output.Reason),
+	}
+}
+
+// walletOpSetMultisigDataArgs returns the arguments of a walletOpSetMultisigData operation.
+func walletOpSetMultisigDataArgs(owners []types.UnlockHash, signaturesRequired uint64) []interface{} {
+	args := []interface{}{walletOpSetMultisigData, signaturesRequired, len(owners)}
+	for _, owner := range owners {
+		args = append(args, owner.String())
+	}
+	return args
+}
+
+// negated returns the given currency as a negative (big) integer.
+func negated(c types.Currency) *big.Int {
+	return new(big.Int).Neg(c.Big())
+}
+
+// updateWallet atomically applies the given operations to the wallet of the given address using the wallet script,
+// deferring the update if a batch is in progress, and rolls up the given balance deltas (if not nil)
+// into the stats of the prefix of that address.
+func (rdb *RedisDatabase) updateWallet(uh types.UnlockHash, unlocked, locked *big.Int, ops ...interface{}) error {
+	key, field := rdb.getAddressKeyAndField(uh)
+	delta := rdb.addressPrefixDelta(uh)
+	if unlocked != nil {
+		delta.unlocked.Add(&delta.unlocked, unlocked)
+	}
+	if locked != nil {
+		delta.locked.Add(&delta.locked, locked)
+	}
+	args := append([]interface{}{rdb.walletScript.Hash(), 1, key, field, uint64(rdb.networkTime)}, ops...)
+	err := rdb.pipeline.Write("EVALSHA", args...)
+	if err != nil {
+		return fmt.Errorf("redis: failed to update wallet for %s at %s#%s: %v", uh.String(), key, field, err)
+	}
+	// only addresses of which the coin balance changed have to be ranked (again) in the rich list
+	ranked := unlocked != nil || locked != nil
+	if rdb.pipeline.batching {
+		if ranked {
+			rdb.rankAddresses[uh] = struct{}{}
+		}
+		return nil
+	}
+	err = rdb.storeAddressPrefixStats()
+	if err != nil || !ranked {
+		return err
+	}
+	return rdb.rankAddress(uh)
+}
+
+// addressPrefixDelta returns the delta of the stats of the prefix of the given address.
+func (rdb *RedisDatabase) addressPrefixDelta(uh types.UnlockHash) *addressPrefixDelta {
+	prefix := AddressPrefix(uh)
+	delta, ok := rdb.prefixDeltas[prefix]
+	if !ok {
+		delta = new(addressPrefixDelta)
+		rdb.prefixDeltas[prefix] = delta
+	}
+	return delta
+}
+
+// storeAddressPrefixStats applies the deltas of the balance of all address prefixes updated since they were last stored,
+// using a single (atomic) script, deferred if a batch is in progress.
+func (rdb *RedisDatabase) storeAddressPrefixStats() error {
+	if len(rdb.prefixDeltas) == 0 {
+		return nil
+	}
+	args := []interface{}{rdb.addressPrefixStatsScript.Hash(), 1, rdb.key(addressPrefixStatsKey)}
+	for prefix, delta := range rdb.prefixDeltas {
+		args = append(args, prefix, delta.unlocked.String(), delta.locked.String())
+	}
+	rdb.prefixDeltas = make(map[string]*addressPrefixDelta)
+	err := rdb.pipeline.Write("EVALSHA", args...)
+	if err != nil {
+		return fmt.Errorf("redis: failed to update address prefix stats: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package rexplorer
+
+import (
+	"sort"
+
+	"github.com/rivine/rivine/types"
+)
+
+// DefaultRichListSize is the amount of addresses listed by the richlist command, unless specified otherwise.
+const DefaultRichListSize = 100
+
+// RichListEntry is a single address of the rich list, together with its (unlocked and locked) balance.
+type RichListEntry struct {
+	Address types.UnlockHash `json:"address"`
+	Balance SnapshotBalance  `json:"balance"`
+}
+
+// sortRichList orders the given entries by their total balance, highest first,
+// ordering entries with an equal balance by their address, such that the order is deterministic.
+func sortRichList(entries []RichListEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if c := entries[i].Balance.Total().Cmp(entries[j].Balance.Total()); c != 0 {
+			return c > 0
+		}
+		return entries[i].Address.Cmp(entries[j].Address) < 0
+	})
+}
@@ -0,0 +1,148 @@
+package rexplorer
+
+import (
+	"fmt"
+
+	"github.com/rivine/rivine/types"
+)
+
+// rollingStatsWindows lists the rolling windows within which the network activity is counted, shortest first,
+// see RollingStats.
+var rollingStatsWindows = []struct {
+	label   string
+	seconds types.Timestamp
+}{
+	{"24h", 24 * 60 * 60},
+	{"7d", 7 * 24 * 60 * 60},
+}
+
+// RollingStats counts the blocks, transactions, the value transferred, the fees and the new addresses
+// (see DailyStats) of all blocks created within a rolling window (e.g. the last 24 hours) up to the latest block,
+// such that dashboards can show the network activity in near real time.
+// The counters are maintained using a record stored per block: each applied block is added,
+// after which the oldest blocks which fell out of the window are subtracted.
+// Since is the height of the oldest block within the window.
+type RollingStats struct {
+	Window           string            `json:"window"`
+	Since            types.BlockHeight `json:"since"`
+	BlockCount       uint64            `json:"blockCount"`
+	TransactionCount uint64            `json:"txCount"`
+	ValueTransferred types.Currency    `json:"valueTransferred"`
+	Fees             types.Currency    `json:"fees"`
+	NewAddresses     uint64            `json:"newAddresses"`
+}
+
+// rollingStatsBlock is the record stored per applied block, used to subtract the block once it fell out of a window,
+// and to restore the rolling stats it replaced once it is reverted.
+type rollingStatsBlock struct {
+	Timestamp types.Timestamp `json:"timestamp"`
+	Stats     RollingStats    `json:"stats"`
+	Previous  []RollingStats  `json:"previous"`
+}
+
+// newRollingStats returns the empty rolling stats of each window.
+func newRollingStats() []RollingStats {
+	rolling := make([]RollingStats, 0, len(rollingStatsWindows))
+	for _, window := range rollingStatsWindows {
+		rolling = append(rolling, RollingStats{
+			Window:           window.label,
+			ValueTransferred: types.ZeroCurrency,
+			Fees:             types.ZeroCurrency,
+		})
+	}
+	return rolling
+}
+
+// add returns the sum of both rolling stats, keeping the window and the oldest block of the given stats.
+func (rs RollingStats) add(other RollingStats) RollingStats {
+	rs.BlockCount += other.BlockCount
+	rs.TransactionCount += other.TransactionCount
+	rs.ValueTransferred = rs.ValueTransferred.Add(other.ValueTransferred)
+	rs.Fees = rs.Fees.Add(other.Fees)
+	rs.NewAddresses += other.NewAddresses
+	return rs
+}
+
+// sub returns the given rolling stats minus the other stats, keeping the window and the oldest block of the given stats.
+// The counters and values never drop below zero.
+func (rs RollingStats) sub(other RollingStats) RollingStats {
+	sub := func(a, b uint64) uint64 {
+		if a < b {
+			return 0
+		}
+		return a - b
+	}
+	rs.BlockCount = sub(rs.BlockCount, other.BlockCount)
+	rs.TransactionCount = sub(rs.TransactionCount, other.TransactionCount)
+	rs.ValueTransferred = subCurrencyOrZero(rs.ValueTransferred, other.ValueTransferred)
+	rs.Fees = subCurrencyOrZero(rs.Fees, other.Fees)
+	rs.NewAddresses = sub(rs.NewAddresses, other.NewAddresses)
+	return rs
+}
+
+// rollRollingStats adds the given block applied at the given height to the given rolling stats (of each window),
+// subtracting the oldest blocks created before the window ending at the given block.
+// The record of those blocks is taken using the given function, which returns ErrNotFound if no record is stored,
+// as is the case for blocks applied prior to upgrading an existing database.
+func rollRollingStats(current []RollingStats, height types.BlockHeight, block rollingStatsBlock, getBlock func(types.BlockHeight) (rollingStatsBlock, error)) ([]RollingStats, error) {
+	rolled := newRollingStats()
+	for i, window := range rollingStatsWindows {
+		stats := rolled[i]
+		stats.Since = height
+		if i < len(current) && current[i].BlockCount > 0 {
+			stats = current[i]
+		}
+		stats = stats.add(block.Stats)
+		var cutoff types.Timestamp
+		if block.Timestamp > window.seconds {
+			cutoff = block.Timestamp - window.seconds
+		}
+		for ; stats.Since < height; stats.Since++ {
+			record, err := getBlock(stats.Since)
+			if err == ErrNotFound {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to get rolling stats of block %d: %v", stats.Since, err)
+			}
+			if record.Timestamp > cutoff {
+				break
+			}
+			stats = stats.sub(record.Stats)
+		}
+		rolled[i] = stats
+	}
+	return rolled, nil
+}
+
+// applyRollingStats adds the given stats of the block applied at the current block height to the rolling stats,
+// in case the database supports it.
+func (explorer *Explorer) applyRollingStats(timestamp types.Timestamp, daily DailyStats) {
+	rsdb, ok := explorer.db.(RollingStatsDatabase)
+	if !ok {
+		return
+	}
+	err := rsdb.ApplyRollingStats(explorer.stats.BlockHeight, timestamp, RollingStats{
+		BlockCount:       daily.BlockCount,
+		TransactionCount: daily.TransactionCount,
+		ValueTransferred: daily.ValueTransferred,
+		Fees:             daily.Fees,
+		NewAddresses:     daily.NewAddresses,
+	})
+	if err != nil {
+		panic(fmt.Sprintf("failed to apply block %d to the rolling stats: %v", explorer.stats.BlockHeight, err))
+	}
+}
+
+// revertRollingStats restores the rolling stats as they were prior to applying the block reverted
+// at the current block height, in case the database supports it.
+func (explorer *Explorer) revertRollingStats() {
+	rsdb, ok := explorer.db.(RollingStatsDatabase)
+	if !ok {
+		return
+	}
+	err := rsdb.RevertRollingStats(explorer.stats.BlockHeight)
+	if err != nil {
+		panic(fmt.Sprintf("failed to revert block %d from the rolling stats: %v", explorer.stats.BlockHeight, err))
+	}
+}
@@ -0,0 +1,532 @@
+package rexplorer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/rivine/rivine/crypto"
+	"github.com/rivine/rivine/types"
+)
+
+// RosettaVersion is the version of the Rosetta specification implemented by RosettaAPI.
+const RosettaVersion = "1.4.13"
+
+// rosettaMaxRequestSize is the maximum size of the body of a Rosetta request.
+const rosettaMaxRequestSize = 1 << 16
+
+// The operation types of the Rosetta transactions, all operations having the rosettaStatusSuccess status.
+const (
+	// rosettaOperationInput spends a coin output, debiting its owner
+	rosettaOperationInput = "INPUT"
+	// rosettaOperationOutput creates a coin output, crediting its owner
+	rosettaOperationOutput = "OUTPUT"
+	// rosettaOperationMinerPayout creates a miner payout, crediting its owner
+	rosettaOperationMinerPayout = "MINER_PAYOUT"
+
+	rosettaStatusSuccess = "SUCCESS"
+)
+
+// rosettaErrors are all errors returned by the RosettaAPI, as listed by its /network/options endpoint.
+var (
+	rosettaErrUnknownNetwork      = &rosettaError{Code: 1, Message: "unknown network"}
+	rosettaErrInvalidRequest      = &rosettaError{Code: 2, Message: "invalid request"}
+	rosettaErrBlockNotFound       = &rosettaError{Code: 3, Message: "block not found"}
+	rosettaErrHistoricalBalance   = &rosettaError{Code: 4, Message: "historical balance lookups are not supported"}
+	rosettaErrInvalidAddress      = &rosettaError{Code: 5, Message: "invalid address"}
+	rosettaErrUnsupportedDatabase = &rosettaError{Code: 6, Message: "blocks and transactions are not stored by the database driver"}
+	rosettaErrInternal            = &rosettaError{Code: 7, Message: "internal error", Retriable: true}
+	rosettaErrUnknownEndpoint     = &rosettaError{Code: 8, Message: "unknown endpoint"}
+	rosettaErrors                 = []*rosettaError{
+		rosettaErrUnknownNetwork, rosettaErrInvalidRequest, rosettaErrBlockNotFound, rosettaErrHistoricalBalance,
+		rosettaErrInvalidAddress, rosettaErrUnsupportedDatabase, rosettaErrInternal, rosettaErrUnknownEndpoint,
+	}
+)
+
+// RosettaAPI implements the (read-only) Data API of the Rosetta specification (see https://www.rosetta-api.org),
+// on top of the data stored in a Database, such that exchanges can integrate the chain using their standard tooling.
+// Following (POST) endpoints are served:
+//
+//	/network/list     the (single) network of the explorer
+//	/network/options  the version, operation types, statuses and errors of the API
+//	/network/status   the current and genesis block
+//	/block            a block, by index (height) and/or hash (ID), the current block if neither is given
+//	/account/balance  the current coin balance of an address
+//
+// Each transaction of a block is represented by an INPUT operation for every coin output it spends,
+// and an OUTPUT operation for every coin output it creates, the miner payouts of a block being represented
+// as MINER_PAYOUT operations of an additional transaction, identified by the ID of the block.
+// The balance of an address includes its locked coins, as the coin outputs are credited when created,
+// regardless of their lock. Blocks can only be served if the database stores
+// the block and transaction records (see BlockDatabase and TransactionDatabase).
+type RosettaAPI struct {
+	db       Database
+	mut      sync.Locker
+	network  rosettaNetworkIdentifier
+	currency rosettaCurrency
+}
+
+// NewRosettaAPI creates a RosettaAPI serving the data stored in the given database, explored for the given chain.
+// The given locker (if not nil) is held while reading the database,
+// such that the API only observes the data stored by entire consensus changes.
+func NewRosettaAPI(db Database, mut sync.Locker, bcInfo types.BlockchainInfo, chainCts types.ChainConstants) *RosettaAPI {
+	return &RosettaAPI{
+		db:      db,
+		mut:     mut,
+		network: rosettaNetworkIdentifier{Blockchain: bcInfo.Name, Network: bcInfo.NetworkName},
+		currency: rosettaCurrency{
+			Symbol: bcInfo.CoinUnit,
+			// one coin is a power of 10 of the smallest unit
+			Decimals: len(chainCts.CurrencyUnits.OneCoin.String()) - 1,
+		},
+	}
+}
+
+// RosettaAPI creates a RosettaAPI serving the data stored by this explorer,
+// reading the database in between the consensus changes it processes.
+func (explorer *Explorer) RosettaAPI() *RosettaAPI {
+	return NewRosettaAPI(explorer.db, &explorer.mut, explorer.bcInfo, explorer.chainCts)
+}
+
+// The (JSON-encoded) objects of the Rosetta Data API, see https://www.rosetta-api.org/docs/api_objects.html
+type (
+	rosettaNetworkIdentifier struct {
+		Blockchain string `json:"blockchain"`
+		Network    string `json:"network"`
+	}
+	rosettaBlockIdentifier struct {
+		Index int64  `json:"index"`
+		Hash  string `json:"hash"`
+	}
+	rosettaPartialBlockIdentifier struct {
+		Index *int64  `json:"index,omitempty"`
+		Hash  *string `json:"hash,omitempty"`
+	}
+	rosettaTransactionIdentifier struct {
+		Hash string `json:"hash"`
+	}
+	rosettaOperationIdentifier struct {
+		Index int64 `json:"index"`
+	}
+	rosettaAccountIdentifier struct {
+		Address string `json:"address"`
+	}
+	rosettaCurrency struct {
+		Symbol   string `json:"symbol"`
+		Decimals int    `json:"decimals"`
+	}
+	rosettaAmount struct {
+		Value    string          `json:"value"`
+		Currency rosettaCurrency `json:"currency"`
+	}
+	rosettaCoinChange struct {
+		CoinIdentifier struct {
+			Identifier string `json:"identifier"`
+		} `json:"coin_identifier"`
+		CoinAction string `json:"coin_action"`
+	}
+	rosettaOperation struct {
+		OperationIdentifier rosettaOperationIdentifier `json:"operation_identifier"`
+		Type                string                     `json:"type"`
+		Status              string                     `json:"status"`
+		Account             rosettaAccountIdentifier   `json:"account"`
+		Amount              rosettaAmount              `json:"amount"`
+		CoinChange          rosettaCoinChange          `json:"coin_change"`
+	}
+	rosettaTransaction struct {
+		TransactionIdentifier rosettaTransactionIdentifier `json:"transaction_identifier"`
+		Operations            []rosettaOperation           `json:"operations"`
+	}
+	rosettaBlock struct {
+		BlockIdentifier       rosettaBlockIdentifier `json:"block_identifier"`
+		ParentBlockIdentifier rosettaBlockIdentifier `json:"parent_block_identifier"`
+		// UNIX epoch timestamp in milliseconds
+		Timestamp    int64                `json:"timestamp"`
+		Transactions []rosettaTransaction `json:"transactions"`
+	}
+	rosettaError struct {
+		Code      int32  `json:"code"`
+		Message   string `json:"message"`
+		Retriable bool   `json:"retriable"`
+		// Details (if any) describe the specific occurrence of the error
+		Details map[string]interface{} `json:"details,omitempty"`
+	}
+)
+
+// The (JSON-encoded) requests and responses of the endpoints of the RosettaAPI.
+type (
+	rosettaNetworkRequest struct {
+		NetworkIdentifier rosettaNetworkIdentifier `json:"network_identifier"`
+	}
+	rosettaNetworkListResponse struct {
+		NetworkIdentifiers []rosettaNetworkIdentifier `json:"network_identifiers"`
+	}
+	rosettaNetworkOptionsResponse struct {
+		Version struct {
+			RosettaVersion    string `json:"rosetta_version"`
+			NodeVersion       string `json:"node_version"`
+			MiddlewareVersion string `json:"middleware_version"`
+		} `json:"version"`
+		Allow struct {
+			OperationStatuses []struct {
+				Status     string `json:"status"`
+				Successful bool   `json:"successful"`
+			} `json:"operation_statuses"`
+			OperationTypes          []string        `json:"operation_types"`
+			Errors                  []*rosettaError `json:"errors"`
+			HistoricalBalanceLookup bool            `json:"historical_balance_lookup"`
+			CallMethods             []string        `json:"call_methods"`
+			BalanceExemptions       []interface{}   `json:"balance_exemptions"`
+			MempoolCoins            bool            `json:"mempool_coins"`
+		} `json:"allow"`
+	}
+	rosettaNetworkStatusResponse struct {
+		CurrentBlockIdentifier rosettaBlockIdentifier `json:"current_block_identifier"`
+		CurrentBlockTimestamp  int64                  `json:"current_block_timestamp"`
+		GenesisBlockIdentifier rosettaBlockIdentifier `json:"genesis_block_identifier"`
+		Peers                  []interface{}          `json:"peers"`
+	}
+	rosettaBlockRequest struct {
+		NetworkIdentifier rosettaNetworkIdentifier      `json:"network_identifier"`
+		BlockIdentifier   rosettaPartialBlockIdentifier `json:"block_identifier"`
+	}
+	rosettaBlockResponse struct {
+		Block rosettaBlock `json:"block"`
+	}
+	rosettaAccountBalanceRequest struct {
+		NetworkIdentifier rosettaNetworkIdentifier       `json:"network_identifier"`
+		AccountIdentifier rosettaAccountIdentifier       `json:"account_identifier"`
+		BlockIdentifier   *rosettaPartialBlockIdentifier `json:"block_identifier,omitempty"`
+	}
+	rosettaAccountBalanceResponse struct {
+		BlockIdentifier rosettaBlockIdentifier `json:"block_identifier"`
+		Balances        []rosettaAmount        `json:"balances"`
+		Metadata        struct {
+			Unlocked types.Currency `json:"unlocked"`
+			Locked   types.Currency `json:"locked"`
+		} `json:"metadata"`
+	}
+)
+
+// ServeHTTP implements http.Handler.ServeHTTP
+func (api *RosettaAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		api.writeError(w, http.StatusMethodNotAllowed, rosettaErrInvalidRequest.with("method %s not allowed", r.Method))
+		return
+	}
+	var (
+		response interface{}
+		err      *rosettaError
+	)
+	body := io.LimitReader(r.Body, rosettaMaxRequestSize)
+	switch strings.TrimSuffix(r.URL.Path, "/") {
+	case "/network/list":
+		response, err = api.networkList()
+	case "/network/options":
+		var request rosettaNetworkRequest
+		if err = decodeRosettaRequest(body, &request); err == nil {
+			response, err = api.networkOptions(request)
+		}
+	case "/network/status":
+		var request rosettaNetworkRequest
+		if err = decodeRosettaRequest(body, &request); err == nil {
+			response, err = api.networkStatus(request)
+		}
+	case "/block":
+		var request rosettaBlockRequest
+		if err = decodeRosettaRequest(body, &request); err == nil {
+			response, err = api.block(request)
+		}
+	case "/account/balance":
+		var request rosettaAccountBalanceRequest
+		if err = decodeRosettaRequest(body, &request); err == nil {
+			response, err = api.accountBalance(request)
+		}
+	default:
+		err = rosettaErrUnknownEndpoint.with("%s is not served", r.URL.Path)
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err.Code == rosettaErrUnknownEndpoint.Code {
+			status = http.StatusNotFound
+		}
+		api.writeError(w, status, err)
+		return
+	}
+	writeRosettaResponse(w, http.StatusOK, response)
+}
+
+func (api *RosettaAPI) networkList() (interface{}, *rosettaError) {
+	return rosettaNetworkListResponse{NetworkIdentifiers: []rosettaNetworkIdentifier{api.network}}, nil
+}
+
+func (api *RosettaAPI) networkOptions(request rosettaNetworkRequest) (interface{}, *rosettaError) {
+	if err := api.checkNetwork(request.NetworkIdentifier); err != nil {
+		return nil, err
+	}
+	var response rosettaNetworkOptionsResponse
+	response.Version.RosettaVersion = RosettaVersion
+	response.Version.NodeVersion = version.String()
+	response.Version.MiddlewareVersion = version.String()
+	response.Allow.OperationStatuses = append(response.Allow.OperationStatuses, struct {
+		Status     string `json:"status"`
+		Successful bool   `json:"successful"`
+	}{Status: rosettaStatusSuccess, Successful: true})
+	response.Allow.OperationTypes = []string{rosettaOperationInput, rosettaOperationOutput, rosettaOperationMinerPayout}
+	response.Allow.Errors = rosettaErrors
+	response.Allow.CallMethods = []string{}
+	response.Allow.BalanceExemptions = []interface{}{}
+	return response, nil
+}
+
+func (api *RosettaAPI) networkStatus(request rosettaNetworkRequest) (interface{}, *rosettaError) {
+	if err := api.checkNetwork(request.NetworkIdentifier); err != nil {
+		return nil, err
+	}
+	bdb, ok := api.db.(BlockDatabase)
+	if !ok {
+		return nil, rosettaErrUnsupportedDatabase
+	}
+	api.lock()
+	defer api.unlock()
+	current, err := api.currentBlockRecord(bdb)
+	if err != nil {
+		return nil, err
+	}
+	genesis, gerr := bdb.GetBlockRecord(0)
+	if gerr != nil {
+		return nil, rosettaErrInternal.with("failed to get genesis block: %v", gerr)
+	}
+	return rosettaNetworkStatusResponse{
+		CurrentBlockIdentifier: newRosettaBlockIdentifier(current.Height, current.ID),
+		CurrentBlockTimestamp:  int64(current.Timestamp) * 1000,
+		GenesisBlockIdentifier: newRosettaBlockIdentifier(genesis.Height, genesis.ID),
+		Peers:                  []interface{}{},
+	}, nil
+}
+
+func (api *RosettaAPI) block(request rosettaBlockRequest) (interface{}, *rosettaError) {
+	if err := api.checkNetwork(request.NetworkIdentifier); err != nil {
+		return nil, err
+	}
+	bdb, ok := api.db.(BlockDatabase)
+	if !ok {
+		return nil, rosettaErrUnsupportedDatabase
+	}
+	txdb, ok := api.db.(TransactionDatabase)
+	if !ok {
+		return nil, rosettaErrUnsupportedDatabase
+	}
+	api.lock()
+	defer api.unlock()
+
+	var (
+		record BlockRecord
+		err    error
+	)
+	id := request.BlockIdentifier
+	switch {
+	case id.Hash != nil:
+		var blockID types.BlockID
+		err = (*crypto.Hash)(&blockID).LoadString(*id.Hash)
+		if err != nil {
+			return nil, rosettaErrInvalidRequest.with("invalid block hash %q: %v", *id.Hash, err)
+		}
+		record, err = bdb.GetBlockRecordByID(blockID)
+		if err == nil && id.Index != nil && int64(record.Height) != *id.Index {
+			return nil, rosettaErrBlockNotFound.with("block %s is not at index %d", *id.Hash, *id.Index)
+		}
+	case id.Index != nil:
+		if *id.Index < 0 {
+			return nil, rosettaErrInvalidRequest.with("invalid block index %d", *id.Index)
+		}
+		record, err = bdb.GetBlockRecord(types.BlockHeight(*id.Index))
+	default:
+		var rerr *rosettaError
+		record, rerr = api.currentBlockRecord(bdb)
+		if rerr != nil {
+			return nil, rerr
+		}
+	}
+	switch err {
+	case nil:
+	case ErrNotFound:
+		return nil, rosettaErrBlockNotFound
+	default:
+		return nil, rosettaErrInternal.with("failed to get block: %v", err)
+	}
+
+	block := rosettaBlock{
+		BlockIdentifier:       newRosettaBlockIdentifier(record.Height, record.ID),
+		ParentBlockIdentifier: newRosettaBlockIdentifier(record.Height, record.ID),
+		Timestamp:             int64(record.Timestamp) * 1000,
+		Transactions:          make([]rosettaTransaction, 0, len(record.TransactionIDs)+1),
+	}
+	if record.Height > 0 {
+		// the parent of the genesis block is the genesis block itself, as required by the specification
+		block.ParentBlockIdentifier = newRosettaBlockIdentifier(record.Height-1, record.ParentID)
+	}
+	if len(record.MinerPayouts) > 0 {
+		tx := rosettaTransaction{TransactionIdentifier: rosettaTransactionIdentifier{Hash: record.ID.String()}}
+		for _, payout := range record.MinerPayouts {
+			tx.Operations = append(tx.Operations, api.newOperation(
+				len(tx.Operations), rosettaOperationMinerPayout, payout.UnlockHash, payout.Value, false, payout.ID))
+		}
+		block.Transactions = append(block.Transactions, tx)
+	}
+	for _, txID := range record.TransactionIDs {
+		txRecord, err := txdb.GetTransactionRecord(txID)
+		if err != nil {
+			return nil, rosettaErrInternal.with("failed to get tx %s: %v", txID.String(), err)
+		}
+		tx := rosettaTransaction{
+			TransactionIdentifier: rosettaTransactionIdentifier{Hash: txID.String()},
+			Operations:            []rosettaOperation{},
+		}
+		for _, ci := range txRecord.CoinInputs {
+			tx.Operations = append(tx.Operations, api.newOperation(
+				len(tx.Operations), rosettaOperationInput, ci.UnlockHash, ci.Value, true, ci.ParentID))
+		}
+		for _, co := range txRecord.CoinOutputs {
+			tx.Operations = append(tx.Operations, api.newOperation(
+				len(tx.Operations), rosettaOperationOutput, co.Condition.UnlockHash(), co.Value, false, co.ID))
+		}
+		block.Transactions = append(block.Transactions, tx)
+	}
+	return rosettaBlockResponse{Block: block}, nil
+}
+
+func (api *RosettaAPI) accountBalance(request rosettaAccountBalanceRequest) (interface{}, *rosettaError) {
+	if err := api.checkNetwork(request.NetworkIdentifier); err != nil {
+		return nil, err
+	}
+	var address types.UnlockHash
+	err := address.LoadString(request.AccountIdentifier.Address)
+	if err != nil {
+		return nil, rosettaErrInvalidAddress.with("invalid address %q: %v", request.AccountIdentifier.Address, err)
+	}
+	bdb, ok := api.db.(BlockDatabase)
+	if !ok {
+		return nil, rosettaErrUnsupportedDatabase
+	}
+	api.lock()
+	defer api.unlock()
+	current, rerr := api.currentBlockRecord(bdb)
+	if rerr != nil {
+		return nil, rerr
+	}
+	if id := request.BlockIdentifier; id != nil {
+		// only the balance as of the current block is stored
+		if (id.Index != nil && *id.Index != int64(current.Height)) || (id.Hash != nil && *id.Hash != current.ID.String()) {
+			return nil, rosettaErrHistoricalBalance
+		}
+	}
+	wallet, err := api.db.GetWallet(address)
+	if err != nil && err != ErrNotFound {
+		return nil, rosettaErrInternal.with("failed to get wallet %s: %v", address.String(), err)
+	}
+	response := rosettaAccountBalanceResponse{BlockIdentifier: newRosettaBlockIdentifier(current.Height, current.ID)}
+	response.Metadata.Unlocked = wallet.Balance.Unlocked
+	response.Metadata.Locked = wallet.Balance.Locked.Total
+	response.Balances = []rosettaAmount{{
+		Value:    wallet.Balance.Unlocked.Add(wallet.Balance.Locked.Total).String(),
+		Currency: api.currency,
+	}}
+	return response, nil
+}
+
+// checkNetwork ensures the given network identifier identifies the network of the explorer.
+func (api *RosettaAPI) checkNetwork(network rosettaNetworkIdentifier) *rosettaError {
+	if network != api.network {
+		return rosettaErrUnknownNetwork.with("expected network %s/%s", api.network.Blockchain, api.network.Network)
+	}
+	return nil
+}
+
+// currentBlockRecord returns the record of the current block, to be called while holding the lock.
+func (api *RosettaAPI) currentBlockRecord(bdb BlockDatabase) (BlockRecord, *rosettaError) {
+	stats, err := bdb.GetNetworkStats()
+	if err != nil {
+		return BlockRecord{}, rosettaErrInternal.with("failed to get network stats: %v", err)
+	}
+	record, err := bdb.GetBlockRecord(stats.BlockHeight)
+	switch err {
+	case nil:
+		return record, nil
+	case ErrNotFound:
+		// no block has been applied yet
+		return BlockRecord{}, rosettaErrBlockNotFound.with("no block has been explored yet")
+	default:
+		return BlockRecord{}, rosettaErrInternal.with("failed to get block %d: %v", stats.BlockHeight, err)
+	}
+}
+
+// newOperation creates the operation with the given index and type, crediting (or debiting if spent)
+// the given value to the given address, by creating (or spending) the coin output with the given ID.
+func (api *RosettaAPI) newOperation(index int, typ string, address types.UnlockHash, value types.Currency, spent bool, id types.CoinOutputID) rosettaOperation {
+	op := rosettaOperation{
+		OperationIdentifier: rosettaOperationIdentifier{Index: int64(index)},
+		Type:                typ,
+		Status:              rosettaStatusSuccess,
+		Account:             rosettaAccountIdentifier{Address: address.String()},
+		Amount:              rosettaAmount{Value: value.String(), Currency: api.currency},
+	}
+	op.CoinChange.CoinIdentifier.Identifier = id.String()
+	op.CoinChange.CoinAction = "coin_created"
+	if spent {
+		op.Amount.Value = "-" + op.Amount.Value
+		op.CoinChange.CoinAction = "coin_spent"
+	}
+	return op
+}
+
+func newRosettaBlockIdentifier(height types.BlockHeight, id types.BlockID) rosettaBlockIdentifier {
+	return rosettaBlockIdentifier{Index: int64(height), Hash: id.String()}
+}
+
+// with returns a copy of the error, of which the details describe the specific occurrence of the error.
+func (err *rosettaError) with(format string, args ...interface{}) *rosettaError {
+	e := *err
+	e.Details = map[string]interface{}{"error": fmt.Sprintf(format, args...)}
+	return &e
+}
+
+// decodeRosettaRequest decodes the JSON-encoded request read from the given body.
+func decodeRosettaRequest(body io.Reader, request interface{}) *rosettaError {
+	err := json.NewDecoder(body).Decode(request)
+	if err != nil {
+		return rosettaErrInvalidRequest.with("invalid request: %v", err)
+	}
+	return nil
+}
+
+func (api *RosettaAPI) lock() {
+	if api.mut != nil {
+		api.mut.Lock()
+	}
+}
+
+func (api *RosettaAPI) unlock() {
+	if api.mut != nil {
+		api.mut.Unlock()
+	}
+}
+
+func (api *RosettaAPI) writeError(w http.ResponseWriter, status int, err *rosettaError) {
+	writeRosettaResponse(w, status, err)
+}
+
+func writeRosettaResponse(w http.ResponseWriter, status int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		status = http.StatusInternalServerError
+		b, _ = json.Marshal(rosettaErrInternal.with("failed to JSON-encode response: %v", err))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(b)
+}
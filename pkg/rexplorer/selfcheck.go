@@ -0,0 +1,242 @@
+package rexplorer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rivine/rivine/crypto"
+	"github.com/rivine/rivine/modules"
+	"github.com/rivine/rivine/types"
+)
+
+// DefaultSelfCheckSampleSize is the default amount of wallets verified by the startup self-check.
+const DefaultSelfCheckSampleSize = 100
+
+// SelfCheckError is returned by SelfCheck in case the stored data is found to be corrupt,
+// listing all problems found.
+type SelfCheckError struct {
+	Problems []string
+}
+
+// Error implements error.Error
+func (err SelfCheckError) Error() string {
+	return fmt.Sprintf("self-check found %d problem(s): %s", len(err.Problems), strings.Join(err.Problems, "; "))
+}
+
+// SelfCheck runs a fast consistency verification of the data stored in the given Database,
+// meant to be run before the explorer subscribes to the consensus set.
+//
+// It validates the checksum of the network stats (as stored in the explorer state),
+// verifies the network stats are consistent with themselves, and verifies a random sample
+// of (at most sampleSize) wallets against their locked coin outputs and the network stats.
+// If the Database rolls up its wallets per address prefix, the stats of the prefixes of the sampled wallets
+// are verified as well, pinpointing the buckets which are inconsistent.
+// A SelfCheckError is returned in case the stored data is found to be corrupt.
+func SelfCheck(db Database, sampleSize int) error {
+	state, err := db.GetExplorerState()
+	if err != nil {
+		return fmt.Errorf("failed to get explorer state: %v", err)
+	}
+	stats, err := db.GetNetworkStats()
+	if err != nil {
+		return fmt.Errorf("failed to get network stats: %v", err)
+	}
+
+	var problems []string
+	addProblem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	// validate the checksum of the stats, only stored since the introduction of this self-check
+	if state.StatsChecksum != (crypto.Hash{}) {
+		if checksum := networkStatsChecksum(stats); checksum != state.StatsChecksum {
+			addProblem("network stats checksum mismatch: expected %s, computed %s",
+				state.StatsChecksum.String(), checksum.String())
+		}
+	}
+
+	// validate the network stats themselves
+	if state.CurrentChangeID == modules.ConsensusChangeBeginning && stats.TransactionCount > 0 {
+		addProblem("network stats are defined for %d transactions, while no consensus change was processed",
+			stats.TransactionCount)
+	}
+	if stats.LockedCoins.Cmp(stats.Coins) > 0 {
+		addProblem("locked coins (%s) exceed the total amount of coins (%s)",
+			stats.LockedCoins.String(), stats.Coins.String())
+	}
+	if stats.MaturityLockedCoins.Cmp(stats.LockedCoins) > 0 {
+		addProblem("maturity locked coins (%s) exceed the total amount of locked coins (%s)",
+			stats.MaturityLockedCoins.String(), stats.LockedCoins.String())
+	}
+	if total := stats.ConditionTypes.Total(); total > stats.CointOutputCount {
+		addProblem("coin outputs counted per condition type (%d) exceed the coin output count (%d)",
+			total, stats.CointOutputCount)
+	}
+	if total := stats.TransactionVersions.Total(); total > stats.TransactionCount {
+		addProblem("transactions counted per version (%d) exceed the transaction count (%d)",
+			total, stats.TransactionCount)
+	}
+	if stats.FeeStats != nil && stats.FeeStats.Count > stats.TransactionCount {
+		addProblem("transactions counted in the fee stats (%d) exceed the transaction count (%d)",
+			stats.FeeStats.Count, stats.TransactionCount)
+	}
+	if stats.BurnedCoins.Cmp(stats.Coins) > 0 {
+		addProblem("burned coins (%s) exceed the total amount of coins (%s)",
+			stats.BurnedCoins.String(), stats.Coins.String())
+	}
+	if stats.MinerPayouts.Cmp(stats.Coins) > 0 {
+		addProblem("miner payouts (%s) exceed the total amount of coins (%s)",
+			stats.MinerPayouts.String(), stats.Coins.String())
+	}
+	if stats.LockedCointOutputCount > stats.CointOutputCount {
+		addProblem("locked coin output count (%d) exceeds the coin output count (%d)",
+			stats.LockedCointOutputCount, stats.CointOutputCount)
+	}
+	if stats.MaturityLockedCoinOutputCount > stats.LockedCointOutputCount {
+		addProblem("maturity locked coin output count (%d) exceeds the locked coin output count (%d)",
+			stats.MaturityLockedCoinOutputCount, stats.LockedCointOutputCount)
+	}
+	if stats.CointInputCount > stats.CointOutputCount {
+		addProblem("coin input count (%d) exceeds the coin output count (%d)",
+			stats.CointInputCount, stats.CointOutputCount)
+	}
+	if stats.ValueTransactionCount > stats.TransactionCount {
+		addProblem("value transaction count (%d) exceeds the transaction count (%d)",
+			stats.ValueTransactionCount, stats.TransactionCount)
+	}
+
+	// verify a random sample of wallets
+	if sampleSize > 0 {
+		addresses, err := db.SampleAddresses(sampleSize)
+		if err != nil {
+			return fmt.Errorf("failed to sample addresses: %v", err)
+		}
+		var sampledLockedCoins types.Currency
+		for _, address := range addresses {
+			wallet, err := db.GetWallet(address)
+			if err == ErrNotFound {
+				continue // nothing stored for this address, nothing to verify
+			}
+			if err != nil {
+				return fmt.Errorf("failed to get sampled wallet %s: %v", address.String(), err)
+			}
+			sampledLockedCoins = sampledLockedCoins.Add(wallet.Balance.Locked.Total)
+			if total := wallet.Balance.Unlocked.Add(wallet.Balance.Locked.Total); total.Cmp(stats.Coins) > 0 {
+				addProblem("balance of wallet %s (%s) exceeds the total amount of coins (%s)",
+					address.String(), total.String(), stats.Coins.String())
+			}
+			var lockedOutputsTotal types.Currency
+			for id, output := range wallet.Balance.Locked.Outputs {
+				lockedOutputsTotal = lockedOutputsTotal.Add(output.Amount)
+				info, err := db.GetCoinOutput(id)
+				if err == ErrNotFound {
+					addProblem("locked output %s of wallet %s is not stored", id.String(), address.String())
+					continue
+				}
+				if err != nil {
+					return fmt.Errorf("failed to get locked output %s of sampled wallet %s: %v",
+						id.String(), address.String(), err)
+				}
+				if info.State != CoinOutputStateLocked {
+					addProblem("locked output %s of wallet %s has unexpected state %d",
+						id.String(), address.String(), info.State)
+				}
+				if info.UnlockHash != address {
+					addProblem("locked output %s of wallet %s is owned by %s",
+						id.String(), address.String(), info.UnlockHash.String())
+				}
+				if info.Value.Cmp(output.Amount) != 0 {
+					addProblem("locked output %s of wallet %s has value %s, while the wallet registered %s",
+						id.String(), address.String(), info.Value.String(), output.Amount.String())
+				}
+			}
+			if lockedOutputsTotal.Cmp(wallet.Balance.Locked.Total) != 0 {
+				addProblem("locked balance of wallet %s (%s) doesn't match the sum of its locked outputs (%s)",
+					address.String(), wallet.Balance.Locked.Total.String(), lockedOutputsTotal.String())
+			}
+		}
+		if sampledLockedCoins.Cmp(stats.LockedCoins) > 0 {
+			addProblem("locked coins of the sampled wallets (%s) exceed the total amount of locked coins (%s)",
+				sampledLockedCoins.String(), stats.LockedCoins.String())
+		}
+
+		// verify the stats of the address prefixes of the sampled wallets
+		if pdb, ok := db.(AddressPrefixDatabase); ok {
+			unique := make(map[string]struct{}, len(addresses))
+			var prefixes []string
+			for _, address := range addresses {
+				prefix := AddressPrefix(address)
+				if _, ok := unique[prefix]; !ok {
+					unique[prefix] = struct{}{}
+					prefixes = append(prefixes, prefix)
+				}
+			}
+			prefixProblems, err := VerifyAddressPrefixes(pdb, prefixes)
+			if err != nil {
+				return err
+			}
+			problems = append(problems, prefixProblems...)
+		}
+	}
+
+	if len(problems) > 0 {
+		return SelfCheckError{Problems: problems}
+	}
+	return nil
+}
+
+// networkStatsChecksum computes the checksum of the given network stats,
+// stored as part of the explorer state, such that the stats can be validated at startup.
+//
+// The stats are hashed as they were encoded by the oldest version defining all non-zero stats,
+// e.g. stats which don't define any maturity locked coin outputs, miner fees, burned coins or condition types
+// are hashed as they were encoded prior to the introduction of those stats,
+// such that the checksums stored by older versions remain valid.
+func networkStatsChecksum(stats NetworkStats) crypto.Hash {
+	// nil pointers cannot be encoded, hence the undefined fee stats are hashed as empty fee stats
+	feeStats := stats.FeeStats
+	if feeStats == nil {
+		feeStats = &FeeStats{}
+	}
+	// the stats introduced by each version, oldest first, together with whether or not they're defined
+	versions := []struct {
+		defined bool
+		fields  []interface{}
+	}{
+		{true, []interface{}{
+			stats.Timestamp, stats.BlockHeight, stats.TransactionCount, stats.ValueTransactionCount,
+			stats.CointOutputCount, stats.LockedCointOutputCount, stats.CointInputCount,
+			stats.MinerPayoutCount, stats.TransactionFeeCount, stats.MinerPayouts, stats.TransactionFees,
+			stats.Coins, stats.LockedCoins}},
+		{stats.MaturityLockedCoinOutputCount != 0 || !stats.MaturityLockedCoins.IsZero(), []interface{}{
+			stats.MaturityLockedCoinOutputCount, stats.MaturityLockedCoins}},
+		{stats.MinerFeeCount != 0 || !stats.MinerFees.IsZero(), []interface{}{
+			stats.MinerFeeCount, stats.MinerFees}},
+		{stats.BurnedCoinOutputCount != 0 || !stats.BurnedCoins.IsZero(), []interface{}{
+			stats.BurnedCoinOutputCount, stats.BurnedCoins}},
+		{!stats.ConditionTypes.IsZero(), []interface{}{
+			stats.ConditionTypes}},
+		{len(stats.TransactionVersions) > 0, []interface{}{
+			stats.TransactionVersions}},
+		{stats.FeeStats != nil, []interface{}{
+			feeStats}},
+		{!stats.ValueTransferred.IsZero(), []interface{}{
+			stats.ValueTransferred}},
+		{!stats.TimeLockedCoins.IsZero() || !stats.HeightLockedCoins.IsZero() || !stats.GenesisCoins.IsZero(), []interface{}{
+			stats.TimeLockedCoins, stats.HeightLockedCoins, stats.GenesisCoins}},
+		{stats.Difficulty.Cmp(types.Difficulty{}) != 0, []interface{}{
+			stats.Difficulty}},
+	}
+	n := 1
+	for i := len(versions) - 1; i > 0; i-- {
+		if versions[i].defined {
+			n = i + 1
+			break
+		}
+	}
+	var fields []interface{}
+	for _, version := range versions[:n] {
+		fields = append(fields, version.fields...)
+	}
+	return crypto.HashAll(fields...)
+}
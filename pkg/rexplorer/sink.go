@@ -0,0 +1,73 @@
+package rexplorer
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rivine/rivine/modules"
+)
+
+// ChangeSink is an external system the events of every consensus change are delivered to, see KafkaSink and NATSSink.
+//
+// Unlike the events published to the EventFeed, the events are delivered for all consensus changes,
+// including those processed while the consensus set is syncing, and prior to storing the consensus change,
+// such that every event is delivered at least once: should the delivery fail, the explorer panics
+// (as it does for a failing database), and the consensus change is processed (and its events delivered) again
+// once restarted, as the explorer resumes from the last consensus change stored.
+// Events can thus be delivered more than once, the ID of their consensus change identifying duplicates.
+type ChangeSink interface {
+	// DeliverChange delivers the events of a single consensus change, returning once all of them are delivered.
+	DeliverChange(change ChangeEvents) error
+	// Close the sink, releasing all its resources.
+	Close() error
+}
+
+// ChangeEvents collects the events of a single consensus change, in the order they occurred.
+type ChangeEvents struct {
+	ID     modules.ConsensusChangeID
+	Events []Event
+}
+
+// deliverChange delivers the given events of the consensus change with the given ID to all sinks of the explorer,
+// panicking should any sink fail to deliver them.
+func (explorer *Explorer) deliverChange(id modules.ConsensusChangeID, events []Event) {
+	change := ChangeEvents{ID: id, Events: events}
+	for _, sink := range explorer.sinks {
+		err := sink.DeliverChange(change)
+		if err != nil {
+			panic(fmt.Sprintf("failed to deliver the events of consensus change %x: %v", id[:], err))
+		}
+	}
+}
+
+// The amount of times a sink attempts to deliver a consensus change, and the backoff before the first retry,
+// doubled for each retry after that, up to the maximum backoff.
+const (
+	sinkMaxAttempts  = 10
+	sinkRetryBackoff = time.Second
+	sinkMaxBackoff   = 30 * time.Second
+)
+
+// retryDelivery calls deliver until it succeeds, up to sinkMaxAttempts times,
+// calling reset (e.g. to reconnect) prior to every retry.
+func retryDelivery(sink string, deliver func() error, reset func()) error {
+	backoff := sinkRetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := deliver()
+		if err == nil {
+			return nil
+		}
+		if attempt == sinkMaxAttempts {
+			return fmt.Errorf("failed to deliver to %s after %d attempts: %v", sink, attempt, err)
+		}
+		log.Printf("[ERROR] failed to deliver to %s (attempt %d/%d), retrying in %v: %v",
+			sink, attempt, sinkMaxAttempts, backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > sinkMaxBackoff {
+			backoff = sinkMaxBackoff
+		}
+		reset()
+	}
+}
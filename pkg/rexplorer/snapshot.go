@@ -0,0 +1,61 @@
+package rexplorer
+
+import (
+	"log"
+	"time"
+)
+
+// snapshotPollInterval is the interval at which the explorer polls for snapshot holds,
+// and at which it polls for the release of a snapshot hold while paused.
+const snapshotPollInterval = 500 * time.Millisecond
+
+// holdSnapshots polls the given database for snapshot holds, requested by external clients,
+// pausing the application of blocks for as long as a snapshot is held.
+// It runs until the explorer is closed.
+func (explorer *Explorer) holdSnapshots(sdb SnapshotDatabase) {
+	defer explorer.background.Done()
+	ticker := time.NewTicker(snapshotPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-explorer.closing:
+			return
+		case <-ticker.C:
+		}
+		explorer.mut.Lock()
+		explorer.waitForSnapshotRelease(sdb, ticker)
+		explorer.mut.Unlock()
+	}
+}
+
+// waitForSnapshotRelease blocks for as long as a snapshot is held (or until the explorer is closed),
+// acknowledging each hold. As the explorer lock is held by the caller, no blocks can be applied meanwhile.
+func (explorer *Explorer) waitForSnapshotRelease(sdb SnapshotDatabase, ticker *time.Ticker) {
+	token, err := sdb.GetSnapshotHold()
+	if err != nil {
+		log.Println("[ERROR] failed to get snapshot hold:", err)
+		return
+	}
+	if token == "" {
+		return // nothing to do
+	}
+	log.Printf("snapshot held at block height %d, pausing the application of blocks...", explorer.stats.BlockHeight)
+	for token != "" {
+		err = sdb.AcknowledgeSnapshotHold(token)
+		if err != nil {
+			log.Println("[ERROR] failed to acknowledge snapshot hold:", err)
+			return
+		}
+		select {
+		case <-explorer.closing:
+			return
+		case <-ticker.C:
+		}
+		token, err = sdb.GetSnapshotHold()
+		if err != nil {
+			log.Println("[ERROR] failed to get snapshot hold:", err)
+			return
+		}
+	}
+	log.Println("snapshot released, resuming the application of blocks...")
+}
@@ -0,0 +1,1063 @@
+package rexplorer
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rivine/rivine/crypto"
+	"github.com/rivine/rivine/types"
+)
+
+type (
+	// SQLDatabase is a Database (client) implementation for relational databases,
+	// using the standard database/sql package. The SQL driver and dialect in use
+	// are defined by the (build-tag enabled) database driver which opens it, e.g. postgres or sqlite.
+	//
+	// Contrary to the RedisDatabase, all wallets, coin outputs, multisig owners and counterparties
+	// are stored in a relational schema, such that they can be joined by SQL consumers.
+	// The explorer state, network stats and chain health are stored as JSON values in the meta table.
+	//
+	// Just like the RedisDatabase, only one rexplorer instance should write to the database (schema) used.
+	//
+	// Following tables are created (if they do not exist yet) by this Database implementation:
+	//
+	//	  rexplorer_meta(name, value)														JSON-encoded internal state, network info, stats and health
+	//	  rexplorer_wallets(address, unlocked, locked, signatures_required)					all unique addresses and their balance
+	//	  rexplorer_multisig_owners(address, owner)											owners of all multisig addresses
+	//	  rexplorer_coin_outputs(id, unlockhash, value, state, lock_type, lock_value,
+	//	                         description, raw_condition)								all coin outputs, spent or not
+	//	  rexplorer_counterparties(address, counterparty, tx_count, sent, received)			all counterparties of an address
+	//	  rexplorer_wallet_group_flows(day, sweep_count, sweep_value, refill_count, refill_value)
+	//	                                                                                    sweeps and refills between hot/cold wallets
+	//	  rexplorer_multisig_signatures(coin_output_id, address, signer)					owners that signed each spent multisig coin output
+	//	  rexplorer_multisig_signers(address, signer, spend_count, value)					signing activity of each multisig owner
+	//	  rexplorer_block_summaries(height, id, timestamp, output_count, output_value, histogram,
+	//	                            tx_count, value_transferred, fees, miner_payouts, creator, difficulty)
+	//	                                                                                    output and transaction summary of each block
+	//
+	// Currencies are stored as (base 10) numbers in the smallest coin unit, where the dialect allows it,
+	// addresses and IDs are stored in their Rivine-defined hex-encoded string format.
+	SQLDatabase struct {
+		db      *sql.DB
+		dialect SQLDialect
+
+		blockFrequency LockValue
+
+		// cached version of the chain stats
+		networkBlockHeight types.BlockHeight
+		networkTime        types.Timestamp
+	}
+
+	// SQLDialect defines the differences between the supported SQL databases.
+	SQLDialect struct {
+		// Name of the database, used in error messages
+		Name string
+		// CurrencyType is the column type used to store currencies
+		CurrencyType string
+		// BytesType is the column type used to store raw bytes
+		BytesType string
+		// NumberedPlaceholders is true if placeholders are defined as $n, instead of ?
+		NumberedPlaceholders bool
+		// SingleConnection is true if only a single connection should be opened,
+		// required for embedded databases which do not support concurrent writers
+		SingleConnection bool
+	}
+)
+
+var (
+	_ Database = (*SQLDatabase)(nil)
+)
+
+const (
+	sqlMetaNameState   = "state"
+	sqlMetaNameNetwork = "network"
+	sqlMetaNameStats   = "stats"
+	sqlMetaNameHealth  = "health"
+	sqlMetaNameParams  = "chainparams"
+	sqlMetaNameAliases = "aliases"
+)
+
+// NewSQLDatabase creates a new SQL Database client, used by the internal explorer module,
+// see SQLDatabase for more information.
+func NewSQLDatabase(driverName, dataSourceName string, dialect SQLDialect, bcInfo types.BlockchainInfo, chainCts types.ChainConstants) (*SQLDatabase, error) {
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s database: %v", dialect.Name, err)
+	}
+	if dialect.SingleConnection {
+		db.SetMaxOpenConns(1)
+	}
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to %s database: %v", dialect.Name, err)
+	}
+	sdb := SQLDatabase{
+		db:             db,
+		dialect:        dialect,
+		blockFrequency: LockValue(chainCts.BlockFrequency),
+	}
+	// ensure the schema is created
+	err = sdb.createSchema()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	// ensure the network info is as expected (or register if this is a fresh db)
+	err = sdb.registerOrValidateNetworkInfo(bcInfo)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &sdb, nil
+}
+
+// createSchema creates all tables (and indices) used, in case they don't exist yet.
+func (sdb *SQLDatabase) createSchema() error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS rexplorer_meta (
+			name TEXT PRIMARY KEY,
+			value TEXT NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS rexplorer_wallets (
+			address TEXT PRIMARY KEY,
+			unlocked ` + sdb.dialect.CurrencyType + ` NOT NULL,
+			locked ` + sdb.dialect.CurrencyType + ` NOT NULL,
+			signatures_required BIGINT NOT NULL DEFAULT 0
+		)`,
+		`CREATE TABLE IF NOT EXISTS rexplorer_multisig_owners (
+			address TEXT NOT NULL,
+			owner TEXT NOT NULL,
+			PRIMARY KEY (address, owner)
+		)`,
+		`CREATE INDEX IF NOT EXISTS rexplorer_multisig_owners_owner ON rexplorer_multisig_owners (owner)`,
+		`CREATE TABLE IF NOT EXISTS rexplorer_coin_outputs (
+			id TEXT PRIMARY KEY,
+			unlockhash TEXT NOT NULL,
+			value ` + sdb.dialect.CurrencyType + ` NOT NULL,
+			state SMALLINT NOT NULL,
+			lock_type SMALLINT NOT NULL,
+			lock_value BIGINT NOT NULL,
+			description ` + sdb.dialect.BytesType + `,
+			raw_condition ` + sdb.dialect.BytesType + `
+		)`,
+		`CREATE INDEX IF NOT EXISTS rexplorer_coin_outputs_unlockhash ON rexplorer_coin_outputs (unlockhash)`,
+		`CREATE INDEX IF NOT EXISTS rexplorer_coin_outputs_lock ON rexplorer_coin_outputs (state, lock_type, lock_value)`,
+		`CREATE TABLE IF NOT EXISTS rexplorer_counterparties (
+			address TEXT NOT NULL,
+			counterparty TEXT NOT NULL,
+			tx_count BIGINT NOT NULL,
+			sent ` + sdb.dialect.CurrencyType + ` NOT NULL,
+			received ` + sdb.dialect.CurrencyType + ` NOT NULL,
+			PRIMARY KEY (address, counterparty)
+		)`,
+		`CREATE TABLE IF NOT EXISTS rexplorer_wallet_group_flows (
+			day TEXT PRIMARY KEY,
+			sweep_count BIGINT NOT NULL,
+			sweep_value ` + sdb.dialect.CurrencyType + ` NOT NULL,
+			refill_count BIGINT NOT NULL,
+			refill_value ` + sdb.dialect.CurrencyType + ` NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS rexplorer_multisig_signatures (
+			coin_output_id TEXT NOT NULL,
+			address TEXT NOT NULL,
+			signer TEXT NOT NULL,
+			PRIMARY KEY (coin_output_id, signer)
+		)`,
+		`CREATE INDEX IF NOT EXISTS rexplorer_multisig_signatures_address ON rexplorer_multisig_signatures (address)`,
+		`CREATE TABLE IF NOT EXISTS rexplorer_multisig_signers (
+			address TEXT NOT NULL,
+			signer TEXT NOT NULL,
+			spend_count BIGINT NOT NULL,
+			value ` + sdb.dialect.CurrencyType + ` NOT NULL,
+			PRIMARY KEY (address, signer)
+		)`,
+		`CREATE TABLE IF NOT EXISTS rexplorer_block_summaries (
+			height BIGINT PRIMARY KEY,
+			id TEXT NOT NULL,
+			timestamp BIGINT NOT NULL,
+			output_count BIGINT NOT NULL,
+			output_value ` + sdb.dialect.CurrencyType + ` NOT NULL,
+			histogram TEXT NOT NULL
+		)`,
+	}
+	for _, statement := range statements {
+		_, err := sdb.db.Exec(statement)
+		if err != nil {
+			return fmt.Errorf("failed to create %s schema: %v", sdb.dialect.Name, err)
+		}
+	}
+	// columns added to existing tables, in case they don't exist yet,
+	// defaulting to the zero value for all rows stored prior to adding them
+	columns := []struct {
+		table, column, definition string
+	}{
+		{"rexplorer_block_summaries", "tx_count", `BIGINT NOT NULL DEFAULT 0`},
+		{"rexplorer_block_summaries", "value_transferred", sdb.dialect.CurrencyType + ` NOT NULL DEFAULT '0'`},
+		{"rexplorer_block_summaries", "fees", sdb.dialect.CurrencyType + ` NOT NULL DEFAULT '0'`},
+		{"rexplorer_block_summaries", "miner_payouts", sdb.dialect.CurrencyType + ` NOT NULL DEFAULT '0'`},
+		{"rexplorer_block_summaries", "creator", `TEXT NOT NULL DEFAULT ''`},
+		{"rexplorer_block_summaries", "difficulty", sdb.dialect.CurrencyType + ` NOT NULL DEFAULT '0'`},
+	}
+	for _, c := range columns {
+		err := sdb.ensureColumn(c.table, c.column, c.definition)
+		if err != nil {
+			return fmt.Errorf("failed to create %s schema: %v", sdb.dialect.Name, err)
+		}
+	}
+	return nil
+}
+
+// ensureColumn adds a column with the given definition to the given table, in case it doesn't exist yet.
+func (sdb *SQLDatabase) ensureColumn(table, column, definition string) error {
+	rows, err := sdb.db.Query(`SELECT ` + column + ` FROM ` + table + ` LIMIT 0`)
+	if err == nil {
+		return rows.Close()
+	}
+	_, err = sdb.db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column + ` ` + definition)
+	if err != nil {
+		return fmt.Errorf("failed to add column %s to table %s: %v", column, table, err)
+	}
+	return nil
+}
+
+// Close implements Database.Close
+func (sdb *SQLDatabase) Close() error {
+	err := sdb.db.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close %s db client: %v", sdb.dialect.Name, err)
+	}
+	return nil
+}
+
+// rebind rewrites the ? placeholders of a query into the placeholders used by the dialect.
+func (sdb *SQLDatabase) rebind(query string) string {
+	if !sdb.dialect.NumberedPlaceholders {
+		return query
+	}
+	var (
+		sb strings.Builder
+		n  int
+	)
+	for _, r := range query {
+		if r != '?' {
+			sb.WriteRune(r)
+			continue
+		}
+		n++
+		sb.WriteString("$" + strconv.Itoa(n))
+	}
+	return sb.String()
+}
+
+func (sdb *SQLDatabase) exec(query string, args ...interface{}) error {
+	_, err := sdb.db.Exec(sdb.rebind(query), args...)
+	return err
+}
+
+func (sdb *SQLDatabase) queryRow(query string, args ...interface{}) *sql.Row {
+	return sdb.db.QueryRow(sdb.rebind(query), args...)
+}
+
+func (sdb *SQLDatabase) query(query string, args ...interface{}) (*sql.Rows, error) {
+	return sdb.db.Query(sdb.rebind(query), args...)
+}
+
+// getMeta gets and decodes a JSON-encoded value from the meta table,
+// returning ErrNotFound if it isn't stored yet.
+func (sdb *SQLDatabase) getMeta(name string, v interface{}) error {
+	var value string
+	switch err := sdb.queryRow(`SELECT value FROM rexplorer_meta WHERE name = ?`, name).Scan(&value); err {
+	case nil:
+		return json.Unmarshal([]byte(value), v)
+	case sql.ErrNoRows:
+		return ErrNotFound
+	default:
+		return err
+	}
+}
+
+// setMeta encodes and stores a value as JSON in the meta table.
+func (sdb *SQLDatabase) setMeta(name string, v interface{}) error {
+	return sdb.exec(`INSERT INTO rexplorer_meta (name, value) VALUES (?, ?)
+		ON CONFLICT (name) DO UPDATE SET value = excluded.value`, name, string(JSONMarshal(v)))
+}
+
+// registerOrValidateNetworkInfo registeres the network name and chain name if it doesn't exist yet,
+// otherwise it ensures that the returned network info matches the expected network info.
+func (sdb *SQLDatabase) registerOrValidateNetworkInfo(bcInfo types.BlockchainInfo) error {
+	networkInfo := NetworkInfo{
+		ChainName:   bcInfo.Name,
+		NetworkName: bcInfo.NetworkName,
+	}
+	var receivedNetworkInfo NetworkInfo
+	switch err := sdb.getMeta(sqlMetaNameNetwork, &receivedNetworkInfo); err {
+	case nil:
+	case ErrNotFound:
+		err = sdb.setMeta(sqlMetaNameNetwork, networkInfo)
+		if err != nil {
+			return fmt.Errorf("failed to register network info: %v", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("failed to validate network info: %v", err)
+	}
+	if receivedNetworkInfo != networkInfo {
+		return fmt.Errorf("cannot store data for chain %s/%s: db has already data for chain %s/%s stored",
+			networkInfo.ChainName, networkInfo.NetworkName,
+			receivedNetworkInfo.ChainName, receivedNetworkInfo.NetworkName)
+	}
+	return nil
+}
+
+// GetExplorerState implements Database.GetExplorerState
+func (sdb *SQLDatabase) GetExplorerState() (ExplorerState, error) {
+	var state ExplorerState
+	switch err := sdb.getMeta(sqlMetaNameState, &state); err {
+	case nil:
+		return state, nil
+	case ErrNotFound:
+		// default to fresh explorer state if not stored yet
+		return NewExplorerState(), nil
+	default:
+		return ExplorerState{}, err
+	}
+}
+
+// SetExplorerState implements Database.SetExplorerState
+func (sdb *SQLDatabase) SetExplorerState(state ExplorerState) error {
+	return sdb.setMeta(sqlMetaNameState, state)
+}
+
+// GetNetworkStats implements Database.GetNetworkStats
+func (sdb *SQLDatabase) GetNetworkStats() (NetworkStats, error) {
+	var stats NetworkStats
+	switch err := sdb.getMeta(sqlMetaNameStats, &stats); err {
+	case nil:
+		err = upgradeNetworkStats(&stats)
+		if err != nil {
+			return NetworkStats{}, fmt.Errorf("%s: failed to upgrade network stats: %v", sdb.dialect.Name, err)
+		}
+	case ErrNotFound:
+		// default to fresh network stats if not stored yet
+		stats = NewNetworkStats()
+	default:
+		return NetworkStats{}, err
+	}
+	sdb.networkTime, sdb.networkBlockHeight = stats.Timestamp, stats.BlockHeight
+	return stats, nil
+}
+
+// SetNetworkStats implements Database.SetNetworkStats
+func (sdb *SQLDatabase) SetNetworkStats(stats NetworkStats) error {
+	err := sdb.setMeta(sqlMetaNameStats, stats)
+	if err != nil {
+		return err
+	}
+	sdb.networkTime, sdb.networkBlockHeight = stats.Timestamp, stats.BlockHeight
+	return nil
+}
+
+// SetChainHealth implements Database.SetChainHealth
+func (sdb *SQLDatabase) SetChainHealth(health ChainHealth) error {
+	return sdb.setMeta(sqlMetaNameHealth, health)
+}
+
+// GetChainParametersHistory implements Database.GetChainParametersHistory
+func (sdb *SQLDatabase) GetChainParametersHistory() ([]ChainParametersRecord, error) {
+	var history []ChainParametersRecord
+	switch err := sdb.getMeta(sqlMetaNameParams, &history); err {
+	case nil, ErrNotFound:
+		// no history is stored yet for a fresh database
+		return history, nil
+	default:
+		return nil, err
+	}
+}
+
+// SetChainParametersHistory implements Database.SetChainParametersHistory
+func (sdb *SQLDatabase) SetChainParametersHistory(history []ChainParametersRecord) error {
+	return sdb.setMeta(sqlMetaNameParams, history)
+}
+
+// GetAddressAliases implements Database.GetAddressAliases
+func (sdb *SQLDatabase) GetAddressAliases() ([]AddressAlias, error) {
+	var aliases []AddressAlias
+	switch err := sdb.getMeta(sqlMetaNameAliases, &aliases); err {
+	case nil, ErrNotFound:
+		// no aliases are recorded yet
+		return aliases, nil
+	default:
+		return nil, err
+	}
+}
+
+// SetAddressAliases implements Database.SetAddressAliases
+func (sdb *SQLDatabase) SetAddressAliases(aliases []AddressAlias) error {
+	return sdb.setMeta(sqlMetaNameAliases, aliases)
+}
+
+// AddCoinOutput implements Database.AddCoinOutput
+func (sdb *SQLDatabase) AddCoinOutput(id types.CoinOutputID, co CoinOutput) error {
+	return sdb.addCoinOutput(id, co, CoinOutputStateLiquid, LockTypeNone, 0)
+}
+
+// AddLockedCoinOutput implements Database.AddLockedCoinOutput
+func (sdb *SQLDatabase) AddLockedCoinOutput(id types.CoinOutputID, co CoinOutput, lt LockType, lockValue LockValue) error {
+	return sdb.addCoinOutput(id, co, CoinOutputStateLocked, lt, lockValue)
+}
+
+func (sdb *SQLDatabase) addCoinOutput(id types.CoinOutputID, co CoinOutput, state CoinOutputState, lt LockType, lockValue LockValue) error {
+	uh := co.Condition.UnlockHash()
+	err := sdb.exec(`INSERT INTO rexplorer_coin_outputs
+		(id, unlockhash, value, state, lock_type, lock_value, description, raw_condition)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		id.String(), uh.String(), co.Value.String(), int64(state), int64(lt), int64(lockValue),
+		[]byte(co.Description), []byte(EncodeCondition(co.Condition)))
+	if err != nil {
+		return fmt.Errorf("%s: failed to add coin output %s: %v", sdb.dialect.Name, id.String(), err)
+	}
+	// store address (an address never gets deleted) and update its balance
+	return sdb.updateWalletBalance(uh, func(unlocked, locked *types.Currency) {
+		if state == CoinOutputStateLocked {
+			*locked = locked.Add(co.Value)
+		} else {
+			*unlocked = unlocked.Add(co.Value)
+		}
+	})
+}
+
+// SpendCoinOutput implements Database.SpendCoinOutput
+func (sdb *SQLDatabase) SpendCoinOutput(id types.CoinOutputID) (types.UnlockHash, types.Currency, error) {
+	uh, value, err := sdb.updateCoinOutputState(id, CoinOutputStateLiquid, CoinOutputStateSpent)
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf(
+			"%s: failed to spend coin output: %v", sdb.dialect.Name, err)
+	}
+	err = sdb.updateWalletBalance(uh, func(unlocked, _ *types.Currency) {
+		*unlocked = unlocked.Sub(value)
+	})
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, err
+	}
+	return uh, value, nil
+}
+
+// RevertCoinInput implements Database.RevertCoinInput
+// more or less a reverse process of SpendCoinOutput
+func (sdb *SQLDatabase) RevertCoinInput(id types.CoinOutputID) (types.UnlockHash, types.Currency, error) {
+	uh, value, err := sdb.updateCoinOutputState(id, CoinOutputStateSpent, CoinOutputStateLiquid)
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf(
+			"%s: failed to revert coin input: %v", sdb.dialect.Name, err)
+	}
+	err = sdb.updateWalletBalance(uh, func(unlocked, _ *types.Currency) {
+		*unlocked = unlocked.Add(value)
+	})
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, err
+	}
+	return uh, value, nil
+}
+
+// updateCoinOutputState updates the state of a coin output,
+// returning an error in case the coin output isn't in the expected state.
+func (sdb *SQLDatabase) updateCoinOutputState(id types.CoinOutputID, from, to CoinOutputState) (types.UnlockHash, types.Currency, error) {
+	info, err := sdb.GetCoinOutput(id)
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf("cannot get coin output %s: %v", id.String(), err)
+	}
+	if info.State != from {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf(
+			"cannot update coin output %s: unexpected state %d", id.String(), info.State)
+	}
+	err = sdb.exec(`UPDATE rexplorer_coin_outputs SET state = ? WHERE id = ?`, int64(to), id.String())
+	if err != nil {
+		return types.UnlockHash{}, types.Currency{}, fmt.Errorf("cannot update coin output %s: %v", id.String(), err)
+	}
+	return info.UnlockHash, info.Value, nil
+}
+
+// RevertCoinOutput implements Database.RevertCoinOutput
+func (sdb *SQLDatabase) RevertCoinOutput(id types.CoinOutputID) (CoinOutputState, error) {
+	info, err := sdb.GetCoinOutput(id)
+	if err != nil {
+		return CoinOutputStateNil, fmt.Errorf(
+			"%s: failed to revert coin output: cannot get coin output %s: %v", sdb.dialect.Name, id.String(), err)
+	}
+	err = sdb.exec(`DELETE FROM rexplorer_coin_outputs WHERE id = ?`, id.String())
+	if err != nil {
+		return CoinOutputStateNil, fmt.Errorf(
+			"%s: failed to revert coin output: cannot drop coin output %s: %v", sdb.dialect.Name, id.String(), err)
+	}
+	if info.State == CoinOutputStateSpent {
+		return info.State, nil
+	}
+	err = sdb.updateWalletBalance(info.UnlockHash, func(unlocked, locked *types.Currency) {
+		if info.State == CoinOutputStateLocked {
+			*locked = locked.Sub(info.Value)
+		} else {
+			*unlocked = unlocked.Sub(info.Value)
+		}
+	})
+	if err != nil {
+		return CoinOutputStateNil, err
+	}
+	return info.State, nil
+}
+
+// ApplyCoinOutputLocks implements Database.ApplyCoinOutputLocks
+func (sdb *SQLDatabase) ApplyCoinOutputLocks(height types.BlockHeight, time types.Timestamp) (uint64, types.Currency, error) {
+	sdb.networkTime, sdb.networkBlockHeight = time, height
+	return sdb.updateCoinOutputLocks(CoinOutputStateLocked, CoinOutputStateLiquid, `SELECT id, unlockhash, value FROM rexplorer_coin_outputs
+		WHERE state = ? AND ((lock_type = ? AND lock_value <= ?) OR (lock_type = ? AND lock_value <= ?))`,
+		int64(CoinOutputStateLocked), int64(LockTypeHeight), int64(height), int64(LockTypeTime), int64(time))
+}
+
+// RevertCoinOutputLocks implements Database.RevertCoinOutputLocks
+func (sdb *SQLDatabase) RevertCoinOutputLocks(height types.BlockHeight, time types.Timestamp) (uint64, types.Currency, error) {
+	sdb.networkTime, sdb.networkBlockHeight = time, height
+	return sdb.updateCoinOutputLocks(CoinOutputStateLiquid, CoinOutputStateLocked, `SELECT id, unlockhash, value FROM rexplorer_coin_outputs
+		WHERE state = ? AND ((lock_type = ? AND lock_value > ?) OR (lock_type = ? AND lock_value > ?))`,
+		int64(CoinOutputStateLiquid), int64(LockTypeHeight), int64(height), int64(LockTypeTime), int64(time))
+}
+
+// updateCoinOutputLocks moves all coin outputs selected by the given query from one state to another,
+// moving their value between the locked and unlocked balance of the wallets they belong to.
+func (sdb *SQLDatabase) updateCoinOutputLocks(from, to CoinOutputState, query string, args ...interface{}) (n uint64, coins types.Currency, err error) {
+	type lockedOutput struct {
+		ID         types.CoinOutputID
+		UnlockHash types.UnlockHash
+		Value      types.Currency
+	}
+	rows, err := sdb.query(query, args...)
+	if err != nil {
+		return 0, types.Currency{}, fmt.Errorf("%s: failed to get coin outputs to (un)lock: %v", sdb.dialect.Name, err)
+	}
+	var outputs []lockedOutput
+	for rows.Next() {
+		var output lockedOutput
+		err = rows.Scan(sqlStringLoader{&output.ID}, sqlStringLoader{&output.UnlockHash}, sqlStringLoader{&output.Value})
+		if err != nil {
+			rows.Close()
+			return 0, types.Currency{}, fmt.Errorf("%s: failed to scan coin output to (un)lock: %v", sdb.dialect.Name, err)
+		}
+		outputs = append(outputs, output)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return 0, types.Currency{}, fmt.Errorf("%s: failed to get coin outputs to (un)lock: %v", sdb.dialect.Name, err)
+	}
+
+	for _, output := range outputs {
+		err = sdb.exec(`UPDATE rexplorer_coin_outputs SET state = ? WHERE id = ?`, int64(to), output.ID.String())
+		if err != nil {
+			return 0, types.Currency{}, fmt.Errorf("%s: failed to (un)lock coin output %s: %v",
+				sdb.dialect.Name, output.ID.String(), err)
+		}
+		err = sdb.updateWalletBalance(output.UnlockHash, func(unlocked, locked *types.Currency) {
+			if to == CoinOutputStateLiquid {
+				*locked, *unlocked = locked.Sub(output.Value), unlocked.Add(output.Value)
+			} else {
+				*locked, *unlocked = locked.Add(output.Value), unlocked.Sub(output.Value)
+			}
+		})
+		if err != nil {
+			return 0, types.Currency{}, err
+		}
+		coins = coins.Add(output.Value)
+		n++
+	}
+	return n, coins, nil
+}
+
+// updateWalletBalance updates the balance of a wallet using the given update function,
+// creating the wallet if it doesn't exist yet.
+func (sdb *SQLDatabase) updateWalletBalance(address types.UnlockHash, update func(unlocked, locked *types.Currency)) error {
+	var unlocked, locked types.Currency
+	err := sdb.queryRow(`SELECT unlocked, locked FROM rexplorer_wallets WHERE address = ?`, address.String()).Scan(
+		sqlStringLoader{&unlocked}, sqlStringLoader{&locked})
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("%s: failed to get wallet for %s: %v", sdb.dialect.Name, address.String(), err)
+	}
+	update(&unlocked, &locked)
+	err = sdb.exec(`INSERT INTO rexplorer_wallets (address, unlocked, locked) VALUES (?, ?, ?)
+		ON CONFLICT (address) DO UPDATE SET unlocked = excluded.unlocked, locked = excluded.locked`,
+		address.String(), unlocked.String(), locked.String())
+	if err != nil {
+		return fmt.Errorf("%s: failed to set wallet for %s: %v", sdb.dialect.Name, address.String(), err)
+	}
+	return nil
+}
+
+// SetMultisigAddresses implements Database.SetMultisigAddresses
+func (sdb *SQLDatabase) SetMultisigAddresses(address types.UnlockHash, owners []types.UnlockHash, signaturesRequired uint64) error {
+	var n int64
+	err := sdb.queryRow(`SELECT COUNT(*) FROM rexplorer_multisig_owners WHERE address = ?`, address.String()).Scan(&n)
+	if err != nil {
+		return fmt.Errorf("%s: failed to get multisig owners of %s: %v", sdb.dialect.Name, address.String(), err)
+	}
+	if n > 0 {
+		return nil // nothing to do
+	}
+	err = sdb.exec(`INSERT INTO rexplorer_wallets (address, unlocked, locked, signatures_required) VALUES (?, ?, ?, ?)
+		ON CONFLICT (address) DO UPDATE SET signatures_required = excluded.signatures_required`,
+		address.String(), "0", "0", int64(signaturesRequired))
+	if err != nil {
+		return fmt.Errorf("%s: failed to set multisig wallet for %s: %v", sdb.dialect.Name, address.String(), err)
+	}
+	for _, owner := range owners {
+		err = sdb.exec(`INSERT INTO rexplorer_multisig_owners (address, owner) VALUES (?, ?)
+			ON CONFLICT (address, owner) DO NOTHING`, address.String(), owner.String())
+		if err != nil {
+			return fmt.Errorf("%s: failed to add owner %s to multisig wallet %s: %v",
+				sdb.dialect.Name, owner.String(), address.String(), err)
+		}
+	}
+	return nil
+}
+
+// ApplyCounterpartyTransfer implements Database.ApplyCounterpartyTransfer
+func (sdb *SQLDatabase) ApplyCounterpartyTransfer(from, to types.UnlockHash, value types.Currency) error {
+	err := sdb.updateCounterparty(from, to, func(cp *AddressCounterparty) {
+		cp.TransactionCount++
+		cp.Sent = cp.Sent.Add(value)
+	})
+	if err != nil {
+		return err
+	}
+	return sdb.updateCounterparty(to, from, func(cp *AddressCounterparty) {
+		cp.TransactionCount++
+		cp.Received = cp.Received.Add(value)
+	})
+}
+
+// RevertCounterpartyTransfer implements Database.RevertCounterpartyTransfer
+func (sdb *SQLDatabase) RevertCounterpartyTransfer(from, to types.UnlockHash, value types.Currency) error {
+	err := sdb.updateCounterparty(from, to, func(cp *AddressCounterparty) {
+		cp.TransactionCount--
+		cp.Sent = subCurrencyOrZero(cp.Sent, value)
+	})
+	if err != nil {
+		return err
+	}
+	return sdb.updateCounterparty(to, from, func(cp *AddressCounterparty) {
+		cp.TransactionCount--
+		cp.Received = subCurrencyOrZero(cp.Received, value)
+	})
+}
+
+// updateCounterparty updates the counterparty of an address, using the given update function.
+// Contrary to the RedisDatabase, all counterparties of an address are tracked.
+func (sdb *SQLDatabase) updateCounterparty(address, counterparty types.UnlockHash, update func(*AddressCounterparty)) error {
+	var cp AddressCounterparty
+	err := sdb.queryRow(`SELECT tx_count, sent, received FROM rexplorer_counterparties WHERE address = ? AND counterparty = ?`,
+		address.String(), counterparty.String()).Scan(&cp.TransactionCount, sqlStringLoader{&cp.Sent}, sqlStringLoader{&cp.Received})
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("%s: failed to get counterparty %s of %s: %v",
+			sdb.dialect.Name, counterparty.String(), address.String(), err)
+	}
+	update(&cp)
+	if cp.TransactionCount == 0 {
+		err = sdb.exec(`DELETE FROM rexplorer_counterparties WHERE address = ? AND counterparty = ?`,
+			address.String(), counterparty.String())
+	} else {
+		err = sdb.exec(`INSERT INTO rexplorer_counterparties (address, counterparty, tx_count, sent, received) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT (address, counterparty) DO UPDATE SET tx_count = excluded.tx_count, sent = excluded.sent, received = excluded.received`,
+			address.String(), counterparty.String(), int64(cp.TransactionCount), cp.Sent.String(), cp.Received.String())
+	}
+	if err != nil {
+		return fmt.Errorf("%s: failed to update counterparty %s of %s: %v",
+			sdb.dialect.Name, counterparty.String(), address.String(), err)
+	}
+	return nil
+}
+
+// ApplyWalletGroupFlows implements Database.ApplyWalletGroupFlows
+func (sdb *SQLDatabase) ApplyWalletGroupFlows(timestamp types.Timestamp, flows WalletGroupFlows) error {
+	return sdb.updateWalletGroupFlows(timestamp, func(current WalletGroupFlows) WalletGroupFlows {
+		return current.Add(flows)
+	})
+}
+
+// RevertWalletGroupFlows implements Database.RevertWalletGroupFlows
+func (sdb *SQLDatabase) RevertWalletGroupFlows(timestamp types.Timestamp, flows WalletGroupFlows) error {
+	return sdb.updateWalletGroupFlows(timestamp, func(current WalletGroupFlows) WalletGroupFlows {
+		return current.Sub(flows)
+	})
+}
+
+// updateWalletGroupFlows updates both the total and the daily wallet group flows,
+// removing the daily flows once they're no longer used.
+func (sdb *SQLDatabase) updateWalletGroupFlows(timestamp types.Timestamp, update func(WalletGroupFlows) WalletGroupFlows) error {
+	for _, day := range []string{flowsFieldTotal, walletGroupFlowsDay(timestamp)} {
+		var flows WalletGroupFlows
+		err := sdb.queryRow(`SELECT sweep_count, sweep_value, refill_count, refill_value FROM rexplorer_wallet_group_flows WHERE day = ?`, day).Scan(
+			&flows.Sweeps.TransactionCount, sqlStringLoader{&flows.Sweeps.Value}, &flows.Refills.TransactionCount, sqlStringLoader{&flows.Refills.Value})
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("%s: failed to get wallet group flows of %s: %v", sdb.dialect.Name, day, err)
+		}
+		flows = update(flows)
+		if flows.IsZero() && day != flowsFieldTotal {
+			err = sdb.exec(`DELETE FROM rexplorer_wallet_group_flows WHERE day = ?`, day)
+		} else {
+			err = sdb.exec(`INSERT INTO rexplorer_wallet_group_flows (day, sweep_count, sweep_value, refill_count, refill_value) VALUES (?, ?, ?, ?, ?)
+				ON CONFLICT (day) DO UPDATE SET sweep_count = excluded.sweep_count, sweep_value = excluded.sweep_value,
+				refill_count = excluded.refill_count, refill_value = excluded.refill_value`,
+				day, int64(flows.Sweeps.TransactionCount), flows.Sweeps.Value.String(),
+				int64(flows.Refills.TransactionCount), flows.Refills.Value.String())
+		}
+		if err != nil {
+			return fmt.Errorf("%s: failed to update wallet group flows of %s: %v", sdb.dialect.Name, day, err)
+		}
+	}
+	return nil
+}
+
+// GetWalletGroupFlows implements Database.GetWalletGroupFlows
+func (sdb *SQLDatabase) GetWalletGroupFlows() (total WalletGroupFlows, daily map[string]WalletGroupFlows, err error) {
+	rows, err := sdb.query(`SELECT day, sweep_count, sweep_value, refill_count, refill_value FROM rexplorer_wallet_group_flows`)
+	if err != nil {
+		return WalletGroupFlows{}, nil, fmt.Errorf("%s: failed to get wallet group flows: %v", sdb.dialect.Name, err)
+	}
+	defer rows.Close()
+	daily = make(map[string]WalletGroupFlows)
+	for rows.Next() {
+		var (
+			day   string
+			flows WalletGroupFlows
+		)
+		err = rows.Scan(&day, &flows.Sweeps.TransactionCount, sqlStringLoader{&flows.Sweeps.Value},
+			&flows.Refills.TransactionCount, sqlStringLoader{&flows.Refills.Value})
+		if err != nil {
+			return WalletGroupFlows{}, nil, fmt.Errorf("%s: failed to scan wallet group flows: %v", sdb.dialect.Name, err)
+		}
+		if day == flowsFieldTotal {
+			total = flows
+		} else {
+			daily[day] = flows
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		return WalletGroupFlows{}, nil, fmt.Errorf("%s: failed to get wallet group flows: %v", sdb.dialect.Name, err)
+	}
+	return total, daily, nil
+}
+
+// ApplyMultisigSpend implements Database.ApplyMultisigSpend
+func (sdb *SQLDatabase) ApplyMultisigSpend(spend MultisigSpend) error {
+	for _, signer := range spend.Signers {
+		err := sdb.exec(`INSERT INTO rexplorer_multisig_signatures (coin_output_id, address, signer) VALUES (?, ?, ?)
+			ON CONFLICT (coin_output_id, signer) DO NOTHING`,
+			spend.CoinOutputID.String(), spend.Address.String(), signer.String())
+		if err != nil {
+			return fmt.Errorf("%s: failed to store signer %s of multisig spend %s: %v",
+				sdb.dialect.Name, signer.String(), spend.CoinOutputID.String(), err)
+		}
+		err = sdb.updateMultisigSignerStats(spend.Address, signer, func(stats *MultisigSignerStats) {
+			stats.SpendCount++
+			stats.Value = stats.Value.Add(spend.Value)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevertMultisigSpend implements Database.RevertMultisigSpend
+func (sdb *SQLDatabase) RevertMultisigSpend(spend MultisigSpend) error {
+	err := sdb.exec(`DELETE FROM rexplorer_multisig_signatures WHERE coin_output_id = ?`, spend.CoinOutputID.String())
+	if err != nil {
+		return fmt.Errorf("%s: failed to remove signers of multisig spend %s: %v",
+			sdb.dialect.Name, spend.CoinOutputID.String(), err)
+	}
+	for _, signer := range spend.Signers {
+		err = sdb.updateMultisigSignerStats(spend.Address, signer, func(stats *MultisigSignerStats) {
+			stats.SpendCount--
+			stats.Value = subCurrencyOrZero(stats.Value, spend.Value)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateMultisigSignerStats updates the signing stats of an owner of a multisig wallet,
+// using the given update function, removing the stats once the owner no longer signed any spend.
+func (sdb *SQLDatabase) updateMultisigSignerStats(address, signer types.UnlockHash, update func(*MultisigSignerStats)) error {
+	var stats MultisigSignerStats
+	err := sdb.queryRow(`SELECT spend_count, value FROM rexplorer_multisig_signers WHERE address = ? AND signer = ?`,
+		address.String(), signer.String()).Scan(&stats.SpendCount, sqlStringLoader{&stats.Value})
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("%s: failed to get signing stats of %s for %s: %v",
+			sdb.dialect.Name, signer.String(), address.String(), err)
+	}
+	update(&stats)
+	if stats.SpendCount == 0 {
+		err = sdb.exec(`DELETE FROM rexplorer_multisig_signers WHERE address = ? AND signer = ?`,
+			address.String(), signer.String())
+	} else {
+		err = sdb.exec(`INSERT INTO rexplorer_multisig_signers (address, signer, spend_count, value) VALUES (?, ?, ?, ?)
+			ON CONFLICT (address, signer) DO UPDATE SET spend_count = excluded.spend_count, value = excluded.value`,
+			address.String(), signer.String(), int64(stats.SpendCount), stats.Value.String())
+	}
+	if err != nil {
+		return fmt.Errorf("%s: failed to update signing stats of %s for %s: %v",
+			sdb.dialect.Name, signer.String(), address.String(), err)
+	}
+	return nil
+}
+
+// GetMultisigSpends implements Database.GetMultisigSpends
+func (sdb *SQLDatabase) GetMultisigSpends(address types.UnlockHash) (map[types.CoinOutputID][]types.UnlockHash, error) {
+	rows, err := sdb.query(`SELECT coin_output_id, signer FROM rexplorer_multisig_signatures WHERE address = ?`, address.String())
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get multisig spends of %s: %v", sdb.dialect.Name, address.String(), err)
+	}
+	defer rows.Close()
+	spends := make(map[types.CoinOutputID][]types.UnlockHash)
+	for rows.Next() {
+		var (
+			id     types.CoinOutputID
+			signer types.UnlockHash
+		)
+		err = rows.Scan(sqlStringLoader{&id}, sqlStringLoader{&signer})
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to scan multisig spend of %s: %v", sdb.dialect.Name, address.String(), err)
+		}
+		spends[id] = append(spends[id], signer)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get multisig spends of %s: %v", sdb.dialect.Name, address.String(), err)
+	}
+	return spends, nil
+}
+
+// GetMultisigSignerStats implements Database.GetMultisigSignerStats
+func (sdb *SQLDatabase) GetMultisigSignerStats(address types.UnlockHash) (map[types.UnlockHash]MultisigSignerStats, error) {
+	rows, err := sdb.query(`SELECT signer, spend_count, value FROM rexplorer_multisig_signers WHERE address = ?`, address.String())
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get multisig signing stats of %s: %v", sdb.dialect.Name, address.String(), err)
+	}
+	defer rows.Close()
+	signers := make(map[types.UnlockHash]MultisigSignerStats)
+	for rows.Next() {
+		var (
+			signer types.UnlockHash
+			stats  MultisigSignerStats
+		)
+		err = rows.Scan(sqlStringLoader{&signer}, &stats.SpendCount, sqlStringLoader{&stats.Value})
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to scan multisig signing stats of %s: %v", sdb.dialect.Name, address.String(), err)
+		}
+		signers[signer] = stats
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get multisig signing stats of %s: %v", sdb.dialect.Name, address.String(), err)
+	}
+	return signers, nil
+}
+
+// SetBlockSummary implements Database.SetBlockSummary
+func (sdb *SQLDatabase) SetBlockSummary(summary BlockSummary) error {
+	err := sdb.exec(`INSERT INTO rexplorer_block_summaries (height, id, timestamp, output_count, output_value, histogram,
+		tx_count, value_transferred, fees, miner_payouts, creator, difficulty) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (height) DO UPDATE SET id = excluded.id, timestamp = excluded.timestamp,
+		output_count = excluded.output_count, output_value = excluded.output_value, histogram = excluded.histogram,
+		tx_count = excluded.tx_count, value_transferred = excluded.value_transferred, fees = excluded.fees,
+		miner_payouts = excluded.miner_payouts, creator = excluded.creator, difficulty = excluded.difficulty`,
+		int64(summary.Height), summary.ID.String(), int64(summary.Timestamp), int64(summary.OutputCount),
+		summary.OutputValue.String(), string(JSONMarshal(summary.Histogram)),
+		int64(summary.TransactionCount), summary.ValueTransferred.String(), summary.Fees.String(),
+		summary.MinerPayouts.String(), summary.Creator.String(), summary.Difficulty.String())
+	if err != nil {
+		return fmt.Errorf("%s: failed to set summary of block %d: %v", sdb.dialect.Name, summary.Height, err)
+	}
+	return nil
+}
+
+// RevertBlockSummary implements Database.RevertBlockSummary
+func (sdb *SQLDatabase) RevertBlockSummary(height types.BlockHeight) error {
+	err := sdb.exec(`DELETE FROM rexplorer_block_summaries WHERE height = ?`, int64(height))
+	if err != nil {
+		return fmt.Errorf("%s: failed to revert summary of block %d: %v", sdb.dialect.Name, height, err)
+	}
+	return nil
+}
+
+// GetBlockSummary implements Database.GetBlockSummary
+func (sdb *SQLDatabase) GetBlockSummary(height types.BlockHeight) (BlockSummary, error) {
+	summary := BlockSummary{Height: height}
+	var histogram string
+	err := sdb.queryRow(`SELECT id, timestamp, output_count, output_value, histogram,
+		tx_count, value_transferred, fees, miner_payouts, creator, difficulty FROM rexplorer_block_summaries WHERE height = ?`,
+		int64(height)).Scan(sqlStringLoader{(*crypto.Hash)(&summary.ID)}, &summary.Timestamp, &summary.OutputCount,
+		sqlStringLoader{&summary.OutputValue}, &histogram,
+		&summary.TransactionCount, sqlStringLoader{&summary.ValueTransferred}, sqlStringLoader{&summary.Fees},
+		sqlStringLoader{&summary.MinerPayouts}, sqlStringLoader{&summary.Creator},
+		sqlStringLoader{difficultyLoader{&summary.Difficulty}})
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		return BlockSummary{}, ErrNotFound
+	default:
+		return BlockSummary{}, fmt.Errorf("%s: failed to get summary of block %d: %v", sdb.dialect.Name, height, err)
+	}
+	err = json.Unmarshal([]byte(histogram), &summary.Histogram)
+	if err != nil {
+		return BlockSummary{}, fmt.Errorf(
+			"%s: failed to decode histogram of block %d: %v", sdb.dialect.Name, height, err)
+	}
+	return summary, nil
+}
+
+// GetWallet implements Database.GetWallet
+func (sdb *SQLDatabase) GetWallet(address types.UnlockHash) (Wallet, error) {
+	var wallet Wallet
+	err := sdb.queryRow(`SELECT unlocked, locked, signatures_required FROM rexplorer_wallets WHERE address = ?`,
+		address.String()).Scan(sqlStringLoader{&wallet.Balance.Unlocked}, sqlStringLoader{&wallet.Balance.Locked.Total},
+		&wallet.MultiSignData.SignaturesRequired)
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		return Wallet{}, ErrNotFound
+	default:
+		return Wallet{}, fmt.Errorf("%s: failed to get wallet for %s: %v", sdb.dialect.Name, address.String(), err)
+	}
+
+	// collect the locked outputs
+	rows, err := sdb.query(`SELECT id, value, lock_type, lock_value, description FROM rexplorer_coin_outputs
+		WHERE unlockhash = ? AND state = ?`, address.String(), int64(CoinOutputStateLocked))
+	if err != nil {
+		return Wallet{}, fmt.Errorf("%s: failed to get locked outputs of wallet %s: %v", sdb.dialect.Name, address.String(), err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			id        types.CoinOutputID
+			lockType  LockType
+			lockValue LockValue
+			output    WalletLockedOutput
+		)
+		err = rows.Scan(sqlStringLoader{&id}, sqlStringLoader{&output.Amount},
+			sqlStringLoader{&lockType}, sqlStringLoader{&lockValue}, &output.Description)
+		if err != nil {
+			return Wallet{}, fmt.Errorf("%s: failed to scan locked output of wallet %s: %v", sdb.dialect.Name, address.String(), err)
+		}
+		output.LockedUntil = sdb.lockValueAsLockTime(lockType, lockValue)
+		output.Reason = CoinOutputLockReason(lockType, output.Description)
+		if wallet.Balance.Locked.Outputs == nil {
+			wallet.Balance.Locked.Outputs = make(WalletLockedOutputMap)
+		}
+		wallet.Balance.Locked.Outputs[id] = output
+	}
+	err = rows.Err()
+	if err != nil {
+		return Wallet{}, fmt.Errorf("%s: failed to get locked outputs of wallet %s: %v", sdb.dialect.Name, address.String(), err)
+	}
+	wallet.Balance.Locked.updateHorizons(sdb.networkTime)
+
+	// collect the multisig properties
+	wallet.MultiSignData.Owners, err = sdb.queryAddresses(`SELECT owner FROM rexplorer_multisig_owners WHERE address = ?`, address.String())
+	if err != nil {
+		return Wallet{}, fmt.Errorf("%s: failed to get multisig owners of wallet %s: %v", sdb.dialect.Name, address.String(), err)
+	}
+	wallet.MultiSignAddresses, err = sdb.queryAddresses(`SELECT address FROM rexplorer_multisig_owners WHERE owner = ?`, address.String())
+	if err != nil {
+		return Wallet{}, fmt.Errorf("%s: failed to get multisig addresses of wallet %s: %v", sdb.dialect.Name, address.String(), err)
+	}
+	// the wallet is assembled from the columns as stored, hence always in the latest structure
+	wallet.Version = WalletVersion
+	return wallet, nil
+}
+
+// queryAddresses returns all addresses returned by a single-column query.
+func (sdb *SQLDatabase) queryAddresses(query string, args ...interface{}) ([]types.UnlockHash, error) {
+	rows, err := sdb.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var addresses []types.UnlockHash
+	for rows.Next() {
+		var uh types.UnlockHash
+		err = rows.Scan(sqlStringLoader{&uh})
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, uh)
+	}
+	return addresses, rows.Err()
+}
+
+// SampleAddresses implements Database.SampleAddresses
+func (sdb *SQLDatabase) SampleAddresses(n int) ([]types.UnlockHash, error) {
+	addresses, err := sdb.queryAddresses(`SELECT address FROM rexplorer_wallets ORDER BY RANDOM() LIMIT ?`, n)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to sample %d addresses: %v", sdb.dialect.Name, n, err)
+	}
+	return addresses, nil
+}
+
+// GetCoinOutput implements Database.GetCoinOutput
+func (sdb *SQLDatabase) GetCoinOutput(id types.CoinOutputID) (CoinOutputInfo, error) {
+	info := CoinOutputInfo{ID: id}
+	err := sdb.queryRow(`SELECT unlockhash, value, state, lock_type, lock_value, description, raw_condition
+		FROM rexplorer_coin_outputs WHERE id = ?`, id.String()).Scan(
+		sqlStringLoader{&info.UnlockHash}, sqlStringLoader{&info.Value}, sqlStringLoader{&info.State},
+		sqlStringLoader{&info.LockType}, sqlStringLoader{&info.LockValue},
+		(*[]byte)(&info.Description), (*[]byte)(&info.RawCondition))
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		return CoinOutputInfo{}, ErrNotFound
+	default:
+		return CoinOutputInfo{}, fmt.Errorf("%s: failed to get coin output %s: %v", sdb.dialect.Name, id.String(), err)
+	}
+	err = info.decodeCondition()
+	if err != nil {
+		return CoinOutputInfo{}, fmt.Errorf(
+			"%s: failed to decode raw condition of coin output %s: %v", sdb.dialect.Name, id.String(), err)
+	}
+	return info, nil
+}
+
+// sqlStringLoader is used to scan a (text or numeric) column into a StringLoader,
+// such that all values can be scanned using the same (string) format as used for their CSV encoding.
+type sqlStringLoader struct {
+	StringLoader
+}
+
+// Scan implements sql.Scanner.Scan
+func (sl sqlStringLoader) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case string:
+		return sl.LoadString(v)
+	case []byte:
+		return sl.LoadString(string(v))
+	case int64:
+		return sl.LoadString(strconv.FormatInt(v, 10))
+	default:
+		return fmt.Errorf("cannot scan value of type %T as a string", src)
+	}
+}
+
+func (sdb *SQLDatabase) lockValueAsLockTime(lt LockType, value LockValue) LockValue {
+	switch lt {
+	case LockTypeTime:
+		return value
+	case LockTypeHeight:
+		return LockValue(sdb.networkTime) + (value-LockValue(sdb.networkBlockHeight))*sdb.blockFrequency
+	default:
+		panic(fmt.Sprintf("invalid lock type %d", lt))
+	}
+}
@@ -0,0 +1,29 @@
+//go:build sqlite
+// +build sqlite
+
+package rexplorer
+
+import (
+	// registers the "sqlite3" database/sql driver
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteDialect defines the SQLite dialect used by the SQLDatabase.
+//
+// SQLite has no arbitrary-precision numeric type, hence currencies are stored as text.
+var sqliteDialect = SQLDialect{
+	Name:             "sqlite",
+	CurrencyType:     "TEXT",
+	BytesType:        "BLOB",
+	SingleConnection: true,
+}
+
+func init() {
+	RegisterDatabaseDriver("sqlite", func(cfg DatabaseConfig) (Database, error) {
+		address := cfg.Address
+		if address == "" {
+			address = "file:rexplorer.sqlite?_journal_mode=WAL&_busy_timeout=5000"
+		}
+		return NewSQLDatabase("sqlite3", address, sqliteDialect, cfg.BlockchainInfo, cfg.ChainConstants)
+	})
+}
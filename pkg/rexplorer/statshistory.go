@@ -0,0 +1,77 @@
+package rexplorer
+
+import (
+	"fmt"
+
+	"github.com/rivine/rivine/types"
+)
+
+// NetworkStatsSnapshot is a snapshot of the NetworkStats as of the latest block created on a single UTC day,
+// such that historical charts of the supply, the transaction count and the locked coins can be built
+// without replaying the chain. FirstHeight is the height of the first block created on that day,
+// used to drop the snapshot once all blocks of that day are reverted.
+type NetworkStatsSnapshot struct {
+	Date        string            `json:"date"`
+	FirstHeight types.BlockHeight `json:"firstHeight"`
+	Stats       NetworkStats      `json:"stats"`
+}
+
+// applyNetworkStatsSnapshot snapshots the network stats as of the given block, applied at the current block height,
+// as the snapshot of the day it was created on, in case the database supports it.
+// It has to be called once the block is applied completely, including the difficulty its children have to meet.
+func (explorer *Explorer) applyNetworkStatsSnapshot(block types.Block) {
+	nshdb, ok := explorer.db.(NetworkStatsHistoryDatabase)
+	if !ok {
+		return
+	}
+	date := walletGroupFlowsDay(block.Timestamp)
+	snapshot, err := nshdb.GetNetworkStatsSnapshot(date)
+	switch err {
+	case nil:
+	case ErrNotFound:
+		snapshot = NetworkStatsSnapshot{Date: date, FirstHeight: explorer.stats.BlockHeight}
+	default:
+		panic(fmt.Sprintf("failed to get network stats snapshot of %s: %v", date, err))
+	}
+	snapshot.Stats = explorer.stats
+	snapshot.Stats.updateVelocity()
+	snapshot.Stats.updateSupply()
+	err = nshdb.SetNetworkStatsSnapshot(snapshot)
+	if err != nil {
+		panic(fmt.Sprintf("failed to set network stats snapshot of %s: %v", date, err))
+	}
+}
+
+// revertNetworkStatsSnapshot restores the snapshot of the day the given block was created on,
+// reverted at the given height, as the network stats as of the block prior to it, in case the database supports it.
+// The snapshot is dropped if the reverted block was the first block of its day.
+// It has to be called once the block is reverted completely.
+func (explorer *Explorer) revertNetworkStatsSnapshot(block types.Block, height types.BlockHeight) {
+	nshdb, ok := explorer.db.(NetworkStatsHistoryDatabase)
+	if !ok {
+		return
+	}
+	date := walletGroupFlowsDay(block.Timestamp)
+	snapshot, err := nshdb.GetNetworkStatsSnapshot(date)
+	switch err {
+	case nil:
+	case ErrNotFound:
+		return // the block was applied prior to upgrading an existing database
+	default:
+		panic(fmt.Sprintf("failed to get network stats snapshot of %s: %v", date, err))
+	}
+	if snapshot.FirstHeight >= height {
+		err = nshdb.DeleteNetworkStatsSnapshot(date)
+		if err != nil {
+			panic(fmt.Sprintf("failed to delete network stats snapshot of %s: %v", date, err))
+		}
+		return
+	}
+	snapshot.Stats = explorer.stats
+	snapshot.Stats.updateVelocity()
+	snapshot.Stats.updateSupply()
+	err = nshdb.SetNetworkStatsSnapshot(snapshot)
+	if err != nil {
+		panic(fmt.Sprintf("failed to set network stats snapshot of %s: %v", date, err))
+	}
+}
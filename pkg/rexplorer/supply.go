@@ -0,0 +1,117 @@
+package rexplorer
+
+import (
+	"github.com/rivine/rivine/types"
+)
+
+// SupplyBreakdown breaks the coins of the network down into the liquid coins, the coins locked by time
+// and by block height, and the burned coins, which together make up the total amount of coins.
+// The genesis coins are the part of the coins which are still held by the (unspent) coin outputs
+// allocated by the genesis block (e.g. the foundation holdings), no matter if they are liquid or locked.
+//
+// The supply breakdown is derived from the other network stats, and thus not part of the stats checksum.
+type SupplyBreakdown struct {
+	Liquid       types.Currency `json:"liquid"`
+	TimeLocked   types.Currency `json:"timeLocked"`
+	HeightLocked types.Currency `json:"heightLocked"`
+	Burned       types.Currency `json:"burned"`
+	Genesis      types.Currency `json:"genesis"`
+}
+
+// conditionLockType returns the type of the lock of a coin output protected by the given (locked) condition.
+func conditionLockType(condition types.UnlockConditionProxy) LockType {
+	tlc, ok := condition.Condition.(*types.TimeLockCondition)
+	if !ok {
+		return LockTypeNone
+	}
+	if tlc.LockTime < types.LockTimeMinTimestampValue {
+		return LockTypeHeight
+	}
+	return LockTypeTime
+}
+
+// lockCoins registers the given coins as locked by the given lock type.
+func (stats *NetworkStats) lockCoins(lt LockType, coins types.Currency) {
+	switch lt {
+	case LockTypeHeight:
+		stats.HeightLockedCoins = stats.HeightLockedCoins.Add(coins)
+	case LockTypeTime:
+		stats.TimeLockedCoins = stats.TimeLockedCoins.Add(coins)
+	}
+}
+
+// unlockCoins unregisters the given coins as locked by the given lock type.
+func (stats *NetworkStats) unlockCoins(lt LockType, coins types.Currency) {
+	switch lt {
+	case LockTypeHeight:
+		stats.HeightLockedCoins = stats.subUntracked("height locked coins", stats.HeightLockedCoins, coins)
+	case LockTypeTime:
+		stats.TimeLockedCoins = stats.subUntracked("time locked coins", stats.TimeLockedCoins, coins)
+	}
+}
+
+// updateSupply recomputes the supply breakdown of the network stats.
+//
+// Burned coin outputs are created as unlocked (see newCoinOutputCreation), such that the locked coins
+// and the burned coins don't overlap, and the liquid coins are the coins which are neither locked nor burned.
+func (stats *NetworkStats) updateSupply() {
+	stats.Supply = SupplyBreakdown{
+		Liquid:       subCurrencyOrZero(subCurrencyOrZero(stats.Coins, stats.LockedCoins), stats.BurnedCoins),
+		TimeLocked:   stats.TimeLockedCoins,
+		HeightLocked: stats.HeightLockedCoins,
+		Burned:       stats.BurnedCoins,
+		Genesis:      stats.GenesisCoins,
+	}
+}
+
+// isGenesisCoinOutput returns true if the coin output with the given ID is allocated by the genesis block.
+func (explorer *Explorer) isGenesisCoinOutput(id types.CoinOutputID) bool {
+	if explorer.genesisCoinOutputs == nil {
+		explorer.genesisCoinOutputs = make(map[types.CoinOutputID]struct{})
+		for _, co := range newGenesisAllocation(explorer.chainCts.GenesisBlock()).CoinOutputs {
+			explorer.genesisCoinOutputs[co.ID] = struct{}{}
+		}
+	}
+	_, ok := explorer.genesisCoinOutputs[id]
+	return ok
+}
+
+// applyCoinLocks unlocks the coin outputs which can be spent as of the current block height and time,
+// registering the coins unlocked by block height separately from those unlocked by time,
+// given the time of the previous block. It returns the amount and value of the coin outputs unlocked by block height.
+func (explorer *Explorer) applyCoinLocks(previousTime types.Timestamp) (n uint64, coins types.Currency, err error) {
+	// as only the height changed since the previous call, only coin outputs locked by height can unlock
+	n, coins, err = explorer.db.ApplyCoinOutputLocks(explorer.stats.BlockHeight, previousTime)
+	if err != nil {
+		return 0, types.Currency{}, err
+	}
+	explorer.stats.unlockCoins(LockTypeHeight, coins)
+	nt, timeCoins, err := explorer.db.ApplyCoinOutputLocks(explorer.stats.BlockHeight, explorer.stats.Timestamp)
+	if err != nil {
+		return 0, types.Currency{}, err
+	}
+	explorer.stats.unlockCoins(LockTypeTime, timeCoins)
+	explorer.stats.LockedCointOutputCount -= n + nt
+	explorer.stats.LockedCoins = explorer.stats.LockedCoins.Sub(coins.Add(timeCoins))
+	return n, coins, nil
+}
+
+// revertCoinLocks locks the coin outputs which cannot be spent (yet) as of the current block height and time,
+// registering the coins locked by time separately from those locked by block height,
+// given the height of the reverted block. It returns the amount and value of the coin outputs locked by block height.
+func (explorer *Explorer) revertCoinLocks(revertedHeight types.BlockHeight) (n uint64, coins types.Currency, err error) {
+	// as only the time changed since the previous call, only coin outputs locked by time can lock
+	nt, timeCoins, err := explorer.db.RevertCoinOutputLocks(revertedHeight, explorer.stats.Timestamp)
+	if err != nil {
+		return 0, types.Currency{}, err
+	}
+	explorer.stats.lockCoins(LockTypeTime, timeCoins)
+	n, coins, err = explorer.db.RevertCoinOutputLocks(explorer.stats.BlockHeight, explorer.stats.Timestamp)
+	if err != nil {
+		return 0, types.Currency{}, err
+	}
+	explorer.stats.lockCoins(LockTypeHeight, coins)
+	explorer.stats.LockedCointOutputCount += n + nt
+	explorer.stats.LockedCoins = explorer.stats.LockedCoins.Add(coins.Add(timeCoins))
+	return n, coins, nil
+}
@@ -0,0 +1,291 @@
+package rexplorer
+
+import (
+	"fmt"
+
+	"github.com/rivine/rivine/types"
+)
+
+// The versions of the 3Bot transactions, as defined by tfchain,
+// supported on the networks of which the transaction controllers register them.
+const (
+	// TransactionVersionBotRegistration defines the transaction version of a 3Bot registration transaction,
+	// registering a new 3Bot, which gets the next available (sequential) bot ID assigned.
+	TransactionVersionBotRegistration types.TransactionVersion = 144
+	// TransactionVersionBotRecordUpdate defines the transaction version of a 3Bot record update transaction,
+	// updating the addresses and names of a 3Bot, and/or extending its expiration.
+	TransactionVersionBotRecordUpdate types.TransactionVersion = 145
+	// TransactionVersionBotNameTransfer defines the transaction version of a 3Bot name transfer transaction,
+	// transferring names from one 3Bot to another.
+	TransactionVersionBotNameTransfer types.TransactionVersion = 146
+)
+
+// BotMonth is the duration (in seconds) of a single month a 3Bot is paid for, as defined by tfchain.
+const BotMonth = 60 * 60 * 24 * 30
+
+type (
+	// BotID identifies a 3Bot, the first registered 3Bot having ID 1.
+	BotID uint32
+
+	// BotRecord records the (latest) state of a single 3Bot, as registered and updated by 3Bot transactions.
+	// The transaction ID is the ID of the transaction which created this state of the record.
+	// A 3Bot is expired once its expiration lies in the past, in which case its names can be claimed by other 3Bots.
+	BotRecord struct {
+		ID            BotID               `json:"id"`
+		Addresses     []string            `json:"addresses,omitempty"`
+		Names         []string            `json:"names,omitempty"`
+		PublicKey     types.SiaPublicKey  `json:"publickey"`
+		Expiration    types.Timestamp     `json:"expiration"`
+		TransactionID types.TransactionID `json:"txID"`
+	}
+
+	// botRecordChanges defines the addresses or names added to and removed from a 3Bot record,
+	// as defined by a 3Bot record update transaction.
+	botRecordChanges struct {
+		Add    []string `json:"add"`
+		Remove []string `json:"remove"`
+	}
+)
+
+// botExpiration returns the expiration of a 3Bot paid for the given amount of months, starting at the given time.
+// Just like tfchain, expirations are stored with an accuracy of a minute.
+func botExpiration(start types.Timestamp, months uint8) types.Timestamp {
+	return start - start%60 + types.Timestamp(months)*BotMonth
+}
+
+// updateBotRecordValues returns the given values, without the removed values and with the added values appended,
+// skipping added values which are already present.
+func updateBotRecordValues(values []string, changes botRecordChanges) []string {
+	var updated []string
+	for _, value := range values {
+		if !containsString(changes.Remove, value) {
+			updated = append(updated, value)
+		}
+	}
+	for _, value := range changes.Add {
+		if !containsString(updated, value) {
+			updated = append(updated, value)
+		}
+	}
+	return updated
+}
+
+// containsString returns true if the given value is part of the given values.
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// getBotRecordUpdates returns the updated records of the 3Bots registered or updated by the given transaction,
+// which has to be a 3Bot transaction, as applied at the current block time. Updates of 3Bots
+// which aren't stored (as they were registered prior to the dataset indexing them) are skipped.
+func (explorer *Explorer) getBotRecordUpdates(tbdb ThreeBotDatabase, tx types.Transaction) ([]BotRecord, error) {
+	txID := tx.ID()
+	switch tx.Version {
+	case TransactionVersionBotRegistration:
+		var data struct {
+			Addresses      []string `json:"addresses"`
+			Names          []string `json:"names"`
+			NrOfMonths     uint8    `json:"nrofmonths"`
+			Identification struct {
+				PublicKey types.SiaPublicKey `json:"publickey"`
+			} `json:"identification"`
+		}
+		err := unmarshalTransactionData(tx, &data)
+		if err != nil {
+			return nil, err
+		}
+		count, err := tbdb.GetBotCount()
+		if err != nil {
+			return nil, err
+		}
+		return []BotRecord{{
+			ID:            count + 1,
+			Addresses:     data.Addresses,
+			Names:         data.Names,
+			PublicKey:     data.Identification.PublicKey,
+			Expiration:    botExpiration(explorer.stats.Timestamp, data.NrOfMonths),
+			TransactionID: txID,
+		}}, nil
+
+	case TransactionVersionBotRecordUpdate:
+		var data struct {
+			ID         BotID            `json:"id"`
+			Addresses  botRecordChanges `json:"addresses"`
+			Names      botRecordChanges `json:"names"`
+			NrOfMonths uint8            `json:"nrofmonths"`
+		}
+		err := unmarshalTransactionData(tx, &data)
+		if err != nil {
+			return nil, err
+		}
+		record, err := tbdb.GetBotRecord(data.ID)
+		if err == ErrNotFound {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if record.Expiration <= explorer.stats.Timestamp {
+			// the names of an expired 3Bot are released, and its (new) expiration starts now
+			record.Names = nil
+			record.Expiration = botExpiration(explorer.stats.Timestamp, data.NrOfMonths)
+		} else {
+			record.Expiration += types.Timestamp(data.NrOfMonths) * BotMonth
+		}
+		record.Addresses = updateBotRecordValues(record.Addresses, data.Addresses)
+		record.Names = updateBotRecordValues(record.Names, data.Names)
+		record.TransactionID = txID
+		return []BotRecord{record}, nil
+
+	case TransactionVersionBotNameTransfer:
+		var data struct {
+			Sender struct {
+				ID BotID `json:"id"`
+			} `json:"sender"`
+			Receiver struct {
+				ID BotID `json:"id"`
+			} `json:"receiver"`
+			Names []string `json:"names"`
+		}
+		err := unmarshalTransactionData(tx, &data)
+		if err != nil {
+			return nil, err
+		}
+		var records []BotRecord
+		for _, update := range []struct {
+			id      BotID
+			changes botRecordChanges
+		}{
+			{data.Sender.ID, botRecordChanges{Remove: data.Names}},
+			{data.Receiver.ID, botRecordChanges{Add: data.Names}},
+		} {
+			record, err := tbdb.GetBotRecord(update.id)
+			if err == ErrNotFound {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			record.Names = updateBotRecordValues(record.Names, update.changes)
+			record.TransactionID = txID
+			records = append(records, record)
+		}
+		return records, nil
+
+	default:
+		return nil, nil
+	}
+}
+
+// updateBotNames updates the reverse index from name to bot ID, given the previous and updated names of a 3Bot.
+// Names no longer owned by the 3Bot are only dropped from the index if they still refer to that 3Bot,
+// as they might have been claimed by another 3Bot (e.g. the receiver of a name transfer) in the meantime.
+func updateBotNames(tbdb ThreeBotDatabase, id BotID, previous, updated []string) error {
+	for _, name := range previous {
+		if containsString(updated, name) {
+			continue
+		}
+		owner, err := tbdb.GetBotIDByName(name)
+		if err == ErrNotFound || (err == nil && owner != id) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		err = tbdb.DeleteBotName(name)
+		if err != nil {
+			return err
+		}
+	}
+	for _, name := range updated {
+		err := tbdb.SetBotName(name, id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyBotRecords registers or updates the 3Bot records of the given transaction,
+// in case it is a 3Bot transaction and the database supports it.
+func (explorer *Explorer) applyBotRecords(tx types.Transaction) {
+	tbdb, ok := explorer.db.(ThreeBotDatabase)
+	if !ok {
+		return
+	}
+	records, err := explorer.getBotRecordUpdates(tbdb, tx)
+	if err != nil {
+		panic(fmt.Sprintf("failed to get 3Bot records updated by tx %s: %v", tx.ID().String(), err))
+	}
+	for _, record := range records {
+		var previous []string
+		if tx.Version != TransactionVersionBotRegistration {
+			old, err := tbdb.GetBotRecord(record.ID)
+			if err != nil {
+				panic(fmt.Sprintf("failed to get 3Bot record %d: %v", record.ID, err))
+			}
+			previous = old.Names
+		}
+		err = updateBotNames(tbdb, record.ID, previous, record.Names)
+		if err == nil {
+			err = tbdb.SetBotRecord(record)
+		}
+		if err != nil {
+			panic(fmt.Sprintf("failed to set 3Bot record %d: %v", record.ID, err))
+		}
+	}
+}
+
+// revertBotRecords reverts the 3Bot records registered or updated by the given transaction to their previous state,
+// in case it is a 3Bot transaction and the database supports it, skipping the records which weren't updated by it.
+func (explorer *Explorer) revertBotRecords(tx types.Transaction) {
+	tbdb, ok := explorer.db.(ThreeBotDatabase)
+	if !ok {
+		return
+	}
+	var ids []BotID
+	switch tx.Version {
+	case TransactionVersionBotRegistration:
+		// as 3Bots are reverted in the reverse order they were registered in, the last registered 3Bot is reverted
+		count, err := tbdb.GetBotCount()
+		if err != nil {
+			panic(fmt.Sprintf("failed to get 3Bot count: %v", err))
+		}
+		ids = append(ids, count)
+	case TransactionVersionBotRecordUpdate, TransactionVersionBotNameTransfer:
+		records, err := explorer.getBotRecordUpdates(tbdb, tx)
+		if err != nil {
+			panic(fmt.Sprintf("failed to get 3Bot records updated by tx %s: %v", tx.ID().String(), err))
+		}
+		for _, record := range records {
+			ids = append(ids, record.ID)
+		}
+	default:
+		return
+	}
+	txID := tx.ID()
+	for _, id := range ids {
+		record, err := tbdb.GetBotRecord(id)
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			panic(fmt.Sprintf("failed to get 3Bot record %d: %v", id, err))
+		}
+		if record.TransactionID != txID {
+			continue
+		}
+		previous, err := tbdb.RevertBotRecord(id)
+		if err == nil {
+			err = updateBotNames(tbdb, id, record.Names, previous.Names)
+		}
+		if err != nil {
+			panic(fmt.Sprintf("failed to revert 3Bot record %d: %v", id, err))
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package rexplorer
+
+import (
+	"fmt"
+
+	"github.com/rivine/rivine/types"
+)
+
+// AddressTotals records the total value of the coins received and sent by an address over its lifetime,
+// such that its net flow can be analysed without replaying its history. All coin outputs created for the address
+// are received (including miner payouts and the change of its own transactions), and all coin outputs it spent are sent,
+// such that the total received minus the total sent equals the (unlocked and locked) balance of the address.
+type AddressTotals struct {
+	TotalReceived types.Currency `json:"totalReceived"`
+	TotalSent     types.Currency `json:"totalSent"`
+}
+
+// IsZero returns true if the address neither received nor sent any coins.
+func (at AddressTotals) IsZero() bool {
+	return at.TotalReceived.IsZero() && at.TotalSent.IsZero()
+}
+
+// addressTotalsDelta collects the coins received and sent by each address within a single block.
+type addressTotalsDelta map[types.UnlockHash]*AddressTotals
+
+// totals returns the totals of the given address, adding them if they don't exist yet.
+func (delta addressTotalsDelta) totals(uh types.UnlockHash) *AddressTotals {
+	totals, ok := delta[uh]
+	if !ok {
+		totals = &AddressTotals{TotalReceived: types.ZeroCurrency, TotalSent: types.ZeroCurrency}
+		delta[uh] = totals
+	}
+	return totals
+}
+
+// receive adds the given value to the coins received by the given address.
+func (delta addressTotalsDelta) receive(uh types.UnlockHash, value types.Currency) {
+	totals := delta.totals(uh)
+	totals.TotalReceived = totals.TotalReceived.Add(value)
+}
+
+// send adds the given value to the coins sent by the given address.
+func (delta addressTotalsDelta) send(uh types.UnlockHash, value types.Currency) {
+	totals := delta.totals(uh)
+	totals.TotalSent = totals.TotalSent.Add(value)
+}
+
+// addresses returns the addresses of which the totals changed, sorted.
+func (delta addressTotalsDelta) addresses() []types.UnlockHash {
+	set := make(map[types.UnlockHash]struct{}, len(delta))
+	for uh := range delta {
+		set[uh] = struct{}{}
+	}
+	return sortedAddresses(set)
+}
+
+// applyAddressTotals adds the coins received and sent within the block applied at the current block height
+// to the totals of the addresses involved, in case the database supports it.
+func (explorer *Explorer) applyAddressTotals(delta addressTotalsDelta) {
+	atdb, ok := explorer.db.(AddressTotalsDatabase)
+	if !ok {
+		return
+	}
+	for _, uh := range delta.addresses() {
+		err := atdb.ApplyAddressTotals(uh, *delta[uh])
+		if err != nil {
+			panic(fmt.Sprintf("failed to apply totals of block %d to %s: %v", explorer.stats.BlockHeight, uh.String(), err))
+		}
+	}
+}
+
+// revertAddressTotals subtracts the coins received and sent within the block reverted at the current block height
+// from the totals of the addresses involved, in case the database supports it.
+func (explorer *Explorer) revertAddressTotals(delta addressTotalsDelta) {
+	atdb, ok := explorer.db.(AddressTotalsDatabase)
+	if !ok {
+		return
+	}
+	for _, uh := range delta.addresses() {
+		err := atdb.RevertAddressTotals(uh, *delta[uh])
+		if err != nil {
+			panic(fmt.Sprintf("failed to revert totals of block %d from %s: %v", explorer.stats.BlockHeight, uh.String(), err))
+		}
+	}
+}
@@ -0,0 +1,641 @@
+package rexplorer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rivine/rivine/encoding"
+	"github.com/rivine/rivine/types"
+)
+
+// Environment variables defining the default OTLP endpoint and headers,
+// as defined by the OpenTelemetry specification.
+const (
+	OTLPEndpointEnvVar = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	OTLPHeadersEnvVar  = "OTEL_EXPORTER_OTLP_HEADERS"
+)
+
+// The defaults of a Tracer.
+const (
+	// DefaultTraceSampleRatio is the default ratio of the consensus changes which are traced
+	DefaultTraceSampleRatio = 1.0
+
+	// otlpTracesPath is the path of the OTLP/HTTP traces endpoint, relative to the OTLP endpoint
+	otlpTracesPath = "/v1/traces"
+	// otlpExportInterval is the maximum duration a span is buffered, prior to being exported
+	otlpExportInterval = 5 * time.Second
+	// otlpExportTimeout is the maximum duration a single export can take
+	otlpExportTimeout = 10 * time.Second
+	// otlpBatchSize is the maximum amount of spans exported at once
+	otlpBatchSize = 512
+	// otlpQueueSize is the amount of spans that can be queued,
+	// before spans are dropped in order not to block the explorer
+	otlpQueueSize = 8192
+)
+
+// The kinds and status codes of a span, as defined by OTLP.
+const (
+	otlpSpanKindInternal = 1
+	otlpSpanKindClient   = 3
+
+	otlpStatusCodeError = 2
+)
+
+// TracerConfig collects all configuration used to create a Tracer.
+type TracerConfig struct {
+	// Endpoint is the base URL of the OTLP/HTTP collector (e.g. "http://localhost:4318"),
+	// to which the path of the traces endpoint (/v1/traces) is appended.
+	Endpoint string
+	// Headers are added to every export request (e.g. used for authentication).
+	Headers map[string]string
+	// SampleRatio is the ratio (in the [0, 1] range) of the consensus changes which are traced,
+	// all consensus changes are traced if 1 (DefaultTraceSampleRatio).
+	SampleRatio float64
+
+	BlockchainInfo types.BlockchainInfo
+}
+
+// Tracer traces the processing of consensus changes using OpenTelemetry spans,
+// exporting them in the background to an OTLP/HTTP collector (using the JSON encoding),
+// such that slow database operations and oversized blocks can be diagnosed using distributed tracing tools.
+//
+// Each (sampled) consensus change is traced as a single trace, of which the root span covers the processing
+// of the entire consensus change, with a child span for every block reverted and applied, as well as for the
+// calls made to the database to store the consensus change, and (for the Redis drivers) every round trip to the Redis server.
+// Database calls made outside of a traced consensus change (e.g. by the API) aren't traced.
+//
+// Exporting is best effort: spans which can't be exported are logged and dropped,
+// and never block (or break) the explorer. A nil Tracer traces nothing.
+type Tracer struct {
+	endpoint    string
+	headers     map[string]string
+	sampleRatio float64
+	resource    []otlpKeyValue
+	client      *http.Client
+
+	mu sync.Mutex
+	// the innermost span in progress of the trace in progress, nil if no trace is in progress,
+	// the parent of the spans started by the database
+	current *traceSpan
+
+	queue   chan *traceSpan
+	flush   chan chan struct{}
+	closing chan struct{}
+	done    sync.WaitGroup
+	once    sync.Once
+}
+
+// NewTracer creates a new Tracer, exporting its spans to the OTLP/HTTP collector defined by the given config.
+func NewTracer(cfg TracerConfig) (*Tracer, error) {
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q: expected an absolute HTTP(S) URL", cfg.Endpoint)
+	}
+	if cfg.SampleRatio < 0 || cfg.SampleRatio > 1 {
+		return nil, fmt.Errorf("invalid trace sample ratio %v: expected a ratio within the [0, 1] range", cfg.SampleRatio)
+	}
+	resource := []otlpKeyValue{
+		otlpAttribute("service.name", "rexplorer"),
+		otlpAttribute("service.version", version.String()),
+		otlpAttribute("rexplorer.chain", cfg.BlockchainInfo.Name),
+		otlpAttribute("rexplorer.network", cfg.BlockchainInfo.NetworkName),
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		resource = append(resource, otlpAttribute("host.name", hostname))
+	}
+	tracer := &Tracer{
+		endpoint:    strings.TrimRight(cfg.Endpoint, "/") + otlpTracesPath,
+		headers:     cfg.Headers,
+		sampleRatio: cfg.SampleRatio,
+		resource:    resource,
+		client:      &http.Client{Timeout: otlpExportTimeout},
+		queue:       make(chan *traceSpan, otlpQueueSize),
+		flush:       make(chan chan struct{}),
+		closing:     make(chan struct{}),
+	}
+	tracer.done.Add(1)
+	go tracer.run()
+	return tracer, nil
+}
+
+// ParseOTLPHeaders parses headers defined in the "key1=value1,key2=value2" format
+// (as used by the OTEL_EXPORTER_OTLP_HEADERS environment variable), adding them to the given headers.
+func ParseOTLPHeaders(str string, headers map[string]string) error {
+	for _, pair := range strings.Split(str, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		key := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || key == "" {
+			return fmt.Errorf("invalid OTLP header %q: expected the key=value format", pair)
+		}
+		value, err := url.QueryUnescape(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return fmt.Errorf("invalid OTLP header %q: %v", pair, err)
+		}
+		headers[key] = value
+	}
+	return nil
+}
+
+// Close the tracer, exporting all spans queued so far. Closing a nil tracer is a no-op.
+func (tracer *Tracer) Close() error {
+	if tracer == nil {
+		return nil
+	}
+	tracer.once.Do(func() {
+		close(tracer.closing)
+		tracer.done.Wait()
+	})
+	return nil
+}
+
+// startTrace starts a new trace, of which the root span is returned,
+// nil if the tracer is nil or the trace isn't sampled.
+// The root span is the parent of the spans started by the database until it ends.
+func (tracer *Tracer) startTrace(name string, attributes ...otlpKeyValue) *traceSpan {
+	if tracer == nil || !tracer.sample() {
+		return nil
+	}
+	span := &traceSpan{
+		tracer:     tracer,
+		name:       name,
+		kind:       otlpSpanKindInternal,
+		start:      time.Now(),
+		attributes: attributes,
+	}
+	rand.Read(span.traceID[:])
+	rand.Read(span.spanID[:])
+	tracer.mu.Lock()
+	tracer.current = span
+	tracer.mu.Unlock()
+	return span
+}
+
+// sample decides whether or not a new trace is sampled, using the sample ratio of the tracer.
+func (tracer *Tracer) sample() bool {
+	if tracer.sampleRatio >= 1 {
+		return true
+	}
+	var b [8]byte
+	rand.Read(b[:])
+	return float64(binary.BigEndian.Uint64(b[:])>>11)/(1<<53) < tracer.sampleRatio
+}
+
+// currentSpan returns the innermost span in progress, nil if no trace is in progress.
+func (tracer *Tracer) currentSpan() *traceSpan {
+	if tracer == nil {
+		return nil
+	}
+	tracer.mu.Lock()
+	defer tracer.mu.Unlock()
+	return tracer.current
+}
+
+// queueSpan queues the given (ended) span to be exported,
+// dropping it (and logging as such) in case the queue is full.
+func (tracer *Tracer) queueSpan(span *traceSpan) {
+	select {
+	case tracer.queue <- span:
+	default:
+		log.Printf("[ERROR] trace queue is full, dropping span %s", span.name)
+	}
+}
+
+// flushSpans exports all spans queued so far, waiting until they are exported.
+func (tracer *Tracer) flushSpans() {
+	flushed := make(chan struct{})
+	select {
+	case tracer.flush <- flushed:
+		<-flushed
+	case <-tracer.closing:
+	}
+}
+
+func (tracer *Tracer) run() {
+	defer tracer.done.Done()
+	ticker := time.NewTicker(otlpExportInterval)
+	defer ticker.Stop()
+	var batch []*traceSpan
+	export := func() {
+		for len(batch) > 0 {
+			n := len(batch)
+			if n > otlpBatchSize {
+				n = otlpBatchSize
+			}
+			err := tracer.export(batch[:n])
+			if err != nil {
+				log.Printf("[ERROR] failed to export %d spans to %s: %v", n, tracer.endpoint, err)
+			}
+			batch = batch[n:]
+		}
+		batch = nil
+	}
+	drain := func() {
+		for {
+			select {
+			case span := <-tracer.queue:
+				batch = append(batch, span)
+			default:
+				return
+			}
+		}
+	}
+	for {
+		select {
+		case <-tracer.closing:
+			drain()
+			export()
+			return
+		case flushed := <-tracer.flush:
+			drain()
+			export()
+			close(flushed)
+		case <-ticker.C:
+			export()
+		case span := <-tracer.queue:
+			batch = append(batch, span)
+			if len(batch) >= otlpBatchSize {
+				export()
+			}
+		}
+	}
+}
+
+// export the given spans to the OTLP/HTTP collector, as a single (JSON-encoded) ExportTraceServiceRequest.
+func (tracer *Tracer) export(spans []*traceSpan) error {
+	scope := otlpScopeSpans{
+		Scope: otlpScope{Name: "github.com/threefoldfoundation/rexplorer", Version: version.String()},
+		Spans: make([]otlpSpan, 0, len(spans)),
+	}
+	for _, span := range spans {
+		scope.Spans = append(scope.Spans, span.otlp())
+	}
+	b, err := json.Marshal(otlpTracesRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: tracer.resource},
+		ScopeSpans: []otlpScopeSpans{scope},
+	}}})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, tracer.endpoint, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "rexplorer/"+version.String())
+	for key, value := range tracer.headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := tracer.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
+
+// traceSpan is a single span of a trace. All methods of a nil span are no-ops,
+// such that code can be traced regardless of whether or not it is sampled.
+type traceSpan struct {
+	tracer  *Tracer
+	parent  *traceSpan
+	traceID [16]byte
+	spanID  [8]byte
+
+	name       string
+	kind       int
+	start, end time.Time
+	attributes []otlpKeyValue
+	err        error
+}
+
+// child starts a new span as child of the span,
+// which is the parent of the spans started by the database until it ends.
+func (span *traceSpan) child(name string, attributes ...otlpKeyValue) *traceSpan {
+	child := span.newChild(name, otlpSpanKindInternal, attributes)
+	if child != nil {
+		span.tracer.mu.Lock()
+		span.tracer.current = child
+		span.tracer.mu.Unlock()
+	}
+	return child
+}
+
+// newChild creates a new span as child of the span, nil if the span is nil.
+func (span *traceSpan) newChild(name string, kind int, attributes []otlpKeyValue) *traceSpan {
+	if span == nil {
+		return nil
+	}
+	child := &traceSpan{
+		tracer:     span.tracer,
+		parent:     span,
+		traceID:    span.traceID,
+		name:       name,
+		kind:       kind,
+		start:      time.Now(),
+		attributes: attributes,
+	}
+	rand.Read(child.spanID[:])
+	return child
+}
+
+// setAttributes adds the given attributes to the span.
+func (span *traceSpan) setAttributes(attributes ...otlpKeyValue) {
+	if span == nil {
+		return
+	}
+	span.attributes = append(span.attributes, attributes...)
+}
+
+// setBlockAttributes adds the attributes of the given block, at the given height, to the span,
+// its (binary-encoded) size being computed only if the span is not nil.
+func (span *traceSpan) setBlockAttributes(block types.Block, height types.BlockHeight) {
+	if span == nil {
+		return
+	}
+	span.setAttributes(
+		otlpAttribute("rexplorer.block.id", block.ID().String()),
+		otlpAttribute("rexplorer.block.height", height),
+		otlpAttribute("rexplorer.block.transactions", len(block.Transactions)),
+		otlpAttribute("rexplorer.block.size", len(encoding.Marshal(block))),
+	)
+}
+
+// finish ends the span, marking it as failed if the given error isn't nil.
+func (span *traceSpan) finish(err error) {
+	if span == nil {
+		return
+	}
+	if err != nil && span.err == nil {
+		span.err = err
+	}
+	span.end = time.Now()
+	span.tracer.mu.Lock()
+	if span.tracer.current == span {
+		// the parent of a root span is nil, ending the trace
+		span.tracer.current = span.parent
+	}
+	span.tracer.mu.Unlock()
+	span.tracer.queueSpan(span)
+}
+
+// finishDeferred ends the (deferred) span, marking it as failed if the stack is unwinding due to a panic,
+// in which case all spans queued so far are exported prior to panicking again.
+// The panic isn't recovered if the span is nil.
+func (span *traceSpan) finishDeferred() {
+	if span == nil {
+		return
+	}
+	if r := recover(); r != nil {
+		span.finish(fmt.Errorf("panic: %v", r))
+		span.tracer.flushSpans()
+		panic(r)
+	}
+	span.finish(nil)
+}
+
+// otlp returns the span as defined by OTLP.
+func (span *traceSpan) otlp() otlpSpan {
+	s := otlpSpan{
+		TraceID:           hex.EncodeToString(span.traceID[:]),
+		SpanID:            hex.EncodeToString(span.spanID[:]),
+		Name:              span.name,
+		Kind:              span.kind,
+		StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+		Attributes:        span.attributes,
+	}
+	if span.parent != nil {
+		s.ParentSpanID = hex.EncodeToString(span.parent.spanID[:])
+	}
+	if span.err != nil {
+		s.Status = otlpStatus{Code: otlpStatusCodeError, Message: span.err.Error()}
+	}
+	return s
+}
+
+// The (JSON-encoded) messages of the OTLP/HTTP traces endpoint,
+// see https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/trace/v1/trace.proto
+type (
+	otlpTracesRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            otlpStatus     `json:"status"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string       `json:"key"`
+		Value otlpAnyValue `json:"value"`
+	}
+	// otlpAnyValue defines a single value, of which exactly one field is defined,
+	// 64-bit integers being encoded as JSON strings
+	otlpAnyValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+	}
+)
+
+// otlpAttribute creates an attribute with the given key and (string, bool, integer or float64) value,
+// any other value being formatted as a string.
+func otlpAttribute(key string, value interface{}) otlpKeyValue {
+	var v otlpAnyValue
+	switch value := value.(type) {
+	case string:
+		v.StringValue = &value
+	case bool:
+		v.BoolValue = &value
+	case int:
+		str := strconv.FormatInt(int64(value), 10)
+		v.IntValue = &str
+	case int64:
+		str := strconv.FormatInt(value, 10)
+		v.IntValue = &str
+	case uint64:
+		str := strconv.FormatUint(value, 10)
+		v.IntValue = &str
+	case types.BlockHeight:
+		str := strconv.FormatUint(uint64(value), 10)
+		v.IntValue = &str
+	case float64:
+		v.DoubleValue = &value
+	default:
+		str := fmt.Sprint(value)
+		v.StringValue = &str
+	}
+	return otlpKeyValue{Key: key, Value: v}
+}
+
+// tracedConn is a redis.Conn which traces every round trip to the Redis server,
+// as a child of the span in progress of the tracer (if any), such that slow Redis operations can be diagnosed.
+// A round trip starts when the commands sent (using Send) are flushed, and ends once all their replies are received.
+type tracedConn struct {
+	redis.Conn
+	tracer *Tracer
+
+	// the commands sent since the last flush
+	sent []string
+	// the round trip in progress (if any) and the amount of replies it still expects
+	span    *traceSpan
+	pending int
+}
+
+// newTracedConn wraps the given connection, tracing its round trips using the given tracer.
+func newTracedConn(conn redis.Conn, tracer *Tracer) redis.Conn {
+	return &tracedConn{
+		Conn:   conn,
+		tracer: tracer,
+	}
+}
+
+// Do implements redis.Conn.Do
+func (c *tracedConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	commands := c.sent
+	if cmd != "" {
+		commands = append(commands, cmd)
+	}
+	c.sent = nil
+	span := c.startRoundTrip(commands)
+	reply, err := c.Conn.Do(cmd, args...)
+	// Do receives all pending replies
+	c.finishRoundTrip(err)
+	span.finish(err)
+	return reply, err
+}
+
+// Send implements redis.Conn.Send
+func (c *tracedConn) Send(cmd string, args ...interface{}) error {
+	c.sent = append(c.sent, cmd)
+	return c.Conn.Send(cmd, args...)
+}
+
+// Flush implements redis.Conn.Flush
+func (c *tracedConn) Flush() error {
+	commands := c.sent
+	c.sent = nil
+	if len(commands) > 0 {
+		// commands flushed before all replies of the round trip in progress are received extend that round trip
+		if c.span == nil {
+			c.span = c.startRoundTrip(commands)
+		}
+		c.pending += len(commands)
+	}
+	err := c.Conn.Flush()
+	if err != nil {
+		c.finishRoundTrip(err)
+	}
+	return err
+}
+
+// Receive implements redis.Conn.Receive
+func (c *tracedConn) Receive() (interface{}, error) {
+	reply, err := c.Conn.Receive()
+	if _, ok := err.(redis.Error); err != nil && !ok {
+		c.finishRoundTrip(err)
+		return reply, err
+	}
+	if c.pending > 0 {
+		c.pending--
+		if c.pending == 0 {
+			c.finishRoundTrip(nil)
+		}
+	}
+	return reply, err
+}
+
+// startRoundTrip starts the span of a round trip of the given commands,
+// nil if no trace is in progress.
+func (c *tracedConn) startRoundTrip(commands []string) *traceSpan {
+	parent := c.tracer.currentSpan()
+	if parent == nil || len(commands) == 0 {
+		return nil
+	}
+	name := commands[0]
+	if len(commands) > 1 {
+		name = "PIPELINE"
+	}
+	return parent.newChild("redis "+name, otlpSpanKindClient, []otlpKeyValue{
+		otlpAttribute("db.system", "redis"),
+		otlpAttribute("db.operation", name),
+		otlpAttribute("db.redis.commands", summarizeRedisCommands(commands)),
+		otlpAttribute("db.redis.pipeline_length", len(commands)),
+	})
+}
+
+// finishRoundTrip ends the round trip in progress (if any).
+func (c *tracedConn) finishRoundTrip(err error) {
+	if c.span == nil {
+		c.pending = 0
+		return
+	}
+	c.span.finish(err)
+	c.span = nil
+	c.pending = 0
+}
+
+// summarizeRedisCommands summarizes the given commands, as the sorted list of the distinct commands,
+// each followed by the amount of times it occurs (if more than once), e.g. "EXEC, HSET x12, MULTI".
+func summarizeRedisCommands(commands []string) string {
+	counts := make(map[string]int)
+	for _, cmd := range commands {
+		counts[strings.ToUpper(cmd)]++
+	}
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		if n := counts[name]; n > 1 {
+			names[i] = fmt.Sprintf("%s x%d", name, n)
+		}
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,107 @@
+package rexplorer
+
+import (
+	"fmt"
+
+	"github.com/rivine/rivine/types"
+)
+
+type (
+	// TransactionRecord records a single transaction, such that transaction pages can be rendered
+	// from the stored data alone, without requiring a daemon. Besides the transaction itself,
+	// it records the block it was applied in, the IDs of the outputs it created,
+	// and the owner and value of the coin outputs it spent.
+	TransactionRecord struct {
+		ID                types.TransactionID                 `json:"id"`
+		BlockHeight       types.BlockHeight                   `json:"blockHeight"`
+		BlockID           types.BlockID                       `json:"blockID"`
+		Version           types.TransactionVersion            `json:"version"`
+		CoinInputs        []TransactionRecordCoinInput        `json:"coinInputs,omitempty"`
+		CoinOutputs       []TransactionRecordCoinOutput       `json:"coinOutputs,omitempty"`
+		BlockStakeInputs  []types.BlockStakeInput             `json:"blockStakeInputs,omitempty"`
+		BlockStakeOutputs []TransactionRecordBlockStakeOutput `json:"blockStakeOutputs,omitempty"`
+		MinerFees         []types.Currency                    `json:"minerFees,omitempty"`
+		Fee               types.Currency                      `json:"fee"`
+		ArbitraryData     []byte                              `json:"arbitraryData,omitempty"`
+	}
+
+	// TransactionRecordCoinInput records a single coin input of a transaction,
+	// as well as the owner and value of the coin output it spent.
+	TransactionRecordCoinInput struct {
+		ParentID    types.CoinOutputID           `json:"parentID"`
+		Fulfillment types.UnlockFulfillmentProxy `json:"fulfillment"`
+		UnlockHash  types.UnlockHash             `json:"unlockhash"`
+		Value       types.Currency               `json:"value"`
+	}
+
+	// TransactionRecordCoinOutput records a single coin output of a transaction, as well as its ID.
+	TransactionRecordCoinOutput struct {
+		ID        types.CoinOutputID         `json:"id"`
+		Value     types.Currency             `json:"value"`
+		Condition types.UnlockConditionProxy `json:"condition"`
+	}
+
+	// TransactionRecordBlockStakeOutput records a single block stake output of a transaction, as well as its ID.
+	TransactionRecordBlockStakeOutput struct {
+		ID        types.BlockStakeOutputID   `json:"id"`
+		Value     types.Currency             `json:"value"`
+		Condition types.UnlockConditionProxy `json:"condition"`
+	}
+)
+
+// newTransactionRecord records the given transaction, applied in the given block at the given height.
+// The coin inputs are recorded as given, as their owner and value aren't part of the transaction.
+func newTransactionRecord(tx types.Transaction, blockID types.BlockID, height types.BlockHeight, inputs []TransactionRecordCoinInput) TransactionRecord {
+	record := TransactionRecord{
+		ID:               tx.ID(),
+		BlockHeight:      height,
+		BlockID:          blockID,
+		Version:          tx.Version,
+		CoinInputs:       inputs,
+		BlockStakeInputs: tx.BlockStakeInputs,
+		MinerFees:        tx.MinerFees,
+		Fee:              transactionFee(tx),
+		ArbitraryData:    tx.ArbitraryData,
+	}
+	for i, co := range tx.CoinOutputs {
+		record.CoinOutputs = append(record.CoinOutputs, TransactionRecordCoinOutput{
+			ID:        tx.CoinOutputID(uint64(i)),
+			Value:     co.Value,
+			Condition: co.Condition,
+		})
+	}
+	for i, bso := range tx.BlockStakeOutputs {
+		record.BlockStakeOutputs = append(record.BlockStakeOutputs, TransactionRecordBlockStakeOutput{
+			ID:        tx.BlockStakeOutputID(uint64(i)),
+			Value:     bso.Value,
+			Condition: bso.Condition,
+		})
+	}
+	return record
+}
+
+// storeTransactionRecord stores the record of the given transaction, applied in the given block at the current block height,
+// in case the database supports it.
+func (explorer *Explorer) storeTransactionRecord(tx types.Transaction, blockID types.BlockID, inputs []TransactionRecordCoinInput) {
+	txdb, ok := explorer.db.(TransactionDatabase)
+	if !ok {
+		return
+	}
+	err := txdb.SetTransactionRecord(newTransactionRecord(tx, blockID, explorer.stats.BlockHeight, inputs))
+	if err != nil {
+		panic(fmt.Sprintf("failed to set record of tx %s: %v", tx.ID().String(), err))
+	}
+}
+
+// revertTransactionRecord deletes the record of the given transaction, in case the database supports it.
+func (explorer *Explorer) revertTransactionRecord(tx types.Transaction) {
+	txdb, ok := explorer.db.(TransactionDatabase)
+	if !ok {
+		return
+	}
+	id := tx.ID()
+	err := txdb.RevertTransactionRecord(id)
+	if err != nil {
+		panic(fmt.Sprintf("failed to revert record of tx %s: %v", id.String(), err))
+	}
+}
@@ -0,0 +1,78 @@
+package rexplorer
+
+import (
+	"sort"
+
+	"github.com/rivine/rivine/types"
+)
+
+type (
+	// TransactionVersionStats counts the transactions per transaction version, ordered by version,
+	// such that the network-wide uptake of new transaction types (e.g. minting, 3Bot and ERC20 transactions) can be monitored.
+	// Versions without any transaction aren't listed.
+	TransactionVersionStats []TransactionVersionCount
+
+	// TransactionVersionCount is the amount of transactions counted for a single transaction version.
+	TransactionVersionCount struct {
+		Version types.TransactionVersion `json:"version"`
+		Count   uint64                   `json:"count"`
+	}
+)
+
+// Total returns the amount of transactions counted, no matter their version.
+func (tvs TransactionVersionStats) Total() (total uint64) {
+	for _, tvc := range tvs {
+		total += tvc.Count
+	}
+	return total
+}
+
+// Count returns the amount of transactions counted for the given version.
+func (tvs TransactionVersionStats) Count(version types.TransactionVersion) uint64 {
+	i := tvs.search(version)
+	if i < len(tvs) && tvs[i].Version == version {
+		return tvs[i].Count
+	}
+	return 0
+}
+
+// search returns the index of the given version, or the index at which it would have to be inserted.
+func (tvs TransactionVersionStats) search(version types.TransactionVersion) int {
+	return sort.Search(len(tvs), func(i int) bool {
+		return tvs[i].Version >= version
+	})
+}
+
+// applyTransactionVersion counts the given applied transaction for its version.
+func (stats *NetworkStats) applyTransactionVersion(tx types.Transaction) {
+	tvs := stats.TransactionVersions
+	i := tvs.search(tx.Version)
+	if i < len(tvs) && tvs[i].Version == tx.Version {
+		tvs[i].Count++
+		return
+	}
+	tvs = append(tvs, TransactionVersionCount{})
+	copy(tvs[i+1:], tvs[i:])
+	tvs[i] = TransactionVersionCount{Version: tx.Version, Count: 1}
+	stats.TransactionVersions = tvs
+}
+
+// revertTransactionVersion uncounts the given reverted transaction for its version,
+// dropping the version as soon as its counter reaches zero.
+func (stats *NetworkStats) revertTransactionVersion(tx types.Transaction) {
+	tvs := stats.TransactionVersions
+	i := tvs.search(tx.Version)
+	if i >= len(tvs) || tvs[i].Version != tx.Version {
+		stats.warnUntracked("transaction version count")
+		return
+	}
+	if tvs[i].Count > 1 {
+		tvs[i].Count--
+		return
+	}
+	tvs = append(tvs[:i], tvs[i+1:]...)
+	if len(tvs) == 0 {
+		tvs = nil
+	}
+	stats.TransactionVersions = tvs
+}
@@ -0,0 +1,40 @@
+package rexplorer
+
+import (
+	"log"
+
+	"github.com/rivine/rivine/types"
+)
+
+// subUntracked returns the given stat a minus b, or zero in case b is bigger than a.
+//
+// Most network stats (e.g. the miner fees, the burned coins or the counters per condition type) were introduced
+// by an upgrade of rexplorer, and are only tracked for the blocks applied since. Reverting a block applied
+// prior to upgrading an existing database could therefore drop such a stat below zero, hence the clamp.
+// The network stats of a dataset explored from the genesis block onwards track all blocks (see NetworkStats.FromGenesis),
+// in which case a stat dropping below zero indicates a bug instead, and a warning is logged.
+func (stats *NetworkStats) subUntracked(stat string, a, b types.Currency) types.Currency {
+	if a.Cmp(b) < 0 {
+		stats.warnUntracked(stat)
+		return types.Currency{}
+	}
+	return a.Sub(b)
+}
+
+// decUntracked returns the given counter a minus n, or zero in case n is bigger than a, see subUntracked.
+func (stats *NetworkStats) decUntracked(stat string, a, n uint64) uint64 {
+	if a < n {
+		stats.warnUntracked(stat)
+		return 0
+	}
+	return a - n
+}
+
+// warnUntracked logs a warning in case the given stat drops below zero,
+// while all blocks are tracked by the network stats, see subUntracked.
+func (stats *NetworkStats) warnUntracked(stat string) {
+	if stats.FromGenesis {
+		log.Printf("[WARNING] the %s of the network stats dropped below zero at height %d, while all blocks are tracked",
+			stat, stats.BlockHeight)
+	}
+}
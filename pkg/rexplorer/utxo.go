@@ -0,0 +1,85 @@
+package rexplorer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/rivine/rivine/types"
+)
+
+// UnspentCoinOutput is a single coin output as exported by ExportUnspentCoinOutputs,
+// being unspent at the height of the export. The lock type and value define the lock of the coin output (if any),
+// as it was defined when the coin output was created, whether or not the lock expired since.
+type UnspentCoinOutput struct {
+	ID         types.CoinOutputID         `json:"id"`
+	UnlockHash types.UnlockHash           `json:"unlockhash"`
+	Value      types.Currency             `json:"value"`
+	Condition  types.UnlockConditionProxy `json:"condition"`
+	LockType   LockType                   `json:"lockType"`
+	LockValue  LockValue                  `json:"lockValue"`
+}
+
+// ExportUnspentCoinOutputs writes all coin outputs unspent at the given height to the given writer,
+// as one JSON-encoded UnspentCoinOutput per line, ordered by ID, such that exports of the same height are identical.
+// Returns the amount of exported coin outputs, and their total value.
+//
+// Only the current height can be exported, unless the database records the provenance of the coin outputs
+// (see CoinOutputProvenanceDatabase), in which case the coin outputs created at or before the given height,
+// and not spent at or before that height, are exported. This fails should the provenance of a coin output not be stored,
+// as it was created prior to the dataset recording provenance.
+func ExportUnspentCoinOutputs(db CoinOutputSetDatabase, height types.BlockHeight, w io.Writer) (n uint64, value types.Currency, err error) {
+	stats, err := db.GetNetworkStats()
+	if err != nil {
+		return 0, types.Currency{}, fmt.Errorf("failed to get network stats: %v", err)
+	}
+	if height > stats.BlockHeight {
+		return 0, types.Currency{}, fmt.Errorf("height %d isn't explored yet, the current height being %d", height, stats.BlockHeight)
+	}
+	var pdb CoinOutputProvenanceDatabase
+	if height < stats.BlockHeight {
+		var ok bool
+		pdb, ok = db.(CoinOutputProvenanceDatabase)
+		if !ok {
+			return 0, types.Currency{}, fmt.Errorf(
+				"the provenance of coin outputs isn't recorded, only the current height %d can be exported", stats.BlockHeight)
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	err = db.IterateCoinOutputs(func(info CoinOutputInfo) error {
+		if pdb != nil {
+			provenance, err := pdb.GetCoinOutputProvenance(info.ID)
+			if err == ErrNotFound {
+				return fmt.Errorf(
+					"no provenance stored for coin output %s, cannot tell whether it was unspent at height %d", info.ID.String(), height)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to get provenance of coin output %s: %v", info.ID.String(), err)
+			}
+			if provenance.BlockHeight > height || (provenance.SpendBlockHeight != nil && *provenance.SpendBlockHeight <= height) {
+				return nil
+			}
+		} else if info.State == CoinOutputStateSpent {
+			return nil
+		}
+		err := encoder.Encode(UnspentCoinOutput{
+			ID:         info.ID,
+			UnlockHash: info.UnlockHash,
+			Value:      info.Value,
+			Condition:  info.Condition,
+			LockType:   info.LockType,
+			LockValue:  info.LockValue,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to export coin output %s: %v", info.ID.String(), err)
+		}
+		n++
+		value = value.Add(info.Value)
+		return nil
+	})
+	if err != nil {
+		return 0, types.Currency{}, err
+	}
+	return n, value, nil
+}
@@ -0,0 +1,45 @@
+package rexplorer
+
+import (
+	"math/big"
+
+	"github.com/rivine/rivine/types"
+)
+
+// CirculatingSupply returns the coins which aren't provably unspendable (burned).
+func (stats *NetworkStats) CirculatingSupply() types.Currency {
+	return subCurrencyOrZero(stats.Coins, stats.BurnedCoins)
+}
+
+// coinVelocity returns the given value moved divided by the given (circulating) supply,
+// or zero if there is no supply.
+func coinVelocity(moved, supply types.Currency) float64 {
+	if supply.IsZero() {
+		return 0
+	}
+	velocity, _ := new(big.Rat).SetFrac(moved.Big(), supply.Big()).Float64()
+	return velocity
+}
+
+// transactionValue returns the total value of the coin outputs created by the given transaction.
+func transactionValue(tx types.Transaction) (value types.Currency) {
+	for _, co := range tx.CoinOutputs {
+		value = value.Add(co.Value)
+	}
+	return value
+}
+
+// applyValueTransferred adds the value transferred by the given transaction to the network stats.
+func (stats *NetworkStats) applyValueTransferred(tx types.Transaction) {
+	stats.ValueTransferred = stats.ValueTransferred.Add(transactionValue(tx))
+}
+
+// revertValueTransferred subtracts the value transferred by the given transaction from the network stats.
+func (stats *NetworkStats) revertValueTransferred(tx types.Transaction) {
+	stats.ValueTransferred = stats.subUntracked("value transferred", stats.ValueTransferred, transactionValue(tx))
+}
+
+// updateVelocity recomputes the coin velocity of the network stats.
+func (stats *NetworkStats) updateVelocity() {
+	stats.Velocity = coinVelocity(stats.ValueTransferred, stats.CirculatingSupply())
+}
@@ -1,4 +1,4 @@
-package main
+package rexplorer
 
 import "github.com/rivine/rivine/build"
 
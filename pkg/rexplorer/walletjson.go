@@ -0,0 +1,656 @@
+package rexplorer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/rivine/rivine/types"
+)
+
+// The wallet structures are decoded and encoded for every balance update (by all drivers but the Redis drivers,
+// whose wallets are updated by a Lua script), and are decoded each time the balance of a wallet is read.
+// As such their JSON encoding is hand-written, avoiding the reflection and (intermediate) allocations
+// of encoding/json, while producing the same JSON as encoding/json would produce for them.
+// Contrary to encoding/json, object keys are matched exactly, as the wallets are encoded by rexplorer itself.
+
+// MarshalJSON implements json.Marshaller.MarshalJSON
+func (w Wallet) MarshalJSON() ([]byte, error) {
+	return w.appendJSON(make([]byte, 0, 256))
+}
+
+// appendJSON appends the JSON encoding of the wallet, the properties being sorted by name.
+func (w Wallet) appendJSON(buf []byte) ([]byte, error) {
+	buf = append(buf, '{')
+	var err error
+	if w.Activity != nil {
+		buf, err = appendJSONProperty(buf, "activity", w.Activity)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal activity: %v", err)
+		}
+	}
+	if !w.Balance.IsZero() {
+		buf = appendJSONKey(buf, "balance")
+		buf = w.Balance.appendJSON(buf)
+	}
+	if w.BlockCreator != nil {
+		buf, err = appendJSONProperty(buf, "blockCreator", w.BlockCreator)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal block creator stats: %v", err)
+		}
+	}
+	if !w.BlockStakes.IsZero() {
+		buf = appendJSONKey(buf, "blockstakes")
+		buf = append(buf, `{"unlocked":`...)
+		buf = appendJSONCurrency(buf, w.BlockStakes.Unlocked)
+		buf = append(buf, `,"locked":`...)
+		buf = appendJSONCurrency(buf, w.BlockStakes.Locked)
+		buf = append(buf, '}')
+	}
+	if len(w.MultiSignData.Owners) > 0 {
+		buf = appendJSONKey(buf, "multisign")
+		buf = append(buf, `{"owners":`...)
+		buf = appendJSONUnlockHashes(buf, w.MultiSignData.Owners)
+		buf = append(buf, `,"signaturesRequired":`...)
+		buf = strconv.AppendUint(buf, w.MultiSignData.SignaturesRequired, 10)
+		buf = append(buf, '}')
+	}
+	if len(w.MultiSignAddresses) > 0 {
+		buf = appendJSONKey(buf, "multisignaddresses")
+		buf = appendJSONUnlockHashes(buf, w.MultiSignAddresses)
+	}
+	if w.Totals != nil {
+		buf, err = appendJSONProperty(buf, "totals", w.Totals)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal totals: %v", err)
+		}
+	}
+	buf = appendJSONKey(buf, "version")
+	buf = strconv.AppendUint(buf, WalletVersion, 10)
+	return append(buf, '}'), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaller.UnmarshalJSON
+func (w *Wallet) UnmarshalJSON(b []byte) error {
+	r := jsonReader{buf: b}
+	err := r.object(func(key []byte) (err error) {
+		switch string(key) {
+		case "version":
+			w.Version, err = r.uint64()
+		case "balance":
+			err = w.Balance.decodeJSON(&r)
+		case "multisignaddresses":
+			w.MultiSignAddresses, err = r.unlockHashes()
+		case "multisign":
+			err = r.object(func(key []byte) (err error) {
+				switch string(key) {
+				case "owners":
+					w.MultiSignData.Owners, err = r.unlockHashes()
+				case "signaturesRequired":
+					w.MultiSignData.SignaturesRequired, err = r.uint64()
+				default:
+					err = r.skip()
+				}
+				return
+			})
+		case "blockstakes":
+			err = r.object(func(key []byte) error {
+				switch string(key) {
+				case "unlocked":
+					return r.currency(&w.BlockStakes.Unlocked)
+				case "locked":
+					return r.currency(&w.BlockStakes.Locked)
+				default:
+					return r.skip()
+				}
+			})
+		case "activity":
+			err = r.value(&w.Activity)
+		case "blockCreator":
+			err = r.value(&w.BlockCreator)
+		case "totals":
+			err = r.value(&w.Totals)
+		default:
+			err = r.skip()
+		}
+		return
+	})
+	if err == nil {
+		err = r.end()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal wallet: %v", err)
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaller.MarshalJSON
+func (wb WalletBalance) MarshalJSON() ([]byte, error) {
+	return wb.appendJSON(make([]byte, 0, 128)), nil
+}
+
+// appendJSON appends the JSON encoding of the balance, omitting the zero unlocked and locked balance.
+func (wb WalletBalance) appendJSON(buf []byte) []byte {
+	buf = append(buf, '{')
+	if !wb.Locked.Total.IsZero() {
+		buf = appendJSONKey(buf, "locked")
+		buf = wb.Locked.appendJSON(buf)
+	}
+	if !wb.Unlocked.IsZero() {
+		buf = appendJSONKey(buf, "unlocked")
+		buf = appendJSONCurrency(buf, wb.Unlocked)
+	}
+	return append(buf, '}')
+}
+
+// UnmarshalJSON implements json.Unmarshaller.UnmarshalJSON
+func (wb *WalletBalance) UnmarshalJSON(b []byte) error {
+	r := jsonReader{buf: b}
+	err := wb.decodeJSON(&r)
+	if err == nil {
+		err = r.end()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal wallet balance: %v", err)
+	}
+	return nil
+}
+
+func (wb *WalletBalance) decodeJSON(r *jsonReader) error {
+	return r.object(func(key []byte) error {
+		switch string(key) {
+		case "unlocked":
+			return r.currency(&wb.Unlocked)
+		case "locked":
+			return wb.Locked.decodeJSON(r)
+		default:
+			return r.skip()
+		}
+	})
+}
+
+// MarshalJSON implements json.Marshaller.MarshalJSON
+func (wlb WalletLockedBalance) MarshalJSON() ([]byte, error) {
+	return wlb.appendJSON(make([]byte, 0, 128)), nil
+}
+
+func (wlb WalletLockedBalance) appendJSON(buf []byte) []byte {
+	buf = append(buf, `{"total":`...)
+	buf = appendJSONCurrency(buf, wlb.Total)
+	buf = append(buf, `,"outputs":`...)
+	buf = wlb.Outputs.appendJSON(buf)
+	if h := wlb.Horizons; h != nil {
+		buf = append(buf, `,"horizons":{"asOf":`...)
+		buf = strconv.AppendUint(buf, uint64(h.AsOf), 10)
+		buf = append(buf, `,"day":`...)
+		buf = appendJSONCurrency(buf, h.Day)
+		buf = append(buf, `,"month":`...)
+		buf = appendJSONCurrency(buf, h.Month)
+		buf = append(buf, `,"year":`...)
+		buf = appendJSONCurrency(buf, h.Year)
+		buf = append(buf, `,"later":`...)
+		buf = appendJSONCurrency(buf, h.Later)
+		buf = append(buf, '}')
+	}
+	return append(buf, '}')
+}
+
+// UnmarshalJSON implements json.Unmarshaller.UnmarshalJSON
+func (wlb *WalletLockedBalance) UnmarshalJSON(b []byte) error {
+	r := jsonReader{buf: b}
+	err := wlb.decodeJSON(&r)
+	if err == nil {
+		err = r.end()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal locked wallet balance: %v", err)
+	}
+	return nil
+}
+
+func (wlb *WalletLockedBalance) decodeJSON(r *jsonReader) error {
+	return r.object(func(key []byte) error {
+		switch string(key) {
+		case "total":
+			return r.currency(&wlb.Total)
+		case "outputs":
+			return wlb.Outputs.decodeJSON(r)
+		case "horizons":
+			if r.null() {
+				wlb.Horizons = nil
+				return nil
+			}
+			wlb.Horizons = new(LockHorizons)
+			return r.object(func(key []byte) (err error) {
+				switch string(key) {
+				case "asOf":
+					var v uint64
+					v, err = r.uint64()
+					wlb.Horizons.AsOf = types.Timestamp(v)
+				case "day":
+					err = r.currency(&wlb.Horizons.Day)
+				case "month":
+					err = r.currency(&wlb.Horizons.Month)
+				case "year":
+					err = r.currency(&wlb.Horizons.Year)
+				case "later":
+					err = r.currency(&wlb.Horizons.Later)
+				default:
+					err = r.skip()
+				}
+				return
+			})
+		default:
+			return r.skip()
+		}
+	})
+}
+
+// MarshalJSON implements json.Marshaller.MarshalJSON
+func (wlom WalletLockedOutputMap) MarshalJSON() ([]byte, error) {
+	return wlom.appendJSON(make([]byte, 0, 64+len(wlom)*192)), nil
+}
+
+// appendJSON appends the JSON encoding of the locked outputs, as an object sorted by ID.
+func (wlom WalletLockedOutputMap) appendJSON(buf []byte) []byte {
+	ids := make([]types.CoinOutputID, 0, len(wlom))
+	for id := range wlom {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return bytes.Compare(ids[i][:], ids[j][:]) < 0
+	})
+	buf = append(buf, '{')
+	for i, id := range ids {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		output := wlom[id]
+		buf = append(buf, '"')
+		buf = append(buf, id.String()...)
+		buf = append(buf, `":{"amount":`...)
+		buf = appendJSONCurrency(buf, output.Amount)
+		buf = append(buf, `,"lockedUntil":`...)
+		buf = strconv.AppendUint(buf, uint64(output.LockedUntil), 10)
+		buf = append(buf, `,"description":`...)
+		if output.Description == nil {
+			buf = append(buf, "null"...)
+		} else {
+			buf = append(buf, '"')
+			n := len(buf)
+			buf = append(buf, make([]byte, base64.StdEncoding.EncodedLen(len(output.Description)))...)
+			base64.StdEncoding.Encode(buf[n:], output.Description)
+			buf = append(buf, '"')
+		}
+		if output.Reason != "" {
+			buf = append(buf, `,"reason":`...)
+			buf = appendJSONString(buf, string(output.Reason))
+		}
+		buf = append(buf, '}')
+	}
+	return append(buf, '}')
+}
+
+// UnmarshalJSON implements json.Unmarshaller.UnmarshalJSON
+func (wlom *WalletLockedOutputMap) UnmarshalJSON(b []byte) error {
+	r := jsonReader{buf: b}
+	err := wlom.decodeJSON(&r)
+	if err == nil {
+		err = r.end()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal raw WalletLockedOutputMap: %v", err)
+	}
+	return nil
+}
+
+func (wlom *WalletLockedOutputMap) decodeJSON(r *jsonReader) error {
+	*wlom = make(WalletLockedOutputMap)
+	return r.object(func(key []byte) error {
+		var id types.CoinOutputID
+		err := id.LoadString(string(key))
+		if err != nil {
+			return fmt.Errorf("failed to locked output %s: %v", key, err)
+		}
+		var output WalletLockedOutput
+		err = r.object(func(key []byte) (err error) {
+			switch string(key) {
+			case "amount":
+				err = r.currency(&output.Amount)
+			case "lockedUntil":
+				var v uint64
+				v, err = r.uint64()
+				output.LockedUntil = LockValue(v)
+			case "description":
+				if r.null() {
+					output.Description = nil
+					return nil
+				}
+				var b []byte
+				b, err = r.string()
+				if err == nil {
+					output.Description = make([]byte, base64.StdEncoding.DecodedLen(len(b)))
+					var n int
+					n, err = base64.StdEncoding.Decode(output.Description, b)
+					output.Description = output.Description[:n]
+				}
+			case "reason":
+				var b []byte
+				b, err = r.string()
+				output.Reason = LockReason(b)
+			default:
+				err = r.skip()
+			}
+			return
+		})
+		if err != nil {
+			return fmt.Errorf("failed to locked output %s: %v", key, err)
+		}
+		(*wlom)[id] = output
+		return nil
+	})
+}
+
+// appendJSONKey appends the given key of a JSON object, preceded by a comma unless it is the first key.
+func appendJSONKey(buf []byte, key string) []byte {
+	if buf[len(buf)-1] != '{' {
+		buf = append(buf, ',')
+	}
+	buf = append(buf, '"')
+	buf = append(buf, key...)
+	return append(buf, `":`...)
+}
+
+// appendJSONProperty appends the given property of a JSON object, encoding its value using encoding/json.
+func appendJSONProperty(buf []byte, key string, v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(appendJSONKey(buf, key), b...), nil
+}
+
+// appendJSONCurrency appends the given currency as a (decimal) JSON string, the same as types.Currency.MarshalJSON.
+func appendJSONCurrency(buf []byte, c types.Currency) []byte {
+	buf = append(buf, '"')
+	buf = append(buf, c.String()...)
+	return append(buf, '"')
+}
+
+// appendJSONUnlockHashes appends the given unlock hashes as a JSON array of (hex) strings.
+func appendJSONUnlockHashes(buf []byte, uhs []types.UnlockHash) []byte {
+	if uhs == nil {
+		return append(buf, "null"...)
+	}
+	buf = append(buf, '[')
+	for i, uh := range uhs {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, '"')
+		buf = append(buf, uh.String()...)
+		buf = append(buf, '"')
+	}
+	return append(buf, ']')
+}
+
+// appendJSONString appends the given string as a JSON string,
+// using encoding/json only if it contains characters which have to be escaped.
+func appendJSONString(buf []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x20 || c >= 0x7f || c == '"' || c == '\\' || c == '<' || c == '>' || c == '&' {
+			b, _ := json.Marshal(s)
+			return append(buf, b...)
+		}
+	}
+	buf = append(buf, '"')
+	buf = append(buf, s...)
+	return append(buf, '"')
+}
+
+var errJSONUnexpectedEnd = errors.New("unexpected end of JSON input")
+
+// jsonReader is a minimal JSON reader, used by the hand-written JSON decoders of the wallet structures.
+// Values it doesn't decode itself are skipped, or decoded using encoding/json (see value).
+type jsonReader struct {
+	buf []byte
+	pos int
+}
+
+// peek returns the next non-whitespace character, without consuming it, 0 if no characters are left.
+func (r *jsonReader) peek() byte {
+	for r.pos < len(r.buf) {
+		switch c := r.buf[r.pos]; c {
+		case ' ', '\t', '\n', '\r':
+			r.pos++
+		default:
+			return c
+		}
+	}
+	return 0
+}
+
+// consume consumes the given (next non-whitespace) character.
+func (r *jsonReader) consume(c byte) error {
+	switch next := r.peek(); next {
+	case c:
+		r.pos++
+		return nil
+	case 0:
+		return errJSONUnexpectedEnd
+	default:
+		return fmt.Errorf("invalid character %q at offset %d, expected %q", next, r.pos, c)
+	}
+}
+
+// null consumes the next value if it is null, returning true if so.
+func (r *jsonReader) null() bool {
+	if r.peek() == 'n' && bytes.HasPrefix(r.buf[r.pos:], []byte("null")) {
+		r.pos += 4
+		return true
+	}
+	return false
+}
+
+// end ensures no values are left.
+func (r *jsonReader) end() error {
+	if c := r.peek(); c != 0 {
+		return fmt.Errorf("invalid character %q at offset %d after top-level value", c, r.pos)
+	}
+	return nil
+}
+
+// object reads an object (null being read as an empty object), calling the given function for each key,
+// which has to read (or skip) the value of that key. The given key is only valid until the function returns.
+func (r *jsonReader) object(fn func(key []byte) error) error {
+	if r.null() {
+		return nil
+	}
+	err := r.consume('{')
+	if err != nil {
+		return err
+	}
+	if r.peek() == '}' {
+		r.pos++
+		return nil
+	}
+	for {
+		key, err := r.string()
+		if err != nil {
+			return err
+		}
+		err = r.consume(':')
+		if err != nil {
+			return err
+		}
+		err = fn(key)
+		if err != nil {
+			return err
+		}
+		switch c := r.peek(); c {
+		case ',':
+			r.pos++
+		case '}':
+			r.pos++
+			return nil
+		case 0:
+			return errJSONUnexpectedEnd
+		default:
+			return fmt.Errorf("invalid character %q at offset %d after object key:value pair", c, r.pos)
+		}
+	}
+}
+
+// array reads an array (null being read as an empty array), calling the given function for each element,
+// which has to read (or skip) that element.
+func (r *jsonReader) array(fn func() error) error {
+	if r.null() {
+		return nil
+	}
+	err := r.consume('[')
+	if err != nil {
+		return err
+	}
+	if r.peek() == ']' {
+		r.pos++
+		return nil
+	}
+	for {
+		err = fn()
+		if err != nil {
+			return err
+		}
+		switch c := r.peek(); c {
+		case ',':
+			r.pos++
+		case ']':
+			r.pos++
+			return nil
+		case 0:
+			return errJSONUnexpectedEnd
+		default:
+			return fmt.Errorf("invalid character %q at offset %d after array element", c, r.pos)
+		}
+	}
+}
+
+// string reads a string, returning its (unescaped) content, which references the read JSON
+// unless the string contains escape sequences, in which case it is unescaped using encoding/json.
+func (r *jsonReader) string() ([]byte, error) {
+	err := r.consume('"')
+	if err != nil {
+		return nil, err
+	}
+	start, escaped := r.pos, false
+	for ; r.pos < len(r.buf); r.pos++ {
+		switch r.buf[r.pos] {
+		case '\\':
+			escaped = true
+			r.pos++
+		case '"':
+			r.pos++
+			if !escaped {
+				return r.buf[start : r.pos-1], nil
+			}
+			var s string
+			err = json.Unmarshal(r.buf[start-1:r.pos], &s)
+			if err != nil {
+				return nil, err
+			}
+			return []byte(s), nil
+		}
+	}
+	return nil, errJSONUnexpectedEnd
+}
+
+// literal reads a number, true, false or null, returning it as is.
+func (r *jsonReader) literal() ([]byte, error) {
+	if r.peek() == 0 {
+		return nil, errJSONUnexpectedEnd
+	}
+	start := r.pos
+	for ; r.pos < len(r.buf); r.pos++ {
+		switch r.buf[r.pos] {
+		case ',', '}', ']', ' ', '\t', '\n', '\r':
+			return r.buf[start:r.pos], nil
+		}
+	}
+	return r.buf[start:], nil
+}
+
+// uint64 reads an unsigned integer (null being read as 0).
+func (r *jsonReader) uint64() (uint64, error) {
+	if r.null() {
+		return 0, nil
+	}
+	b, err := r.literal()
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(string(b), 10, 64)
+}
+
+// currency reads a currency, encoded as a (decimal) string or number.
+func (r *jsonReader) currency(c *types.Currency) error {
+	var b []byte
+	var err error
+	if r.peek() == '"' {
+		b, err = r.string()
+	} else {
+		b, err = r.literal()
+	}
+	if err != nil {
+		return err
+	}
+	return c.UnmarshalJSON(b)
+}
+
+// unlockHashes reads an array of (hex-encoded) unlock hashes, null being read as a nil slice.
+func (r *jsonReader) unlockHashes() ([]types.UnlockHash, error) {
+	var uhs []types.UnlockHash
+	err := r.array(func() error {
+		b, err := r.string()
+		if err != nil {
+			return err
+		}
+		var uh types.UnlockHash
+		err = uh.LoadString(string(b))
+		uhs = append(uhs, uh)
+		return err
+	})
+	return uhs, err
+}
+
+// skip skips the next value.
+func (r *jsonReader) skip() error {
+	switch r.peek() {
+	case '{':
+		return r.object(func([]byte) error { return r.skip() })
+	case '[':
+		return r.array(r.skip)
+	case '"':
+		_, err := r.string()
+		return err
+	default:
+		_, err := r.literal()
+		return err
+	}
+}
+
+// value reads the next value into the given (reference) value, using encoding/json.
+func (r *jsonReader) value(v interface{}) error {
+	start := r.peek()
+	if start == 0 {
+		return errJSONUnexpectedEnd
+	}
+	begin := r.pos
+	err := r.skip()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(r.buf[begin:r.pos], v)
+}
@@ -0,0 +1,101 @@
+package rexplorer
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/rivine/rivine/types"
+)
+
+const (
+	// DefaultWalletOutputsPageSize is the amount of locked outputs listed per page, if no (valid) limit is given.
+	DefaultWalletOutputsPageSize = 100
+	// MaxWalletOutputsPageSize is the maximum amount of locked outputs listed per page.
+	MaxWalletOutputsPageSize = 1000
+)
+
+type (
+	// WalletOutputsDatabase is an optional interface which can be implemented by a Database,
+	// listing the locked outputs of a wallet page by page, such that wallets with a lot of locked outputs
+	// can be listed without having to get (and encode) all of them at once.
+	WalletOutputsDatabase interface {
+		// GetWalletLockedOutputs returns at most limit locked outputs of the wallet of the given address,
+		// ordered by ID, starting after the given cursor (the ID of the last output of the previous page),
+		// or from the first output if no cursor is given.
+		// ErrNotFound is returned if the wallet doesn't exist.
+		GetWalletLockedOutputs(address types.UnlockHash, cursor *types.CoinOutputID, limit int) (WalletLockedOutputPage, error)
+	}
+
+	// WalletLockedOutputPage is a page of the locked outputs of a wallet, ordered by ID.
+	WalletLockedOutputPage struct {
+		Outputs []WalletLockedOutputEntry `json:"outputs"`
+		// NextCursor is the cursor of the next page, nil if this is the last page.
+		NextCursor *types.CoinOutputID `json:"nextCursor,omitempty"`
+	}
+	// WalletLockedOutputEntry is a locked output of a wallet, together with its ID.
+	WalletLockedOutputEntry struct {
+		ID types.CoinOutputID `json:"id"`
+		WalletLockedOutput
+	}
+)
+
+// normalizeWalletOutputsPageSize returns the given limit,
+// clamped to MaxWalletOutputsPageSize, or DefaultWalletOutputsPageSize if it isn't positive.
+func normalizeWalletOutputsPageSize(limit int) int {
+	switch {
+	case limit <= 0:
+		return DefaultWalletOutputsPageSize
+	case limit > MaxWalletOutputsPageSize:
+		return MaxWalletOutputsPageSize
+	default:
+		return limit
+	}
+}
+
+// getWalletLockedOutputs gets a page of the locked outputs of the given wallet,
+// using the WalletOutputsDatabase interface if implemented by the given database,
+// and paginating the locked outputs of the (entire) wallet otherwise.
+// The limit is normalized, see normalizeWalletOutputsPageSize.
+// ErrNotFound is returned as is if the wallet doesn't exist.
+func getWalletLockedOutputs(db Database, address types.UnlockHash, cursor *types.CoinOutputID, limit int) (WalletLockedOutputPage, error) {
+	limit = normalizeWalletOutputsPageSize(limit)
+	if wodb, ok := db.(WalletOutputsDatabase); ok {
+		return wodb.GetWalletLockedOutputs(address, cursor, limit)
+	}
+	wallet, err := db.GetWallet(address)
+	if err != nil {
+		return WalletLockedOutputPage{}, err
+	}
+	return paginateWalletLockedOutputs(wallet.Balance.Locked.Outputs, cursor, limit), nil
+}
+
+// paginateWalletLockedOutputs returns the page of the given locked outputs
+// of at most limit outputs starting after the given cursor, if any.
+func paginateWalletLockedOutputs(outputs WalletLockedOutputMap, cursor *types.CoinOutputID, limit int) WalletLockedOutputPage {
+	page := WalletLockedOutputPage{Outputs: []WalletLockedOutputEntry{}}
+	for _, id := range sortedLockedOutputIDs(outputs) {
+		if cursor != nil && bytes.Compare(id[:], cursor[:]) <= 0 {
+			continue
+		}
+		if len(page.Outputs) == limit {
+			last := page.Outputs[limit-1].ID
+			page.NextCursor = &last
+			break
+		}
+		page.Outputs = append(page.Outputs, WalletLockedOutputEntry{ID: id, WalletLockedOutput: outputs[id]})
+	}
+	return page
+}
+
+// parseWalletOutputsCursor parses the given cursor, returning nil if it is empty.
+func parseWalletOutputsCursor(str string) (*types.CoinOutputID, error) {
+	if str == "" {
+		return nil, nil
+	}
+	var id types.CoinOutputID
+	err := id.LoadString(str)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor %q: %v", str, err)
+	}
+	return &id, nil
+}
@@ -0,0 +1,369 @@
+package rexplorer
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rivine/rivine/types"
+)
+
+// WebhookEvent defines the type of a notification sent to the webhooks of a watched address.
+type WebhookEvent string
+
+// All events the webhooks of a watched address are notified of.
+const (
+	// WebhookEventBalanceChanged is sent each time the coin balance of a watched address changed.
+	WebhookEventBalanceChanged WebhookEvent = "balance-changed"
+	// WebhookEventOutputLocked is sent for every locked coin output received by a watched address,
+	// as well as for every coin output of a watched address which is locked again as a block is reverted.
+	WebhookEventOutputLocked WebhookEvent = "output-locked"
+	// WebhookEventOutputUnlocked is sent for every locked coin output of a watched address which unlocked.
+	WebhookEventOutputUnlocked WebhookEvent = "output-unlocked"
+)
+
+// The headers of the requests made to a webhook.
+const (
+	// WebhookHeaderEvent is the WebhookEvent of the payload.
+	WebhookHeaderEvent = "X-Rexplorer-Event"
+	// WebhookHeaderSignature is the signature of the payload, in the "sha256=<hex>" format,
+	// being the HMAC-SHA256 of the request body using the secret of the webhook as key.
+	WebhookHeaderSignature = "X-Rexplorer-Signature"
+)
+
+type (
+	// Webhook is an HTTP(S) endpoint registered for a (watched) address,
+	// notified of the changes of its balance and the locks of its coin outputs.
+	// The secret is generated when registering the webhook, and is used to sign its payloads.
+	Webhook struct {
+		Address types.UnlockHash `json:"address"`
+		URL     string           `json:"url"`
+		Secret  string           `json:"secret"`
+	}
+
+	// WebhookPayload defines the JSON payload every webhook is notified with.
+	WebhookPayload struct {
+		Event     WebhookEvent     `json:"event"`
+		Chain     string           `json:"chain"`
+		Network   string           `json:"network"`
+		Timestamp int64            `json:"timestamp"`
+		Address   types.UnlockHash `json:"address"`
+		Data      interface{}      `json:"data"`
+	}
+
+	// WebhookOutputData defines the data of a WebhookEventOutputLocked or WebhookEventOutputUnlocked payload,
+	// being the coin output, its value and the lock value it is (or was) locked until, as of the given block height.
+	WebhookOutputData struct {
+		ID          types.CoinOutputID `json:"id"`
+		Amount      types.Currency     `json:"amount"`
+		LockedUntil LockValue          `json:"lockedUntil"`
+		Height      types.BlockHeight  `json:"height"`
+	}
+)
+
+// WebhookDatabase is an optional interface which can be implemented by a Database,
+// storing the webhooks registered for watched addresses, which are notified by the explorer while the consensus set is synced.
+type WebhookDatabase interface {
+	Database
+
+	GetWebhooks() ([]Webhook, error)
+	SetWebhooks(webhooks []Webhook) error
+}
+
+// AddWebhook registers the given URL as webhook of the given address, generating its secret,
+// and returns the updated webhooks and the registered webhook.
+func AddWebhook(webhooks []Webhook, address types.UnlockHash, rawURL string) ([]Webhook, Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, Webhook{}, fmt.Errorf("invalid webhook URL %q: expected an absolute HTTP(S) URL", rawURL)
+	}
+	for _, webhook := range webhooks {
+		if webhook.Address == address && webhook.URL == rawURL {
+			return nil, Webhook{}, fmt.Errorf("webhook %s is already registered for %s", rawURL, address.String())
+		}
+	}
+	var secret [32]byte
+	_, err = rand.Read(secret[:])
+	if err != nil {
+		return nil, Webhook{}, fmt.Errorf("failed to generate webhook secret: %v", err)
+	}
+	webhook := Webhook{Address: address, URL: rawURL, Secret: hex.EncodeToString(secret[:])}
+	return append(webhooks, webhook), webhook, nil
+}
+
+// RemoveWebhook removes the webhook with the given URL registered for the given address,
+// or all webhooks of the given address if the URL is empty, returning the updated webhooks.
+func RemoveWebhook(webhooks []Webhook, address types.UnlockHash, rawURL string) ([]Webhook, error) {
+	kept := make([]Webhook, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		if webhook.Address == address && (rawURL == "" || webhook.URL == rawURL) {
+			continue
+		}
+		kept = append(kept, webhook)
+	}
+	if len(kept) == len(webhooks) {
+		if rawURL == "" {
+			return nil, fmt.Errorf("no webhooks are registered for %s", address.String())
+		}
+		return nil, fmt.Errorf("webhook %s is not registered for %s", rawURL, address.String())
+	}
+	return kept, nil
+}
+
+// SignWebhookPayload returns the signature of the given payload, signed using the given secret,
+// as sent in the WebhookHeaderSignature header.
+func SignWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+const (
+	// webhookTimeout is the maximum duration a single webhook request can take
+	webhookTimeout = 30 * time.Second
+	// webhookQueueSize is the amount of notifications that can be queued,
+	// before notifications are dropped in order not to block the explorer
+	webhookQueueSize = 1024
+	// the amount of times a notification is attempted to be delivered, and the backoff before the first retry,
+	// doubled for each retry after that
+	webhookMaxAttempts  = 5
+	webhookRetryBackoff = time.Second
+)
+
+// webhookNotification is a single (JSON-encoded) payload to be delivered to a webhook.
+type webhookNotification struct {
+	webhook Webhook
+	event   WebhookEvent
+	payload []byte
+}
+
+// webhookNotifier notifies the webhooks of the watched addresses in the background,
+// in the order the notifications are queued, such that a slow or failing webhook never blocks (or breaks) the explorer.
+// Failed deliveries are retried up to webhookMaxAttempts times, in case the webhook can't be reached,
+// or responds with a 5xx or 429 status.
+//
+// The wallets of the watched addresses are compared to their wallets as of the previous consensus change,
+// which are kept in memory, such that the locks and unlocks of their coin outputs can be detected.
+type webhookNotifier struct {
+	db     WebhookDatabase
+	bcInfo types.BlockchainInfo
+	client *http.Client
+
+	// the wallet of each watched address, as of the previous consensus change,
+	// only accessed while holding the lock of the explorer
+	wallets map[types.UnlockHash]Wallet
+
+	queue   chan webhookNotification
+	closing chan struct{}
+	done    sync.WaitGroup
+	once    sync.Once
+}
+
+func newWebhookNotifier(db WebhookDatabase, bcInfo types.BlockchainInfo) *webhookNotifier {
+	wn := &webhookNotifier{
+		db:      db,
+		bcInfo:  bcInfo,
+		client:  &http.Client{Timeout: webhookTimeout},
+		wallets: make(map[types.UnlockHash]Wallet),
+		queue:   make(chan webhookNotification, webhookQueueSize),
+		closing: make(chan struct{}),
+	}
+	wn.done.Add(1)
+	go wn.run()
+	return wn
+}
+
+// notify the webhooks of all watched addresses of the changes of their wallets since the previous consensus change,
+// as of the given height. The balance of addresses watched since the previous consensus change is only reported
+// if they're one of the given addresses involved in the consensus change.
+func (wn *webhookNotifier) notify(height types.BlockHeight, involved map[types.UnlockHash]struct{}) error {
+	webhooks, err := wn.db.GetWebhooks()
+	if err != nil {
+		return fmt.Errorf("failed to get webhooks: %v", err)
+	}
+	watched := make(map[types.UnlockHash][]Webhook)
+	for _, webhook := range webhooks {
+		watched[webhook.Address] = append(watched[webhook.Address], webhook)
+	}
+	for address := range wn.wallets {
+		if _, ok := watched[address]; !ok {
+			delete(wn.wallets, address)
+		}
+	}
+	for _, address := range sortedAddresses(webhookAddresses(watched)) {
+		wallet, err := wn.db.GetWallet(address)
+		if err != nil && err != ErrNotFound {
+			return fmt.Errorf("failed to get wallet %s: %v", address.String(), err)
+		}
+		// the unlock horizons change over time without any coins moving, hence they're not reported
+		wallet.Balance.Locked.Horizons = nil
+		previous, known := wn.wallets[address]
+		wn.wallets[address] = wallet
+
+		var events []WebhookEvent
+		var data []interface{}
+		if known {
+			// report the outputs which are no longer locked, and the outputs which are locked since
+			for _, id := range sortedLockedOutputIDs(previous.Balance.Locked.Outputs) {
+				if _, ok := wallet.Balance.Locked.Outputs[id]; ok {
+					continue
+				}
+				output := previous.Balance.Locked.Outputs[id]
+				info, err := wn.db.GetCoinOutput(id)
+				if err == ErrNotFound {
+					// removed by a reverted block
+					continue
+				}
+				if err != nil {
+					return fmt.Errorf("failed to get coin output %s: %v", id.String(), err)
+				}
+				if info.State == CoinOutputStateLocked {
+					continue
+				}
+				events = append(events, WebhookEventOutputUnlocked)
+				data = append(data, WebhookOutputData{ID: id, Amount: output.Amount, LockedUntil: output.LockedUntil, Height: height})
+			}
+			for _, id := range sortedLockedOutputIDs(wallet.Balance.Locked.Outputs) {
+				if _, ok := previous.Balance.Locked.Outputs[id]; ok {
+					continue
+				}
+				output := wallet.Balance.Locked.Outputs[id]
+				events = append(events, WebhookEventOutputLocked)
+				data = append(data, WebhookOutputData{ID: id, Amount: output.Amount, LockedUntil: output.LockedUntil, Height: height})
+			}
+		}
+		_, isInvolved := involved[address]
+		if (known && !walletBalancesEqual(previous.Balance, wallet.Balance)) || (!known && isInvolved) {
+			events = append(events, WebhookEventBalanceChanged)
+			data = append(data, BalanceEventData{Address: address, Height: height, Balance: wallet.Balance})
+		}
+		for i, event := range events {
+			wn.queuePayload(watched[address], WebhookPayload{
+				Event:     event,
+				Chain:     wn.bcInfo.Name,
+				Network:   wn.bcInfo.NetworkName,
+				Timestamp: time.Now().Unix(),
+				Address:   address,
+				Data:      data[i],
+			})
+		}
+	}
+	return nil
+}
+
+func webhookAddresses(watched map[types.UnlockHash][]Webhook) map[types.UnlockHash]struct{} {
+	set := make(map[types.UnlockHash]struct{}, len(watched))
+	for address := range watched {
+		set[address] = struct{}{}
+	}
+	return set
+}
+
+func sortedLockedOutputIDs(outputs WalletLockedOutputMap) []types.CoinOutputID {
+	ids := make([]types.CoinOutputID, 0, len(outputs))
+	for id := range outputs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return bytes.Compare(ids[i][:], ids[j][:]) < 0 })
+	return ids
+}
+
+// walletBalancesEqual returns true if both balances define the same unlocked and locked coins.
+func walletBalancesEqual(a, b WalletBalance) bool {
+	return a.Unlocked.Cmp(b.Unlocked) == 0 && a.Locked.Total.Cmp(b.Locked.Total) == 0
+}
+
+// queuePayload queues the notification of the given webhooks of the given payload.
+// The notification is dropped (and logged as such) in case the queue is full.
+func (wn *webhookNotifier) queuePayload(webhooks []Webhook, payload WebhookPayload) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("[ERROR] failed to JSON-encode %s webhook payload: %v", payload.Event, err)
+		return
+	}
+	for _, webhook := range webhooks {
+		select {
+		case wn.queue <- webhookNotification{webhook: webhook, event: payload.Event, payload: b}:
+		default:
+			log.Printf("[ERROR] webhook queue is full, dropping %s notification of %s", payload.Event, webhook.URL)
+		}
+	}
+}
+
+// close the notifier, waiting until the notification being delivered (if any) is delivered,
+// dropping all notifications queued after it. Closing a nil notifier is a no-op.
+func (wn *webhookNotifier) close() {
+	if wn == nil {
+		return
+	}
+	wn.once.Do(func() {
+		close(wn.closing)
+		wn.done.Wait()
+	})
+}
+
+func (wn *webhookNotifier) run() {
+	defer wn.done.Done()
+	for {
+		select {
+		case <-wn.closing:
+			return
+		case notification := <-wn.queue:
+			err := wn.deliver(notification)
+			if err != nil {
+				log.Printf("[ERROR] %s webhook %s of %s failed: %v",
+					notification.event, notification.webhook.URL, notification.webhook.Address.String(), err)
+			}
+		}
+	}
+}
+
+// deliver a single notification, retrying failed deliveries until the notifier is closed.
+func (wn *webhookNotifier) deliver(notification webhookNotification) error {
+	backoff := webhookRetryBackoff
+	for attempt := 1; ; attempt++ {
+		retry, err := wn.post(notification)
+		if err == nil || !retry || attempt == webhookMaxAttempts {
+			return err
+		}
+		select {
+		case <-wn.closing:
+			return fmt.Errorf("%v (not retried as the explorer is closing)", err)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post the payload of the given notification to its webhook,
+// returning whether or not the request should be retried in case it failed.
+func (wn *webhookNotifier) post(notification webhookNotification) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, notification.webhook.URL, bytes.NewReader(notification.payload))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "rexplorer/"+version.String())
+	req.Header.Set(WebhookHeaderEvent, string(notification.event))
+	req.Header.Set(WebhookHeaderSignature, SignWebhookPayload(notification.webhook.Secret, notification.payload))
+	resp, err := wn.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return false, nil
+}
@@ -0,0 +1,266 @@
+package rexplorer
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// websocketConn is the server side of a WebSocket connection, as defined by RFC 6455,
+// implementing only what is required to stream (text) messages to a client and to receive its (text) messages:
+// extensions and subprotocols are not supported.
+type websocketConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+	// serializes the writes, as control frames are written by the reader as well
+	wmu sync.Mutex
+}
+
+const (
+	// websocketGUID is the GUID defined by RFC 6455, used to compute the accept key of the opening handshake.
+	websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	// websocketMaxMessageSize is the maximum size of a message received from a client.
+	websocketMaxMessageSize = 64 * 1024
+	// websocketWriteTimeout is the time a client has to receive a single frame.
+	websocketWriteTimeout = 10 * time.Second
+)
+
+// The opcodes of the WebSocket frames.
+const (
+	websocketOpContinuation = 0x0
+	websocketOpText         = 0x1
+	websocketOpBinary       = 0x2
+	websocketOpClose        = 0x8
+	websocketOpPing         = 0x9
+	websocketOpPong         = 0xA
+)
+
+// The status codes of the WebSocket close frames sent by the server.
+const (
+	websocketCloseNormal        = 1000
+	websocketCloseGoingAway     = 1001
+	websocketCloseProtocol      = 1002
+	websocketCloseInvalidData   = 1007
+	websocketClosePolicy        = 1008
+	websocketCloseTooBig        = 1009
+	websocketCloseInternalError = 1011
+	websocketCloseTryAgain      = 1013
+)
+
+// errWebSocketClosed is returned when reading from a connection closed by the client.
+var errWebSocketClosed = errors.New("websocket closed by client")
+
+// upgradeWebSocket performs the opening handshake of a WebSocket connection, hijacking the HTTP connection.
+// An error response is written, and an error returned, in case the request isn't a valid WebSocket handshake.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*websocketConn, error) {
+	if r.Method != http.MethodGet ||
+		!headerContainsToken(r.Header, "Connection", "upgrade") ||
+		!headerContainsToken(r.Header, "Upgrade", "websocket") {
+		err := errors.New("expected a WebSocket handshake")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, err
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		err := errors.New("unsupported WebSocket version, only version 13 is supported")
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, err.Error(), http.StatusUpgradeRequired)
+		return nil, err
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		err := errors.New("missing WebSocket key")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, err
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		err := errors.New("connection cannot be upgraded to a WebSocket")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, err
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to hijack connection: %v", err)
+	}
+	h := sha1.New()
+	io.WriteString(h, key+websocketGUID)
+	ws := &websocketConn{conn: conn, rw: rw}
+	conn.SetWriteDeadline(time.Now().Add(websocketWriteTimeout))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+		base64.StdEncoding.EncodeToString(h.Sum(nil)))
+	err = rw.Flush()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to complete WebSocket handshake: %v", err)
+	}
+	return ws, nil
+}
+
+// headerContainsToken returns true if the given (comma-separated) header contains the given token, ignoring case.
+func headerContainsToken(header http.Header, name, token string) bool {
+	for _, value := range header[http.CanonicalHeaderKey(name)] {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WriteText sends the given payload as a single text message.
+func (ws *websocketConn) WriteText(payload []byte) error {
+	return ws.writeFrame(websocketOpText, payload)
+}
+
+// WriteClose sends a close frame with the given status code and reason,
+// after which no other messages should be sent.
+func (ws *websocketConn) WriteClose(code uint16, reason string) error {
+	payload := make([]byte, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, code)
+	copy(payload[2:], reason)
+	return ws.writeFrame(websocketOpClose, payload)
+}
+
+func (ws *websocketConn) writeFrame(opcode byte, payload []byte) error {
+	ws.wmu.Lock()
+	defer ws.wmu.Unlock()
+	// frames sent by the server are never fragmented, nor masked
+	header := []byte{0x80 | opcode, 0}
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xFFFF:
+		header[1] = 126
+		header = append(header, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header[1] = 127
+		header = append(header, make([]byte, 8)...)
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+	ws.conn.SetWriteDeadline(time.Now().Add(websocketWriteTimeout))
+	_, err := ws.rw.Write(header)
+	if err == nil {
+		_, err = ws.rw.Write(payload)
+	}
+	if err == nil {
+		err = ws.rw.Flush()
+	}
+	return err
+}
+
+// ReadMessage reads the next (text or binary) message sent by the client,
+// answering the pings received meanwhile. errWebSocketClosed is returned once the client closed the connection,
+// in which case the close frame is echoed, as required by RFC 6455.
+func (ws *websocketConn) ReadMessage() ([]byte, error) {
+	var message []byte
+	fragmented := false
+	for {
+		fin, opcode, payload, err := ws.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch opcode {
+		case websocketOpClose:
+			ws.WriteClose(websocketCloseNormal, "")
+			return nil, errWebSocketClosed
+		case websocketOpPing:
+			err = ws.writeFrame(websocketOpPong, payload)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		case websocketOpPong:
+			continue
+		case websocketOpText, websocketOpBinary:
+			if fragmented {
+				return nil, ws.fail(websocketCloseProtocol, "expected a continuation frame")
+			}
+			message = payload
+		case websocketOpContinuation:
+			if !fragmented {
+				return nil, ws.fail(websocketCloseProtocol, "unexpected continuation frame")
+			}
+			message = append(message, payload...)
+		default:
+			return nil, ws.fail(websocketCloseProtocol, fmt.Sprintf("unknown opcode %d", opcode))
+		}
+		if len(message) > websocketMaxMessageSize {
+			return nil, ws.fail(websocketCloseTooBig, "message too big")
+		}
+		if fin {
+			return message, nil
+		}
+		fragmented = true
+	}
+}
+
+func (ws *websocketConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var header [2]byte
+	_, err = io.ReadFull(ws.rw, header[:])
+	if err != nil {
+		return
+	}
+	fin, opcode = header[0]&0x80 != 0, header[0]&0x0F
+	if header[0]&0x70 != 0 {
+		err = ws.fail(websocketCloseProtocol, "reserved bits set without negotiated extension")
+		return
+	}
+	if header[1]&0x80 == 0 {
+		err = ws.fail(websocketCloseProtocol, "frames sent by the client must be masked")
+		return
+	}
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var b [2]byte
+		_, err = io.ReadFull(ws.rw, b[:])
+		length = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		_, err = io.ReadFull(ws.rw, b[:])
+		length = binary.BigEndian.Uint64(b[:])
+	}
+	if err != nil {
+		return
+	}
+	if length > websocketMaxMessageSize {
+		err = ws.fail(websocketCloseTooBig, "message too big")
+		return
+	}
+	var mask [4]byte
+	_, err = io.ReadFull(ws.rw, mask[:])
+	if err != nil {
+		return
+	}
+	payload = make([]byte, length)
+	_, err = io.ReadFull(ws.rw, payload)
+	if err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return
+}
+
+// fail closes the connection with the given status code and reason, returning the reason as an error.
+func (ws *websocketConn) fail(code uint16, reason string) error {
+	ws.WriteClose(code, reason)
+	return errors.New(reason)
+}
+
+// Close the underlying connection.
+func (ws *websocketConn) Close() error {
+	return ws.conn.Close()
+}
@@ -20,8 +20,8 @@ for os in darwin linux; do
 	mkdir -p "$folder"
 	# compile binary
 	GOOS=${os} go build -a \
-			-ldflags="-X main.rawVersion=${full_version} -s -w" \
-			-o "${folder}/rexplorer" .
+			-ldflags="-X github.com/threefoldfoundation/rexplorer/pkg/rexplorer.rawVersion=${full_version} -s -w" \
+			-o "${folder}/rexplorer" ./cmd/rexplorer
 	# add other artifacts
 	cp -r release_notes LICENSE README.md "$folder"
 	# zip
@@ -1,11 +1,12 @@
 package main
 
 import (
-	"encoding/json"
 	"flag"
 	"fmt"
+	"time"
 
 	"github.com/rivine/rivine/types"
+	"github.com/threefoldfoundation/rexplorer/pkg/client"
 
 	"github.com/gomodule/redigo/redis"
 )
@@ -13,35 +14,54 @@ import (
 func main() {
 	flag.Parse()
 
-	conn, err := redis.Dial("tcp", dbAddress, redis.DialDatabase(dbSlot))
+	cl, err := client.Dial(dbAddress, dbSlot,
+		redis.DialPassword(dbPassword), redis.DialUseTLS(dbTLS))
 	if err != nil {
 		panic(err)
 	}
+	cl.SetKeyPrefix(dbKeyPrefix)
+	conn := cl.Conn()
+	// hold a snapshot, such that no blocks are applied by a running rexplorer while we sum the coins
+	if snapshot {
+		hold, err := cl.HoldSnapshot(snapshotTTL, snapshotTimeout)
+		if err != nil {
+			panic(err)
+		}
+		defer hold.Release()
+	}
 	// get stats, so we know what are the to be expected total coins and total locked coins
-	b, err := redis.Bytes(conn.Do("GET", "stats"))
+	b, err := redis.Bytes(conn.Do("GET", cl.Key("stats")))
 	if err != nil {
 		panic("failed to get network stats: " + err.Error())
 	}
 	var stats struct {
+		Version     uint64            `json:"version"`
 		BlockHeight types.BlockHeight `json:"blockHeight"`
 		Coins       types.Currency    `json:"coins"`
 		LockedCoins types.Currency    `json:"lockedCoins"`
 	}
-	err = json.Unmarshal(b, &stats)
+	err = cl.Unmarshal(b, &stats)
 	if err != nil {
-		panic("failed to json-unmarshal network stats: " + err.Error())
+		panic("failed to unmarshal network stats: " + err.Error())
 	}
-
-	// get all unique addresses
-	addresses, err := redis.Strings(conn.Do("SMEMBERS", "addresses"))
-	if err != nil {
-		panic("failed to get all unique addresses: " + err.Error())
+	if stats.Version > supportedStatsVersion {
+		panic(fmt.Sprintf("unsupported network stats version %d: this test supports up to version %d",
+			stats.Version, supportedStatsVersion))
 	}
 
-	// compute total unlocked and locked coins for all addresses
+	// compute total unlocked and locked coins for all unique addresses,
+	// as an address can be returned multiple times by the iterator, we have to track the ones we've seen
 	var unlockedCoins, lockedCoins types.Currency
-	for _, addr := range addresses {
+	seen := make(map[types.UnlockHash]struct{})
+	it := cl.Addresses(0)
+	for it.Next() {
+		addr := it.Address()
+		if _, ok := seen[addr]; ok {
+			continue
+		}
+		seen[addr] = struct{}{}
 		var wallet struct {
+			Version uint64 `json:"version"`
 			Balance struct {
 				Unlocked types.Currency `json:"unlocked"`
 				Locked   struct {
@@ -51,19 +71,25 @@ func main() {
 		}
 		addressKey, addressField := getAddressKeyAndField(addr)
 		b, err := redis.Bytes(conn.Do("HGET", addressKey, addressField))
-		if err != nil {
-			if err != redis.ErrNil {
-				panic("failed to get wallet " + err.Error())
-			}
-			b = []byte("{}")
+		if err != nil && err != redis.ErrNil {
+			panic("failed to get wallet " + err.Error())
 		}
-		err = json.Unmarshal(b, &wallet)
-		if err != nil {
-			panic("failed to json-unmarshal wallet: " + err.Error())
+		if err == nil {
+			err = cl.UnmarshalWallet(b, &wallet)
+			if err != nil {
+				panic("failed to unmarshal wallet: " + err.Error())
+			}
+			if wallet.Version > supportedWalletVersion {
+				panic(fmt.Sprintf("unsupported version %d of wallet %s: this test supports up to version %d",
+					wallet.Version, addr.String(), supportedWalletVersion))
+			}
 		}
 		unlockedCoins = unlockedCoins.Add(wallet.Balance.Unlocked)
 		lockedCoins = lockedCoins.Add(wallet.Balance.Locked.Total)
 	}
+	if err := it.Err(); err != nil {
+		panic("failed to iterate over all unique addresses: " + err.Error())
+	}
 	totalCoins := unlockedCoins.Add(lockedCoins)
 
 	// ensure our total coin count is as expected
@@ -96,17 +122,38 @@ func main() {
 		"sumcoins test on block height %d passed :)\n", stats.BlockHeight)
 }
 
-func getAddressKeyAndField(addr string) (key, field string) {
-	key, field = "a:"+addr[:6], addr[6:]
+// the latest versions of the network stats and wallet payloads this test knows the structure of,
+// payloads stored prior to being versioned are version 0
+const (
+	supportedStatsVersion  = 1
+	supportedWalletVersion = 1
+)
+
+func getAddressKeyAndField(uh types.UnlockHash) (key, field string) {
+	str := uh.String()
+	key, field = dbKeyPrefix+"a:"+str[:6], str[6:]
 	return
 }
 
 var (
-	dbAddress string
-	dbSlot    int
+	dbAddress   string
+	dbSlot      int
+	dbPassword  string
+	dbTLS       bool
+	dbKeyPrefix string
+
+	snapshot        bool
+	snapshotTTL     time.Duration
+	snapshotTimeout time.Duration
 )
 
 func init() {
 	flag.StringVar(&dbAddress, "db-address", ":6379", "(tcp) address of the redis db")
 	flag.IntVar(&dbSlot, "db-slot", 0, "slot/index of the redis db")
+	flag.StringVar(&dbPassword, "db-password", "", "password used to authenticate to the redis db")
+	flag.BoolVar(&dbTLS, "db-tls", false, "connect to the redis db using TLS")
+	flag.StringVar(&dbKeyPrefix, "db-key-prefix", "", "prefix of all keys, as used by rexplorer (e.g. \"tft:standard:\")")
+	flag.BoolVar(&snapshot, "snapshot", false, "hold a snapshot, required when rexplorer is running while testing")
+	flag.DurationVar(&snapshotTTL, "snapshot-ttl", 10*time.Minute, "time after which a held snapshot is released automatically")
+	flag.DurationVar(&snapshotTimeout, "snapshot-timeout", 30*time.Second, "time to wait for rexplorer to acknowledge a snapshot hold")
 }